@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// aiResponseCache reuses the stored AI replies of an entry when a pull request's diff has not
+// changed since they were generated.
+type aiResponseCache struct {
+	ar     *AutoReviewPRHandler
+	auto   *model.AutoReviewPR
+	maxAge time.Duration
+}
+
+// aiCache returns the AI reply cache for auto, or nil when caching is disabled or there is no
+// storage to keep the replies in.
+func (ar *AutoReviewPRHandler) aiCache(auto *model.AutoReviewPR) *aiResponseCache {
+	if ar.Storage == nil || ar.AICache.Disabled {
+		return nil
+	}
+	maxAge, _ := ar.AICache.MaxAgeDuration()
+	return &aiResponseCache{ar: ar, auto: auto, maxAge: maxAge}
+}
+
+func (c *aiResponseCache) Load(key string) (model.AIResponse, bool) {
+	r, err := c.ar.Storage.LoadAIResponse(key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Warnf("Reading cached AI reply failed: %v", err)
+		}
+		c.ar.countAICache("miss")
+		return r, false
+	}
+	if time.Since(r.CreatedAt) > c.maxAge {
+		c.ar.countAICache("expired")
+		return r, false
+	}
+	c.ar.countAICache("hit")
+	what := r.Kind + " reply"
+	if r.Path != "" {
+		what += " of " + r.Path
+	}
+	log.Infof("PR #%d: diff unchanged; reusing the cached AI %s from %s", r.PullRequestID, what, r.CreatedAt.Format(time.RFC3339))
+	return r, true
+}
+
+func (c *aiResponseCache) Store(r model.AIResponse) {
+	r.ProcessName, r.Workspace, r.RepoSlug = c.auto.ProcessName, c.auto.Workspace, c.auto.RepoSlug
+	r.CreatedAt = time.Now()
+	if err := c.ar.Storage.SaveAIResponse(r); err != nil {
+		log.Warnf("Caching AI %s reply for PR #%d failed: %v", r.Kind, r.PullRequestID, err)
+	}
+}
+
+func (ar *AutoReviewPRHandler) countAICache(result string) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.aiCacheResults == nil {
+		ar.aiCacheResults = make(map[string]int64)
+	}
+	ar.aiCacheResults[result]++
+}
+
+func (ar *AutoReviewPRHandler) writeAICacheMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_ai_cache_total AI calls looked up in the reply cache, by result.\n# TYPE code_nim_ai_cache_total counter\n")
+	for _, result := range []string{"hit", "miss", "expired"} {
+		fmt.Fprintf(b, "code_nim_ai_cache_total{result=%q} %d\n", result, ar.aiCacheResults[result])
+	}
+}
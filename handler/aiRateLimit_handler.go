@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// writeAIRateLimitMetrics appends the queue of the AI request scheduler, when aiRateLimit is set.
+func (ar *AutoReviewPRHandler) writeAIRateLimitMetrics(b *strings.Builder) {
+	if ar.AIScheduler == nil {
+		return
+	}
+	st := ar.AIScheduler.Stats()
+	b.WriteString("# HELP code_nim_ai_requests_waiting AI requests waiting for a slot under aiRateLimit, by repository.\n# TYPE code_nim_ai_requests_waiting gauge\n")
+	for _, repo := range st.Repos() {
+		fmt.Fprintf(b, "code_nim_ai_requests_waiting{repo=%q} %d\n", repo, st.Waiting[repo])
+	}
+	b.WriteString("# HELP code_nim_ai_requests_admitted_total AI requests admitted by the rate limit scheduler.\n# TYPE code_nim_ai_requests_admitted_total counter\n")
+	fmt.Fprintf(b, "code_nim_ai_requests_admitted_total %d\n", st.Admitted)
+	b.WriteString("# HELP code_nim_ai_requests_queued_total AI requests that had to wait for a slot.\n# TYPE code_nim_ai_requests_queued_total counter\n")
+	fmt.Fprintf(b, "code_nim_ai_requests_queued_total %d\n", st.Queued)
+	b.WriteString("# HELP code_nim_ai_requests_timeout_total AI requests that gave up after aiRateLimit.maxWait.\n# TYPE code_nim_ai_requests_timeout_total counter\n")
+	fmt.Fprintf(b, "code_nim_ai_requests_timeout_total %d\n", st.Timeouts)
+	b.WriteString("# HELP code_nim_ai_request_wait_seconds_total Time admitted AI requests spent waiting for a slot.\n# TYPE code_nim_ai_request_wait_seconds_total counter\n")
+	fmt.Fprintf(b, "code_nim_ai_request_wait_seconds_total %g\n", st.Waited.Seconds())
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"code_nim/review"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxAnalyzeBody bounds the request of POST /api/v1/analyze.
+const maxAnalyzeBody = 10 << 20
+
+// Analyze handles POST /api/v1/analyze. It reviews the unified diff of the request with the AI
+// settings of a configured entry and answers with the findings anchored to file lines, without
+// reading from or posting to any git host. The AI budget applies as for pull request reviews.
+func (ar *AutoReviewPRHandler) Analyze(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxAnalyzeBody+1))
+	if err != nil || len(body) > maxAnalyzeBody {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Request must be at most %d bytes", maxAnalyzeBody),
+		})
+	}
+	var req model.AnalyzeRequest
+	if err := json.Unmarshal(body, &req); err != nil || !strings.Contains(req.Diff, "diff --git") {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Request must be JSON with a unified git diff in \"diff\"",
+		})
+	}
+	auto, ok := ar.analyzeEntry(req.ProcessName)
+	if !ok {
+		return c.JSON(http.StatusNotFound, model.Response{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("No entry %q configured; set processName to one of the configured entries", req.ProcessName),
+		})
+	}
+	if exceeded, reason := ar.budgetExceeded(); exceeded {
+		return c.JSON(http.StatusServiceUnavailable, model.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    reason,
+		})
+	}
+
+	r := review.New(auto)
+	if ar.Timings != nil {
+		// Not ar.observeDuration: that counts AI calls against the job in progress, if any
+		r.Observe = ar.Timings.Observe
+	}
+	if cache := ar.aiCache(&auto); cache != nil {
+		r.Cache = cache
+	}
+	var result review.Result
+	if req.Summary {
+		result, err = r.Review(&model.PullRequest{Title: req.Title, Description: req.Description}, req.Diff)
+	} else {
+		result, err = r.ReviewDiff(req.Diff)
+	}
+	ar.noteProviderResult(&auto, err)
+	if err != nil {
+		log.Errorf("Analyze request for %s failed: %v", auto.ProcessName, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, helper.ErrRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		return c.JSON(status, model.Response{StatusCode: status, Message: err.Error()})
+	}
+	log.Infof("Analyzed a diff with %s: %d files, %d findings", auto.ProcessName, len(result.Files), len(result.Findings()))
+	return c.JSON(http.StatusOK, analyzeResponse(&auto, result))
+}
+
+// analyzeEntry returns the entry named processName (workspace/repoSlug for unnamed entries), or
+// the only entry when it is empty.
+func (ar *AutoReviewPRHandler) analyzeEntry(processName string) (model.AutoReviewPR, bool) {
+	entries := ar.entrySnapshot()
+	if processName == "" {
+		if len(entries) != 1 {
+			return model.AutoReviewPR{}, false
+		}
+		for _, auto := range entries {
+			return auto, true
+		}
+	}
+	auto, ok := entries[processName]
+	return auto, ok
+}
+
+// analyzeResponse converts a review result into the JSON answer of POST /api/v1/analyze.
+func analyzeResponse(auto *model.AutoReviewPR, result review.Result) model.AnalyzeResponse {
+	resp := model.AnalyzeResponse{Findings: []model.AnalyzeFinding{}}
+	if result.Summary != "" {
+		resp.Summary = review.RenderSummaryIn(result.Summary, auto.Language)
+	}
+	for _, c := range result.Findings() {
+		typ, severity, title := helper.ParseFindingHeading(c.Body)
+		f := model.AnalyzeFinding{
+			Path:      c.Path,
+			Line:      c.Position,
+			StartLine: c.StartLine,
+			Type:      typ,
+			Severity:  severity,
+			Title:     title,
+			Body:      review.RenderFindingIn(c.Body, helper.ReviewStyle(helper.PackageConfig(auto, c.Path)), auto.Language),
+			Fix:       c.Fix,
+		}
+		if c.FromLine > 0 {
+			f.FromLine = c.FromLine
+		}
+		resp.Findings = append(resp.Findings, f)
+	}
+	for _, s := range result.Skipped {
+		resp.Skipped = append(resp.Skipped, model.AnalyzeSkippedFile{Path: s.Path, Reason: s.Reason})
+	}
+	for path, err := range result.Errors {
+		if resp.Errors == nil {
+			resp.Errors = map[string]string{}
+		}
+		resp.Errors[path] = err.Error()
+	}
+	return resp
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// approvalThreshold returns the most severe finding that still allows auto-approval.
+func approvalThreshold(auto *model.AutoReviewPR) string {
+	t := strings.TrimSpace(auto.AutoApproveMaxSeverity)
+	if t == "" {
+		return helper.SeverityNone
+	}
+	if !helper.ValidSeverity(t) {
+		log.Warnf("Unknown autoApproveMaxSeverity %q for %s; approving only without findings", t, entryKey(*auto))
+		return helper.SeverityNone
+	}
+	if strings.EqualFold(t, helper.SeverityNone) {
+		return helper.SeverityNone
+	}
+	return strings.ToUpper(t[:1]) + strings.ToLower(t[1:])
+}
+
+// autoApprovalNote explains the bot's approval policy at the end of the summary comment.
+func autoApprovalNote(auto *model.AutoReviewPR) string {
+	if !auto.AutoApprove {
+		return ""
+	}
+	threshold := approvalThreshold(auto)
+	if threshold == helper.SeverityNone {
+		return "> **Auto-approval:** Nim approves this PR only when none of its findings remain open.\n\n"
+	}
+	return fmt.Sprintf("> **Auto-approval:** Nim approves this PR when no open finding is more severe than **%s**, and withdraws its approval otherwise.\n\n", threshold)
+}
+
+// openFindings returns the bot findings still open on the pull request: inline comments and
+// unanchored findings that are neither deleted nor resolved, plus the findings table of the
+// latest consolidated (minimal-mode) comment.
+func openFindings(comments []model.PullRequestComment) []model.Finding {
+	var findings []model.Finding
+	latestTable := ""
+	for _, comment := range comments {
+		raw := comment.Content.Raw
+		if comment.Deleted || comment.Resolution != nil || !hasBotMarker(raw) {
+			continue
+		}
+		if comment.Inline != nil {
+			typ, severity, title := helper.ParseFindingHeading(raw)
+			findings = append(findings, model.Finding{
+				Path: comment.Inline.Path, Line: comment.Inline.To,
+				Type: typ, Severity: severity, Title: title, Body: raw,
+			})
+			continue
+		}
+		if strings.Contains(raw, unanchoredMarker) {
+			for _, section := range strings.Split(raw, unanchoredSeparator)[1:] {
+				heading, body, _ := strings.Cut(strings.TrimSpace(section), "\n")
+				typ, severity, title := helper.ParseFindingHeading(body)
+				findings = append(findings, model.Finding{
+					Path: unanchoredPath(heading),
+					Type: typ, Severity: severity, Title: title, Body: body,
+				})
+			}
+			continue
+		}
+		if strings.Contains(raw, "## Findings\n") {
+			latestTable = raw
+		}
+	}
+	return append(findings, helper.FindingsTableRows(latestTable)...)
+}
+
+// remainingSeverities returns the severities of the bot findings still open on the pull request.
+func remainingSeverities(findings []model.Finding) []string {
+	severities := make([]string, 0, len(findings))
+	for _, f := range findings {
+		severities = append(severities, f.Severity)
+	}
+	return severities
+}
+
+// loadOpenFindings fetches the open bot findings of the pull request once per run.
+func (ar *AutoReviewPRHandler) loadOpenFindings(run *reviewRun) ([]model.Finding, error) {
+	if run.OpenFindings != nil {
+		return run.OpenFindings, nil
+	}
+	auto, pr := run.Auto, run.PR
+	comments, err := ar.Bitbucket.FetchPullRequestComments(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	if err != nil {
+		return nil, err
+	}
+	run.PostedComments = comments
+	run.OpenFindings = openFindings(comments)
+	if run.OpenFindings == nil {
+		run.OpenFindings = []model.Finding{}
+	}
+	return run.OpenFindings, nil
+}
+
+// approveStage casts the bot's vote once new changes have been reviewed: it approves when the
+// highest remaining finding severity is within autoApproveMaxSeverity and withdraws the approval
+// otherwise. An incomplete review leaves the vote unchanged.
+func (ar *AutoReviewPRHandler) approveStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.AutoApprove {
+		return nil
+	}
+	if !auto.ReviewsInline() {
+		log.Debugf("PR #%d: mode %s reviews no file; not voting", pr.ID, auto.Mode)
+		return nil
+	}
+	if !run.SummaryPosted && run.InlinePosted == 0 && !run.HasNewCommits {
+		log.Debugf("PR #%d: nothing new reviewed; keeping the current approval vote", pr.ID)
+		return nil
+	}
+	if run.PostErr != nil {
+		log.Warnf("PR #%d: not changing the approval vote because the review is incomplete: %v", pr.ID, run.PostErr)
+		return nil
+	}
+
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Errorf("Error fetching comments for approval of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	severities := remainingSeverities(findings)
+	highest := helper.HighestSeverity(severities)
+	threshold := approvalThreshold(auto)
+	approve := helper.SeverityRank(highest) <= helper.SeverityRank(threshold)
+
+	publishStart := time.Now()
+	if approve {
+		err = ar.Bitbucket.ApprovePullRequest(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	} else {
+		err = ar.Bitbucket.UnapprovePullRequest(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	}
+	ar.observe(run, "publish", publishStart)
+	if err != nil {
+		log.Errorf("Failed to update approval of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	if approve {
+		log.Infof("✓ Approved PR #%d (%d open findings, highest severity %s, threshold %s)", pr.ID, len(severities), highest, threshold)
+	} else {
+		log.Infof("Withheld approval of PR #%d (%d open findings, highest severity %s above threshold %s)", pr.ID, len(severities), highest, threshold)
+	}
+	run.Approved = approve
+	return nil
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// autoFixStage commits, when autoFix is enabled, the mechanical fixes of the findings posted
+// in this run to a new branch and opens a follow-up pull request into the PR's source branch.
+// Fork pull requests and the bot's own follow-up pull requests are skipped. A failure is
+// logged; the review itself is not affected.
+func (ar *AutoReviewPRHandler) autoFixStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.AutoFix.Enabled || len(run.Fixes) == 0 || run.LatestCommitHash == "" || isAutoFixPullRequest(auto, pr) {
+		return nil
+	}
+	if repo, ok := forkOf(auto, pr); ok {
+		log.Infof("Skipping auto-fix for PR #%d: source branch is in fork %s", pr.ID, repo)
+		return nil
+	}
+
+	byPath := map[string][]model.ReviewComment{}
+	for _, f := range run.Fixes {
+		byPath[f.Path] = append(byPath[f.Path], f)
+	}
+	paths := make([]string, 0, len(byPath))
+	for p := range byPath {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	files := map[string]string{}
+	var applied []model.ReviewComment
+	for _, p := range paths {
+		if len(applied) >= auto.AutoFix.Limit() {
+			break
+		}
+		content, found, err := ar.Bitbucket.FetchFileContent(auto.Workspace, auto.RepoSlug, run.LatestCommitHash, p, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching %s to auto-fix PR #%d: %v", p, pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			continue
+		}
+		if !found {
+			continue
+		}
+		fixes := byPath[p]
+		if room := auto.AutoFix.Limit() - len(applied); len(fixes) > room {
+			fixes = fixes[:room]
+		}
+		fixed, done := helper.ApplyFixes(content, fixes)
+		if len(done) == 0 || fixed == content {
+			continue
+		}
+		files[p] = fixed
+		applied = append(applied, done...)
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	branch := helper.AutoFixBranch(auto.AutoFix.Prefix(), pr.ID, run.LatestCommitHash)
+	var fixPR int
+	posted, err := ar.deliverComment(auto, pr, run.LatestCommitHash, "autofix:"+branch, func() error {
+		message := fmt.Sprintf("Apply %s from the review of PR #%d", helper.Pluralize(len(applied), "mechanical fix", "mechanical fixes"), pr.ID)
+		if _, err := ar.Bitbucket.CommitFiles(auto.Workspace, auto.RepoSlug, branch, run.LatestCommitHash, message, files, auto.Username, auto.AppPassword); err != nil {
+			return fmt.Errorf("commit fixes to %s: %w", branch, err)
+		}
+		title := fmt.Sprintf("Auto-fix: %s for #%d", helper.Pluralize(len(applied), "mechanical fix", "mechanical fixes"), pr.ID)
+		description := helper.FormatAutoFixDescription(auto.Workspace, auto.RepoSlug, pr.ID, applied)
+		id, err := ar.Bitbucket.CreatePullRequest(auto.Workspace, auto.RepoSlug, title, description, branch, pr.Source.Branch.Name, auto.Username, auto.AppPassword)
+		if err != nil {
+			return fmt.Errorf("open follow-up pull request from %s: %w", branch, err)
+		}
+		fixPR = id
+		note := fmt.Sprintf("🔧 Opened #%d with %s for the findings of this review. Merge it into `%s` to apply them.\n%s",
+			id, helper.Pluralize(len(applied), "mechanical fix", "mechanical fixes"), pr.Source.Branch.Name, reviewBotMarker)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, note))
+	})
+	if err != nil {
+		log.Errorf("Auto-fix failed for PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	if posted {
+		log.Infof("✓ Opened auto-fix PR #%d with %d fixes for PR #%d", fixPR, len(applied), pr.ID)
+	}
+	return nil
+}
+
+// isAutoFixPullRequest reports whether pr is a follow-up pull request opened by autoFixStage,
+// recognised by the branch prefix of its source branch.
+func isAutoFixPullRequest(auto *model.AutoReviewPR, pr *model.PullRequest) bool {
+	return strings.HasPrefix(pr.Source.Branch.Name, auto.AutoFix.Prefix())
+}
+
+// forkOf returns the repository of pr's source branch when it is a fork of the entry's repository.
+func forkOf(auto *model.AutoReviewPR, pr *model.PullRequest) (string, bool) {
+	repo := strings.TrimSpace(pr.Source.Repository.FullName)
+	return repo, repo != "" && !strings.EqualFold(repo, auto.Workspace+"/"+auto.RepoSlug)
+}
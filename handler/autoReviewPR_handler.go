@@ -2,9 +2,23 @@ package handler
 
 import (
 	"code_nim/helper"
+	"code_nim/helper/analysis"
 	"code_nim/helper/atlassian"
+	"code_nim/helper/gerrit"
+	"code_nim/helper/github"
+	"code_nim/helper/httpcache"
+	"code_nim/helper/leader"
+	"code_nim/helper/ledger"
+	"code_nim/helper/notify"
+	"code_nim/helper/queue"
+	"code_nim/helper/ratelimit"
+	"code_nim/helper/secrets"
+	"code_nim/helper/storage"
+	"code_nim/helper/timing"
+	"code_nim/helper/usage"
 	"code_nim/log"
 	"code_nim/model"
+	"code_nim/review"
 	"fmt"
 	"strings"
 	"sync"
@@ -37,6 +51,9 @@ const reviewMarkerPrefix = "<!-- auto-review-base:"
 const reviewMarkerSuffix = "-->"
 const reviewBotMarker = "<!-- auto-review-bot -->"
 
+// commentModeMinimal posts a single consolidated comment per PR instead of inline comments.
+const commentModeMinimal = "minimal"
+
 func hasBotMarker(raw string) bool {
 	return strings.Contains(raw, reviewMarkerPrefix) || strings.Contains(raw, reviewBotMarker)
 }
@@ -81,241 +98,285 @@ func shortHash(hash string) string {
 }
 
 type AutoReviewPRHandler struct {
-	Bitbucket atlassian.Bitbucket
-	mutex     sync.Mutex // Prevents concurrent review executions
-	isRunning bool       // Flag to track if review is currently running
-}
-
-func (ar *AutoReviewPRHandler) HandlerAutoReviewPR() {
-	var cfg model.Task
-	helper.LoadConfigFile(&cfg)
-	log.Info("Init Review PullRequest Handler")
-
-	s, err := gocron.NewScheduler()
-	if err != nil {
-		log.Errorf("Failed to create scheduler: %v", err)
-		return
-	}
-
-	reviewTask := func(auto model.AutoReviewPR) error {
-		// Check if another review is already running (thread-safe check)
-		ar.mutex.Lock()
-		if ar.isRunning {
-			ar.mutex.Unlock()
-			log.Infof("Skipping review execution - another review process is already running for %s/%s", auto.Workspace, auto.RepoSlug)
-			return nil
-		}
-		ar.isRunning = true
-		ar.mutex.Unlock()
+	Bitbucket   atlassian.Bitbucket
+	Gerrit      gerrit.Gerrit // Client of the entries with gitProvider gerrit
+	GitHub      github.GitHub // Client of the entries with gitProvider github
+	Storage     storage.Storage
+	Transcripts model.TranscriptSettings
+	// DashboardURL is the public base URL of this service, used to link finding details.
+	DashboardURL string
+	Queue        queue.JobQueue // Review jobs wait here; nil runs scheduled reviews inline
+	Webhook      model.WebhookSettings
+	Leader       *leader.Elector // With several replicas, only the leader runs scheduled reviews; nil always leads
+	// Notifications remembers delivered comments so restarts and retries do not post them twice.
+	Notifications *ledger.Ledger
+	Usage         *usage.Tracker // AI token accounting; reviews pause once its daily budget is spent
+	Timings       *timing.Recorder
+	// Benchmark exports the latest model benchmark results in /metrics; nil without benchmarks.
+	Benchmark *BenchmarkHandler
+	// AICache controls the reuse of stored AI replies for unchanged diffs.
+	AICache model.AICacheSettings
+	// Secrets resolves the vault: and aws-sm: references of the entries again on
+	// secrets.refreshInterval; nil leaves the credentials as resolved at startup.
+	Secrets *secrets.Resolver
+	// HTTPCache is the Bitbucket response cache, exported in /metrics; nil when caching is off.
+	HTTPCache *httpcache.Transport
+	// AIScheduler queues the AI requests of all jobs under aiRateLimit, exported in /metrics;
+	// nil when no limit is set.
+	AIScheduler *ratelimit.Scheduler
+	// Notifier sends review events to the notifiers of the entries; nil sends none.
+	Notifier      *notify.Dispatcher
+	runID         string // Short ID of the review run in progress, for comment footers
+	queueSettings model.QueueSettings
+	// circuitSettings configures when jobs failing on Bitbucket are paused
+	circuitSettings model.CircuitBreakerSettings
 
-		// Ensure we reset the running flag when done
-		defer func() {
-			ar.mutex.Lock()
-			ar.isRunning = false
-			ar.mutex.Unlock()
-			log.Info("Review PR Handler completed - lock released")
-		}()
+	statsMutex        sync.Mutex
+	placementRejected map[string]int64            // Rejected inline comment placements by reason
+	webhookRejected   map[string]int64            // Rejected webhook deliveries by reason
+	findingsDeferred  int64                       // Findings held back on busy PRs
+	postingTotals     model.PostingResult         // Inline comment outcomes since start
+	aiCacheResults    map[string]int64            // AI reply cache lookups by result
+	secretRefreshes   map[string]int64            // Secret refreshes by result
+	taskPanics        map[string]int64            // Recovered panics by task
+	reviewedUpdates   map[string]map[int]string   // updated_on of each PR at its last complete review, by entryKey
+	unchangedSkipped  int64                       // PRs skipped because they had not changed
+	deliveries        map[string]time.Time        // Recently accepted webhook delivery IDs
+	jobs              map[string]*model.JobStatus // Runtime state per entryKey, guarded by statsMutex
+	runBaseline       model.JobStatus             // Counters of the current job when its run began
+	runPRs            []model.JobRunPR            // Pull requests looked at by the current run
+	providers         map[string]*providerWindow  // Recent AI call results by provider
+	startedAt         time.Time                   // When the handler was set up, for uptime
+	feedbackStats     []feedbackReport            // Feedback per repository, refreshed after each collection
+	cronJobs          map[string]gocron.Job       // Scheduled cron job per entryKey; absent while paused
+	scheduler         gocron.Scheduler
+	currentJob        string // entryKey of the review in progress
+	entries           map[string]model.AutoReviewPR
+	mutex             sync.Mutex // Prevents concurrent review executions
+	isRunning         bool       // Flag to track if review is currently running
 
-		startTime := time.Now()
-		log.Infof("Start Review PR Handler for %s/%s (acquired lock)", auto.Workspace, auto.RepoSlug)
-		allPR, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
-		if err != nil {
-			log.Errorf("Error rotating session: %v", err)
-			return err
-		}
-		log.Infof("Fetched %d pull requests for review", len(allPR))
-		for i, pullRequest := range allPR {
-			log.Infof("Processing PR #%d: '%s' by %s", pullRequest.ID, pullRequest.Title, pullRequest.Author.DisplayName)
+	// Per team, guarded by statsMutex
+	digestSentAt    map[string]time.Time                  // When the last digest was sent
+	digestBaselines map[string]map[string]model.JobStatus // Job counters at the last digest, by entryKey
 
-			// Add small delay between PRs to reduce API load and prevent rate limiting
-			if i > 0 {
-				time.Sleep(2 * time.Second)
-				log.Debugf("Added delay before processing PR #%d", pullRequest.ID)
-			}
+	// Ignored pull requests by ignoredKey, read from Storage on first use; guarded by statsMutex
+	ignored       map[string]model.IgnoredPullRequest
+	ignoredLoaded bool
+	// When the posting window of each entryKey opens, while a run is scheduled for it
+	windowRuns map[string]time.Time
+	// Latest credential self-test, guarded by statsMutex; nil until one ran
+	selfTest *model.SelfTestReport
+	// UUID of the bot account by Bitbucket username, guarded by statsMutex
+	botUUIDs map[string]string
+}
 
-			// Summary-only mode flag: when true, we will generate summary but skip inline review
-			skipInlineByDisplayName := false
-			skipAllByLGTM := false
+// recordTranscript stores an AI exchange when transcript recording is enabled.
+func (ar *AutoReviewPRHandler) recordTranscript(t model.Transcript) {
+	if !ar.Transcripts.Enabled || ar.Storage == nil {
+		return
+	}
+	if err := ar.Storage.SaveTranscript(t); err != nil {
+		log.Errorf("Failed to record %s transcript for PR #%d: %v", t.Kind, t.PullRequestID, err)
+	}
+}
 
-			ignorePROfName := false
-			for _, displayNameConfig := range auto.IgnorePullRequestOf.DisplayNames {
-				log.Debugf("Checking if PR author '%s' matches ignore list entry '%s'", pullRequest.Author.DisplayName, displayNameConfig)
-				if displayNameConfig == pullRequest.Author.DisplayName {
-					log.Infof("Will ignore PR #%d by %s (matches ignore list)", pullRequest.ID, displayNameConfig)
-					ignorePROfName = true
-					break // No need to check other ignore entries once we found a match
-				}
-			}
-			if ignorePROfName {
-				log.Infof("Author is in ignore list → summary-only mode for PR #%d", pullRequest.ID)
-				skipInlineByDisplayName = true
-			}
+// notifyChannelBitbucket is the ledger channel of comments posted on Bitbucket pull requests.
+const notifyChannelBitbucket = "bitbucket"
 
-			log.Infof("Starting review process for PR #%d by %s", pullRequest.ID, pullRequest.Author.DisplayName)
-			comments, err := ar.Bitbucket.FetchPullRequestComments(pullRequest.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
-			if err != nil {
-				log.Errorf("Error Pull Comments: %v", err)
-				return err
-			}
-			maxTotal := auto.MaxTotalComments
-			if maxTotal <= 0 {
-				maxTotal = 200
-			}
-			if len(comments) >= maxTotal {
-				log.Infof("PR #%d reached total comment limit (max=%d, total=%d); skipping AI review", pullRequest.ID, maxTotal, len(comments))
-				continue
-			}
+// deliverComment posts a Bitbucket comment on pr unless the same subject was already posted for
+// commit. It reports whether the comment was posted now.
+func (ar *AutoReviewPRHandler) deliverComment(auto *model.AutoReviewPR, pr *model.PullRequest, commit, subject string, post func() error) (bool, error) {
+	return ar.Notifications.Deliver(model.Notification{
+		Channel:       notifyChannelBitbucket,
+		Workspace:     auto.Workspace,
+		RepoSlug:      auto.RepoSlug,
+		PullRequestID: pr.ID,
+		Commit:        commit,
+		Subject:       subject,
+	}, post)
+}
 
-			// Check for existing summary and inline review comments independently
-			hasSummary := false
-			hasInlineReview := false
-			existingInlineComments := make(map[string]bool)
-			lastReviewedHash := ""
+// reviewer returns the embeddable review core configured for auto, reporting its step timings.
+// With a run, the prompts include its linter findings and learned preferences; run may be nil.
+func (ar *AutoReviewPRHandler) reviewer(auto *model.AutoReviewPR, run *reviewRun) *review.Reviewer {
+	r := review.New(*auto)
+	r.Observe = func(stage string, d time.Duration) { ar.observeDuration(run, stage, d) }
+	if cache := ar.aiCache(auto); cache != nil {
+		r.Cache = cache
+	}
+	if run != nil && (run.LintFindings != nil || run.Preferences != "") {
+		findings, preferences := run.LintFindings, run.Preferences
+		r.FileContext = func(path string) string { return analysis.PromptContext(findings[path]) + preferences }
+	}
+	return r
+}
 
-			for i2, comment := range comments {
-				log.Debugf("Check Comment of %s - %s in PR : %d - %d", comment.User.Username, comment.User.DisplayName, pullRequest.ID, i2)
+// observe records the time spent in stage since start, process-wide and, when run is non-nil,
+// for its pull request.
+func (ar *AutoReviewPRHandler) observe(run *reviewRun, stage string, start time.Time) {
+	ar.observeDuration(run, stage, time.Since(start))
+}
 
-				// Detect an already-posted summary in general comments (not inline)
-				if comment.Content.Raw != "" && comment.Inline == nil {
-					lc := strings.ToLower(strings.TrimSpace(comment.Content.Raw))
-					if strings.HasPrefix(lc, "## summary") ||
-						strings.Contains(lc, "summary by ") ||
-						strings.Contains(lc, "- **new features**") ||
-						strings.Contains(lc, "- **bug fixes**") ||
-						strings.Contains(lc, "- **documentation**") ||
-						strings.Contains(lc, "- **refactor**") ||
-						strings.Contains(lc, "- **performance**") ||
-						strings.Contains(lc, "- **tests**") ||
-						strings.Contains(lc, "- **chores**") {
-						hasSummary = true
-					}
-				}
+func (ar *AutoReviewPRHandler) observeDuration(run *reviewRun, stage string, d time.Duration) {
+	if ar.Timings != nil {
+		ar.Timings.Observe(stage, d)
+	}
+	if run != nil && run.Breakdown != nil {
+		run.Breakdown.Add(stage, d)
+	}
+	if stage == "ai" {
+		ar.updateCurrentJob(func(js *model.JobStatus) { js.AICalls++ })
+	}
+}
 
-				// If a commenter says 'LGTM', pause all bot reviews for this PR.
-				if comment.Inline == nil && !hasBotMarker(comment.Content.Raw) {
-					lcBody := strings.ToLower(strings.TrimSpace(comment.Content.Raw))
-					if strings.Contains(lcBody, "lgtm") {
-						skipAllByLGTM = true
-						log.Infof("LGTM detected by %s; will skip all reviews for PR #%d", comment.User.DisplayName, pullRequest.ID)
-					}
-				}
+// budgetExceeded reports whether the daily AI budget is spent.
+func (ar *AutoReviewPRHandler) budgetExceeded() (bool, string) {
+	if ar.Usage == nil {
+		return false, ""
+	}
+	return ar.Usage.BudgetExceeded()
+}
 
-				// NOTE: Do not skip inline reviews just because a human reviewer left comments.
-				// Inline review is only paused by explicit LGTM (see skipAllByLGTM).
+func (ar *AutoReviewPRHandler) HandlerAutoReviewPR(cfg model.Task) {
+	log.Info("Init Review PullRequest Handler")
 
-				// Detect existing inline review comments posted by the bot (to avoid duplicates).
-				// Use hidden marker to distinguish bot comments when accounts are shared.
-				if comment.Inline != nil && hasBotMarker(comment.Content.Raw) {
-					hasInlineReview = true
-					key := fmt.Sprintf("%s:%d", comment.Inline.Path, comment.Inline.To)
-					existingInlineComments[key] = true
-					log.Debugf("Found existing inline review (by bot) at %s:%d", comment.Inline.Path, comment.Inline.To)
-				}
-			}
-			if skipAllByLGTM {
-				log.Infof("Skipping PR #%d because LGTM pause is active", pullRequest.ID)
-				continue
-			}
-			lastReviewedHash = extractLastReviewedHash(comments)
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		log.Errorf("Failed to create scheduler: %v", err)
+		return
+	}
 
-			commits, err := ar.Bitbucket.FetchPullRequestCommits(pullRequest.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
-			if err != nil {
-				log.Errorf("Error fetching commits for PR #%d: %v", pullRequest.ID, err)
-			}
-			latestCommitHash := ""
-			if len(commits) > 0 {
-				// Bitbucket returns PR commits newest-first; latest is the first element.
-				latestCommitHash = commits[0].Hash
-				log.Debugf("PR #%d: Found %d commits, latest=%s", pullRequest.ID, len(commits), shortHash(latestCommitHash))
-				// Log all commit hashes for debugging
-				for i, c := range commits {
-					log.Debugf("PR #%d: commit[%d]=%s", pullRequest.ID, i, shortHash(c.Hash))
-				}
-			} else {
-				log.Debugf("PR #%d: No commits found", pullRequest.ID)
-			}
-			log.Debugf("PR #%d: lastReviewedHash=%s, latestCommitHash=%s", pullRequest.ID, shortHash(lastReviewedHash), shortHash(latestCommitHash))
-			hasNewCommits := false
-			useDeltaDiff := false
-			if latestCommitHash != "" {
-				if lastReviewedHash == "" {
-					hasNewCommits = true
-				} else if lastReviewedHash != latestCommitHash {
-					hasNewCommits = true
-					for _, c := range commits {
-						if c.Hash == lastReviewedHash {
-							useDeltaDiff = true
-							break
-						}
-					}
-				}
-			}
-			if latestCommitHash == "" {
-				log.Infof("PR #%d commit tracking unavailable; using full diff", pullRequest.ID)
-			} else if hasNewCommits {
-				if lastReviewedHash == "" {
-					log.Infof("PR #%d has new commits; first review detected (latest %s)", pullRequest.ID, shortHash(latestCommitHash))
-				} else {
-					log.Infof("PR #%d has new commits since %s (latest %s)", pullRequest.ID, shortHash(lastReviewedHash), shortHash(latestCommitHash))
-				}
-			} else {
-				log.Infof("PR #%d has no new commits since last review (latest commit %s already reviewed)", pullRequest.ID, shortHash(latestCommitHash))
+	cfg.AutoReviewPRs = ar.expandEntries(cfg.AutoReviewPRs)
+	ar.statsMutex.Lock()
+	ar.entries = map[string]model.AutoReviewPR{}
+	ar.startedAt = time.Now()
+	ar.jobs = map[string]*model.JobStatus{}
+	ar.digestSentAt = map[string]time.Time{}
+	ar.digestBaselines = map[string]map[string]model.JobStatus{}
+	for _, review := range cfg.AutoReviewPRs {
+		key := entryKey(review)
+		ar.entries[key] = review
+		ar.jobs[key] = &model.JobStatus{Name: key, Workspace: review.Workspace, RepoSlug: review.RepoSlug, Cron: review.Cron}
+		for _, w := range review.FreezeWindows {
+			if _, _, _, err := helper.FreezeRange(w, time.Now()); err != nil {
+				log.Warnf("Ignoring freeze window of %s: %v", key, err)
 			}
+		}
+	}
+	ar.statsMutex.Unlock()
+	ar.queueSettings = cfg.Queue
+	ar.circuitSettings = cfg.CircuitBreaker
+	ar.startupSelfTest(cfg.SelfTest)
+	if ar.Queue != nil && cfg.Queue.Works() {
+		go ar.runWorker()
+	} else if ar.Queue != nil {
+		log.Infof("Queue role %s: this replica enqueues reviews but does not run them", cfg.Queue.RoleName())
+	}
 
-			// Fetch diff for both summary and inline review
-			log.Debugf("Check Diff PR: %d - %d", pullRequest.ID, i)
-			var diff string
-			if useDeltaDiff {
-				diff, err = ar.Bitbucket.FetchDiffBetweenCommits(auto.Workspace, auto.RepoSlug, lastReviewedHash, latestCommitHash, auto.Username, auto.AppPassword)
-			} else {
-				diff, err = ar.Bitbucket.FetchPullRequestDiff(pullRequest.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
-			}
-			if err != nil {
-				log.Errorf("Error fetching diff: %v", err)
-				return err
+	ar.scheduler = s
+	ar.cronJobs = map[string]gocron.Job{}
+	for i, review := range cfg.AutoReviewPRs {
+		log.Info("Setup Review ", i, " ==> ", review.Cron)
+		if err := ar.scheduleCron(entryKey(review), review.Cron); err != nil {
+			log.Error(err)
+		}
+		if (review.IsGerrit() || review.IsGitHub()) && (review.StaleReminders.Enabled() || review.Feedback.Enabled()) {
+			log.Warnf("Stale reminders and feedback collection are Bitbucket-only; not scheduled for %s", entryKey(review))
+			continue
+		}
+		if review.StaleReminders.Enabled() {
+			log.Info("Setup Stale PR Reminders ", i, " ==> ", review.StaleReminders.Cron)
+			if err := ar.scheduleStaleReminders(review); err != nil {
+				log.Error(err)
 			}
-			if strings.TrimSpace(diff) == "" || !strings.Contains(diff, "diff --git") {
-				if useDeltaDiff {
-					log.Warnf("Delta diff empty for PR #%d; falling back to full PR diff", pullRequest.ID)
-					diff, err = ar.Bitbucket.FetchPullRequestDiff(pullRequest.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
-					if err != nil {
-						log.Errorf("Error fetching fallback full diff: %v", err)
-						return err
-					}
-				}
-				if strings.TrimSpace(diff) == "" {
-					log.Warnf("PR #%d diff is empty after fallback; skipping review", pullRequest.ID)
-					continue
-				}
+		}
+		if review.Feedback.Enabled() {
+			log.Info("Setup Feedback Collection ", i, " ==> ", review.Feedback.Cron)
+			if err := ar.scheduleFeedback(review); err != nil {
+				log.Error(err)
 			}
+		}
+	}
+	if interval, ok := cfg.Secrets.RefreshDuration(); ok && interval > 0 && ar.Secrets != nil {
+		log.Info("Setup Secret Refresh ==> every ", interval)
+		if err := ar.scheduleSecretRefresh(interval); err != nil {
+			log.Error(err)
+		}
+	}
+	for i, d := range cfg.Digests {
+		log.Info("Setup Review Digest ", i, " ==> ", d.Team, " ", d.Schedule())
+		if err := ar.scheduleDigest(d); err != nil {
+			log.Error(err)
+		}
+	}
+	s.Start()
+	for _, review := range cfg.AutoReviewPRs {
+		if review.Feedback.Enabled() && ar.Storage != nil {
+			go ar.refreshFeedbackStats()
+			break
+		}
+	}
+}
 
-			if !hasSummary || (hasNewCommits && latestCommitHash != "") {
-				// STEP 1: Check and post summary comment if it doesn't exist
-				_, _ = ar.PostSummaryComment(&auto, &pullRequest, diff, lastReviewedHash, latestCommitHash)
-			} else {
-				log.Infof("Summary already exists for PR #%d, skipping", pullRequest.ID)
-			}
+// reviewTask reviews the open pull requests of one config entry.
+// When prID is non-zero only that pull request is reviewed.
+func (ar *AutoReviewPRHandler) reviewTask(auto model.AutoReviewPR, prID int) (err error) {
+	if exceeded, reason := ar.budgetExceeded(); exceeded {
+		log.Warnf("Pausing review for %s/%s: %s", auto.Workspace, auto.RepoSlug, reason)
+		return nil
+	}
 
-			// STEP 2: Check and post inline review comments if they don't exist (delegated)
-			skipInlineDueToExisting := hasInlineReview && !hasNewCommits
-			_, _ = ar.ensureInlineReviewComments(&auto, &pullRequest, diff, existingInlineComments, skipInlineByDisplayName, skipInlineDueToExisting, len(comments))
-		}
+	if open, until := ar.circuitOpen(entryKey(auto)); open && !auto.IsGerrit() && !auto.IsGitHub() {
+		log.Warnf("Skipping review of %s: Bitbucket kept failing, circuit open until %s", entryKey(auto), until.Format(time.RFC3339))
+		return nil
+	}
 
-		duration := time.Since(startTime)
-		log.Infof("Review PR Handler completed for %s/%s in %v", auto.Workspace, auto.RepoSlug, duration)
+	// Check if another review is already running (thread-safe check)
+	ar.mutex.Lock()
+	if ar.isRunning {
+		ar.mutex.Unlock()
+		log.Infof("Skipping review execution - another review process is already running for %s/%s", auto.Workspace, auto.RepoSlug)
 		return nil
 	}
+	ar.isRunning = true
+	ar.mutex.Unlock()
 
-	for i, review := range cfg.AutoReviewPRs {
-		review := review
-		log.Info("Setup Review ", i, " ==> ", review.Cron)
-		_, err := s.NewJob(
-			gocron.CronJob(review.Cron, true),
-			gocron.NewTask(func() { _ = reviewTask(review) }),
-		)
-		if err != nil {
-			log.Error(err)
-		}
+	// Ensure we reset the running flag when done
+	defer func() {
+		ar.mutex.Lock()
+		ar.isRunning = false
+		ar.mutex.Unlock()
+		log.Info("Review PR Handler completed - lock released")
+	}()
+
+	startTime := time.Now()
+	ar.beginJob(entryKey(auto))
+	defer func() { ar.finishJob(startTime, err) }()
+	if !auto.IsGerrit() && !auto.IsGitHub() {
+		defer func() { ar.recordBitbucketResult(auto, err) }()
 	}
-	s.Start()
+	// Runs before finishJob, so a panic is recorded as the run's error
+	defer ar.recoverPanic("review of "+entryKey(auto), &err)
+
+	log.Infof("Start Review PR Handler for %s/%s (run %s, acquired lock)", auto.Workspace, auto.RepoSlug, ar.runID)
+	if auto.IsGerrit() {
+		return ar.reviewGerritChanges(&auto, prID)
+	}
+	if auto.IsGitHub() {
+		return ar.reviewGitHubPullRequests(&auto, prID)
+	}
+	allPR, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
+	if err != nil {
+		log.Errorf("Error rotating session: %v", err)
+		ar.noteJobError(jobErrorAPI)
+		return fmt.Errorf("%w: %w", errBitbucket, err)
+	}
+	log.Infof("Fetched %d pull requests for review", len(allPR))
+	if err := ar.reviewPullRequests(&auto, allPR, prID); err != nil {
+		return err
+	}
+
+	duration := time.Since(startTime)
+	log.Infof("Review PR Handler completed for %s/%s in %v", auto.Workspace, auto.RepoSlug, duration)
+	return nil
 }
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"code_nim/helper/benchmark"
+	"code_nim/helper/leader"
+	"code_nim/helper/storage"
+	"code_nim/helper/usage"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/labstack/echo/v4"
+)
+
+// errBenchmarkRunning is returned when a benchmark is started while another one runs.
+var errBenchmarkRunning = errors.New("a benchmark is already running")
+
+// BenchmarkHandler runs the model benchmark suite on a schedule or on demand.
+type BenchmarkHandler struct {
+	Storage  storage.Storage
+	Usage    *usage.Tracker  // Token and cost accounting; the benchmark pauses once its daily budget is spent
+	Leader   *leader.Elector // Only the leader runs the scheduled benchmark; nil always leads
+	Settings model.BenchmarkSettings
+	DataDir  string
+	Entries  []model.AutoReviewPR // Review entries whose AI settings are benchmarked
+
+	mutex   sync.Mutex
+	running bool
+	latest  *model.BenchmarkRun
+}
+
+func (bh *BenchmarkHandler) suiteDir() string {
+	if bh.Settings.SuiteDir != "" {
+		return bh.Settings.SuiteDir
+	}
+	dir := bh.DataDir
+	if strings.TrimSpace(dir) == "" {
+		dir = "data"
+	}
+	return filepath.Join(dir, "benchmark-suite")
+}
+
+// HandlerBenchmark schedules the benchmark when benchmark.cron is set.
+func (bh *BenchmarkHandler) HandlerBenchmark() {
+	if !bh.Settings.Enabled() {
+		return
+	}
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		log.Errorf("Failed to create scheduler: %v", err)
+		return
+	}
+	_, err = s.NewJob(
+		gocron.CronJob(bh.Settings.Cron, false),
+		gocron.NewTask(func() {
+			if !bh.Leader.IsLeader() {
+				log.Debugf("Skipping scheduled benchmark: this replica is not the leader")
+				return
+			}
+			_, _ = bh.Run()
+		}),
+	)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	log.Infof("Setup Model Benchmark ==> %s (suite %s)", bh.Settings.Cron, bh.suiteDir())
+	s.Start()
+}
+
+// Run benchmarks every candidate on the suite, stores the run and returns it.
+func (bh *BenchmarkHandler) Run() (model.BenchmarkRun, error) {
+	bh.mutex.Lock()
+	if bh.running {
+		bh.mutex.Unlock()
+		return model.BenchmarkRun{}, errBenchmarkRunning
+	}
+	bh.running = true
+	bh.mutex.Unlock()
+	defer func() {
+		bh.mutex.Lock()
+		bh.running = false
+		bh.mutex.Unlock()
+	}()
+
+	run := model.BenchmarkRun{StartedAt: time.Now()}
+	cases, err := benchmark.LoadSuite(bh.suiteDir())
+	if err != nil {
+		log.Errorf("Failed to load benchmark suite: %v", err)
+		return run, err
+	}
+	for _, c := range cases {
+		run.Cases = append(run.Cases, c.Name)
+	}
+	candidates := benchmark.Candidates(bh.Entries, bh.Settings.Candidates)
+	log.Infof("Starting model benchmark: %d candidates on %d cases", len(candidates), len(cases))
+	for _, cfg := range candidates {
+		if bh.Usage != nil {
+			if exceeded, reason := bh.Usage.BudgetExceeded(); exceeded {
+				log.Warnf("Stopping model benchmark before %s: %s", cfg.ProcessName, reason)
+				break
+			}
+		}
+		before := bh.candidateUsage(cfg.ProcessName)
+		result := benchmark.Run(cfg, cases, bh.Settings.LineTolerance)
+		after := bh.candidateUsage(cfg.ProcessName)
+		result.Tokens = after.TotalTokens - before.TotalTokens
+		result.CostUSD = after.CostUSD - before.CostUSD
+		log.Infof("Benchmark %s: recall=%.2f precision=%.2f placement=%.2f latency=%s tokens=%d cost=$%.4f errors=%d",
+			result.Candidate, result.Recall, result.Precision, result.PlacementRate, result.AvgCaseLatency.Round(time.Millisecond), result.Tokens, result.CostUSD, result.Errors)
+		run.Results = append(run.Results, result)
+	}
+	run.Duration = time.Since(run.StartedAt)
+
+	if bh.Storage != nil {
+		if err := bh.Storage.SaveBenchmarkRun(run); err != nil {
+			log.Errorf("Failed to store benchmark run: %v", err)
+		}
+	}
+	bh.mutex.Lock()
+	bh.latest = &run
+	bh.mutex.Unlock()
+	log.Infof("Model benchmark completed in %s", run.Duration.Round(time.Second))
+	return run, nil
+}
+
+// candidateUsage returns today's usage of a candidate. A run crossing midnight under-reports.
+func (bh *BenchmarkHandler) candidateUsage(label string) model.UsageRecord {
+	if bh.Usage == nil {
+		return model.UsageRecord{}
+	}
+	for _, r := range bh.Usage.Today() {
+		if r.Workspace == benchmark.Workspace && r.RepoSlug == label {
+			return r
+		}
+	}
+	return model.UsageRecord{}
+}
+
+// Latest returns the most recent run, loading it from storage after a restart.
+func (bh *BenchmarkHandler) Latest() *model.BenchmarkRun {
+	bh.mutex.Lock()
+	defer bh.mutex.Unlock()
+	if bh.latest == nil && bh.Storage != nil {
+		runs, err := bh.Storage.ListBenchmarkRuns(time.Now().AddDate(0, 0, -90))
+		if err != nil {
+			log.Errorf("Failed to load benchmark runs: %v", err)
+		} else if len(runs) > 0 {
+			bh.latest = &runs[len(runs)-1]
+		}
+	}
+	return bh.latest
+}
+
+// GetBenchmarks handles GET /api/v1/benchmarks.
+// Optional query days (default 90) selects how far back runs are returned, oldest first.
+func (bh *BenchmarkHandler) GetBenchmarks(c echo.Context) error {
+	days := 90
+	if raw := c.QueryParam("days"); raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d <= 0 {
+			return c.JSON(http.StatusBadRequest, model.Response{
+				StatusCode: http.StatusBadRequest,
+				Message:    "days must be a positive integer",
+			})
+		}
+		days = d
+	}
+	runs, err := bh.Storage.ListBenchmarkRuns(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Errorf("Failed to load benchmark runs: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	if runs == nil {
+		runs = []model.BenchmarkRun{}
+	}
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Benchmark runs",
+		Data:       runs,
+	})
+}
+
+// RunBenchmark handles POST /api/v1/benchmarks/run and starts a benchmark in the background.
+func (bh *BenchmarkHandler) RunBenchmark(c echo.Context) error {
+	bh.mutex.Lock()
+	running := bh.running
+	bh.mutex.Unlock()
+	if running {
+		return c.JSON(http.StatusConflict, model.Response{
+			StatusCode: http.StatusConflict,
+			Message:    errBenchmarkRunning.Error(),
+		})
+	}
+	go func() { _, _ = bh.Run() }()
+	return c.JSON(http.StatusAccepted, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    fmt.Sprintf("Benchmark started on the suite in %s", bh.suiteDir()),
+	})
+}
+
+// WriteMetrics appends the results of the latest run as Prometheus gauges.
+func (bh *BenchmarkHandler) WriteMetrics(b *strings.Builder) {
+	run := bh.Latest()
+	if run == nil {
+		return
+	}
+	gauges := []struct {
+		name, help string
+		value      func(r model.BenchmarkResult) float64
+	}{
+		{"code_nim_benchmark_recall", "Share of expected findings the model reported in the latest benchmark.", func(r model.BenchmarkResult) float64 { return r.Recall }},
+		{"code_nim_benchmark_precision", "Share of placed findings near an expected one in the latest benchmark.", func(r model.BenchmarkResult) float64 { return r.Precision }},
+		{"code_nim_benchmark_placement_rate", "Share of findings anchored to a diff line in the latest benchmark.", func(r model.BenchmarkResult) float64 { return r.PlacementRate }},
+		{"code_nim_benchmark_case_latency_seconds", "Average time to review one benchmark case.", func(r model.BenchmarkResult) float64 { return r.AvgCaseLatency.Seconds() }},
+		{"code_nim_benchmark_cost_usd", "Estimated cost of the latest benchmark run per model.", func(r model.BenchmarkResult) float64 { return r.CostUSD }},
+		{"code_nim_benchmark_errors", "Files the model failed to review in the latest benchmark.", func(r model.BenchmarkResult) float64 { return float64(r.Errors) }},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, r := range run.Results {
+			fmt.Fprintf(b, "%s{candidate=%q} %g\n", g.name, r.Candidate, g.value(r))
+		}
+	}
+	b.WriteString("# HELP code_nim_benchmark_last_run_timestamp_seconds Start of the latest benchmark run.\n# TYPE code_nim_benchmark_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(b, "code_nim_benchmark_last_run_timestamp_seconds %d\n", run.StartedAt.Unix())
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"time"
+)
+
+// Build status the bot owns on each commit.
+const (
+	buildStatusKey  = "code-nim-review"
+	buildStatusName = "code-nim review"
+)
+
+// setBuildStatus posts state on the latest commit of the run's pull request. A failure is
+// logged and counted; the review itself is not affected.
+func (ar *AutoReviewPRHandler) setBuildStatus(run *reviewRun, state, description string) {
+	auto, pr := run.Auto, run.PR
+	status := model.BuildStatus{
+		Key:         buildStatusKey,
+		State:       state,
+		Name:        buildStatusName,
+		URL:         fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d", auto.Workspace, auto.RepoSlug, pr.ID),
+		Description: description,
+	}
+	publishStart := time.Now()
+	err := ar.Bitbucket.SetBuildStatus(auto.Workspace, auto.RepoSlug, run.LatestCommitHash, status, auto.Username, auto.AppPassword)
+	ar.observe(run, "publish", publishStart)
+	if err != nil {
+		log.Errorf("Failed to set build status %s for PR #%d: %v", state, pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return
+	}
+	log.Infof("✓ Build status of PR #%d at %s: %s", pr.ID, shortHash(run.LatestCommitHash), state)
+}
+
+// buildStatusGuard marks the latest commit INPROGRESS before a new commit is reviewed, so a
+// branch restriction requiring passing builds blocks the merge until the review is done.
+func (ar *AutoReviewPRHandler) buildStatusGuard(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		if run.Auto.BuildStatus.Enabled && run.LatestCommitHash != "" && (!run.HasSummary || run.HasNewCommits) {
+			ar.setBuildStatus(run, model.BuildInProgress, "Reviewing the latest changes")
+		}
+		return next.Run(run)
+	})
+}
+
+// buildStatusStage reports the review result on the latest commit: FAILED when an open
+// finding is at least buildStatus.failSeverity, SUCCESSFUL otherwise. When the review could
+// not be completed the status stays INPROGRESS and the next scan retries it.
+func (ar *AutoReviewPRHandler) buildStatusStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.BuildStatus.Enabled || run.LatestCommitHash == "" {
+		return nil
+	}
+	if !run.SummaryPosted && run.InlinePosted == 0 && !run.HasNewCommits {
+		log.Debugf("PR #%d: nothing new reviewed; keeping the current build status", pr.ID)
+		return nil
+	}
+	if run.PostErr != nil {
+		log.Warnf("PR #%d: review incomplete; leaving the build status in progress", pr.ID)
+		return nil
+	}
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Errorf("Error fetching comments for the build status of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+
+	failAt := failSeverity(auto.BuildStatus.FailSeverity)
+	blocking := 0
+	for _, f := range findings {
+		if helper.SeverityRank(f.Severity) >= helper.SeverityRank(failAt) {
+			blocking++
+		}
+	}
+	if blocking > 0 {
+		ar.setBuildStatus(run, model.BuildFailed, fmt.Sprintf("%d open %s at or above %s", blocking, helper.Pluralize(blocking, "finding", "findings"), failAt))
+		return nil
+	}
+	ar.setBuildStatus(run, model.BuildSuccessful, fmt.Sprintf("No open findings at or above %s", failAt))
+	return nil
+}
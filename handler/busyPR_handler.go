@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// defaultBusyPRMinSeverity is the least severe finding still posted on a busy pull request.
+const defaultBusyPRMinSeverity = "Major"
+
+// busyPRMinSeverity returns the severity below which findings are deferred on pr, or "" when
+// the pull request does not have enough human discussion to count as busy.
+func busyPRMinSeverity(auto *model.AutoReviewPR, pr *model.PullRequest, humanComments int) string {
+	if auto.BusyPRHumanComments <= 0 || humanComments < auto.BusyPRHumanComments {
+		return ""
+	}
+	minSeverity := strings.TrimSpace(auto.BusyPRMinSeverity)
+	if minSeverity == "" || !helper.ValidSeverity(minSeverity) {
+		minSeverity = defaultBusyPRMinSeverity
+	}
+	log.Infof("PR #%d is busy (%d human comments, threshold %d); posting only %s and above", pr.ID, humanComments, auto.BusyPRHumanComments, minSeverity)
+	return minSeverity
+}
+
+// fileMinSeverity returns the severity below which findings on filePath are deferred: minSeverity,
+// raised to the minSeverity of the file's package profile and lowered to
+// criticalPathReview.minSeverity (default Minor) for files under criticalPaths.
+func fileMinSeverity(auto *model.AutoReviewPR, minSeverity, filePath string) string {
+	if _, profile := helper.PackageProfileFor(auto, filePath); profile != nil {
+		s := strings.TrimSpace(profile.MinSeverity)
+		if helper.ValidSeverity(s) && (minSeverity == "" || helper.SeverityRank(s) > helper.SeverityRank(minSeverity)) {
+			minSeverity = s
+		}
+	}
+	if minSeverity == "" || !helper.IsCriticalPath(auto.CriticalPaths, filePath) {
+		return minSeverity
+	}
+	critical := strings.TrimSpace(auto.CriticalPathReview.MinSeverity)
+	if critical == "" || !helper.ValidSeverity(critical) {
+		critical = model.DefaultCriticalPathMinSeverity
+	}
+	if helper.SeverityRank(critical) < helper.SeverityRank(minSeverity) {
+		return critical
+	}
+	return minSeverity
+}
+
+// deferFinding keeps a finding less severe than minSeverity out of the pull request and stores
+// it as deferred, so it still shows up in reports. It reports whether the finding was deferred.
+func (ar *AutoReviewPRHandler) deferFinding(auto *model.AutoReviewPR, pr *model.PullRequest, c model.ReviewComment, body, minSeverity string) bool {
+	_, severity, _ := helper.ParseFindingHeading(body)
+	if helper.SeverityRank(severity) >= helper.SeverityRank(minSeverity) {
+		return false
+	}
+	ar.statsMutex.Lock()
+	ar.findingsDeferred++
+	ar.statsMutex.Unlock()
+	if ar.Storage == nil {
+		log.Debugf("Dropping deferred finding at %s:%d in PR #%d: no reports store", c.Path, c.Position, pr.ID)
+		return true
+	}
+	f := newFinding(auto, pr, c, body)
+	f.Deferred = true
+	if _, err := ar.Storage.SaveFinding(f); err != nil {
+		log.Errorf("Failed to store deferred finding for %s:%d in PR #%d: %v", c.Path, c.Position, pr.ID, err)
+	}
+	return true
+}
+
+// writeDeferredMetrics appends the count of findings held back on busy pull requests.
+func (ar *AutoReviewPRHandler) writeDeferredMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_findings_deferred_total Low-severity findings stored instead of posted because the PR had heavy human review activity.\n# TYPE code_nim_findings_deferred_total counter\n")
+	fmt.Fprintf(b, "code_nim_findings_deferred_total %d\n", ar.findingsDeferred)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// unchanged reports whether pr has the same updated_on as at its last complete review by this
+// process. Bitbucket moves updated_on when commits are pushed or the pull request is edited, so
+// such a pull request has nothing new to review. Pull requests without updated_on are never
+// skipped.
+func (ar *AutoReviewPRHandler) unchanged(auto *model.AutoReviewPR, pr *model.PullRequest) bool {
+	if pr.UpdatedOn == "" {
+		return false
+	}
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.reviewedUpdates[entryKey(*auto)][pr.ID] != pr.UpdatedOn {
+		return false
+	}
+	ar.unchangedSkipped++
+	return true
+}
+
+// markReviewed remembers the updated_on of a pull request that was reviewed completely.
+func (ar *AutoReviewPRHandler) markReviewed(auto *model.AutoReviewPR, pr *model.PullRequest) {
+	if pr.UpdatedOn == "" {
+		return
+	}
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.reviewedUpdates == nil {
+		ar.reviewedUpdates = make(map[string]map[int]string)
+	}
+	key := entryKey(*auto)
+	if ar.reviewedUpdates[key] == nil {
+		ar.reviewedUpdates[key] = make(map[int]string)
+	}
+	ar.reviewedUpdates[key][pr.ID] = pr.UpdatedOn
+}
+
+// forgetClosed drops the remembered pull requests of an entry that are not among open.
+func (ar *AutoReviewPRHandler) forgetClosed(auto *model.AutoReviewPR, open []model.PullRequest) {
+	ids := make(map[int]bool, len(open))
+	for _, pr := range open {
+		ids[pr.ID] = true
+	}
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	for id := range ar.reviewedUpdates[entryKey(*auto)] {
+		if !ids[id] {
+			delete(ar.reviewedUpdates[entryKey(*auto)], id)
+		}
+	}
+}
+
+func (ar *AutoReviewPRHandler) writeChangeDetectionMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_pull_requests_unchanged_total Open pull requests skipped because they were not updated since their last review.\n# TYPE code_nim_pull_requests_unchanged_total counter\n")
+	fmt.Fprintf(b, "code_nim_pull_requests_unchanged_total %d\n", ar.unchangedSkipped)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"code_nim/helper/atlassian"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errBitbucket marks errors of a run that came from Bitbucket rather than from the AI provider.
+var errBitbucket = errors.New("bitbucket request failed")
+
+// bitbucketFailure reports whether err, the outcome of a run, is a Bitbucket failure: an error
+// listing the pull requests, marked with errBitbucket, or an unexpected Bitbucket status.
+func bitbucketFailure(err error) bool {
+	var se *atlassian.StatusError
+	return errors.Is(err, errBitbucket) || errors.As(err, &se)
+}
+
+// circuitOpen reports whether the circuit of the job is open, and until when.
+func (ar *AutoReviewPRHandler) circuitOpen(key string) (bool, time.Time) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	js, ok := ar.jobs[key]
+	if !ok || js.CircuitOpenUntil.IsZero() || time.Now().After(js.CircuitOpenUntil) {
+		return false, time.Time{}
+	}
+	return true, js.CircuitOpenUntil
+}
+
+// recordBitbucketResult counts a run that failed on Bitbucket against the job, and opens its
+// circuit once circuitBreaker.failures runs in a row failed. Each further failure after the
+// cool-down keeps it open for twice as long. A run without Bitbucket failure closes it.
+func (ar *AutoReviewPRHandler) recordBitbucketResult(auto model.AutoReviewPR, err error) {
+	key := entryKey(auto)
+	threshold := ar.circuitSettings.Threshold()
+	failed := err != nil && bitbucketFailure(err)
+	var opened time.Time
+	var failures int
+	ar.statsMutex.Lock()
+	if js, ok := ar.jobs[key]; ok {
+		if !failed {
+			if js.BitbucketFailures > 0 && !js.CircuitOpenUntil.IsZero() {
+				log.Infof("Bitbucket answers again for %s; closing its circuit", key)
+			}
+			js.BitbucketFailures, js.CircuitOpenUntil = 0, time.Time{}
+		} else {
+			js.BitbucketFailures++
+			failures = js.BitbucketFailures
+			if threshold > 0 && failures >= threshold {
+				cooldown, _ := ar.circuitSettings.CooldownAfter(failures)
+				js.CircuitOpenUntil = time.Now().Add(cooldown)
+				opened = js.CircuitOpenUntil
+			}
+		}
+	}
+	ar.statsMutex.Unlock()
+	if opened.IsZero() {
+		return
+	}
+
+	message := fmt.Sprintf("%d review runs in a row failed on Bitbucket (last error: %v). Reviews of %s are paused until %s; check the app password and that the repository still exists. Run the job manually to retry now.",
+		failures, err, key, opened.Format(time.RFC3339))
+	log.Errorf("Circuit open for %s: %s", key, message)
+	if ar.Notifier != nil && len(auto.Notifiers) > 0 {
+		ar.Notifier.Send(auto.Notifiers, model.NotificationEvent{
+			Kind:        model.NotifyCircuitOpen,
+			ProcessName: auto.ProcessName,
+			Workspace:   auto.Workspace,
+			RepoSlug:    auto.RepoSlug,
+			URL:         fmt.Sprintf("https://bitbucket.org/%s/%s", auto.Workspace, auto.RepoSlug),
+			Message:     message,
+		})
+	}
+}
+
+// closeCircuit forgets the Bitbucket failures of the job, e.g. when it is run manually.
+func (ar *AutoReviewPRHandler) closeCircuit(key string) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if js, ok := ar.jobs[key]; ok {
+		js.BitbucketFailures, js.CircuitOpenUntil = 0, time.Time{}
+	}
+}
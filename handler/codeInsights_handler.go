@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// insightsReportID is the Code Insights report the bot owns on each commit.
+const insightsReportID = "code-nim-review"
+
+// Code Insights field limits.
+const (
+	maxInsightsAnnotations = 1000
+	maxAnnotationSummary   = 450
+	maxAnnotationDetails   = 2000
+)
+
+// insightsFailSeverity returns the least severe open finding that fails the report.
+func insightsFailSeverity(auto *model.AutoReviewPR) string {
+	return failSeverity(auto.CodeInsights.FailSeverity)
+}
+
+// failSeverity normalises a configured failSeverity, defaulting to Major.
+func failSeverity(setting string) string {
+	s := strings.TrimSpace(setting)
+	if s == "" || !helper.ValidSeverity(s) || strings.EqualFold(s, helper.SeverityNone) {
+		return "Major"
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
+
+// insightsSeverity maps a finding severity to a Code Insights annotation severity. Findings
+// without a known severity are reported as HIGH, in line with helper.SeverityRank.
+func insightsSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return "CRITICAL"
+	case "major":
+		return "HIGH"
+	case "minor":
+		return "MEDIUM"
+	case "trivial", "info":
+		return "LOW"
+	}
+	return "HIGH"
+}
+
+// insightsAnnotationType classifies a finding as a vulnerability, a bug or a code smell.
+func insightsAnnotationType(f model.Finding) string {
+	switch {
+	case strings.Contains(f.Body, "osv.dev/vulnerability/"):
+		return "VULNERABILITY"
+	case strings.EqualFold(f.Type, "Refactor"), strings.EqualFold(f.Type, "Nitpick"):
+		return "CODE_SMELL"
+	}
+	return "BUG"
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// insightsAnnotations converts open findings into annotations with stable external IDs.
+func insightsAnnotations(auto *model.AutoReviewPR, pr *model.PullRequest, findings []model.Finding) []model.InsightsAnnotation {
+	annotations := make([]model.InsightsAnnotation, 0, len(findings))
+	for i, f := range findings {
+		if i == maxInsightsAnnotations {
+			log.Warnf("PR #%d has %d open findings; the Code Insights report lists the first %d", pr.ID, len(findings), maxInsightsAnnotations)
+			break
+		}
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%s", f.Path, f.Line, f.Title)))
+		title := strings.TrimSpace(f.Title)
+		if title == "" {
+			title = "Review finding"
+		}
+		a := model.InsightsAnnotation{
+			ExternalID:     fmt.Sprintf("%s-%d-%s", insightsReportID, i+1, hex.EncodeToString(sum[:6])),
+			AnnotationType: insightsAnnotationType(f),
+			Summary:        truncateRunes(title, maxAnnotationSummary),
+			Details:        truncateRunes(strings.TrimSpace(strings.ReplaceAll(helper.WithoutFindingMarkers(f.Body), reviewBotMarker, "")), maxAnnotationDetails),
+			Path:           f.Path,
+			Severity:       insightsSeverity(f.Severity),
+		}
+		if f.Line > 0 {
+			a.Line = f.Line
+			a.Link = helper.PullRequestLineURL(auto.Workspace, auto.RepoSlug, pr.ID, f.Path, f.Line)
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations
+}
+
+// insightsStage publishes the open findings as a Code Insights report on the latest commit, so
+// they appear in the PR's Reports tab and can gate merges. The report fails when a finding is at
+// least codeInsights.failSeverity, and stays pending when the review could not be completed.
+func (ar *AutoReviewPRHandler) insightsStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.CodeInsights.Enabled || run.LatestCommitHash == "" {
+		return nil
+	}
+	if !run.SummaryPosted && run.InlinePosted == 0 && !run.HasNewCommits {
+		log.Debugf("PR #%d: nothing new reviewed; keeping the current Code Insights report", pr.ID)
+		return nil
+	}
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Errorf("Error fetching comments for the Code Insights report of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+
+	failAt := insightsFailSeverity(auto)
+	highest := helper.HighestSeverity(remainingSeverities(findings))
+	result := model.InsightsPassed
+	details := fmt.Sprintf("No open findings at or above %s.", failAt)
+	switch {
+	case run.PostErr != nil:
+		result = model.InsightsPending
+		details = "The review could not be completed; the next scan retries it."
+	case len(findings) > 0 && helper.SeverityRank(highest) >= helper.SeverityRank(failAt):
+		result = model.InsightsFailed
+		details = fmt.Sprintf("Open findings at or above %s need attention.", failAt)
+	}
+	report := model.InsightsReport{
+		Title:      "Nim AI review",
+		Details:    details,
+		ReportType: "BUG",
+		Reporter:   "code-nim",
+		Link:       fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d", auto.Workspace, auto.RepoSlug, pr.ID),
+		Result:     result,
+		Data: []model.InsightsDatum{
+			{Title: "Open findings", Type: "NUMBER", Value: len(findings)},
+			{Title: "Highest severity", Type: "TEXT", Value: highest},
+			{Title: "Fails at", Type: "TEXT", Value: failAt},
+		},
+	}
+
+	publishStart := time.Now()
+	err = ar.Bitbucket.PublishReport(auto.Workspace, auto.RepoSlug, run.LatestCommitHash, insightsReportID, report, insightsAnnotations(auto, pr, findings), auto.Username, auto.AppPassword)
+	ar.observe(run, "publish", publishStart)
+	if err != nil {
+		log.Errorf("Failed to publish Code Insights report for PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	log.Infof("✓ Published Code Insights report for PR #%d at %s: %s (%d findings)", pr.ID, shortHash(run.LatestCommitHash), result, len(findings))
+	return nil
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/model"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const runMarkerPrefix = "<!-- auto-review-run:"
+
+// newRunID returns a short random ID for a review run, shown in comment footers and job history.
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(b)
+}
+
+// commentFooter renders the footer of a bot comment: the AI model, the run that posted it and
+// a feedback line. It is empty unless commentFooter.enabled is set.
+func commentFooter(auto *model.AutoReviewPR, runID string) string {
+	if !auto.CommentFooter.Enabled {
+		return ""
+	}
+	parts := []string{"Model `" + helper.AIModelName(auto) + "`"}
+	if runID != "" {
+		parts = append(parts, "Run `"+runID+"`")
+	}
+	feedback := strings.TrimSpace(auto.CommentFooter.Feedback)
+	if feedback == "" {
+		feedback = helper.FeedbackLine(auto.Language)
+	}
+	if !strings.EqualFold(feedback, "none") {
+		parts = append(parts, feedback)
+	}
+	footer := "\n\n---\n_" + strings.Join(parts, " · ") + "_"
+	if runID != "" {
+		footer += "\n" + runMarkerPrefix + runID + " -->"
+	}
+	return footer
+}
+
+// withFooter appends the comment footer of auto to body for the run in progress.
+func (ar *AutoReviewPRHandler) withFooter(auto *model.AutoReviewPR, body string) string {
+	return body + commentFooter(auto, ar.runID)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"strings"
+)
+
+// summaryKindMarker marks the summary and consolidated comments of the bot, so later runs find
+// them without reading their text.
+const summaryKindMarker = "<!-- code-nim:summary -->"
+
+// isSummaryComment reports whether the raw text of a general comment is a bot summary (or
+// consolidated comment): it carries summaryKindMarker or, posted before that marker existed,
+// carries the bot marker and opens with the summary title of the entry's language.
+func isSummaryComment(auto *model.AutoReviewPR, raw string) bool {
+	if strings.Contains(raw, summaryKindMarker) {
+		return true
+	}
+	return hasBotMarker(raw) && strings.HasPrefix(strings.TrimSpace(raw), helper.SummaryTitle(auto.Language, ""))
+}
+
+// withFindingMarker returns the body of a finding on line of path followed by its finding marker.
+func withFindingMarker(path string, line int, body string) string {
+	hash := helper.FindingHash(path, line, body)
+	if hash == "" {
+		return body
+	}
+	return body + "\n" + helper.FindingMarker(hash)
+}
+
+// deleteDuplicateFindings deletes the later copies of bot inline findings that filterStage found
+// by their finding marker, line and body, such as a comment posted twice because Bitbucket
+// stored it but the first request timed out. The oldest copy stays.
+func (ar *AutoReviewPRHandler) deleteDuplicateFindings(run *reviewRun) {
+	auto, pr := run.Auto, run.PR
+	deleted := 0
+	for _, id := range run.DuplicateFindings {
+		if err := ar.Bitbucket.DeletePullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, id, auto.Username, auto.AppPassword); err != nil {
+			log.Errorf("Failed to delete duplicate finding comment %d on PR #%d: %v", id, pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Infof("✓ Deleted %d duplicate finding comments on PR #%d", deleted, pr.ID)
+	}
+}
@@ -4,70 +4,317 @@ import (
 	"code_nim/helper"
 	"code_nim/log"
 	"code_nim/model"
+	"code_nim/review"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
-// ensureSummaryComment generates and posts a summary comment if one doesn't already exist.
-// Returns (posted, error). If hasSummaryAlready is true, it only logs and returns (false, nil).
-func (ar *AutoReviewPRHandler) PostSummaryComment(auto *model.AutoReviewPR, pr *model.PullRequest, diff string, lastReviewedHash, latestCommitHash string) (bool, error) {
-
-	log.Infof("No summary found for PR #%d, generating one...", pr.ID)
-	summaryPrompt := helper.CreateSummaryPrompt(pr, diff)
-	summaryText, sumErr := helper.GetAISummary(summaryPrompt, auto)
+// generateSummary asks the AI for the summary of the run's diff, keeps it in run.SummaryText and
+// returns the raw Markdown text. An empty text with a nil error means the AI returned nothing usable.
+func (ar *AutoReviewPRHandler) generateSummary(run *reviewRun, auto *model.AutoReviewPR) (string, error) {
+	pr, diff := run.PR, run.Diff
+	summaryPrompt := helper.CreateSummaryPrompt(pr, diff, auto)
+	summaryText, sumErr := ar.reviewer(auto, run).Summarize(pr, diff)
+	ar.noteProviderResult(auto, sumErr)
 	if sumErr != nil {
 		log.Errorf("AI summary error for PR #%d: %v", pr.ID, sumErr)
-		return false, sumErr
+		ar.noteAIError(run)
+		return "", sumErr
 	}
+	ar.recordTranscript(model.Transcript{
+		Kind:          "summary",
+		ProcessName:   auto.ProcessName,
+		Workspace:     auto.Workspace,
+		RepoSlug:      auto.RepoSlug,
+		PullRequestID: pr.ID,
+		Prompt:        summaryPrompt,
+		Response:      summaryText,
+	})
 
 	trimmed := strings.TrimSpace(summaryText)
 	if trimmed == "" {
 		log.Warnf("AI returned empty summary text for PR #%d", pr.ID)
-		return false, nil
+		return "", nil
 	}
 	log.Debugf("AI summary response length: %d chars (first 100): %s", len(trimmed), trimmed[:min(100, len(trimmed))])
+	run.SummaryText = summaryText
+	return summaryText, nil
+}
 
-	head := "Summary by Nim\n\n"
+// summaryHead returns the title line of a summary comment in the entry's language.
+func summaryHead(auto *model.AutoReviewPR, lastReviewedHash, latestCommitHash string) string {
 	if lastReviewedHash != "" && latestCommitHash != "" && lastReviewedHash != latestCommitHash {
-		head = fmt.Sprintf("Summary by Nim (new commits since %s)\n\n", shortHash(lastReviewedHash))
+		return helper.SummaryTitle(auto.Language, shortHash(lastReviewedHash)) + "\n\n"
 	}
-	marker := reviewBotMarker
+	return helper.SummaryTitle(auto.Language, "") + "\n\n"
+}
+
+// summaryMarker returns the hidden bot and summary markers, including the reviewed commit when
+// known.
+func summaryMarker(latestCommitHash string) string {
+	return strings.Replace(reviewedMarker(latestCommitHash), reviewBotMarker, reviewBotMarker+"\n"+summaryKindMarker, 1)
+}
+
+// reviewedMarker returns the hidden bot marker, including the reviewed commit when known.
+func reviewedMarker(latestCommitHash string) string {
 	if latestCommitHash != "" {
-		marker = fmt.Sprintf("%s\n\n<!-- auto-review-base:%s -->", reviewBotMarker, latestCommitHash)
+		return fmt.Sprintf("%s\n\n<!-- auto-review-base:%s -->", reviewBotMarker, latestCommitHash)
+	}
+	return reviewBotMarker
+}
+
+// postReviewedNote posts, for entries whose mode leaves out the summary, a short note that
+// records commit as reviewed, so the next run reviews only the commits after it.
+func (ar *AutoReviewPRHandler) postReviewedNote(run *reviewRun, auto *model.AutoReviewPR, pr *model.PullRequest, commit string, posting model.PostingResult) error {
+	body := fmt.Sprintf("🔍 Reviewed `%s` inline: %s.\n\n%s", shortHash(commit), helper.Pluralize(posting.Posted, "new finding", "new findings"), reviewedMarker(commit))
+	posted, err := ar.postReviewComment(run, auto, pr, commit, "reviewed", body)
+	if err != nil {
+		log.Errorf("Failed to post the reviewed note of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	if posted {
+		log.Infof("✓ Recorded %s as reviewed on PR #%d", shortHash(commit), pr.ID)
 	}
-	body := head + helper.FormatSummaryBody(summaryText) + "\n\n" + marker
+	return nil
+}
+
+// PostSummaryComment generates and posts the summary comment of the run's diff, rendered with
+// auto. Returns (posted, error). A non-empty welcome is placed between the title and the summary.
+func (ar *AutoReviewPRHandler) PostSummaryComment(run *reviewRun, auto *model.AutoReviewPR, welcome string) (bool, error) {
+	return ar.postSummary(run, auto, welcome, summaryMarker(run.LatestCommitHash))
+}
+
+// postSummary generates the summary of the run's diff and posts it ending in marker.
+func (ar *AutoReviewPRHandler) postSummary(run *reviewRun, auto *model.AutoReviewPR, welcome, marker string) (bool, error) {
+	pr, diff, latestCommitHash := run.PR, run.Diff, run.LatestCommitHash
+	log.Infof("No summary found for PR #%d, generating one...", pr.ID)
+	summaryText, err := ar.generateSummary(run, auto)
+	if err != nil || summaryText == "" {
+		return false, err
+	}
+
+	body := summaryHead(auto, run.LastReviewedHash, latestCommitHash) + welcome + helper.FormatDiffStats(helper.ComputeDiffStats(diff)) + helper.LocalizeSummary(helper.FormatSummaryBody(summaryText), auto.Language) + "\n\n" + contractChangesNote(auto, diff) + helper.LocalizeSummary(run.ReviewersNote, auto.Language) + skippedFilesNote(auto, diff) + autoApprovalNote(auto) + marker
 	log.Debugf("Posting summary comment with body length: %d", len(body))
-	if err := ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, body); err != nil {
+	posted, err := ar.postReviewComment(run, auto, pr, latestCommitHash, "summary", body)
+	if err != nil {
 		log.Errorf("Failed to post summary comment: %v", err)
+		ar.noteJobError(jobErrorAPI)
 		return false, err
 	}
+	if !posted {
+		log.Infof("Summary for PR #%d at %s was already posted; skipping", pr.ID, shortHash(latestCommitHash))
+		return false, nil
+	}
 	log.Infof("✓ Posted summary comment for PR #%d", pr.ID)
 	return true, nil
 }
 
+// skippedFilesNote lists the files of diff left out of inline review, or returns "" when there
+// are none.
+func skippedFilesNote(auto *model.AutoReviewPR, diff string) string {
+	return helper.FormatSkippedFiles(helper.SkippedFiles(auto, helper.ParseDiff(diff)))
+}
+
+// contractChangesNote lists the endpoints and fields changed in the API contract files of diff,
+// or returns "" when no contract changed.
+func contractChangesNote(auto *model.AutoReviewPR, diff string) string {
+	return helper.LocalizeSummary(helper.FormatContractChanges(helper.ContractChangesInDiff(diff)), auto.Language)
+}
+
+// PostConsolidatedComment implements minimal mode: it posts exactly one general comment
+// holding the summary and a findings table instead of separate inline comments. The table is
+// left out when the run is summary-only.
+func (ar *AutoReviewPRHandler) PostConsolidatedComment(run *reviewRun, auto *model.AutoReviewPR, welcome string) (bool, error) {
+	pr, diff, latestCommitHash, skipFindings := run.PR, run.Diff, run.LatestCommitHash, run.SkipInline
+	log.Infof("Generating consolidated review comment for PR #%d (minimal mode)", pr.ID)
+	var summaryText string
+	if auto.PostsSummary() {
+		var err error
+		if summaryText, err = ar.generateSummary(run, auto); err != nil {
+			return false, err
+		}
+	}
+
+	var findings []model.ReviewComment
+	collect := func(c model.ReviewComment, body string) error {
+		c.Body = body
+		findings = append(findings, c)
+		return nil
+	}
+	_, inlineErr := ar.ensureInlineReviewComments(run, auto, pr, diff, map[string]bool{}, map[string]bool{}, skipFindings, false, 0, "", collect)
+	if summaryText == "" && len(findings) == 0 {
+		log.Warnf("Nothing to post for PR #%d in minimal mode", pr.ID)
+		return false, nil
+	}
+
+	detailURLs := ar.storeFindingDetails(auto, pr, findings)
+
+	var b strings.Builder
+	b.WriteString(summaryHead(auto, run.LastReviewedHash, latestCommitHash))
+	b.WriteString(welcome)
+	b.WriteString(helper.FormatDiffStats(helper.ComputeDiffStats(diff)))
+	if summaryText != "" {
+		b.WriteString(helper.LocalizeSummary(helper.FormatSummaryBody(summaryText), auto.Language))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(contractChangesNote(auto, diff))
+	b.WriteString(helper.LocalizeSummary(run.ReviewersNote, auto.Language))
+	b.WriteString(skippedFilesNote(auto, diff))
+	if !skipFindings {
+		b.WriteString("## Findings\n\n")
+		b.WriteString(helper.FormatFindingsTable(auto, pr.ID, findings, detailURLs))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(autoApprovalNote(auto))
+	b.WriteString(summaryMarker(latestCommitHash))
+
+	body := b.String()
+	log.Debugf("Posting consolidated comment with body length: %d (%d findings)", len(body), len(findings))
+	posted, err := ar.postReviewComment(run, auto, pr, latestCommitHash, "consolidated", body)
+	if err != nil {
+		log.Errorf("Failed to post consolidated comment: %v", err)
+		ar.noteJobError(jobErrorAPI)
+		return false, err
+	}
+	if !posted {
+		log.Infof("Consolidated review for PR #%d at %s was already posted; skipping", pr.ID, shortHash(latestCommitHash))
+		return false, nil
+	}
+	log.Infof("✓ Posted consolidated review comment for PR #%d with %d findings", pr.ID, len(findings))
+	return true, inlineErr
+}
+
+// postReviewComment posts a general comment about the review of commit. Without a known commit
+// repeated reviews cannot be told apart, so the comment is always posted.
+func (ar *AutoReviewPRHandler) postReviewComment(run *reviewRun, auto *model.AutoReviewPR, pr *model.PullRequest, commit, subject, body string) (bool, error) {
+	post := func() error {
+		publishStart := time.Now()
+		defer ar.observe(run, "publish", publishStart)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, body))
+	}
+	if commit == "" {
+		err := post()
+		return err == nil, err
+	}
+	return ar.deliverComment(auto, pr, commit, subject, post)
+}
+
+// newFinding converts a rendered review comment into a stored finding.
+func newFinding(auto *model.AutoReviewPR, pr *model.PullRequest, c model.ReviewComment, body string) model.Finding {
+	typ, severity, title := helper.ParseFindingHeading(body)
+	return model.Finding{
+		ProcessName:      auto.ProcessName,
+		Workspace:        auto.Workspace,
+		RepoSlug:         auto.RepoSlug,
+		PullRequestID:    pr.ID,
+		PullRequestTitle: pr.Title,
+		Path:             c.Path,
+		Line:             c.Position,
+		OldLine:          oldLine(c),
+		Type:             typ,
+		Severity:         severity,
+		Title:            title,
+		Body:             body,
+	}
+}
+
+// oldLine returns the source line of a finding on a removed line, or 0.
+func oldLine(c model.ReviewComment) int {
+	if c.Position > 0 || c.FromLine < 0 {
+		return 0
+	}
+	return c.FromLine
+}
+
+// findingLine returns the line content fingerprints of an inline finding are keyed on: its
+// destination line, or the source line of a finding on a removed line.
+func findingLine(to, from int) int {
+	if to <= 0 && from > 0 {
+		return from
+	}
+	return to
+}
+
+// inlineKey identifies the diff line of an inline comment for duplicate detection. Removed
+// lines have no destination line, so they are keyed by their source line.
+func inlineKey(path string, to, from int) string {
+	if to <= 0 && from > 0 {
+		return fmt.Sprintf("%s:-%d", path, from)
+	}
+	return fmt.Sprintf("%s:%d", path, to)
+}
+
+// saveFinding records a posted finding in the review history and returns its ID,
+// or "" when it could not be stored.
+func (ar *AutoReviewPRHandler) saveFinding(auto *model.AutoReviewPR, pr *model.PullRequest, c model.ReviewComment, body string) string {
+	if ar.Storage == nil {
+		return ""
+	}
+	id, err := ar.Storage.SaveFinding(newFinding(auto, pr, c, body))
+	if err != nil {
+		log.Errorf("Failed to store finding for %s:%d in PR #%d: %v", c.Path, c.Position, pr.ID, err)
+		return ""
+	}
+	return id
+}
+
+// storeFindingDetails records the findings of a consolidated comment in the review history and
+// returns the detail page URL of each one. It returns nil when no dashboardUrl is configured.
+func (ar *AutoReviewPRHandler) storeFindingDetails(auto *model.AutoReviewPR, pr *model.PullRequest, findings []model.ReviewComment) []string {
+	base := strings.TrimRight(strings.TrimSpace(ar.DashboardURL), "/")
+	urls := make([]string, len(findings))
+	for i, f := range findings {
+		if id := ar.saveFinding(auto, pr, f, f.Body); id != "" && base != "" {
+			urls[i] = base + "/findings/" + id
+		}
+	}
+	if base == "" {
+		return nil
+	}
+	return urls
+}
+
+// inlineSink receives an inline comment that passed all filters together with its rendered body.
+// It lets callers collect findings instead of posting them to Bitbucket.
+type inlineSink func(c model.ReviewComment, body string) error
+
 // ensureInlineReviewComments generates and posts inline review comments if they don't already exist.
-// Returns (postedCount, error). Skips when skipInline is true or hasInlineAlready is true.
+// It returns how many comments were posted, failed or skipped. Skips when skipInline is true or
+// hasInlineAlready is true. Comments are posted postConcurrency at a time and retried on 5xx.
+// The error wraps errIncompleteReview when files could not be reviewed or findings not posted.
+// When deferBelow is set, findings less severe than it, or on critical files than
+// criticalPathReview.minSeverity, are stored as deferred instead of posted; so are findings
+// below the minSeverity of a file's package profile.
+// When sink is non-nil, comments are handed to it instead of being posted; otherwise posted
+// findings are added to run.PostedFindings and, with a fix, run.Fixes.
 func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
+	run *reviewRun,
 	auto *model.AutoReviewPR,
 	pr *model.PullRequest,
 	diff string,
 	existingInlineComments map[string]bool,
+	existingFingerprints map[string]bool,
 	skipInline bool,
 	hasInlineAlready bool,
 	totalCommentCount int,
-) (int, error) {
+	deferBelow string,
+	sink inlineSink,
+) (model.PostingResult, error) {
 	if skipInline {
-		log.Infof("Skipping inline review for PR #%d due to reviewer presence in displayNames", pr.ID)
-		return 0, nil
+		log.Infof("Skipping inline review for PR #%d: summary only (mode or ignore list)", pr.ID)
+		return model.PostingResult{}, nil
 	}
 	if hasInlineAlready {
 		log.Infof("Inline review already exists for PR #%d, skipping", pr.ID)
-		return 0, nil
+		return model.PostingResult{}, nil
 	}
 
 	log.Infof("No inline review found for PR #%d, generating one...", pr.ID)
-	parsed := ar.Bitbucket.ParseDiff(diff)
+	reviewer := ar.reviewer(auto, run)
+	parsed := reviewer.ParseDiff(diff)
 
 	outOfRange := 0
 	deletedLine := 0
@@ -75,10 +322,18 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 	emptyBody := 0
 	commandBody := 0
 	missingLocation := 0
+	placementRejected := 0
 	duplicateCount := 0
 	aiCount := 0
 	filteredCount := 0
 	postedCount := 0
+	aiErrors := 0
+	postErrors := 0
+	deferred := 0
+	capped := 0
+	retries := 0
+	var abortErr error                   // rate limit or rejected credentials; the remaining files are not reviewed
+	var unanchored []model.ReviewComment // rejected placements, posted together as a fallback
 
 	maxInline := auto.MaxInlineComments
 	if maxInline <= 0 {
@@ -91,12 +346,12 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 	remainingByInline := maxInline - len(existingInlineComments)
 	if remainingByInline <= 0 {
 		log.Infof("Inline review limit reached for PR #%d (max=%d, existing=%d); skipping new comments", pr.ID, maxInline, len(existingInlineComments))
-		return 0, nil
+		return model.PostingResult{}, nil
 	}
 	remainingByTotal := maxTotal - totalCommentCount
 	if remainingByTotal <= 0 {
 		log.Infof("Total comment limit reached for PR #%d (max=%d, total=%d); skipping new comments", pr.ID, maxTotal, totalCommentCount)
-		return 0, nil
+		return model.PostingResult{}, nil
 	}
 	remaining := remainingByInline
 	if remainingByTotal < remaining {
@@ -115,70 +370,90 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 		fileEmptyBody := 0
 		fileCommand := 0
 		fileAiCount := 0
+		filePlacement := 0
 		fileInvalidAI := false
 		fileAIError := false
-		filePath := file["path"].(string)
+		filePath := file.Path
 		log.Debugf("Check File path %s", filePath)
-		hunks := file["hunks"].([]map[string]interface{})
-		allLines, lineMap := helper.BuildDiffSnippetAndLineMap(hunks)
-		if len(allLines) == 0 {
+		fileDeferBelow := fileMinSeverity(auto, deferBelow, filePath)
+		if reason := reviewer.SkipReason(file); reason != "" {
+			log.Infof("Posted 0 inline comments for file %s (skipped: %s)", filePath, reason)
+			continue
+		}
+
+		// Call AI provider (Gemini or self) based on configuration and anchor its findings
+		fileReview, err := reviewer.ReviewFile(pr, filePath, file.Hunks)
+		if errors.Is(err, review.ErrEmptySnippet) {
 			emptySnippet++
 			log.Infof("Posted 0 inline comments for file %s (emptyDiffSnippet)", filePath)
 			continue
 		}
-		prompt := helper.CreatePrompt(filePath, allLines, pr)
-
-		// Call AI provider (Gemini or self) based on configuration
-		comments, err := helper.GetAIResponse(prompt, auto)
+		ar.noteProviderResult(auto, err)
 
 		// Add small delay after AI API call to prevent rate limiting
 		time.Sleep(1 * time.Second)
 
+		if abortsRun(err) {
+			log.Errorf("AI error for file %s in PR #%d: %v; not reviewing the remaining files", filePath, pr.ID, err)
+			ar.noteAIError(run)
+			aiErrors++
+			abortErr = err
+			break
+		}
+		if errors.Is(err, helper.ErrAIInvalidResponse) {
+			// Asked twice already; the file is left without findings but the review goes on
+			log.Errorf("AI returned no usable findings for file %s in PR #%d: %v", filePath, pr.ID, err)
+			ar.noteAIError(run)
+			fileInvalidAI = true
+			log.Infof("Posted 0 inline comments for file %s (invalidAI=true)", filePath)
+			continue
+		}
 		if err != nil {
 			log.Errorf("AI error for file %s in PR #%d: %v", filePath, pr.ID, err)
+			ar.noteAIError(run)
 			fileAIError = true
+			aiErrors++
 			log.Infof("Posted 0 inline comments for file %s (aiError=true)", filePath)
 			continue
 		}
-		fileAiCount = len(comments)
+		ar.recordTranscript(model.Transcript{
+			Kind:          "inline",
+			ProcessName:   auto.ProcessName,
+			Workspace:     auto.Workspace,
+			RepoSlug:      auto.RepoSlug,
+			PullRequestID: pr.ID,
+			Path:          filePath,
+			Prompt:        fileReview.Prompt,
+			Findings:      fileReview.Raw,
+		})
+		fileAiCount = len(fileReview.Raw)
 		aiCount += fileAiCount
 		if fileAiCount == 0 {
 			fileInvalidAI = true
 		}
 
-		for i := range comments {
-			// Use anchor text to correct the index if present
-			if comments[i].Anchor != "" {
-				idx := helper.NearestMatchingLineIndex(allLines, comments[i].Anchor, comments[i].Position-1)
-				if idx >= 0 && idx < len(lineMap) {
-					comments[i].Position = idx + 1
-				}
-			}
-			// Map AI diff index (1-based within provided snippet) to file lines
-			if comments[i].Position <= 0 || comments[i].Position > len(lineMap) {
-				log.Debugf("Skip comment with out-of-range position %d for file %s", comments[i].Position, filePath)
-				comments[i].Position = 0
+		for _, rejected := range fileReview.Rejected {
+			log.Debugf("Reject placement of comment in %s (%s)", filePath, rejected.Reason)
+			unanchored = ar.rejectPlacement(unanchored, rejected.Comment, rejected.Reason)
+			switch rejected.Reason {
+			case helper.PlacementOutOfRange:
 				fileOutOfRange++
 				outOfRange++
-				continue
-			}
-			mapping := lineMap[comments[i].Position-1]
-			if mapping.ToLine <= 0 {
-				// Deleted lines have no destination; skip commenting on them
-				log.Debugf("Skip comment on deleted line (no destination) at diff idx %d for file %s", comments[i].Position, filePath)
-				comments[i].Position = 0
+			case helper.PlacementDeletedLine:
 				fileDeleted++
 				deletedLine++
-				continue
+			default:
+				filePlacement++
+				placementRejected++
 			}
-			comments[i].Path = filePath
-			comments[i].Position = mapping.ToLine   // destination/new file line
-			comments[i].FromLine = mapping.FromLine // source/old file line (-1 for added lines)
 		}
+		comments := fileReview.Placed
 
-		for _, c := range comments {
-			if postedCount >= remaining {
+		var batch []*inlinePost
+		for i, c := range comments {
+			if postedCount+len(batch) >= remaining {
 				log.Infof("Reached comment cap for PR #%d (inlineMax=%d, totalMax=%d); stopping", pr.ID, maxInline, maxTotal)
+				capped += len(comments) - i
 				break
 			}
 			if c.Body == "" {
@@ -192,12 +467,12 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 				continue
 			}
 			filteredCount++
-			if c.Path == "" || c.Position <= 0 {
+			if c.Path == "" || c.Position <= 0 && c.FromLine <= 0 {
 				fileMissing++
 				missingLocation++
 				continue
 			}
-			key := fmt.Sprintf("%s:%d", c.Path, c.Position)
+			key := inlineKey(c.Path, c.Position, c.FromLine)
 			if existingInlineComments[key] {
 				log.Debugf("Skipping duplicate inline comment at %s", key)
 				fileDup++
@@ -205,42 +480,76 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 				continue
 			}
 
-			formattedBody := helper.FormatReviewBody(c.Body)
+			formattedBody := helper.AdaptCommentBody(helper.LocalizeFinding(helper.FormatReviewBodyForTone(c.Body, helper.ReviewStyle(helper.PackageConfig(auto, c.Path))), auto.Language), ar.Bitbucket.Capabilities())
+			// Content-based dedup: the same finding may come back on a shifted line
+			line := findingLine(c.Position, c.FromLine)
+			fingerprints := helper.FindingFingerprints(c.Path, line, formattedBody)
+			lookup := append(helper.NearbyFingerprints(c.Path, line, formattedBody), helper.FindingHash(c.Path, line, formattedBody))
+			if helper.HasFingerprint(existingFingerprints, lookup) {
+				log.Debugf("Skipping near-duplicate inline comment at %s (content already posted)", key)
+				fileDup++
+				duplicateCount++
+				continue
+			}
 			if !strings.Contains(formattedBody, reviewBotMarker) {
 				formattedBody = formattedBody + "\n\n" + reviewBotMarker
 			}
-			// Convert FromLine: -1 means added line (no source), use 0 for API
-			fromLineForAPI := c.FromLine
-			if fromLineForAPI < 0 {
-				fromLineForAPI = 0
+			for _, fp := range fingerprints {
+				existingFingerprints[fp] = true
 			}
-			err := ar.Bitbucket.PushPullRequestInlineComment(
-				pr.ID,
-				auto.Workspace,
-				auto.RepoSlug,
-				auto.Username,
-				auto.AppPassword,
-				c.Path,
-				fromLineForAPI, // from line in old/source file
-				c.Position,     // to line in new/destination file
-				formattedBody,
-			)
-			if err != nil {
-				log.Errorf("Failed to post inline comment: %v", err)
-			} else {
-				log.Debugf("✓ Posted inline comment on %s at line %d (from=%d, to=%d)", c.Path, c.Position, fromLineForAPI, c.Position)
-				postedCount++
-				filePosted++
-				existingInlineComments[key] = true
+			if fileDeferBelow != "" && ar.deferFinding(auto, pr, c, formattedBody, fileDeferBelow) {
+				deferred++
+				continue
+			}
+			// Claimed now so a later finding of this file on the same line is a duplicate;
+			// released again if the post fails.
+			existingInlineComments[key] = true
+			batch = append(batch, &inlinePost{comment: c, key: key, body: formattedBody, fingerprints: fingerprints})
+		}
+
+		if sink != nil {
+			// Collected findings keep the order of the review
+			ar.postInlineBatch(batch, 1, func(p *inlinePost) (bool, error) { return true, sink(p.comment, p.body) })
+		} else {
+			ar.postInlineBatch(batch, postConcurrency(auto), func(p *inlinePost) (bool, error) { return ar.sendInline(run, auto, pr, p) })
+		}
+		for _, p := range batch {
+			retries += p.retries
+			if p.err != nil {
+				log.Errorf("Failed to post inline comment at %s: %v", p.key, p.err)
+				ar.noteJobError(jobErrorAPI)
+				postErrors++
+				delete(existingInlineComments, p.key)
+				for _, fp := range p.fingerprints {
+					delete(existingFingerprints, fp)
+				}
+				continue
+			}
+			if !p.posted {
+				log.Debugf("Skipping inline comment at %s: already posted in an earlier run", p.key)
+				fileDup++
+				duplicateCount++
+				continue
+			}
+			log.Debugf("✓ Posted inline comment on %s (from=%d, to=%d)", p.comment.Path, p.comment.FromLine, p.comment.Position)
+			postedCount++
+			filePosted++
+			if sink == nil {
+				ar.saveFinding(auto, pr, p.comment, p.body)
+				run.PostedFindings = append(run.PostedFindings, p.comment)
+				if p.comment.Fix != "" {
+					run.Fixes = append(run.Fixes, p.comment)
+				}
 			}
 		}
 		if filePosted == 0 && (fileAiCount > 0 || fileInvalidAI || fileAIError) {
-			log.Infof("Posted 0 inline comments for file %s (ai=%d, dup=%d, deleted=%d, outOfRange=%d, missingLocation=%d, empty=%d, command=%d, invalidAI=%t, aiError=%t)",
+			log.Infof("Posted 0 inline comments for file %s (ai=%d, dup=%d, deleted=%d, outOfRange=%d, placement=%d, missingLocation=%d, empty=%d, command=%d, invalidAI=%t, aiError=%t)",
 				filePath,
 				fileAiCount,
 				fileDup,
 				fileDeleted,
 				fileOutOfRange,
+				filePlacement,
 				fileMissing,
 				fileEmptyBody,
 				fileCommand,
@@ -249,10 +558,16 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 			)
 		}
 	}
+	if len(unanchored) > 0 && postedCount < remaining {
+		postedCount += ar.postUnanchoredFindings(run, auto, pr, unanchored, existingFingerprints, sink)
+	}
+	if deferred > 0 {
+		log.Infof("Deferred %d low-severity findings on busy PR #%d to the reports store", deferred, pr.ID)
+	}
 	if postedCount > 0 {
 		log.Infof("✓ Posted %d inline review comments for PR #%d", postedCount, pr.ID)
 	} else {
-		log.Infof("No inline comments posted for PR #%d (ai=%d, filtered=%d, empty=%d, command=%d, outOfRange=%d, deleted=%d, missingLocation=%d, dup=%d, emptySnippet=%d)",
+		log.Infof("No inline comments posted for PR #%d (ai=%d, filtered=%d, empty=%d, command=%d, outOfRange=%d, deleted=%d, placement=%d, missingLocation=%d, dup=%d, emptySnippet=%d)",
 			pr.ID,
 			aiCount,
 			filteredCount,
@@ -260,10 +575,132 @@ func (ar *AutoReviewPRHandler) ensureInlineReviewComments(
 			commandBody,
 			outOfRange,
 			deletedLine,
+			placementRejected,
 			missingLocation,
 			duplicateCount,
 			emptySnippet,
 		)
 	}
-	return postedCount, nil
+	result := model.PostingResult{
+		Posted:  postedCount,
+		Failed:  postErrors,
+		Skipped: emptyBody + commandBody + missingLocation + duplicateCount + deferred + capped,
+		Retries: retries,
+	}
+	if sink == nil {
+		log.Infof("Inline posting for PR #%d: posted=%d failed=%d skipped=%d retries=%d", pr.ID, result.Posted, result.Failed, result.Skipped, result.Retries)
+		ar.recordPosting(result)
+	}
+	if abortErr != nil {
+		return result, fmt.Errorf("%w: %w", errIncompleteReview, abortErr)
+	}
+	if aiErrors > 0 || postErrors > 0 {
+		return result, fmt.Errorf("%w: %d files failed AI review, %d comments failed to post", errIncompleteReview, aiErrors, postErrors)
+	}
+	return result, nil
+}
+
+const unanchoredMarker = "<!-- auto-review-unanchored -->"
+const unanchoredSeparator = "\n---\n"
+
+// rejectPlacement counts a rejected placement and moves the comment into the unanchored bucket.
+func (ar *AutoReviewPRHandler) rejectPlacement(bucket []model.ReviewComment, c model.ReviewComment, reason string) []model.ReviewComment {
+	ar.statsMutex.Lock()
+	if ar.placementRejected == nil {
+		ar.placementRejected = map[string]int64{}
+	}
+	ar.placementRejected[reason]++
+	ar.statsMutex.Unlock()
+	return append(bucket, c)
+}
+
+// postUnanchoredFindings reports findings whose placement was rejected in one general comment
+// (or hands them to sink without a line) so they are not silently lost. It returns how many
+// findings were reported.
+func (ar *AutoReviewPRHandler) postUnanchoredFindings(run *reviewRun, auto *model.AutoReviewPR, pr *model.PullRequest, findings []model.ReviewComment, existingFingerprints map[string]bool, sink inlineSink) int {
+	var b strings.Builder
+	b.WriteString("## Findings without a diff line\n\nThese findings could not be placed on a changed line, so they are listed here.\n\n")
+	b.WriteString(unanchoredMarker)
+	reported := 0
+	var reportedFindings []model.ReviewComment
+	for _, c := range findings {
+		if c.Body == "" || helper.LooksLikeCommand(c.Body) {
+			continue
+		}
+		body := helper.AdaptCommentBody(helper.LocalizeFinding(helper.FormatReviewBodyForTone(c.Body, helper.ReviewStyle(helper.PackageConfig(auto, c.Path))), auto.Language), ar.Bitbucket.Capabilities())
+		fingerprints := helper.FindingFingerprints(c.Path, 0, body)
+		if helper.HasFingerprint(existingFingerprints, append(helper.NearbyFingerprints(c.Path, 0, body), helper.FindingHash(c.Path, 0, body))) {
+			continue
+		}
+		for _, fp := range fingerprints {
+			existingFingerprints[fp] = true
+		}
+		if sink != nil {
+			if err := sink(c, body); err == nil {
+				reported++
+			}
+			continue
+		}
+		b.WriteString(unanchoredSeparator)
+		fmt.Fprintf(&b, "\n**`%s`**\n\n%s\n", c.Path, withFindingMarker(c.Path, 0, body))
+		reported++
+		c.Body = body
+		reportedFindings = append(reportedFindings, c)
+	}
+	if sink != nil || reported == 0 {
+		return reported
+	}
+	b.WriteString("\n" + reviewBotMarker)
+
+	var subject strings.Builder
+	subject.WriteString("unanchored")
+	for _, c := range reportedFindings {
+		if fps := helper.FindingFingerprints(c.Path, 0, c.Body); len(fps) > 0 {
+			subject.WriteString(":" + fps[0])
+		}
+	}
+	posted, err := ar.deliverComment(auto, pr, "", subject.String(), func() error {
+		publishStart := time.Now()
+		defer ar.observe(run, "publish", publishStart)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, b.String()))
+	})
+	if err != nil {
+		log.Errorf("Failed to post unanchored findings for PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return 0
+	}
+	if !posted {
+		log.Infof("Unanchored findings for PR #%d were already posted; skipping", pr.ID)
+		return 0
+	}
+	for _, c := range reportedFindings {
+		ar.saveFinding(auto, pr, c, c.Body)
+	}
+	log.Infof("✓ Posted %d unanchored findings for PR #%d in a general comment", reported, pr.ID)
+	return reported
+}
+
+// unanchoredFingerprints returns the finding marker hashes and the fingerprints of the findings
+// listed in an unanchored findings comment, so they are not reported again on the next run.
+func unanchoredFingerprints(raw string) []string {
+	if !strings.Contains(raw, unanchoredMarker) {
+		return nil
+	}
+	var out []string
+	for _, section := range strings.Split(raw, unanchoredSeparator)[1:] {
+		section = strings.TrimSpace(section)
+		head, body, _ := strings.Cut(section, "\n")
+		path := unanchoredPath(head)
+		if path == "" {
+			continue
+		}
+		out = append(out, helper.FindingMarkers(body)...)
+		out = append(out, helper.FindingFingerprints(path, 0, body)...)
+	}
+	return out
+}
+
+// unanchoredPath returns the file path from the "**`path`**" heading of an unanchored finding.
+func unanchoredPath(head string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(head), "**`"), "`**")
 }
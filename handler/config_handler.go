@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/sops"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxConfigBody caps the size of a config posted for validation.
+const maxConfigBody = 1 << 20
+
+type ConfigHandler struct{}
+
+// ValidateConfig handles POST /api/v1/config/validate. It lints the YAML config in the request
+// body, or the running service's config file when the body is empty, and answers with the
+// warnings found. Unparseable YAML is answered with 400, and so is a SOPS-encrypted body, which
+// the service does not decrypt for callers.
+func (ch *ConfigHandler) ValidateConfig(c echo.Context) error {
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxConfigBody+1))
+	if err != nil || len(data) > maxConfigBody {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Config must be at most %d bytes", maxConfigBody),
+		})
+	}
+	if len(data) == 0 {
+		if data, err = helper.ReadConfigFile(); err != nil {
+			log.Errorf("Failed to read config for validation: %v", err)
+			return c.JSON(http.StatusInternalServerError, model.Response{
+				StatusCode: http.StatusInternalServerError,
+				Message:    "Failed to read the config file",
+			})
+		}
+	} else if sops.IsEncrypted(data) {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "SOPS-encrypted configs are only validated from the config file; post the decrypted YAML or an empty body",
+		})
+	}
+
+	warnings, err := helper.LintConfig(data)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Invalid YAML: %v", err),
+		})
+	}
+	if warnings == nil {
+		warnings = []model.ConfigWarning{}
+	}
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    fmt.Sprintf("%d warning(s)", len(warnings)),
+		Data:       warnings,
+	})
+}
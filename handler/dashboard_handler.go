@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// dashboardFindings is how many of the most recent findings the dashboard lists.
+const dashboardFindings = 50
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return time.Since(t).Round(time.Second).String() + " ago"
+	},
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+	"round":   func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>code-nim dashboard</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 1200px; margin: 2em auto; padding: 0 1em; color: #172b4d; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #dfe1e6; vertical-align: top; }
+th { background: #f4f5f7; }
+.meta { color: #5e6c84; }
+.badge { display: inline-block; padding: 2px 8px; border-radius: 3px; background: #dfe1e6; }
+.ok { background: #e3fcef; } .error { background: #ffebe6; } .paused { background: #fffae6; }
+button { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>code-nim</h1>
+<p class="meta">{{if .Replica}}Replica {{.Replica}}: {{if .Leader}}<span class="badge ok">leader</span>{{else}}<span class="badge paused">standby</span> (scheduled reviews run on the leader){{end}} · {{end}}{{if .QueueEnabled}}Queue: {{.QueueDepth}} waiting · {{end}}{{if .BudgetReason}}<span class="badge error">AI budget: {{.BudgetReason}}</span>{{else}}AI budget OK{{end}}</p>
+
+<h2>Jobs</h2>
+<table>
+<tr><th>Job</th><th>Schedule</th><th>Last run</th><th>Duration</th><th>Result</th><th>Runs</th><th>PRs reviewed</th><th>Findings</th><th>AI errors</th><th>API errors</th><th></th></tr>
+{{range .Jobs}}
+<tr>
+<td><strong>{{.Name}}</strong><br><span class="meta">{{.Workspace}}/{{.RepoSlug}}</span></td>
+<td><code>{{.Cron}}</code>{{if .Paused}} <span class="badge paused">paused</span>{{else}}<br><span class="meta">next {{.NextRunAt.Format "2006-01-02 15:04:05"}}</span>{{end}}{{if .Frozen}}<br><span class="badge paused">frozen: {{.Frozen}} until {{.FrozenUntil.Format "2006-01-02 15:04"}}</span>{{end}}</td>
+<td>{{if .Running}}<span class="badge">running</span>{{else}}{{ago .LastRunAt}}{{end}}</td>
+<td>{{round .LastDuration}}</td>
+<td>{{if .LastResult}}<span class="badge {{.LastResult}}" title="{{.LastError}}">{{.LastResult}}</span>{{end}}</td>
+<td>{{.Runs}}</td>
+<td>{{.PRsReviewed}}</td>
+<td>{{.FindingsPosted}}</td>
+<td>{{.AIErrors}} / {{.AICalls}} ({{percent .AIErrorRate}})</td>
+<td>{{.APIErrors}}</td>
+<td>
+<button onclick="jobAction('{{.Name}}', 'trigger')">Run now</button>
+{{if .Paused}}<button onclick="jobAction('{{.Name}}', 'resume')">Resume</button>{{else}}<button onclick="jobAction('{{.Name}}', 'pause')">Pause</button>{{end}}
+<button onclick="reschedule('{{.Name}}', '{{.Cron}}')">Schedule</button>
+</td>
+</tr>
+{{else}}
+<tr><td colspan="11" class="meta">No jobs configured.</td></tr>
+{{end}}
+</table>
+
+<h2>Recent findings</h2>
+<table>
+<tr><th>When</th><th>Repository</th><th>PR</th><th>Location</th><th>Severity</th><th>Finding</th></tr>
+{{range .Findings}}
+<tr>
+<td class="meta">{{.CreatedAt.Format "2006-01-02 15:04"}}</td>
+<td>{{.Workspace}}/{{.RepoSlug}}</td>
+<td>#{{.PullRequestID}} {{.PullRequestTitle}}</td>
+<td><code>{{.Path}}{{if .Line}}:{{.Line}}{{end}}</code></td>
+<td>{{.Severity}}{{if .Deferred}} (deferred){{end}}</td>
+<td><a href="findings/{{.ID}}">{{.Title}}</a></td>
+</tr>
+{{else}}
+<tr><td colspan="6" class="meta">No findings in the last 7 days.</td></tr>
+{{end}}
+</table>
+
+<script>
+function jobAction(name, action) {
+  fetch("api/v1/jobs/" + encodeURIComponent(name) + "/" + action, { method: "POST" })
+    .then(function (r) { return r.json(); })
+    .then(function (body) { alert(body.message); location.reload(); })
+    .catch(function (err) { alert(err); });
+}
+function reschedule(name, current) {
+  var cron = prompt("Cron with seconds for " + name, current);
+  if (!cron) { return; }
+  fetch("api/v1/jobs/" + encodeURIComponent(name), {
+    method: "PATCH",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ cron: cron })
+  })
+    .then(function (r) { return r.json(); })
+    .then(function (body) { alert(body.message); location.reload(); })
+    .catch(function (err) { alert(err); });
+}
+</script>
+</body>
+</html>
+`))
+
+// Dashboard handles GET /dashboard and renders job status and recent review history.
+func (ar *AutoReviewPRHandler) Dashboard(c echo.Context) error {
+	data := struct {
+		Jobs         []model.JobStatus
+		Findings     []model.Finding
+		QueueEnabled bool
+		QueueDepth   int
+		BudgetReason string
+		Replica      string
+		Leader       bool
+	}{Jobs: ar.jobStatuses(), Replica: ar.Leader.Identity(), Leader: ar.Leader.IsLeader()}
+
+	if ar.Queue != nil {
+		st := ar.Queue.Stats()
+		data.QueueEnabled = true
+		data.QueueDepth = st.DepthHigh + st.DepthLow
+	}
+	if exceeded, reason := ar.budgetExceeded(); exceeded {
+		data.BudgetReason = reason
+	}
+	if ar.Storage != nil {
+		findings, err := ar.Storage.ListFindings(model.FindingFilter{From: time.Now().AddDate(0, 0, -7)})
+		if err != nil {
+			log.Errorf("Failed to load recent findings for the dashboard: %v", err)
+		}
+		// Newest first
+		for i := len(findings) - 1; i >= 0 && len(data.Findings) < dashboardFindings; i-- {
+			data.Findings = append(data.Findings, findings[i])
+		}
+	}
+
+	var b strings.Builder
+	if err := dashboardTemplate.Execute(&b, data); err != nil {
+		log.Errorf("Failed to render dashboard: %v", err)
+		return c.String(http.StatusInternalServerError, "failed to render dashboard")
+	}
+	return c.HTML(http.StatusOK, b.String())
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"strings"
+	"time"
+)
+
+// describeStage writes the description of a pull request opened with an empty or one-line
+// description when describePR is enabled. The generated description spans several lines, so a
+// pull request is described at most once and later edits by the author are never overwritten.
+// A failure is logged; the review itself is not affected.
+func (ar *AutoReviewPRHandler) describeStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.DescribePR.Enabled || !helper.NeedsDescription(pr.Description) {
+		return nil
+	}
+	diff := run.Diff
+	if run.UseDeltaDiff {
+		// The description covers the whole pull request, not only the commits since the last review.
+		full, err := ar.Bitbucket.FetchPullRequestDiff(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching full diff to describe PR #%d: %v", pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			return nil
+		}
+		diff = full
+	}
+
+	log.Infof("PR #%d has no description, generating one...", pr.ID)
+	generated, err := ar.reviewer(auto, run).Describe(pr, diff)
+	ar.noteProviderResult(auto, err)
+	if err != nil {
+		log.Errorf("AI description error for PR #%d: %v", pr.ID, err)
+		ar.noteAIError(run)
+		return nil
+	}
+	ar.recordTranscript(model.Transcript{
+		Kind:          "description",
+		ProcessName:   auto.ProcessName,
+		Workspace:     auto.Workspace,
+		RepoSlug:      auto.RepoSlug,
+		PullRequestID: pr.ID,
+		Prompt:        helper.CreateDescriptionPrompt(pr, diff, auto),
+		Response:      generated,
+	})
+	if !strings.Contains(generated, "\n") {
+		log.Warnf("AI returned no usable description for PR #%d", pr.ID)
+		return nil
+	}
+
+	description := helper.ComposeDescription(pr.Description, generated)
+	publishStart := time.Now()
+	err = ar.Bitbucket.UpdatePullRequestDescription(pr.ID, auto.Workspace, auto.RepoSlug, description, auto.Username, auto.AppPassword)
+	ar.observe(run, "publish", publishStart)
+	if err != nil {
+		log.Errorf("Failed to update the description of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	pr.Description = description
+	log.Infof("✓ Wrote description of PR #%d", pr.ID)
+	return nil
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/notify"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// digestPeriod is how far back the first digest after a start looks for findings.
+const digestPeriod = 24 * time.Hour
+
+// scheduleDigest registers the digest job of a team on the review scheduler.
+func (ar *AutoReviewPRHandler) scheduleDigest(d model.DigestSettings) error {
+	_, err := ar.scheduler.NewJob(
+		gocron.CronJob(d.Schedule(), true),
+		gocron.NewTask(ar.safeTask("digest of "+d.Team, func() { ar.sendDigest(d) })),
+	)
+	return err
+}
+
+// digestCovers reports whether the entry key belongs to the team of d. Entries expanded from
+// several repositories are covered by the processName they were configured with.
+func digestCovers(d model.DigestSettings, key string) bool {
+	if len(d.Entries) == 0 {
+		return true
+	}
+	for _, name := range d.Entries {
+		if strings.EqualFold(key, name) || strings.HasPrefix(strings.ToLower(key), strings.ToLower(name)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sendDigest mails the team of d what the reviews of its entries did since the previous digest
+// (or the last day, for the first one): pull requests reviewed, the most severe new findings,
+// the unresolved critical findings on open pull requests, and AI and API error counts. It runs
+// only on the replica that schedules reviews, so each team gets one mail.
+func (ar *AutoReviewPRHandler) sendDigest(d model.DigestSettings) {
+	if !ar.queueSettings.Schedules() || !ar.Leader.IsLeader() {
+		log.Debugf("Skipping the review digest of %s: this replica does not schedule reviews", d.Team)
+		return
+	}
+	now := time.Now()
+	ar.statsMutex.Lock()
+	since, ok := ar.digestSentAt[d.Team]
+	baseline := ar.digestBaselines[d.Team]
+	counters := map[string]model.JobStatus{}
+	for key, js := range ar.jobs {
+		if digestCovers(d, key) {
+			counters[key] = *js
+		}
+	}
+	ar.statsMutex.Unlock()
+	if !ok {
+		since = now.Add(-digestPeriod)
+	}
+
+	report := helper.DigestReport{Team: d.Team, Since: since, Until: now}
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Counters run since the start, so the first digest reports everything since then
+		js, prev := counters[key], baseline[key]
+		if js.Runs < prev.Runs {
+			prev = model.JobStatus{}
+		}
+		report.Activity = append(report.Activity, helper.DigestActivity{
+			Name:           key,
+			PRsReviewed:    js.PRsReviewed - prev.PRsReviewed,
+			FindingsPosted: js.FindingsPosted - prev.FindingsPosted,
+			AIErrors:       js.AIErrors - prev.AIErrors,
+			APIErrors:      js.APIErrors - prev.APIErrors,
+		})
+	}
+
+	entries := ar.entrySnapshot()
+	var findings []model.Finding
+	for _, key := range keys {
+		auto, ok := entries[key]
+		if !ok {
+			continue
+		}
+		if ar.Storage != nil {
+			stored, err := ar.Storage.ListFindings(model.FindingFilter{Workspace: auto.Workspace, RepoSlug: auto.RepoSlug, From: since, To: now})
+			if err != nil {
+				log.Errorf("Error loading the findings of %s for the digest of %s: %v", key, d.Team, err)
+			}
+			findings = append(findings, stored...)
+		}
+		if auto.IsGerrit() || auto.IsGitHub() {
+			continue
+		}
+		critical, err := ar.openCriticalFindings(auto)
+		if err != nil {
+			log.Errorf("Error reading the open pull requests of %s for the digest of %s: %v", key, d.Team, err)
+			report.Incomplete = append(report.Incomplete, key)
+		}
+		report.Critical = append(report.Critical, critical...)
+	}
+	report.TopFindings = helper.TopFindings(findings, d.TopFindingsLimit())
+
+	subject := fmt.Sprintf("[code-nim] Review digest for %s: %d PRs reviewed, %d unresolved critical", d.Team, digestReviewed(report), len(report.Critical))
+	if err := notify.SendMail(d.SMTP, subject, helper.FormatDigest(report)); err != nil {
+		log.Errorf("Failed to send the review digest of %s: %v", d.Team, err)
+		return
+	}
+	log.Infof("Sent the review digest of %s to %s", d.Team, strings.Join(d.SMTP.To, ", "))
+	ar.statsMutex.Lock()
+	ar.digestSentAt[d.Team] = now
+	ar.digestBaselines[d.Team] = counters
+	ar.statsMutex.Unlock()
+}
+
+// openCriticalFindings returns the unresolved critical bot findings on the open pull requests
+// of auto.
+func (ar *AutoReviewPRHandler) openCriticalFindings(auto model.AutoReviewPR) ([]model.Finding, error) {
+	prs, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
+	if err != nil {
+		return nil, err
+	}
+	critical := helper.SeverityRank("Critical")
+	var out []model.Finding
+	for _, pr := range prs {
+		comments, err := ar.Bitbucket.FetchPullRequestComments(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+		if err != nil {
+			return out, err
+		}
+		for _, f := range openFindings(comments) {
+			if !helper.ValidSeverity(f.Severity) || helper.SeverityRank(f.Severity) < critical {
+				continue
+			}
+			f.Workspace, f.RepoSlug = auto.Workspace, auto.RepoSlug
+			f.PullRequestID, f.PullRequestTitle = pr.ID, pr.Title
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+func digestReviewed(r helper.DigestReport) int64 {
+	var n int64
+	for _, a := range r.Activity {
+		n += a.PRsReviewed
+	}
+	return n
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// maxFeedbackText bounds the reply text kept with a feedback record.
+const maxFeedbackText = 500
+
+// feedbackWindowDays is the period summarised by the feedback metrics.
+const feedbackWindowDays = 30
+
+// Defaults of adaptivePrompt.minDismissals and adaptivePrompt.windowDays.
+const (
+	defaultMinDismissals        = 3
+	defaultPreferenceWindowDays = 90
+)
+
+// feedbackCount counts the feedback on one kind of finding.
+type feedbackCount struct {
+	Key       string `json:"key"`
+	Helpful   int    `json:"helpful"`
+	Unhelpful int    `json:"unhelpful"`
+}
+
+// feedbackReport summarises the feedback on the comments of one repository. Precision is the
+// share of helpful verdicts.
+type feedbackReport struct {
+	Workspace  string          `json:"workspace"`
+	RepoSlug   string          `json:"repoSlug"`
+	Helpful    int             `json:"helpful"`
+	Unhelpful  int             `json:"unhelpful"`
+	Precision  float64         `json:"precision"`
+	ByCategory []feedbackCount `json:"byCategory"`
+}
+
+// summarizeFeedback aggregates feedback per repository, most feedback first.
+func summarizeFeedback(list []model.Feedback) []feedbackReport {
+	repos := map[string]*feedbackReport{}
+	categories := map[string]map[string]*feedbackCount{}
+	for _, fb := range list {
+		key := fb.Workspace + "/" + fb.RepoSlug
+		r, ok := repos[key]
+		if !ok {
+			r = &feedbackReport{Workspace: fb.Workspace, RepoSlug: fb.RepoSlug}
+			repos[key] = r
+			categories[key] = map[string]*feedbackCount{}
+		}
+		category := findingCategory(model.Finding{Type: fb.Type})
+		if fb.Path == "" && fb.Type == "" {
+			category = "comment"
+		}
+		c, ok := categories[key][category]
+		if !ok {
+			c = &feedbackCount{Key: category}
+			categories[key][category] = c
+		}
+		if fb.Verdict == model.FeedbackHelpful {
+			r.Helpful++
+			c.Helpful++
+		} else {
+			r.Unhelpful++
+			c.Unhelpful++
+		}
+	}
+	out := make([]feedbackReport, 0, len(repos))
+	for key, r := range repos {
+		r.Precision = float64(r.Helpful) / float64(r.Helpful+r.Unhelpful)
+		for _, c := range categories[key] {
+			r.ByCategory = append(r.ByCategory, *c)
+		}
+		sort.Slice(r.ByCategory, func(i, j int) bool {
+			a, b := r.ByCategory[i], r.ByCategory[j]
+			if a.Helpful+a.Unhelpful != b.Helpful+b.Unhelpful {
+				return a.Helpful+a.Unhelpful > b.Helpful+b.Unhelpful
+			}
+			return a.Key < b.Key
+		})
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Helpful+out[i].Unhelpful != out[j].Helpful+out[j].Unhelpful {
+			return out[i].Helpful+out[i].Unhelpful > out[j].Helpful+out[j].Unhelpful
+		}
+		return out[i].Workspace+"/"+out[i].RepoSlug < out[j].Workspace+"/"+out[j].RepoSlug
+	})
+	return out
+}
+
+// scheduleFeedback registers the feedback collection job of an entry on the review scheduler.
+func (ar *AutoReviewPRHandler) scheduleFeedback(auto model.AutoReviewPR) error {
+	_, err := ar.scheduler.NewJob(
+		gocron.CronJob(auto.Feedback.Cron, true),
+		gocron.NewTask(ar.safeTask("feedback of "+entryKey(auto), func() { ar.collectFeedback(ar.current(auto)) })),
+	)
+	return err
+}
+
+// collectFeedback stores the replies to bot comments on the entry's open pull requests that
+// read as helpful or unhelpful, once per reply, and refreshes the feedback metrics. It runs
+// only on the replica that schedules reviews so replies are not recorded twice.
+func (ar *AutoReviewPRHandler) collectFeedback(auto model.AutoReviewPR) {
+	key := entryKey(auto)
+	if ar.Storage == nil {
+		return
+	}
+	if !ar.queueSettings.Schedules() || !ar.Leader.IsLeader() {
+		log.Debugf("Skipping feedback collection for %s: this replica does not schedule reviews", key)
+		return
+	}
+	stored, err := ar.Storage.ListFeedback(model.FeedbackFilter{Workspace: auto.Workspace, RepoSlug: auto.RepoSlug})
+	if err != nil {
+		log.Errorf("Error loading feedback of %s: %v", key, err)
+		return
+	}
+	seen := make(map[string]bool, len(stored))
+	for _, fb := range stored {
+		seen[fmt.Sprintf("%d:%d", fb.PullRequestID, fb.ReplyID)] = true
+	}
+
+	prs, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
+	if err != nil {
+		log.Errorf("Error fetching pull requests for feedback of %s: %v", key, err)
+		return
+	}
+	collected := 0
+	for _, pr := range prs {
+		comments, err := ar.Bitbucket.FetchPullRequestComments(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching comments for feedback on PR #%d: %v", pr.ID, err)
+			continue
+		}
+		for _, fb := range feedbackReplies(&auto, pr.ID, comments) {
+			id := fmt.Sprintf("%d:%d", fb.PullRequestID, fb.ReplyID)
+			if seen[id] {
+				continue
+			}
+			if err := ar.Storage.SaveFeedback(fb); err != nil {
+				log.Errorf("Failed to store feedback on PR #%d: %v", pr.ID, err)
+				continue
+			}
+			seen[id] = true
+			collected++
+		}
+	}
+	log.Infof("Feedback collection for %s: %d new %s on %d open PRs", key, collected, helper.Pluralize(collected, "reply", "replies"), len(prs))
+	ar.refreshFeedbackStats()
+}
+
+// feedbackReplies classifies the direct replies of people to the bot's comments.
+func feedbackReplies(auto *model.AutoReviewPR, prID int, comments []model.PullRequestComment) []model.Feedback {
+	bot := map[int]model.PullRequestComment{}
+	for _, c := range comments {
+		if hasBotMarker(c.Content.Raw) {
+			bot[c.ID] = c
+		}
+	}
+	var out []model.Feedback
+	for _, c := range comments {
+		if c.Parent == nil || c.Deleted || hasBotMarker(c.Content.Raw) {
+			continue
+		}
+		parent, ok := bot[c.Parent.ID]
+		if !ok {
+			continue
+		}
+		verdict := helper.ClassifyFeedback(c.Content.Raw)
+		if verdict == "" {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339Nano, c.CreatedOn)
+		if err != nil {
+			created = time.Now()
+		}
+		typ, severity, title := helper.ParseFindingHeading(parent.Content.Raw)
+		fb := model.Feedback{
+			Workspace:     auto.Workspace,
+			RepoSlug:      auto.RepoSlug,
+			PullRequestID: prID,
+			CommentID:     parent.ID,
+			ReplyID:       c.ID,
+			Type:          typ,
+			Severity:      severity,
+			Verdict:       verdict,
+			Author:        c.User.DisplayName,
+			Text:          truncateRunes(strings.TrimSpace(c.Content.Raw), maxFeedbackText),
+			CreatedAt:     created,
+		}
+		if parent.Inline != nil {
+			fb.Path, fb.Line, fb.Title = parent.Inline.Path, parent.Inline.To, title
+		}
+		out = append(out, fb)
+	}
+	return out
+}
+
+// refreshFeedbackStats recomputes the feedback metrics over the last feedbackWindowDays.
+func (ar *AutoReviewPRHandler) refreshFeedbackStats() {
+	list, err := ar.Storage.ListFeedback(model.FeedbackFilter{From: time.Now().AddDate(0, 0, -feedbackWindowDays)})
+	if err != nil {
+		log.Errorf("Error loading feedback for metrics: %v", err)
+		return
+	}
+	reports := summarizeFeedback(list)
+	ar.statsMutex.Lock()
+	ar.feedbackStats = reports
+	ar.statsMutex.Unlock()
+}
+
+// writeFeedbackMetrics exposes the helpful/unhelpful replies and precision per repository.
+func (ar *AutoReviewPRHandler) writeFeedbackMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.feedbackStats == nil {
+		return
+	}
+	fmt.Fprintf(b, "# HELP code_nim_feedback_replies Replies to bot comments in the last %d days by verdict.\n# TYPE code_nim_feedback_replies gauge\n", feedbackWindowDays)
+	for _, r := range ar.feedbackStats {
+		repo := r.Workspace + "/" + r.RepoSlug
+		fmt.Fprintf(b, "code_nim_feedback_replies{repo=%q,verdict=%q} %d\n", repo, model.FeedbackHelpful, r.Helpful)
+		fmt.Fprintf(b, "code_nim_feedback_replies{repo=%q,verdict=%q} %d\n", repo, model.FeedbackUnhelpful, r.Unhelpful)
+	}
+	fmt.Fprintf(b, "# HELP code_nim_feedback_precision Share of helpful verdicts among the feedback replies of the last %d days.\n# TYPE code_nim_feedback_precision gauge\n", feedbackWindowDays)
+	for _, r := range ar.feedbackStats {
+		fmt.Fprintf(b, "code_nim_feedback_precision{repo=%q} %g\n", r.Workspace+"/"+r.RepoSlug, r.Precision)
+	}
+}
+
+// learnedPreferences returns the prompt rules learned from the repository's feedback when
+// adaptivePrompt is enabled, or "".
+func (ar *AutoReviewPRHandler) learnedPreferences(auto *model.AutoReviewPR) string {
+	settings := auto.AdaptivePrompt
+	if !settings.Enabled || ar.Storage == nil {
+		return ""
+	}
+	minDismissals := settings.MinDismissals
+	if minDismissals <= 0 {
+		minDismissals = defaultMinDismissals
+	}
+	days := settings.WindowDays
+	if days <= 0 {
+		days = defaultPreferenceWindowDays
+	}
+	list, err := ar.Storage.ListFeedback(model.FeedbackFilter{Workspace: auto.Workspace, RepoSlug: auto.RepoSlug, From: time.Now().AddDate(0, 0, -days)})
+	if err != nil {
+		log.Errorf("Error loading feedback of %s for adaptive prompting: %v", entryKey(*auto), err)
+		return ""
+	}
+	preferences := helper.LearnedPreferences(list, minDismissals)
+	if preferences != "" {
+		log.Infof("Adding %d learned %s from %d feedback replies to the review prompts of %s",
+			strings.Count(preferences, "\n- "), helper.Pluralize(strings.Count(preferences, "\n- "), "preference", "preferences"), len(list), entryKey(*auto))
+	}
+	return preferences
+}
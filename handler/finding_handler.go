@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FindingHandler struct {
+	Storage storage.Storage
+}
+
+var findingPageTemplate = template.Must(template.New("finding").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} · code-nim</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #172b4d; }
+.meta { color: #5e6c84; margin-bottom: 1em; }
+.badge { display: inline-block; padding: 2px 8px; border-radius: 3px; background: #dfe1e6; margin-right: 4px; }
+pre { background: #f4f5f7; padding: 1em; white-space: pre-wrap; word-wrap: break-word; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">
+{{if .Type}}<span class="badge">{{.Type}}</span>{{end}}{{if .Severity}}<span class="badge">{{.Severity}}</span>{{end}}{{if .Deferred}}<span class="badge">Deferred (busy PR)</span>{{end}}
+{{.Workspace}}/{{.RepoSlug}} · <a href="{{.LineURL}}">PR #{{.PullRequestID}}: {{.PullRequestTitle}}</a> · <code>{{.Path}}:{{.Line}}</code>
+</div>
+<pre>{{.Body}}</pre>
+<div class="meta">Recorded {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}} by {{.ProcessName}}</div>
+</body>
+</html>
+`))
+
+// FindingDetails handles GET /findings/:id and renders the stored finding as HTML.
+func (fh *FindingHandler) FindingDetails(c echo.Context) error {
+	f, err := fh.Storage.GetFinding(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.String(http.StatusNotFound, "finding not found (it may have expired)")
+		}
+		log.Errorf("Failed to load finding %s: %v", c.Param("id"), err)
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	var page strings.Builder
+	err = findingPageTemplate.Execute(&page, struct {
+		model.Finding
+		LineURL string
+	}{
+		Finding: f,
+		LineURL: findingLineURL(f),
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.HTML(http.StatusOK, page.String())
+}
+
+// GetFinding handles GET /api/v1/findings/:id and returns the stored finding as JSON.
+func (fh *FindingHandler) GetFinding(c echo.Context) error {
+	f, err := fh.Storage.GetFinding(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, model.Response{
+				StatusCode: http.StatusNotFound,
+				Message:    "Finding not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "OK",
+		Data:       f,
+	})
+}
+
+func findingLineURL(f model.Finding) string {
+	return helper.PullRequestLineURL(f.Workspace, f.RepoSlug, f.PullRequestID, f.Path, f.Line)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const freezeMarkerPrefix = "<!-- auto-review-frozen:"
+
+// freezeName names a freeze window in notices and job status.
+func freezeName(w *model.FreezeWindow) string {
+	if strings.TrimSpace(w.Name) != "" {
+		return w.Name
+	}
+	return "Release freeze"
+}
+
+// freezeMarker identifies the notice for one occurrence of a freeze window, so each PR gets
+// one notice per freeze rather than one per scan.
+func freezeMarker(start time.Time) string {
+	return fmt.Sprintf("%s%d -->", freezeMarkerPrefix, start.Unix())
+}
+
+// freezeNotice renders the comment posted on pull requests while the repository is frozen.
+func freezeNotice(w *model.FreezeWindow, start, end time.Time) string {
+	var b strings.Builder
+	b.WriteString("## 🧊 Repository is frozen\n\n")
+	fmt.Fprintf(&b, "**%s** is in effect until **%s UTC**. Nim will not review this PR until the freeze ends.\n\n",
+		freezeName(w), end.UTC().Format("2006-01-02 15:04"))
+	if msg := strings.TrimSpace(w.Message); msg != "" {
+		b.WriteString(msg + "\n\n")
+	}
+	b.WriteString(freezeMarker(start) + "\n" + reviewBotMarker)
+	return b.String()
+}
+
+// freezeGuard stops the review of a pull request while one of the repo's freeze windows is
+// active, posting a freeze notice once per PR and freeze occurrence instead. Pull requests of
+// GitHub and Gerrit entries are skipped without a notice.
+func (ar *AutoReviewPRHandler) freezeGuard(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		auto, pr := run.Auto, run.PR
+		w, start, end, _ := helper.ActiveFreeze(auto.FreezeWindows, time.Now())
+		if w == nil {
+			return next.Run(run)
+		}
+		run.Skip = fmt.Sprintf("repository is frozen (%s until %s)", freezeName(w), end.UTC().Format(time.RFC3339))
+		if auto.IsGitHub() || auto.IsGerrit() {
+			// The freeze notice is a Bitbucket comment
+			return nil
+		}
+
+		marker := freezeMarker(start)
+		for _, comment := range run.Comments {
+			if comment.Inline == nil && strings.Contains(comment.Content.Raw, marker) {
+				return nil
+			}
+		}
+		posted, err := ar.deliverComment(auto, pr, "", "freeze:"+marker, func() error {
+			publishStart := time.Now()
+			defer ar.observe(run, "publish", publishStart)
+			return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, freezeNotice(w, start, end)))
+		})
+		if err != nil {
+			log.Errorf("Failed to post freeze notice on PR #%d: %v", pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			return nil
+		}
+		if !posted {
+			return nil
+		}
+		log.Infof("✓ Posted freeze notice (%s) on PR #%d", freezeName(w), pr.ID)
+		return nil
+	})
+}
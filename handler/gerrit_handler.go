@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"code_nim/review"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gerritReviewTag marks the bot's review messages, so Gerrit can hide them as automated and the
+// bot can tell which patchsets it already reviewed.
+const gerritReviewTag = "autogenerated:code-nim"
+
+// gerritLineEnd ends a comment range past the last character of its end line, so the whole
+// line is covered; Gerrit needs a character offset and the review has no line lengths.
+const gerritLineEnd = 1000
+
+// Gerrit Code-Review votes.
+const (
+	gerritVoteReject  = -1
+	gerritVoteNeutral = 0
+	gerritVoteApprove = 1
+)
+
+// reviewGerritChanges reviews the current patchset of each open change of a gerrit entry that
+// the bot has not reviewed yet. When change is non-zero only that change is reviewed.
+func (ar *AutoReviewPRHandler) reviewGerritChanges(auto *model.AutoReviewPR, change int) error {
+	changes, err := ar.Gerrit.FetchOpenChanges(auto.Gerrit.URL, auto.RepoSlug, auto.Username, auto.AppPassword)
+	if err != nil {
+		log.Errorf("Error listing Gerrit changes of %s: %v", auto.RepoSlug, err)
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	log.Infof("Fetched %d open Gerrit changes of %s", len(changes), auto.RepoSlug)
+	for _, ch := range changes {
+		if change != 0 && ch.Number != change {
+			continue
+		}
+		if ch.WorkInProgress {
+			log.Debugf("Skipping Gerrit change %d: work in progress", ch.Number)
+			continue
+		}
+		if strings.EqualFold(ch.Owner.Username, auto.Username) {
+			continue
+		}
+		if err := ar.reviewGerritChange(auto, ch); err != nil {
+			log.Errorf("Gerrit change %d not reviewed: %v", ch.Number, err)
+		}
+	}
+	return nil
+}
+
+// reviewGerritChange reviews the current patchset of ch and posts the summary, the inline
+// findings and the vote as one review.
+func (ar *AutoReviewPRHandler) reviewGerritChange(auto *model.AutoReviewPR, ch model.GerritChange) error {
+	rev, ok := ch.Revisions[ch.CurrentRevision]
+	if !ok {
+		return fmt.Errorf("current revision %s not returned", ch.CurrentRevision)
+	}
+	messages, err := ar.Gerrit.FetchMessages(auto.Gerrit.URL, ch.Number, auto.Username, auto.AppPassword)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	for _, m := range messages {
+		if m.Tag == gerritReviewTag && m.RevisionNumber == rev.Number {
+			log.Debugf("Skipping Gerrit change %d: patchset %d already reviewed", ch.Number, rev.Number)
+			return nil
+		}
+	}
+	if marker, ok := helper.FindSkipMarker(auto.SkipMarkers, ch.Subject, rev.Commit.Message); ok {
+		log.Infof("Skipping Gerrit change %d: skip marker %q in the commit message", ch.Number, marker)
+		return nil
+	}
+	diff, err := ar.Gerrit.FetchPatch(auto.Gerrit.URL, ch.Number, ch.CurrentRevision, auto.Username, auto.AppPassword)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+
+	pr := gerritPullRequest(ch, rev)
+	log.Infof("Reviewing Gerrit change %d patchset %d: %s", ch.Number, rev.Number, ch.Subject)
+	result, err := ar.reviewer(auto, nil).Review(pr, diff)
+	if err != nil {
+		ar.noteAIError(nil)
+		return fmt.Errorf("summary: %w", err)
+	}
+	for path, err := range result.Errors {
+		log.Errorf("Gerrit change %d: review of %s failed: %v", ch.Number, path, err)
+		ar.noteAIError(nil)
+	}
+
+	input, placed := gerritReview(auto, result, ar.Gerrit.Capabilities())
+	postStart := time.Now()
+	err = ar.Gerrit.PostReview(auto.Gerrit.URL, ch.Number, ch.CurrentRevision, input, auto.Username, auto.AppPassword)
+	ar.observe(nil, "publish", postStart)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	log.Infof("✓ Reviewed Gerrit change %d patchset %d: %d inline comments, %s %+d", ch.Number, rev.Number, placed, auto.Gerrit.LabelName(), input.Labels[auto.Gerrit.LabelName()])
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		js.PRsReviewed++
+		js.FindingsPosted += int64(placed)
+	})
+	return nil
+}
+
+// gerritPullRequest describes a change as the pull request the reviewer expects: the commit
+// subject is the title and the rest of the commit message, without the Change-Id footer, the
+// description.
+func gerritPullRequest(ch model.GerritChange, rev model.GerritRevision) *model.PullRequest {
+	pr := &model.PullRequest{ID: ch.Number, Title: ch.Subject, CreatedOn: ch.Created, State: ch.Status}
+	if _, body, ok := strings.Cut(rev.Commit.Message, "\n"); ok {
+		var lines []string
+		for _, line := range strings.Split(body, "\n") {
+			if !strings.HasPrefix(line, "Change-Id: ") {
+				lines = append(lines, line)
+			}
+		}
+		pr.Description = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	pr.Author.DisplayName = ch.Owner.Name
+	pr.Source.Branch.Name = ch.Branch
+	return pr
+}
+
+// gerritReview turns a review result into Gerrit's review input and returns it with the number
+// of inline comments. Findings that could not be placed on a line are listed in the message.
+// The vote is -1 when a finding reaches gerrit.failSeverity, else +1 with gerrit.approve, else 0.
+// Bodies and ranges are posted as far as caps allows.
+func gerritReview(auto *model.AutoReviewPR, result review.Result, caps model.ProviderCapabilities) (model.GerritReviewInput, int) {
+	input := model.GerritReviewInput{Comments: map[string][]model.GerritCommentInput{}, Tag: gerritReviewTag}
+	var severities, unplaced []string
+	placed := 0
+	for _, file := range result.Files {
+		for _, c := range file.Placed {
+			if auto.MaxInlineComments > 0 && placed >= auto.MaxInlineComments {
+				break
+			}
+			comment := model.GerritCommentInput{
+				Line:       c.Position,
+				Message:    helper.AdaptCommentBody(helper.LocalizeFinding(helper.FormatReviewBodyForTone(c.Body, helper.ReviewStyle(helper.PackageConfig(auto, c.Path))), auto.Language), caps),
+				Unresolved: true,
+			}
+			if c.Position == 0 {
+				comment.Side, comment.Line = "PARENT", c.FromLine
+			} else if caps.LineRanges && c.StartLine > 0 && c.StartLine < c.Position {
+				comment.Range = &model.GerritRange{StartLine: c.StartLine, EndLine: c.Position, EndCharacter: gerritLineEnd}
+			}
+			input.Comments[c.Path] = append(input.Comments[c.Path], comment)
+			_, severity, _ := helper.ParseFindingHeading(c.Body)
+			severities = append(severities, severity)
+			placed++
+		}
+		for _, r := range file.Rejected {
+			_, severity, title := helper.ParseFindingHeading(r.Comment.Body)
+			severities = append(severities, severity)
+			unplaced = append(unplaced, fmt.Sprintf("- %s: %s", r.Comment.Path, title))
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString(review.RenderSummaryIn(result.Summary, auto.Language))
+	if note := helper.FormatSkippedFiles(result.Skipped); note != "" {
+		msg.WriteString("\n\n" + strings.TrimSpace(note))
+	}
+	if len(unplaced) > 0 {
+		sort.Strings(unplaced)
+		msg.WriteString("\n\nFindings outside the changed lines:\n" + strings.Join(unplaced, "\n"))
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(&msg, "\n\n%d %s could not be reviewed.", len(result.Errors), helper.Pluralize(len(result.Errors), "file", "files"))
+	}
+
+	failAt := failSeverity(auto.Gerrit.FailSeverity)
+	vote := gerritVoteNeutral
+	if helper.SeverityRank(helper.HighestSeverity(severities)) >= helper.SeverityRank(failAt) && len(severities) > 0 {
+		vote = gerritVoteReject
+		fmt.Fprintf(&msg, "\n\n%s -1: a finding is at or above %s.", auto.Gerrit.LabelName(), failAt)
+	} else if auto.Gerrit.Approve && len(result.Errors) == 0 && auto.ReviewsInline() {
+		vote = gerritVoteApprove
+	}
+	input.Message = helper.AdaptCommentBody(strings.TrimLeft(msg.String(), "\n"), caps)
+	input.Labels = map[string]int{auto.Gerrit.LabelName(): vote}
+	return input, placed
+}
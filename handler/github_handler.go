@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"code_nim/review"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reviewGitHubPullRequests runs the GitHub pipeline for each open pull request of a github
+// entry. When number is non-zero only that pull request is reviewed. Like the Bitbucket run, a
+// failed pull request is skipped and reported, and credential or rate-limit errors end the run.
+func (ar *AutoReviewPRHandler) reviewGitHubPullRequests(auto *model.AutoReviewPR, number int) error {
+	baseURL := auto.GitHub.BaseURL()
+	pulls, err := ar.GitHub.FetchOpenPullRequests(baseURL, auto.Workspace, auto.RepoSlug, auto.AppPassword)
+	if err != nil {
+		log.Errorf("Error listing GitHub pull requests of %s/%s: %v", auto.Workspace, auto.RepoSlug, err)
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	log.Infof("Fetched %d open GitHub pull requests of %s/%s", len(pulls), auto.Workspace, auto.RepoSlug)
+	preferences := ar.learnedPreferences(auto)
+	reviewed, failed := 0, 0
+	var firstErr error
+	for _, pull := range pulls {
+		if number != 0 && pull.Number != number {
+			continue
+		}
+		if pull.Draft {
+			log.Debugf("Skipping GitHub pull request %d: draft", pull.Number)
+			continue
+		}
+		if strings.EqualFold(pull.User.Login, auto.Username) {
+			continue
+		}
+		pr := githubPullRequest(pull)
+		if reason, ok := ar.ignoredReason(auto, pr); ok {
+			log.Debugf("Skipping GitHub pull request %d: %s", pull.Number, reason)
+			ar.notePullRequest(model.JobRunPR{ID: pr.ID, Outcome: model.PROutcomeSkipped, Reason: reason})
+			continue
+		}
+		reviewed++
+		run, err := ar.runPipeline(ar.newGitHubPipeline(pull), auto, pr, preferences)
+		ar.notePullRequest(pullRequestOutcome(run, err))
+		switch {
+		case err == nil:
+		case errors.Is(err, errHaltReview):
+			return nil
+		case abortsRun(err):
+			log.Errorf("Stopping the review of %s/%s at GitHub pull request %d: %v", auto.Workspace, auto.RepoSlug, pull.Number, err)
+			return err
+		case errors.Is(err, helper.ErrNotFound):
+			log.Warnf("Skipping GitHub pull request %d: %v (closed or deleted during the run?)", pull.Number, err)
+		default:
+			log.Errorf("GitHub pull request %d not reviewed: %v", pull.Number, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pull requests failed: %w", failed, reviewed, firstErr)
+	}
+	return nil
+}
+
+// githubPullRequest describes a GitHub pull request as the pull request the reviewer expects.
+func githubPullRequest(pull model.GitHubPullRequest) *model.PullRequest {
+	pr := &model.PullRequest{ID: pull.Number, Title: pull.Title, Description: pull.Body, CreatedOn: pull.CreatedAt, State: pull.State}
+	pr.Author.DisplayName = pull.User.Login
+	pr.Source.Branch.Name = pull.Head.Ref
+	return pr
+}
+
+// newGitHubPipeline builds the pipeline of one GitHub pull request:
+// fetch → filter → analyze → post → notify. It has the guards of the Bitbucket pipeline: the
+// daily AI budget before fetch, and freeze and posting windows before analyze. A freeze skips
+// the pull request without a notice.
+func (ar *AutoReviewPRHandler) newGitHubPipeline(pull model.GitHubPullRequest) *reviewPipeline {
+	p := &reviewPipeline{stages: []reviewStage{
+		newStage("fetch", ar.githubFetchStage),
+		newStage("filter", func(run *reviewRun) error { return ar.githubFilterStage(run, pull) }),
+		newStage("analyze", ar.githubAnalyzeStage),
+		newStage("post", func(run *reviewRun) error { return ar.githubPostStage(run, pull) }),
+		newStage("notify", ar.githubNotifyStage),
+	}}
+	p.Use(ar.timeStage)
+	p.Use(ar.budgetGuard, "fetch")
+	p.Use(ar.freezeGuard, "analyze")
+	p.Use(ar.postingGuard, "analyze")
+	return p
+}
+
+// githubFetchStage loads the reviews of the pull request as top-level comments, so the filter
+// and guards read them like Bitbucket comments.
+func (ar *AutoReviewPRHandler) githubFetchStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	reviews, err := ar.GitHub.FetchReviews(auto.GitHub.BaseURL(), auto.Workspace, auto.RepoSlug, pr.ID, auto.AppPassword)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	run.Comments = make([]model.PullRequestComment, 0, len(reviews))
+	for _, r := range reviews {
+		var c model.PullRequestComment
+		c.ID = int(r.ID)
+		c.User.Username, c.User.DisplayName = r.User.Login, r.User.Login
+		c.Content.Raw = r.Body
+		run.Comments = append(run.Comments, c)
+	}
+	run.GitHubReviews = reviews
+	return nil
+}
+
+// githubFilterStage skips pull requests whose head commit the bot already reviewed, that carry
+// a skip marker or that a configured reviewer paused with an LGTM marker, and reviews the
+// pull requests of ignored authors as summary only.
+func (ar *AutoReviewPRHandler) githubFilterStage(run *reviewRun, pull model.GitHubPullRequest) error {
+	auto, pr := run.Auto, run.PR
+	run.LatestCommitHash = pull.Head.SHA
+	for _, r := range run.GitHubReviews {
+		if strings.EqualFold(r.User.Login, auto.Username) && hasBotMarker(r.Body) {
+			run.HasSummary = true
+			if r.CommitID == pull.Head.SHA {
+				run.Skip = fmt.Sprintf("commit %s already reviewed", shortHash(pull.Head.SHA))
+				return nil
+			}
+		}
+	}
+	if marker, ok := helper.FindSkipMarker(auto.SkipMarkers, pr.Title, pr.Description); ok {
+		run.Skip = fmt.Sprintf("skip marker %q in the title or description", marker)
+		return nil
+	}
+	for _, comment := range run.Comments {
+		if hasBotMarker(comment.Content.Raw) || !isConfiguredReviewer(auto, comment) {
+			continue
+		}
+		if marker, ok := helper.FindLGTMMarker(auto.LGTMMarkers, comment.Content.Raw); ok {
+			log.Infof("LGTM marker %q by %s; will skip all reviews for GitHub pull request %d", marker, comment.User.DisplayName, pr.ID)
+			run.Skip = "LGTM pause is active"
+			return nil
+		}
+	}
+	run.SkipInline = !auto.ReviewsInline() || containsString(auto.IgnorePullRequestOf.DisplayNames, pr.Author.DisplayName)
+	return nil
+}
+
+// githubAnalyzeStage fetches the diff of the pull request.
+func (ar *AutoReviewPRHandler) githubAnalyzeStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	diff, err := ar.GitHub.FetchDiff(auto.GitHub.BaseURL(), auto.Workspace, auto.RepoSlug, pr.ID, auto.AppPassword)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	if strings.TrimSpace(diff) == "" {
+		run.Skip = "diff is empty"
+		return nil
+	}
+	run.Diff = diff
+	if helper.SkipsAI(auto, diff) {
+		run.Skip = "model routes leave every changed file out of AI review"
+	}
+	return nil
+}
+
+// githubPostStage reviews the head commit and submits the summary and all inline findings as
+// one pull request review, so watchers get a single notification.
+func (ar *AutoReviewPRHandler) githubPostStage(run *reviewRun, pull model.GitHubPullRequest) error {
+	auto, pr := run.Auto, run.PR
+	reviewAuto := auto
+	if run.SkipInline && auto.ReviewsInline() {
+		summaryOnly := *auto
+		summaryOnly.Mode = model.ReviewModeSummary
+		reviewAuto = &summaryOnly
+	}
+	log.Infof("Reviewing GitHub pull request %d at %s: %s", pr.ID, shortHash(pull.Head.SHA), pr.Title)
+	result, err := ar.reviewer(reviewAuto, run).Review(pr, run.Diff)
+	if err != nil {
+		ar.noteAIError(run)
+		return fmt.Errorf("summary: %w", err)
+	}
+	for path, err := range result.Errors {
+		log.Errorf("GitHub pull request %d: review of %s failed: %v", pr.ID, path, err)
+		ar.noteAIError(run)
+	}
+
+	input := githubReview(reviewAuto, result, pull.Head.SHA, ar.GitHub.Capabilities())
+	postStart := time.Now()
+	err = ar.GitHub.CreateReview(auto.GitHub.BaseURL(), auto.Workspace, auto.RepoSlug, pr.ID, input, auto.AppPassword)
+	ar.observe(run, "publish", postStart)
+	if err != nil {
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	run.SummaryPosted = auto.PostsSummary()
+	run.InlinePosted = len(input.Comments)
+	if len(result.Errors) > 0 {
+		run.PostErr = fmt.Errorf("%w: %d files failed AI review", errIncompleteReview, len(result.Errors))
+	}
+	return nil
+}
+
+// githubNotifyStage reports the outcome of the pull request review.
+func (ar *AutoReviewPRHandler) githubNotifyStage(run *reviewRun) error {
+	log.Infof("✓ Reviewed GitHub pull request %d at %s: one review with %d inline comments", run.PR.ID, shortHash(run.LatestCommitHash), run.InlinePosted)
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		js.PRsReviewed++
+		js.FindingsPosted += int64(run.InlinePosted)
+	})
+	return nil
+}
+
+// githubReview turns a review result into a single COMMENT review of commit. Findings that
+// could not be placed on a line are listed in the body, which ends with the marker naming the
+// reviewed commit. Bodies and ranges are posted as far as caps allows.
+func githubReview(auto *model.AutoReviewPR, result review.Result, commit string, caps model.ProviderCapabilities) model.GitHubReviewInput {
+	input := model.GitHubReviewInput{CommitID: commit, Event: "COMMENT"}
+	var unplaced []string
+	for _, file := range result.Files {
+		for _, c := range file.Placed {
+			if auto.MaxInlineComments > 0 && len(input.Comments) >= auto.MaxInlineComments {
+				break
+			}
+			comment := model.GitHubReviewComment{
+				Path: c.Path,
+				Line: c.Position,
+				Side: "RIGHT",
+				Body: helper.AdaptCommentBody(helper.LocalizeFinding(helper.FormatReviewBodyForTone(c.Body, helper.ReviewStyle(helper.PackageConfig(auto, c.Path))), auto.Language), caps),
+			}
+			if c.Position == 0 {
+				comment.Side, comment.Line = "LEFT", c.FromLine
+			} else if caps.LineRanges && c.StartLine > 0 && c.StartLine < c.Position {
+				comment.StartLine, comment.StartSide = c.StartLine, "RIGHT"
+			}
+			input.Comments = append(input.Comments, comment)
+		}
+		for _, r := range file.Rejected {
+			_, _, title := helper.ParseFindingHeading(r.Comment.Body)
+			unplaced = append(unplaced, fmt.Sprintf("- `%s`: %s", r.Comment.Path, title))
+		}
+	}
+
+	var body strings.Builder
+	body.WriteString(review.RenderSummaryIn(result.Summary, auto.Language))
+	if note := helper.FormatSkippedFiles(result.Skipped); note != "" {
+		body.WriteString("\n\n" + strings.TrimSpace(note))
+	}
+	if len(unplaced) > 0 {
+		sort.Strings(unplaced)
+		body.WriteString("\n\n**Findings outside the changed lines**\n" + strings.Join(unplaced, "\n"))
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(&body, "\n\n%d %s could not be reviewed.", len(result.Errors), helper.Pluralize(len(result.Errors), "file", "files"))
+	}
+	fmt.Fprintf(&body, "\n\n%s%s %s", reviewMarkerPrefix, commit, reviewMarkerSuffix)
+	// Without a summary (mode inline) the body starts with a note or the marker
+	input.Body = helper.AdaptCommentBody(strings.TrimLeft(body.String(), "\n"), caps)
+	return input
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/github"
+	"code_nim/model"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// fakeGitHub serves fixed pull requests and reviews and records the pull requests it was asked about.
+type fakeGitHub struct {
+	github.GitHub
+	pulls      []model.GitHubPullRequest
+	reviews    []model.GitHubReview
+	reviewsErr error
+	fetched    []int // pull requests whose reviews were fetched
+	diffs      []int // pull requests whose diff was fetched
+}
+
+func (f *fakeGitHub) FetchOpenPullRequests(_, _, _, _ string) ([]model.GitHubPullRequest, error) {
+	return f.pulls, nil
+}
+
+func (f *fakeGitHub) FetchReviews(_, _, _ string, number int, _ string) ([]model.GitHubReview, error) {
+	f.fetched = append(f.fetched, number)
+	return f.reviews, f.reviewsErr
+}
+
+func (f *fakeGitHub) FetchDiff(_, _, _ string, number int, _ string) (string, error) {
+	f.diffs = append(f.diffs, number)
+	return "", nil
+}
+
+func githubEntry() *model.AutoReviewPR {
+	return &model.AutoReviewPR{GitProvider: "github", Workspace: "acme", RepoSlug: "api", Username: "nim-bot", AppPassword: "token"}
+}
+
+func githubPulls(numbers ...int) []model.GitHubPullRequest {
+	var pulls []model.GitHubPullRequest
+	for _, n := range numbers {
+		pull := model.GitHubPullRequest{Number: n, Title: "Change", State: "open"}
+		pull.User.Login = "dev"
+		pull.Head.SHA = "0123456789abcdef"
+		pulls = append(pulls, pull)
+	}
+	return pulls
+}
+
+func TestReviewGitHubPullRequestsStopsOnAuthError(t *testing.T) {
+	fake := &fakeGitHub{
+		pulls:      githubPulls(1, 2),
+		reviewsErr: &github.StatusError{Op: "fetch reviews", StatusCode: http.StatusUnauthorized},
+	}
+	ar := &AutoReviewPRHandler{GitHub: fake}
+
+	err := ar.reviewGitHubPullRequests(githubEntry(), 0)
+	if !errors.Is(err, helper.ErrAuth) {
+		t.Fatalf("reviewGitHubPullRequests() = %v, want an ErrAuth error", err)
+	}
+	if len(fake.fetched) != 1 {
+		t.Errorf("fetched the reviews of %v, want only the first pull request", fake.fetched)
+	}
+}
+
+func TestReviewGitHubPullRequestsSkips(t *testing.T) {
+	tests := []struct {
+		name    string
+		auto    func(auto *model.AutoReviewPR)
+		reviews []model.GitHubReview
+	}{
+		{
+			name: "listed in ignorePullRequests",
+			auto: func(auto *model.AutoReviewPR) { auto.IgnorePullRequests = []int{1} },
+		},
+		{
+			name:    "LGTM marker",
+			reviews: []model.GitHubReview{{User: model.GitHubUser{Login: "lead"}, Body: "LGTM", State: "APPROVED"}},
+		},
+		{
+			name:    "head commit already reviewed",
+			reviews: []model.GitHubReview{{User: model.GitHubUser{Login: "nim-bot"}, Body: "ok\n\n" + reviewMarkerPrefix + "0123456789abcdef " + reviewMarkerSuffix, CommitID: "0123456789abcdef"}},
+		},
+		{
+			name: "freeze window",
+			auto: func(auto *model.AutoReviewPR) {
+				auto.FreezeWindows = []model.FreezeWindow{{Name: "release", From: "2000-01-01", To: "2999-01-01"}}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGitHub{pulls: githubPulls(1), reviews: tt.reviews}
+			ar := &AutoReviewPRHandler{GitHub: fake}
+			auto := githubEntry()
+			if tt.auto != nil {
+				tt.auto(auto)
+			}
+			if err := ar.reviewGitHubPullRequests(auto, 0); err != nil {
+				t.Fatalf("reviewGitHubPullRequests() = %v", err)
+			}
+			if len(fake.diffs) != 0 {
+				t.Errorf("fetched the diff of %v, want the pull request skipped", fake.diffs)
+			}
+		})
+	}
+}
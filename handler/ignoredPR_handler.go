@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ignoredKey identifies a pull request in the ignore list.
+func ignoredKey(workspace, repoSlug string, prID int) string {
+	return fmt.Sprintf("%s/%s#%d", strings.ToLower(workspace), strings.ToLower(repoSlug), prID)
+}
+
+// loadIgnored reads the persisted ignore list on first use. A failed read is retried on the
+// next use. Callers hold ar.statsMutex.
+func (ar *AutoReviewPRHandler) loadIgnored() {
+	if ar.ignoredLoaded {
+		return
+	}
+	if ar.ignored == nil {
+		ar.ignored = make(map[string]model.IgnoredPullRequest)
+	}
+	if ar.Storage != nil {
+		list, err := ar.Storage.ListIgnoredPullRequests()
+		if err != nil {
+			log.Errorf("Failed to load the ignored pull requests: %v", err)
+			return
+		}
+		for _, p := range list {
+			ar.ignored[ignoredKey(p.Workspace, p.RepoSlug, p.PullRequestID)] = p
+		}
+	}
+	ar.ignoredLoaded = true
+}
+
+// ignoredReason reports whether pr is ignored, by the entry's ignorePullRequests or an earlier
+// IgnoreCommand, and why. An ignored pull request is skipped before its comments are fetched.
+func (ar *AutoReviewPRHandler) ignoredReason(auto *model.AutoReviewPR, pr *model.PullRequest) (string, bool) {
+	for _, id := range auto.IgnorePullRequests {
+		if id == pr.ID {
+			return "listed in ignorePullRequests", true
+		}
+	}
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	ar.loadIgnored()
+	p, ok := ar.ignored[ignoredKey(auto.Workspace, auto.RepoSlug, pr.ID)]
+	if !ok || p.Lifted {
+		return "", false
+	}
+	return fmt.Sprintf("ignored by %s on %s", p.By, p.IgnoredAt.Format("2006-01-02")), true
+}
+
+// findIgnoreCommand returns the first top-level human comment of pr that asks to ignore it. The
+// comment already honored and earlier ones are left out, so a lifted ignore is not applied again.
+func (ar *AutoReviewPRHandler) findIgnoreCommand(auto *model.AutoReviewPR, pr *model.PullRequest, comments []model.PullRequestComment) (model.PullRequestComment, string, bool) {
+	ar.statsMutex.Lock()
+	ar.loadIgnored()
+	honored := ar.ignored[ignoredKey(auto.Workspace, auto.RepoSlug, pr.ID)].CommentID
+	ar.statsMutex.Unlock()
+	for _, c := range comments {
+		if c.ID <= honored || c.Inline != nil || c.Parent != nil || c.Deleted || hasBotMarker(c.Content.Raw) {
+			continue
+		}
+		if reason, ok := helper.ParseIgnoreCommand(c.Content.Raw); ok {
+			return c, reason, true
+		}
+	}
+	return model.PullRequestComment{}, "", false
+}
+
+// ignorePullRequest persists the IgnoreCommand of comment, so later runs skip pr without reading
+// its comments again, and confirms it on the pull request. A failed save still ignores pr until
+// the service restarts.
+func (ar *AutoReviewPRHandler) ignorePullRequest(auto *model.AutoReviewPR, pr *model.PullRequest, comment model.PullRequestComment, reason string) {
+	p := model.IgnoredPullRequest{
+		Workspace:     auto.Workspace,
+		RepoSlug:      auto.RepoSlug,
+		PullRequestID: pr.ID,
+		By:            comment.User.DisplayName,
+		Reason:        reason,
+		CommentID:     comment.ID,
+		IgnoredAt:     time.Now(),
+	}
+	if ar.Storage != nil {
+		if err := ar.Storage.SaveIgnoredPullRequest(p); err != nil {
+			log.Errorf("Failed to persist the ignore of PR #%d: %v", pr.ID, err)
+		}
+	}
+	ar.statsMutex.Lock()
+	ar.loadIgnored()
+	ar.ignored[ignoredKey(p.Workspace, p.RepoSlug, p.PullRequestID)] = p
+	ar.statsMutex.Unlock()
+	log.Infof("PR #%d of %s/%s is ignored from now on, as %s asked", pr.ID, auto.Workspace, auto.RepoSlug, p.By)
+
+	_, err := ar.deliverComment(auto, pr, "", fmt.Sprintf("ignored:%d", comment.ID), func() error {
+		note := fmt.Sprintf("🙈 This pull request is no longer reviewed, as %s asked with `%s`. An admin can lift this with `DELETE /api/v1/ignored/%s/%s/%d`.\n%s",
+			p.By, model.IgnoreCommand, auto.Workspace, auto.RepoSlug, pr.ID, reviewBotMarker)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, note))
+	})
+	if err != nil {
+		log.Errorf("Failed to confirm the ignore of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+	}
+}
+
+// forgetIgnoredClosed drops the ignored pull requests of an entry that are not among open.
+func (ar *AutoReviewPRHandler) forgetIgnoredClosed(auto *model.AutoReviewPR, open []model.PullRequest) {
+	ids := make(map[int]bool, len(open))
+	for _, pr := range open {
+		ids[pr.ID] = true
+	}
+	ar.statsMutex.Lock()
+	ar.loadIgnored()
+	var closed []model.IgnoredPullRequest
+	for key, p := range ar.ignored {
+		if strings.EqualFold(p.Workspace, auto.Workspace) && strings.EqualFold(p.RepoSlug, auto.RepoSlug) && !ids[p.PullRequestID] {
+			closed = append(closed, p)
+			delete(ar.ignored, key)
+		}
+	}
+	ar.statsMutex.Unlock()
+	for _, p := range closed {
+		if ar.Storage == nil {
+			continue
+		}
+		if err := ar.Storage.DeleteIgnoredPullRequest(p.Workspace, p.RepoSlug, p.PullRequestID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Errorf("Failed to forget the ignore of closed PR #%d: %v", p.PullRequestID, err)
+		}
+	}
+}
+
+// ListIgnored handles GET /api/v1/ignored and answers with the pull requests ignored through
+// comments, oldest first. Pull requests in the ignorePullRequests of an entry are not listed.
+func (ar *AutoReviewPRHandler) ListIgnored(c echo.Context) error {
+	ar.statsMutex.Lock()
+	ar.loadIgnored()
+	list := make([]model.IgnoredPullRequest, 0, len(ar.ignored))
+	for _, p := range ar.ignored {
+		if !p.Lifted {
+			list = append(list, p)
+		}
+	}
+	ar.statsMutex.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].IgnoredAt.Before(list[j].IgnoredAt) })
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: "Ignored pull requests", Data: list})
+}
+
+// UnignorePullRequest handles DELETE /api/v1/ignored/:workspace/:repoSlug/:prID. The pull
+// request is reviewed again on the next run; the comment that ignored it is not honored again.
+func (ar *AutoReviewPRHandler) UnignorePullRequest(c echo.Context) error {
+	workspace, repoSlug := c.Param("workspace"), c.Param("repoSlug")
+	prID, err := strconv.Atoi(c.Param("prID"))
+	if err != nil || prID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Invalid pull request ID %q", c.Param("prID")),
+		})
+	}
+	key := ignoredKey(workspace, repoSlug, prID)
+	ar.statsMutex.Lock()
+	ar.loadIgnored()
+	p, found := ar.ignored[key]
+	found = found && !p.Lifted
+	if found {
+		p.Lifted = true
+		ar.ignored[key] = p
+	}
+	ar.statsMutex.Unlock()
+	if !found {
+		return c.JSON(http.StatusNotFound, model.Response{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("PR #%d of %s/%s is not ignored", prID, workspace, repoSlug),
+		})
+	}
+	if ar.Storage != nil {
+		if err := ar.Storage.SaveIgnoredPullRequest(p); err != nil {
+			log.Errorf("Failed to persist the lifted ignore of PR #%d: %v", prID, err)
+			return c.JSON(http.StatusInternalServerError, model.Response{
+				StatusCode: http.StatusInternalServerError,
+				Message:    err.Error(),
+			})
+		}
+	}
+	log.Infof("Lifted the ignore of PR #%d of %s/%s", prID, workspace, repoSlug)
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: "Pull request is reviewed again"})
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"code_nim/helper/atlassian"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPostConcurrency = 4
+	defaultPostRetries     = 2
+)
+
+// postRetryDelay is the wait before the first retry of a failed post; it doubles per retry.
+var postRetryDelay = time.Second
+
+// inlinePost is an inline comment queued for posting and, once sent, its outcome.
+type inlinePost struct {
+	comment      model.ReviewComment
+	key          string // duplicate detection key, see inlineKey
+	body         string
+	fingerprints []string
+	posted       bool // false with a nil err when an earlier run already posted it
+	retries      int
+	err          error
+}
+
+func postConcurrency(auto *model.AutoReviewPR) int {
+	if auto.PostConcurrency <= 0 {
+		return defaultPostConcurrency
+	}
+	return auto.PostConcurrency
+}
+
+func postRetries(auto *model.AutoReviewPR) int {
+	switch {
+	case auto.PostRetries < 0:
+		return 0
+	case auto.PostRetries == 0:
+		return defaultPostRetries
+	}
+	return auto.PostRetries
+}
+
+// postInlineBatch sends posts with at most concurrency of them in flight and records the
+// outcome of each in place. A panic while sending a post is recovered as the error of that post.
+func (ar *AutoReviewPRHandler) postInlineBatch(posts []*inlinePost, concurrency int, send func(p *inlinePost) (bool, error)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, p := range posts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(p *inlinePost) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer ar.recoverPanic("posting of "+p.key, &p.err)
+			p.posted, p.err = send(p)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// retryOnServerError calls send until it succeeds, fails with anything but a 5xx response, or
+// has been retried retries times. It returns the number of retries made.
+func retryOnServerError(retries int, what string, send func() error) (int, error) {
+	delay := postRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := send()
+		if err == nil || attempt == retries || !atlassian.IsServerError(err) {
+			return attempt, err
+		}
+		log.Warnf("%s failed (%v); retrying in %s (%d/%d)", what, err, delay, attempt+1, retries)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// sendInline posts one inline comment to Bitbucket through the delivery ledger. A range is
+// tried first when the API supports ranges and falls back to a comment on its last line; each
+// call is retried on 5xx.
+func (ar *AutoReviewPRHandler) sendInline(run *reviewRun, auto *model.AutoReviewPR, pr *model.PullRequest, p *inlinePost) (bool, error) {
+	c := p.comment
+	// Convert FromLine: -1 means added line (no source), use 0 for API
+	fromLine := c.FromLine
+	if fromLine < 0 {
+		fromLine = 0
+	}
+	retries := postRetries(auto)
+	body := ar.withFooter(auto, withFindingMarker(c.Path, findingLine(c.Position, c.FromLine), p.body))
+	post := func() error {
+		publishStart := time.Now()
+		defer ar.observe(run, "publish", publishStart)
+		if c.StartLine > 0 && c.EndLine == c.Position && ar.Bitbucket.Capabilities().LineRanges {
+			n, err := retryOnServerError(retries, fmt.Sprintf("Posting %s lines %d-%d", c.Path, c.StartLine, c.EndLine), func() error {
+				return ar.Bitbucket.PushPullRequestInlineRangeComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword,
+					c.Path, c.StartLine, c.EndLine, body)
+			})
+			p.retries += n
+			if err == nil {
+				return nil
+			}
+			log.Warnf("Posting %s lines %d-%d as a range failed (%v); commenting on line %d only", c.Path, c.StartLine, c.EndLine, err, c.Position)
+		}
+		n, err := retryOnServerError(retries, "Posting inline comment at "+p.key, func() error {
+			return ar.Bitbucket.PushPullRequestInlineComment(
+				pr.ID,
+				auto.Workspace,
+				auto.RepoSlug,
+				auto.Username,
+				auto.AppPassword,
+				c.Path,
+				fromLine,   // from line in old/source file
+				c.Position, // to line in new/destination file
+				body,
+			)
+		})
+		p.retries += n
+		return err
+	}
+	if len(p.fingerprints) > 0 {
+		return ar.deliverComment(auto, pr, "", "finding:"+p.fingerprints[0], post)
+	}
+	if err := post(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordPosting adds the outcome of one review's inline comments to the process totals.
+func (ar *AutoReviewPRHandler) recordPosting(result model.PostingResult) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	ar.postingTotals.Posted += result.Posted
+	ar.postingTotals.Failed += result.Failed
+	ar.postingTotals.Skipped += result.Skipped
+	ar.postingTotals.Retries += result.Retries
+}
@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/labstack/echo/v4"
+)
+
+// Kinds of errors counted per job.
+const (
+	jobErrorAI  = "ai"
+	jobErrorAPI = "api"
+)
+
+// updateCurrentJob applies fn to the status of the job under review, if any.
+func (ar *AutoReviewPRHandler) updateCurrentJob(fn func(js *model.JobStatus)) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if js, ok := ar.jobs[ar.currentJob]; ok {
+		fn(js)
+	}
+}
+
+// noteJobError counts an AI or Bitbucket API error against the job under review.
+func (ar *AutoReviewPRHandler) noteJobError(kind string) {
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		if kind == jobErrorAI {
+			js.AIErrors++
+		} else {
+			js.APIErrors++
+		}
+	})
+}
+
+// noteAIError counts a failed AI request against the job under review and, when run is
+// non-nil, against its pull request.
+func (ar *AutoReviewPRHandler) noteAIError(run *reviewRun) {
+	if run != nil {
+		run.AIErrors++
+	}
+	ar.noteJobError(jobErrorAI)
+}
+
+// beginJob marks the job as running; reviews run one at a time so a single current job suffices.
+func (ar *AutoReviewPRHandler) beginJob(key string) {
+	ar.statsMutex.Lock()
+	ar.currentJob = key
+	ar.runID = newRunID()
+	ar.runPRs = nil
+	ar.statsMutex.Unlock()
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		js.Running = true
+		js.LastRunAt = time.Now()
+		ar.runBaseline = *js
+	})
+}
+
+// maxRecentRuns is how many runs per job are kept for the status page.
+const maxRecentRuns = 10
+
+// maxRunPullRequests bounds the pull requests recorded per run.
+const maxRunPullRequests = 100
+
+// notePullRequest records what the run in progress did with a pull request.
+func (ar *AutoReviewPRHandler) notePullRequest(pr model.JobRunPR) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.currentJob != "" && len(ar.runPRs) < maxRunPullRequests {
+		ar.runPRs = append(ar.runPRs, pr)
+	}
+}
+
+// finishJob records the outcome of the run started by beginJob.
+func (ar *AutoReviewPRHandler) finishJob(start time.Time, err error) {
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		js.Running = false
+		js.Runs++
+		js.LastDuration = time.Since(start)
+		js.LastResult = "ok"
+		js.LastError = ""
+		if err != nil {
+			js.LastResult = "error"
+			js.LastError = err.Error()
+		}
+		run := model.JobRun{
+			ID:             ar.runID,
+			StartedAt:      start,
+			FinishedAt:     start.Add(js.LastDuration),
+			Duration:       js.LastDuration,
+			Result:         js.LastResult,
+			Error:          js.LastError,
+			PRsProcessed:   int64(len(ar.runPRs)),
+			PRsReviewed:    js.PRsReviewed - ar.runBaseline.PRsReviewed,
+			FindingsPosted: js.FindingsPosted - ar.runBaseline.FindingsPosted,
+			PullRequests:   ar.runPRs,
+		}
+		js.RecentRuns = append([]model.JobRun{run}, js.RecentRuns...)
+		if len(js.RecentRuns) > maxRecentRuns {
+			js.RecentRuns = js.RecentRuns[:maxRecentRuns]
+		}
+	})
+	ar.statsMutex.Lock()
+	ar.currentJob = ""
+	ar.runID = ""
+	ar.runPRs = nil
+	ar.statsMutex.Unlock()
+}
+
+// isPaused reports whether scheduled and webhook reviews of the job are paused.
+func (ar *AutoReviewPRHandler) isPaused(key string) bool {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	js, ok := ar.jobs[key]
+	return ok && js.Paused
+}
+
+// jobStatuses returns a copy of every job status, sorted by name.
+func (ar *AutoReviewPRHandler) jobStatuses() []model.JobStatus {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	out := make([]model.JobStatus, 0, len(ar.jobs))
+	for key, js := range ar.jobs {
+		status := *js
+		status.RecentRuns = append([]model.JobRun(nil), js.RecentRuns...)
+		if w, _, end, _ := helper.ActiveFreeze(ar.entries[key].FreezeWindows, time.Now()); w != nil {
+			status.Frozen, status.FrozenUntil = freezeName(w), end
+		}
+		if job, ok := ar.cronJobs[key]; ok {
+			if next, err := job.NextRun(); err == nil {
+				status.NextRunAt = next
+			}
+		}
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// entry returns the config entry of key. Entries are looked up when they are used rather than
+// kept, since rotated secrets replace them.
+func (ar *AutoReviewPRHandler) entry(key string) (model.AutoReviewPR, bool) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	auto, ok := ar.entries[key]
+	return auto, ok
+}
+
+// entrySnapshot returns a copy of the config entries by entryKey.
+func (ar *AutoReviewPRHandler) entrySnapshot() map[string]model.AutoReviewPR {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	return maps.Clone(ar.entries)
+}
+
+// current returns the latest version of auto, which a scheduled task captured when it was
+// scheduled.
+func (ar *AutoReviewPRHandler) current(auto model.AutoReviewPR) model.AutoReviewPR {
+	if latest, ok := ar.entry(entryKey(auto)); ok {
+		return latest
+	}
+	return auto
+}
+
+// scheduleCron registers (or re-registers) the cron job that enqueues reviews of key.
+// The cron expression includes seconds, like the cron field of the YAML config.
+func (ar *AutoReviewPRHandler) scheduleCron(key, cron string) error {
+	if _, ok := ar.entry(key); !ok {
+		return fmt.Errorf("job %q not found", key)
+	}
+	definition := gocron.CronJob(cron, true)
+	task := gocron.NewTask(ar.safeTask("schedule of "+key, func() {
+		if auto, ok := ar.entry(key); ok {
+			ar.scheduleReview(auto)
+		}
+	}))
+
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	var (
+		job gocron.Job
+		err error
+	)
+	if existing, ok := ar.cronJobs[key]; ok {
+		job, err = ar.scheduler.Update(existing.ID(), definition, task)
+	} else {
+		job, err = ar.scheduler.NewJob(definition, task)
+	}
+	if err != nil {
+		return err
+	}
+	ar.cronJobs[key] = job
+	if js, ok := ar.jobs[key]; ok {
+		js.Cron = cron
+	}
+	return nil
+}
+
+// unscheduleCron removes the cron job of key from the scheduler.
+func (ar *AutoReviewPRHandler) unscheduleCron(key string) error {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	job, ok := ar.cronJobs[key]
+	if !ok {
+		return nil
+	}
+	if err := ar.scheduler.RemoveJob(job.ID()); err != nil {
+		return err
+	}
+	delete(ar.cronJobs, key)
+	return nil
+}
+
+// setPaused changes the paused flag of a job and reports whether the job exists.
+func (ar *AutoReviewPRHandler) setPaused(key string, paused bool) bool {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	js, ok := ar.jobs[key]
+	if ok {
+		js.Paused = paused
+	}
+	return ok
+}
+
+// jobCron returns the current cron expression of a job.
+func (ar *AutoReviewPRHandler) jobCron(key string) string {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if js, ok := ar.jobs[key]; ok {
+		return js.Cron
+	}
+	return ""
+}
+
+// jobName returns the :name route parameter; unnamed jobs are "workspace/repoSlug" and arrive URL-encoded.
+func jobName(c echo.Context) string {
+	name := c.Param("name")
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+func jobNotFound(c echo.Context, name string) error {
+	return c.JSON(http.StatusNotFound, model.Response{
+		StatusCode: http.StatusNotFound,
+		Message:    fmt.Sprintf("Job %q not found", name),
+	})
+}
+
+// ListJobs handles GET /api/v1/jobs.
+func (ar *AutoReviewPRHandler) ListJobs(c echo.Context) error {
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Jobs",
+		Data:       ar.jobStatuses(),
+	})
+}
+
+// TriggerJob handles POST /api/v1/jobs/:name/trigger and reviews the job's open PRs now,
+// even when the job is paused.
+func (ar *AutoReviewPRHandler) TriggerJob(c echo.Context) error {
+	name := jobName(c)
+	auto, ok := ar.entry(name)
+	if !ok {
+		return jobNotFound(c, name)
+	}
+	// Running a job by hand retries Bitbucket at once, e.g. after its app password was replaced
+	ar.closeCircuit(name)
+	if ar.Queue == nil {
+		go func() { _ = ar.reviewTask(auto, 0) }()
+		log.Infof("Manually triggered review for %s", name)
+		return c.JSON(http.StatusAccepted, model.Response{
+			StatusCode: http.StatusAccepted,
+			Message:    "Review started",
+		})
+	}
+	pos, err := ar.Queue.Push(model.ReviewJob{
+		ProcessName: name,
+		Priority:    model.PriorityHigh,
+		Source:      "manual",
+	})
+	if err != nil {
+		log.Warnf("Manual review for %s not queued: %v", name, err)
+		return c.JSON(http.StatusServiceUnavailable, model.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    err.Error(),
+		})
+	}
+	log.Infof("Manually queued review for %s at position %d", name, pos)
+	return c.JSON(http.StatusAccepted, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    "Review queued",
+		Data:       map[string]interface{}{"processName": name, "queuePosition": pos},
+	})
+}
+
+// PauseJob handles POST /api/v1/jobs/:name/pause. The cron job is removed from the scheduler and
+// webhook reviews are ignored until the job is resumed.
+func (ar *AutoReviewPRHandler) PauseJob(c echo.Context) error {
+	name := jobName(c)
+	if !ar.setPaused(name, true) {
+		return jobNotFound(c, name)
+	}
+	if err := ar.unscheduleCron(name); err != nil {
+		log.Errorf("Failed to remove cron job of %s: %v", name, err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	log.Infof("Paused job %s", name)
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: "Job paused"})
+}
+
+// ResumeJob handles POST /api/v1/jobs/:name/resume and puts the cron job back on the scheduler.
+func (ar *AutoReviewPRHandler) ResumeJob(c echo.Context) error {
+	name := jobName(c)
+	if _, ok := ar.entry(name); !ok {
+		return jobNotFound(c, name)
+	}
+	if err := ar.scheduleCron(name, ar.jobCron(name)); err != nil {
+		log.Errorf("Failed to reschedule cron job of %s: %v", name, err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	ar.setPaused(name, false)
+	log.Infof("Resumed job %s", name)
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: "Job resumed"})
+}
+
+// UpdateJob handles PATCH /api/v1/jobs/:name with a JSON body {"cron": "..."} and reschedules the
+// job. A paused job keeps the new schedule and uses it once resumed.
+func (ar *AutoReviewPRHandler) UpdateJob(c echo.Context) error {
+	name := jobName(c)
+	if _, ok := ar.entry(name); !ok {
+		return jobNotFound(c, name)
+	}
+	var req struct {
+		Cron string `json:"cron"`
+	}
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Cron) == "" {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Body must be a JSON object with a non-empty cron",
+		})
+	}
+	cron := strings.TrimSpace(req.Cron)
+
+	paused := ar.isPaused(name)
+	err := ar.scheduleCron(name, cron)
+	if err == nil && paused {
+		// Only validated the expression; stay unscheduled until resumed.
+		err = ar.unscheduleCron(name)
+	}
+	if err != nil {
+		log.Warnf("Rejected new schedule %q for %s: %v", cron, name, err)
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Invalid cron %q: %v", cron, err),
+		})
+	}
+	log.Infof("Rescheduled job %s ==> %s", name, cron)
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Job rescheduled",
+		Data:       map[string]interface{}{"name": name, "cron": cron, "paused": paused},
+	})
+}
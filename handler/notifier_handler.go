@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// notificationEvent returns an event of kind about the pull request of run.
+func notificationEvent(kind string, run *reviewRun) model.NotificationEvent {
+	auto, pr := run.Auto, run.PR
+	return model.NotificationEvent{
+		Kind:          kind,
+		ProcessName:   auto.ProcessName,
+		Workspace:     auto.Workspace,
+		RepoSlug:      auto.RepoSlug,
+		PullRequestID: pr.ID,
+		Title:         pr.Title,
+		Author:        pr.Author.DisplayName,
+		URL:           fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d", auto.Workspace, auto.RepoSlug, pr.ID),
+		Commit:        run.LatestCommitHash,
+	}
+}
+
+// notifyReviewed sends review_completed, with the open findings by severity, when the run
+// posted a summary or findings, and critical_finding with the findings posted in this run.
+func (ar *AutoReviewPRHandler) notifyReviewed(run *reviewRun) {
+	auto, pr := run.Auto, run.PR
+	if ar.Notifier == nil || len(auto.Notifiers) == 0 || (!run.SummaryPosted && run.InlinePosted == 0) {
+		return
+	}
+	completed := notificationEvent(model.NotifyReviewCompleted, run)
+	if findings, err := ar.loadOpenFindings(run); err != nil {
+		log.Warnf("Could not count the open findings of PR #%d for notifications: %v", pr.ID, err)
+	} else {
+		completed.Severities = countSeverities(findings, func(string) bool { return true })
+	}
+	ar.Notifier.Send(auto.Notifiers, completed)
+
+	if len(run.PostedFindings) == 0 {
+		return
+	}
+	critical := notificationEvent(model.NotifyCriticalFinding, run)
+	critical.Findings = ar.notifiedFindings(run, func(string) bool { return true })
+	ar.Notifier.Send(auto.Notifiers, critical)
+}
+
+// countSeverities counts the findings on the paths keep accepts by severity, or returns nil
+// when there are none.
+func countSeverities(findings []model.Finding, keep func(path string) bool) map[string]int {
+	var counts map[string]int
+	for _, f := range findings {
+		if !keep(f.Path) {
+			continue
+		}
+		severity := strings.TrimSpace(f.Severity)
+		if severity == "" {
+			severity = "Unrated"
+		}
+		if counts == nil {
+			counts = map[string]int{}
+		}
+		counts[severity]++
+	}
+	return counts
+}
+
+// notifiedFindings returns the findings posted in this run on the paths keep accepts.
+func (ar *AutoReviewPRHandler) notifiedFindings(run *reviewRun, keep func(path string) bool) []model.NotifiedFinding {
+	auto, pr := run.Auto, run.PR
+	var findings []model.NotifiedFinding
+	for _, c := range run.PostedFindings {
+		if !keep(c.Path) {
+			continue
+		}
+		_, severity, title := helper.ParseFindingHeading(c.Body)
+		findings = append(findings, model.NotifiedFinding{
+			Severity: severity,
+			Title:    title,
+			Path:     c.Path,
+			Line:     c.Position,
+			URL:      helper.PullRequestLineURL(auto.Workspace, auto.RepoSlug, pr.ID, c.Path, c.Position),
+		})
+	}
+	return findings
+}
+
+// notifyPackages sends review_completed and critical_finding to the notifiers of the package
+// profiles of the changed files, with the open and new findings on the files of each package.
+func (ar *AutoReviewPRHandler) notifyPackages(run *reviewRun) {
+	auto, pr := run.Auto, run.PR
+	if ar.Notifier == nil || len(auto.Packages) == 0 || (!run.SummaryPosted && run.InlinePosted == 0) {
+		return
+	}
+	packages := helper.PackageFiles(auto, run.Diff)
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		if len(auto.PackageProfiles[name].Notifiers) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Warnf("Could not count the open findings of PR #%d for package notifications: %v", pr.ID, err)
+	}
+	for _, name := range names {
+		targets := auto.PackageProfiles[name].Notifiers
+		inPackage := func(path string) bool {
+			pkg, _ := helper.PackageProfileFor(auto, path)
+			return pkg == name
+		}
+		completed := notificationEvent(model.NotifyReviewCompleted, run)
+		completed.Package, completed.Files = name, packages[name]
+		completed.Severities = countSeverities(findings, inPackage)
+		ar.Notifier.Send(targets, completed)
+
+		if posted := ar.notifiedFindings(run, inPackage); len(posted) > 0 {
+			critical := notificationEvent(model.NotifyCriticalFinding, run)
+			critical.Package, critical.Findings = name, posted
+			ar.Notifier.Send(targets, critical)
+		}
+	}
+}
+
+// notifyCriticalPaths sends critical_path, with the changed files under the entry's
+// criticalPaths, when the run reviewed a commit that touches them.
+func (ar *AutoReviewPRHandler) notifyCriticalPaths(run *reviewRun) {
+	auto := run.Auto
+	if ar.Notifier == nil || len(auto.Notifiers) == 0 || len(auto.CriticalPaths) == 0 || (!run.HasNewCommits && !run.SummaryPosted && run.InlinePosted == 0) {
+		return
+	}
+	files := helper.CriticalFiles(auto.CriticalPaths, run.Diff)
+	if len(files) == 0 {
+		return
+	}
+	e := notificationEvent(model.NotifyCriticalPath, run)
+	e.Files = files
+	ar.Notifier.Send(auto.Notifiers, e)
+}
+
+// notifyAIFailure sends ai_failure when the AI provider failed during the run, whether the run
+// went on without the failed parts or stopped.
+func (ar *AutoReviewPRHandler) notifyAIFailure(run *reviewRun, err error) {
+	if ar.Notifier == nil || len(run.Auto.Notifiers) == 0 || run.AIErrors == 0 {
+		return
+	}
+	e := notificationEvent(model.NotifyAIFailure, run)
+	e.Message = fmt.Sprintf("%d AI %s failed while reviewing this pull request.", run.AIErrors, helper.Pluralize(run.AIErrors, "request", "requests"))
+	if err != nil {
+		e.Message += " The review stopped: " + err.Error()
+	} else {
+		e.Message += " The review went on without the failed parts; the next scan tries them again."
+	}
+	ar.Notifier.Send(run.Auto.Notifiers, e)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// botAccount returns the UUID of the account auto posts with, looked up once per username.
+func (ar *AutoReviewPRHandler) botAccount(auto *model.AutoReviewPR) (string, error) {
+	ar.statsMutex.Lock()
+	uuid, ok := ar.botUUIDs[auto.Username]
+	ar.statsMutex.Unlock()
+	if ok {
+		return uuid, nil
+	}
+	user, err := ar.Bitbucket.FetchCurrentUser(auto.Username, auto.AppPassword)
+	if err != nil {
+		return "", err
+	}
+	ar.statsMutex.Lock()
+	if ar.botUUIDs == nil {
+		ar.botUUIDs = map[string]string{}
+	}
+	ar.botUUIDs[auto.Username] = user.UUID
+	ar.statsMutex.Unlock()
+	return user.UUID, nil
+}
+
+// humanApprover returns the name of a participant other than the bot account who currently
+// approves pr, or "" when no human has.
+func (ar *AutoReviewPRHandler) humanApprover(auto *model.AutoReviewPR, pr *model.PullRequest) (string, error) {
+	bot, err := ar.botAccount(auto)
+	if err != nil {
+		return "", fmt.Errorf("look up the bot account: %w", err)
+	}
+	participants, err := ar.Bitbucket.FetchPullRequestParticipants(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range participants {
+		approved := p.Approved || strings.EqualFold(p.State, "approved")
+		if !approved || p.User.UUID == "" || p.User.UUID == bot {
+			continue
+		}
+		if p.User.DisplayName != "" {
+			return p.User.DisplayName, nil
+		}
+		return p.User.Nickname, nil
+	}
+	return "", nil
+}
+
+// approvalFilter makes the run summary-only when skipInlineWhenApproved is set and a human
+// reviewer approved the pull request. A failed lookup is logged and the PR reviewed as usual.
+func (ar *AutoReviewPRHandler) approvalFilter(run *reviewRun) {
+	auto, pr := run.Auto, run.PR
+	if !auto.SkipInlineWhenApproved || run.SkipInline {
+		return
+	}
+	name, err := ar.humanApprover(auto, pr)
+	if err != nil {
+		log.Warnf("Could not check the approvals of PR #%d; reviewing inline: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return
+	}
+	if name != "" {
+		log.Infof("PR #%d is approved by %s → summary-only mode", pr.ID, name)
+		run.ApprovedBy = name
+		run.SkipInline = true
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"code_nim/log"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// recoverPanic, deferred by a scheduled task, turns a panic of the task into an error in *err,
+// logs it with its stack and counts it in /metrics, so a bug hit by one review does not crash
+// the process or stop the other jobs.
+func (ar *AutoReviewPRHandler) recoverPanic(task string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e := fmt.Errorf("%s panicked: %v", task, r)
+	log.Errorf("%v\n%s", e, debug.Stack())
+	if err != nil {
+		*err = e
+	}
+	kind, _, _ := strings.Cut(task, " ")
+	ar.statsMutex.Lock()
+	if ar.taskPanics == nil {
+		ar.taskPanics = map[string]int64{}
+	}
+	ar.taskPanics[kind]++
+	ar.statsMutex.Unlock()
+}
+
+// safeTask wraps a scheduled task so a panic in it is recovered and recorded.
+func (ar *AutoReviewPRHandler) safeTask(task string, fn func()) func() {
+	return func() {
+		defer ar.recoverPanic(task, nil)
+		fn()
+	}
+}
+
+func (ar *AutoReviewPRHandler) writePanicMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_task_panics_total Panics recovered in scheduled tasks and reviews, by task.\n# TYPE code_nim_task_panics_total counter\n")
+	tasks := make([]string, 0, len(ar.taskPanics))
+	for task := range ar.taskPanics {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+	for _, task := range tasks {
+		fmt.Fprintf(b, "code_nim_task_panics_total{task=%q} %d\n", task, ar.taskPanics[task])
+	}
+}
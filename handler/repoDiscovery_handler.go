@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"strings"
+)
+
+// expandEntries replaces each entry naming several repositories or a glob with one entry per
+// repository. Globs are matched against the workspace repositories listed by Bitbucket, the
+// projects listed by Gerrit for gerrit entries or the owner's repositories for github entries; an
+// entry whose repositories cannot be listed is left out until the next start.
+func (ar *AutoReviewPRHandler) expandEntries(entries []model.AutoReviewPR) []model.AutoReviewPR {
+	var out []model.AutoReviewPR
+	for _, auto := range entries {
+		if !auto.MultiRepo() {
+			out = append(out, auto)
+			continue
+		}
+		patterns := auto.RepoPatterns()
+		name := auto.ProcessName
+		if strings.TrimSpace(name) == "" {
+			name = auto.Workspace + "/" + strings.Join(patterns, ",")
+		}
+		slugs := uniqueSlugs(patterns)
+		for _, p := range patterns {
+			if model.IsRepoGlob(p) {
+				var available []string
+				var err error
+				if auto.IsGerrit() {
+					available, err = ar.Gerrit.FetchProjects(auto.Gerrit.URL, auto.Username, auto.AppPassword)
+				} else if auto.IsGitHub() {
+					available, err = ar.GitHub.FetchRepositories(auto.GitHub.BaseURL(), auto.Workspace, auto.AppPassword)
+				} else {
+					available, err = ar.Bitbucket.FetchRepositories(auto.Workspace, auto.Username, auto.AppPassword)
+				}
+				if err != nil {
+					log.Errorf("Discovering repositories for %s failed, skipping the entry: %v", name, err)
+					slugs = nil
+				} else {
+					slugs = helper.MatchRepositories(patterns, available)
+				}
+				break
+			}
+		}
+		if len(slugs) == 0 {
+			log.Warnf("No repository of workspace %s matches %s for %s", auto.Workspace, strings.Join(patterns, ", "), name)
+			continue
+		}
+		log.Infof("Entry %s covers %d repositories: %s", name, len(slugs), strings.Join(slugs, ", "))
+		out = append(out, helper.ExpandRepoEntry(auto, slugs)...)
+	}
+	return out
+}
+
+// uniqueSlugs returns the slugs in order without case-insensitive duplicates.
+func uniqueSlugs(slugs []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range slugs {
+		if !seen[strings.ToLower(s)] {
+			seen[strings.ToLower(s)] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
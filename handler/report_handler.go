@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	Storage storage.Storage
+}
+
+// countEntry is one row of a ranked count, e.g. a category and how often it was found.
+type countEntry struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// repoReport summarises the findings of one repository.
+type repoReport struct {
+	Workspace  string       `json:"workspace"`
+	RepoSlug   string       `json:"repoSlug"`
+	Total      int          `json:"total"`
+	BySeverity []countEntry `json:"bySeverity"`
+	ByCategory []countEntry `json:"byCategory"`
+}
+
+// rankCounts sorts counts by descending count, then key.
+func rankCounts(counts map[string]int) []countEntry {
+	out := make([]countEntry, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, countEntry{Key: k, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// findingCategory returns the finding type, or "uncategorized".
+func findingCategory(f model.Finding) string {
+	if t := strings.TrimSpace(f.Type); t != "" {
+		return t
+	}
+	return "uncategorized"
+}
+
+// findingSeverity returns the finding severity, or "unknown".
+func findingSeverity(f model.Finding) string {
+	if s := strings.TrimSpace(f.Severity); s != "" {
+		return s
+	}
+	return "unknown"
+}
+
+// reportRange reads the from and to queries (YYYY-MM-DD, inclusive; default the last 30 days).
+// A non-empty message describes an invalid range.
+func reportRange(c echo.Context, now time.Time) (time.Time, time.Time, string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from, to := today.AddDate(0, 0, -29), today
+	var err error
+	if raw := c.QueryParam("from"); raw != "" {
+		if from, err = time.ParseInLocation("2006-01-02", raw, now.Location()); err != nil {
+			return from, to, "from must be a date formatted as YYYY-MM-DD"
+		}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		if to, err = time.ParseInLocation("2006-01-02", raw, now.Location()); err != nil {
+			return from, to, "to must be a date formatted as YYYY-MM-DD"
+		}
+	}
+	if to.Before(from) {
+		return from, to, "from must not be after to"
+	}
+	return from, to, ""
+}
+
+// GetReports handles GET /api/v1/reports.
+// Optional queries: workspace, repo, severity, from and to (YYYY-MM-DD, inclusive; default the last 30 days).
+func (rh *ReportHandler) GetReports(c echo.Context) error {
+	now := time.Now()
+	from, to, msg := reportRange(c, now)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    msg,
+		})
+	}
+
+	filter := model.FindingFilter{
+		Workspace: c.QueryParam("workspace"),
+		RepoSlug:  c.QueryParam("repo"),
+		Severity:  c.QueryParam("severity"),
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	}
+	findings, err := rh.Storage.ListFindings(filter)
+	if err != nil {
+		log.Errorf("Failed to list findings for report: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+
+	bySeverity := map[string]int{}
+	byCategory := map[string]int{}
+	byDay := map[string]int{}
+	deferred := 0
+	repos := map[string]*repoReport{}
+	repoSeverity := map[string]map[string]int{}
+	repoCategory := map[string]map[string]int{}
+	for _, f := range findings {
+		severity, category := findingSeverity(f), findingCategory(f)
+		bySeverity[severity]++
+		byCategory[category]++
+		byDay[f.CreatedAt.In(now.Location()).Format("2006-01-02")]++
+		if f.Deferred {
+			deferred++
+		}
+
+		key := f.Workspace + "/" + f.RepoSlug
+		r, ok := repos[key]
+		if !ok {
+			r = &repoReport{Workspace: f.Workspace, RepoSlug: f.RepoSlug}
+			repos[key] = r
+			repoSeverity[key] = map[string]int{}
+			repoCategory[key] = map[string]int{}
+		}
+		r.Total++
+		repoSeverity[key][severity]++
+		repoCategory[key][category]++
+	}
+
+	repoList := make([]repoReport, 0, len(repos))
+	for key, r := range repos {
+		r.BySeverity = rankCounts(repoSeverity[key])
+		r.ByCategory = rankCounts(repoCategory[key])
+		repoList = append(repoList, *r)
+	}
+	sort.Slice(repoList, func(i, j int) bool {
+		if repoList[i].Total != repoList[j].Total {
+			return repoList[i].Total > repoList[j].Total
+		}
+		return repoList[i].Workspace+"/"+repoList[i].RepoSlug < repoList[j].Workspace+"/"+repoList[j].RepoSlug
+	})
+
+	days := make([]countEntry, 0, len(byDay))
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		days = append(days, countEntry{Key: key, Count: byDay[key]})
+	}
+
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Findings report",
+		Data: map[string]interface{}{
+			"from":       from.Format("2006-01-02"),
+			"to":         to.Format("2006-01-02"),
+			"total":      len(findings),
+			"deferred":   deferred,
+			"bySeverity": rankCounts(bySeverity),
+			"byCategory": rankCounts(byCategory),
+			"byDay":      days,
+			"byRepo":     repoList,
+		},
+	})
+}
+
+// GetRiskScores handles GET /api/v1/reports/risk: the latest merge risk of each pull request
+// scored in the range, riskiest first, and how many got each recommendation.
+// Optional queries: workspace, repo, from and to (YYYY-MM-DD, inclusive; default the last 30 days).
+func (rh *ReportHandler) GetRiskScores(c echo.Context) error {
+	now := time.Now()
+	from, to, msg := reportRange(c, now)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    msg,
+		})
+	}
+
+	scores, err := rh.Storage.ListRiskScores(model.RiskScoreFilter{
+		Workspace: c.QueryParam("workspace"),
+		RepoSlug:  c.QueryParam("repo"),
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		log.Errorf("Failed to list risk scores: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	// Scores are listed oldest first, so the last one of a pull request is its latest
+	latest := map[string]model.RiskScore{}
+	for _, r := range scores {
+		latest[fmt.Sprintf("%s/%s#%d", r.Workspace, r.RepoSlug, r.PullRequestID)] = r
+	}
+	byRecommendation := map[string]int{}
+	list := make([]model.RiskScore, 0, len(latest))
+	for _, r := range latest {
+		byRecommendation[r.Recommendation]++
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Risk report",
+		Data: map[string]interface{}{
+			"from":             from.Format("2006-01-02"),
+			"to":               to.Format("2006-01-02"),
+			"total":            len(list),
+			"byRecommendation": rankCounts(byRecommendation),
+			"pullRequests":     list,
+		},
+	})
+}
+
+// GetFeedback handles GET /api/v1/feedback: the helpful and unhelpful replies to bot comments
+// and the resulting precision per repository and category.
+// Optional queries: workspace, repo, from and to (YYYY-MM-DD, inclusive; default the last 30 days).
+func (rh *ReportHandler) GetFeedback(c echo.Context) error {
+	now := time.Now()
+	from, to, msg := reportRange(c, now)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    msg,
+		})
+	}
+
+	list, err := rh.Storage.ListFeedback(model.FeedbackFilter{
+		Workspace: c.QueryParam("workspace"),
+		RepoSlug:  c.QueryParam("repo"),
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		log.Errorf("Failed to list feedback: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	helpful := 0
+	for _, fb := range list {
+		if fb.Verdict == model.FeedbackHelpful {
+			helpful++
+		}
+	}
+	precision := 0.0
+	if len(list) > 0 {
+		precision = float64(helpful) / float64(len(list))
+	}
+
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Feedback report",
+		Data: map[string]interface{}{
+			"from":      from.Format("2006-01-02"),
+			"to":        to.Format("2006-01-02"),
+			"helpful":   helpful,
+			"unhelpful": len(list) - helpful,
+			"precision": precision,
+			"byRepo":    summarizeFeedback(list),
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"code_nim/helper/queue"
+	"code_nim/log"
+	"fmt"
+)
+
+// reviewClaimKey identifies one commit of a pull request for claims between queue workers.
+func reviewClaimKey(run *reviewRun) string {
+	return fmt.Sprintf("%s/%s#%d@%s", run.Auto.Workspace, run.Auto.RepoSlug, run.PR.ID, run.LatestCommitHash)
+}
+
+// claimGuard makes sure only one worker of a shared queue posts the review of a commit. The
+// claim outlives the run so a worker that fetched comments before this one posted cannot post
+// again; it is released when posting fails so the next scan can retry.
+func (ar *AutoReviewPRHandler) claimGuard(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		claimer, ok := ar.Queue.(queue.Claimer)
+		if !ok || run.LatestCommitHash == "" {
+			return next.Run(run)
+		}
+		key := reviewClaimKey(run)
+		claimed, err := claimer.Claim(key, ar.queueSettings.ClaimDuration())
+		if err != nil {
+			log.Errorf("Failed to claim review of %s: %v", key, err)
+			ar.noteJobError(jobErrorAPI)
+			run.Skip = "could not claim the review from the shared queue"
+			return nil
+		}
+		if !claimed {
+			run.Skip = "another worker is reviewing this commit"
+			return nil
+		}
+		err = next.Run(run)
+		if err != nil || run.PostErr != nil {
+			if releaseErr := claimer.Release(key); releaseErr != nil {
+				log.Warnf("Failed to release review claim %s: %v", key, releaseErr)
+			}
+		}
+		return err
+	})
+}
@@ -0,0 +1,607 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/atlassian"
+	"code_nim/helper/timing"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errHaltReview stops the review of the current and all remaining pull requests of a run.
+var errHaltReview = errors.New("review halted")
+
+// errIncompleteReview reports that part of a pull request could not be reviewed or its findings posted.
+var errIncompleteReview = errors.New("review incomplete")
+
+// abortsRun reports whether err would fail the remaining pull requests of the run as well:
+// Bitbucket or the AI provider rejected the credentials or is rate limiting.
+func abortsRun(err error) bool {
+	return errors.Is(err, helper.ErrAuth) || errors.Is(err, helper.ErrRateLimited)
+}
+
+// reviewRun carries one pull request through the review pipeline. Stages read what earlier
+// stages filled in and set Skip to end the pipeline early for this pull request.
+type reviewRun struct {
+	Auto *model.AutoReviewPR
+	PR   *model.PullRequest
+
+	Comments             []model.PullRequestComment
+	GitHubReviews        []model.GitHubReview // reviews of a GitHub pull request, also in Comments
+	Commits              []model.PullRequestCommit
+	HasSummary           bool
+	HasInlineReview      bool
+	ExistingInline       map[string]bool // "path:line" of bot inline comments
+	ExistingFingerprints map[string]bool
+	SkipInline           bool // summary-only: by the entry's mode, the author ignore list or a human approval
+	HumanComments        int  // live comments without the bot marker
+	// DuplicateFindings are the IDs of later exact copies (finding marker, line and body) of bot inline findings
+	DuplicateFindings []int
+
+	LastReviewedHash string
+	SummarizedHash   string // commit of the latest summary posted through the summary-only route
+	LatestCommitHash string
+	HasNewCommits    bool
+	UseDeltaDiff     bool
+	Diff             string
+
+	SummaryPosted bool
+	InlinePosted  int
+	Posting       model.PostingResult
+	PostErr       error // first error while generating or posting the review
+	Approved      bool
+	OpenFindings  []model.Finding // open bot findings after posting; loaded on first use
+	// PostedComments are the comments of the PR after posting, loaded with OpenFindings
+	PostedComments []model.PullRequestComment
+
+	Skip    string    // reason the pipeline stopped early; empty while it is still running
+	Started time.Time // when the pipeline started, for the review status comment
+
+	// Deferred marks a skip that waits for a posting window; the pull request is not marked reviewed
+	Deferred bool
+	// ApprovedBy names the human reviewer whose approval made the run summary-only
+	ApprovedBy string
+
+	Breakdown     *timing.Breakdown              // stage and step durations of this pull request
+	Preferences   string                         // learned team preferences added to inline review prompts
+	LintFindings  map[string][]model.LintFinding // linter output by path
+	ReviewersNote string                         // "Suggested reviewers" section of the summary
+	SummaryText   string                         // AI summary generated in this run, if any
+	// PostedFindings are the inline findings posted in this run; Fixes those of them that carry a mechanical fix
+	PostedFindings []model.ReviewComment
+	Fixes          []model.ReviewComment
+	AIErrors       int // failed AI requests while reviewing this pull request
+}
+
+// reviewStage is one step of the pull request review pipeline.
+type reviewStage interface {
+	Name() string
+	Run(run *reviewRun) error
+}
+
+// stageFunc adapts a function to a reviewStage.
+type stageFunc struct {
+	name string
+	fn   func(run *reviewRun) error
+}
+
+func (s stageFunc) Name() string             { return s.name }
+func (s stageFunc) Run(run *reviewRun) error { return s.fn(run) }
+func newStage(name string, fn func(run *reviewRun) error) reviewStage {
+	return stageFunc{name: name, fn: fn}
+}
+
+// stageMiddleware wraps a stage to add behaviour around it, such as guards or instrumentation.
+type stageMiddleware func(next reviewStage) reviewStage
+
+// wrapStage returns a stage with the same name as next that runs fn instead.
+func wrapStage(next reviewStage, fn func(run *reviewRun) error) reviewStage {
+	return stageFunc{name: next.Name(), fn: fn}
+}
+
+// reviewPipeline runs its stages in order for each pull request.
+type reviewPipeline struct {
+	stages []reviewStage
+}
+
+// Use wraps the stages named in only (every stage when only is empty) with mw.
+// Middleware added later runs outermost.
+func (p *reviewPipeline) Use(mw stageMiddleware, only ...string) {
+	for i, s := range p.stages {
+		if len(only) > 0 && !containsString(only, s.Name()) {
+			continue
+		}
+		p.stages[i] = mw(s)
+	}
+}
+
+// Run executes the stages until one fails or marks the run skipped.
+func (p *reviewPipeline) Run(run *reviewRun) error {
+	for _, s := range p.stages {
+		if err := s.Run(run); err != nil {
+			return err
+		}
+		if run.Skip != "" {
+			log.Infof("PR #%d stopped at %s stage: %s", run.PR.ID, s.Name(), run.Skip)
+			return nil
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// newReviewPipeline builds the default pipeline:
+// fetch → filter → analyze → lint → reviewers → post → describe → tests → autofix → title → approve → insights → status → report → notify.
+// The lint, reviewers, describe, tests, autofix, title, insights, status and report stages only run when the entry
+// configures staticAnalysis, reviewerSuggestions, describePR, testSuggestions, autoFix, titlePolicy, codeInsights, buildStatus and statusReport; with buildStatus the commit is marked in progress before posting.
+// During a freeze window the pipeline stops before analyze and posts a freeze notice instead;
+// outside the entry's active hours it stops there too and is run again when they open.
+// With a shared queue, a worker posts only after claiming the pull request's latest commit.
+// The post stage renders and posts comments through PostSummaryComment, PostConsolidatedComment
+// and ensureInlineReviewComments, which time their own ai, parse, anchor and publish steps.
+func (ar *AutoReviewPRHandler) newReviewPipeline() *reviewPipeline {
+	p := &reviewPipeline{stages: []reviewStage{
+		newStage("fetch", ar.fetchStage),
+		newStage("filter", ar.filterStage),
+		newStage("analyze", ar.analyzeStage),
+		newStage("lint", ar.lintStage),
+		newStage("reviewers", ar.reviewersStage),
+		newStage("post", ar.postStage),
+		newStage("describe", ar.describeStage),
+		newStage("tests", ar.testsStage),
+		newStage("autofix", ar.autoFixStage),
+		newStage("title", ar.titleStage),
+		newStage("approve", ar.approveStage),
+		newStage("risk", ar.riskStage),
+		newStage("insights", ar.insightsStage),
+		newStage("status", ar.buildStatusStage),
+		newStage("report", ar.reportStage),
+		newStage("notify", ar.notifyStage),
+	}}
+	p.Use(ar.timeStage)
+	p.Use(ar.budgetGuard, "fetch")
+	p.Use(ar.freezeGuard, "analyze")
+	p.Use(ar.postingGuard, "analyze")
+	p.Use(ar.buildStatusGuard, "post")
+	p.Use(ar.claimGuard, "post")
+	return p
+}
+
+// timeStage records how long each run of a stage takes.
+func (ar *AutoReviewPRHandler) timeStage(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		defer ar.observe(run, next.Name(), time.Now())
+		return next.Run(run)
+	})
+}
+
+// budgetGuard halts the run before a stage when the daily AI budget is spent.
+func (ar *AutoReviewPRHandler) budgetGuard(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		if exceeded, reason := ar.budgetExceeded(); exceeded {
+			log.Warnf("Pausing review before PR #%d: %s", run.PR.ID, reason)
+			return errHaltReview
+		}
+		return next.Run(run)
+	})
+}
+
+// fetchStage loads the comments and commits of the pull request.
+func (ar *AutoReviewPRHandler) fetchStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	log.Infof("Starting review process for PR #%d by %s", pr.ID, pr.Author.DisplayName)
+	comments, err := ar.Bitbucket.FetchPullRequestComments(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	if err != nil {
+		log.Errorf("Error Pull Comments: %v", err)
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	run.Comments = comments
+
+	commits, err := ar.Bitbucket.FetchPullRequestCommits(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	if err != nil {
+		log.Errorf("Error fetching commits for PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+	}
+	run.Commits = commits
+	// Bitbucket lists the newest commit first
+	pr.CommitMessages = nil
+	for i := len(commits) - 1; i >= 0; i-- {
+		pr.CommitMessages = append(pr.CommitMessages, commits[i].Message)
+	}
+	return nil
+}
+
+// filterStage decides whether and how the pull request is reviewed: skip markers, the ignore
+// list, the total comment cap, LGTM pauses, human approvals, and which bot comments already exist.
+func (ar *AutoReviewPRHandler) filterStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if isAutoFixPullRequest(auto, pr) {
+		run.Skip = fmt.Sprintf("auto-fix pull request from %s", pr.Source.Branch.Name)
+		return nil
+	}
+	if marker, ok := helper.FindSkipMarker(auto.SkipMarkers, pr.Title, pr.Description); ok {
+		run.Skip = fmt.Sprintf("skip marker %q in the title or description", marker)
+		return nil
+	}
+	if comment, reason, ok := ar.findIgnoreCommand(auto, pr, run.Comments); ok {
+		ar.ignorePullRequest(auto, pr, comment, reason)
+		run.Skip = fmt.Sprintf("ignored by %s with %s", comment.User.DisplayName, model.IgnoreCommand)
+		return nil
+	}
+	if !auto.ReviewsInline() {
+		log.Debugf("Mode is %s → summary only for PR #%d", auto.Mode, pr.ID)
+		run.SkipInline = true
+	}
+	for _, displayNameConfig := range auto.IgnorePullRequestOf.DisplayNames {
+		log.Debugf("Checking if PR author '%s' matches ignore list entry '%s'", pr.Author.DisplayName, displayNameConfig)
+		if displayNameConfig == pr.Author.DisplayName {
+			log.Infof("Author is in ignore list → summary-only mode for PR #%d", pr.ID)
+			run.SkipInline = true
+			break
+		}
+	}
+
+	maxTotal := auto.MaxTotalComments
+	if maxTotal <= 0 {
+		maxTotal = 200
+	}
+	if len(run.Comments) >= maxTotal {
+		run.Skip = fmt.Sprintf("total comment limit reached (max=%d, total=%d)", maxTotal, len(run.Comments))
+		return nil
+	}
+
+	run.ExistingInline = make(map[string]bool)
+	run.ExistingFingerprints = make(map[string]bool)
+	seenFindings := map[string]bool{} // marker, line and body of live bot inline comments
+	skipAllByLGTM := false
+	for i, comment := range run.Comments {
+		log.Debugf("Check Comment of %s - %s in PR : %d - %d", comment.User.Username, comment.User.DisplayName, pr.ID, i)
+		if !comment.Deleted && !hasBotMarker(comment.Content.Raw) {
+			run.HumanComments++
+		}
+
+		// Detect an already-posted summary in general comments (not inline)
+		if comment.Inline == nil && !comment.Deleted && isSummaryComment(auto, comment.Content.Raw) {
+			run.HasSummary = true
+		}
+
+		// Findings the bot could not place on a line count as an existing inline review.
+		if comment.Inline == nil {
+			if fps := unanchoredFingerprints(comment.Content.Raw); fps != nil {
+				run.HasInlineReview = true
+				for _, fp := range fps {
+					run.ExistingFingerprints[fp] = true
+				}
+			}
+		}
+
+		// An LGTM marker of a configured reviewer pauses all bot reviews for this PR.
+		if comment.Inline == nil && !comment.Deleted && !hasBotMarker(comment.Content.Raw) && isConfiguredReviewer(auto, comment) {
+			if marker, ok := helper.FindLGTMMarker(auto.LGTMMarkers, comment.Content.Raw); ok {
+				skipAllByLGTM = true
+				log.Infof("LGTM marker %q by %s; will skip all reviews for PR #%d", marker, comment.User.DisplayName, pr.ID)
+			}
+		}
+
+		// NOTE: Do not skip inline reviews just because a human reviewer left comments.
+		// Inline review is only paused by explicit LGTM (see skipAllByLGTM).
+
+		// Detect existing inline review comments posted by the bot (to avoid duplicates).
+		// Use hidden marker to distinguish bot comments when accounts are shared.
+		if comment.Inline != nil && hasBotMarker(comment.Content.Raw) {
+			run.HasInlineReview = true
+			key := inlineKey(comment.Inline.Path, comment.Inline.To, comment.Inline.From)
+			run.ExistingInline[key] = true
+			// The finding marker identifies the finding even after its text was edited
+			hashes := helper.FindingMarkers(comment.Content.Raw)
+			for _, hash := range hashes {
+				run.ExistingFingerprints[hash] = true
+			}
+			fps := helper.FindingFingerprints(comment.Inline.Path, findingLine(comment.Inline.To, comment.Inline.From), comment.Content.Raw)
+			for _, fp := range fps {
+				run.ExistingFingerprints[fp] = true
+			}
+			// Only an exact copy (same marker, line and body) of a live finding is a duplicate
+			if len(hashes) > 0 && len(fps) > 0 && !comment.Deleted {
+				copyKey := hashes[0] + "|" + key + "|" + fps[0]
+				if seenFindings[copyKey] {
+					run.DuplicateFindings = append(run.DuplicateFindings, comment.ID)
+				}
+				seenFindings[copyKey] = true
+			}
+			log.Debugf("Found existing inline review (by bot) at %s", key)
+		}
+	}
+	if skipAllByLGTM {
+		run.Skip = "LGTM pause is active"
+		return nil
+	}
+	ar.approvalFilter(run)
+	return nil
+}
+
+// isConfiguredReviewer reports whether comment is by one of the entry's displayNames, matched
+// on display name or nickname; anyone counts when none are configured.
+func isConfiguredReviewer(auto *model.AutoReviewPR, comment model.PullRequestComment) bool {
+	if len(auto.DisplayNames) == 0 {
+		return true
+	}
+	for _, name := range auto.DisplayNames {
+		name = strings.TrimSpace(name)
+		if name != "" && (strings.EqualFold(name, comment.User.DisplayName) || strings.EqualFold(name, comment.User.Username)) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeStage works out which commits are new since the last bot review and fetches the
+// matching diff: the delta between commits when possible, else the full pull request diff.
+func (ar *AutoReviewPRHandler) analyzeStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	run.LastReviewedHash = extractLastReviewedHash(run.Comments)
+	run.SummarizedHash = extractSummarizedHash(run.Comments)
+	if len(run.Commits) > 0 {
+		// Bitbucket returns PR commits newest-first; latest is the first element.
+		run.LatestCommitHash = run.Commits[0].Hash
+		log.Debugf("PR #%d: Found %d commits, latest=%s", pr.ID, len(run.Commits), shortHash(run.LatestCommitHash))
+		for i, c := range run.Commits {
+			log.Debugf("PR #%d: commit[%d]=%s", pr.ID, i, shortHash(c.Hash))
+		}
+	} else {
+		log.Debugf("PR #%d: No commits found", pr.ID)
+	}
+	log.Debugf("PR #%d: lastReviewedHash=%s, latestCommitHash=%s", pr.ID, shortHash(run.LastReviewedHash), shortHash(run.LatestCommitHash))
+	if run.LatestCommitHash != "" {
+		if run.LastReviewedHash == "" {
+			run.HasNewCommits = true
+		} else if run.LastReviewedHash != run.LatestCommitHash {
+			run.HasNewCommits = true
+			for _, c := range run.Commits {
+				if c.Hash == run.LastReviewedHash {
+					run.UseDeltaDiff = true
+					break
+				}
+			}
+		}
+	}
+	if run.LatestCommitHash == "" {
+		log.Infof("PR #%d commit tracking unavailable; using full diff", pr.ID)
+	} else if run.HasNewCommits {
+		if run.LastReviewedHash == "" {
+			log.Infof("PR #%d has new commits; first review detected (latest %s)", pr.ID, shortHash(run.LatestCommitHash))
+		} else {
+			log.Infof("PR #%d has new commits since %s (latest %s)", pr.ID, shortHash(run.LastReviewedHash), shortHash(run.LatestCommitHash))
+		}
+	} else {
+		log.Infof("PR #%d has no new commits since last review (latest commit %s already reviewed)", pr.ID, shortHash(run.LatestCommitHash))
+	}
+
+	log.Debugf("Check Diff PR: %d", pr.ID)
+	var diff string
+	var err error
+	if run.UseDeltaDiff {
+		diff, err = ar.Bitbucket.FetchDiffBetweenCommits(auto.Workspace, auto.RepoSlug, run.LastReviewedHash, run.LatestCommitHash, auto.Username, auto.AppPassword)
+	} else {
+		diff, err = ar.Bitbucket.FetchPullRequestDiff(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+	}
+	if err != nil {
+		log.Errorf("Error fetching diff: %v", err)
+		ar.noteJobError(jobErrorAPI)
+		return err
+	}
+	if strings.TrimSpace(diff) == "" || !strings.Contains(diff, "diff --git") {
+		if run.UseDeltaDiff {
+			log.Warnf("Delta diff empty for PR #%d; falling back to full PR diff", pr.ID)
+			diff, err = ar.Bitbucket.FetchPullRequestDiff(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+			if err != nil {
+				log.Errorf("Error fetching fallback full diff: %v", err)
+				ar.noteJobError(jobErrorAPI)
+				return err
+			}
+		}
+		if strings.TrimSpace(diff) == "" {
+			run.Skip = "diff is empty after fallback"
+			return nil
+		}
+	}
+	run.Diff = diff
+	if helper.SkipsAI(auto, diff) {
+		run.Skip = "model routes leave every changed file out of AI review"
+	}
+	return nil
+}
+
+// needsSummary reports whether a summary (or consolidated comment) is posted in this run: there
+// is none yet, or new commits arrived since the last one and the summary-only route has not
+// summarized them already.
+func (run *reviewRun) needsSummary() bool {
+	if run.SummarizedHash != "" && run.SummarizedHash == run.LatestCommitHash && !strings.EqualFold(run.Auto.CommentMode, commentModeMinimal) {
+		return false
+	}
+	return !run.HasSummary || (run.HasNewCommits && run.LatestCommitHash != "")
+}
+
+// postsSummary reports whether the AI summary is posted in this run: the entry's mode includes
+// it and needsSummary.
+func (run *reviewRun) postsSummary() bool {
+	return run.Auto.PostsSummary() && run.needsSummary()
+}
+
+// postStage generates, renders and posts the summary (or consolidated comment) and inline comments.
+func (ar *AutoReviewPRHandler) postStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	ar.deleteDuplicateFindings(run)
+	needsSummary := run.needsSummary()
+	// A first-time contributor's first summary is friendlier and links the contribution guide.
+	summaryAuto, welcome := auto, ""
+	if needsSummary && run.LastReviewedHash == "" && auto.PostsSummary() {
+		if welcome = ar.firstTimeWelcome(auto, pr); welcome != "" {
+			summaryAuto = welcomeConfig(auto)
+		}
+	}
+
+	if strings.EqualFold(auto.CommentMode, commentModeMinimal) {
+		// Minimal mode: one consolidated comment (summary + findings table), no inline comments
+		if needsSummary {
+			run.SummaryPosted, run.PostErr = ar.PostConsolidatedComment(run, summaryAuto, welcome)
+		} else {
+			log.Infof("Consolidated review already exists for PR #%d, skipping", pr.ID)
+		}
+		return nil
+	}
+
+	switch {
+	case run.postsSummary():
+		run.SummaryPosted, run.PostErr = ar.PostSummaryComment(run, summaryAuto, welcome)
+	case needsSummary:
+		log.Debugf("Mode is %s → no summary for PR #%d", auto.Mode, pr.ID)
+	default:
+		log.Infof("Summary already exists for PR #%d, skipping", pr.ID)
+	}
+
+	skipInlineDueToExisting := run.HasInlineReview && !run.HasNewCommits
+	var inlineErr error
+	deferBelow := busyPRMinSeverity(auto, pr, run.HumanComments)
+	run.Posting, inlineErr = ar.ensureInlineReviewComments(run, auto, pr, run.Diff, run.ExistingInline, run.ExistingFingerprints, run.SkipInline, skipInlineDueToExisting, len(run.Comments), deferBelow, nil)
+	run.InlinePosted = run.Posting.Posted
+	if run.PostErr == nil {
+		run.PostErr = inlineErr
+	}
+	// Without a summary, a short note carries the marker of the reviewed commit
+	if !auto.PostsSummary() && run.HasNewCommits && run.PostErr == nil {
+		run.PostErr = ar.postReviewedNote(run, auto, pr, run.LatestCommitHash, run.Posting)
+	}
+	if abortsRun(run.PostErr) {
+		return run.PostErr
+	}
+	return nil
+}
+
+// notifyStage reports the outcome of the pull request review.
+func (ar *AutoReviewPRHandler) notifyStage(run *reviewRun) error {
+	log.Infof("PR #%d reviewed: summary posted=%t, inline comments posted=%d, approved=%t", run.PR.ID, run.SummaryPosted, run.InlinePosted, run.Approved)
+	ar.updateCurrentJob(func(js *model.JobStatus) {
+		js.PRsReviewed++
+		js.FindingsPosted += int64(run.InlinePosted)
+	})
+	ar.notifyReviewed(run)
+	ar.notifyCriticalPaths(run)
+	ar.notifyPackages(run)
+	return nil
+}
+
+// prRetryDelay is the wait before a pull request that failed with a Bitbucket 5xx is tried again.
+var prRetryDelay = 5 * time.Second
+
+// reviewPullRequests runs the pipeline for each pull request, pausing briefly between them.
+// A pull request failing with a 5xx is retried once; other failures skip it and are reported
+// after the rest were reviewed, except rate limits and rejected credentials, which stop the run.
+func (ar *AutoReviewPRHandler) reviewPullRequests(auto *model.AutoReviewPR, prs []model.PullRequest, prID int) error {
+	pipeline := ar.newReviewPipeline()
+	preferences := ar.learnedPreferences(auto)
+	if prID == 0 {
+		ar.forgetClosed(auto, prs)
+		ar.forgetIgnoredClosed(auto, prs)
+	}
+	reviewed, failed := 0, 0
+	var firstErr error
+	for i := range prs {
+		pr := &prs[i]
+		if prID != 0 && pr.ID != prID {
+			continue
+		}
+		if reason, ok := ar.ignoredReason(auto, pr); ok {
+			log.Debugf("Skipping PR #%d: %s", pr.ID, reason)
+			ar.notePullRequest(model.JobRunPR{ID: pr.ID, Outcome: model.PROutcomeSkipped, Reason: reason})
+			continue
+		}
+		if prID == 0 && !auto.ReviewUnchanged && ar.unchanged(auto, pr) {
+			log.Debugf("Skipping PR #%d: not updated since its last review (%s)", pr.ID, pr.UpdatedOn)
+			ar.notePullRequest(model.JobRunPR{ID: pr.ID, Outcome: model.PROutcomeUnchanged, Reason: "not updated since its last review"})
+			continue
+		}
+		log.Infof("Processing PR #%d: '%s' by %s", pr.ID, pr.Title, pr.Author.DisplayName)
+
+		// Add small delay between PRs to reduce API load and prevent rate limiting
+		if reviewed > 0 {
+			time.Sleep(2 * time.Second)
+			log.Debugf("Added delay before processing PR #%d", pr.ID)
+		}
+		reviewed++
+
+		run, err := ar.runPipeline(pipeline, auto, pr, preferences)
+		if atlassian.IsServerError(err) {
+			// Only fetch and analyze fail with Bitbucket errors, before anything is posted
+			log.Warnf("PR #%d: %v; retrying once in %s", pr.ID, err, prRetryDelay)
+			time.Sleep(prRetryDelay)
+			run, err = ar.runPipeline(pipeline, auto, pr, preferences)
+		}
+		ar.notePullRequest(pullRequestOutcome(run, err))
+		switch {
+		case err == nil:
+		case errors.Is(err, errHaltReview):
+			return nil
+		case abortsRun(err):
+			log.Errorf("Stopping the review of %s/%s at PR #%d: %v", auto.Workspace, auto.RepoSlug, pr.ID, err)
+			return err
+		case errors.Is(err, helper.ErrNotFound):
+			log.Warnf("Skipping PR #%d: %v (declined or deleted during the run?)", pr.ID, err)
+		default:
+			log.Errorf("Skipping PR #%d after an error: %v", pr.ID, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pull requests failed: %w", failed, reviewed, firstErr)
+	}
+	return nil
+}
+
+// runPipeline reviews one pull request, adding preferences to its inline review prompts. A pull
+// request reviewed without errors is remembered, so it is skipped until it is updated again. The
+// run is returned for the job history.
+func (ar *AutoReviewPRHandler) runPipeline(pipeline *reviewPipeline, auto *model.AutoReviewPR, pr *model.PullRequest, preferences string) (run *reviewRun, err error) {
+	run = &reviewRun{Auto: auto, PR: pr, Started: time.Now(), Breakdown: timing.NewBreakdown(), Preferences: preferences}
+	defer func() { log.Infof("PR #%d timings: %s", pr.ID, run.Breakdown) }()
+	// A panic fails only this pull request; it is not marked reviewed, so the next scan retries it
+	defer ar.recoverPanic(fmt.Sprintf("review of PR #%d", pr.ID), &err)
+	err = pipeline.Run(run)
+	if err == nil && run.PostErr == nil && !run.Deferred {
+		ar.markReviewed(auto, pr)
+	}
+	ar.notifyAIFailure(run, err)
+	return run, err
+}
+
+// pullRequestOutcome describes what a pipeline run did with its pull request for the job history.
+func pullRequestOutcome(run *reviewRun, err error) model.JobRunPR {
+	out := model.JobRunPR{ID: run.PR.ID, Outcome: model.PROutcomeReviewed, SummaryPosted: run.SummaryPosted, FindingsPosted: run.InlinePosted}
+	switch {
+	case errors.Is(err, errHaltReview):
+		out.Outcome, out.Reason = model.PROutcomeSkipped, "AI budget exhausted for today"
+	case err != nil:
+		out.Outcome, out.Reason = model.PROutcomeFailed, err.Error()
+	case run.Skip != "":
+		out.Outcome, out.Reason = model.PROutcomeSkipped, run.Skip
+	case run.PostErr != nil:
+		out.Outcome, out.Reason = model.PROutcomeFailed, run.PostErr.Error()
+	}
+	return out
+}
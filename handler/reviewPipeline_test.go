@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"code_nim/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPipelineKeepsStateOfEachPullRequest(t *testing.T) {
+	ar := &AutoReviewPRHandler{}
+	auto := &model.AutoReviewPR{Workspace: "acme", RepoSlug: "api"}
+	prompts := map[int]string{}
+	pipeline := &reviewPipeline{stages: []reviewStage{
+		newStage("lint", func(run *reviewRun) error {
+			// Only the first pull request has linter findings, a posted fix and a failed AI request
+			if run.PR.ID == 1 {
+				run.LintFindings = map[string][]model.LintFinding{"main.go": {{Linter: "vet", Path: "main.go", Line: 3, Message: "unreachable code"}}}
+				run.SummaryText = "Adds a handler."
+				run.Fixes = append(run.Fixes, model.ReviewComment{Path: "main.go", Fix: "return nil"})
+				ar.noteAIError(run)
+			}
+			return nil
+		}),
+		newStage("post", func(run *reviewRun) error {
+			if r := ar.reviewer(auto, run); r.FileContext != nil {
+				prompts[run.PR.ID] = r.FileContext("main.go")
+			}
+			ar.observeDuration(run, "ai", time.Second)
+			return nil
+		}),
+	}}
+
+	first, err := ar.runPipeline(pipeline, auto, &model.PullRequest{ID: 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ar.runPipeline(pipeline, auto, &model.PullRequest{ID: 2}, "\nTeam preferences: wrap errors.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(prompts[1], "unreachable code") {
+		t.Errorf("prompt of PR #1 = %q, want its linter findings", prompts[1])
+	}
+	if strings.Contains(prompts[2], "unreachable code") || !strings.Contains(prompts[2], "wrap errors") {
+		t.Errorf("prompt of PR #2 = %q, want only the preferences", prompts[2])
+	}
+	if first.AIErrors != 1 || second.AIErrors != 0 {
+		t.Errorf("AI errors = %d and %d, want 1 and 0", first.AIErrors, second.AIErrors)
+	}
+	if second.SummaryText != "" || len(second.Fixes) != 0 || second.LintFindings != nil {
+		t.Errorf("PR #2 inherited state of PR #1: %+v", second)
+	}
+	if got := second.Breakdown.String(); got != first.Breakdown.String() || !strings.Contains(got, "ai") {
+		t.Errorf("timings = %q and %q, want one ai step each", first.Breakdown, got)
+	}
+}
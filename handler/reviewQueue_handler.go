@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/httpcache"
+	"code_nim/helper/queue"
+	"code_nim/helper/timing"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// entryKey identifies a config entry: its processName, or workspace/repoSlug when unnamed.
+func entryKey(auto model.AutoReviewPR) string {
+	if strings.TrimSpace(auto.ProcessName) != "" {
+		return auto.ProcessName
+	}
+	return auto.Workspace + "/" + auto.RepoSlug
+}
+
+// scheduleReview is the cron entry point: it enqueues a low-priority scan of the repo,
+// or runs it directly when no queue is configured. Standby and worker-only replicas skip it.
+func (ar *AutoReviewPRHandler) scheduleReview(auto model.AutoReviewPR) {
+	if !ar.queueSettings.Schedules() {
+		log.Debugf("Skipping scheduled review for %s: this replica is a queue worker", entryKey(auto))
+		return
+	}
+	if !ar.Leader.IsLeader() {
+		log.Debugf("Skipping scheduled review for %s: this replica is not the leader", entryKey(auto))
+		return
+	}
+	if ar.isPaused(entryKey(auto)) {
+		log.Infof("Skipping scheduled review for %s: job is paused", entryKey(auto))
+		return
+	}
+	if ar.Queue == nil {
+		_ = ar.reviewTask(auto, 0)
+		return
+	}
+	pos, err := ar.Queue.Push(model.ReviewJob{
+		ProcessName: entryKey(auto),
+		Priority:    model.PriorityLow,
+		Source:      "cron",
+	})
+	if err != nil {
+		log.Warnf("Scheduled review for %s/%s not queued: %v", auto.Workspace, auto.RepoSlug, err)
+		return
+	}
+	log.Infof("Queued scheduled review for %s/%s at position %d", auto.Workspace, auto.RepoSlug, pos)
+}
+
+// runWorker consumes review jobs one at a time, preserving the single-review-at-a-time guarantee.
+func (ar *AutoReviewPRHandler) runWorker() {
+	for {
+		job, ok := ar.Queue.Pop(nil)
+		if !ok {
+			return
+		}
+		auto, found := ar.entry(job.ProcessName)
+		if !found {
+			log.Warnf("Dropping review job %s: unknown config entry %q", job.ID, job.ProcessName)
+			continue
+		}
+		log.Infof("Running %s review job %s for %s (PR %d)", job.Source, job.ID, job.ProcessName, job.PullRequestID)
+		_ = ar.reviewTask(auto, job.PullRequestID)
+	}
+}
+
+// bitbucketWebhookPayload is the subset of Bitbucket pull request events we use.
+type bitbucketWebhookPayload struct {
+	PullRequest struct {
+		ID        int    `json:"id"`
+		State     string `json:"state"`
+		UpdatedOn string `json:"updated_on"` // RFC 3339; the time of the change the event reports
+	} `json:"pullrequest"`
+	Repository struct {
+		FullName string `json:"full_name"` // "workspace/repo-slug"
+	} `json:"repository"`
+}
+
+// BitbucketWebhook handles POST /webhook/bitbucket for pullrequest:created/updated events.
+// Deliveries must carry a valid HMAC signature when the repository has a webhookSecret, and
+// replayed or stale deliveries are rejected. It enqueues a high-priority review and answers
+// 202 with the queue position, or 503 with Retry-After when the queue is full.
+func (ar *AutoReviewPRHandler) BitbucketWebhook(c echo.Context) error {
+	event := c.Request().Header.Get("X-Event-Key")
+	if event != "" && event != "pullrequest:created" && event != "pullrequest:updated" {
+		return c.JSON(http.StatusOK, model.Response{
+			StatusCode: http.StatusOK,
+			Message:    fmt.Sprintf("Event %s ignored", event),
+		})
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBody+1))
+	if err != nil || len(body) > maxWebhookBody {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid pull request webhook payload",
+		})
+	}
+	var payload bitbucketWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.PullRequest.ID == 0 || payload.Repository.FullName == "" {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid pull request webhook payload",
+		})
+	}
+	if status, reason := ar.verifyWebhook(c.Request().Header, body, payload); status != 0 {
+		log.Warnf("Rejecting webhook for %s PR #%d from %s: %s", payload.Repository.FullName, payload.PullRequest.ID, c.RealIP(), reason)
+		ar.noteWebhookRejected(reason)
+		return c.JSON(status, model.Response{
+			StatusCode: status,
+			Message:    fmt.Sprintf("Webhook rejected: %s", reason),
+		})
+	}
+	// A delivery that is not queued may be sent again; its retry is not a replay
+	accepted := false
+	defer func() {
+		if !accepted {
+			ar.forgetDelivery(webhookDeliveryKey(c.Request().Header, body))
+		}
+	}()
+	if ar.Queue == nil {
+		return c.JSON(http.StatusServiceUnavailable, model.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "Review queue is not enabled",
+		})
+	}
+
+	var queued []map[string]interface{}
+	var paused []string
+	for key, auto := range ar.entrySnapshot() {
+		if !strings.EqualFold(auto.Workspace+"/"+auto.RepoSlug, payload.Repository.FullName) {
+			continue
+		}
+		if ar.isPaused(key) {
+			log.Infof("Ignoring webhook for %s PR #%d: job %s is paused", payload.Repository.FullName, payload.PullRequest.ID, key)
+			paused = append(paused, key)
+			continue
+		}
+		pos, err := ar.Queue.Push(model.ReviewJob{
+			ProcessName:   key,
+			PullRequestID: payload.PullRequest.ID,
+			Priority:      model.PriorityHigh,
+			Source:        "webhook",
+		})
+		if errors.Is(err, queue.ErrFull) {
+			log.Warnf("Rejecting webhook for %s PR #%d: %v", payload.Repository.FullName, payload.PullRequest.ID, err)
+			c.Response().Header().Set("Retry-After", "60")
+			return c.JSON(http.StatusServiceUnavailable, model.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Message:    "Review queue is full, retry later",
+			})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, model.Response{
+				StatusCode: http.StatusInternalServerError,
+				Message:    err.Error(),
+			})
+		}
+		log.Infof("Queued webhook review for %s PR #%d at position %d", payload.Repository.FullName, payload.PullRequest.ID, pos)
+		queued = append(queued, map[string]interface{}{"processName": key, "queuePosition": pos})
+	}
+	if len(queued) == 0 && len(paused) > 0 {
+		sort.Strings(paused)
+		return c.JSON(http.StatusOK, model.Response{
+			StatusCode: http.StatusOK,
+			Message:    "ignored: paused",
+			Data:       map[string]interface{}{"paused": paused},
+		})
+	}
+	if len(queued) == 0 {
+		return c.JSON(http.StatusNotFound, model.Response{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("No review configured for %s", payload.Repository.FullName),
+		})
+	}
+	accepted = true
+	return c.JSON(http.StatusAccepted, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    "Review queued",
+		Data:       queued,
+	})
+}
+
+// Metrics handles GET /metrics and exposes queue saturation, AI usage, stage timings, placement
+// rejections and feedback in Prometheus text format.
+func (ar *AutoReviewPRHandler) Metrics(c echo.Context) error {
+	var b strings.Builder
+	if ar.Usage != nil {
+		writeUsageMetrics(&b, ar.Usage)
+	}
+	if ar.Timings != nil {
+		writeTimingMetrics(&b, ar.Timings)
+	}
+	ar.writePlacementMetrics(&b)
+	ar.writePostingMetrics(&b)
+	ar.writeHTTPCacheMetrics(&b)
+	writeJSONRepairMetrics(&b)
+	writeAIStreamMetrics(&b)
+	ar.writeAICacheMetrics(&b)
+	ar.writeAIRateLimitMetrics(&b)
+	ar.writeSecretMetrics(&b)
+	ar.writePanicMetrics(&b)
+	ar.writeChangeDetectionMetrics(&b)
+	ar.writeDeferredMetrics(&b)
+	ar.writeFeedbackMetrics(&b)
+	ar.writeWebhookMetrics(&b)
+	ar.writeSelfTestMetrics(&b)
+	if ar.Benchmark != nil {
+		ar.Benchmark.WriteMetrics(&b)
+	}
+	writeSuppressedMetrics(&b, ar.Notifications.Suppressed())
+	if ar.Leader != nil {
+		b.WriteString("# HELP code_nim_leader Whether this replica is the leader and runs scheduled reviews.\n# TYPE code_nim_leader gauge\n")
+		leading := 0
+		if ar.Leader.IsLeader() {
+			leading = 1
+		}
+		fmt.Fprintf(&b, "code_nim_leader{identity=%q} %d\n", ar.Leader.Identity(), leading)
+	}
+	if ar.Queue == nil {
+		return c.Blob(http.StatusOK, "text/plain; version=0.0.4", []byte(b.String()))
+	}
+	st := ar.Queue.Stats()
+	b.WriteString("# HELP code_nim_queue_depth Review jobs waiting in the queue.\n# TYPE code_nim_queue_depth gauge\n")
+	fmt.Fprintf(&b, "code_nim_queue_depth{priority=\"high\"} %d\n", st.DepthHigh)
+	fmt.Fprintf(&b, "code_nim_queue_depth{priority=\"low\"} %d\n", st.DepthLow)
+	b.WriteString("# HELP code_nim_queue_threshold Depth at which low-priority jobs are shed.\n# TYPE code_nim_queue_threshold gauge\n")
+	fmt.Fprintf(&b, "code_nim_queue_threshold %d\n", st.Threshold)
+	b.WriteString("# HELP code_nim_queue_capacity Maximum number of queued jobs.\n# TYPE code_nim_queue_capacity gauge\n")
+	fmt.Fprintf(&b, "code_nim_queue_capacity %d\n", st.Capacity)
+	b.WriteString("# HELP code_nim_queue_saturation Queue depth divided by the shedding threshold.\n# TYPE code_nim_queue_saturation gauge\n")
+	fmt.Fprintf(&b, "code_nim_queue_saturation %g\n", st.Saturation())
+	b.WriteString("# HELP code_nim_queue_enqueued_total Review jobs accepted into the queue.\n# TYPE code_nim_queue_enqueued_total counter\n")
+	for _, p := range []string{model.PriorityHigh, model.PriorityLow} {
+		fmt.Fprintf(&b, "code_nim_queue_enqueued_total{priority=%q} %d\n", p, st.Enqueued[p])
+	}
+	b.WriteString("# HELP code_nim_queue_shed_total Review jobs dropped because the queue was saturated.\n# TYPE code_nim_queue_shed_total counter\n")
+	for _, p := range []string{model.PriorityHigh, model.PriorityLow} {
+		fmt.Fprintf(&b, "code_nim_queue_shed_total{priority=%q} %d\n", p, st.Shed[p])
+	}
+	b.WriteString("# HELP code_nim_queue_rejected_total Webhook jobs rejected because the queue was full.\n# TYPE code_nim_queue_rejected_total counter\n")
+	fmt.Fprintf(&b, "code_nim_queue_rejected_total %d\n", st.Rejected)
+	b.WriteString("# HELP code_nim_queue_processed_total Review jobs handed to the worker.\n# TYPE code_nim_queue_processed_total counter\n")
+	fmt.Fprintf(&b, "code_nim_queue_processed_total %d\n", st.Processed)
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4", []byte(b.String()))
+}
+
+// writeTimingMetrics appends per-stage review durations as a Prometheus summary.
+func writeTimingMetrics(b *strings.Builder, r *timing.Recorder) {
+	snapshot := r.Snapshot()
+	stages := make([]string, 0, len(snapshot))
+	for stage := range snapshot {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	b.WriteString("# HELP code_nim_stage_duration_seconds Time spent per review stage (pipeline stages and ai/parse/anchor/publish steps).\n# TYPE code_nim_stage_duration_seconds summary\n")
+	for _, stage := range stages {
+		s := snapshot[stage]
+		fmt.Fprintf(b, "code_nim_stage_duration_seconds{stage=%q,quantile=\"0.5\"} %g\n", stage, s.P50.Seconds())
+		fmt.Fprintf(b, "code_nim_stage_duration_seconds{stage=%q,quantile=\"0.9\"} %g\n", stage, s.P90.Seconds())
+		fmt.Fprintf(b, "code_nim_stage_duration_seconds{stage=%q,quantile=\"0.99\"} %g\n", stage, s.P99.Seconds())
+		fmt.Fprintf(b, "code_nim_stage_duration_seconds_sum{stage=%q} %g\n", stage, s.Sum.Seconds())
+		fmt.Fprintf(b, "code_nim_stage_duration_seconds_count{stage=%q} %d\n", stage, s.Count)
+	}
+}
+
+// writePlacementMetrics appends the count of rejected inline comment placements by reason.
+func (ar *AutoReviewPRHandler) writePlacementMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_placement_rejected_total Inline comments whose placement failed validation and fell back to the unanchored findings comment.\n# TYPE code_nim_placement_rejected_total counter\n")
+	for _, reason := range []string{helper.PlacementOutOfRange, helper.PlacementDeletedLine, helper.PlacementOutsideHunk, helper.PlacementAnchorMissing} {
+		fmt.Fprintf(b, "code_nim_placement_rejected_total{reason=%q} %d\n", reason, ar.placementRejected[reason])
+	}
+}
+
+// writePostingMetrics appends the outcomes of inline comment posting and the retries after 5xx responses.
+func (ar *AutoReviewPRHandler) writePostingMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	t := ar.postingTotals
+	b.WriteString("# HELP code_nim_inline_comments_total Inline comments by posting result.\n# TYPE code_nim_inline_comments_total counter\n")
+	fmt.Fprintf(b, "code_nim_inline_comments_total{result=\"posted\"} %d\n", t.Posted)
+	fmt.Fprintf(b, "code_nim_inline_comments_total{result=\"failed\"} %d\n", t.Failed)
+	fmt.Fprintf(b, "code_nim_inline_comments_total{result=\"skipped\"} %d\n", t.Skipped)
+	b.WriteString("# HELP code_nim_inline_post_retries_total Inline comment posts sent again after a 5xx response.\n# TYPE code_nim_inline_post_retries_total counter\n")
+	fmt.Fprintf(b, "code_nim_inline_post_retries_total %d\n", t.Retries)
+}
+
+func (ar *AutoReviewPRHandler) writeHTTPCacheMetrics(b *strings.Builder) {
+	if ar.HTTPCache == nil {
+		return
+	}
+	stats := ar.HTTPCache.Stats()
+	b.WriteString("# HELP code_nim_bitbucket_cache_total Cacheable Bitbucket requests by cache result.\n# TYPE code_nim_bitbucket_cache_total counter\n")
+	for _, result := range []string{httpcache.ResultHit, httpcache.ResultRevalidated, httpcache.ResultMiss} {
+		fmt.Fprintf(b, "code_nim_bitbucket_cache_total{result=%q} %d\n", result, stats[result])
+	}
+}
+
+func writeJSONRepairMetrics(b *strings.Builder) {
+	stats := helper.JSONRepairStats()
+	b.WriteString("# HELP code_nim_ai_json_repairs_total Unparsable AI review replies by outcome of the JSON repair pass.\n# TYPE code_nim_ai_json_repairs_total counter\n")
+	for _, result := range []string{helper.JSONRepairRepaired, helper.JSONRepairFailed} {
+		fmt.Fprintf(b, "code_nim_ai_json_repairs_total{result=%q} %d\n", result, stats[result])
+	}
+}
+
+// writeAIStreamMetrics appends the streamed review replies by outcome.
+func writeAIStreamMetrics(b *strings.Builder) {
+	stats := helper.AIStreamStats()
+	b.WriteString("# HELP code_nim_ai_streams_total Streamed AI review replies by outcome; aborted replies left the JSON format and were stopped early.\n# TYPE code_nim_ai_streams_total counter\n")
+	for _, result := range []string{helper.AIStreamComplete, helper.AIStreamAborted, helper.AIStreamTruncated} {
+		fmt.Fprintf(b, "code_nim_ai_streams_total{result=%q} %d\n", result, stats[result])
+	}
+}
+
+// maxWebhookBody caps the size of a webhook delivery.
+const maxWebhookBody = 10 << 20
+
+// Reasons a webhook delivery is rejected, used in logs, responses and metrics.
+const (
+	webhookUnsigned         = "unsigned"
+	webhookInvalidSignature = "invalid_signature"
+	webhookReplayed         = "replayed"
+	webhookStale            = "stale"
+)
+
+// verifyWebhook checks the signature, age and replay of a webhook delivery. Once any entry has
+// a webhookSecret (or webhook.requireSignature is set) every delivery must be signed: with a
+// secret of the repository it names, or with any configured secret when that repository has
+// none. It returns the HTTP status and reason to reject it with, or 0 when the delivery is accepted.
+func (ar *AutoReviewPRHandler) verifyWebhook(header http.Header, body []byte, payload bitbucketWebhookPayload) (int, string) {
+	var repoSecrets, allSecrets []string
+	for _, auto := range ar.entrySnapshot() {
+		if auto.WebhookSecret == "" {
+			continue
+		}
+		allSecrets = append(allSecrets, auto.WebhookSecret)
+		if strings.EqualFold(auto.Workspace+"/"+auto.RepoSlug, payload.Repository.FullName) {
+			repoSecrets = append(repoSecrets, auto.WebhookSecret)
+		}
+	}
+	signature := webhookSignature(header)
+	if len(allSecrets) > 0 || ar.Webhook.RequireSignature {
+		if signature == "" {
+			return http.StatusUnauthorized, webhookUnsigned
+		}
+		secrets := repoSecrets
+		if len(secrets) == 0 {
+			secrets = allSecrets
+		}
+		if !helper.VerifyWebhookSignature(body, signature, secrets) {
+			return http.StatusUnauthorized, webhookInvalidSignature
+		}
+	}
+
+	maxAge, ok := ar.Webhook.MaxAgeDuration()
+	if !ok {
+		log.Warnf("Invalid webhook.maxAge %q; using %v", ar.Webhook.MaxAge, maxAge)
+	}
+	if maxAge > 0 {
+		if updated, err := time.Parse(time.RFC3339Nano, payload.PullRequest.UpdatedOn); err == nil && time.Since(updated) > maxAge {
+			return http.StatusBadRequest, webhookStale
+		}
+	}
+
+	if ar.seenDelivery(webhookDeliveryKey(header, body), maxAge) {
+		return http.StatusConflict, webhookReplayed
+	}
+	return 0, ""
+}
+
+// webhookSignature returns the signature header of a delivery, or "" when it is unsigned.
+func webhookSignature(header http.Header) string {
+	for _, h := range helper.WebhookSignatureHeaders {
+		if signature := header.Get(h); signature != "" {
+			return signature
+		}
+	}
+	return ""
+}
+
+// webhookDeliveryKey identifies a delivery for replay detection by what its signature covers:
+// the body, and the signature itself. Delivery ID headers are not signed, so a replay could
+// simply change them.
+func webhookDeliveryKey(header http.Header, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(webhookSignature(header)))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// forgetDelivery drops a delivery key so that a retry of a delivery we could not accept is not
+// rejected as a replay.
+func (ar *AutoReviewPRHandler) forgetDelivery(id string) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	delete(ar.deliveries, id)
+}
+
+// seenDelivery records a delivery key and reports whether it was already seen. Keys are kept for
+// maxAge (an hour when the age check is disabled); older deliveries are rejected as stale anyway.
+func (ar *AutoReviewPRHandler) seenDelivery(id string, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		maxAge = time.Hour
+	}
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	now := time.Now()
+	if ar.deliveries == nil {
+		ar.deliveries = map[string]time.Time{}
+	}
+	for d, at := range ar.deliveries {
+		if now.Sub(at) > maxAge {
+			delete(ar.deliveries, d)
+		}
+	}
+	if _, ok := ar.deliveries[id]; ok {
+		return true
+	}
+	ar.deliveries[id] = now
+	return false
+}
+
+func (ar *AutoReviewPRHandler) noteWebhookRejected(reason string) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.webhookRejected == nil {
+		ar.webhookRejected = map[string]int64{}
+	}
+	ar.webhookRejected[reason]++
+}
+
+// writeWebhookMetrics appends the count of rejected webhook deliveries by reason.
+func (ar *AutoReviewPRHandler) writeWebhookMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_webhook_rejected_total Webhook deliveries rejected by signature, replay or age checks.\n# TYPE code_nim_webhook_rejected_total counter\n")
+	for _, reason := range []string{webhookUnsigned, webhookInvalidSignature, webhookReplayed, webhookStale} {
+		fmt.Fprintf(b, "code_nim_webhook_rejected_total{reason=%q} %d\n", reason, ar.webhookRejected[reason])
+	}
+}
+
+// writeSuppressedMetrics appends the count of duplicate notifications suppressed per channel.
+func writeSuppressedMetrics(b *strings.Builder, suppressed map[string]int64) {
+	b.WriteString("# HELP code_nim_notifications_suppressed_total Notifications not sent again because they were already delivered.\n# TYPE code_nim_notifications_suppressed_total counter\n")
+	fmt.Fprintf(b, "code_nim_notifications_suppressed_total{channel=%q} %d\n", notifyChannelBitbucket, suppressed[notifyChannelBitbucket])
+	channels := make([]string, 0, len(suppressed))
+	for channel := range suppressed {
+		if channel != notifyChannelBitbucket {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		fmt.Fprintf(b, "code_nim_notifications_suppressed_total{channel=%q} %d\n", channel, suppressed[channel])
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"code_nim/helper/queue"
+	"code_nim/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBody(repo string, prID int) string {
+	return fmt.Sprintf(`{"pullrequest":{"id":%d,"state":"OPEN","updated_on":%q},"repository":{"full_name":%q}}`,
+		prID, time.Now().UTC().Format(time.RFC3339), repo)
+}
+
+// delivery is one webhook call and the status it must get.
+type delivery struct {
+	body      string
+	signature string
+	uuid      string
+	want      int
+}
+
+func TestBitbucketWebhookVerification(t *testing.T) {
+	signed := webhookBody("acme/api", 1)
+	other := webhookBody("acme/web", 2)
+	tests := []struct {
+		name       string
+		entries    []model.AutoReviewPR
+		queue      bool
+		paused     string // key of a paused job
+		deliveries []delivery
+	}{
+		{
+			name:       "valid signature",
+			entries:    []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:      true,
+			deliveries: []delivery{{body: signed, signature: sign("s3cret", signed), want: http.StatusAccepted}},
+		},
+		{
+			name:       "signature with another secret",
+			entries:    []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:      true,
+			deliveries: []delivery{{body: signed, signature: sign("guess", signed), want: http.StatusUnauthorized}},
+		},
+		{
+			name:       "signature of another body",
+			entries:    []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:      true,
+			deliveries: []delivery{{body: signed, signature: sign("s3cret", other), want: http.StatusUnauthorized}},
+		},
+		{
+			name:       "missing signature",
+			entries:    []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:      true,
+			deliveries: []delivery{{body: signed, want: http.StatusUnauthorized}},
+		},
+		{
+			name: "missing signature for a repository without a secret",
+			entries: []model.AutoReviewPR{
+				{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"},
+				{Workspace: "acme", RepoSlug: "web"},
+			},
+			queue:      true,
+			deliveries: []delivery{{body: other, want: http.StatusUnauthorized}},
+		},
+		{
+			name:       "no secret configured",
+			entries:    []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api"}},
+			queue:      true,
+			deliveries: []delivery{{body: signed, want: http.StatusAccepted}},
+		},
+		{
+			name:    "replay",
+			entries: []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:   true,
+			deliveries: []delivery{
+				{body: signed, signature: sign("s3cret", signed), uuid: "{1}", want: http.StatusAccepted},
+				{body: signed, signature: sign("s3cret", signed), uuid: "{1}", want: http.StatusConflict},
+			},
+		},
+		{
+			name:    "replay with a new delivery ID",
+			entries: []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			queue:   true,
+			deliveries: []delivery{
+				{body: signed, signature: sign("s3cret", signed), uuid: "{1}", want: http.StatusAccepted},
+				{body: signed, signature: sign("s3cret", signed), uuid: "{2}", want: http.StatusConflict},
+			},
+		},
+		{
+			name:    "paused job",
+			entries: []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api"}},
+			queue:   true,
+			paused:  "acme/api",
+			deliveries: []delivery{
+				{body: signed, want: http.StatusOK},
+				{body: signed, want: http.StatusOK},
+			},
+		},
+		{
+			name:    "retry after the queue was unavailable",
+			entries: []model.AutoReviewPR{{Workspace: "acme", RepoSlug: "api", WebhookSecret: "s3cret"}},
+			deliveries: []delivery{
+				{body: signed, signature: sign("s3cret", signed), uuid: "{1}", want: http.StatusServiceUnavailable},
+				{body: signed, signature: sign("s3cret", signed), uuid: "{1}", want: http.StatusServiceUnavailable},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := &AutoReviewPRHandler{entries: map[string]model.AutoReviewPR{}}
+			for _, auto := range tt.entries {
+				ar.entries[entryKey(auto)] = auto
+			}
+			if tt.queue {
+				ar.Queue = queue.New(model.QueueSettings{})
+			}
+			if tt.paused != "" {
+				ar.jobs = map[string]*model.JobStatus{tt.paused: {Name: tt.paused, Paused: true}}
+			}
+			e := echo.New()
+			for i, d := range tt.deliveries {
+				req := httptest.NewRequest(http.MethodPost, "/webhook/bitbucket", strings.NewReader(d.body))
+				req.Header.Set("X-Event-Key", "pullrequest:updated")
+				if d.signature != "" {
+					req.Header.Set("X-Hub-Signature", d.signature)
+				}
+				if d.uuid != "" {
+					req.Header.Set("X-Request-UUID", d.uuid)
+				}
+				rec := httptest.NewRecorder()
+				if err := ar.BitbucketWebhook(e.NewContext(req, rec)); err != nil {
+					t.Fatal(err)
+				}
+				if rec.Code != d.want {
+					t.Errorf("delivery %d: status %d, want %d: %s", i+1, rec.Code, d.want, rec.Body.String())
+				}
+			}
+			if tt.paused != "" && ar.Queue.Stats().DepthHigh != 0 {
+				t.Errorf("queued %d reviews of a paused job", ar.Queue.Stats().DepthHigh)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultSuggestedReviewers is how many reviewers are suggested when reviewerSuggestions.max is unset.
+	defaultSuggestedReviewers = 3
+	// historyFiles and historyCommits bound the history lookups for changed files without owners.
+	historyFiles   = 10
+	historyCommits = 10
+)
+
+// loadCodeowners reads the CODEOWNERS rules at commit: codeownersPath when set, else the first
+// of helper.CodeownersPaths that exists. It returns nil when there is no CODEOWNERS file.
+func (ar *AutoReviewPRHandler) loadCodeowners(auto *model.AutoReviewPR, commit string) []helper.CodeownersRule {
+	paths := helper.CodeownersPaths
+	if p := strings.TrimSpace(auto.ReviewerSuggestions.CodeownersPath); p != "" {
+		paths = []string{p}
+	}
+	for _, p := range paths {
+		content, found, err := ar.Bitbucket.FetchFileContent(auto.Workspace, auto.RepoSlug, commit, p, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching %s of %s/%s: %v", p, auto.Workspace, auto.RepoSlug, err)
+			ar.noteJobError(jobErrorAPI)
+			return nil
+		}
+		if found {
+			log.Debugf("Using code owners from %s at %s", p, shortHash(commit))
+			return helper.ParseCodeowners(content)
+		}
+	}
+	return nil
+}
+
+// suggestReviewers ranks reviewers for the files of the reviewed diff: code owners first, by
+// the number of changed files they own, then the authors of recent commits on changed files
+// that have no owner, by commit count. The PR author is never suggested.
+func (ar *AutoReviewPRHandler) suggestReviewers(run *reviewRun) []model.SuggestedReviewer {
+	auto, pr := run.Auto, run.PR
+	commit := pr.Destination.Commit.Hash
+	if commit == "" {
+		log.Debugf("PR #%d: target commit unknown; not suggesting reviewers", pr.ID)
+		return nil
+	}
+	var paths []string
+	for _, file := range helper.ParseDiff(run.Diff) {
+		if p, _ := file["path"].(string); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	author := model.BitbucketUser{DisplayName: pr.Author.DisplayName, UUID: pr.Author.UUID, AccountID: pr.Author.AccountID}
+
+	rules := ar.loadCodeowners(auto, commit)
+	var owners, historians []*model.SuggestedReviewer
+	add := func(list *[]*model.SuggestedReviewer, u model.BitbucketUser) {
+		if helper.SameUser(u, author) {
+			return
+		}
+		for _, s := range *list {
+			if helper.SameUser(s.User, u) {
+				s.Score++
+				return
+			}
+		}
+		*list = append(*list, &model.SuggestedReviewer{User: u, Score: 1})
+	}
+	var unowned []string
+	for _, p := range paths {
+		fileOwners := helper.CodeownersFor(rules, p)
+		if len(fileOwners) == 0 {
+			unowned = append(unowned, p)
+		}
+		for _, o := range fileOwners {
+			add(&owners, helper.CodeownersUser(o))
+		}
+	}
+	for i, p := range unowned {
+		if i == historyFiles {
+			log.Debugf("PR #%d: history of %d more files without owners not checked", pr.ID, len(unowned)-historyFiles)
+			break
+		}
+		authors, err := ar.Bitbucket.FetchFileAuthors(auto.Workspace, auto.RepoSlug, commit, p, historyCommits, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Warnf("Could not read the history of %s for reviewer suggestions: %v", p, err)
+			continue
+		}
+		for _, a := range authors {
+			add(&historians, a)
+		}
+	}
+
+	byScore := func(list []*model.SuggestedReviewer) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	}
+	byScore(owners)
+	byScore(historians)
+	max := auto.ReviewerSuggestions.Max
+	if max <= 0 {
+		max = defaultSuggestedReviewers
+	}
+	var out []model.SuggestedReviewer
+	for _, s := range owners {
+		s.Reason = fmt.Sprintf("owns %d changed %s (CODEOWNERS)", s.Score, helper.Pluralize(s.Score, "file", "files"))
+		out = append(out, *s)
+	}
+	for _, s := range historians {
+		duplicate := false
+		for _, o := range owners {
+			duplicate = duplicate || helper.SameUser(o.User, s.User)
+		}
+		if duplicate {
+			continue
+		}
+		s.Reason = fmt.Sprintf("%d recent %s on the changed files", s.Score, helper.Pluralize(s.Score, "commit", "commits"))
+		out = append(out, *s)
+	}
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out
+}
+
+// renderSuggestedReviewers renders the "Suggested reviewers" section of the summary.
+func renderSuggestedReviewers(suggestions []model.SuggestedReviewer) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Suggested reviewers\n\n")
+	for _, s := range suggestions {
+		name := s.User.DisplayName
+		switch {
+		case s.User.AccountID != "":
+			name = mention(s.User.AccountID)
+		case name != "":
+			name = "**" + name + "**"
+		default:
+			name = "`" + s.User.UUID + "`"
+		}
+		fmt.Fprintf(&b, "- %s — %s\n", name, s.Reason)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// reviewersStage suggests reviewers for the summary about to be posted and, with
+// reviewerSuggestions.autoAdd, adds them to the pull request on its first review.
+func (ar *AutoReviewPRHandler) reviewersStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.ReviewerSuggestions.Enabled || !run.needsSummary() {
+		return nil
+	}
+	suggestions := ar.suggestReviewers(run)
+	run.ReviewersNote = renderSuggestedReviewers(suggestions)
+	log.Infof("PR #%d: %d suggested %s", pr.ID, len(suggestions), helper.Pluralize(len(suggestions), "reviewer", "reviewers"))
+	if !auto.ReviewerSuggestions.AutoAdd || run.LastReviewedHash != "" {
+		return nil
+	}
+	var addable []model.BitbucketUser
+	for _, s := range suggestions {
+		if s.User.UUID != "" || s.User.AccountID != "" {
+			addable = append(addable, s.User)
+		}
+	}
+	if len(addable) == 0 {
+		return nil
+	}
+	if err := ar.Bitbucket.AddPullRequestReviewers(pr.ID, auto.Workspace, auto.RepoSlug, addable, auto.Username, auto.AppPassword); err != nil {
+		log.Errorf("Failed to add suggested reviewers to PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	log.Infof("✓ Added %d suggested %s to PR #%d", len(addable), helper.Pluralize(len(addable), "reviewer", "reviewers"), pr.ID)
+	return nil
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// riskMarker follows the merge recommendation line of a summary, so later reviews replace it.
+const riskMarker = "<!-- code-nim:risk -->"
+
+// withMergeRecommendation returns body with line as its merge recommendation, replacing an
+// earlier one or, without one, inserted before the bot marker.
+func withMergeRecommendation(body, line string) string {
+	block := line + "\n" + riskMarker + "\n\n"
+	if i := strings.Index(body, riskMarker); i >= 0 {
+		start := strings.LastIndex(body[:i], "\n\n") + 2
+		if start < 2 {
+			start = 0
+		}
+		end := i + len(riskMarker)
+		for end < len(body) && body[end] == '\n' {
+			end++
+		}
+		return body[:start] + block + body[end:]
+	}
+	if i := strings.Index(body, reviewBotMarker); i >= 0 {
+		return body[:i] + block + body[i:]
+	}
+	return body + "\n\n" + block
+}
+
+// riskStage scores the merge risk of a reviewed pull request from its open findings, its size
+// and the critical paths it touches, writes the merge recommendation into the summary of the
+// reviewed commit and stores the score for the risk report.
+func (ar *AutoReviewPRHandler) riskStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.RiskScore.Enabled || run.LatestCommitHash == "" || run.PostErr != nil {
+		return nil
+	}
+	if !run.SummaryPosted && run.InlinePosted == 0 && !run.HasNewCommits {
+		log.Debugf("PR #%d: nothing new reviewed; keeping the merge recommendation", pr.ID)
+		return nil
+	}
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Errorf("Error fetching comments for the risk score of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	score := helper.ScoreRisk(auto.RiskScore, auto.CriticalPaths, findings, run.Diff)
+	score.ProcessName = auto.ProcessName
+	score.Workspace, score.RepoSlug = auto.Workspace, auto.RepoSlug
+	score.PullRequestID, score.PullRequestTitle = pr.ID, pr.Title
+	score.Commit = run.LatestCommitHash
+	log.Infof("PR #%d risk score %d/100: %s", pr.ID, score.Score, score.Recommendation)
+	if err := ar.Storage.SaveRiskScore(score); err != nil {
+		log.Warnf("Failed to store the risk score of PR #%d: %v", pr.ID, err)
+	}
+
+	// The summary of the reviewed commit is the latest summary comment carrying its marker
+	reviewed := fmt.Sprintf("<!-- auto-review-base:%s -->", run.LatestCommitHash)
+	var summary *model.PullRequestComment
+	for i := range run.PostedComments {
+		c := &run.PostedComments[i]
+		if c.Inline == nil && !c.Deleted && strings.Contains(c.Content.Raw, reviewed) && strings.Contains(c.Content.Raw, summaryKindMarker) {
+			summary = c
+		}
+	}
+	if summary == nil {
+		log.Debugf("PR #%d: no summary of %s to add the merge recommendation to", pr.ID, shortHash(run.LatestCommitHash))
+		return nil
+	}
+	body := withMergeRecommendation(summary.Content.Raw, helper.LocalizeSummary(helper.FormatMergeRecommendation(score), auto.Language))
+	if body == summary.Content.Raw {
+		return nil
+	}
+	if err := ar.Bitbucket.UpdatePullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, summary.ID, body, auto.Username, auto.AppPassword); err != nil {
+		log.Errorf("Failed to add the merge recommendation to the summary of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	log.Infof("✓ Added merge recommendation to the summary of PR #%d", pr.ID)
+	return nil
+}
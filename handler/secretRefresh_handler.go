@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"code_nim/log"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// scheduleSecretRefresh resolves the secret references of the entries every interval. It runs on
+// every replica, since workers and webhook verification use the credentials too.
+func (ar *AutoReviewPRHandler) scheduleSecretRefresh(interval time.Duration) error {
+	_, err := ar.scheduler.NewJob(
+		gocron.DurationJob(interval),
+		gocron.NewTask(ar.safeTask("secretRefresh", ar.refreshSecrets)),
+	)
+	return err
+}
+
+// refreshSecrets replaces the credentials of the entries whose vault: or aws-sm: secrets were
+// rotated. Runs already under way keep the credentials they started with; an entry whose
+// secrets cannot be read keeps its current ones.
+func (ar *AutoReviewPRHandler) refreshSecrets() {
+	changed, errs := ar.Secrets.Refresh(ar.entrySnapshot())
+	for _, err := range errs {
+		log.Warnf("Keeping current credentials: secret refresh failed for %v", err)
+	}
+
+	ar.statsMutex.Lock()
+	for key, auto := range changed {
+		ar.entries[key] = auto
+	}
+	if ar.secretRefreshes == nil {
+		ar.secretRefreshes = make(map[string]int64)
+	}
+	ar.secretRefreshes["rotated"] += int64(len(changed))
+	ar.secretRefreshes["error"] += int64(len(errs))
+	ar.statsMutex.Unlock()
+
+	for key := range changed {
+		log.Infof("Picked up rotated secrets for %s", key)
+	}
+}
+
+func (ar *AutoReviewPRHandler) writeSecretMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	b.WriteString("# HELP code_nim_secret_refresh_total Entries whose secret references were resolved again, by result.\n# TYPE code_nim_secret_refresh_total counter\n")
+	for _, result := range []string{"rotated", "error"} {
+		fmt.Fprintf(b, "code_nim_secret_refresh_total{result=%q} %d\n", result, ar.secretRefreshes[result])
+	}
+}
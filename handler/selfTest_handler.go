@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// selfTestPrompt is the generation sent to check an AI key; its answer is not used.
+const selfTestPrompt = "Reply with the single word OK."
+
+// credentialID identifies a secret without keeping it, so entries sharing credentials are
+// checked once per self-test.
+func credentialID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// runSelfTest checks the Bitbucket credentials of every entry with the user endpoint and each
+// of its AI keys with a tiny generation, and keeps the report for /metrics.
+func (ar *AutoReviewPRHandler) runSelfTest() model.SelfTestReport {
+	report := model.SelfTestReport{StartedAt: time.Now(), OK: true}
+	entries := ar.entrySnapshot()
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	done := map[string]model.SelfTestCheck{}
+	check := func(name, id string, test func() (string, error)) model.SelfTestCheck {
+		if c, ok := done[id]; ok {
+			c.Name = name
+			return c
+		}
+		started := time.Now()
+		detail, err := test()
+		c := model.SelfTestCheck{Name: name, OK: err == nil, Detail: detail, DurationMs: time.Since(started).Milliseconds()}
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		done[id] = c
+		return c
+	}
+
+	for _, key := range keys {
+		auto := entries[key]
+		entry := model.SelfTestEntry{ProcessName: key, Provider: model.GitProviderBitbucket, OK: true}
+		if auto.IsGerrit() || auto.IsGitHub() {
+			entry.Provider = strings.ToLower(strings.TrimSpace(auto.GitProvider))
+			entry.Checks = append(entry.Checks, model.SelfTestCheck{Name: entry.Provider, OK: true, Skipped: true, Detail: "only Bitbucket credentials are checked"})
+		} else {
+			entry.Checks = append(entry.Checks, check("bitbucket", credentialID("bitbucket", auto.Username, auto.AppPassword), func() (string, error) {
+				user, err := ar.Bitbucket.FetchCurrentUser(auto.Username, auto.AppPassword)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("authenticated as %s", user.DisplayName), nil
+			}))
+		}
+
+		aiKeys := auto.AIKeys
+		if len(aiKeys) == 0 {
+			aiKeys = []string{""} // the entry's aiKey or geminiKey
+		}
+		modelName := helper.AIModelName(&auto)
+		for i, k := range aiKeys {
+			name := "ai"
+			cfg := auto
+			if len(auto.AIKeys) > 0 {
+				name = fmt.Sprintf("ai key %d/%d", i+1, len(auto.AIKeys))
+				cfg.AIKeys, cfg.AIKey = nil, k
+			}
+			id := credentialID("ai", cfg.AIProvider, modelName, cfg.SelfAPIBaseURL, cfg.AIKey, cfg.GeminiKey)
+			entry.Checks = append(entry.Checks, check(name, id, func() (string, error) {
+				if _, err := helper.GetAISummary(selfTestPrompt, &cfg); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("generated with %s", modelName), nil
+			}))
+		}
+
+		for _, c := range entry.Checks {
+			entry.OK = entry.OK && c.OK
+		}
+		report.OK = report.OK && entry.OK
+		report.Entries = append(report.Entries, entry)
+	}
+
+	ar.statsMutex.Lock()
+	ar.selfTest = &report
+	ar.statsMutex.Unlock()
+	return report
+}
+
+// logSelfTest logs each failed check of report, or that every check passed.
+func logSelfTest(report model.SelfTestReport) {
+	if report.OK {
+		log.Infof("Self-test passed for %d entries", len(report.Entries))
+		return
+	}
+	for _, entry := range report.Entries {
+		for _, c := range entry.Checks {
+			if !c.OK {
+				log.Errorf("Self-test of %s failed on %s: %s", entry.ProcessName, c.Name, c.Detail)
+			}
+		}
+	}
+}
+
+// startupSelfTest runs the self-test as selfTest.onStartup asks: in the background for "log",
+// or before anything is scheduled for "fail", exiting when a check fails.
+func (ar *AutoReviewPRHandler) startupSelfTest(settings model.SelfTestSettings) {
+	switch settings.Mode() {
+	case model.SelfTestOff:
+		return
+	case model.SelfTestFail:
+		report := ar.runSelfTest()
+		logSelfTest(report)
+		if !report.OK {
+			log.Fatalf("Self-test failed; fix the credentials above or set selfTest.onStartup to log")
+		}
+	default:
+		go func() {
+			defer ar.recoverPanic("self-test", nil)
+			logSelfTest(ar.runSelfTest())
+		}()
+	}
+}
+
+// SelfTest handles GET /api/v1/selftest. It checks the credentials of every entry again and
+// answers 200 when all checks pass, 503 otherwise, with the report of each entry.
+func (ar *AutoReviewPRHandler) SelfTest(c echo.Context) error {
+	report := ar.runSelfTest()
+	if !report.OK {
+		return c.JSON(http.StatusServiceUnavailable, model.Response{StatusCode: http.StatusServiceUnavailable, Message: "Self-test failed", Data: report})
+	}
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: "Self-test passed", Data: report})
+}
+
+// writeSelfTestMetrics exports the result of the latest self-test per entry.
+func (ar *AutoReviewPRHandler) writeSelfTestMetrics(b *strings.Builder) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.selfTest == nil {
+		return
+	}
+	b.WriteString("# HELP code_nim_selftest_ok Whether the credentials of an entry passed the latest self-test.\n# TYPE code_nim_selftest_ok gauge\n")
+	for _, entry := range ar.selfTest.Entries {
+		ok := 0
+		if entry.OK {
+			ok = 1
+		}
+		fmt.Fprintf(b, "code_nim_selftest_ok{job=%q} %d\n", entry.ProcessName, ok)
+	}
+}
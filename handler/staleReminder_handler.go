@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+const staleMarkerPrefix = "<!-- auto-review-stale:"
+
+// defaultStaleAfterDays is how long a pull request is open before it is reminded about.
+const defaultStaleAfterDays = 7
+
+// staleMarker dates a reminder, so the next one waits for staleReminders.repeatDays.
+func staleMarker(day time.Time) string {
+	return staleMarkerPrefix + day.UTC().Format("2006-01-02") + " -->"
+}
+
+// lastStaleReminder returns the date of the newest reminder in comments, or the zero time.
+func lastStaleReminder(comments []model.PullRequestComment) time.Time {
+	var last time.Time
+	for _, c := range comments {
+		raw := c.Content.Raw
+		start := strings.Index(raw, staleMarkerPrefix)
+		if c.Inline != nil || start == -1 {
+			continue
+		}
+		date, _, _ := strings.Cut(raw[start+len(staleMarkerPrefix):], " -->")
+		if day, err := time.Parse("2006-01-02", strings.TrimSpace(date)); err == nil && day.After(last) {
+			last = day
+		}
+	}
+	return last
+}
+
+// hasReviewerActivity reports whether anyone but the author and the bot commented on the PR.
+func hasReviewerActivity(pr *model.PullRequest, comments []model.PullRequestComment) bool {
+	for _, c := range comments {
+		if !c.Deleted && !hasBotMarker(c.Content.Raw) && c.User.DisplayName != pr.Author.DisplayName {
+			return true
+		}
+	}
+	return false
+}
+
+// mention renders a Bitbucket mention of an account ID.
+func mention(accountID string) string {
+	return "@{" + strings.Trim(strings.TrimSpace(accountID), "@{}") + "}"
+}
+
+// staleNotice renders the reminder posted on a pull request that has been open for days.
+func staleNotice(settings model.StaleReminderSettings, pr *model.PullRequest, days, open int, noActivity bool, now time.Time) string {
+	author := pr.Author.DisplayName
+	if pr.Author.AccountID != "" {
+		author = mention(pr.Author.AccountID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "## ⏰ This PR has been open for %d days\n\n", days)
+	fmt.Fprintf(&b, "Hi %s, a friendly reminder that this pull request is still waiting:\n\n", author)
+	if open > 0 {
+		fmt.Fprintf(&b, "- %d Nim %s not resolved yet.\n", open, helper.Pluralize(open, "finding is", "findings are"))
+	}
+	if noActivity {
+		b.WriteString("- No reviewer has commented yet.\n")
+	}
+	b.WriteString("\n")
+	if len(settings.Reviewers) > 0 {
+		mentions := make([]string, 0, len(settings.Reviewers))
+		for _, r := range settings.Reviewers {
+			mentions = append(mentions, mention(r))
+		}
+		fmt.Fprintf(&b, "%s, could you take a look when you have a moment?\n\n", strings.Join(mentions, " "))
+	}
+	b.WriteString("If this PR is blocked or no longer needed, a short comment or declining it keeps the queue clear.\n\n")
+	b.WriteString(staleMarker(now) + "\n" + reviewBotMarker)
+	return b.String()
+}
+
+// scheduleStaleReminders registers the reminder job of an entry on the review scheduler.
+func (ar *AutoReviewPRHandler) scheduleStaleReminders(auto model.AutoReviewPR) error {
+	_, err := ar.scheduler.NewJob(
+		gocron.CronJob(auto.StaleReminders.Cron, true),
+		gocron.NewTask(ar.safeTask("staleReminders of "+entryKey(auto), func() { ar.remindStale(ar.current(auto)) })),
+	)
+	return err
+}
+
+// remindStale posts a reminder on each open pull request of the entry that is older than
+// staleReminders.afterDays and has unresolved bot findings or no reviewer comments, at most
+// once per staleReminders.repeatDays. Like scheduled reviews it runs only on the replica that
+// schedules reviews, and not while the job is paused, the repository is frozen or posting is
+// closed by its active or quiet hours.
+func (ar *AutoReviewPRHandler) remindStale(auto model.AutoReviewPR) {
+	key := entryKey(auto)
+	if !ar.queueSettings.Schedules() || !ar.Leader.IsLeader() {
+		log.Debugf("Skipping stale PR reminders for %s: this replica does not schedule reviews", key)
+		return
+	}
+	if ar.isPaused(key) {
+		log.Infof("Skipping stale PR reminders for %s: job is paused", key)
+		return
+	}
+	now := time.Now()
+	if w, _, _, _ := helper.ActiveFreeze(auto.FreezeWindows, now); w != nil {
+		log.Infof("Skipping stale PR reminders for %s: %s is in effect", key, freezeName(w))
+		return
+	}
+	if allowed, _ := helper.PostingAllowed(auto.ActiveHours, auto.QuietHours, now); !allowed {
+		log.Infof("Skipping stale PR reminders for %s: outside active hours", key)
+		return
+	}
+	settings := auto.StaleReminders
+	after := settings.AfterDays
+	if after <= 0 {
+		after = defaultStaleAfterDays
+	}
+	repeat := settings.RepeatDays
+	if repeat <= 0 {
+		repeat = after
+	}
+
+	prs, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
+	if err != nil {
+		log.Errorf("Error fetching pull requests for stale reminders of %s: %v", key, err)
+		return
+	}
+	reminded := 0
+	for i := range prs {
+		pr := &prs[i]
+		created, err := time.Parse(time.RFC3339Nano, pr.CreatedOn)
+		if err != nil {
+			log.Debugf("PR #%d: cannot parse created_on %q: %v", pr.ID, pr.CreatedOn, err)
+			continue
+		}
+		days := int(now.Sub(created).Hours() / 24)
+		if days < after {
+			continue
+		}
+		comments, err := ar.Bitbucket.FetchPullRequestComments(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching comments for stale reminder of PR #%d: %v", pr.ID, err)
+			continue
+		}
+		if last := lastStaleReminder(comments); !last.IsZero() && now.Sub(last) < time.Duration(repeat)*24*time.Hour {
+			continue
+		}
+		open := len(openFindings(comments))
+		noActivity := !hasReviewerActivity(pr, comments)
+		if open == 0 && !noActivity {
+			continue
+		}
+
+		marker := staleMarker(now)
+		posted, err := ar.deliverComment(&auto, pr, "", "stale:"+marker, func() error {
+			publishStart := time.Now()
+			defer ar.observe(nil, "publish", publishStart)
+			return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(&auto, staleNotice(settings, pr, days, open, noActivity, now)))
+		})
+		if err != nil {
+			log.Errorf("Failed to post stale reminder on PR #%d: %v", pr.ID, err)
+			continue
+		}
+		if posted {
+			reminded++
+			log.Infof("✓ Posted stale reminder on PR #%d (open %d days, %d open findings, reviewer activity=%t)", pr.ID, days, open, !noActivity)
+		}
+	}
+	log.Infof("Stale PR reminders for %s: %d of %d open PRs reminded", key, reminded, len(prs))
+}
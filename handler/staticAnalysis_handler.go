@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/analysis"
+	"code_nim/log"
+	"fmt"
+)
+
+// lintStage runs the configured linters on the PR branch so that ensureInlineReviewComments
+// can add their findings to each file's prompt. A failed checkout only loses the linter
+// context; the AI review still runs. Linters run code of the checkout, such as an
+// eslint.config.js, as the service user, so pull requests from forks are never linted.
+func (ar *AutoReviewPRHandler) lintStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.StaticAnalysis.Enabled() || run.SkipInline {
+		return nil
+	}
+	if repo, ok := forkOf(auto, pr); ok {
+		log.Infof("Skipping static analysis for PR #%d: source branch is in fork %s", pr.ID, repo)
+		return nil
+	}
+	var changed []string
+	for _, file := range helper.ParseDiff(run.Diff) {
+		if p, _ := file["path"].(string); p != "" {
+			changed = append(changed, p)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	checkout := analysis.Checkout{
+		CloneURL: fmt.Sprintf("https://bitbucket.org/%s/%s.git", auto.Workspace, auto.RepoSlug),
+		Branch:   pr.Source.Branch.Name,
+		Username: auto.Username,
+		Password: auto.AppPassword,
+	}
+	findings, err := analysis.Run(auto.StaticAnalysis, checkout, changed)
+	if err != nil {
+		log.Warnf("Static analysis skipped for PR #%d: %v", pr.ID, err)
+		return nil
+	}
+	total := 0
+	for _, f := range findings {
+		total += len(f)
+	}
+	log.Infof("Static analysis found %d issues in %d changed files of PR #%d", total, len(findings), pr.ID)
+	run.LintFindings = findings
+	return nil
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"strings"
+	"time"
+)
+
+// statusReportMarker identifies the "Review status" comment, which is updated in place.
+const statusReportMarker = "<!-- auto-review-status -->"
+
+// reportStage posts, when statusReport is enabled, a "Review status" comment describing the
+// run: commit reviewed, files analyzed and skipped, findings, AI model and duration. Later runs
+// update the same comment. Runs that reviewed nothing new leave it as it is. A failure is
+// logged; the review itself is not affected.
+func (ar *AutoReviewPRHandler) reportStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.StatusReport.Enabled || run.LatestCommitHash == "" {
+		return nil
+	}
+	if !run.SummaryPosted && run.InlinePosted == 0 && !run.HasNewCommits {
+		log.Debugf("PR #%d: nothing new reviewed; keeping the review status comment", pr.ID)
+		return nil
+	}
+	report := helper.ReviewRunReport{
+		Commit:      run.LatestCommitHash,
+		SummaryOnly: summaryOnlyReason(run),
+		Posting:     run.Posting,
+		Model:       helper.AIModelName(auto),
+		Duration:    time.Since(run.Started),
+		Incomplete:  run.PostErr != nil,
+	}
+	report.FilesAnalyzed, report.FilesSkipped = helper.CountReviewedFiles(auto, helper.ParseDiff(run.Diff))
+	findings, err := ar.loadOpenFindings(run)
+	if err != nil {
+		log.Errorf("Error fetching comments for the review status of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	report.OpenFindings = findings
+	body := ar.withFooter(auto, helper.FormatReviewRunReport(report)+"\n"+statusReportMarker+"\n"+reviewBotMarker)
+
+	for _, c := range run.Comments {
+		if c.Inline != nil || c.Deleted || !strings.Contains(c.Content.Raw, statusReportMarker) {
+			continue
+		}
+		if err := ar.Bitbucket.UpdatePullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, c.ID, body, auto.Username, auto.AppPassword); err != nil {
+			log.Errorf("Failed to update the review status of PR #%d: %v", pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			return nil
+		}
+		log.Infof("✓ Updated review status of PR #%d", pr.ID)
+		return nil
+	}
+	posted, err := ar.deliverComment(auto, pr, run.LatestCommitHash, "status", func() error {
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, body)
+	})
+	if err != nil {
+		log.Errorf("Failed to post the review status of PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	if posted {
+		log.Infof("✓ Posted review status of PR #%d", pr.ID)
+	}
+	return nil
+}
+
+// summaryOnlyReason explains in the review status why no file was reviewed inline, or returns
+// "" when files were.
+func summaryOnlyReason(run *reviewRun) string {
+	switch {
+	case !run.SkipInline:
+		return ""
+	case !run.Auto.ReviewsInline():
+		return "the review mode is summary"
+	case run.ApprovedBy != "":
+		return "approved by " + run.ApprovedBy
+	}
+	return "the author is on the ignore list"
+}
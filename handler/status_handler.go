@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// providerWindowSize is how many recent calls per AI provider decide its health.
+const providerWindowSize = 20
+
+// providerWindow keeps the outcome of the latest calls to one AI provider.
+type providerWindow struct {
+	results     []bool // true for a failed call, oldest first
+	lastSuccess time.Time
+	lastError   time.Time
+}
+
+// providerName returns the provider an entry's AI calls go to.
+func providerName(auto *model.AutoReviewPR) string {
+	p := strings.ToLower(strings.TrimSpace(auto.AIProvider))
+	if p == "" {
+		return "gemini"
+	}
+	return p
+}
+
+// noteProviderResult records the outcome of one AI call for the status page.
+func (ar *AutoReviewPRHandler) noteProviderResult(auto *model.AutoReviewPR, err error) {
+	ar.statsMutex.Lock()
+	defer ar.statsMutex.Unlock()
+	if ar.providers == nil {
+		ar.providers = map[string]*providerWindow{}
+	}
+	name := providerName(auto)
+	w, ok := ar.providers[name]
+	if !ok {
+		w = &providerWindow{}
+		ar.providers[name] = w
+	}
+	w.results = append(w.results, err != nil)
+	if len(w.results) > providerWindowSize {
+		w.results = w.results[len(w.results)-providerWindowSize:]
+	}
+	if err != nil {
+		w.lastError = time.Now()
+	} else {
+		w.lastSuccess = time.Now()
+	}
+}
+
+// health summarises the window: down when the last three calls (or all calls so far) failed,
+// degraded when any recent call failed.
+func (w *providerWindow) health(name string) model.ProviderHealth {
+	h := model.ProviderHealth{Provider: name, Status: model.ProviderUnknown, RecentCalls: len(w.results), LastSuccessAt: w.lastSuccess, LastErrorAt: w.lastError}
+	for _, failed := range w.results {
+		if failed {
+			h.RecentErrors++
+		}
+	}
+	if len(w.results) == 0 {
+		return h
+	}
+	trailing := 0
+	for i := len(w.results) - 1; i >= 0 && w.results[i]; i-- {
+		trailing++
+	}
+	switch {
+	case trailing >= 3 || trailing == len(w.results):
+		h.Status = model.ProviderDown
+	case h.RecentErrors > 0:
+		h.Status = model.ProviderDegraded
+	default:
+		h.Status = model.ProviderUp
+	}
+	return h
+}
+
+// serviceStatus collects the public view of the service. It leaves out error messages,
+// repositories and findings, so it can be shown without authentication.
+func (ar *AutoReviewPRHandler) serviceStatus() model.ServiceStatus {
+	ar.statsMutex.Lock()
+	status := model.ServiceStatus{Status: "ok", StartedAt: ar.startedAt, Providers: []model.ProviderHealth{}, Jobs: []model.JobSummary{}}
+	for name, w := range ar.providers {
+		status.Providers = append(status.Providers, w.health(name))
+	}
+	ar.statsMutex.Unlock()
+	sort.Slice(status.Providers, func(i, j int) bool { return status.Providers[i].Provider < status.Providers[j].Provider })
+	if !status.StartedAt.IsZero() {
+		status.Uptime = time.Since(status.StartedAt).Round(time.Second)
+	}
+
+	for _, p := range status.Providers {
+		if p.Status == model.ProviderDown || p.Status == model.ProviderDegraded {
+			status.Status = "degraded"
+		}
+	}
+	for _, js := range ar.jobStatuses() {
+		runs := make([]model.JobRun, len(js.RecentRuns))
+		for i, r := range js.RecentRuns {
+			// The status page is public: no error messages or pull requests
+			r.Error, r.PullRequests = "", nil
+			runs[i] = r
+		}
+		status.Jobs = append(status.Jobs, model.JobSummary{
+			Name:       js.Name,
+			Paused:     js.Paused,
+			Running:    js.Running,
+			Frozen:     js.Frozen != "",
+			NextRunAt:  js.NextRunAt,
+			LastResult: js.LastResult,
+			RecentRuns: runs,
+		})
+		if js.LastResult == "error" {
+			status.Status = "degraded"
+		}
+	}
+	if ar.Queue != nil {
+		st := ar.Queue.Stats()
+		depth := st.DepthHigh + st.DepthLow
+		status.QueueDepth = &depth
+	}
+	return status
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return time.Since(t).Round(time.Second).String() + " ago"
+	},
+	"round": func(d time.Duration) string { return d.Round(time.Second).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>code-nim status</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 960px; margin: 1em auto; padding: 0 1em; color: #172b4d; font-size: 14px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #dfe1e6; }
+th { background: #f4f5f7; }
+.meta { color: #5e6c84; }
+.badge { display: inline-block; padding: 1px 6px; border-radius: 3px; background: #dfe1e6; }
+.ok, .up { background: #e3fcef; } .error, .down { background: #ffebe6; } .degraded, .paused { background: #fffae6; }
+.runs span { display: inline-block; width: 10px; height: 10px; margin-right: 2px; border-radius: 2px; }
+</style>
+</head>
+<body>
+<h2>code-nim <span class="badge {{if eq .Status "ok"}}ok{{else}}degraded{{end}}">{{.Status}}</span></h2>
+<p class="meta">Up {{round .Uptime}} · started {{.StartedAt.Format "2006-01-02 15:04"}}{{if .QueueDepth}} · queue: {{.QueueDepth}} waiting{{end}}</p>
+
+<h3>AI providers</h3>
+<table>
+<tr><th>Provider</th><th>Status</th><th>Recent errors</th><th>Last success</th><th>Last error</th></tr>
+{{range .Providers}}
+<tr><td>{{.Provider}}</td><td><span class="badge {{.Status}}">{{.Status}}</span></td><td>{{.RecentErrors}} / {{.RecentCalls}}</td><td>{{ago .LastSuccessAt}}</td><td>{{ago .LastErrorAt}}</td></tr>
+{{else}}
+<tr><td colspan="5" class="meta">No AI calls since startup.</td></tr>
+{{end}}
+</table>
+
+<h3>Jobs</h3>
+<table>
+<tr><th>Job</th><th>State</th><th>Next run</th><th>Recent runs (newest first)</th></tr>
+{{range .Jobs}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{if .Running}}<span class="badge">running</span>{{else if .Paused}}<span class="badge paused">paused</span>{{else if .Frozen}}<span class="badge paused">frozen</span>{{else if .LastResult}}<span class="badge {{.LastResult}}">{{.LastResult}}</span>{{else}}<span class="meta">not run yet</span>{{end}}</td>
+<td>{{if .Paused}}-{{else if .NextRunAt.IsZero}}-{{else}}{{.NextRunAt.Format "2006-01-02 15:04"}}{{end}}</td>
+<td class="runs">{{range .RecentRuns}}<span class="{{.Result}}" title="{{.StartedAt.Format "2006-01-02 15:04"}}{{if .ID}} (run {{.ID}}){{end}}: {{.Result}}, {{.PRsReviewed}} PRs, {{.FindingsPosted}} findings in {{round .Duration}}"></span>{{end}}</td>
+</tr>
+{{else}}
+<tr><td colspan="4" class="meta">No jobs configured.</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// StatusPage handles GET /status: a small self-contained page with uptime, provider health,
+// queue depth and the latest runs per job, meant to be embedded in an internal portal.
+func (ar *AutoReviewPRHandler) StatusPage(c echo.Context) error {
+	var b strings.Builder
+	if err := statusTemplate.Execute(&b, ar.serviceStatus()); err != nil {
+		log.Errorf("Failed to render status page: %v", err)
+		return c.String(http.StatusInternalServerError, "failed to render status page")
+	}
+	return c.HTML(http.StatusOK, b.String())
+}
+
+// GetStatus handles GET /api/v1/status and returns the status page data as JSON.
+func (ar *AutoReviewPRHandler) GetStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Status",
+		Data:       ar.serviceStatus(),
+	})
+}
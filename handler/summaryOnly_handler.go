@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"code_nim/helper/timing"
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const summarizedMarkerPrefix = "<!-- auto-review-summary:"
+
+// summarizedMarker marks a summary posted through the summary-only route. Unlike the review
+// marker it does not count the commit as reviewed, so the scheduled review still posts inline
+// comments for it; it only skips writing the same summary again.
+func summarizedMarker(latestCommitHash string) string {
+	if latestCommitHash == "" {
+		return reviewBotMarker
+	}
+	return fmt.Sprintf("%s\n%s\n\n%s%s %s", reviewBotMarker, summaryKindMarker, summarizedMarkerPrefix, latestCommitHash, reviewMarkerSuffix)
+}
+
+// extractSummarizedHash returns the commit of the latest summary-only summary in comments.
+func extractSummarizedHash(comments []model.PullRequestComment) string {
+	var last string
+	for _, comment := range comments {
+		raw := comment.Content.Raw
+		if comment.Inline != nil || comment.Deleted {
+			continue
+		}
+		start := strings.Index(raw, summarizedMarkerPrefix)
+		if start == -1 {
+			continue
+		}
+		start += len(summarizedMarkerPrefix)
+		if end := strings.Index(raw[start:], reviewMarkerSuffix); end != -1 {
+			if hash := strings.TrimSpace(raw[start : start+end]); hash != "" {
+				last = hash
+			}
+		}
+	}
+	return last
+}
+
+// newSummaryPipeline builds the summary-only pipeline: fetch → filter → analyze → summary.
+// Skip markers, LGTM pauses, the AI budget and freeze windows apply as in a full review.
+func (ar *AutoReviewPRHandler) newSummaryPipeline() *reviewPipeline {
+	p := &reviewPipeline{stages: []reviewStage{
+		newStage("fetch", ar.fetchStage),
+		newStage("filter", ar.filterStage),
+		newStage("analyze", ar.analyzeStage),
+		newStage("summary", ar.summaryOnlyStage),
+	}}
+	p.Use(ar.timeStage)
+	p.Use(ar.budgetGuard, "fetch")
+	p.Use(ar.freezeGuard, "analyze")
+	return p
+}
+
+// summaryOnlyStage posts the summary of the latest commit unless it already has one.
+func (ar *AutoReviewPRHandler) summaryOnlyStage(run *reviewRun) error {
+	if !run.needsSummary() {
+		run.Skip = "the latest commit is already summarized"
+		return nil
+	}
+	run.SummaryPosted, run.PostErr = ar.postSummary(run, run.Auto, "", summarizedMarker(run.LatestCommitHash))
+	return run.PostErr
+}
+
+// PostSummaryOnly handles POST /api/v1/summary/:workspace/:repoSlug/:prID. It generates and
+// posts only the summary of the pull request, without inline review, and answers once it is
+// posted. The scheduled review later adds the inline comments without summarizing the same
+// commit again. It answers 409 while another review is running.
+func (ar *AutoReviewPRHandler) PostSummaryOnly(c echo.Context) error {
+	workspace, repoSlug := c.Param("workspace"), c.Param("repoSlug")
+	prID, err := strconv.Atoi(c.Param("prID"))
+	if err != nil || prID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("Invalid pull request ID %q", c.Param("prID")),
+		})
+	}
+	var auto *model.AutoReviewPR
+	var jobKey string
+	for key, entry := range ar.entrySnapshot() {
+		if entry.IsGerrit() || entry.IsGitHub() || ar.isPaused(key) {
+			continue
+		}
+		if strings.EqualFold(entry.Workspace, workspace) && strings.EqualFold(entry.RepoSlug, repoSlug) {
+			auto, jobKey = &entry, key
+			break
+		}
+	}
+	if auto == nil {
+		return c.JSON(http.StatusNotFound, model.Response{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("No active review configured for %s/%s", workspace, repoSlug),
+		})
+	}
+
+	ar.mutex.Lock()
+	if ar.isRunning {
+		ar.mutex.Unlock()
+		c.Response().Header().Set("Retry-After", "30")
+		return c.JSON(http.StatusConflict, model.Response{
+			StatusCode: http.StatusConflict,
+			Message:    "Another review is running, retry later",
+		})
+	}
+	ar.isRunning = true
+	ar.mutex.Unlock()
+	defer func() {
+		ar.mutex.Lock()
+		ar.isRunning = false
+		ar.mutex.Unlock()
+	}()
+
+	// Recorded as a run of the job, like scheduled reviews, so the status page and job history show it
+	startTime := time.Now()
+	var jobErr error
+	ar.beginJob(jobKey)
+	defer func() { ar.finishJob(startTime, jobErr) }()
+
+	prs, err := ar.Bitbucket.FetchAllPullRequests(auto.Username, auto.AppPassword, auto.Workspace, auto.RepoSlug)
+	if err != nil {
+		log.Errorf("Error fetching pull requests of %s/%s for a summary: %v", auto.Workspace, auto.RepoSlug, err)
+		ar.noteJobError(jobErrorAPI)
+		jobErr = fmt.Errorf("%w: %w", errBitbucket, err)
+		return c.JSON(http.StatusBadGateway, model.Response{
+			StatusCode: http.StatusBadGateway,
+			Message:    err.Error(),
+		})
+	}
+	var pr *model.PullRequest
+	for i := range prs {
+		if prs[i].ID == prID {
+			pr = &prs[i]
+			break
+		}
+	}
+	if pr == nil {
+		return c.JSON(http.StatusNotFound, model.Response{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("PR #%d is not open in %s/%s", prID, auto.Workspace, auto.RepoSlug),
+		})
+	}
+
+	log.Infof("Summary-only run for %s/%s PR #%d", auto.Workspace, auto.RepoSlug, prID)
+	run := &reviewRun{Auto: auto, PR: pr, Started: time.Now(), Breakdown: timing.NewBreakdown()}
+	err = func() (err error) {
+		defer func() { log.Infof("PR #%d summary timings: %s", pr.ID, run.Breakdown) }()
+		defer ar.recoverPanic(fmt.Sprintf("summary of PR #%d", pr.ID), &err)
+		return ar.newSummaryPipeline().Run(run)
+	}()
+	ar.notePullRequest(pullRequestOutcome(run, err))
+	if !errors.Is(err, errHaltReview) {
+		jobErr = err
+	}
+	if errors.Is(err, errHaltReview) {
+		return c.JSON(http.StatusServiceUnavailable, model.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "AI budget exhausted for today",
+		})
+	}
+	if err != nil {
+		log.Errorf("Summary-only run for PR #%d failed: %v", prID, err)
+		return c.JSON(http.StatusBadGateway, model.Response{
+			StatusCode: http.StatusBadGateway,
+			Message:    err.Error(),
+		})
+	}
+	data := map[string]interface{}{"processName": auto.ProcessName, "pullRequestId": prID, "posted": run.SummaryPosted}
+	message := "Summary posted"
+	if !run.SummaryPosted {
+		message = "No summary posted"
+		if run.Skip != "" {
+			data["reason"] = run.Skip
+		}
+	}
+	return c.JSON(http.StatusOK, model.Response{StatusCode: http.StatusOK, Message: message, Data: data})
+}
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// minScrubbedSecret is the shortest credential scrubbed from free text; shorter values would
+	// match ordinary words. The config itself is always redacted field by field.
+	minScrubbedSecret = 6
+	// maxBundleLogLines keeps the most recent matching log lines in a support bundle.
+	maxBundleLogLines = 5000
+)
+
+// secretParamPattern catches credentials passed as query or form parameters in logged URLs.
+var secretParamPattern = regexp.MustCompile(`(?i)\b(key|token|password|secret|sig|signature)=[^&\s"']+`)
+
+// transcriptMeta describes an AI exchange without its prompt or response text.
+type transcriptMeta struct {
+	ID             string    `json:"id"`
+	Kind           string    `json:"kind"`
+	Path           string    `json:"path,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	PromptChars    int       `json:"promptChars"`
+	ResponseChars  int       `json:"responseChars"`
+	Findings       int       `json:"findings"`
+	ResponseStatus string    `json:"responseStatus"` // "ok", "no findings" or "empty"
+}
+
+// bundleSecrets returns the credential values of a config entry to scrub from text, longest first.
+func bundleSecrets(auto model.AutoReviewPR) []string {
+	candidates := append([]string{auto.AppPassword, auto.GeminiKey, auto.AIKey, auto.WebhookSecret}, auto.AIKeys...)
+	notifiers := append([]model.NotifierSettings(nil), auto.Notifiers...)
+	for _, p := range auto.PackageProfiles {
+		notifiers = append(notifiers, p.Notifiers...)
+	}
+	for _, n := range notifiers {
+		candidates = append(candidates, n.URL, n.SMTP.Password)
+		for _, v := range n.Headers {
+			candidates = append(candidates, v)
+		}
+	}
+	var secrets []string
+	for _, s := range candidates {
+		if len(strings.TrimSpace(s)) >= minScrubbedSecret {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// mask replaces a non-empty credential.
+func mask(s *string) {
+	if *s != "" {
+		*s = redacted
+	}
+}
+
+// redactNotifiers returns a copy of notifiers with their URLs, header values and SMTP
+// passwords replaced. URLs are masked whole, since webhook URLs such as Slack's carry their
+// token in the path.
+func redactNotifiers(notifiers []model.NotifierSettings) []model.NotifierSettings {
+	if notifiers == nil {
+		return nil
+	}
+	out := make([]model.NotifierSettings, len(notifiers))
+	for i, n := range notifiers {
+		mask(&n.URL)
+		mask(&n.SMTP.Password)
+		if n.Headers != nil {
+			headers := make(map[string]string, len(n.Headers))
+			for k := range n.Headers {
+				headers[k] = redacted
+			}
+			n.Headers = headers
+		}
+		out[i] = n
+	}
+	return out
+}
+
+// redactEntry returns a copy of a config entry with its credentials replaced.
+func redactEntry(auto model.AutoReviewPR) model.AutoReviewPR {
+	mask(&auto.AppPassword)
+	mask(&auto.GeminiKey)
+	mask(&auto.AIKey)
+	mask(&auto.WebhookSecret)
+	keys := make([]string, len(auto.AIKeys))
+	for i := range keys {
+		keys[i] = redacted
+	}
+	auto.AIKeys = keys
+	auto.Notifiers = redactNotifiers(auto.Notifiers)
+	if auto.PackageProfiles != nil {
+		profiles := make(map[string]model.PackageProfile, len(auto.PackageProfiles))
+		for name, p := range auto.PackageProfiles {
+			p.Notifiers = redactNotifiers(p.Notifiers)
+			profiles[name] = p
+		}
+		auto.PackageProfiles = profiles
+	}
+	return auto
+}
+
+// sanitize removes the entry's credentials and credential-looking URL parameters from text.
+func sanitize(text string, secrets []string) string {
+	for _, s := range secrets {
+		text = strings.ReplaceAll(text, s, redacted)
+	}
+	return secretParamPattern.ReplaceAllString(text, "$1="+redacted)
+}
+
+// bundleLogLines returns the log lines of the last days that mention the pull request.
+func bundleLogLines(prID int, since time.Time) ([]string, error) {
+	pattern := regexp.MustCompile(fmt.Sprintf(`PR #?%d\b`, prID))
+	var files []string
+	for _, sub := range []string{"info", "error"} {
+		matches, err := filepath.Glob(filepath.Join(log.Dir(), sub, "*.log"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var lines []string
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return lines, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			if pattern.MatchString(scanner.Text()) {
+				lines = append(lines, scanner.Text())
+			}
+		}
+		f.Close()
+	}
+	if len(lines) > maxBundleLogLines {
+		lines = lines[len(lines)-maxBundleLogLines:]
+	}
+	return lines, nil
+}
+
+// SupportBundle handles GET /api/v1/jobs/:name/support-bundle?pr=<id>&days=<n> and returns a zip
+// to attach to bug reports about a review. It holds the job's config without credentials, the
+// runtime state, stage timings, AI exchange metadata (no prompt or response text), the findings
+// and comments recorded for the PR, and the log lines of the last days (default 7) that mention it.
+func (ar *AutoReviewPRHandler) SupportBundle(c echo.Context) error {
+	name := jobName(c)
+	auto, ok := ar.entry(name)
+	if !ok {
+		return jobNotFound(c, name)
+	}
+	prID, err := strconv.Atoi(c.QueryParam("pr"))
+	if err != nil || prID <= 0 {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "pr must be a pull request ID",
+		})
+	}
+	days := 7
+	if raw := c.QueryParam("days"); raw != "" {
+		if days, err = strconv.Atoi(raw); err != nil || days <= 0 {
+			return c.JSON(http.StatusBadRequest, model.Response{
+				StatusCode: http.StatusBadRequest,
+				Message:    "days must be a positive number",
+			})
+		}
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+	secrets := bundleSecrets(auto)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	var contents []string
+	var problems []string
+	add := func(file string, data []byte) {
+		w, err := zw.Create(file)
+		if err == nil {
+			_, err = w.Write([]byte(sanitize(string(data), secrets)))
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+			return
+		}
+		contents = append(contents, file)
+	}
+	addJSON := func(file string, v interface{}) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+			return
+		}
+		add(file, data)
+	}
+
+	if data, err := yaml.Marshal(redactEntry(auto)); err == nil {
+		add("config.yaml", data)
+	} else {
+		problems = append(problems, fmt.Sprintf("config.yaml: %v", err))
+	}
+
+	runtime := map[string]interface{}{
+		"queueBackend": ar.queueSettings.Backend,
+		"queueRole":    ar.queueSettings.RoleName(),
+	}
+	if ar.Leader != nil {
+		runtime["replica"] = ar.Leader.Identity()
+		runtime["leader"] = ar.Leader.IsLeader()
+	}
+	for _, js := range ar.jobStatuses() {
+		if js.Name == name {
+			runtime["job"] = js
+		}
+	}
+	if ar.Queue != nil {
+		runtime["queue"] = ar.Queue.Stats()
+	}
+	addJSON("runtime.json", runtime)
+	if ar.Timings != nil {
+		addJSON("timings.json", ar.Timings.Snapshot())
+	}
+
+	if ar.Storage != nil {
+		transcripts, err := ar.Storage.ListTranscripts(model.TranscriptFilter{Workspace: auto.Workspace, RepoSlug: auto.RepoSlug, PullRequestID: prID, From: since})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("transcripts: %v", err))
+		}
+		metas := make([]transcriptMeta, 0, len(transcripts))
+		for _, t := range transcripts {
+			status := "ok"
+			if strings.TrimSpace(t.Response) == "" {
+				status = "empty"
+			} else if t.Kind == "inline" && len(t.Findings) == 0 {
+				status = "no findings"
+			}
+			metas = append(metas, transcriptMeta{
+				ID: t.ID, Kind: t.Kind, Path: t.Path, CreatedAt: t.CreatedAt,
+				PromptChars: len(t.Prompt), ResponseChars: len(t.Response), Findings: len(t.Findings), ResponseStatus: status,
+			})
+		}
+		addJSON("ai-exchanges.json", metas)
+
+		findings, err := ar.Storage.ListFindings(model.FindingFilter{Workspace: auto.Workspace, RepoSlug: auto.RepoSlug, From: since})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("findings: %v", err))
+		}
+		prFindings := []model.Finding{}
+		for _, f := range findings {
+			if f.PullRequestID == prID {
+				prFindings = append(prFindings, f)
+			}
+		}
+		addJSON("findings.json", prFindings)
+
+		sent, err := ar.Storage.ListNotifications(since)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("notifications: %v", err))
+		}
+		prSent := []model.SentNotification{}
+		for _, n := range sent {
+			if n.PullRequestID == prID && strings.EqualFold(n.Workspace, auto.Workspace) && strings.EqualFold(n.RepoSlug, auto.RepoSlug) {
+				prSent = append(prSent, n)
+			}
+		}
+		addJSON("notifications.json", prSent)
+	}
+
+	lines, err := bundleLogLines(prID, since)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("logs: %v", err))
+	}
+	add("logs.txt", []byte(strings.Join(lines, "\n")+"\n"))
+
+	addJSON("manifest.json", map[string]interface{}{
+		"generatedAt":   now,
+		"job":           name,
+		"workspace":     auto.Workspace,
+		"repoSlug":      auto.RepoSlug,
+		"pullRequestId": prID,
+		"days":          days,
+		"contents":      contents,
+		"problems":      problems,
+		"notes": "Credentials are redacted. Prompt and response text is left out; enable transcripts to inspect it. " +
+			"logs.txt holds every log line mentioning the PR number, which may include other repositories' PRs with the same number.",
+	})
+	if err := zw.Close(); err != nil {
+		log.Errorf("Failed to build support bundle for %s PR #%d: %v", name, prID, err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to build the support bundle",
+		})
+	}
+
+	file := fmt.Sprintf("code-nim-support-%s-pr%d-%s.zip", strings.ReplaceAll(name, "/", "_"), prID, now.Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", file))
+	log.Infof("Built support bundle for %s pull request %d (%d files)", name, prID, len(contents)+1)
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
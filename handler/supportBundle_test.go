@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"code_nim/log"
+	"code_nim/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSupportBundleRedactsNotifiers(t *testing.T) {
+	const (
+		slackURL     = "https://hooks.slack.com/services/T000/B000/XXXXXXXXXXXXXXXXXXXXXXXX"
+		teamsURL     = "https://example.webhook.office.com/webhookb2/teams-token-123456"
+		bearer       = "Bearer hook-token-abcdef"
+		smtpPassword = "smtp-password-123456"
+	)
+	auto := model.AutoReviewPR{
+		Workspace: "acme",
+		RepoSlug:  "api",
+		Notifiers: []model.NotifierSettings{
+			{Type: "slack", URL: slackURL},
+			{Type: "webhook", URL: "https://hooks.example.com/review", Headers: map[string]string{"Authorization": bearer}},
+			{Type: "email", SMTP: model.SMTPSettings{Host: "smtp.example.com", Username: "bot", Password: smtpPassword}},
+		},
+		PackageProfiles: map[string]model.PackageProfile{
+			"payments": {Notifiers: []model.NotifierSettings{{Type: "teams", URL: teamsURL}}},
+		},
+	}
+	secrets := []string{slackURL, teamsURL, bearer, smtpPassword}
+
+	// bundleLogLines reads log.Dir() relative to the working directory.
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir("a/b")
+	logDir := filepath.Join(log.Dir(), "info")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logLine := "Failed to notify about PR #7: POST " + slackURL + " with " + bearer + " and " + smtpPassword + "\n"
+	if err := os.WriteFile(filepath.Join(logDir, "app.log"), []byte(logLine), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ar := &AutoReviewPRHandler{entries: map[string]model.AutoReviewPR{entryKey(auto): auto}}
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/acme%2Fapi/support-bundle?pr=7", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues("acme%2Fapi")
+	if err := ar.SupportBundle(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs string
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range secrets {
+			if bytes.Contains(data, []byte(s)) {
+				t.Errorf("%s contains %q", f.Name, s)
+			}
+		}
+		if f.Name == "logs.txt" {
+			logs = string(data)
+		}
+	}
+	if !strings.Contains(logs, "Failed to notify about PR #7") {
+		t.Errorf("logs.txt lacks the PR's log line: %q", logs)
+	}
+
+	// The bundle masks a copy; the job's own config keeps its notifiers.
+	if got, _ := ar.entry(entryKey(auto)); got.Notifiers[0].URL != slackURL || got.PackageProfiles["payments"].Notifiers[0].URL != teamsURL {
+		t.Error("SupportBundle changed the job's config")
+	}
+}
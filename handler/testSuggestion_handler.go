@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"code_nim/review"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const testsMarkerPrefix = "<!-- auto-review-tests:"
+
+// testsMarker identifies the test suggestions for one file, so each file of a pull request gets
+// them at most once however many commits touch it.
+func testsMarker(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return testsMarkerPrefix + hex.EncodeToString(sum[:])[:12] + " -->"
+}
+
+// testsStage posts, when testSuggestions is enabled, one comment recommending specific test
+// cases for each changed source file that has no changed test file and no suggestions yet.
+// At most testSuggestions.maxFiles files are covered per run. A failure is logged; the review
+// itself is not affected.
+func (ar *AutoReviewPRHandler) testsStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.TestSuggestions.Enabled || run.SkipInline {
+		return nil
+	}
+	diff := run.Diff
+	if run.UseDeltaDiff {
+		// A test changed in an earlier commit of the pull request counts as well.
+		full, err := ar.Bitbucket.FetchPullRequestDiff(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword)
+		if err != nil {
+			log.Errorf("Error fetching full diff to suggest tests for PR #%d: %v", pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+			return nil
+		}
+		diff = full
+	}
+	reviewer := ar.reviewer(auto, run)
+	parsed := reviewer.ParseDiff(diff)
+	hunksByPath := map[string][]review.Hunk{}
+	for _, file := range parsed {
+		hunksByPath[file.Path] = file.Hunks
+	}
+
+	var suggestions []helper.TestSuggestion
+	var markers []string
+	for _, path := range reviewer.UntestedFiles(parsed) {
+		if len(markers) >= auto.TestSuggestions.FileLimit() {
+			break
+		}
+		marker := testsMarker(path)
+		if hasBotComment(run.Comments, marker) {
+			continue
+		}
+		cases, err := reviewer.SuggestTests(pr, path, hunksByPath[path])
+		if errors.Is(err, review.ErrEmptySnippet) {
+			continue
+		}
+		ar.noteProviderResult(auto, err)
+		if err != nil {
+			log.Errorf("AI test suggestion error for %s in PR #%d: %v", path, pr.ID, err)
+			ar.noteAIError(run)
+			if abortsRun(err) {
+				break
+			}
+			continue
+		}
+		ar.recordTranscript(model.Transcript{
+			Kind:          "tests",
+			ProcessName:   auto.ProcessName,
+			Workspace:     auto.Workspace,
+			RepoSlug:      auto.RepoSlug,
+			PullRequestID: pr.ID,
+			Path:          path,
+			Prompt:        reviewer.TestsPrompt(pr, path, hunksByPath[path]),
+			Response:      cases,
+		})
+		// Files without behaviour worth testing are marked in the comment as well, so they are
+		// not asked about again once it is posted
+		markers = append(markers, marker)
+		if cases != "" {
+			suggestions = append(suggestions, helper.TestSuggestion{Path: path, Cases: cases})
+		}
+	}
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	body := helper.FormatTestSuggestions(suggestions) + strings.Join(markers, "\n") + "\n" + reviewBotMarker
+	posted, err := ar.deliverComment(auto, pr, run.LatestCommitHash, "tests:"+strings.Join(markers, ""), func() error {
+		publishStart := time.Now()
+		defer ar.observe(run, "publish", publishStart)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, body))
+	})
+	if err != nil {
+		log.Errorf("Failed to post test suggestions on PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	if posted {
+		log.Infof("✓ Posted test suggestions for %d files on PR #%d", len(suggestions), pr.ID)
+	}
+	return nil
+}
+
+// hasBotComment reports whether a live general comment of comments contains marker.
+func hasBotComment(comments []model.PullRequestComment, marker string) bool {
+	for _, c := range comments {
+		if c.Inline == nil && !c.Deleted && strings.Contains(c.Content.Raw, marker) {
+			return true
+		}
+	}
+	return false
+}
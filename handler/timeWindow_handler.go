@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// postingGuard holds the review of a pull request outside the entry's active hours or inside
+// its quiet hours. Nothing is generated or posted and the pull request is not marked reviewed;
+// the job runs again as soon as posting is allowed.
+func (ar *AutoReviewPRHandler) postingGuard(next reviewStage) reviewStage {
+	return wrapStage(next, func(run *reviewRun) error {
+		auto := run.Auto
+		allowed, opens := helper.PostingAllowed(auto.ActiveHours, auto.QuietHours, time.Now())
+		if allowed {
+			return next.Run(run)
+		}
+		run.Deferred = true
+		if opens.IsZero() {
+			run.Skip = "posting is closed all week by activeHours and quietHours"
+			return nil
+		}
+		run.Skip = fmt.Sprintf("outside active hours, held until %s", opens.UTC().Format(time.RFC3339))
+		ar.scheduleWindowOpen(*auto, opens)
+		return nil
+	})
+}
+
+// scheduleWindowOpen runs the job of auto again at opens, once per opening, so the reviews held
+// by postingGuard are posted when the window opens rather than at the next cron tick.
+func (ar *AutoReviewPRHandler) scheduleWindowOpen(auto model.AutoReviewPR, opens time.Time) {
+	key := entryKey(auto)
+	ar.statsMutex.Lock()
+	if ar.windowRuns == nil {
+		ar.windowRuns = make(map[string]time.Time)
+	}
+	if ar.windowRuns[key].Equal(opens) {
+		ar.statsMutex.Unlock()
+		return
+	}
+	ar.windowRuns[key] = opens
+	ar.statsMutex.Unlock()
+
+	_, err := ar.scheduler.NewJob(
+		gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(opens)),
+		gocron.NewTask(ar.safeTask("posting window of "+key, func() {
+			ar.statsMutex.Lock()
+			delete(ar.windowRuns, key)
+			ar.statsMutex.Unlock()
+			if latest, ok := ar.entry(key); ok {
+				log.Infof("Posting window of %s is open; reviewing held pull requests", key)
+				ar.scheduleReview(latest)
+			}
+		})),
+	)
+	if err != nil {
+		log.Errorf("Failed to schedule the held reviews of %s: %v; they wait for the next run", key, err)
+		ar.statsMutex.Lock()
+		delete(ar.windowRuns, key)
+		ar.statsMutex.Unlock()
+		return
+	}
+	log.Infof("Holding reviews of %s until %s (activeHours/quietHours)", key, opens.UTC().Format(time.RFC3339))
+}
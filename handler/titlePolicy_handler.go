@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const titleMarkerPrefix = "<!-- auto-review-title:"
+
+// titleMarker identifies the reminder for one title, so a PR gets one reminder per title
+// rather than one per scan, and a new one when the title is changed to another bad title.
+func titleMarker(title string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(title)))
+	return titleMarkerPrefix + hex.EncodeToString(sum[:])[:12] + " -->"
+}
+
+// titleNotice renders the reminder posted when the PR title does not follow the policy.
+func titleNotice(title, reason, suggestion string) string {
+	var b strings.Builder
+	b.WriteString("## ✏️ PR title convention\n\n")
+	fmt.Fprintf(&b, "The title `%s` does not follow this repository's convention: %s.\n\n", title, reason)
+	if suggestion != "" {
+		fmt.Fprintf(&b, "Suggested title:\n\n```\n%s\n```\n\n", suggestion)
+	}
+	b.WriteString("This is a reminder only; the review is not affected. Editing the title is enough, no reply is needed.\n\n")
+	b.WriteString(titleMarker(title) + "\n" + reviewBotMarker)
+	return b.String()
+}
+
+// latestBotSummary returns the newest summary comment the bot posted, or "".
+func latestBotSummary(comments []model.PullRequestComment) string {
+	summary := ""
+	for _, c := range comments {
+		if c.Inline == nil && !c.Deleted && strings.Contains(c.Content.Raw, reviewMarkerPrefix) {
+			summary = c.Content.Raw
+		}
+	}
+	return summary
+}
+
+// suggestTitle asks the AI for a title following the policy, based on the summary generated
+// in this run or the latest posted one. It returns "" when no valid title could be produced.
+func (ar *AutoReviewPRHandler) suggestTitle(run *reviewRun) string {
+	auto, pr := run.Auto, run.PR
+	summary := run.SummaryText
+	if summary == "" {
+		summary = latestBotSummary(run.Comments)
+	}
+	if summary == "" {
+		log.Debugf("PR #%d: no summary to suggest a title from", pr.ID)
+		return ""
+	}
+	suggestion, err := ar.reviewer(auto, run).SuggestTitle(pr, summary, helper.TitleRules(auto.TitlePolicy))
+	ar.noteProviderResult(auto, err)
+	if err != nil {
+		log.Errorf("AI title suggestion error for PR #%d: %v", pr.ID, err)
+		ar.noteAIError(run)
+		return ""
+	}
+	if ok, _ := helper.CheckTitle(auto.TitlePolicy, suggestion); !ok || suggestion == "" {
+		log.Warnf("PR #%d: discarding suggested title %q that does not follow the policy", pr.ID, suggestion)
+		return ""
+	}
+	return suggestion
+}
+
+// titleStage posts a reminder, once per title, when the title of the pull request does not
+// follow titlePolicy. With titlePolicy.task it also opens a task on the pull request, or adds
+// the task to the reminder as a checklist item when the provider has no tasks.
+func (ar *AutoReviewPRHandler) titleStage(run *reviewRun) error {
+	auto, pr := run.Auto, run.PR
+	if !auto.TitlePolicy.Enabled() {
+		return nil
+	}
+	ok, reason := helper.CheckTitle(auto.TitlePolicy, pr.Title)
+	if ok {
+		return nil
+	}
+	marker := titleMarker(pr.Title)
+	for _, comment := range run.Comments {
+		if comment.Inline == nil && strings.Contains(comment.Content.Raw, marker) {
+			return nil
+		}
+	}
+	log.Infof("PR #%d title %q does not follow the title policy: %s", pr.ID, pr.Title, reason)
+
+	suggestion := ""
+	if auto.TitlePolicy.SuggestTitle {
+		suggestion = ar.suggestTitle(run)
+	}
+	task := "Update the PR title to follow the title convention"
+	if suggestion != "" {
+		task += ", e.g. " + suggestion
+	}
+	notice := titleNotice(pr.Title, reason, suggestion)
+	openTask := auto.TitlePolicy.Task && ar.Bitbucket.Capabilities().Tasks
+	if auto.TitlePolicy.Task && !openTask {
+		notice += "\n\n- [ ] " + task
+	}
+	posted, err := ar.deliverComment(auto, pr, "", "title:"+marker, func() error {
+		publishStart := time.Now()
+		defer ar.observe(run, "publish", publishStart)
+		return ar.Bitbucket.PushPullRequestComment(pr.ID, auto.Workspace, auto.RepoSlug, auto.Username, auto.AppPassword, ar.withFooter(auto, notice))
+	})
+	if err != nil {
+		log.Errorf("Failed to post title reminder on PR #%d: %v", pr.ID, err)
+		ar.noteJobError(jobErrorAPI)
+		return nil
+	}
+	if !posted {
+		return nil
+	}
+	log.Infof("✓ Posted title reminder on PR #%d", pr.ID)
+
+	if openTask {
+		if err := ar.Bitbucket.CreatePullRequestTask(pr.ID, auto.Workspace, auto.RepoSlug, task, auto.Username, auto.AppPassword); err != nil {
+			log.Errorf("Failed to open title task on PR #%d: %v", pr.ID, err)
+			ar.noteJobError(jobErrorAPI)
+		}
+	}
+	return nil
+}
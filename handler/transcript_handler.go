@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/labstack/echo/v4"
+)
+
+type TranscriptHandler struct {
+	Storage   storage.Storage
+	Settings  model.TranscriptSettings
+	Retention model.RetentionSettings
+	AICache   model.AICacheSettings
+}
+
+func (th *TranscriptHandler) retentionDays() int {
+	if th.Settings.RetentionDays <= 0 {
+		return 30
+	}
+	return th.Settings.RetentionDays
+}
+
+// PurgeExpired removes transcripts older than the configured retention, then the rest of the
+// review history whose own retention has passed.
+func (th *TranscriptHandler) PurgeExpired() (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -th.retentionDays())
+	purged, err := th.Storage.PurgeTranscripts(cutoff)
+	if err != nil {
+		log.Errorf("Failed to purge transcripts older than %s: %v", cutoff.Format(time.RFC3339), err)
+		return purged, err
+	}
+	log.Infof("Purged %d transcripts older than %d days", purged, th.retentionDays())
+	th.purgeHistory()
+	return purged, nil
+}
+
+// purgeHistory removes findings, feedback and risk scores older than their own retention, and
+// the cached AI replies too old to be reused. Kinds without a retention are kept.
+func (th *TranscriptHandler) purgeHistory() {
+	kinds := []struct {
+		name  string
+		days  int
+		purge func(time.Time) (int, error)
+	}{
+		{"findings", th.Retention.FindingsDays, th.Storage.PurgeFindings},
+		{"feedback records", th.Retention.FeedbackDays, th.Storage.PurgeFeedback},
+		{"risk scores", th.Retention.RiskScoresDays, th.Storage.PurgeRiskScores},
+	}
+	for _, k := range kinds {
+		if k.days <= 0 {
+			continue
+		}
+		purged, err := k.purge(time.Now().AddDate(0, 0, -k.days))
+		if err != nil {
+			log.Errorf("Failed to purge %s older than %d days: %v", k.name, k.days, err)
+			continue
+		}
+		log.Infof("Purged %d %s older than %d days", purged, k.name, k.days)
+	}
+
+	maxAge, _ := th.AICache.MaxAgeDuration()
+	purged, err := th.Storage.PurgeAIResponses(time.Now().Add(-maxAge))
+	if err != nil {
+		log.Errorf("Failed to purge cached AI replies older than %s: %v", maxAge, err)
+		return
+	}
+	log.Infof("Purged %d cached AI replies older than %s", purged, maxAge)
+}
+
+// HandlerTranscriptRetention schedules the automatic purge of expired transcripts and review history.
+// It runs even when transcript recording is off because findings and AI replies may still be stored.
+func (th *TranscriptHandler) HandlerTranscriptRetention() {
+	cron := th.Settings.PurgeCron
+	if cron == "" {
+		cron = "0 3 * * *"
+	}
+
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		log.Errorf("Failed to create scheduler: %v", err)
+		return
+	}
+	_, err = s.NewJob(
+		gocron.CronJob(cron, false),
+		gocron.NewTask(func() { _, _ = th.PurgeExpired() }),
+	)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	log.Infof("Setup Transcript Retention ==> %s (keep %d days)", cron, th.retentionDays())
+	s.Start()
+}
+
+// PurgeTranscripts handles POST /api/v1/transcripts/purge and its older alias /api/transcripts/purge.
+// Optional query olderThanDays overrides the configured retention; 0 purges every transcript.
+// Findings, feedback and risk scores are left to their own retention.
+func (th *TranscriptHandler) PurgeTranscripts(c echo.Context) error {
+	cutoff := time.Now().AddDate(0, 0, -th.retentionDays())
+	if raw := c.QueryParam("olderThanDays"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 0 {
+			return c.JSON(http.StatusBadRequest, model.Response{
+				StatusCode: http.StatusBadRequest,
+				Message:    "olderThanDays must be a non-negative integer",
+			})
+		}
+		cutoff = time.Now().AddDate(0, 0, -days)
+	}
+
+	purged, err := th.Storage.PurgeTranscripts(cutoff)
+	if err != nil {
+		log.Errorf("On-demand transcript purge failed: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	log.Infof("On-demand purge removed %d transcripts created before %s", purged, cutoff.Format(time.RFC3339))
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Transcripts purged",
+		Data: map[string]interface{}{
+			"purged":    purged,
+			"olderThan": cutoff,
+		},
+	})
+}
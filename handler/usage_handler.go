@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"code_nim/helper/usage"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type UsageHandler struct {
+	Tracker *usage.Tracker
+}
+
+// GetUsage handles GET /api/v1/usage.
+// Optional queries from and to (YYYY-MM-DD) select the days, default today; workspace and
+// repoSlug filter the records.
+func (uh *UsageHandler) GetUsage(c echo.Context) error {
+	now := time.Now()
+	from, to := now, now
+	var err error
+	if raw := c.QueryParam("from"); raw != "" {
+		if from, err = time.ParseInLocation("2006-01-02", raw, now.Location()); err != nil {
+			return c.JSON(http.StatusBadRequest, model.Response{
+				StatusCode: http.StatusBadRequest,
+				Message:    "from must be a date formatted as YYYY-MM-DD",
+			})
+		}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		if to, err = time.ParseInLocation("2006-01-02", raw, now.Location()); err != nil {
+			return c.JSON(http.StatusBadRequest, model.Response{
+				StatusCode: http.StatusBadRequest,
+				Message:    "to must be a date formatted as YYYY-MM-DD",
+			})
+		}
+	}
+	if to.Before(from) || to.Sub(from) > 366*24*time.Hour {
+		return c.JSON(http.StatusBadRequest, model.Response{
+			StatusCode: http.StatusBadRequest,
+			Message:    "from must not be after to and the range is limited to one year",
+		})
+	}
+
+	records, err := uh.Tracker.Records(from, to)
+	if err != nil {
+		log.Errorf("Failed to load AI usage: %v", err)
+		return c.JSON(http.StatusInternalServerError, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+		})
+	}
+	workspace, repoSlug := c.QueryParam("workspace"), c.QueryParam("repoSlug")
+	filtered := []model.UsageRecord{}
+	for _, r := range records {
+		if (workspace == "" || strings.EqualFold(r.Workspace, workspace)) && (repoSlug == "" || strings.EqualFold(r.RepoSlug, repoSlug)) {
+			filtered = append(filtered, r)
+		}
+	}
+
+	exceeded, reason := uh.Tracker.BudgetExceeded()
+	settings := uh.Tracker.Settings()
+	return c.JSON(http.StatusOK, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "AI usage",
+		Data: map[string]interface{}{
+			"records": filtered,
+			"budget": map[string]interface{}{
+				"dailyTokenBudget": settings.DailyTokenBudget,
+				"dailyCostBudget":  settings.DailyCostBudget,
+				"exceeded":         exceeded,
+				"reason":           reason,
+			},
+		},
+	})
+}
+
+// writeUsageMetrics appends today's AI usage per repository in Prometheus text format.
+func writeUsageMetrics(b *strings.Builder, t *usage.Tracker) {
+	records := t.Today()
+	b.WriteString("# HELP code_nim_ai_calls_today AI calls made today.\n# TYPE code_nim_ai_calls_today gauge\n")
+	for _, r := range records {
+		fmt.Fprintf(b, "code_nim_ai_calls_today{workspace=%q,repo=%q} %d\n", r.Workspace, r.RepoSlug, r.Calls)
+	}
+	b.WriteString("# HELP code_nim_ai_tokens_today AI tokens used today.\n# TYPE code_nim_ai_tokens_today gauge\n")
+	for _, r := range records {
+		fmt.Fprintf(b, "code_nim_ai_tokens_today{workspace=%q,repo=%q,kind=\"prompt\"} %d\n", r.Workspace, r.RepoSlug, r.PromptTokens)
+		fmt.Fprintf(b, "code_nim_ai_tokens_today{workspace=%q,repo=%q,kind=\"response\"} %d\n", r.Workspace, r.RepoSlug, r.ResponseTokens)
+		fmt.Fprintf(b, "code_nim_ai_tokens_today{workspace=%q,repo=%q,kind=\"cached\"} %d\n", r.Workspace, r.RepoSlug, r.CachedTokens)
+	}
+	b.WriteString("# HELP code_nim_ai_cost_usd_today Estimated AI cost in USD today.\n# TYPE code_nim_ai_cost_usd_today gauge\n")
+	for _, r := range records {
+		fmt.Fprintf(b, "code_nim_ai_cost_usd_today{workspace=%q,repo=%q} %g\n", r.Workspace, r.RepoSlug, r.CostUSD)
+	}
+	exceeded := 0
+	if ok, _ := t.BudgetExceeded(); ok {
+		exceeded = 1
+	}
+	b.WriteString("# HELP code_nim_ai_budget_exceeded 1 when the daily AI budget is spent and reviews are paused.\n# TYPE code_nim_ai_budget_exceeded gauge\n")
+	fmt.Fprintf(b, "code_nim_ai_budget_exceeded %d\n", exceeded)
+}
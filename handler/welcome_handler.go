@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// firstTimeWelcome returns the welcome block of the first summary when the author of pr has no
+// merged pull request in the repository yet. It returns "" for returning contributors, when the
+// author is unknown, or when the lookup fails, so a provider error never blocks the review.
+func (ar *AutoReviewPRHandler) firstTimeWelcome(auto *model.AutoReviewPR, pr *model.PullRequest) string {
+	if !auto.WelcomeFirstTimeContributors || pr.Author.UUID == "" {
+		return ""
+	}
+	merged, err := ar.Bitbucket.CountMergedPullRequests(auto.Workspace, auto.RepoSlug, pr.Author.UUID, auto.Username, auto.AppPassword)
+	if err != nil {
+		log.Warnf("Could not check whether %s is a first-time contributor to %s/%s: %v", pr.Author.DisplayName, auto.Workspace, auto.RepoSlug, err)
+		return ""
+	}
+	if merged > 0 {
+		return ""
+	}
+	log.Infof("PR #%d is the first contribution of %s to %s/%s; posting a welcome summary", pr.ID, pr.Author.DisplayName, auto.Workspace, auto.RepoSlug)
+	return welcomeBlock(auto, pr)
+}
+
+// welcomeBlock renders the greeting shown above the summary for a first-time contributor.
+func welcomeBlock(auto *model.AutoReviewPR, pr *model.PullRequest) string {
+	name := strings.TrimSpace(pr.Author.DisplayName)
+	if name == "" {
+		name = "and thanks for your contribution"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "👋 **Welcome, %s!** This looks like your first pull request to `%s/%s`. ", name, auto.Workspace, auto.RepoSlug)
+	b.WriteString("The summary below explains what the change does and points out patterns used in this codebase. ")
+	b.WriteString("Review comments are suggestions to discuss, not verdicts: reply on a comment if something is unclear.\n\n")
+	if guide := strings.TrimSpace(auto.ContributingGuideURL); guide != "" {
+		fmt.Fprintf(&b, "Before the next round, please read the [contribution guide](%s).\n\n", guide)
+	}
+	return b.String()
+}
+
+// welcomeConfig returns the entry used to review a first-time contribution: the same settings
+// with the mentoring tone, so the summary explains the change for a newcomer.
+func welcomeConfig(auto *model.AutoReviewPR) *model.AutoReviewPR {
+	welcome := *auto
+	welcome.ReviewStyle = helper.ToneMentoring
+	return &welcome
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AIScheduler admits AI requests under limits shared by all jobs (see package
+// helper/ratelimit). Acquire blocks until the request may be sent; done reports the tokens it
+// used and whether the provider still rate limited it.
+type AIScheduler interface {
+	Acquire(repo, modelName string, promptTokens int) (done func(tokens int, rateLimited bool), err error)
+}
+
+var (
+	aiSchedulerMutex sync.RWMutex
+	aiScheduler      AIScheduler
+)
+
+// SetAIScheduler makes every AI call wait for a slot of s; nil sends calls at once.
+func SetAIScheduler(s AIScheduler) {
+	aiSchedulerMutex.Lock()
+	defer aiSchedulerMutex.Unlock()
+	aiScheduler = s
+}
+
+// EstimateTokens roughly counts the tokens of text, at four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// withAISlot runs call, the AI request for prompt, once the AI scheduler admits it, and
+// reports the usage call fills in back to the scheduler. A request that found no slot in
+// time fails with ErrRateLimited, so the run stops like on a 429 and is retried later.
+func withAISlot(cfg *model.AutoReviewPR, prompt string, call func(usage *model.AIUsage) error) error {
+	var usage model.AIUsage
+	aiSchedulerMutex.RLock()
+	s := aiScheduler
+	aiSchedulerMutex.RUnlock()
+	if s == nil {
+		return call(&usage)
+	}
+	done, err := s.Acquire(cfg.Workspace+"/"+cfg.RepoSlug, AIModelName(cfg), EstimateTokens(prompt))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRateLimited, err)
+	}
+	err = call(&usage)
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.PromptTokens + usage.ResponseTokens
+	}
+	done(int(tokens), errors.Is(err, ErrRateLimited))
+	return err
+}
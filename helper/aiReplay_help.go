@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRecording marks a prompt without a stored reply in aiReplay mode replay.
+var ErrNoRecording = errors.New("no recorded AI reply for this prompt")
+
+var (
+	aiReplayMutex sync.RWMutex
+	aiReplayMode  string
+	aiReplayDir   string
+)
+
+// SetAIReplay makes every AI call record its reply in dir or answer from it, per mode
+// ("record", "replay" or "auto"); any other mode calls the provider as usual.
+func SetAIReplay(mode, dir string) {
+	aiReplayMutex.Lock()
+	defer aiReplayMutex.Unlock()
+	aiReplayMode, aiReplayDir = mode, dir
+}
+
+// aiReplayKey hashes what determines a reply: the kind of call, provider, model and prompt.
+func aiReplayKey(kind string, cfg *model.AutoReviewPR, prompt string) string {
+	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
+	sum := sha256.Sum256([]byte(strings.Join([]string{kind, provider, AIModelName(cfg), prompt}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// withAIReplay answers rec from the stored reply of its prompt or runs call, storing its
+// reply, as the replay mode asks. call fills rec.Text or rec.Findings.
+func withAIReplay(rec *model.AIRecording, cfg *model.AutoReviewPR, call func() error) error {
+	aiReplayMutex.RLock()
+	mode, dir := aiReplayMode, aiReplayDir
+	aiReplayMutex.RUnlock()
+	switch mode {
+	case model.AIReplayRecord, model.AIReplayReplay, model.AIReplayAuto:
+	default:
+		return call()
+	}
+	rec.Key = aiReplayKey(rec.Kind, cfg, rec.Prompt)
+	file := filepath.Join(dir, rec.Key[:2], rec.Key+".json")
+	if mode == model.AIReplayReplay || mode == model.AIReplayAuto {
+		raw, err := os.ReadFile(file)
+		if err == nil {
+			var stored model.AIRecording
+			if err := json.Unmarshal(raw, &stored); err != nil {
+				return fmt.Errorf("decode AI recording %s: %w", file, err)
+			}
+			log.Debugf("Replaying AI %s reply %s", rec.Kind, rec.Key[:12])
+			rec.Text, rec.Findings = stored.Text, stored.Findings
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if mode == model.AIReplayReplay {
+			return fmt.Errorf("%w (key %s)", ErrNoRecording, rec.Key[:12])
+		}
+	}
+	if err := call(); err != nil {
+		return err
+	}
+	rec.Provider = strings.ToLower(strings.TrimSpace(cfg.AIProvider))
+	rec.Model = AIModelName(cfg)
+	rec.RecordedAt = time.Now().UTC()
+	if err := writeAIRecording(file, *rec); err != nil {
+		log.Warnf("Failed to record AI %s reply %s: %v", rec.Kind, rec.Key[:12], err)
+	}
+	return nil
+}
+
+// writeAIRecording stores rec in file atomically.
+func writeAIRecording(file string, rec model.AIRecording) error {
+	raw, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", file, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,276 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Outcomes of streamed review replies, counted by AIStreamStats.
+const (
+	AIStreamComplete  = "complete"  // the reply streamed to its end
+	AIStreamAborted   = "aborted"   // the reply left the JSON format and was stopped early
+	AIStreamTruncated = "truncated" // the reply reached the output token limit
+)
+
+// maxStreamPreamble is how much text a streamed reply may open with before its JSON.
+const maxStreamPreamble = 200
+
+var aiStreams = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: map[string]int64{}}
+
+// AIStreamStats returns how many streamed review replies ended with each AIStream* outcome
+// since start.
+func AIStreamStats() map[string]int64 {
+	aiStreams.Lock()
+	defer aiStreams.Unlock()
+	out := make(map[string]int64, len(aiStreams.counts))
+	for k, v := range aiStreams.counts {
+		out[k] = v
+	}
+	return out
+}
+
+func countAIStream(result string) {
+	aiStreams.Lock()
+	aiStreams.counts[result]++
+	aiStreams.Unlock()
+}
+
+// errOffFormat marks a streamed reply that stopped being a {"reviews": [...]} object.
+var errOffFormat = errors.New("reply left the JSON format")
+
+// streamValidator checks a review reply as it streams in: a short preamble or code fence may
+// come first, then one JSON object whose first key is "reviews" (or an array), with balanced
+// brackets outside of strings. What follows the closed value is kept but not checked.
+type streamValidator struct {
+	text     strings.Builder
+	preamble int
+	stack    []byte // open brackets of the JSON value
+	started  bool
+	closed   bool
+	inString bool
+	escaped  bool
+	keyState int // 0 before the first key of the object, 1 reading it, 2 checked
+	key      strings.Builder
+}
+
+// Feed adds chunk to the reply and returns an error wrapping errOffFormat as soon as the reply
+// cannot become a valid review anymore.
+func (v *streamValidator) Feed(chunk string) error {
+	v.text.WriteString(chunk)
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+		if v.closed {
+			continue
+		}
+		if !v.started {
+			switch c {
+			case '{', '[':
+				v.started = true
+				v.stack = append(v.stack, c)
+				if c == '[' {
+					v.keyState = 2
+				}
+			case ' ', '\t', '\r', '\n':
+			default:
+				if v.preamble++; v.preamble > maxStreamPreamble {
+					return fmt.Errorf("%w: no JSON in the first %d characters", errOffFormat, maxStreamPreamble)
+				}
+			}
+			continue
+		}
+		if v.inString {
+			switch {
+			case v.escaped:
+				v.escaped = false
+			case c == '\\':
+				v.escaped = true
+			case c == '"':
+				v.inString = false
+				if v.keyState == 1 {
+					v.keyState = 2
+					if key := v.key.String(); key != "reviews" {
+						return fmt.Errorf("%w: the object starts with %q instead of \"reviews\"", errOffFormat, key)
+					}
+				}
+				continue
+			}
+			if v.keyState == 1 {
+				v.key.WriteByte(c)
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			v.inString = true
+			if v.keyState == 0 {
+				v.keyState = 1
+			}
+		case '{', '[':
+			if v.keyState == 0 {
+				return fmt.Errorf("%w: the object opens with %q instead of a key", errOffFormat, c)
+			}
+			v.stack = append(v.stack, c)
+		case '}', ']':
+			open := byte('{')
+			if c == ']' {
+				open = '['
+			}
+			if v.stack[len(v.stack)-1] != open {
+				return fmt.Errorf("%w: unbalanced %q after %d characters", errOffFormat, c, v.text.Len()-len(chunk)+i)
+			}
+			v.stack = v.stack[:len(v.stack)-1]
+			v.closed = len(v.stack) == 0
+		}
+	}
+	return nil
+}
+
+// String returns the reply streamed so far.
+func (v *streamValidator) String() string {
+	return v.text.String()
+}
+
+// readSSE passes the data of each server-sent event of body to onData until it returns an
+// error or the stream ends.
+func readSSE(body io.Reader, onData func(data []byte) error) error {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	var data []byte
+	flush := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		d := data
+		data = nil
+		return onData(d)
+	}
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if value, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(value, []byte(" "))...)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// geminiStreamURL turns a generateContent URL into its server-sent events streaming variant.
+func geminiStreamURL(url string) string {
+	url = strings.Replace(url, ":generateContent", ":streamGenerateContent", 1)
+	if strings.Contains(url, "?") {
+		return strings.Replace(url, "?", "?alt=sse&", 1)
+	}
+	return url + "?alt=sse"
+}
+
+// geminiCandidateText joins the text parts of the first candidate of a generateContent reply.
+func geminiCandidateText(result map[string]interface{}) string {
+	var b strings.Builder
+	if c, ok := result["candidates"].([]interface{}); ok && len(c) > 0 {
+		if candidate, ok := c[0].(map[string]interface{}); ok {
+			if content, ok := candidate["content"].(map[string]interface{}); ok {
+				parts, _ := content["parts"].([]interface{})
+				for _, p := range parts {
+					if part, ok := p.(map[string]interface{}); ok {
+						t, _ := part["text"].(string)
+						b.WriteString(t)
+					}
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// streamAIResponseOfGeminiAPI is getAIResponseOfGeminiAPI with the reply streamed as
+// server-sent events. Each chunk is checked as it arrives, and a reply that leaves the JSON
+// format is stopped at once with ErrAIInvalidResponse instead of being generated to its end.
+func streamAIResponseOfGeminiAPI(prompt string, url string, headers map[string]string, cachedContent string, usage *model.AIUsage) ([]model.ReviewComment, error) {
+	payload := map[string]interface{}{
+		"contents": []map[string]interface{}{{"parts": []map[string]string{{"text": prompt}}}},
+		"generationConfig": map[string]interface{}{
+			"maxOutputTokens": 8192,
+			"temperature":     0.8,
+			"topP":            0.95,
+		},
+	}
+	if cachedContent != "" {
+		payload["cachedContent"] = cachedContent
+	}
+	b, _ := json.Marshal(payload)
+	resp, err := postJSON(geminiStreamURL(url), headers, b)
+	if err != nil {
+		log.Errorf("Failed to make streaming request to Gemini API: %v", err)
+		return nil, err
+	}
+	// Closing the body early also stops the generation
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		var errorResult model.GeminiErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errorResult)
+		return nil, aiStatusError("gemini", resp.StatusCode, errorResult.Error.Message)
+	}
+
+	var v streamValidator
+	var finish string
+	var latest model.AIUsage // every chunk reports the usage so far
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk map[string]interface{}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("gemini: decode stream chunk: %w", err)
+		}
+		var u model.AIUsage
+		if extractUsage(chunk, &u); u.TotalTokens > 0 || u.PromptTokens > 0 {
+			latest = u
+		}
+		if reason := geminiFinishReason(chunk); reason != "" {
+			finish = reason
+		}
+		return v.Feed(geminiCandidateText(chunk))
+	})
+	if usage != nil {
+		usage.PromptTokens += latest.PromptTokens
+		usage.ResponseTokens += latest.ResponseTokens
+		usage.TotalTokens += latest.TotalTokens
+		usage.CachedTokens += latest.CachedTokens
+	}
+	if errors.Is(err, errOffFormat) {
+		countAIStream(AIStreamAborted)
+		text := v.String()
+		log.Warnf("Stopped the streamed gemini reply after %d characters: %v. Start: %s", len(text), err, text[:min(100, len(text))])
+		return nil, fmt.Errorf("gemini: %w: %v", ErrAIInvalidResponse, err)
+	}
+	if err != nil {
+		log.Errorf("Gemini stream failed: %v", err)
+		return nil, err
+	}
+	text := strings.TrimSpace(v.String())
+	if finish == "MAX_TOKENS" {
+		countAIStream(AIStreamTruncated)
+		return truncatedReviewComments(text, "gemini")
+	}
+	countAIStream(AIStreamComplete)
+	return parseReviewComments(text, "gemini")
+}
@@ -0,0 +1,220 @@
+// Package analysis runs linters on a checkout of a pull request branch, so their deterministic
+// findings can be given to the AI review alongside the diff.
+package analysis
+
+import (
+	"bytes"
+	"code_nim/log"
+	"code_nim/model"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFindingsPerFile caps the linter findings kept for one file.
+const maxFindingsPerFile = 30
+
+// passedEnv are the only variables of the service environment the clone and linter processes
+// inherit: tool locations, module and build caches, and proxies. This keeps credentials out of
+// their environment, but it is not a sandbox: a linter that runs code of the checkout, such as
+// eslint loading eslint.config.js, runs it as the service user, with access to the config file
+// and the data directory. Only branches of trusted repositories may be analyzed.
+var passedEnv = []string{"PATH", "GOPATH", "GOMODCACHE", "GOCACHE", "GOPROXY", "GOFLAGS", "GONOSUMDB", "GOPRIVATE",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy", "NODE_PATH"}
+
+// eslintExtensions are the changed files handed to eslint.
+var eslintExtensions = map[string]bool{".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true}
+
+// outputLine matches "path:line[:column]: message", optionally prefixed with "vet: ".
+var outputLine = regexp.MustCompile(`^(?:vet: )?([^\s:][^:]*):(\d+)(?::(\d+))?: (.+)$`)
+
+// Checkout describes the branch to analyze.
+type Checkout struct {
+	CloneURL string // e.g. https://bitbucket.org/workspace/repo.git
+	Branch   string
+	Username string
+	Password string
+}
+
+// Run clones the branch into a temporary directory, runs each linter there and returns the
+// findings on changedFiles keyed by path. The linters may run code of the branch, so checkout
+// must not be a branch of an untrusted fork. The directory is removed afterwards. A linter that
+// is not installed or fails without output is logged and skipped.
+func Run(settings model.StaticAnalysisSettings, checkout Checkout, changedFiles []string) (map[string][]model.LintFinding, error) {
+	dir, err := os.MkdirTemp("", "code-nim-analysis-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	home := filepath.Join(dir, "home")
+	if err := os.MkdirAll(home, 0o700); err != nil {
+		return nil, err
+	}
+	env := sandboxEnv(home)
+
+	timeout := settings.TimeoutDuration()
+	if err := clone(timeout, env, src, checkout); err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for _, f := range changedFiles {
+		changed[f] = true
+	}
+	found := map[string][]model.LintFinding{}
+	for _, l := range settings.Linters {
+		argv := linterCommand(l, src, changedFiles)
+		if len(argv) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			log.Warnf("Skipping linter %s: %s is not installed", l.Name, argv[0])
+			continue
+		}
+		output, err := runSandboxed(timeout, env, src, argv)
+		findings := ParseOutput(l.Name, src, output)
+		if err != nil && len(findings) == 0 {
+			log.Warnf("Linter %s failed: %v", l.Name, err)
+			continue
+		}
+		kept := 0
+		for _, f := range findings {
+			if !changed[f.Path] || len(found[f.Path]) >= maxFindingsPerFile {
+				continue
+			}
+			found[f.Path] = append(found[f.Path], f)
+			kept++
+		}
+		log.Infof("Linter %s reported %d findings, %d on changed files", l.Name, len(findings), kept)
+	}
+	return found, nil
+}
+
+// sandboxEnv returns the environment of clone and linter processes.
+func sandboxEnv(home string) []string {
+	env := []string{"HOME=" + home, "GIT_TERMINAL_PROMPT=0", "CI=true"}
+	for _, k := range passedEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+	return env
+}
+
+// clone fetches the tip of the branch. Credentials are passed as an HTTP header through git's
+// environment config, so they appear neither in the process list nor in the checkout.
+func clone(timeout time.Duration, env []string, dst string, c Checkout) error {
+	if c.CloneURL == "" || c.Branch == "" {
+		return errors.New("pull request source branch is unknown")
+	}
+	if c.Username != "" || c.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+		env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic "+auth)
+	}
+	argv := []string{"git", "clone", "--quiet", "--depth", "1", "--single-branch", "--branch", c.Branch, c.CloneURL, dst}
+	if output, err := runSandboxed(timeout, env, "", argv); err != nil {
+		return fmt.Errorf("clone %s: %v: %s", c.Branch, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// runSandboxed runs argv in dir with env only, returning combined output. It does not isolate
+// the process beyond its environment (see passedEnv).
+func runSandboxed(timeout time.Duration, env []string, dir string, argv []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return out.Bytes(), fmt.Errorf("timed out after %s", timeout)
+	}
+	return out.Bytes(), err
+}
+
+// linterCommand returns the command line of l, or nil when it has nothing to check.
+func linterCommand(l model.LinterSettings, root string, changedFiles []string) []string {
+	if len(l.Command) > 0 {
+		return l.Command
+	}
+	switch l.Name {
+	case model.LinterGoVet, model.LinterStaticcheck:
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+			log.Debugf("Skipping linter %s: no go.mod at the repository root", l.Name)
+			return nil
+		}
+		if l.Name == model.LinterGoVet {
+			return []string{"go", "vet", "./..."}
+		}
+		return []string{"staticcheck", "./..."}
+	case model.LinterESLint:
+		argv := []string{"eslint", "--format", "unix", "--no-error-on-unmatched-pattern"}
+		n := len(argv)
+		for _, f := range changedFiles {
+			if eslintExtensions[path.Ext(f)] {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(f))); err == nil {
+					argv = append(argv, f)
+				}
+			}
+		}
+		if len(argv) == n {
+			return nil
+		}
+		return argv
+	}
+	log.Warnf("Skipping unknown linter %q: set command for custom linters", l.Name)
+	return nil
+}
+
+// ParseOutput extracts "path:line[:column]: message" findings from linter output. Paths are
+// made relative to root and use forward slashes.
+func ParseOutput(linter, root string, output []byte) []model.LintFinding {
+	var findings []model.LintFinding
+	prefix := filepath.ToSlash(root) + "/"
+	for _, ln := range strings.Split(string(output), "\n") {
+		m := outputLine.FindStringSubmatch(strings.TrimSpace(ln))
+		if m == nil {
+			continue
+		}
+		p := strings.TrimPrefix(filepath.ToSlash(m[1]), prefix)
+		p = strings.TrimPrefix(p, "./")
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		findings = append(findings, model.LintFinding{Linter: linter, Path: p, Line: line, Column: col, Message: strings.TrimSpace(m[4])})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// PromptContext renders the findings of one file for its review prompt, or "" when there are none.
+func PromptContext(findings []model.LintFinding) string {
+	if len(findings) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nStatic analysis findings for this file (deterministic linter output on the PR branch, new-file line numbers). Confirm the ones on changed lines, explain their impact and how to fix them; do not report findings on lines outside the diff:\n")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "- [%s] line %d: %s\n", f.Linter, f.Line, f.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the Bitbucket client and the AI helpers. Callers branch on them with
+// errors.Is: a rate limit or failed authentication stops the whole run, a missing resource
+// only skips the pull request, an invalid AI response is asked for again and a truncated one
+// is asked for in smaller parts.
+var (
+	// ErrRateLimited marks errors caused by quota or rate limits (HTTP 429).
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrAuth marks rejected credentials or missing permissions (HTTP 401 and 403).
+	ErrAuth = errors.New("authentication failed")
+	// ErrNotFound marks a resource that does not exist (any more), such as a deleted pull request.
+	ErrNotFound = errors.New("not found")
+	// ErrAIInvalidResponse marks an AI reply that is empty or not the requested JSON.
+	ErrAIInvalidResponse = errors.New("invalid AI response")
+	// ErrAITruncated marks an AI reply that was cut off at the output token limit.
+	ErrAITruncated = errors.New("AI response truncated")
+)
+
+// aiStatusError describes a non-200 reply of an AI provider, wrapping ErrRateLimited or ErrAuth
+// when the status calls for it.
+func aiStatusError(provider string, status int, message string) error {
+	switch status {
+	case 429:
+		return fmt.Errorf("%s: %w: %s", provider, ErrRateLimited, message)
+	case 401, 403:
+		return fmt.Errorf("%s: %w (status %d): %s", provider, ErrAuth, status, message)
+	}
+	return fmt.Errorf("%s status %d: %s", provider, status, message)
+}
@@ -0,0 +1,436 @@
+// Package bitbucket_fake serves the Bitbucket client from fixture files, so the review pipeline
+// can run end to end against recorded data. Fixtures are laid out like the API:
+//
+//	<fixtures>/<workspace>/<repoSlug>/pullrequests.json                    GET .../pullrequests
+//	<fixtures>/<workspace>/<repoSlug>/pullrequests/<id>/diff               GET .../pullrequests/<id>/diff
+//	<fixtures>/<workspace>/<repoSlug>/pullrequests/<id>/commits.json       GET .../pullrequests/<id>/commits
+//	<fixtures>/<workspace>/<repoSlug>/pullrequests/<id>/comments.json      GET .../pullrequests/<id>/comments
+//	<fixtures>/<workspace>/<repoSlug>/pullrequests/<id>/participants.json  participants of GET .../pullrequests/<id>
+//	<fixtures>/<workspace>/<repoSlug>/diff/<from>..<to>                    GET .../diff/<from>..<to>
+//	<fixtures>/<workspace>/<repoSlug>/src/<commit>/<path>                  GET .../src/<commit>/<path>
+//
+// JSON files hold either the API page ({"values": [...]}) or a plain array. Posted comments are
+// written to the record directory and served back with the fixture comments, so a second run
+// sees what the first one posted; other writes are appended to actions.jsonl of the repository.
+package bitbucket_fake
+
+import (
+	"code_nim/helper"
+	"code_nim/helper/atlassian"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client is the fake Bitbucket client.
+type Client struct {
+	fixtures  string
+	recordDir string
+	mutex     sync.Mutex // Serializes access to recordDir
+	nextPR    int        // ID of the next pull request opened through CreatePullRequest, once set
+}
+
+// New returns a client reading fixtures from settings.Fixtures and recording writes under
+// settings.RecordPath(dataDir).
+func New(settings model.FakeBitbucketSettings, dataDir string) atlassian.Bitbucket {
+	return &Client{fixtures: settings.Fixtures, recordDir: settings.RecordPath(dataDir)}
+}
+
+// Capabilities reports the comment features of Bitbucket Cloud, which the fake stands in for.
+func (c *Client) Capabilities() model.ProviderCapabilities {
+	return model.ProviderCapabilities{Markdown: true, LineRanges: true, Tasks: true}
+}
+
+// action is one write recorded in actions.jsonl.
+type action struct {
+	Time          time.Time   `json:"time"`
+	Action        string      `json:"action"`
+	PullRequestID int         `json:"pullRequestId,omitempty"`
+	Commit        string      `json:"commit,omitempty"`
+	Payload       interface{} `json:"payload,omitempty"`
+}
+
+func notFound(op string) error {
+	return &atlassian.StatusError{Op: op, StatusCode: http.StatusNotFound}
+}
+
+func (c *Client) repoDir(workspace, repoSlug string) string {
+	return filepath.Join(c.fixtures, workspace, repoSlug)
+}
+
+func (c *Client) prDir(workspace, repoSlug string, prID int) string {
+	return filepath.Join(c.repoDir(workspace, repoSlug), "pullrequests", strconv.Itoa(prID))
+}
+
+func (c *Client) recordedCommentsFile(workspace, repoSlug string, prID int) string {
+	return filepath.Join(c.recordDir, workspace, repoSlug, "pullrequests", strconv.Itoa(prID), "comments.json")
+}
+
+// readValues decodes the API page or plain JSON array in path into out. found is false when
+// the file does not exist.
+func readValues(path string, out interface{}) (found bool, err error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		return true, json.Unmarshal(raw, out)
+	}
+	var page struct {
+		Values json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(raw, &page); err != nil {
+		return true, fmt.Errorf("decode %s: %w", path, err)
+	}
+	if len(page.Values) == 0 {
+		return true, nil
+	}
+	return true, json.Unmarshal(page.Values, out)
+}
+
+// record appends a write to the actions.jsonl of the repository.
+func (c *Client) record(workspace, repoSlug string, a action) error {
+	a.Time = time.Now().UTC()
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(c.recordDir, workspace, repoSlug)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "actions.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(raw, '\n'))
+	log.Debugf("Recorded fake Bitbucket %s on %s/%s", a.Action, workspace, repoSlug)
+	return err
+}
+
+// FetchAllPullRequests returns the open pull requests of pullrequests.json.
+func (c *Client) FetchAllPullRequests(username, appPassword, workspace, repoSlug string) ([]model.PullRequest, error) {
+	all, err := c.pullRequests(workspace, repoSlug)
+	if err != nil {
+		return nil, err
+	}
+	var open []model.PullRequest
+	for _, pr := range all {
+		if pr.State == "" || strings.EqualFold(pr.State, "OPEN") {
+			open = append(open, pr)
+		}
+	}
+	return open, nil
+}
+
+// pullRequests returns every pull request of pullrequests.json, whatever its state.
+func (c *Client) pullRequests(workspace, repoSlug string) ([]model.PullRequest, error) {
+	var prs []model.PullRequest
+	found, err := readValues(filepath.Join(c.repoDir(workspace, repoSlug), "pullrequests.json"), &prs)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, notFound(fmt.Sprintf("no fixture pull requests for %s/%s", workspace, repoSlug))
+	}
+	return prs, nil
+}
+
+// FetchCurrentUser accepts any credentials and returns an account named after the username.
+func (c *Client) FetchCurrentUser(username, appPassword string) (model.BitbucketUser, error) {
+	name := username
+	if name == "" {
+		name = "fake"
+	}
+	return model.BitbucketUser{DisplayName: name, UUID: "{fake-" + name + "}"}, nil
+}
+
+// FetchRepositories lists the repository folders of the workspace.
+func (c *Client) FetchRepositories(workspace, username, appPassword string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.fixtures, workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, notFound("no fixture workspace " + workspace)
+		}
+		return nil, err
+	}
+	var slugs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			slugs = append(slugs, e.Name())
+		}
+	}
+	return slugs, nil
+}
+
+// CountMergedPullRequests counts the MERGED pull requests of the author in pullrequests.json.
+func (c *Client) CountMergedPullRequests(workspace, repoSlug, authorUUID, username, appPassword string) (int, error) {
+	prs, err := c.pullRequests(workspace, repoSlug)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, pr := range prs {
+		if strings.EqualFold(pr.State, "MERGED") && pr.Author.UUID == authorUUID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// FetchPullRequestDiff reads the diff file of the pull request.
+func (c *Client) FetchPullRequestDiff(prID int, workspace, repoSlug, username, appPassword string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(c.prDir(workspace, repoSlug, prID), "diff"))
+	if os.IsNotExist(err) {
+		return "", notFound(fmt.Sprintf("no fixture diff for PR #%d", prID))
+	}
+	return string(raw), err
+}
+
+// FetchPullRequestCommits reads commits.json of the pull request; none when it is missing.
+func (c *Client) FetchPullRequestCommits(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestCommit, error) {
+	commits := []model.PullRequestCommit{}
+	_, err := readValues(filepath.Join(c.prDir(workspace, repoSlug, prID), "commits.json"), &commits)
+	return commits, err
+}
+
+// FetchPullRequestParticipants reads participants.json of the pull request; none when it is
+// missing.
+func (c *Client) FetchPullRequestParticipants(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestParticipant, error) {
+	participants := []model.PullRequestParticipant{}
+	_, err := readValues(filepath.Join(c.prDir(workspace, repoSlug, prID), "participants.json"), &participants)
+	return participants, err
+}
+
+// FetchDiffBetweenCommits reads diff/<from>..<to>. Without it the diff is empty, so the review
+// falls back to the full pull request diff.
+func (c *Client) FetchDiffBetweenCommits(workspace, repoSlug, fromHash, toHash, username, appPassword string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(c.repoDir(workspace, repoSlug), "diff", fromHash+".."+toHash))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(raw), err
+}
+
+// ParseDiff splits a unified diff into files and hunks; see helper.ParseDiff.
+func (c *Client) ParseDiff(diff string) []map[string]interface{} {
+	return helper.ParseDiff(diff)
+}
+
+// FetchPullRequestComments returns the fixture comments followed by the recorded ones.
+func (c *Client) FetchPullRequestComments(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestComment, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	comments, _, err := c.comments(prID, workspace, repoSlug)
+	return comments, err
+}
+
+// comments returns the fixture and recorded comments of a pull request, and the recorded ones
+// alone. Callers hold c.mutex.
+func (c *Client) comments(prID int, workspace, repoSlug string) (all, recorded []model.PullRequestComment, err error) {
+	all = []model.PullRequestComment{}
+	if _, err := readValues(filepath.Join(c.prDir(workspace, repoSlug, prID), "comments.json"), &all); err != nil {
+		return nil, nil, err
+	}
+	if _, err := readValues(c.recordedCommentsFile(workspace, repoSlug, prID), &recorded); err != nil {
+		return nil, nil, err
+	}
+	return append(all, recorded...), recorded, nil
+}
+
+// postComment records a comment by username with an ID above every existing comment.
+func (c *Client) postComment(prID int, workspace, repoSlug, username string, comment model.PullRequestComment) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	all, recorded, err := c.comments(prID, workspace, repoSlug)
+	if err != nil {
+		return err
+	}
+	comment.ID = 1
+	for _, existing := range all {
+		if existing.ID >= comment.ID {
+			comment.ID = existing.ID + 1
+		}
+	}
+	comment.User.DisplayName, comment.User.Username = username, username
+	comment.CreatedOn = time.Now().UTC().Format(time.RFC3339Nano)
+	log.Debugf("Recorded fake comment %d on %s/%s PR #%d", comment.ID, workspace, repoSlug, prID)
+	return writeComments(c.recordedCommentsFile(workspace, repoSlug, prID), append(recorded, comment))
+}
+
+// writeComments replaces a recorded comments file atomically. Callers hold c.mutex.
+func writeComments(file string, comments []model.PullRequestComment) error {
+	raw, err := json.MarshalIndent(map[string]interface{}{"values": comments}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// PushPullRequestComment records a pull request comment.
+func (c *Client) PushPullRequestComment(prID int, workspace, repoSlug, username, appPassword, commentText string) error {
+	var comment model.PullRequestComment
+	comment.Content.Raw = commentText
+	return c.postComment(prID, workspace, repoSlug, username, comment)
+}
+
+// PushPullRequestInlineComment records an inline comment on path.
+func (c *Client) PushPullRequestInlineComment(prID int, workspace, repoSlug, username, appPassword, path string, fromLine, toLine int, content string) error {
+	var comment model.PullRequestComment
+	comment.Content.Raw = content
+	comment.Inline = &struct {
+		Path string `json:"path"`
+		To   int    `json:"to"`
+		From int    `json:"from"`
+	}{Path: path, To: toLine, From: fromLine}
+	return c.postComment(prID, workspace, repoSlug, username, comment)
+}
+
+// PushPullRequestInlineRangeComment records an inline comment anchored on its last line.
+func (c *Client) PushPullRequestInlineRangeComment(prID int, workspace, repoSlug, username, appPassword, path string, startLine, endLine int, content string) error {
+	return c.PushPullRequestInlineComment(prID, workspace, repoSlug, username, appPassword, path, 0, endLine, content)
+}
+
+// ApprovePullRequest records the approval.
+func (c *Client) ApprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "approve", PullRequestID: prID})
+}
+
+// UnapprovePullRequest records the withdrawn approval.
+func (c *Client) UnapprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "unapprove", PullRequestID: prID})
+}
+
+// PublishReport records the Code Insights report and its annotations.
+func (c *Client) PublishReport(workspace, repoSlug, commit, reportID string, report model.InsightsReport, annotations []model.InsightsAnnotation, username, appPassword string) error {
+	payload := map[string]interface{}{"id": reportID, "report": report, "annotations": annotations}
+	return c.record(workspace, repoSlug, action{Action: "publish_report", Commit: commit, Payload: payload})
+}
+
+// SetBuildStatus records the build status.
+func (c *Client) SetBuildStatus(workspace, repoSlug, commit string, status model.BuildStatus, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "set_build_status", Commit: commit, Payload: status})
+}
+
+// UpdatePullRequestDescription records the new description; pullrequests.json is not changed.
+func (c *Client) UpdatePullRequestDescription(prID int, workspace, repoSlug, description, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "update_description", PullRequestID: prID, Payload: description})
+}
+
+// UpdatePullRequestComment replaces a recorded comment; edits of fixture comments are recorded
+// as actions.
+func (c *Client) UpdatePullRequestComment(prID int, workspace, repoSlug string, commentID int, content, username, appPassword string) error {
+	edit := func(comment *model.PullRequestComment) { comment.Content.Raw = content }
+	if updated, err := c.editRecordedComment(prID, workspace, repoSlug, commentID, edit); updated || err != nil {
+		return err
+	}
+	return c.record(workspace, repoSlug, action{Action: "update_comment", PullRequestID: prID, Payload: map[string]interface{}{"id": commentID, "raw": content}})
+}
+
+// DeletePullRequestComment marks a recorded comment deleted; deletions of fixture comments are
+// recorded as actions.
+func (c *Client) DeletePullRequestComment(prID int, workspace, repoSlug string, commentID int, username, appPassword string) error {
+	edit := func(comment *model.PullRequestComment) { comment.Deleted = true }
+	if deleted, err := c.editRecordedComment(prID, workspace, repoSlug, commentID, edit); deleted || err != nil {
+		return err
+	}
+	return c.record(workspace, repoSlug, action{Action: "delete_comment", PullRequestID: prID, Payload: map[string]interface{}{"id": commentID}})
+}
+
+// editRecordedComment applies edit to a recorded comment and reports whether it exists.
+func (c *Client) editRecordedComment(prID int, workspace, repoSlug string, commentID int, edit func(*model.PullRequestComment)) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, recorded, err := c.comments(prID, workspace, repoSlug)
+	if err != nil {
+		return false, err
+	}
+	for i := range recorded {
+		if recorded[i].ID == commentID {
+			edit(&recorded[i])
+			return true, writeComments(c.recordedCommentsFile(workspace, repoSlug, prID), recorded)
+		}
+	}
+	return false, nil
+}
+
+// CreatePullRequestTask records the task.
+func (c *Client) CreatePullRequestTask(prID int, workspace, repoSlug, content, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "create_task", PullRequestID: prID, Payload: content})
+}
+
+// FetchFileContent reads src/<commit>/<path>.
+func (c *Client) FetchFileContent(workspace, repoSlug, commit, path, username, appPassword string) (string, bool, error) {
+	raw, err := os.ReadFile(filepath.Join(c.repoDir(workspace, repoSlug), "src", commit, filepath.FromSlash(strings.TrimLeft(path, "/"))))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return string(raw), true, nil
+}
+
+// FetchFileAuthors returns no authors: fixtures carry no file history.
+func (c *Client) FetchFileAuthors(workspace, repoSlug, commit, path string, limit int, username, appPassword string) ([]model.BitbucketUser, error) {
+	return nil, nil
+}
+
+// AddPullRequestReviewers records the reviewers.
+func (c *Client) AddPullRequestReviewers(prID int, workspace, repoSlug string, reviewers []model.BitbucketUser, username, appPassword string) error {
+	return c.record(workspace, repoSlug, action{Action: "add_reviewers", PullRequestID: prID, Payload: reviewers})
+}
+
+// CommitFiles records the commit and returns a hash derived from its content.
+func (c *Client) CommitFiles(workspace, repoSlug, branch, parent, message string, files map[string]string, username, appPassword string) (string, error) {
+	raw, err := json.Marshal([]interface{}{branch, parent, message, files})
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(raw)
+	hash := hex.EncodeToString(sum[:])
+	payload := map[string]interface{}{"branch": branch, "parent": parent, "message": message, "files": files}
+	return hash, c.record(workspace, repoSlug, action{Action: "commit_files", Commit: hash, Payload: payload})
+}
+
+// CreatePullRequest records the pull request and returns an ID above those of the fixtures.
+func (c *Client) CreatePullRequest(workspace, repoSlug, title, description, sourceBranch, destinationBranch, username, appPassword string) (int, error) {
+	prs, _ := c.pullRequests(workspace, repoSlug)
+	c.mutex.Lock()
+	for _, pr := range prs {
+		if pr.ID >= c.nextPR {
+			c.nextPR = pr.ID + 1
+		}
+	}
+	if c.nextPR == 0 {
+		c.nextPR = 1
+	}
+	id := c.nextPR
+	c.nextPR++
+	c.mutex.Unlock()
+	payload := map[string]string{"title": title, "description": description, "source": sourceBranch, "destination": destinationBranch}
+	return id, c.record(workspace, repoSlug, action{Action: "create_pull_request", PullRequestID: id, Payload: payload})
+}
@@ -5,10 +5,22 @@ import "code_nim/model"
 // Bitbucket exposes the operations your app cares about.
 // ctx lets the caller cancel / set timeouts.
 type Bitbucket interface {
+	// Capabilities returns the comment features of the Bitbucket API.
+	Capabilities() model.ProviderCapabilities
 	FetchAllPullRequests(username, appPassword, workspace, repoSlug string) ([]model.PullRequest, error)
+	// FetchCurrentUser returns the account the credentials belong to.
+	FetchCurrentUser(username, appPassword string) (model.BitbucketUser, error)
+	// FetchRepositories returns the slugs of every repository of the workspace the user can read.
+	FetchRepositories(workspace, username, appPassword string) ([]string, error)
+	// CountMergedPullRequests returns how many pull requests of the author (a Bitbucket user UUID)
+	// have been merged in the repository.
+	CountMergedPullRequests(workspace, repoSlug, authorUUID, username, appPassword string) (int, error)
 	FetchPullRequestDiff(prID int, workspace, repoSlug, username, appPassword string) (string, error)
 	FetchPullRequestCommits(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestCommit, error)
 	FetchDiffBetweenCommits(workspace, repoSlug, fromHash, toHash, username, appPassword string) (string, error)
+	// FetchPullRequestParticipants returns the reviewers and commenters of the pull request with
+	// their approval state.
+	FetchPullRequestParticipants(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestParticipant, error)
 	ParseDiff(diff string) []map[string]interface{}
 	FetchPullRequestComments(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestComment, error)
 	PushPullRequestComment(prID int, workspace, repoSlug, username, appPassword, commentText string) error
@@ -16,4 +28,36 @@ type Bitbucket interface {
 	// Bitbucket Cloud API expects the path, fromLine (source/old file), and toLine (destination/new file)
 	// For added lines, fromLine should be 0; for deleted lines, toLine should be 0
 	PushPullRequestInlineComment(prID int, workspace, repoSlug, username, appPassword, path string, fromLine, toLine int, content string) error
+	// PushPullRequestInlineRangeComment posts a comment on the destination lines startLine to
+	// endLine of a file in the PR.
+	PushPullRequestInlineRangeComment(prID int, workspace, repoSlug, username, appPassword, path string, startLine, endLine int, content string) error
+	// ApprovePullRequest adds the authenticated user's approval; UnapprovePullRequest withdraws it.
+	// Withdrawing an approval that was never given is not an error.
+	ApprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error
+	UnapprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error
+	// PublishReport replaces the Code Insights report reportID on a commit and its annotations.
+	PublishReport(workspace, repoSlug, commit, reportID string, report model.InsightsReport, annotations []model.InsightsAnnotation, username, appPassword string) error
+	// SetBuildStatus creates or updates the build status with status.Key on a commit.
+	SetBuildStatus(workspace, repoSlug, commit string, status model.BuildStatus, username, appPassword string) error
+	// UpdatePullRequestDescription replaces the description of the pull request; other fields are kept.
+	UpdatePullRequestDescription(prID int, workspace, repoSlug, description, username, appPassword string) error
+	// UpdatePullRequestComment replaces the text of a comment on the pull request.
+	UpdatePullRequestComment(prID int, workspace, repoSlug string, commentID int, content, username, appPassword string) error
+	// DeletePullRequestComment deletes a comment of the pull request.
+	DeletePullRequestComment(prID int, workspace, repoSlug string, commentID int, username, appPassword string) error
+	// CreatePullRequestTask opens a task on the pull request.
+	CreatePullRequestTask(prID int, workspace, repoSlug, content, username, appPassword string) error
+	// FetchFileContent returns a file at a commit; found is false when the file does not exist.
+	FetchFileContent(workspace, repoSlug, commit, path, username, appPassword string) (content string, found bool, err error)
+	// FetchFileAuthors returns the authors of the latest commits (at most limit) touching path
+	// up to commit, newest first. Commits by authors without a Bitbucket account are left out.
+	FetchFileAuthors(workspace, repoSlug, commit, path string, limit int, username, appPassword string) ([]model.BitbucketUser, error)
+	// AddPullRequestReviewers adds reviewers to the pull request, keeping the existing ones.
+	AddPullRequestReviewers(prID int, workspace, repoSlug string, reviewers []model.BitbucketUser, username, appPassword string) error
+	// CommitFiles commits files (path -> new content) on top of parent to branch, creating the
+	// branch when it does not exist, and returns the hash of the new commit.
+	CommitFiles(workspace, repoSlug, branch, parent, message string, files map[string]string, username, appPassword string) (string, error)
+	// CreatePullRequest opens a pull request from sourceBranch into destinationBranch and
+	// returns its ID.
+	CreatePullRequest(workspace, repoSlug, title, description, sourceBranch, destinationBranch, username, appPassword string) (int, error)
 }
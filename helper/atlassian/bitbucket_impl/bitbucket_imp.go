@@ -1,12 +1,18 @@
 package bitbucket_impl
 
 import (
+	"bytes"
+	"code_nim/helper"
+	"code_nim/helper/atlassian"
 	"code_nim/log"
 	"code_nim/model"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -18,7 +24,7 @@ func (hc *HttpClient) FetchAllPullRequests(username, appPassword, workspace, rep
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
 		return nil, err
 	}
 
@@ -36,7 +42,7 @@ func (hc *HttpClient) FetchAllPullRequests(username, appPassword, workspace, rep
 	// Check if the request was successful
 	if resp.StatusCode != 200 {
 		log.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
-		return nil, err
+		return nil, &atlassian.StatusError{Op: "failed to list pull requests", StatusCode: resp.StatusCode}
 	}
 
 	// Print the raw response body for debugging
@@ -69,6 +75,68 @@ func (hc *HttpClient) FetchAllPullRequests(username, appPassword, workspace, rep
 	return result.Values, nil
 }
 
+// FetchRepositories follows the pages of the workspace repositories list, asking only for slugs.
+func (hc *HttpClient) FetchRepositories(workspace, username, appPassword string) ([]string, error) {
+	pageURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s?pagelen=100&fields=next,values.slug", url.PathEscape(workspace))
+	var slugs []string
+	for pageURL != "" {
+		log.Debugf("Fetching repositories from URL: %s", pageURL)
+		var page struct {
+			Values []struct {
+				Slug string `json:"slug"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := hc.getJSON(pageURL, &page, username, appPassword); err != nil {
+			return nil, fmt.Errorf("list repositories of %s: %w", workspace, err)
+		}
+		for _, v := range page.Values {
+			slugs = append(slugs, v.Slug)
+		}
+		pageURL = page.Next
+	}
+	return slugs, nil
+}
+
+// CountMergedPullRequests counts the merged pull requests of one author in the repository.
+func (hc *HttpClient) CountMergedPullRequests(workspace, repoSlug, authorUUID, username, appPassword string) (int, error) {
+	query := url.Values{}
+	query.Set("state", "MERGED")
+	query.Set("q", fmt.Sprintf(`author.uuid="%s"`, authorUUID))
+	query.Set("pagelen", "1")
+	query.Set("fields", "size")
+	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests?%s", workspace, repoSlug, query.Encode())
+	log.Debugf("Counting merged pull requests from URL: %s", apiURL)
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	req.SetBasicAuth(username, appPassword)
+
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		rawBody, _ := io.ReadAll(resp.Body)
+		log.Errorf("Failed to count merged pull requests. Status: %d, Body: %s", resp.StatusCode, string(rawBody))
+		return 0, &atlassian.StatusError{Op: "failed to count merged pull requests", StatusCode: resp.StatusCode}
+	}
+	var result struct {
+		Size int `json:"size"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	return result.Size, nil
+}
+
 func (hc *HttpClient) FetchPullRequestDiff(prID int, workspace, repoSlug, username, appPassword string) (string, error) {
 	// Construct the API URL to get the diff for a specific pull request
 	diffAPIURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/diff", workspace, repoSlug, prID)
@@ -76,7 +144,7 @@ func (hc *HttpClient) FetchPullRequestDiff(prID int, workspace, repoSlug, userna
 
 	req, err := http.NewRequest("GET", diffAPIURL, nil)
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
 		return "", err
 	}
 	// Add Basic Authentication header
@@ -91,7 +159,7 @@ func (hc *HttpClient) FetchPullRequestDiff(prID int, workspace, repoSlug, userna
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		log.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
-		return "", fmt.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
+		return "", &atlassian.StatusError{Op: "failed to fetch diff", StatusCode: resp.StatusCode}
 	}
 	rawBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -112,7 +180,7 @@ func (hc *HttpClient) FetchPullRequestCommits(prID int, workspace, repoSlug, use
 	for nextURL != "" {
 		req, err := http.NewRequest("GET", nextURL, nil)
 		if err != nil {
-			log.Fatal(err)
+			log.Error(err)
 			return nil, err
 		}
 		req.SetBasicAuth(username, appPassword)
@@ -125,7 +193,7 @@ func (hc *HttpClient) FetchPullRequestCommits(prID int, workspace, repoSlug, use
 		if resp.StatusCode != 200 {
 			resp.Body.Close()
 			log.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
-			return nil, fmt.Errorf("error: expected status 200 but got %d", resp.StatusCode)
+			return nil, &atlassian.StatusError{Op: "failed to list pull request commits", StatusCode: resp.StatusCode}
 		}
 		rawBody, err := io.ReadAll(resp.Body)
 		if err != nil {
@@ -161,7 +229,7 @@ func (hc *HttpClient) FetchDiffBetweenCommits(workspace, repoSlug, fromHash, toH
 
 	req, err := http.NewRequest("GET", diffAPIURL, nil)
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
 		return "", err
 	}
 	req.SetBasicAuth(username, appPassword)
@@ -174,7 +242,7 @@ func (hc *HttpClient) FetchDiffBetweenCommits(workspace, repoSlug, fromHash, toH
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		log.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
-		return "", fmt.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
+		return "", &atlassian.StatusError{Op: "failed to fetch diff", StatusCode: resp.StatusCode}
 	}
 	rawBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -184,37 +252,22 @@ func (hc *HttpClient) FetchDiffBetweenCommits(workspace, repoSlug, fromHash, toH
 	return string(rawBody), nil
 }
 
-// Minimal diff parser for demonstration
-func (hc *HttpClient) ParseDiff(diff string) []map[string]interface{} {
-	files := []map[string]interface{}{}
-	var currentFile map[string]interface{}
-	var currentHunk map[string]interface{}
-	for _, line := range strings.Split(diff, "\n") {
-		if strings.HasPrefix(line, "diff --git") {
-			if currentFile != nil {
-				files = append(files, currentFile)
-			}
-			currentFile = map[string]interface{}{"path": "", "hunks": []map[string]interface{}{}}
-		} else if strings.HasPrefix(line, "+++ b/") {
-			if currentFile != nil {
-				currentFile["path"] = strings.TrimPrefix(line, "+++ b/")
-			}
-		} else if strings.HasPrefix(line, "@@") {
-			if currentFile != nil {
-				currentHunk = map[string]interface{}{"header": line, "lines": []string{}}
-				hunks := currentFile["hunks"].([]map[string]interface{})
-				currentFile["hunks"] = append(hunks, currentHunk)
-			}
-		} else if currentHunk != nil {
-			lines := currentHunk["lines"].([]string)
-			currentHunk["lines"] = append(lines, line)
-		}
+// FetchPullRequestParticipants reads the participants field of the pull request.
+func (hc *HttpClient) FetchPullRequestParticipants(prID int, workspace, repoSlug, username, appPassword string) ([]model.PullRequestParticipant, error) {
+	prURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d?fields=participants", workspace, repoSlug, prID)
+	log.Debugf("Fetching participants from URL: %s", prURL)
+	var result struct {
+		Participants []model.PullRequestParticipant `json:"participants"`
 	}
-	if currentFile != nil {
-		files = append(files, currentFile)
+	if err := hc.getJSON(prURL, &result, username, appPassword); err != nil {
+		return nil, fmt.Errorf("fetch participants of PR #%d: %w", prID, err)
 	}
-	//log.Debug("Diff Files:", files)
-	return files
+	return result.Participants, nil
+}
+
+// ParseDiff splits a unified diff into files and hunks; see helper.ParseDiff.
+func (hc *HttpClient) ParseDiff(diff string) []map[string]interface{} {
+	return helper.ParseDiff(diff)
 }
 
 // Fetch and list comments for a specific pull request
@@ -227,7 +280,7 @@ func (hc *HttpClient) FetchPullRequestComments(prID int, workspace, repoSlug, us
 	for nextURL != "" {
 		req, err := http.NewRequest("GET", nextURL, nil)
 		if err != nil {
-			log.Fatal(err)
+			log.Error(err)
 			return nil, err
 		}
 		req.SetBasicAuth(username, appPassword)
@@ -240,6 +293,7 @@ func (hc *HttpClient) FetchPullRequestComments(prID int, workspace, repoSlug, us
 		defer resp.Body.Close()
 		if resp.StatusCode != 200 {
 			log.Errorf("Error: Expected status 200 but got %d", resp.StatusCode)
+			return nil, &atlassian.StatusError{Op: "failed to list pull request comments", StatusCode: resp.StatusCode}
 		}
 		rawBody, err := io.ReadAll(resp.Body)
 		if err != nil {
@@ -301,7 +355,7 @@ func (hc *HttpClient) PushPullRequestComment(prID int, workspace, repoSlug, user
 	if resp.StatusCode != 201 {
 		rawBody, _ := io.ReadAll(resp.Body)
 		log.Errorf("Failed to post comment. Status: %d, Body: %s", resp.StatusCode, string(rawBody))
-		return fmt.Errorf("failed to post comment, status: %d", resp.StatusCode)
+		return &atlassian.StatusError{Op: "failed to post comment", StatusCode: resp.StatusCode}
 	}
 
 	log.Debug("Comment posted successfully")
@@ -360,9 +414,399 @@ func (hc *HttpClient) PushPullRequestInlineComment(prID int, workspace, repoSlug
 	if resp.StatusCode != 201 {
 		rawBody, _ := io.ReadAll(resp.Body)
 		log.Errorf("Failed to post inline comment. Status: %d, Body: %s", resp.StatusCode, string(rawBody))
-		return fmt.Errorf("failed to post inline comment, status: %d", resp.StatusCode)
+		return &atlassian.StatusError{Op: "failed to post inline comment", StatusCode: resp.StatusCode}
 	}
 
 	log.Debug("Inline comment posted successfully")
 	return nil
 }
+
+// PushPullRequestInlineRangeComment posts a multi-line inline comment using the start_to and
+// to fields of the inline object.
+func (hc *HttpClient) PushPullRequestInlineRangeComment(prID int, workspace, repoSlug, username, appPassword, path string, startLine, endLine int, content string) error {
+	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/comments", workspace, repoSlug, prID)
+	log.Debugf("Posting inline range comment to URL: %s (path=%s, lines %d-%d)", apiURL, path, startLine, endLine)
+	payload := map[string]interface{}{
+		"content": map[string]string{"raw": content},
+		"inline":  map[string]interface{}{"path": path, "start_to": startLine, "to": endLine},
+	}
+	return hc.sendJSON("POST", apiURL, payload, username, appPassword, http.StatusCreated)
+}
+
+// ApprovePullRequest approves the pull request as the authenticated user.
+func (hc *HttpClient) ApprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error {
+	return hc.setApproval("POST", prID, workspace, repoSlug, username, appPassword)
+}
+
+// UnapprovePullRequest withdraws the authenticated user's approval of the pull request.
+func (hc *HttpClient) UnapprovePullRequest(prID int, workspace, repoSlug, username, appPassword string) error {
+	return hc.setApproval("DELETE", prID, workspace, repoSlug, username, appPassword)
+}
+
+func (hc *HttpClient) setApproval(method string, prID int, workspace, repoSlug, username, appPassword string) error {
+	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/approve", workspace, repoSlug, prID)
+	log.Debugf("%s approval at URL: %s", method, apiURL)
+
+	req, err := http.NewRequest(method, apiURL, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	req.SetBasicAuth(username, appPassword)
+
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 409 on POST: already approved; 404 on DELETE: there was no approval to withdraw.
+	if resp.StatusCode >= 300 && !(method == "POST" && resp.StatusCode == http.StatusConflict) &&
+		!(method == "DELETE" && resp.StatusCode == http.StatusNotFound) {
+		rawBody, _ := io.ReadAll(resp.Body)
+		log.Errorf("Failed to %s approval. Status: %d, Body: %s", method, resp.StatusCode, string(rawBody))
+		return &atlassian.StatusError{Op: "failed to " + method + " approval", StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// maxAnnotationsPerRequest is the Code Insights limit on annotations per bulk request.
+const maxAnnotationsPerRequest = 100
+
+// PublishReport deletes the previous report (and with it its annotations), creates the new one
+// and uploads its annotations in batches.
+func (hc *HttpClient) PublishReport(workspace, repoSlug, commit, reportID string, report model.InsightsReport, annotations []model.InsightsAnnotation, username, appPassword string) error {
+	reportURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/commit/%s/reports/%s", workspace, repoSlug, commit, url.PathEscape(reportID))
+	log.Debugf("Publishing Code Insights report to URL: %s (%d annotations)", reportURL, len(annotations))
+
+	if err := hc.sendJSON("DELETE", reportURL, nil, username, appPassword, http.StatusNoContent, http.StatusNotFound); err != nil {
+		return fmt.Errorf("delete previous report: %w", err)
+	}
+	if err := hc.sendJSON("PUT", reportURL, report, username, appPassword, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	for start := 0; start < len(annotations); start += maxAnnotationsPerRequest {
+		end := start + maxAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		if err := hc.sendJSON("POST", reportURL+"/annotations", annotations[start:end], username, appPassword, http.StatusOK, http.StatusCreated); err != nil {
+			return fmt.Errorf("upload annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetBuildStatus posts status on the commit; Bitbucket updates an existing status with the same key.
+func (hc *HttpClient) SetBuildStatus(workspace, repoSlug, commit string, status model.BuildStatus, username, appPassword string) error {
+	statusURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/commit/%s/statuses/build", workspace, repoSlug, commit)
+	log.Debugf("Setting build status %s=%s at URL: %s", status.Key, status.State, statusURL)
+	if err := hc.sendJSON("POST", statusURL, status, username, appPassword, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("set build status: %w", err)
+	}
+	return nil
+}
+
+// UpdatePullRequestDescription updates the description of the pull request. Bitbucket Cloud
+// updates pull requests with PUT, which requires the title; fields that are not sent are kept.
+func (hc *HttpClient) UpdatePullRequestDescription(prID int, workspace, repoSlug, description, username, appPassword string) error {
+	prURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d", workspace, repoSlug, prID)
+	var current struct {
+		Title string `json:"title"`
+	}
+	if err := hc.getJSON(prURL+"?fields=title", &current, username, appPassword); err != nil {
+		return fmt.Errorf("fetch pull request title: %w", err)
+	}
+	log.Debugf("Updating description of PR #%d at URL: %s", prID, prURL)
+	if err := hc.sendJSON("PUT", prURL, map[string]string{"title": current.Title, "description": description}, username, appPassword, http.StatusOK); err != nil {
+		return fmt.Errorf("update pull request description: %w", err)
+	}
+	return nil
+}
+
+// UpdatePullRequestComment replaces the raw content of a comment.
+func (hc *HttpClient) UpdatePullRequestComment(prID int, workspace, repoSlug string, commentID int, content, username, appPassword string) error {
+	commentURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/comments/%d", workspace, repoSlug, prID, commentID)
+	log.Debugf("Updating comment %d on PR #%d at URL: %s", commentID, prID, commentURL)
+	payload := map[string]interface{}{"content": map[string]string{"raw": content}}
+	if err := hc.sendJSON("PUT", commentURL, payload, username, appPassword, http.StatusOK); err != nil {
+		return fmt.Errorf("update pull request comment: %w", err)
+	}
+	return nil
+}
+
+// DeletePullRequestComment deletes a comment; Bitbucket keeps it in the thread as deleted.
+func (hc *HttpClient) DeletePullRequestComment(prID int, workspace, repoSlug string, commentID int, username, appPassword string) error {
+	commentURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/comments/%d", workspace, repoSlug, prID, commentID)
+	log.Debugf("Deleting comment %d on PR #%d at URL: %s", commentID, prID, commentURL)
+	if err := hc.sendJSON("DELETE", commentURL, nil, username, appPassword, http.StatusNoContent, http.StatusOK); err != nil {
+		return fmt.Errorf("delete pull request comment: %w", err)
+	}
+	return nil
+}
+
+// CreatePullRequestTask opens a task that is not attached to a comment.
+func (hc *HttpClient) CreatePullRequestTask(prID int, workspace, repoSlug, content, username, appPassword string) error {
+	tasksURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/tasks", workspace, repoSlug, prID)
+	log.Debugf("Creating task on PR #%d at URL: %s", prID, tasksURL)
+	payload := map[string]interface{}{"content": map[string]string{"raw": content}}
+	if err := hc.sendJSON("POST", tasksURL, payload, username, appPassword, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("create pull request task: %w", err)
+	}
+	return nil
+}
+
+// FetchFileContent reads path at commit from the src endpoint.
+func (hc *HttpClient) FetchFileContent(workspace, repoSlug, commit, path, username, appPassword string) (string, bool, error) {
+	srcURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/src/%s/%s", workspace, repoSlug, commit, strings.TrimLeft(path, "/"))
+	log.Debugf("Fetching file from URL: %s", srcURL)
+	req, err := http.NewRequest("GET", srcURL, nil)
+	if err != nil {
+		log.Error(err)
+		return "", false, err
+	}
+	req.SetBasicAuth(username, appPassword)
+
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	rawBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err)
+		return "", false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Failed to fetch %s. Status: %d, Body: %s", path, resp.StatusCode, string(rawBody))
+		return "", false, &atlassian.StatusError{Op: "failed to fetch " + path, StatusCode: resp.StatusCode}
+	}
+	return string(rawBody), true, nil
+}
+
+// FetchFileAuthors lists the latest commits touching path. Bitbucket Cloud has no blame API,
+// so the file history stands in for it.
+func (hc *HttpClient) FetchFileAuthors(workspace, repoSlug, commit, path string, limit int, username, appPassword string) ([]model.BitbucketUser, error) {
+	query := url.Values{}
+	query.Set("path", path)
+	query.Set("pagelen", fmt.Sprint(limit))
+	query.Set("fields", "values.author.user.display_name,values.author.user.uuid,values.author.user.account_id")
+	commitsURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/commits/%s?%s", workspace, repoSlug, commit, query.Encode())
+	log.Debugf("Fetching file history from URL: %s", commitsURL)
+	var result struct {
+		Values []struct {
+			Author struct {
+				User *model.BitbucketUser `json:"user"`
+			} `json:"author"`
+		} `json:"values"`
+	}
+	if err := hc.getJSON(commitsURL, &result, username, appPassword); err != nil {
+		return nil, fmt.Errorf("fetch history of %s: %w", path, err)
+	}
+	var authors []model.BitbucketUser
+	for _, v := range result.Values {
+		if v.Author.User != nil {
+			authors = append(authors, *v.Author.User)
+		}
+	}
+	return authors, nil
+}
+
+// AddPullRequestReviewers reads the current title and reviewers and sends the merged list,
+// since Bitbucket replaces the whole reviewers list on update.
+func (hc *HttpClient) AddPullRequestReviewers(prID int, workspace, repoSlug string, reviewers []model.BitbucketUser, username, appPassword string) error {
+	prURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d", workspace, repoSlug, prID)
+	var current struct {
+		Title     string                `json:"title"`
+		Reviewers []model.BitbucketUser `json:"reviewers"`
+	}
+	if err := hc.getJSON(prURL+"?fields=title,reviewers.uuid,reviewers.account_id", &current, username, appPassword); err != nil {
+		return fmt.Errorf("fetch reviewers: %w", err)
+	}
+	type ref struct {
+		UUID      string `json:"uuid,omitempty"`
+		AccountID string `json:"account_id,omitempty"`
+	}
+	var merged []ref
+	seen := map[string]bool{}
+	for _, r := range append(current.Reviewers, reviewers...) {
+		key := r.UUID + "|" + r.AccountID
+		if (r.UUID == "" && r.AccountID == "") || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, ref{UUID: r.UUID, AccountID: r.AccountID})
+	}
+	log.Debugf("Setting %d reviewers on PR #%d at URL: %s", len(merged), prID, prURL)
+	if err := hc.sendJSON("PUT", prURL, map[string]interface{}{"title": current.Title, "reviewers": merged}, username, appPassword, http.StatusOK); err != nil {
+		return fmt.Errorf("add reviewers: %w", err)
+	}
+	return nil
+}
+
+// CommitFiles posts the files as a multipart form to the src endpoint, which commits them in
+// one commit. The hash of the commit is the last element of the Location header.
+func (hc *HttpClient) CommitFiles(workspace, repoSlug, branch, parent, message string, files map[string]string, username, appPassword string) (string, error) {
+	srcURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/src", workspace, repoSlug)
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{{"message", message}, {"branch", branch}, {"parents", parent}}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fields = append(fields, [2]string{strings.TrimLeft(p, "/"), files[p]})
+	}
+	for _, f := range fields {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", srcURL, &body)
+	if err != nil {
+		log.Error(err)
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth(username, appPassword)
+
+	log.Debugf("Committing %d files to branch %s at URL: %s", len(files), branch, srcURL)
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		rawBody, _ := io.ReadAll(resp.Body)
+		log.Errorf("Failed to commit to %s. Status: %d, Body: %s", branch, resp.StatusCode, string(rawBody))
+		return "", &atlassian.StatusError{Op: "failed to commit files", StatusCode: resp.StatusCode}
+	}
+	location := resp.Header.Get("Location")
+	return location[strings.LastIndex(location, "/")+1:], nil
+}
+
+// CreatePullRequest opens a pull request that closes its source branch when merged.
+func (hc *HttpClient) CreatePullRequest(workspace, repoSlug, title, description, sourceBranch, destinationBranch, username, appPassword string) (int, error) {
+	prsURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests", workspace, repoSlug)
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":               title,
+		"description":         description,
+		"source":              map[string]interface{}{"branch": map[string]string{"name": sourceBranch}},
+		"destination":         map[string]interface{}{"branch": map[string]string{"name": destinationBranch}},
+		"close_source_branch": true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", prsURL, bytes.NewReader(payload))
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(username, appPassword)
+
+	log.Debugf("Opening pull request %s -> %s at URL: %s", sourceBranch, destinationBranch, prsURL)
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	defer resp.Body.Close()
+	rawBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		log.Errorf("Failed to open pull request from %s. Status: %d, Body: %s", sourceBranch, resp.StatusCode, string(rawBody))
+		return 0, &atlassian.StatusError{Op: "failed to create pull request", StatusCode: resp.StatusCode}
+	}
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(rawBody, &created); err != nil {
+		return 0, fmt.Errorf("decode created pull request: %w", err)
+	}
+	return created.ID, nil
+}
+
+// FetchCurrentUser reads the user endpoint, which answers for any valid app password.
+func (hc *HttpClient) FetchCurrentUser(username, appPassword string) (model.BitbucketUser, error) {
+	var user model.BitbucketUser
+	if err := hc.getJSON("https://api.bitbucket.org/2.0/user", &user, username, appPassword); err != nil {
+		return user, fmt.Errorf("fetch current user: %w", err)
+	}
+	return user, nil
+}
+
+// getJSON fetches apiURL and decodes the JSON response into out.
+func (hc *HttpClient) getJSON(apiURL string, out interface{}, username, appPassword string) error {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	req.SetBasicAuth(username, appPassword)
+
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer resp.Body.Close()
+	rawBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("GET %s failed. Status: %d, Body: %s", apiURL, resp.StatusCode, string(rawBody))
+		return &atlassian.StatusError{Op: "GET failed", StatusCode: resp.StatusCode}
+	}
+	return json.Unmarshal(rawBody, out)
+}
+
+// sendJSON sends payload (nil for no body) and fails unless the response status is one of ok.
+func (hc *HttpClient) sendJSON(method, apiURL string, payload interface{}, username, appPassword string, ok ...int) error {
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		body = strings.NewReader(string(raw))
+	}
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(username, appPassword)
+
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer resp.Body.Close()
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	rawBody, _ := io.ReadAll(resp.Body)
+	log.Errorf("%s %s failed. Status: %d, Body: %s", method, apiURL, resp.StatusCode, string(rawBody))
+	return &atlassian.StatusError{Op: method + " failed", StatusCode: resp.StatusCode}
+}
@@ -2,18 +2,53 @@ package bitbucket_impl
 
 import (
 	"code_nim/helper/atlassian"
+	"code_nim/helper/httpcache"
+	"code_nim/model"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type HttpClient struct {
 	http *http.Client
 }
 
-// New returns a production client.
+// New returns a production client sending its requests with httpClient; nil uses a client
+// with only the default timeout.
 // You can swap it for a mock in tests.
 func New(httpClient *http.Client) atlassian.Bitbucket {
 	if httpClient == nil {
-		httpClient = &http.Client{}
+		httpClient = &http.Client{Timeout: model.DefaultHTTPTimeout}
 	}
 	return &HttpClient{http: httpClient}
 }
+
+// Capabilities reports Bitbucket Cloud's comment features: Markdown, line ranges and tasks, but
+// no suggestion blocks.
+func (hc *HttpClient) Capabilities() model.ProviderCapabilities {
+	return model.ProviderCapabilities{Markdown: true, LineRanges: true, Tasks: true}
+}
+
+// WithCache returns a copy of httpClient that caches pull request lists and comments per
+// settings. Any write to a repository drops the cached responses of that repository, so
+// comments the bot just posted are never missed. The transport is returned for its Stats.
+func WithCache(httpClient *http.Client, settings model.HTTPCacheSettings) (*http.Client, *httpcache.Transport) {
+	cache := httpcache.New(httpClient.Transport, settings.TTLDuration(), settings.Entries())
+	cache.Cacheable = func(req *http.Request) bool {
+		p := strings.TrimSuffix(req.URL.Path, "/")
+		return strings.HasSuffix(p, "/pullrequests") || strings.HasSuffix(p, "/comments")
+	}
+	cache.Scope = repositoryScope
+	cached := *httpClient
+	cached.Transport = cache
+	return &cached, cache
+}
+
+// repositoryScope maps a URL to its repository, "https://api.bitbucket.org/2.0/repositories/{workspace}/{repo}/".
+func repositoryScope(u *url.URL) string {
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 5)
+	if len(parts) < 4 {
+		return u.Scheme + "://" + u.Host + "/"
+	}
+	return u.Scheme + "://" + u.Host + "/" + strings.Join(parts[:4], "/") + "/"
+}
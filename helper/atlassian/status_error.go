@@ -0,0 +1,38 @@
+package atlassian
+
+import (
+	"code_nim/helper"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StatusError is returned by the Bitbucket client when the API answers with an unexpected status.
+// It matches helper.ErrRateLimited, helper.ErrAuth and helper.ErrNotFound with errors.Is.
+type StatusError struct {
+	Op         string // what failed, e.g. "failed to post inline comment"
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s, status: %d", e.Op, e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case helper.ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case helper.ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case helper.ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// IsServerError reports whether err is a 5xx response from Bitbucket, which may succeed when
+// the request is sent again.
+func IsServerError(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode >= 500
+}
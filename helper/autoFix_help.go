@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// autoFixInstructions asks the AI for the replacement text of mechanically fixable findings
+// when auto-fix is enabled on the entry.
+func autoFixInstructions(cfg *model.AutoReviewPR) string {
+	if !cfg.AutoFix.Enabled {
+		return ""
+	}
+	return `- MECHANICAL FIXES: when a finding is mechanically fixable without judgement (a typo in an identifier, string or comment; a returned error that is ignored where the obvious handling is to return or wrap it; a missing nil/None check with an obvious early return), add "fix": "<replacement>" to its JSON object.
+  - <replacement> is the complete new text of the commented lines (startLineNumber to lineNumber, or lineNumber alone), without diff prefixes, with the file's indentation, lines separated by \n.
+  - The commented lines must be added or context lines, not removed ones. Omit "fix" whenever the change needs a decision, touches other lines, or you are not certain it compiles.
+`
+}
+
+// AutoFixBranch names the fix branch of a pull request's reviewed commit.
+func AutoFixBranch(prefix string, prID int, commit string) string {
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return fmt.Sprintf("%s%d-%s", prefix, prID, commit)
+}
+
+// fixAnchorMatches reports whether line is the commented line described by anchor, the line
+// text the AI quoted with or without its diff prefix.
+func fixAnchorMatches(line, anchor string) bool {
+	anchor = strings.TrimSpace(strings.TrimLeft(anchor, "+- "))
+	line = strings.TrimSpace(line)
+	return anchor == "" || line == anchor || (len(anchor) >= 8 && strings.Contains(line, anchor))
+}
+
+// ApplyFixes replaces the destination lines of each finding with its Fix in content, the file
+// at the reviewed commit. Findings on removed lines, whose lines no longer match their anchor,
+// or that overlap a fix already applied are left out. It returns the new content and the
+// findings that were applied, in line order.
+func ApplyFixes(content string, findings []model.ReviewComment) (string, []model.ReviewComment) {
+	sorted := append([]model.ReviewComment(nil), findings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position > sorted[j].Position })
+	lines := strings.Split(content, "\n")
+	lowest := len(lines) + 1 // first line of the fixes applied so far, bottom-up
+	var applied []model.ReviewComment
+	for _, f := range sorted {
+		end := f.Position
+		start := end
+		if f.StartLine > 0 && f.StartLine <= end {
+			start = f.StartLine
+		}
+		if f.Fix == "" || start <= 0 || end > len(lines) || end >= lowest || !fixAnchorMatches(lines[end-1], f.Anchor) {
+			continue
+		}
+		replacement := strings.Split(strings.TrimRight(strings.ReplaceAll(f.Fix, "\r\n", "\n"), "\n"), "\n")
+		if strings.HasSuffix(lines[end-1], "\r") {
+			for i := range replacement {
+				replacement[i] += "\r"
+			}
+		}
+		lines = append(lines[:start-1], append(replacement, lines[end:]...)...)
+		lowest = start
+		applied = append(applied, f)
+	}
+	for i, j := 0, len(applied)-1; i < j; i, j = i+1, j-1 {
+		applied[i], applied[j] = applied[j], applied[i]
+	}
+	return strings.Join(lines, "\n"), applied
+}
+
+// FormatAutoFixDescription renders the description of the follow-up pull request: one bullet
+// per applied fix, linked to the line of the original pull request.
+func FormatAutoFixDescription(workspace, repoSlug string, prID int, fixes []model.ReviewComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Mechanical fixes for findings of the review of #%d. Merge this pull request into its source branch to apply them.\n\n", prID)
+	for _, f := range fixes {
+		_, severity, title := ParseFindingHeading(f.Body)
+		line := fmt.Sprintf("[`%s:%d`](%s)", f.Path, f.Position, PullRequestLineURL(workspace, repoSlug, prID, f.Path, f.Position))
+		if severity != "" {
+			fmt.Fprintf(&b, "- %s [%s] %s\n", line, severity, title)
+		} else {
+			fmt.Fprintf(&b, "- %s %s\n", line, title)
+		}
+	}
+	b.WriteString("\nEach fix replaces only the commented lines. Review them like any other change before merging.\n")
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const defaultAzureAPIVersion = "2024-06-01"
+
+// azureChatCompletion calls an Azure OpenAI chat deployment and returns the assistant text.
+// When jsonMode is true the deployment is asked to reply with a JSON object. A reply cut off at
+// maxTokens is returned with an error wrapping ErrAITruncated.
+func azureChatCompletion(prompt string, cfg *model.AutoReviewPR, apiKey string, maxTokens int, temperature float64, jsonMode bool, usage *model.AIUsage) (string, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(cfg.AzureEndpoint), "/")
+	deployment := strings.TrimSpace(cfg.AzureDeployment)
+	if deployment == "" {
+		deployment = strings.TrimSpace(cfg.AIModel)
+	}
+	if endpoint == "" || deployment == "" {
+		return "", fmt.Errorf("azureEndpoint and azureDeployment are required when aiProvider=azure-openai")
+	}
+	if apiKey == "" {
+		return "", fmt.Errorf("aiKey is required when aiProvider=azure-openai")
+	}
+	apiVersion := strings.TrimSpace(cfg.AzureAPIVersion)
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s", endpoint, deployment, apiVersion)
+
+	payload := map[string]interface{}{
+		"messages":    []map[string]string{{"role": "user", "content": prompt}},
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+		"top_p":       0.95,
+	}
+	if jsonMode {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
+	b, _ := json.Marshal(payload)
+	log.Debugf("Calling Azure OpenAI deployment %s (api-version %s)", deployment, apiVersion)
+	resp, err := postJSON(url, map[string]string{"api-key": apiKey}, b)
+	if err != nil {
+		log.Errorf("Failed to call Azure OpenAI: %v", err)
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	rawBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		var errorResult struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(rawBody, &errorResult)
+		log.Errorf("Azure OpenAI returned status %d (%s): %s", resp.StatusCode, errorResult.Error.Code, errorResult.Error.Message)
+		return "", aiStatusError("azure openai", resp.StatusCode, errorResult.Error.Message)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+			TotalTokens      int64 `json:"total_tokens"`
+			// Azure caches prompt prefixes of 1,024 tokens or more automatically.
+			PromptTokensDetails struct {
+				CachedTokens int64 `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(rawBody, &result); err != nil {
+		log.Errorf("Failed to decode Azure OpenAI response: %v", err)
+		return "", err
+	}
+	if usage != nil {
+		usage.PromptTokens += result.Usage.PromptTokens
+		usage.ResponseTokens += result.Usage.CompletionTokens
+		usage.TotalTokens += result.Usage.TotalTokens
+		usage.CachedTokens += result.Usage.PromptTokensDetails.CachedTokens
+	}
+	if len(result.Choices) == 0 {
+		log.Error("Azure OpenAI returned no choices")
+		return "", fmt.Errorf("azure openai: %w: no choices", ErrAIInvalidResponse)
+	}
+	text := strings.TrimSpace(result.Choices[0].Message.Content)
+	if result.Choices[0].FinishReason == "length" {
+		log.Warnf("Azure OpenAI response was truncated (finish_reason=length)")
+		return text, fmt.Errorf("azure openai: %w (finish_reason=length)", ErrAITruncated)
+	}
+	return text, nil
+}
+
+// getAIResponseOfAzure requests inline review comments from an Azure OpenAI deployment.
+func getAIResponseOfAzure(prompt string, cfg *model.AutoReviewPR, apiKey string, usage *model.AIUsage) ([]model.ReviewComment, error) {
+	text, err := azureChatCompletion(prompt, cfg, apiKey, 8192, 0.8, true, usage)
+	if errors.Is(err, ErrAITruncated) {
+		return truncatedReviewComments(text, "Azure OpenAI")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseReviewComments(text, "Azure OpenAI")
+}
+
+// getAzureText returns a Markdown text reply (used for summaries).
+func getAzureText(prompt string, cfg *model.AutoReviewPR, apiKey string, usage *model.AIUsage) (string, error) {
+	text, err := azureChatCompletion(prompt, cfg, apiKey, 2048, 0.4, false, usage)
+	if err != nil && !errors.Is(err, ErrAITruncated) {
+		return "", err
+	}
+	text = strings.TrimPrefix(text, "```markdown")
+	text = strings.TrimPrefix(text, "```md")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text), nil
+}
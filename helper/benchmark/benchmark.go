@@ -0,0 +1,187 @@
+// Package benchmark runs a fixed suite of stored diffs through candidate AI models and scores
+// their findings against the expected ones, so models can be compared on the same input.
+package benchmark
+
+import (
+	"code_nim/helper"
+	"code_nim/log"
+	"code_nim/model"
+	"code_nim/review"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace is the usage workspace benchmark calls are accounted under; the repository is the
+// candidate label, so the usage API shows the cost of each candidate.
+const Workspace = "benchmark"
+
+// defaultLineTolerance is how far a finding may be from an expected one and still match it.
+const defaultLineTolerance = 3
+
+// LoadSuite reads the cases of dir: every <name>.diff, with optional metadata in <name>.yaml
+// (title, description and expected findings). Cases are sorted by name.
+func LoadSuite(dir string) ([]model.BenchmarkCase, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.diff"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var cases []model.BenchmarkCase
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(p), ".diff")
+		c := model.BenchmarkCase{Name: name}
+		if meta, err := os.ReadFile(strings.TrimSuffix(p, ".diff") + ".yaml"); err == nil {
+			if err := yaml.Unmarshal(meta, &c); err != nil {
+				return nil, fmt.Errorf("benchmark case %s: %w", name, err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		c.Name, c.Diff = name, string(raw)
+		if c.Title == "" {
+			c.Title = name
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no .diff files in %s", dir)
+	}
+	return cases, nil
+}
+
+// Label names a candidate as provider/model.
+func Label(cfg *model.AutoReviewPR) string {
+	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
+	if provider == "" {
+		provider = "gemini"
+	}
+	return provider + "/" + helper.AIModelName(cfg)
+}
+
+// Candidates returns the distinct AI settings of entries followed by extra. Each candidate
+// keeps only its AI settings and is accounted under Workspace, with its label as repository.
+func Candidates(entries, extra []model.AutoReviewPR) []model.AutoReviewPR {
+	seen := map[string]bool{}
+	var out []model.AutoReviewPR
+	for _, e := range append(append([]model.AutoReviewPR(nil), entries...), extra...) {
+		c := model.AutoReviewPR{
+			GeminiKey:             e.GeminiKey,
+			GeminiModel:           e.GeminiModel,
+			AIProvider:            e.AIProvider,
+			AIModel:               e.AIModel,
+			AIKey:                 e.AIKey,
+			AIKeys:                e.AIKeys,
+			AIKeyRotation:         e.AIKeyRotation,
+			SelfAPIBaseURL:        e.SelfAPIBaseURL,
+			AzureEndpoint:         e.AzureEndpoint,
+			AzureDeployment:       e.AzureDeployment,
+			AzureAPIVersion:       e.AzureAPIVersion,
+			VertexProject:         e.VertexProject,
+			VertexRegion:          e.VertexRegion,
+			VertexCredentialsFile: e.VertexCredentialsFile,
+			// The suite compares models; advisories from OSV.dev would be the same for all.
+			SkipVulnerabilityLookup: true,
+		}
+		key := strings.Join([]string{Label(&c), c.SelfAPIBaseURL, c.AzureEndpoint, c.AzureDeployment, c.VertexProject, c.VertexRegion}, "|")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		c.ProcessName = Label(&c)
+		c.Workspace, c.RepoSlug = Workspace, c.ProcessName
+		out = append(out, c)
+	}
+	return out
+}
+
+// Run reviews every file of every case with cfg and scores the placed findings. A finding
+// matches an expected one on the same path within tolerance lines (0 uses the default).
+// Summaries are not generated: the suite measures findings. Tokens and cost are left for the
+// caller, which reads them from the usage tracker.
+func Run(cfg model.AutoReviewPR, cases []model.BenchmarkCase, tolerance int) model.BenchmarkResult {
+	if tolerance <= 0 {
+		tolerance = defaultLineTolerance
+	}
+	res := model.BenchmarkResult{
+		Candidate: Label(&cfg),
+		Provider:  strings.SplitN(Label(&cfg), "/", 2)[0],
+		Model:     helper.AIModelName(&cfg),
+		Cases:     len(cases),
+	}
+	r := review.New(cfg)
+	var elapsed time.Duration
+	scoredPlaced, scoredNear := 0, 0
+	for _, c := range cases {
+		pr := &model.PullRequest{Title: c.Title, Description: c.Description}
+		start := time.Now()
+		var placed []model.ReviewComment
+		for _, file := range r.ParseDiff(c.Diff) {
+			path := file.Path
+			fr, err := r.ReviewFile(pr, path, file.Hunks)
+			if errors.Is(err, review.ErrEmptySnippet) {
+				continue
+			}
+			if err != nil {
+				log.Warnf("Benchmark %s failed on %s/%s: %v", res.Candidate, c.Name, path, err)
+				res.Errors++
+				continue
+			}
+			placed = append(placed, fr.Placed...)
+			res.Rejected += len(fr.Rejected)
+		}
+		elapsed += time.Since(start)
+		res.Placed += len(placed)
+
+		res.Expected += len(c.Expected)
+		for _, e := range c.Expected {
+			for _, p := range placed {
+				if near(p, e, tolerance) {
+					res.Matched++
+					break
+				}
+			}
+		}
+		if len(c.Expected) == 0 {
+			continue
+		}
+		scoredPlaced += len(placed)
+		for _, p := range placed {
+			for _, e := range c.Expected {
+				if near(p, e, tolerance) {
+					scoredNear++
+					break
+				}
+			}
+		}
+	}
+
+	if res.Expected > 0 {
+		res.Recall = float64(res.Matched) / float64(res.Expected)
+	}
+	if scoredPlaced > 0 {
+		res.Precision = float64(scoredNear) / float64(scoredPlaced)
+	}
+	if total := res.Placed + res.Rejected; total > 0 {
+		res.PlacementRate = float64(res.Placed) / float64(total)
+	}
+	if len(cases) > 0 {
+		res.AvgCaseLatency = elapsed / time.Duration(len(cases))
+	}
+	return res
+}
+
+func near(c model.ReviewComment, e model.BenchmarkExpectation, tolerance int) bool {
+	d := c.Position - e.Line
+	return c.Path == e.Path && d >= -tolerance && d <= tolerance
+}
@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"regexp"
+	"strings"
+)
+
+// CodeownersPaths are the locations tried for the CODEOWNERS file, in order.
+var CodeownersPaths = []string{"CODEOWNERS", ".bitbucket/CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeownersRule assigns owners to the paths matching a gitignore-style pattern.
+type CodeownersRule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// uuidPattern matches a Bitbucket UUID without its braces.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ParseCodeowners reads the rules of a CODEOWNERS file. Comments, blank lines, section headers
+// and rules without owners are skipped.
+func ParseCodeowners(content string) []CodeownersRule {
+	var rules []CodeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		re, err := regexp.Compile(codeownersRegexp(fields[0]))
+		if err != nil {
+			log.Warnf("Ignoring CODEOWNERS pattern %q: %v", fields[0], err)
+			continue
+		}
+		rules = append(rules, CodeownersRule{Pattern: fields[0], Owners: fields[1:], re: re})
+	}
+	return rules
+}
+
+// codeownersRegexp converts a gitignore-style pattern: a leading or inner slash anchors it to
+// the repository root, a trailing slash matches only below a directory, "*" stays within a path
+// segment and "**" spans segments. A match on a directory covers every file below it.
+func codeownersRegexp(pattern string) string {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return b.String()
+}
+
+// CodeownersFor returns the owners of path: those of the last matching rule, as in GitHub and
+// Bitbucket CODEOWNERS.
+func CodeownersFor(rules []CodeownersRule, path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(path) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// CodeownersUser converts an owner token. "@{uuid}" and "@{account-id}" identify an account
+// that can be added as a reviewer; other tokens (usernames, groups, emails) are kept as
+// display names for the suggestion only.
+func CodeownersUser(owner string) model.BitbucketUser {
+	if strings.HasPrefix(owner, "@{") && strings.HasSuffix(owner, "}") {
+		id := strings.Trim(owner[2:len(owner)-1], "{}")
+		if uuidPattern.MatchString(id) {
+			return model.BitbucketUser{UUID: "{" + id + "}"}
+		}
+		return model.BitbucketUser{AccountID: id}
+	}
+	return model.BitbucketUser{DisplayName: owner}
+}
+
+// SameUser reports whether a and b are the same account, by UUID, account ID or, when neither
+// has an ID, display name.
+func SameUser(a, b model.BitbucketUser) bool {
+	switch {
+	case a.UUID != "" && b.UUID != "":
+		return strings.EqualFold(a.UUID, b.UUID)
+	case a.AccountID != "" && b.AccountID != "":
+		return a.AccountID == b.AccountID
+	case a.UUID == "" && a.AccountID == "" || b.UUID == "" && b.AccountID == "":
+		return a.DisplayName != "" && a.DisplayName == b.DisplayName
+	}
+	return false
+}
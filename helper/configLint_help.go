@@ -0,0 +1,824 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configLinter collects warnings while it walks a config file.
+type configLinter struct {
+	lines    map[string]int // Line of each key path, to place semantic warnings
+	warnings []model.ConfigWarning
+}
+
+func (l *configLinter) warn(kind, path, format string, args ...interface{}) {
+	// A missing key is reported at the line of the closest enclosing key.
+	line, at := 0, path
+	for line == 0 && at != "" {
+		line = l.lines[at]
+		i := strings.LastIndexAny(at, ".[")
+		if i < 0 {
+			break
+		}
+		at = at[:i]
+	}
+	l.warnings = append(l.warnings, model.ConfigWarning{Kind: kind, Path: path, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// LintConfig checks a YAML config for unknown keys, deprecated fields, conflicting options and
+// invalid values. It returns an error only when the YAML cannot be parsed.
+func LintConfig(data []byte) ([]model.ConfigWarning, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var cfg model.Task
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	l := &configLinter{lines: map[string]int{}}
+	l.walk(&root, reflect.TypeOf(cfg), "")
+	l.lintTask(cfg)
+	return l.warnings, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlFields maps the YAML keys of a struct type to their field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") || (f.Anonymous && tag == "") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// walk reports keys of node that type t does not define.
+func (l *configLinter) walk(node *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			l.walk(node.Content[0], t, path)
+		}
+		return
+	case yaml.AliasNode:
+		l.walk(node.Alias, t, path)
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := joinPath(path, key.Value)
+			l.lines[p] = key.Line
+			ft, ok := fields[key.Value]
+			if !ok {
+				l.warn(model.ConfigWarningUnknown, p, "unknown key %q is ignored%s", key.Value, suggestKey(key.Value, fields))
+				continue
+			}
+			l.walk(value, ft, p)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			p := joinPath(path, node.Content[i].Value)
+			l.lines[p] = node.Content[i].Line
+			l.walk(node.Content[i+1], t.Elem(), p)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			l.lines[p] = item.Line
+			l.walk(item, t.Elem(), p)
+		}
+	}
+}
+
+// suggestKey names a known key that differs from key only in case or by one typo.
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	for name := range fields {
+		if strings.EqualFold(name, key) || editDistance(strings.ToLower(name), strings.ToLower(key)) == 1 {
+			return fmt.Sprintf(" (did you mean %q?)", name)
+		}
+	}
+	return ""
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// lintTask checks the decoded config for deprecated, conflicting and invalid settings.
+func (l *configLinter) lintTask(cfg model.Task) {
+	seen := map[string]int{}
+	for i, auto := range cfg.AutoReviewPRs {
+		path := fmt.Sprintf("autoReviewPR[%d]", i)
+		key := auto.ProcessName
+		if strings.TrimSpace(key) == "" {
+			key = auto.Workspace + "/" + strings.Join(auto.RepoPatterns(), ",")
+		}
+		if first, ok := seen[key]; ok {
+			l.warn(model.ConfigWarningConflict, path, "entry %q is already defined by autoReviewPR[%d]; set a distinct processName", key, first)
+		} else {
+			seen[key] = i
+		}
+		l.lintEntry(auto, path)
+		l.lintCredentials(cfg, auto, path)
+		refs := map[string]string{
+			"username": auto.Username, "appPassword": auto.AppPassword, "webhookSecret": auto.WebhookSecret,
+			"geminiKey": auto.GeminiKey, "aiKey": auto.AIKey,
+		}
+		for i, n := range auto.Notifiers {
+			refs[fmt.Sprintf("notifiers[%d].url", i)] = n.URL
+			refs[fmt.Sprintf("notifiers[%d].smtp.password", i)] = n.SMTP.Password
+		}
+		l.lintSecretRefs(path, refs, auto.AIKeys)
+	}
+	profiles := make([]string, 0, len(cfg.Credentials))
+	for name := range cfg.Credentials {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		profile := cfg.Credentials[name]
+		l.lintSecretRefs("credentials."+name, map[string]string{
+			"username": profile.Username, "appPassword": profile.AppPassword, "aiKey": profile.AIKey,
+		}, profile.AIKeys)
+	}
+
+	q := cfg.Queue
+	switch q.Backend {
+	case "", model.QueueBackendMemory:
+		if q.RoleName() != model.QueueRoleAll {
+			l.warn(model.ConfigWarningConflict, "queue.role", "queue role %q needs backend redis", q.Role)
+		}
+	case model.QueueBackendRedis:
+		if q.Redis.Addr == "" {
+			l.warn(model.ConfigWarningInvalid, "queue.redis.addr", "queue.redis.addr is required with backend redis")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, "queue.backend", "unknown queue backend %q", q.Backend)
+	}
+	switch q.RoleName() {
+	case model.QueueRoleAll, model.QueueRoleScheduler, model.QueueRoleWorker:
+	default:
+		l.warn(model.ConfigWarningInvalid, "queue.role", "unknown queue role %q", q.Role)
+	}
+	if q.Threshold > 0 && q.Capacity > 0 && q.Capacity < q.Threshold {
+		l.warn(model.ConfigWarningConflict, "queue.capacity", "capacity %d is below threshold %d and is raised to it", q.Capacity, q.Threshold)
+	}
+	if q.ClaimTTL != "" {
+		if d, err := time.ParseDuration(q.ClaimTTL); err != nil || d <= 0 {
+			l.warn(model.ConfigWarningInvalid, "queue.claimTtl", "claimTtl %q is not a positive duration; using 1h", q.ClaimTTL)
+		}
+	}
+
+	if _, ok := cfg.Webhook.MaxAgeDuration(); !ok {
+		l.warn(model.ConfigWarningInvalid, "webhook.maxAge", "maxAge %q is not a duration; using %v", cfg.Webhook.MaxAge, model.DefaultWebhookMaxAge)
+	}
+
+	if cfg.HTTP.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.HTTP.Timeout); err != nil || d <= 0 {
+			l.warn(model.ConfigWarningInvalid, "http.timeout", "timeout %q is not a positive duration; using %v", cfg.HTTP.Timeout, model.DefaultHTTPTimeout)
+		}
+	}
+	if p := strings.TrimSpace(cfg.HTTP.Proxy); p != "" {
+		if u, err := url.Parse(p); err != nil || u.Host == "" {
+			l.warn(model.ConfigWarningInvalid, "http.proxy", "proxy %q is not a URL such as http://proxy.example.com:3128", cfg.HTTP.Proxy)
+		}
+	}
+	if path := strings.TrimSpace(cfg.HTTP.CABundle); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			l.warn(model.ConfigWarningInvalid, "http.caBundle", "%v", err)
+		}
+	}
+	if ttl := cfg.HTTP.Cache.TTL; ttl != "" {
+		if d, err := time.ParseDuration(ttl); err != nil || d < 0 {
+			l.warn(model.ConfigWarningInvalid, "http.cache.ttl", "ttl %q is not a duration; using %v", ttl, cfg.HTTP.Cache.TTLDuration())
+		}
+	}
+	if _, ok := cfg.AICache.MaxAgeDuration(); !ok {
+		l.warn(model.ConfigWarningInvalid, "aiCache.maxAge", "maxAge %q is not a positive duration; using %v", cfg.AICache.MaxAge, model.DefaultAICacheMaxAge)
+	}
+	if _, ok := cfg.Secrets.RefreshDuration(); !ok {
+		l.warn(model.ConfigWarningInvalid, "secrets.refreshInterval", "refreshInterval %q is not a positive duration; secrets are only resolved at startup", cfg.Secrets.RefreshInterval)
+	}
+
+	le := cfg.LeaderElection
+	switch strings.ToLower(strings.TrimSpace(le.Backend)) {
+	case "", model.LeaderBackendKubernetes:
+	case model.LeaderBackendRedis:
+		if le.Redis.Addr == "" {
+			l.warn(model.ConfigWarningInvalid, "leaderElection.redis.addr", "leaderElection.redis.addr is required with backend redis")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, "leaderElection.backend", "unknown leader election backend %q", le.Backend)
+	}
+
+	for i, c := range cfg.Benchmark.Candidates {
+		l.lintAIProvider(c, fmt.Sprintf("benchmark.candidates[%d]", i))
+	}
+	if cfg.Benchmark.LineTolerance < 0 {
+		l.warn(model.ConfigWarningInvalid, "benchmark.lineTolerance", "lineTolerance must not be negative; using 3")
+	}
+
+	if _, ok := cfg.CircuitBreaker.CooldownAfter(0); !ok {
+		l.warn(model.ConfigWarningInvalid, "circuitBreaker", "cooldown and maxCooldown must be positive durations such as 5m; using %v and %v", model.DefaultCircuitCooldown, model.DefaultCircuitMaxCooldown)
+	}
+	switch cfg.SelfTest.Mode() {
+	case model.SelfTestLog, model.SelfTestFail, model.SelfTestOff:
+	default:
+		l.warn(model.ConfigWarningInvalid, "selfTest.onStartup", "unknown mode %q; use log, fail or off (using log)", cfg.SelfTest.OnStartup)
+	}
+	if cfg.FakeBitbucket.RecordDir != "" && !cfg.FakeBitbucket.Enabled() {
+		l.warn(model.ConfigWarningConflict, "fakeBitbucket.recordDir", "recordDir has no effect without fakeBitbucket.fixtures")
+	}
+	switch cfg.AIReplay.ModeName() {
+	case "", model.AIReplayRecord, model.AIReplayReplay, model.AIReplayAuto:
+	default:
+		l.warn(model.ConfigWarningInvalid, "aiReplay.mode", "unknown mode %q; use record, replay or auto (AI replies are not recorded)", cfg.AIReplay.Mode)
+	}
+	rl := cfg.AIRateLimit
+	if rl.RPM < 0 || rl.TPM < 0 {
+		l.warn(model.ConfigWarningInvalid, "aiRateLimit", "rpm and tpm must not be negative; negative limits are ignored")
+	}
+	models := make([]string, 0, len(rl.Models))
+	for name := range rl.Models {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	for _, name := range models {
+		if limit := rl.Models[name]; limit.RPM < 0 || limit.TPM < 0 {
+			l.warn(model.ConfigWarningInvalid, "aiRateLimit.models."+name, "rpm and tpm must not be negative; negative limits are ignored")
+		}
+	}
+	if _, ok := rl.MaxWaitDuration(); !ok {
+		l.warn(model.ConfigWarningInvalid, "aiRateLimit.maxWait", "maxWait %q is not a positive duration; using %v", rl.MaxWait, model.DefaultAIRateLimitMaxWait)
+	}
+	if !rl.Enabled() && (rl.MaxWait != "" || rl.ResponseTokens != 0) {
+		l.warn(model.ConfigWarningConflict, "aiRateLimit", "aiRateLimit has no effect without rpm or tpm")
+	}
+
+	teams := map[string]int{}
+	for i, d := range cfg.Digests {
+		path := fmt.Sprintf("digests[%d]", i)
+		if strings.TrimSpace(d.Team) == "" {
+			l.warn(model.ConfigWarningInvalid, path+".team", "team is required to name the digest")
+		} else if first, ok := teams[d.Team]; ok {
+			l.warn(model.ConfigWarningConflict, path+".team", "team %q already has digests[%d]; set a distinct team", d.Team, first)
+		} else {
+			teams[d.Team] = i
+		}
+		if d.SMTP.Host == "" || d.SMTP.From == "" || len(d.SMTP.To) == 0 {
+			l.warn(model.ConfigWarningInvalid, path+".smtp", "digest needs smtp.host, smtp.from and smtp.to; it is not sent")
+		}
+		for j, name := range d.Entries {
+			if !seenEntry(seen, name) {
+				l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.entries[%d]", path, j), "no entry %q is configured", name)
+			}
+		}
+	}
+}
+
+// seenEntry reports whether name is the processName (or workspace/repoSlug) of an entry.
+func seenEntry(seen map[string]int, name string) bool {
+	for key := range seen {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintCredentials checks the credentials profiles an entry names and the credentials it
+// overrides on its own.
+func (l *configLinter) lintCredentials(cfg model.Task, auto model.AutoReviewPR, path string) {
+	if len(auto.Credentials) == 0 {
+		return
+	}
+	for _, name := range auto.Credentials {
+		if _, ok := cfg.Credentials[strings.TrimSpace(name)]; !ok {
+			l.warn(model.ConfigWarningInvalid, path+".credentials", "unknown credentials profile %q", name)
+		}
+	}
+	overrides := []struct {
+		key string
+		set bool
+	}{{"username", auto.Username != ""}, {"appPassword", auto.AppPassword != ""}, {"aiKey", auto.AIKey != ""}, {"aiKeys", len(auto.AIKeys) > 0}}
+	for _, o := range overrides {
+		if o.set {
+			l.warn(model.ConfigWarningConflict, path+"."+o.key, "%s set on the entry overrides its credentials profile", o.key)
+		}
+	}
+}
+
+// lintSecretRefs checks that the vault: and aws-sm: references among fields and aiKeys name a
+// secret.
+func (l *configLinter) lintSecretRefs(path string, fields map[string]string, aiKeys []string) {
+	for i, k := range aiKeys {
+		fields[fmt.Sprintf("aiKeys[%d]", i)] = k
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := fields[name]
+		for _, scheme := range []string{model.SecretSchemeVault, model.SecretSchemeAWS} {
+			ref, ok := strings.CutPrefix(value, scheme)
+			if !ok {
+				continue
+			}
+			if id, _, _ := strings.Cut(ref, "#"); strings.Trim(id, "/ ") == "" {
+				l.warn(model.ConfigWarningInvalid, path+"."+name, "secret reference %q names no secret", value)
+			}
+		}
+	}
+}
+
+func (l *configLinter) lintEntry(auto model.AutoReviewPR, path string) {
+	for _, p := range auto.RepoPatterns() {
+		if !validRepoPattern(p) {
+			l.warn(model.ConfigWarningInvalid, path+".repoSlug", "repository pattern %q is not a valid glob", p)
+		}
+	}
+	if auto.GeminiKey != "" {
+		if auto.AIKey != "" || len(auto.AIKeys) > 0 {
+			l.warn(model.ConfigWarningDeprecated, path+".geminiKey", "geminiKey is deprecated and ignored because aiKey or aiKeys is set; remove it")
+		} else {
+			l.warn(model.ConfigWarningDeprecated, path+".geminiKey", "geminiKey is deprecated; use aiKey")
+		}
+	}
+	if auto.GeminiModel != "" {
+		if auto.AIModel != "" {
+			l.warn(model.ConfigWarningDeprecated, path+".geminiModel", "geminiModel is deprecated and ignored because aiModel is set; remove it")
+		} else {
+			l.warn(model.ConfigWarningDeprecated, path+".geminiModel", "geminiModel is deprecated; use aiModel")
+		}
+	}
+	if auto.AIKey != "" && len(auto.AIKeys) > 0 {
+		l.warn(model.ConfigWarningConflict, path+".aiKey", "aiKey is ignored because aiKeys is set")
+	}
+	switch auto.AIKeyRotation {
+	case "", KeyRotationRoundRobin, KeyRotationOn429:
+		if auto.AIKeyRotation != "" && len(auto.AIKeys) < 2 {
+			l.warn(model.ConfigWarningConflict, path+".aiKeyRotation", "aiKeyRotation has no effect with fewer than two aiKeys")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".aiKeyRotation", "unknown aiKeyRotation %q; use %q or %q", auto.AIKeyRotation, KeyRotationRoundRobin, KeyRotationOn429)
+	}
+
+	l.lintAIProvider(auto, path)
+	l.lintGitProvider(auto, path)
+
+	switch strings.ToLower(strings.TrimSpace(auto.Tone)) {
+	case ToneDefault, ToneConcise, ToneTerse, ToneMentoring, ToneStrict:
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".tone", "unknown tone %q; the default tone is used", auto.Tone)
+	}
+	switch strings.ToLower(strings.TrimSpace(auto.ReviewStyle)) {
+	case ToneDefault, ToneConcise, ToneTerse, ToneMentoring, ToneStrict:
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".reviewStyle", "unknown reviewStyle %q; the default style is used", auto.ReviewStyle)
+	}
+	if auto.ReviewStyle != "" && auto.Tone != "" && NormalizeTone(auto.ReviewStyle) != NormalizeTone(auto.Tone) {
+		l.warn(model.ConfigWarningConflict, path+".tone", "tone %q is ignored because reviewStyle %q is set; remove one", auto.Tone, auto.ReviewStyle)
+	}
+	if !ValidLanguage(auto.Language) {
+		l.warn(model.ConfigWarningInvalid, path+".language", "unknown language %q; comments are written in English", auto.Language)
+	}
+	if auto.CommentMode != "" && auto.CommentMode != "minimal" {
+		l.warn(model.ConfigWarningInvalid, path+".commentMode", "unknown commentMode %q; inline comments are posted", auto.CommentMode)
+	}
+	switch strings.ToLower(strings.TrimSpace(auto.Mode)) {
+	case "", model.ReviewModeFull, model.ReviewModeInline:
+	case model.ReviewModeSummary:
+		if auto.AutoApprove || auto.Gerrit.Approve {
+			l.warn(model.ConfigWarningConflict, path+".mode", "mode summary reviews no file, so the bot does not vote on approval")
+		}
+		if auto.AutoFix.Enabled || auto.TestSuggestions.Enabled || auto.StaticAnalysis.Enabled() {
+			l.warn(model.ConfigWarningConflict, path+".mode", "mode summary reviews no file; autoFix, testSuggestions and staticAnalysis have no effect")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".mode", "unknown mode %q; both the summary and inline comments are posted", auto.Mode)
+	}
+	if auto.AutoApproveMaxSeverity != "" {
+		if !ValidSeverity(auto.AutoApproveMaxSeverity) {
+			l.warn(model.ConfigWarningInvalid, path+".autoApproveMaxSeverity", "unknown severity %q", auto.AutoApproveMaxSeverity)
+		}
+		if !auto.AutoApprove {
+			l.warn(model.ConfigWarningConflict, path+".autoApproveMaxSeverity", "autoApproveMaxSeverity has no effect unless autoApprove is true")
+		}
+	}
+	if auto.BusyPRHumanComments < 0 {
+		l.warn(model.ConfigWarningInvalid, path+".busyPrHumanComments", "busyPrHumanComments must not be negative; busy-PR deferral is disabled")
+	}
+	if auto.BusyPRMinSeverity != "" {
+		if !ValidSeverity(auto.BusyPRMinSeverity) {
+			l.warn(model.ConfigWarningInvalid, path+".busyPrMinSeverity", "unknown severity %q; Major is used", auto.BusyPRMinSeverity)
+		}
+		if auto.BusyPRHumanComments <= 0 {
+			l.warn(model.ConfigWarningConflict, path+".busyPrMinSeverity", "busyPrMinSeverity has no effect unless busyPrHumanComments is set")
+		}
+	}
+	for i, linter := range auto.StaticAnalysis.Linters {
+		switch {
+		case len(linter.Command) > 0:
+		case linter.Name == model.LinterGoVet, linter.Name == model.LinterStaticcheck, linter.Name == model.LinterESLint:
+		default:
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.staticAnalysis.linters[%d]", path, i), "unknown linter %q; use go-vet, staticcheck, eslint, or set command", linter.Name)
+		}
+	}
+	if auto.StaticAnalysis.Timeout != "" {
+		if d, err := time.ParseDuration(auto.StaticAnalysis.Timeout); err != nil || d <= 0 {
+			l.warn(model.ConfigWarningInvalid, path+".staticAnalysis.timeout", "invalid duration %q; 5m is used", auto.StaticAnalysis.Timeout)
+		}
+	}
+	if auto.CodeInsights.FailSeverity != "" {
+		if !ValidSeverity(auto.CodeInsights.FailSeverity) || strings.EqualFold(strings.TrimSpace(auto.CodeInsights.FailSeverity), SeverityNone) {
+			l.warn(model.ConfigWarningInvalid, path+".codeInsights.failSeverity", "unknown severity %q; Major is used", auto.CodeInsights.FailSeverity)
+		}
+		if !auto.CodeInsights.Enabled {
+			l.warn(model.ConfigWarningConflict, path+".codeInsights.failSeverity", "failSeverity has no effect unless codeInsights.enabled is true")
+		}
+	}
+	if auto.BuildStatus.FailSeverity != "" {
+		if !ValidSeverity(auto.BuildStatus.FailSeverity) || strings.EqualFold(strings.TrimSpace(auto.BuildStatus.FailSeverity), SeverityNone) {
+			l.warn(model.ConfigWarningInvalid, path+".buildStatus.failSeverity", "unknown severity %q; Major is used", auto.BuildStatus.FailSeverity)
+		}
+		if !auto.BuildStatus.Enabled {
+			l.warn(model.ConfigWarningConflict, path+".buildStatus.failSeverity", "failSeverity has no effect unless buildStatus.enabled is true")
+		}
+	}
+	if auto.TitlePolicy.Pattern != "" {
+		if _, err := regexp.Compile(auto.TitlePolicy.Pattern); err != nil {
+			l.warn(model.ConfigWarningInvalid, path+".titlePolicy.pattern", "invalid regular expression: %v; the pattern is not enforced", err)
+		}
+	}
+	if (auto.TitlePolicy.SuggestTitle || auto.TitlePolicy.Task || len(auto.TitlePolicy.Types) > 0) && !auto.TitlePolicy.Enabled() {
+		l.warn(model.ConfigWarningConflict, path+".titlePolicy", "titlePolicy has no effect without pattern or conventional")
+	}
+	if len(auto.TitlePolicy.Types) > 0 && !auto.TitlePolicy.Conventional {
+		l.warn(model.ConfigWarningConflict, path+".titlePolicy.types", "types has no effect unless titlePolicy.conventional is true")
+	}
+	if !auto.StaleReminders.Enabled() && (auto.StaleReminders.AfterDays != 0 || auto.StaleReminders.RepeatDays != 0 || len(auto.StaleReminders.Reviewers) > 0) {
+		l.warn(model.ConfigWarningConflict, path+".staleReminders", "staleReminders has no effect without cron")
+	}
+	if !auto.ReviewerSuggestions.Enabled && (auto.ReviewerSuggestions.AutoAdd || auto.ReviewerSuggestions.Max != 0 || auto.ReviewerSuggestions.CodeownersPath != "") {
+		l.warn(model.ConfigWarningConflict, path+".reviewerSuggestions", "reviewerSuggestions has no effect unless enabled is true")
+	}
+	if auto.CommentFooter.Feedback != "" && !auto.CommentFooter.Enabled {
+		l.warn(model.ConfigWarningConflict, path+".commentFooter.feedback", "feedback has no effect unless commentFooter.enabled is true")
+	}
+	if auto.AdaptivePrompt.Enabled && !auto.Feedback.Enabled() {
+		l.warn(model.ConfigWarningConflict, path+".adaptivePrompt", "adaptivePrompt learns only from stored feedback; set feedback.cron to collect it")
+	}
+	if !auto.AdaptivePrompt.Enabled && (auto.AdaptivePrompt.MinDismissals != 0 || auto.AdaptivePrompt.WindowDays != 0) {
+		l.warn(model.ConfigWarningConflict, path+".adaptivePrompt", "adaptivePrompt has no effect unless enabled is true")
+	}
+	for severity, w := range auto.RiskScore.SeverityWeights {
+		if !ValidSeverity(severity) || strings.EqualFold(strings.TrimSpace(severity), SeverityNone) {
+			l.warn(model.ConfigWarningInvalid, path+".riskScore.severityWeights."+severity, "unknown severity %q; the weight is ignored", severity)
+		} else if w < 0 {
+			l.warn(model.ConfigWarningInvalid, path+".riskScore.severityWeights."+severity, "weight must not be negative")
+		}
+	}
+	for category, w := range auto.RiskScore.CategoryWeights {
+		if w < 0 {
+			l.warn(model.ConfigWarningInvalid, path+".riskScore.categoryWeights."+category, "weight must not be negative")
+		}
+	}
+	if caution, block := auto.RiskScore.Thresholds(); caution > block {
+		l.warn(model.ConfigWarningConflict, path+".riskScore.cautionAt", "cautionAt %d is above blockAt %d; no PR gets the caution recommendation", caution, block)
+	}
+	if !auto.RiskScore.Enabled && (len(auto.RiskScore.SeverityWeights) > 0 || len(auto.RiskScore.CategoryWeights) > 0 || auto.RiskScore.LinesPerPoint != 0 || auto.RiskScore.CriticalPathPoints != 0 || auto.RiskScore.CautionAt != 0 || auto.RiskScore.BlockAt != 0) {
+		l.warn(model.ConfigWarningConflict, path+".riskScore", "riskScore has no effect unless enabled is true")
+	}
+	for i, p := range auto.CriticalPaths {
+		if strings.TrimSpace(p) == "" {
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.criticalPaths[%d]", path, i), "blank pattern is ignored")
+		}
+	}
+	if auto.CriticalPathReview.MinSeverity != "" && (!ValidSeverity(auto.CriticalPathReview.MinSeverity) || strings.EqualFold(strings.TrimSpace(auto.CriticalPathReview.MinSeverity), SeverityNone)) {
+		l.warn(model.ConfigWarningInvalid, path+".criticalPathReview.minSeverity", "unknown severity %q; %s is used", auto.CriticalPathReview.MinSeverity, model.DefaultCriticalPathMinSeverity)
+	}
+	if len(auto.CriticalPaths) == 0 && (auto.CriticalPathReview.Instructions != "" || auto.CriticalPathReview.MinSeverity != "") {
+		l.warn(model.ConfigWarningConflict, path+".criticalPathReview", "criticalPathReview has no effect without criticalPaths")
+	}
+	if auto.CriticalPathReview.MinSeverity != "" && auto.BusyPRHumanComments <= 0 && !packageMinSeverity(auto) {
+		l.warn(model.ConfigWarningConflict, path+".criticalPathReview.minSeverity", "minSeverity has no effect unless busyPrHumanComments or a package profile minSeverity is set")
+	}
+	for i, m := range auto.SkipMarkers {
+		if strings.TrimSpace(m) == "" {
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.skipMarkers[%d]", path, i), "blank skip marker is ignored")
+		}
+	}
+	for _, hours := range []struct {
+		field   string
+		windows []model.TimeWindow
+	}{{"activeHours", auto.ActiveHours}, {"quietHours", auto.QuietHours}} {
+		for i, w := range hours.windows {
+			if err := CheckTimeWindow(w); err != nil {
+				l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.%s[%d]", path, hours.field, i), "%v; the window is ignored", err)
+			}
+		}
+	}
+	if open, opens := PostingAllowed(auto.ActiveHours, auto.QuietHours, time.Now()); !open && opens.IsZero() {
+		l.warn(model.ConfigWarningConflict, path+".quietHours", "activeHours and quietHours leave no time to post; reviews are held forever")
+	}
+	for i, id := range auto.IgnorePullRequests {
+		if id <= 0 {
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.ignorePullRequests[%d]", path, i), "pull request ID %d is not valid", id)
+		}
+	}
+	if auto.PostConcurrency > 10 {
+		l.warn(model.ConfigWarningInvalid, path+".postConcurrency", "postConcurrency %d is likely to hit Bitbucket rate limits; 10 or fewer is recommended", auto.PostConcurrency)
+	}
+	if auto.ContributingGuideURL != "" && !auto.WelcomeFirstTimeContributors {
+		l.warn(model.ConfigWarningConflict, path+".contributingGuideUrl", "contributingGuideUrl has no effect unless welcomeFirstTimeContributors is true")
+	}
+	for i, w := range auto.FreezeWindows {
+		if _, _, _, err := FreezeRange(w, time.Now()); err != nil {
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.freezeWindows[%d]", path, i), "%v", err)
+		}
+	}
+	for i, n := range auto.Notifiers {
+		l.lintNotifier(n, fmt.Sprintf("%s.notifiers[%d]", path, i))
+	}
+	l.lintPackages(auto, path)
+	l.lintModelRoutes(auto, path)
+}
+
+// packageMinSeverity reports whether a package profile of auto sets a minSeverity.
+func packageMinSeverity(auto model.AutoReviewPR) bool {
+	for _, profile := range auto.PackageProfiles {
+		if strings.TrimSpace(profile.MinSeverity) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// lintPackages checks that packages route to known profiles and the settings of each profile.
+func (l *configLinter) lintPackages(auto model.AutoReviewPR, path string) {
+	prefixes := make([]string, 0, len(auto.Packages))
+	for prefix := range auto.Packages {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	used := map[string]bool{}
+	for _, prefix := range prefixes {
+		name, p := auto.Packages[prefix], path+".packages."+prefix
+		if packagePrefix(prefix) == "" {
+			l.warn(model.ConfigWarningInvalid, p, "blank package path is ignored")
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if _, ok := auto.PackageProfiles[name]; !ok {
+			l.warn(model.ConfigWarningInvalid, p, "unknown package profile %q; the files of %s use the entry's settings", name, prefix)
+			continue
+		}
+		used[name] = true
+	}
+	names := make([]string, 0, len(auto.PackageProfiles))
+	for name := range auto.PackageProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile, p := auto.PackageProfiles[name], path+".packageProfiles."+name
+		if !used[name] {
+			l.warn(model.ConfigWarningConflict, p, "package profile %q has no effect; no package routes to it", name)
+		}
+		switch strings.ToLower(strings.TrimSpace(profile.ReviewStyle)) {
+		case ToneDefault, ToneConcise, ToneTerse, ToneMentoring, ToneStrict:
+		default:
+			l.warn(model.ConfigWarningInvalid, p+".reviewStyle", "unknown reviewStyle %q; the default style is used", profile.ReviewStyle)
+		}
+		if profile.MinSeverity != "" && !ValidSeverity(profile.MinSeverity) {
+			l.warn(model.ConfigWarningInvalid, p+".minSeverity", "unknown severity %q is ignored", profile.MinSeverity)
+		}
+		for i, n := range profile.Notifiers {
+			l.lintNotifier(n, fmt.Sprintf("%s.notifiers[%d]", p, i))
+		}
+	}
+}
+
+// lintModelRoutes checks that each model route picks a model or skips AI review, that its
+// conditions can match, and that no earlier route matches every file.
+func (l *configLinter) lintModelRoutes(auto model.AutoReviewPR, path string) {
+	catchAll := ""
+	for i, route := range auto.ModelRoutes {
+		p := fmt.Sprintf("%s.modelRoutes[%d]", path, i)
+		if catchAll != "" {
+			l.warn(model.ConfigWarningConflict, p, "route is never used; %s before it matches every file", catchAll)
+			continue
+		}
+		aiModel := strings.TrimSpace(route.AIModel)
+		switch {
+		case aiModel == "" && !route.SkipAI:
+			l.warn(model.ConfigWarningInvalid, p, "route sets neither aiModel nor skipAI; the files it matches use the entry's model")
+		case aiModel != "" && route.SkipAI:
+			l.warn(model.ConfigWarningConflict, p+".aiModel", "aiModel has no effect when skipAI is true")
+		}
+		paths := 0
+		for j, pattern := range route.Paths {
+			if strings.TrimSpace(pattern) == "" {
+				l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.paths[%d]", p, j), "blank path pattern is ignored")
+				continue
+			}
+			paths++
+		}
+		if route.MinLines < 0 || route.MaxLines < 0 {
+			l.warn(model.ConfigWarningInvalid, p, "minLines and maxLines cannot be negative")
+		}
+		if route.MinLines > 0 && route.MaxLines > 0 && route.MinLines > route.MaxLines {
+			l.warn(model.ConfigWarningInvalid, p, "minLines %d is above maxLines %d; the route matches no file", route.MinLines, route.MaxLines)
+		}
+		if route.Critical && len(auto.CriticalPaths) == 0 {
+			l.warn(model.ConfigWarningConflict, p+".critical", "critical matches no file unless criticalPaths is set")
+		}
+		if len(route.Paths) == 0 && route.MinLines <= 0 && route.MaxLines <= 0 && !route.Critical {
+			catchAll = p
+		} else if len(route.Paths) > 0 && paths == 0 {
+			l.warn(model.ConfigWarningInvalid, p+".paths", "every path pattern is blank; the route matches no file")
+		}
+	}
+}
+
+// lintNotifier checks the type, target and event filters of a notifier.
+func (l *configLinter) lintNotifier(n model.NotifierSettings, path string) {
+	switch strings.ToLower(strings.TrimSpace(n.Type)) {
+	case "slack", "teams", "webhook":
+		if strings.TrimSpace(n.URL) == "" {
+			l.warn(model.ConfigWarningInvalid, path+".url", "%s notifier needs url; it sends nothing", n.Type)
+		}
+	case "email":
+		if n.SMTP.Host == "" || n.SMTP.From == "" || len(n.SMTP.To) == 0 {
+			l.warn(model.ConfigWarningInvalid, path+".smtp", "email notifier needs smtp.host, smtp.from and smtp.to; it sends nothing")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".type", "unknown notifier type %q; use slack, teams, email or webhook", n.Type)
+	}
+	if len(n.Headers) > 0 && !strings.EqualFold(strings.TrimSpace(n.Type), "webhook") {
+		l.warn(model.ConfigWarningConflict, path+".headers", "headers have no effect unless type is webhook")
+	}
+	for i, e := range n.Events {
+		switch e {
+		case model.NotifyReviewCompleted, model.NotifyCriticalFinding, model.NotifyAIFailure, model.NotifyCircuitOpen, model.NotifyCriticalPath:
+		default:
+			l.warn(model.ConfigWarningInvalid, fmt.Sprintf("%s.events[%d]", path, i), "unknown event %q; use %s, %s, %s, %s or %s", e, model.NotifyReviewCompleted, model.NotifyCriticalFinding, model.NotifyAIFailure, model.NotifyCircuitOpen, model.NotifyCriticalPath)
+		}
+	}
+	if n.MinSeverity != "" {
+		if !ValidSeverity(n.MinSeverity) || strings.EqualFold(strings.TrimSpace(n.MinSeverity), SeverityNone) {
+			l.warn(model.ConfigWarningInvalid, path+".minSeverity", "unknown severity %q; Critical is used", n.MinSeverity)
+		}
+		if !n.Wants(model.NotifyCriticalFinding) {
+			l.warn(model.ConfigWarningConflict, path+".minSeverity", "minSeverity has no effect unless events include %s", model.NotifyCriticalFinding)
+		}
+	}
+}
+
+// lintGitProvider checks the git provider of an entry and flags Bitbucket-only features on
+// gerrit and github entries.
+func (l *configLinter) lintGitProvider(auto model.AutoReviewPR, path string) {
+	provider := strings.ToLower(strings.TrimSpace(auto.GitProvider))
+	switch provider {
+	case "", model.GitProviderBitbucket:
+		return
+	case model.GitProviderGerrit:
+		if strings.TrimSpace(auto.Gerrit.URL) == "" {
+			l.warn(model.ConfigWarningInvalid, path+".gerrit.url", "gerrit.url is required when gitProvider is gerrit")
+		}
+		if auto.Gerrit.FailSeverity != "" && (!ValidSeverity(auto.Gerrit.FailSeverity) || strings.EqualFold(strings.TrimSpace(auto.Gerrit.FailSeverity), SeverityNone)) {
+			l.warn(model.ConfigWarningInvalid, path+".gerrit.failSeverity", "unknown severity %q; Major is used", auto.Gerrit.FailSeverity)
+		}
+	case model.GitProviderGitHub:
+		if strings.TrimSpace(auto.Workspace) == "" {
+			l.warn(model.ConfigWarningInvalid, path+".workspace", "workspace must name the repository owner when gitProvider is github")
+		}
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".gitProvider", "unknown gitProvider %q; use %q, %q or %q", auto.GitProvider, model.GitProviderBitbucket, model.GitProviderGerrit, model.GitProviderGitHub)
+		return
+	}
+	bitbucketOnly := []struct {
+		field  string
+		set    bool
+		github bool // also applies to github entries
+	}{
+		{"codeInsights.enabled", auto.CodeInsights.Enabled, false},
+		{"buildStatus.enabled", auto.BuildStatus.Enabled, false},
+		{"describePR.enabled", auto.DescribePR.Enabled, false},
+		{"autoApprove", auto.AutoApprove, false},
+		{"skipInlineWhenApproved", auto.SkipInlineWhenApproved, false},
+		{"staleReminders.cron", auto.StaleReminders.Enabled(), false},
+		{"reviewerSuggestions.enabled", auto.ReviewerSuggestions.Enabled, false},
+		{"feedback.cron", auto.Feedback.Enabled(), false},
+		{"autoFix.enabled", auto.AutoFix.Enabled, false},
+		{"statusReport.enabled", auto.StatusReport.Enabled, false},
+		{"riskScore.enabled", auto.RiskScore.Enabled, false},
+		{"notifiers", len(auto.Notifiers) > 0, false},
+		{"ignorePullRequests", len(auto.IgnorePullRequests) > 0, true},
+		{"activeHours", len(auto.ActiveHours) > 0, true},
+		{"quietHours", len(auto.QuietHours) > 0, true},
+	}
+	for _, f := range bitbucketOnly {
+		if f.set && !(f.github && provider == model.GitProviderGitHub) {
+			l.warn(model.ConfigWarningConflict, path+"."+f.field, "%s is Bitbucket-only and has no effect on a %s entry", f.field, provider)
+		}
+	}
+}
+
+// lintAIProvider checks the AI provider of an entry or benchmark candidate and its settings.
+func (l *configLinter) lintAIProvider(auto model.AutoReviewPR, path string) {
+	provider := strings.ToLower(strings.TrimSpace(auto.AIProvider))
+	switch provider {
+	case "", "gemini", "gemini-vertex", "azure-openai", "self":
+	default:
+		l.warn(model.ConfigWarningInvalid, path+".aiProvider", "unknown aiProvider %q", auto.AIProvider)
+	}
+	if provider == "self" && auto.SelfAPIBaseURL == "" {
+		l.warn(model.ConfigWarningInvalid, path+".selfApiBaseUrl", "selfApiBaseUrl is required when aiProvider is self")
+	}
+	if provider == "azure-openai" && auto.AzureEndpoint == "" {
+		l.warn(model.ConfigWarningInvalid, path+".azureEndpoint", "azureEndpoint is required when aiProvider is azure-openai")
+	}
+	if provider == "gemini-vertex" && auto.VertexProject == "" {
+		l.warn(model.ConfigWarningInvalid, path+".vertexProject", "vertexProject is required when aiProvider is gemini-vertex")
+	}
+	unused := func(set bool, key, wants string) {
+		if set && provider != wants {
+			l.warn(model.ConfigWarningConflict, path+"."+key, "%s has no effect unless aiProvider is %s", key, wants)
+		}
+	}
+	unused(auto.SelfAPIBaseURL != "", "selfApiBaseUrl", "self")
+	unused(auto.AzureEndpoint != "", "azureEndpoint", "azure-openai")
+	unused(auto.AzureDeployment != "", "azureDeployment", "azure-openai")
+	unused(auto.AzureAPIVersion != "", "azureApiVersion", "azure-openai")
+	unused(auto.VertexProject != "", "vertexProject", "gemini-vertex")
+	unused(auto.VertexRegion != "", "vertexRegion", "gemini-vertex")
+	unused(auto.VertexCredentialsFile != "", "vertexCredentialsFile", "gemini-vertex")
+	if auto.ContextCache && provider != "" && provider != "gemini" {
+		l.warn(model.ConfigWarningConflict, path+".contextCache", "contextCache has no effect unless aiProvider is gemini; %s caches prompt prefixes on its own or not at all", provider)
+	}
+	if auto.AIStreaming && provider != "" && provider != "gemini" && provider != "gemini-vertex" {
+		l.warn(model.ConfigWarningConflict, path+".aiStreaming", "aiStreaming has no effect unless aiProvider is gemini or gemini-vertex; %s replies are read in one piece", provider)
+	}
+	if _, ok := ContextCacheTTL(&auto); !ok {
+		l.warn(model.ConfigWarningInvalid, path+".contextCacheTtl", "contextCacheTtl %q is not a duration of at least 1m; using %v", auto.ContextCacheTTL, DefaultContextCacheTTL)
+	}
+}
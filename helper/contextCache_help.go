@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultContextCacheTTL is how long a Gemini context cache lives when contextCacheTtl is unset.
+const DefaultContextCacheTTL = time.Hour
+
+// geminiAPIBase is the Generative Language API endpoint; a variable so it can point at a stub.
+var geminiAPIBase = "https://generativelanguage.googleapis.com"
+
+// contextCacheRefresh is how long before its expiry a context cache is replaced, so no request
+// refers to a cache that expires in flight.
+const contextCacheRefresh = 5 * time.Minute
+
+type contextCacheEntry struct {
+	name    string    // cachedContents/<id>; empty after a failed creation
+	expires time.Time // when name stops being used, or when creation is tried again
+}
+
+var (
+	contextCacheMutex sync.Mutex
+	contextCaches     = map[string]contextCacheEntry{}
+)
+
+// ContextCacheTTL parses cfg.ContextCacheTTL, defaulting to DefaultContextCacheTTL. ok is false
+// when the value is set but not a duration of at least a minute.
+func ContextCacheTTL(cfg *model.AutoReviewPR) (time.Duration, bool) {
+	if strings.TrimSpace(cfg.ContextCacheTTL) == "" {
+		return DefaultContextCacheTTL, true
+	}
+	d, err := time.ParseDuration(cfg.ContextCacheTTL)
+	if err != nil || d < time.Minute {
+		return DefaultContextCacheTTL, false
+	}
+	return d, true
+}
+
+func contextCacheKey(apiKey, modelName, prefix string) string {
+	sum := sha256.Sum256([]byte(apiKey + "\x00" + modelName + "\x00" + prefix))
+	return hex.EncodeToString(sum[:])
+}
+
+// geminiContextCache returns the cachedContents resource holding the instructions of prompt
+// and the rest of the prompt to send with it. The cache is created on first use and renewed
+// before it expires; when contextCache is off, the prompt has no shared instructions or the
+// cache cannot be created, name is empty and rest is the whole prompt.
+func geminiContextCache(cfg *model.AutoReviewPR, apiKey, modelName, prompt string) (name, rest string) {
+	if !cfg.ContextCache {
+		return "", prompt
+	}
+	prefix, rest, ok := splitPromptPrefix(prompt)
+	if !ok {
+		return "", prompt
+	}
+	key := contextCacheKey(apiKey, modelName, prefix)
+	contextCacheMutex.Lock()
+	defer contextCacheMutex.Unlock()
+	if e, ok := contextCaches[key]; ok && time.Now().Before(e.expires) {
+		if e.name == "" {
+			return "", prompt
+		}
+		return e.name, rest
+	}
+	ttl, _ := ContextCacheTTL(cfg)
+	name, err := createGeminiContextCache(apiKey, modelName, prefix, ttl)
+	if err != nil {
+		// Typically the model does not support caching or the instructions are below its
+		// minimum cache size; send whole prompts until the TTL passes.
+		log.Warnf("Gemini context cache for %s unavailable, sending full prompts: %v", modelName, err)
+		contextCaches[key] = contextCacheEntry{expires: time.Now().Add(ttl)}
+		return "", prompt
+	}
+	log.Infof("Created Gemini context cache %s for %s (ttl %s)", name, modelName, ttl)
+	contextCaches[key] = contextCacheEntry{name: name, expires: time.Now().Add(ttl - contextCacheRefresh)}
+	return name, rest
+}
+
+// forgetContextCache drops a context cache that Gemini no longer accepts, so the next call
+// creates a new one.
+func forgetContextCache(name string) {
+	contextCacheMutex.Lock()
+	defer contextCacheMutex.Unlock()
+	for key, e := range contextCaches {
+		if e.name == name {
+			delete(contextCaches, key)
+		}
+	}
+}
+
+// createGeminiContextCache stores prefix as a cachedContents resource of modelName.
+func createGeminiContextCache(apiKey, modelName, prefix string, ttl time.Duration) (string, error) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"model":    "models/" + modelName,
+		"contents": []map[string]interface{}{{"role": "user", "parts": []map[string]string{{"text": prefix}}}},
+		"ttl":      fmt.Sprintf("%ds", int(ttl.Seconds())),
+	})
+	resp, err := postJSON(geminiAPIBase+"/v1beta/cachedContents?key="+apiKey, nil, b)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		var errorResult model.GeminiErrorResponse
+		_ = json.Unmarshal(raw, &errorResult)
+		return "", aiStatusError("gemini context cache", resp.StatusCode, errorResult.Error.Message)
+	}
+	var created struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &created); err != nil || created.Name == "" {
+		return "", fmt.Errorf("gemini context cache: unexpected reply %s", string(raw)[:min(200, len(raw))])
+	}
+	return created.Name, nil
+}
+
+// withContextCache runs call with the context cache of prompt, if any. A failure other than a
+// rate limit, an auth error or an invalid or truncated reply may come from a cache Gemini
+// dropped early, so the cache is forgotten and the whole prompt sent once more.
+func withContextCache(cfg *model.AutoReviewPR, apiKey, modelName, prompt string, call func(prompt, cachedContent string) error) error {
+	name, rest := geminiContextCache(cfg, apiKey, modelName, prompt)
+	if name == "" {
+		return call(prompt, "")
+	}
+	err := call(rest, name)
+	if err == nil || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrAuth) || errors.Is(err, ErrAIInvalidResponse) || errors.Is(err, ErrAITruncated) {
+		return err
+	}
+	log.Warnf("Gemini call with context cache %s failed (%v); retrying without it", name, err)
+	forgetContextCache(name)
+	return call(prompt, "")
+}
@@ -0,0 +1,425 @@
+package helper
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// API contract formats whose changes are diffed structurally.
+const (
+	ContractOpenAPI = "openapi"
+	ContractProto   = "proto"
+)
+
+// Kinds and changes of a ContractChange.
+const (
+	ContractEndpoint = "endpoint"
+	ContractField    = "field"
+
+	ContractAdded   = "added"
+	ContractRemoved = "removed"
+	ContractChanged = "changed"
+)
+
+// ContractFormat returns the contract format of a file path: ContractOpenAPI for
+// openapi*/swagger* YAML and JSON files, ContractProto for *.proto files, and "" otherwise.
+func ContractFormat(filePath string) string {
+	base := strings.ToLower(path.Base(filePath))
+	ext := path.Ext(base)
+	switch {
+	case ext == ".proto":
+		return ContractProto
+	case (strings.HasPrefix(base, "openapi") || strings.HasPrefix(base, "swagger")) &&
+		(ext == ".yaml" || ext == ".yml" || ext == ".json"):
+		return ContractOpenAPI
+	}
+	return ""
+}
+
+// ContractChange is an endpoint or field added, removed or changed by a diff of an API contract.
+type ContractChange struct {
+	Kind     string // ContractEndpoint or ContractField
+	Change   string // ContractAdded, ContractRemoved or ContractChanged
+	Name     string // "GET /pets/{id}", "Pet.name", "PetService.GetPet", "GetPetRequest.id (field 1)"
+	Detail   string // what changed, for ContractChanged: "type string -> integer"
+	Index    int    // 1-based index of the line in the diff snippet
+	LineText string // the line, including its "+" or "-" prefix
+}
+
+// Breaking reports whether existing clients can break on the change: anything but an addition.
+func (c ContractChange) Breaking() bool {
+	return c.Change != ContractAdded
+}
+
+// contractEntry is one endpoint or field declaration seen on a removed or added line.
+type contractEntry struct {
+	kind  string
+	name  string
+	value string // field type or rpc signature, compared to detect changes
+	label string // what value is, for ContractChange.Detail: "type", "signature", ...
+	// changeOnly marks the type line of a property, reported only when its value changes:
+	// the property line itself reports additions and removals.
+	changeOnly bool
+	index      int
+	line       string
+}
+
+// ParseContractChanges returns the endpoints and fields added, removed or changed in the diff
+// snippet of an API contract file (lines prefixed with "+", "-" or " " as built by
+// BuildDiffSnippetAndLineMap). Only the structure visible in the snippet is compared, so a
+// declaration whose enclosing path or message is outside the hunk context is named without it.
+func ParseContractChanges(filePath string, snippet []string) []ContractChange {
+	var removed, added []contractEntry
+	switch ContractFormat(filePath) {
+	case ContractOpenAPI:
+		removed, added = openAPIEntries(snippet)
+	case ContractProto:
+		removed, added = protoEntries(snippet)
+	default:
+		return nil
+	}
+	type entryKey struct {
+		kind, name string
+		changeOnly bool
+	}
+	before := map[entryKey]contractEntry{}
+	for _, e := range removed {
+		before[entryKey{e.kind, e.name, e.changeOnly}] = e
+	}
+	after := map[entryKey]bool{}
+	var changes []ContractChange
+	for _, e := range added {
+		k := entryKey{e.kind, e.name, e.changeOnly}
+		after[k] = true
+		old, ok := before[k]
+		switch {
+		case !ok && e.changeOnly:
+		case !ok:
+			changes = append(changes, ContractChange{Kind: e.kind, Change: ContractAdded, Name: e.name, Index: e.index, LineText: e.line})
+		case old.value != e.value:
+			changes = append(changes, ContractChange{Kind: e.kind, Change: ContractChanged, Name: e.name,
+				Detail: e.label + " " + old.value + " -> " + e.value, Index: e.index, LineText: e.line})
+		}
+	}
+	for _, e := range removed {
+		if !e.changeOnly && !after[entryKey{e.kind, e.name, e.changeOnly}] {
+			changes = append(changes, ContractChange{Kind: e.kind, Change: ContractRemoved, Name: e.name, Index: e.index, LineText: e.line})
+		}
+	}
+	return changes
+}
+
+var (
+	yamlKeyLine = regexp.MustCompile(`^(\s*)["']?([^"'#\s-][^"'#]*?)["']?:(?:\s+(.*)|$)`)
+	jsonKeyLine = regexp.MustCompile(`^(\s*)"([^"]+)"\s*:\s*(.*?),?$`)
+)
+
+// openAPIMethods are the operation keys of an OpenAPI path item.
+var openAPIMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+
+// openAPIKey is a key of a YAML or JSON contract with its indentation.
+type openAPIKey struct {
+	indent int
+	name   string
+}
+
+// openAPIEntries returns the endpoints and fields declared on the removed and added lines of an
+// OpenAPI snippet. The key nesting is followed by indentation, separately for the old
+// (context and removed lines) and new (context and added lines) side of the diff.
+func openAPIEntries(snippet []string) (removed, added []contractEntry) {
+	var oldStack, newStack []openAPIKey
+	for i, ln := range snippet {
+		if ln == "" {
+			continue
+		}
+		prefix, text := ln[0], ln[1:]
+		m := jsonKeyLine.FindStringSubmatch(text)
+		if m == nil {
+			m = yamlKeyLine.FindStringSubmatch(text)
+		}
+		if m == nil {
+			continue
+		}
+		key := openAPIKey{indent: len(m[1]), name: m[2]}
+		value := strings.Trim(strings.TrimSpace(m[3]), `"'`)
+		if prefix != '+' {
+			oldStack = pushOpenAPIKey(oldStack, key)
+			if prefix == '-' {
+				if e, ok := openAPIEntry(oldStack, value); ok {
+					e.index, e.line = i+1, ln
+					removed = append(removed, e)
+				}
+			}
+		}
+		if prefix != '-' {
+			newStack = pushOpenAPIKey(newStack, key)
+			if prefix == '+' {
+				if e, ok := openAPIEntry(newStack, value); ok {
+					e.index, e.line = i+1, ln
+					added = append(added, e)
+				}
+			}
+		}
+	}
+	return removed, added
+}
+
+// pushOpenAPIKey drops the keys at the same or a deeper indentation than key and appends key.
+func pushOpenAPIKey(stack []openAPIKey, key openAPIKey) []openAPIKey {
+	for len(stack) > 0 && stack[len(stack)-1].indent >= key.indent {
+		stack = stack[:len(stack)-1]
+	}
+	return append(stack, key)
+}
+
+// openAPIEntry classifies the last key of stack: a path, an operation of a path, a schema
+// property, or the type of a schema property.
+func openAPIEntry(stack []openAPIKey, value string) (contractEntry, bool) {
+	n := len(stack)
+	key := stack[n-1].name
+	parent := ""
+	if n > 1 {
+		parent = stack[n-2].name
+	}
+	switch {
+	case strings.HasPrefix(key, "/"):
+		return contractEntry{kind: ContractEndpoint, name: key}, true
+	case openAPIMethods[strings.ToLower(key)] && strings.HasPrefix(parent, "/"):
+		return contractEntry{kind: ContractEndpoint, name: strings.ToUpper(key) + " " + parent}, true
+	case parent == "properties":
+		return contractEntry{kind: ContractField, name: openAPIFieldName(stack[:n-1], key)}, true
+	case key == "type" && n > 2 && stack[n-3].name == "properties":
+		return contractEntry{kind: ContractField, name: openAPIFieldName(stack[:n-2], parent), value: value, label: "type", changeOnly: true}, true
+	}
+	return contractEntry{}, false
+}
+
+// openAPIFieldName qualifies a property with its schema name, or its operation when the schema
+// is declared inline.
+func openAPIFieldName(ancestors []openAPIKey, field string) string {
+	for i := len(ancestors) - 1; i > 0; i-- {
+		switch name := ancestors[i-1].name; {
+		case name == "schemas" || name == "definitions":
+			return ancestors[i].name + "." + field
+		case strings.HasPrefix(name, "/") && openAPIMethods[strings.ToLower(ancestors[i].name)]:
+			return strings.ToUpper(ancestors[i].name) + " " + name + " " + field
+		}
+	}
+	return field
+}
+
+var (
+	protoBlockLine = regexp.MustCompile(`^\s*(message|service|enum)\s+(\w+)\s*\{`)
+	protoRPCLine   = regexp.MustCompile(`^\s*rpc\s+(\w+)\s*\(\s*(stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(stream\s+)?([\w.]+)\s*\)`)
+	protoFieldLine = regexp.MustCompile(`^\s*(?:(optional|repeated|required)\s+)?(map\s*<[^>]+>|[\w.]+)\s+(\w+)\s*=\s*(\d+)`)
+	protoEnumValue = regexp.MustCompile(`^\s*(\w+)\s*=\s*(-?\d+)`)
+)
+
+// protoBlock is an enclosing message, service or enum declaration of a proto file.
+type protoBlock struct {
+	kind, name string
+}
+
+// protoEntries returns the rpcs, fields and enum values declared on the removed and added lines
+// of a proto snippet. Fields and enum values are matched by number, so a rename is a change.
+func protoEntries(snippet []string) (removed, added []contractEntry) {
+	var oldStack, newStack []protoBlock
+	for i, ln := range snippet {
+		if ln == "" {
+			continue
+		}
+		prefix, text := ln[0], ln[1:]
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		if prefix != '+' {
+			var e contractEntry
+			var ok bool
+			oldStack, e, ok = protoLine(oldStack, text)
+			if ok && prefix == '-' {
+				e.index, e.line = i+1, ln
+				removed = append(removed, e)
+			}
+		}
+		if prefix != '-' {
+			var e contractEntry
+			var ok bool
+			newStack, e, ok = protoLine(newStack, text)
+			if ok && prefix == '+' {
+				e.index, e.line = i+1, ln
+				added = append(added, e)
+			}
+		}
+	}
+	return removed, added
+}
+
+// protoLine updates the block stack with one proto line and returns the declaration on it.
+func protoLine(stack []protoBlock, text string) ([]protoBlock, contractEntry, bool) {
+	if m := protoBlockLine.FindStringSubmatch(text); m != nil {
+		stack = append(stack, protoBlock{kind: m[1], name: m[2]})
+		return stack, contractEntry{}, false
+	}
+	if strings.TrimSpace(text) == "}" && len(stack) > 0 {
+		return stack[:len(stack)-1], contractEntry{}, false
+	}
+	owner := protoBlock{}
+	if len(stack) > 0 {
+		owner = stack[len(stack)-1]
+	}
+	qualify := func(name string) string {
+		if owner.name == "" {
+			return name
+		}
+		return owner.name + "." + name
+	}
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "reserved ") || strings.HasPrefix(trimmed, "option "):
+		return stack, contractEntry{}, false
+	case owner.kind == "service" || owner.kind == "":
+		if m := protoRPCLine.FindStringSubmatch(text); m != nil {
+			sig := "(" + m[2] + m[3] + ") returns (" + m[4] + m[5] + ")"
+			return stack, contractEntry{kind: ContractEndpoint, name: qualify(m[1]), value: sig, label: "signature"}, true
+		}
+	case owner.kind == "enum":
+		if m := protoEnumValue.FindStringSubmatch(text); m != nil {
+			return stack, contractEntry{kind: ContractField, name: qualify("#" + m[2]), value: m[1], label: "name"}, true
+		}
+	case owner.kind == "message":
+		if m := protoFieldLine.FindStringSubmatch(text); m != nil {
+			typ := strings.TrimSpace(m[1] + " " + m[2])
+			return stack, contractEntry{kind: ContractField, name: qualify("#" + m[4]), value: typ + " " + m[3], label: "declaration"}, true
+		}
+	}
+	return stack, contractEntry{}, false
+}
+
+// ContractFile is a contract file of a diff and its structural changes.
+type ContractFile struct {
+	Path    string
+	Changes []ContractChange
+}
+
+// ContractChangesInDiff returns the structural changes of every API contract file in diff.
+func ContractChangesInDiff(diff string) []ContractFile {
+	var files []ContractFile
+	for _, file := range ParseDiff(diff) {
+		p, _ := file["path"].(string)
+		if ContractFormat(p) == "" {
+			continue
+		}
+		hunks, _ := file["hunks"].([]map[string]interface{})
+		snippet, _ := BuildDiffSnippetAndLineMap(hunks)
+		if changes := ParseContractChanges(p, snippet); len(changes) > 0 {
+			files = append(files, ContractFile{Path: p, Changes: changes})
+		}
+	}
+	return files
+}
+
+// contractChangeText renders a change for the summary and prompts. Proto fields keyed by
+// number are shown with their name, from the line that declares them.
+func contractChangeText(c ContractChange) string {
+	name := c.Name
+	if i := strings.LastIndex(name, ".#"); i >= 0 || strings.HasPrefix(name, "#") {
+		number := name[strings.LastIndex(name, "#")+1:]
+		owner := ""
+		if i >= 0 {
+			owner = name[:i] + "."
+		}
+		decl := strings.TrimSpace(strings.TrimLeft(c.LineText, "+- "))
+		if m := protoFieldLine.FindStringSubmatch(decl); m != nil {
+			name = owner + m[3]
+		} else if m := protoEnumValue.FindStringSubmatch(decl); m != nil {
+			name = owner + m[1]
+		}
+		name += " (field " + number + ")"
+	}
+	s := c.Change + " " + c.Kind + " `" + name + "`"
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+// FormatContractChanges renders the "API Contract Changes" summary section, breaking changes
+// first. It is empty when no contract file changed structurally.
+func FormatContractChanges(files []ContractFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var breaking, additive []string
+	for _, f := range files {
+		for _, c := range f.Changes {
+			text := contractChangeText(c)
+			line := "- " + strings.ToUpper(text[:1]) + text[1:] + " (`" + f.Path + "`)"
+			if c.Breaking() {
+				breaking = append(breaking, line+" ⚠️ breaking.")
+			} else {
+				additive = append(additive, line+".")
+			}
+		}
+	}
+	var b strings.Builder
+	b.WriteString("## API Contract Changes\n\n")
+	for _, l := range append(breaking, additive...) {
+		b.WriteString(l + "\n")
+	}
+	return b.String() + "\n"
+}
+
+// ContractPromptContext renders the contract changes of one file for the review prompt, so the
+// AI can comment on compatibility without repeating the breaking changes reported separately.
+func ContractPromptContext(changes []ContractChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nStructural API contract changes in this file. Breaking ones are already reported as separate comments; do not repeat them, but do flag related compatibility problems such as new required fields or changed defaults:\n")
+	for _, c := range changes {
+		fmt.Fprintf(&b, "- %s (diff line %d)\n", contractChangeText(c), c.Index)
+	}
+	return b.String()
+}
+
+// contractSummaryContext lists the contract changes of diff for the summary prompt.
+func contractSummaryContext(diff string) string {
+	files := ContractChangesInDiff(diff)
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nAPI Contract Changes (computed from the diff; list every breaking one under \"## Breaking Changes\"):\n")
+	for _, f := range files {
+		for _, c := range f.Changes {
+			fmt.Fprintf(&b, "- %s: %s\n", f.Path, contractChangeText(c))
+		}
+	}
+	return b.String()
+}
+
+// ContractFinding renders the review comment posted on the line of a breaking contract change.
+// Position is the 1-based diff snippet index, like an AI finding before anchoring.
+func ContractFinding(c ContractChange) (body string, position int, anchor string) {
+	text := contractChangeText(c)
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Potential issue] [Major] Breaking change: %s\n", text)
+	b.WriteString("Why:\n")
+	switch {
+	case c.Kind == ContractEndpoint && c.Change == ContractRemoved:
+		b.WriteString("  - Clients that still call this endpoint fail once the change is deployed.\n")
+	case c.Kind == ContractEndpoint:
+		b.WriteString("  - Clients built against the old request or response type can no longer call this endpoint.\n")
+	case c.Change == ContractRemoved:
+		b.WriteString("  - Clients that send or read this field lose it silently or are rejected by strict validation.\n")
+	default:
+		b.WriteString("  - Clients built against the old definition decode or send this field differently.\n")
+	}
+	b.WriteString("How (step-by-step):\n")
+	b.WriteString("  - Keep the old definition working for a deprecation period, or version the API (new path, field or message) instead of changing it in place.\n")
+	b.WriteString("  - Regenerate the clients built from this contract and update their callers before the old definition goes away.\n")
+	return b.String(), c.Index, c.LineText[1:]
+}
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// ApplyCredentialProfiles fills the credentials of every entry from the profiles it names.
+// Profiles are applied in order and only fill what is still empty, so a value set on the entry
+// or an earlier profile wins. AI keys come from a profile only when the entry sets none of
+// aiKey, aiKeys and geminiKey. Unknown profile names are returned as errors.
+func ApplyCredentialProfiles(cfg *model.Task) []error {
+	var errs []error
+	for i := range cfg.AutoReviewPRs {
+		auto := &cfg.AutoReviewPRs[i]
+		ownAIKeys := auto.AIKey != "" || len(auto.AIKeys) > 0 || auto.GeminiKey != ""
+		for _, name := range auto.Credentials {
+			profile, ok := cfg.Credentials[strings.TrimSpace(name)]
+			if !ok {
+				errs = append(errs, fmt.Errorf("autoReviewPR[%d]: unknown credentials profile %q", i, name))
+				continue
+			}
+			fill(&auto.Username, profile.Username)
+			fill(&auto.AppPassword, profile.AppPassword)
+			fill(&auto.VertexCredentialsFile, profile.VertexCredentialsFile)
+			if !ownAIKeys {
+				fill(&auto.AIKey, profile.AIKey)
+				if len(auto.AIKeys) == 0 {
+					auto.AIKeys = append([]string(nil), profile.AIKeys...)
+				}
+			}
+		}
+		if len(auto.Credentials) > 0 {
+			log.Debugf("Entry %s uses credentials profiles %s", auto.ProcessName, strings.Join(auto.Credentials, ", "))
+		}
+	}
+	return errs
+}
+
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"code_nim/model"
+	"regexp"
+	"strings"
+)
+
+// DefaultCriticalPathInstructions are the stricter rules added to the inline review prompt of
+// files under an entry's criticalPaths.
+const DefaultCriticalPathInstructions = `- CRITICAL PATH: this file is on a path the team marked as critical (authentication, payments, data integrity or similar). Review it with extra scrutiny:
+  - Check authorization and authentication on every changed entry point, input validation, error handling that could leak data or leave partial state, concurrency and idempotency, and money or precision handling.
+  - Report Potential issue findings of every severity, including Minor ones, even where the tone above says to skip them; a missed defect here costs more than a false alarm.
+  - Flag changes whose safety cannot be judged from the diff alone and name what a reviewer must verify.
+`
+
+// criticalPathInstructions returns the critical-path rules of the inline prompt of filePath, or
+// "" when the file is not under the entry's criticalPaths.
+func criticalPathInstructions(cfg *model.AutoReviewPR, filePath string) string {
+	if !IsCriticalPath(cfg.CriticalPaths, filePath) {
+		return ""
+	}
+	if text := strings.TrimSpace(cfg.CriticalPathReview.Instructions); text != "" {
+		return "- CRITICAL PATH: this file is on a path the team marked as critical. Review it with these extra rules:\n" + text + "\n"
+	}
+	return DefaultCriticalPathInstructions
+}
+
+// criticalPathPatterns compiles the gitignore-style criticalPaths patterns, leaving out blank ones.
+func criticalPathPatterns(patterns []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			res = append(res, regexp.MustCompile(codeownersRegexp(p)))
+		}
+	}
+	return res
+}
+
+// matchesAny reports whether filePath matches one of res.
+func matchesAny(res []*regexp.Regexp, filePath string) bool {
+	for _, re := range res {
+		if re.MatchString(strings.TrimPrefix(filePath, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCriticalPath reports whether filePath matches one of the gitignore-style patterns.
+func IsCriticalPath(patterns []string, filePath string) bool {
+	return matchesAny(criticalPathPatterns(patterns), filePath)
+}
+
+// CriticalFiles returns the files changed in diff that match one of the gitignore-style
+// patterns, in diff order.
+func CriticalFiles(patterns []string, diff string) []string {
+	res := criticalPathPatterns(patterns)
+	if len(res) == 0 {
+		return nil
+	}
+	var files []string
+	for _, file := range ParseDiff(diff) {
+		if filePath, _ := file["path"].(string); matchesAny(res, filePath) {
+			files = append(files, filePath)
+		}
+	}
+	return files
+}
@@ -0,0 +1,124 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// normalizeForFingerprint drops hidden markers, case, punctuation and whitespace so that
+// re-formatted or slightly re-worded copies of the same text compare equal.
+func normalizeForFingerprint(s string) string {
+	s = htmlCommentPattern.ReplaceAllString(s, "")
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:12])
+}
+
+// findingHeading returns the first two non-empty lines of a finding body: its type, severity
+// and title.
+func findingHeading(body string) string {
+	var heading []string
+	for _, ln := range strings.Split(body, "\n") {
+		if strings.TrimSpace(ln) == "" {
+			continue
+		}
+		heading = append(heading, ln)
+		if len(heading) == 2 {
+			break
+		}
+	}
+	return strings.Join(heading, "\n")
+}
+
+// headingWindow is the size of the line buckets of heading keys. A heading matches the same
+// heading in its own or a neighbouring bucket, so less than 2*headingWindow lines away.
+const headingWindow = 5
+
+// FindingFingerprints returns content-based keys for an inline finding on line of path, to be
+// recorded as posted. The first key covers the whole body; the second the heading (type,
+// severity and title) within the line bucket of line, which stays stable when the AI rewords
+// the explanation or the line shifts a little between runs. Findings without a line use 0.
+func FindingFingerprints(path string, line int, body string) []string {
+	return findingKeys(path, line, body, 0)
+}
+
+// NearbyFingerprints returns the keys to look a new finding on line of path up with: its whole
+// body, and its heading in the line buckets around line. Findings with the same title further
+// apart in the file do not match.
+func NearbyFingerprints(path string, line int, body string) []string {
+	return findingKeys(path, line, body, 1)
+}
+
+func findingKeys(path string, line int, body string, spread int) []string {
+	body = htmlCommentPattern.ReplaceAllString(body, "")
+	keys := []string{}
+	if full := normalizeForFingerprint(body); full != "" {
+		keys = append(keys, fingerprint(path, "body", full))
+	}
+	if head := normalizeForFingerprint(findingHeading(body)); head != "" {
+		bucket := max(line, 0) / headingWindow
+		for b := bucket - spread; b <= bucket+spread; b++ {
+			keys = append(keys, fingerprint(path, "heading", strconv.Itoa(b), head))
+		}
+	}
+	return keys
+}
+
+// findingMarkerPattern matches the hidden marker of a posted finding and captures its hash.
+var findingMarkerPattern = regexp.MustCompile(`<!-- code-nim:inline:([0-9a-f]+) -->`)
+
+// FindingHash returns the stable hash of a finding on line of path: it covers the path, the
+// line and the heading (type, severity and title), so findings that share a title elsewhere in
+// the file get other hashes. It returns "" for a body without text.
+func FindingHash(path string, line int, body string) string {
+	head := normalizeForFingerprint(findingHeading(htmlCommentPattern.ReplaceAllString(body, "")))
+	if head == "" {
+		return ""
+	}
+	return fingerprint(path, "finding", strconv.Itoa(line), head)
+}
+
+// FindingMarker returns the hidden marker carrying hash that code-nim adds to each finding it
+// posts, so later runs find the finding whatever happened to the visible text.
+func FindingMarker(hash string) string {
+	return "<!-- code-nim:inline:" + hash + " -->"
+}
+
+// FindingMarkers returns the hashes of the finding markers in a comment, in order.
+func FindingMarkers(raw string) []string {
+	var hashes []string
+	for _, m := range findingMarkerPattern.FindAllStringSubmatch(raw, -1) {
+		hashes = append(hashes, m[1])
+	}
+	return hashes
+}
+
+// HasFingerprint reports whether any of keys is present in seen.
+func HasFingerprint(seen map[string]bool, keys []string) bool {
+	for _, k := range keys {
+		if seen[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutFindingMarkers returns body without its finding markers.
+func WithoutFindingMarkers(body string) string {
+	return findingMarkerPattern.ReplaceAllString(body, "")
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// OSV ecosystems of the dependency manifests that are checked for known vulnerabilities.
+const (
+	EcosystemGo    = "Go"
+	EcosystemNPM   = "npm"
+	EcosystemPyPI  = "PyPI"
+	maxManifestDep = 50 // dependencies looked up per manifest file
+)
+
+// manifestEcosystems maps the base name of a dependency manifest to its OSV ecosystem.
+var manifestEcosystems = map[string]string{
+	"go.mod":           EcosystemGo,
+	"package.json":     EcosystemNPM,
+	"requirements.txt": EcosystemPyPI,
+}
+
+// ManifestEcosystem returns the OSV ecosystem of a dependency manifest path, or "" when the
+// file is not a supported manifest.
+func ManifestEcosystem(filePath string) string {
+	return manifestEcosystems[path.Base(filePath)]
+}
+
+// DependencyChange is a dependency added or upgraded by a diff.
+type DependencyChange struct {
+	Ecosystem   string
+	Name        string
+	Version     string // exact version on the added line, without a leading "v" or range operator
+	FromVersion string // version on the matching removed line; empty for new dependencies
+	Index       int    // 1-based index of the added line in the diff snippet
+	LineText    string // the added line, including its "+" prefix
+}
+
+var (
+	goModRequireLine = regexp.MustCompile(`^(?:require\s+)?([A-Za-z0-9._~/-]+\.[A-Za-z0-9._~/-]+)\s+(v[0-9][^\s]*)`)
+	npmDependency    = regexp.MustCompile(`^"((?:@[^"/]+/)?[^"@/][^"]*)"\s*:\s*"[\^~=v>]*\s*([0-9][0-9A-Za-z.+-]*)"`)
+	pipRequirement   = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*==\s*([0-9][0-9A-Za-z.+!-]*)`)
+)
+
+// npmNonDependencyKeys are package.json fields that look like dependency entries but are not.
+var npmNonDependencyKeys = map[string]bool{"version": true, "node": true, "npm": true, "yarn": true, "pnpm": true}
+
+// parseDependencyLine returns the dependency declared on one manifest line (without its diff
+// prefix). inGoBlock is the go.mod block ("require", "replace", ...) the line is in.
+func parseDependencyLine(ecosystem, inGoBlock, line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	switch ecosystem {
+	case EcosystemGo:
+		if strings.Contains(line, "=>") || (inGoBlock != "" && inGoBlock != "require") {
+			return "", "", false
+		}
+		if inGoBlock == "" && !strings.HasPrefix(line, "require ") {
+			return "", "", false
+		}
+		m := goModRequireLine.FindStringSubmatch(line)
+		if m == nil {
+			return "", "", false
+		}
+		return m[1], strings.TrimSuffix(strings.TrimPrefix(m[2], "v"), "+incompatible"), true
+	case EcosystemNPM:
+		m := npmDependency.FindStringSubmatch(line)
+		if m == nil || npmNonDependencyKeys[m[1]] {
+			return "", "", false
+		}
+		return m[1], m[2], true
+	case EcosystemPyPI:
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		m := pipRequirement.FindStringSubmatch(line)
+		if m == nil {
+			return "", "", false
+		}
+		return strings.ToLower(m[1]), m[2], true
+	}
+	return "", "", false
+}
+
+// ParseDependencyChanges returns the dependencies added or upgraded in the diff snippet of a
+// manifest file (lines prefixed with "+", "-" or " " as built by BuildDiffSnippetAndLineMap).
+// Unchanged and removed dependencies are ignored.
+func ParseDependencyChanges(filePath string, snippet []string) []DependencyChange {
+	ecosystem := ManifestEcosystem(filePath)
+	if ecosystem == "" {
+		return nil
+	}
+	removed := map[string]string{}
+	var added []DependencyChange
+	block := ""
+	for i, ln := range snippet {
+		if ln == "" {
+			continue
+		}
+		prefix, text := ln[0], ln[1:]
+		if ecosystem == EcosystemGo && prefix != '-' {
+			trimmed := strings.TrimSpace(text)
+			if strings.HasSuffix(trimmed, "(") {
+				block = strings.TrimSpace(strings.TrimSuffix(trimmed, "("))
+				continue
+			}
+			if trimmed == ")" {
+				block = ""
+				continue
+			}
+		}
+		if prefix != '+' && prefix != '-' {
+			continue
+		}
+		name, version, ok := parseDependencyLine(ecosystem, block, text)
+		if !ok {
+			continue
+		}
+		if prefix == '-' {
+			removed[name] = version
+			continue
+		}
+		added = append(added, DependencyChange{Ecosystem: ecosystem, Name: name, Version: version, Index: i + 1, LineText: ln})
+	}
+	var changes []DependencyChange
+	for _, d := range added {
+		if from, ok := removed[d.Name]; ok {
+			if from == d.Version {
+				continue // reformatted or moved, not upgraded
+			}
+			d.FromVersion = from
+		}
+		changes = append(changes, d)
+		if len(changes) == maxManifestDep {
+			break
+		}
+	}
+	return changes
+}
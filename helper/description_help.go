@@ -0,0 +1,21 @@
+package helper
+
+import "strings"
+
+// NeedsDescription reports whether a pull request description is empty or a single line, the
+// cases in which the bot may write one.
+func NeedsDescription(description string) bool {
+	d := strings.TrimSpace(strings.ReplaceAll(description, "\r\n", "\n"))
+	return !strings.Contains(d, "\n")
+}
+
+// ComposeDescription returns the description to store: the author's one-line description, if
+// any, followed by the generated sections.
+func ComposeDescription(original, generated string) string {
+	original = strings.TrimSpace(original)
+	generated = strings.TrimSpace(generated)
+	if original == "" {
+		return generated
+	}
+	return original + "\n\n" + generated
+}
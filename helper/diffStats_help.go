@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DiffStats are quick size figures of a change, computed from its unified diff alone.
+type DiffStats struct {
+	Files     int
+	Added     int
+	Removed   int
+	Languages map[string]int // Changed files by language
+	TestLines int            // Added and removed lines in test files
+	CodeLines int            // Added and removed lines in other source files
+}
+
+// nonCodeLanguages do not count towards the test-to-code ratio.
+var nonCodeLanguages = map[string]bool{
+	"YAML": true, "JSON": true, "XML": true, "Markdown": true, "Text": true, "Other": true,
+}
+
+// IsTestFile reports whether a path looks like a test by common naming conventions.
+func IsTestFile(file string) bool {
+	lower := strings.ToLower(file)
+	base := path.Base(lower)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	for _, dir := range []string{"test/", "tests/", "__tests__/", "spec/"} {
+		if strings.HasPrefix(lower, dir) || strings.Contains(lower, "/"+dir) {
+			return true
+		}
+	}
+	if strings.HasSuffix(name, "_test") || strings.HasSuffix(name, ".test") || strings.HasSuffix(name, ".spec") || strings.HasPrefix(name, "test_") {
+		return true
+	}
+	// FooTest.java, FooTests.cs, FooSpec.scala
+	orig := path.Base(file)
+	orig = strings.TrimSuffix(orig, path.Ext(orig))
+	return strings.HasSuffix(orig, "Test") || strings.HasSuffix(orig, "Tests") || strings.HasSuffix(orig, "Spec")
+}
+
+// ComputeDiffStats counts changed files and lines of a unified diff. Deleted files are counted
+// under their old path.
+func ComputeDiffStats(diff string) DiffStats {
+	stats := DiffStats{Languages: map[string]int{}}
+	file, inHunk := "", false
+	count := func(file string, n int) {
+		lang := FileLanguage(file)
+		if IsTestFile(file) {
+			stats.TestLines += n
+		} else if !nonCodeLanguages[lang] {
+			stats.CodeLines += n
+		}
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHunk = false
+			file = ""
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				file = line[i+3:]
+			}
+			stats.Files++
+			stats.Languages[FileLanguage(file)]++
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk:
+		case strings.HasPrefix(line, "+"):
+			stats.Added++
+			count(file, 1)
+		case strings.HasPrefix(line, "-"):
+			stats.Removed++
+			count(file, 1)
+		}
+	}
+	return stats
+}
+
+// FormatDiffStats renders stats as the one-line header of a review summary, or "" for an empty diff.
+func FormatDiffStats(stats DiffStats) string {
+	if stats.Files == 0 {
+		return ""
+	}
+	langs := make([]string, 0, len(stats.Languages))
+	for lang := range stats.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if stats.Languages[langs[i]] != stats.Languages[langs[j]] {
+			return stats.Languages[langs[i]] > stats.Languages[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+	for i, lang := range langs {
+		langs[i] = fmt.Sprintf("%s %d", lang, stats.Languages[lang])
+	}
+	files := "files"
+	if stats.Files == 1 {
+		files = "file"
+	}
+	ratio := "n/a"
+	if stats.CodeLines > 0 {
+		ratio = fmt.Sprintf("%.2f", float64(stats.TestLines)/float64(stats.CodeLines))
+	}
+	return fmt.Sprintf("📊 **+%d / −%d** (net %+d) in %d %s · %s · test-to-code ratio %s\n\n",
+		stats.Added, stats.Removed, stats.Added-stats.Removed, stats.Files, files, strings.Join(langs, ", "), ratio)
+}
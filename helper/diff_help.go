@@ -0,0 +1,62 @@
+package helper
+
+import "strings"
+
+// ParseDiff splits a unified git diff into files, each with its destination "path" and its
+// "hunks" (a "header" such as "@@ -1,3 +1,4 @@" and the raw hunk "lines"). Binary files
+// ("Binary files ... differ" or a "GIT binary patch") have no hunks and "binary" set to true;
+// their path is taken from the "diff --git" line.
+func ParseDiff(diff string) []map[string]interface{} {
+	files := []map[string]interface{}{}
+	var currentFile map[string]interface{}
+	var currentHunk map[string]interface{}
+	gitPath := "" // b/ path of the current "diff --git" line
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git") {
+			if currentFile != nil {
+				files = append(files, currentFile)
+			}
+			currentFile = map[string]interface{}{"path": "", "hunks": []map[string]interface{}{}}
+			currentHunk = nil
+			gitPath = ""
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				gitPath = line[i+3:]
+			}
+		} else if strings.HasPrefix(line, "+++ b/") {
+			if currentFile != nil {
+				currentFile["path"] = strings.TrimPrefix(line, "+++ b/")
+			}
+		} else if currentHunk == nil && (line == "GIT binary patch" || strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ")) {
+			if currentFile != nil {
+				currentFile["binary"] = true
+				if currentFile["path"] == "" {
+					currentFile["path"] = gitPath
+				}
+			}
+		} else if strings.HasPrefix(line, "@@") {
+			if currentFile != nil {
+				currentHunk = map[string]interface{}{"header": line, "lines": []string{}}
+				hunks := currentFile["hunks"].([]map[string]interface{})
+				currentFile["hunks"] = append(hunks, currentHunk)
+			}
+		} else if currentHunk != nil {
+			lines := currentHunk["lines"].([]string)
+			currentHunk["lines"] = append(lines, line)
+		}
+	}
+	if currentFile != nil {
+		files = append(files, currentFile)
+	}
+	return files
+}
+
+// DiffLineCount returns how many hunk lines a file of ParseDiff has.
+func DiffLineCount(file map[string]interface{}) int {
+	n := 0
+	hunks, _ := file["hunks"].([]map[string]interface{})
+	for _, h := range hunks {
+		lines, _ := h["lines"].([]string)
+		n += len(lines)
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DigestActivity is what the reviews of one entry did during a digest period.
+type DigestActivity struct {
+	Name           string
+	PRsReviewed    int64
+	FindingsPosted int64
+	AIErrors       int64
+	APIErrors      int64
+}
+
+// DigestReport is the content of a team's review digest.
+type DigestReport struct {
+	Team         string
+	Since, Until time.Time
+	Activity     []DigestActivity
+	TopFindings  []model.Finding // most severe findings posted during the period
+	Critical     []model.Finding // unresolved critical bot findings on open pull requests
+	// Incomplete lists the entries whose open pull requests could not be read.
+	Incomplete []string
+}
+
+// TopFindings returns the limit most severe of findings, newest first within a severity.
+// Deferred findings were not posted and are left out.
+func TopFindings(findings []model.Finding, limit int) []model.Finding {
+	var posted []model.Finding
+	for _, f := range findings {
+		if !f.Deferred {
+			posted = append(posted, f)
+		}
+	}
+	sort.SliceStable(posted, func(i, j int) bool {
+		if ri, rj := SeverityRank(posted[i].Severity), SeverityRank(posted[j].Severity); ri != rj {
+			return ri > rj
+		}
+		return posted[i].CreatedAt.After(posted[j].CreatedAt)
+	})
+	if len(posted) > limit {
+		posted = posted[:limit]
+	}
+	return posted
+}
+
+// FormatDigest renders a digest as the plain-text body of its mail.
+func FormatDigest(r DigestReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review digest for %s\n%s to %s\n\n", r.Team, r.Since.Format("2006-01-02 15:04"), r.Until.Format("2006-01-02 15:04 MST"))
+
+	var total DigestActivity
+	b.WriteString("ACTIVITY\n")
+	for _, a := range r.Activity {
+		fmt.Fprintf(&b, "- %s: %d %s reviewed, %d %s posted", a.Name, a.PRsReviewed, Pluralize(int(a.PRsReviewed), "PR", "PRs"), a.FindingsPosted, Pluralize(int(a.FindingsPosted), "finding", "findings"))
+		if a.AIErrors > 0 || a.APIErrors > 0 {
+			fmt.Fprintf(&b, ", %d AI / %d API errors", a.AIErrors, a.APIErrors)
+		}
+		b.WriteString("\n")
+		total.PRsReviewed += a.PRsReviewed
+		total.FindingsPosted += a.FindingsPosted
+		total.AIErrors += a.AIErrors
+		total.APIErrors += a.APIErrors
+	}
+	fmt.Fprintf(&b, "Total: %d PRs reviewed, %d findings posted, %d AI errors, %d API errors\n\n", total.PRsReviewed, total.FindingsPosted, total.AIErrors, total.APIErrors)
+
+	b.WriteString("TOP FINDINGS\n")
+	if len(r.TopFindings) == 0 {
+		b.WriteString("No findings were posted.\n")
+	}
+	for _, f := range r.TopFindings {
+		writeDigestFinding(&b, f)
+	}
+
+	fmt.Fprintf(&b, "\nUNRESOLVED CRITICAL ISSUES (%d)\n", len(r.Critical))
+	if len(r.Critical) == 0 {
+		b.WriteString("None on open pull requests.\n")
+	}
+	for _, f := range r.Critical {
+		writeDigestFinding(&b, f)
+	}
+	if len(r.Incomplete) > 0 {
+		fmt.Fprintf(&b, "(Open pull requests of %s could not be read; their critical issues are missing.)\n", strings.Join(r.Incomplete, ", "))
+	}
+	return b.String()
+}
+
+// writeDigestFinding writes one finding line with its pull request and a link to its line.
+func writeDigestFinding(b *strings.Builder, f model.Finding) {
+	severity := f.Severity
+	if severity == "" {
+		severity = "Unrated"
+	}
+	pr := fmt.Sprintf("%s/%s #%d", f.Workspace, f.RepoSlug, f.PullRequestID)
+	if f.PullRequestTitle != "" {
+		pr += " " + f.PullRequestTitle
+	}
+	location := f.Path
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	fmt.Fprintf(b, "- [%s] %s\n  %s, %s\n", severity, f.Title, pr, location)
+	if f.Line > 0 {
+		fmt.Fprintf(b, "  %s\n", PullRequestLineURL(f.Workspace, f.RepoSlug, f.PullRequestID, f.Path, f.Line))
+	}
+}
@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Phrases that mark a reply to a bot comment as feedback. Unhelpful phrases are checked first
+// so "not helpful" is not read as "helpful".
+var (
+	unhelpfulFeedback = regexp.MustCompile(`(?i)👎|:-1:|:thumbsdown:|(^|\s)-1\b|\bnot (relevant|helpful|useful|applicable)\b|\b(irrelevant|unhelpful|useless|false positive|wont ?fix|won't fix)\b`)
+	helpfulFeedback   = regexp.MustCompile(`(?i)👍|:\+1:|:thumbsup:|(^|\s)\+1\b|\b(helpful|useful|good catch|nice catch|great catch|thanks|thank you|fixed)\b`)
+)
+
+// ClassifyFeedback returns model.FeedbackHelpful or model.FeedbackUnhelpful for a reply to a bot
+// comment, or "" when the reply carries no feedback.
+func ClassifyFeedback(text string) string {
+	t := strings.TrimSpace(text)
+	switch {
+	case unhelpfulFeedback.MatchString(t):
+		return model.FeedbackUnhelpful
+	case helpfulFeedback.MatchString(t):
+		return model.FeedbackHelpful
+	}
+	return ""
+}
+
+// maxLearnedPreferences bounds the finding kinds listed in the prompt.
+const maxLearnedPreferences = 8
+
+// LearnedPreferences condenses feedback into prompt rules: finding types and titles with at
+// least minDismissals unhelpful replies and more unhelpful than helpful ones, most dismissed
+// first. It returns "" when nothing qualifies.
+func LearnedPreferences(feedback []model.Feedback, minDismissals int) string {
+	type tally struct {
+		label              string
+		helpful, unhelpful int
+	}
+	var order []string
+	tallies := map[string]*tally{}
+	count := func(key, label string, helpful bool) {
+		t, ok := tallies[key]
+		if !ok {
+			t = &tally{label: label}
+			tallies[key] = t
+			order = append(order, key)
+		}
+		if helpful {
+			t.helpful++
+		} else {
+			t.unhelpful++
+		}
+	}
+	for _, fb := range feedback {
+		helpful := fb.Verdict == model.FeedbackHelpful
+		if t := strings.TrimSpace(fb.Type); t != "" {
+			count("type:"+strings.ToLower(t), "["+t+"] findings", helpful)
+		}
+		if t := strings.TrimSpace(fb.Title); t != "" {
+			count("title:"+strings.ToLower(t), fmt.Sprintf("findings like %q", t), helpful)
+		}
+	}
+	var kept []*tally
+	for _, key := range order {
+		if t := tallies[key]; t.unhelpful >= minDismissals && t.unhelpful > t.helpful {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].unhelpful > kept[j].unhelpful })
+	if len(kept) > maxLearnedPreferences {
+		kept = kept[:maxLearnedPreferences]
+	}
+	var b strings.Builder
+	b.WriteString("\nTeam preferences learned from replies to earlier reviews of this repository. Avoid these unless they point to a real defect:\n")
+	for _, t := range kept {
+		fmt.Fprintf(&b, "- Avoid flagging %s: the team has dismissed them %d %s", t.label, t.unhelpful, Pluralize(t.unhelpful, "time", "times"))
+		if t.helpful > 0 {
+			fmt.Fprintf(&b, " (%d found helpful)", t.helpful)
+		}
+		b.WriteString(".\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,278 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// findingTypes are the finding types the review prompt asks for, lower-cased.
+var findingTypes = map[string]bool{"potential issue": true, "refactor": true, "nitpick": true}
+
+var findingHeadingPattern = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*\[([^\]]+)\]\s*(.*)$`)
+
+// ParseFindingHeading extracts type, severity and title from a review comment that starts
+// with "[<Type>] [<Severity>] <title>". When the title is on the next line it is used instead.
+func ParseFindingHeading(body string) (string, string, string) {
+	var lines []string
+	for _, ln := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(ln) != "" {
+			lines = append(lines, strings.TrimSpace(ln))
+		}
+	}
+	if len(lines) == 0 {
+		return "", "", ""
+	}
+	m := findingHeadingPattern.FindStringSubmatch(lines[0])
+	if m == nil {
+		return "", "", lines[0]
+	}
+	typ := strings.TrimPrefix(strings.TrimSpace(m[1]), "Type: ")
+	severity := strings.TrimPrefix(strings.TrimSpace(m[2]), "Severity: ")
+	title := strings.TrimSpace(m[3])
+	if title == "" && len(lines) > 1 {
+		title = lines[1]
+	}
+	return typ, severity, title
+}
+
+// breakingChangeTitle matches the title the review prompt asks for on breaking changes.
+var breakingChangeTitle = regexp.MustCompile(`(?i)^breaking change\b`)
+
+// EscalateBreakingChange raises a finding titled "Breaking change: ..." with a severity below
+// Major to [Potential issue] [Major], so a breaking change is never posted as a minor remark.
+// Other findings are returned unchanged.
+func EscalateBreakingChange(body string) string {
+	_, severity, title := ParseFindingHeading(body)
+	if !breakingChangeTitle.MatchString(title) || SeverityRank(severity) >= SeverityRank("major") {
+		return body
+	}
+	return setFindingHeading(body, "[Potential issue] [Major] ")
+}
+
+// EscalateSeverity raises a [Potential issue] finding with a known severity below min to min.
+// Other findings are returned unchanged.
+func EscalateSeverity(body, min string) string {
+	typ, severity, _ := ParseFindingHeading(body)
+	if !strings.EqualFold(typ, "potential issue") || !ValidSeverity(severity) || SeverityRank(severity) >= SeverityRank(min) {
+		return body
+	}
+	return setFindingHeading(body, "[Potential issue] ["+min+"] ")
+}
+
+// setFindingHeading replaces the type and severity of the heading on the first non-blank line
+// of body with prefix, keeping the title.
+func setFindingHeading(body, prefix string) string {
+	lines := strings.Split(body, "\n")
+	for i, ln := range lines {
+		if m := findingHeadingPattern.FindStringSubmatch(ln); m != nil {
+			lines[i] = strings.TrimSpace(prefix + m[3])
+			return strings.Join(lines, "\n")
+		}
+		if strings.TrimSpace(ln) != "" {
+			break
+		}
+	}
+	return body
+}
+
+// PullRequestLineURL links to a line of the destination file in the Bitbucket PR diff view.
+func PullRequestLineURL(workspace, repoSlug string, prID int, path string, line int) string {
+	return fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d/diff#L%sT%d",
+		workspace, repoSlug, prID, url.PathEscape(path), line)
+}
+
+// PullRequestOldLineURL links to a line of the source file, such as a removed line, in the
+// Bitbucket PR diff view.
+func PullRequestOldLineURL(workspace, repoSlug string, prID int, path string, line int) string {
+	return fmt.Sprintf("https://bitbucket.org/%s/%s/pull-requests/%d/diff#L%sF%d",
+		workspace, repoSlug, prID, url.PathEscape(path), line)
+}
+
+// FindingsTableSeverities returns the Severity column of a findings table rendered by
+// FormatFindingsTable; rows without a severity ("-") are returned as empty strings.
+func FindingsTableSeverities(raw string) []string {
+	var out []string
+	for _, row := range FindingsTableRows(raw) {
+		out = append(out, row.Severity)
+	}
+	return out
+}
+
+// FindingsTableRows parses the rows of a findings table rendered by FormatFindingsTable back
+// into findings with Path, Line (0 when unanchored), OldLine (for removed lines), Type,
+// Severity and Title set.
+func FindingsTableRows(raw string) []model.Finding {
+	var out []model.Finding
+	for _, ln := range strings.Split(raw, "\n") {
+		// Escaped pipes inside cells are kept aside while the row is split on "|".
+		cells := strings.Split(strings.ReplaceAll(strings.TrimSpace(ln), "\\|", "\x00"), "|")
+		// "| n | File | Line | Severity | Finding |..." splits into a leading empty cell and the columns.
+		if len(cells) < 6 || cells[0] != "" {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(cells[1])); err != nil {
+			continue
+		}
+		for i := range cells {
+			cells[i] = strings.ReplaceAll(cells[i], "\x00", "|")
+		}
+		f := model.Finding{
+			Path:     strings.Trim(strings.TrimSpace(cells[2]), "`"),
+			Severity: strings.TrimSpace(cells[4]),
+			Title:    strings.TrimSpace(cells[5]),
+		}
+		if f.Severity == "-" {
+			f.Severity = ""
+		}
+		// The table shows "<Type>: <title>" for the finding types the review prompt asks for.
+		if typ, title, ok := strings.Cut(f.Title, ": "); ok && findingTypes[strings.ToLower(typ)] {
+			f.Type, f.Title = typ, title
+		}
+		// The line cell is "-", "[n](url)", "[start–end](url)" or "[old n](url)".
+		if line := strings.TrimSpace(cells[3]); strings.HasPrefix(line, "[") {
+			if end := strings.Index(line, "]"); end > 0 {
+				label := line[1:end]
+				if old, ok := strings.CutPrefix(label, "old "); ok {
+					f.OldLine, _ = strconv.Atoi(old)
+				} else {
+					_, last, _ := strings.Cut(label, "–")
+					if last == "" {
+						last = label
+					}
+					f.Line, _ = strconv.Atoi(last)
+				}
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func escapeTableCell(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.TrimSpace(s)
+}
+
+// FormatFindingsTable renders findings as a Markdown table with links to the diff lines.
+// detailURLs is parallel to findings; when an entry is non-empty a "Details" link is added.
+func FormatFindingsTable(auto *model.AutoReviewPR, prID int, findings []model.ReviewComment, detailURLs []string) string {
+	if len(findings) == 0 {
+		return "_No findings._"
+	}
+	withDetails := false
+	for _, u := range detailURLs {
+		if u != "" {
+			withDetails = true
+			break
+		}
+	}
+	var b strings.Builder
+	if withDetails {
+		b.WriteString("| # | File | Line | Severity | Finding | Details |\n")
+		b.WriteString("|---|------|------|----------|---------|---------|\n")
+	} else {
+		b.WriteString("| # | File | Line | Severity | Finding |\n")
+		b.WriteString("|---|------|------|----------|---------|\n")
+	}
+	for i, f := range findings {
+		typ, severity, title := ParseFindingHeading(f.Body)
+		if typ != "" {
+			title = typ + ": " + title
+		}
+		if severity == "" {
+			severity = "-"
+		}
+		line := "-" // unanchored findings have no diff line to link to
+		if f.Position > 0 {
+			label := strconv.Itoa(f.Position)
+			if f.StartLine > 0 && f.StartLine < f.Position {
+				label = fmt.Sprintf("%d–%d", f.StartLine, f.Position)
+			}
+			line = fmt.Sprintf("[%s](%s)", label, PullRequestLineURL(auto.Workspace, auto.RepoSlug, prID, f.Path, f.Position))
+		} else if f.FromLine > 0 {
+			line = fmt.Sprintf("[old %d](%s)", f.FromLine, PullRequestOldLineURL(auto.Workspace, auto.RepoSlug, prID, f.Path, f.FromLine))
+		}
+		fmt.Fprintf(&b, "| %d | `%s` | %s | %s | %s |",
+			i+1, escapeTableCell(f.Path), line, escapeTableCell(severity), escapeTableCell(title))
+		if withDetails {
+			if i < len(detailURLs) && detailURLs[i] != "" {
+				fmt.Fprintf(&b, " [View](%s) |", detailURLs[i])
+			} else {
+				b.WriteString(" - |")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// findingSectionSeparator separates the findings of a merged inline comment.
+const findingSectionSeparator = "\n\n---\n\n"
+
+// MergeSameLineFindings merges the findings anchored to the same file line into one comment
+// with a section per finding, most severe first, so Bitbucket does not stack several bot
+// comments on the line. The merged comment keeps the heading of its first section, and a
+// line range or fix only when its sections agree on it. Findings on other lines keep their
+// order.
+func MergeSameLineFindings(comments []model.ReviewComment) []model.ReviewComment {
+	type lineKey struct {
+		path     string
+		to, from int
+	}
+	groups := map[lineKey][]model.ReviewComment{}
+	var order []lineKey
+	for _, c := range comments {
+		k := lineKey{c.Path, c.Position, c.FromLine}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+	if len(order) == len(comments) {
+		return comments
+	}
+	merged := make([]model.ReviewComment, 0, len(order))
+	for _, k := range order {
+		group := groups[k]
+		if len(group) == 1 {
+			merged = append(merged, group[0])
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			_, si, _ := ParseFindingHeading(group[i].Body)
+			_, sj, _ := ParseFindingHeading(group[j].Body)
+			return SeverityRank(si) > SeverityRank(sj)
+		})
+		c := group[0]
+		sections := []string{strings.TrimSpace(c.Body)}
+		for _, other := range group[1:] {
+			sections = append(sections, strings.TrimSpace(other.Body))
+			if other.StartLine != c.StartLine {
+				c.StartLine, c.EndLine = 0, 0
+			}
+		}
+		// A fix replaces the lines of its own finding; two fixes of one line would conflict.
+		c.Fix = ""
+		fixes := 0
+		for _, f := range group {
+			if f.Fix != "" {
+				fixes++
+				if f.StartLine == c.StartLine {
+					c.Fix = f.Fix
+				}
+			}
+		}
+		if fixes > 1 {
+			c.Fix = ""
+		}
+		c.Body = strings.Join(sections, findingSectionSeparator)
+		merged = append(merged, c)
+	}
+	return merged
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// freezeCronParser accepts the job cron format: five fields with an optional leading seconds field.
+var freezeCronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// FreezeRange returns the occurrence of w that contains now, with ok=false when w is not active.
+// For recurring windows the occurrence is the latest cron start within Duration before now.
+func FreezeRange(w model.FreezeWindow, now time.Time) (start, end time.Time, ok bool, err error) {
+	if w.Cron != "" {
+		schedule, err := freezeCronParser.Parse(w.Cron)
+		if err != nil {
+			return start, end, false, fmt.Errorf("freeze window %q: invalid cron: %w", w.Name, err)
+		}
+		d, err := time.ParseDuration(w.Duration)
+		if err != nil || d <= 0 {
+			return start, end, false, fmt.Errorf("freeze window %q: invalid duration %q", w.Name, w.Duration)
+		}
+		// The first start after now-d is the only one whose range can still contain now.
+		start = schedule.Next(now.UTC().Add(-d).Add(-time.Second))
+		end = start.Add(d)
+		return start, end, !start.After(now) && now.Before(end), nil
+	}
+	if w.From == "" || w.To == "" {
+		return start, end, false, fmt.Errorf("freeze window %q: needs from/to or cron/duration", w.Name)
+	}
+	if start, err = parseFreezeTime(w.From, false); err != nil {
+		return start, end, false, fmt.Errorf("freeze window %q: invalid from: %w", w.Name, err)
+	}
+	if end, err = parseFreezeTime(w.To, true); err != nil {
+		return start, end, false, fmt.Errorf("freeze window %q: invalid to: %w", w.Name, err)
+	}
+	return start, end, !now.Before(start) && now.Before(end), nil
+}
+
+// parseFreezeTime parses a date or RFC 3339 time. A date ends at the end of the day when endOfDay is set.
+func parseFreezeTime(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, errors.New("expected YYYY-MM-DD or RFC 3339")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// ActiveFreeze returns the first window of windows active at now and when that occurrence ends.
+// Invalid windows are reported through errs and otherwise ignored.
+func ActiveFreeze(windows []model.FreezeWindow, now time.Time) (active *model.FreezeWindow, start, end time.Time, errs []error) {
+	for i := range windows {
+		s, e, ok, err := FreezeRange(windows[i], now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			return &windows[i], s, e, errs
+		}
+	}
+	return nil, start, end, errs
+}
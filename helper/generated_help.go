@@ -0,0 +1,125 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Reasons a file is left out of inline review.
+const (
+	SkipGenerated = "generated"
+	SkipSourceMap = "source map"
+	SkipMinified  = "minified"
+	SkipLockfile  = "lockfile"
+	SkipBinary    = "binary"
+	SkipTooLarge  = "file too large to review"
+)
+
+// minifiedLineLength is the length from which a changed line is taken for minified code.
+const minifiedLineLength = 1000
+
+// generatedHeader matches a diff line holding a comment that starts with a marker code generators
+// write near the top of their output: Go's "Code generated ... DO NOT EDIT." or the "@generated"
+// tag used by many other tools.
+var generatedHeader = regexp.MustCompile(`^[-+ ]\s*(//|#|/\*|\*|<!--|--)\s*(Code generated .*DO NOT EDIT|@generated\b)`)
+
+// lockfiles are dependency lockfiles, matched on the file name.
+var lockfiles = map[string]bool{
+	"package-lock.json": true, "npm-shrinkwrap.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"bun.lockb": true, "go.sum": true, "Cargo.lock": true, "Gemfile.lock": true, "poetry.lock": true,
+	"Pipfile.lock": true, "composer.lock": true, "mix.lock": true, "pubspec.lock": true, "Podfile.lock": true,
+	"packages.lock.json": true, "gradle.lockfile": true, "flake.lock": true, "uv.lock": true,
+}
+
+// SkippedFile is a file of the diff left out of inline review, with the reason.
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// GeneratedFileReason returns why a file should not be reviewed inline: it is generated
+// (*.pb.go and friends, or a "Code generated ... DO NOT EDIT" header), a source map, minified
+// (*.min.js or a changed line of minifiedLineLength characters or more) or a lockfile. It
+// returns "" for files worth reviewing.
+func GeneratedFileReason(filePath string, hunks []map[string]interface{}) string {
+	name := path.Base(filePath)
+	switch {
+	case lockfiles[name]:
+		return SkipLockfile
+	case strings.HasSuffix(name, ".map"):
+		return SkipSourceMap
+	case strings.HasSuffix(name, ".min.js"), strings.HasSuffix(name, ".min.css"), strings.HasSuffix(name, ".min.mjs"):
+		return SkipMinified
+	case strings.HasSuffix(name, ".pb.go"), strings.HasSuffix(name, ".pb.gw.go"), strings.HasSuffix(name, "_pb2.py"),
+		strings.HasSuffix(name, "_pb2_grpc.py"), strings.HasSuffix(name, ".pb.cc"), strings.HasSuffix(name, ".pb.h"),
+		strings.HasSuffix(name, "_generated.go"), strings.HasSuffix(name, ".g.dart"), strings.HasSuffix(name, ".freezed.dart"):
+		return SkipGenerated
+	}
+	for _, h := range hunks {
+		lines, _ := h["lines"].([]string)
+		for _, line := range lines {
+			if generatedHeader.MatchString(line) {
+				return SkipGenerated
+			}
+			if strings.HasPrefix(line, "+") && len(line) > minifiedLineLength {
+				return SkipMinified
+			}
+		}
+	}
+	return ""
+}
+
+// SkippedFileReason returns why a file of ParseDiff is left out of inline review for auto:
+// it is binary, has more diff lines than auto.MaxFileLines(), matches a model route with skipAI
+// or, unless auto.ReviewGeneratedFiles, looks generated (see GeneratedFileReason). It returns
+// "" for files to review.
+func SkippedFileReason(auto *model.AutoReviewPR, file map[string]interface{}) string {
+	if binary, _ := file["binary"].(bool); binary {
+		return SkipBinary
+	}
+	if limit := auto.MaxFileLines(); limit > 0 {
+		if n := DiffLineCount(file); n > limit {
+			return fmt.Sprintf("%s: %d diff lines, limit %d", SkipTooLarge, n, limit)
+		}
+	}
+	filePath, _ := file["path"].(string)
+	if name, route := ModelRouteFor(auto, filePath, DiffLineCount(file)); route != nil && route.SkipAI {
+		return SkipRouted + ": " + name
+	}
+	if auto.ReviewGeneratedFiles {
+		return ""
+	}
+	hunks, _ := file["hunks"].([]map[string]interface{})
+	return GeneratedFileReason(filePath, hunks)
+}
+
+// SkippedFiles returns the files of a parsed diff that SkippedFileReason leaves out of inline
+// review for auto.
+func SkippedFiles(auto *model.AutoReviewPR, parsed []map[string]interface{}) []SkippedFile {
+	var skipped []SkippedFile
+	for _, file := range parsed {
+		if reason := SkippedFileReason(auto, file); reason != "" {
+			filePath, _ := file["path"].(string)
+			skipped = append(skipped, SkippedFile{Path: filePath, Reason: reason})
+		}
+	}
+	return skipped
+}
+
+// FormatSkippedFiles renders the summary note listing the files left out of inline review, or
+// "" when there are none.
+func FormatSkippedFiles(files []SkippedFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Skipped files\n\nThese files were not reviewed inline:\n\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "- `%s` (%s)\n", f.Path, f.Reason)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package gerrit
+
+import (
+	"code_nim/helper"
+	"code_nim/model"
+	"fmt"
+	"net/http"
+)
+
+// Gerrit exposes the Gerrit REST operations the reviewer uses. baseURL is the server URL, e.g.
+// https://gerrit.example.com; username and password are the account's HTTP credentials.
+type Gerrit interface {
+	// Capabilities returns the comment features of Gerrit.
+	Capabilities() model.ProviderCapabilities
+	// FetchOpenChanges returns the open changes of project with their current revision.
+	FetchOpenChanges(baseURL, project, username, password string) ([]model.GerritChange, error)
+	// FetchProjects returns the names of the projects the account can read.
+	FetchProjects(baseURL, username, password string) ([]string, error)
+	// FetchPatch returns the unified diff of a revision against its parent.
+	FetchPatch(baseURL string, change int, revision, username, password string) (string, error)
+	// FetchMessages returns the messages of a change, oldest first.
+	FetchMessages(baseURL string, change int, username, password string) ([]model.GerritMessage, error)
+	// PostReview posts a message, label votes and inline comments on a revision in one call.
+	PostReview(baseURL string, change int, revision string, review model.GerritReviewInput, username, password string) error
+}
+
+// StatusError is returned by the Gerrit client when the server answers with an unexpected
+// status. It matches helper.ErrRateLimited, helper.ErrAuth and helper.ErrNotFound with errors.Is.
+type StatusError struct {
+	Op         string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s, status: %d", e.Op, e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case helper.ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case helper.ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case helper.ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
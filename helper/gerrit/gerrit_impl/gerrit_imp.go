@@ -0,0 +1,168 @@
+package gerrit_impl
+
+import (
+	"bytes"
+	"code_nim/helper/gerrit"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// xssiPrefix starts every JSON response of Gerrit.
+const xssiPrefix = ")]}'"
+
+// changesPageSize is how many changes are asked for per page.
+const changesPageSize = 100
+
+type HttpClient struct {
+	http *http.Client
+}
+
+// New returns a client sending its requests with httpClient; nil uses a client with only the
+// default timeout.
+func New(httpClient *http.Client) gerrit.Gerrit {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: model.DefaultHTTPTimeout}
+	}
+	return &HttpClient{http: httpClient}
+}
+
+// Capabilities reports Gerrit's comment features. Gerrit renders plain text with indented
+// preformatted blocks rather than Markdown, has no suggestion blocks and no tasks; unresolved
+// comments play that role.
+func (hc *HttpClient) Capabilities() model.ProviderCapabilities {
+	return model.ProviderCapabilities{LineRanges: true}
+}
+
+// FetchOpenChanges follows the pages of the open changes of project.
+func (hc *HttpClient) FetchOpenChanges(baseURL, project, username, password string) ([]model.GerritChange, error) {
+	var changes []model.GerritChange
+	for start := 0; ; start += changesPageSize {
+		query := url.Values{}
+		query.Set("q", fmt.Sprintf("status:open project:%q", project))
+		query.Add("o", "CURRENT_REVISION")
+		query.Add("o", "CURRENT_COMMIT")
+		query.Add("o", "DETAILED_ACCOUNTS")
+		query.Set("n", fmt.Sprint(changesPageSize))
+		query.Set("S", fmt.Sprint(start))
+		var page []model.GerritChange
+		if err := hc.getJSON(apiURL(baseURL, "/changes/?"+query.Encode()), &page, username, password); err != nil {
+			return nil, fmt.Errorf("list open changes of %s: %w", project, err)
+		}
+		changes = append(changes, page...)
+		if len(page) == 0 || !page[len(page)-1].MoreChanges {
+			return changes, nil
+		}
+	}
+}
+
+func (hc *HttpClient) FetchProjects(baseURL, username, password string) ([]string, error) {
+	var projects map[string]json.RawMessage
+	if err := hc.getJSON(apiURL(baseURL, "/projects/?type=CODE"), &projects, username, password); err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// FetchPatch downloads the revision as a base64-encoded git patch and decodes it. The "-- "
+// signature git format-patch ends with is cut, since a diff parser would read it as a removed line.
+func (hc *HttpClient) FetchPatch(baseURL string, change int, revision, username, password string) (string, error) {
+	raw, err := hc.get(apiURL(baseURL, fmt.Sprintf("/changes/%d/revisions/%s/patch", change, url.PathEscape(revision))), username, password)
+	if err != nil {
+		return "", fmt.Errorf("fetch patch of change %d: %w", change, err)
+	}
+	patch, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return "", fmt.Errorf("decode patch of change %d: %w", change, err)
+	}
+	text := string(patch)
+	if i := strings.LastIndex(text, "\n-- \n"); i >= 0 {
+		text = text[:i+1]
+	}
+	return text, nil
+}
+
+func (hc *HttpClient) FetchMessages(baseURL string, change int, username, password string) ([]model.GerritMessage, error) {
+	var messages []model.GerritMessage
+	if err := hc.getJSON(apiURL(baseURL, fmt.Sprintf("/changes/%d/messages", change)), &messages, username, password); err != nil {
+		return nil, fmt.Errorf("list messages of change %d: %w", change, err)
+	}
+	return messages, nil
+}
+
+func (hc *HttpClient) PostReview(baseURL string, change int, revision string, review model.GerritReviewInput, username, password string) error {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	endpoint := apiURL(baseURL, fmt.Sprintf("/changes/%d/revisions/%s/review", change, url.PathEscape(revision)))
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(username, password)
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		log.Errorf("POST %s failed. Status: %d, Body: %s", endpoint, resp.StatusCode, string(raw))
+		return &gerrit.StatusError{Op: "failed to post review", StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// apiURL returns the authenticated REST URL of path; Gerrit serves it under /a/.
+func apiURL(baseURL, path string) string {
+	return strings.TrimRight(baseURL, "/") + "/a" + path
+}
+
+func (hc *HttpClient) get(endpoint, username, password string) ([]byte, error) {
+	log.Debugf("Fetching from Gerrit URL: %s", endpoint)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("GET %s failed. Status: %d, Body: %s", endpoint, resp.StatusCode, string(raw))
+		return nil, &gerrit.StatusError{Op: "GET failed", StatusCode: resp.StatusCode}
+	}
+	return raw, nil
+}
+
+// getJSON decodes a JSON response after stripping Gerrit's XSSI prefix.
+func (hc *HttpClient) getJSON(endpoint string, out interface{}, username, password string) error {
+	raw, err := hc.get(endpoint, username, password)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes.TrimPrefix(raw, []byte(xssiPrefix)), out)
+}
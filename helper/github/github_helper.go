@@ -0,0 +1,50 @@
+package github
+
+import (
+	"code_nim/helper"
+	"code_nim/model"
+	"fmt"
+	"net/http"
+)
+
+// GitHub exposes the GitHub REST operations the reviewer uses. baseURL is the API URL, e.g.
+// https://api.github.com; token is a personal access or installation token.
+type GitHub interface {
+	// Capabilities returns the comment features of GitHub.
+	Capabilities() model.ProviderCapabilities
+	// FetchOpenPullRequests returns the open pull requests of owner/repo.
+	FetchOpenPullRequests(baseURL, owner, repo, token string) ([]model.GitHubPullRequest, error)
+	// FetchRepositories returns the names of owner's repositories the token can read.
+	FetchRepositories(baseURL, owner, token string) ([]string, error)
+	// FetchDiff returns the unified diff of a pull request.
+	FetchDiff(baseURL, owner, repo string, number int, token string) (string, error)
+	// FetchReviews returns the reviews submitted on a pull request, oldest first.
+	FetchReviews(baseURL, owner, repo string, number int, token string) ([]model.GitHubReview, error)
+	// CreateReview submits a summary and all inline comments as one pull request review.
+	CreateReview(baseURL, owner, repo string, number int, review model.GitHubReviewInput, token string) error
+}
+
+// StatusError is returned by the GitHub client when the API answers with an unexpected status.
+// It matches helper.ErrRateLimited, helper.ErrAuth and helper.ErrNotFound with errors.Is.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	// RateLimited is set for a 403 sent because the rate limit is exhausted.
+	RateLimited bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s, status: %d", e.Op, e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case helper.ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.RateLimited
+	case helper.ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || (e.StatusCode == http.StatusForbidden && !e.RateLimited)
+	case helper.ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
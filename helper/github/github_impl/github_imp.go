@@ -0,0 +1,165 @@
+package github_impl
+
+import (
+	"bytes"
+	"code_nim/helper/github"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// pageSize is how many items are asked for per page, GitHub's maximum.
+const pageSize = 100
+
+const (
+	acceptJSON = "application/vnd.github+json"
+	acceptDiff = "application/vnd.github.diff"
+)
+
+type HttpClient struct {
+	http *http.Client
+}
+
+// New returns a client sending its requests with httpClient; nil uses a client with only the
+// default timeout.
+func New(httpClient *http.Client) github.GitHub {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: model.DefaultHTTPTimeout}
+	}
+	return &HttpClient{http: httpClient}
+}
+
+// Capabilities reports GitHub's comment features; pull requests have no tasks.
+func (hc *HttpClient) Capabilities() model.ProviderCapabilities {
+	return model.ProviderCapabilities{Markdown: true, Suggestions: true, LineRanges: true}
+}
+
+func (hc *HttpClient) FetchOpenPullRequests(baseURL, owner, repo, token string) ([]model.GitHubPullRequest, error) {
+	var pulls []model.GitHubPullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls?state=open", url.PathEscape(owner), url.PathEscape(repo))
+	if err := getPages(hc, baseURL+path, token, &pulls); err != nil {
+		return nil, fmt.Errorf("list open pull requests of %s/%s: %w", owner, repo, err)
+	}
+	return pulls, nil
+}
+
+// FetchRepositories lists the repositories of organization owner, or of user owner when no
+// such organization exists.
+func (hc *HttpClient) FetchRepositories(baseURL, owner, token string) ([]string, error) {
+	var repos []struct {
+		Name string `json:"name"`
+	}
+	err := getPages(hc, fmt.Sprintf("%s/orgs/%s/repos?type=all", baseURL, url.PathEscape(owner)), token, &repos)
+	var status *github.StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		repos = nil
+		err = getPages(hc, fmt.Sprintf("%s/users/%s/repos?type=all", baseURL, url.PathEscape(owner)), token, &repos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list repositories of %s: %w", owner, err)
+	}
+	names := make([]string, 0, len(repos))
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	return names, nil
+}
+
+func (hc *HttpClient) FetchDiff(baseURL, owner, repo string, number int, token string) (string, error) {
+	raw, err := hc.do(http.MethodGet, fmt.Sprintf("%s/repos/%s/%s/pulls/%d", baseURL, url.PathEscape(owner), url.PathEscape(repo), number), acceptDiff, nil, token)
+	if err != nil {
+		return "", fmt.Errorf("fetch diff of pull request %d: %w", number, err)
+	}
+	return string(raw), nil
+}
+
+func (hc *HttpClient) FetchReviews(baseURL, owner, repo string, number int, token string) ([]model.GitHubReview, error) {
+	var reviews []model.GitHubReview
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", url.PathEscape(owner), url.PathEscape(repo), number)
+	if err := getPages(hc, baseURL+path, token, &reviews); err != nil {
+		return nil, fmt.Errorf("list reviews of pull request %d: %w", number, err)
+	}
+	return reviews, nil
+}
+
+func (hc *HttpClient) CreateReview(baseURL, owner, repo string, number int, review model.GitHubReviewInput, token string) error {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", baseURL, url.PathEscape(owner), url.PathEscape(repo), number)
+	if _, err := hc.do(http.MethodPost, endpoint, acceptJSON, body, token); err != nil {
+		return fmt.Errorf("create review on pull request %d: %w", number, err)
+	}
+	return nil
+}
+
+// getPages appends the items of every page of a list endpoint to out, a pointer to a slice.
+func getPages[T any](hc *HttpClient, endpoint, token string, out *[]T) error {
+	for page := 1; ; page++ {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		q.Set("per_page", fmt.Sprint(pageSize))
+		q.Set("page", fmt.Sprint(page))
+		u.RawQuery = q.Encode()
+		raw, err := hc.do(http.MethodGet, u.String(), acceptJSON, nil, token)
+		if err != nil {
+			return err
+		}
+		var items []T
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		*out = append(*out, items...)
+		if len(items) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (hc *HttpClient) do(method, endpoint, accept string, body []byte, token string) ([]byte, error) {
+	log.Debugf("%s GitHub URL: %s", method, endpoint)
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := hc.http.Do(req)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Errorf("%s %s failed. Status: %d, Body: %s", method, endpoint, resp.StatusCode, string(raw))
+		return nil, &github.StatusError{
+			Op:          method + " failed",
+			StatusCode:  resp.StatusCode,
+			RateLimited: resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0",
+		}
+	}
+	return raw, nil
+}
@@ -0,0 +1,29 @@
+package helper
+
+import (
+	"code_nim/model"
+	"net/http"
+	"sync"
+)
+
+var (
+	httpClientMutex sync.RWMutex
+	sharedClient    = &http.Client{Timeout: model.DefaultHTTPTimeout}
+)
+
+// SetHTTPClient installs the client the AI providers send their requests with; nil restores
+// the default client, which only has the default timeout.
+func SetHTTPClient(c *http.Client) {
+	httpClientMutex.Lock()
+	defer httpClientMutex.Unlock()
+	if c == nil {
+		c = &http.Client{Timeout: model.DefaultHTTPTimeout}
+	}
+	sharedClient = c
+}
+
+func httpClient() *http.Client {
+	httpClientMutex.RLock()
+	defer httpClientMutex.RUnlock()
+	return sharedClient
+}
@@ -0,0 +1,204 @@
+// Package httpcache is a small in-memory HTTP cache for GET requests. Entries are served from
+// memory for a short TTL and revalidated with ETag / Last-Modified afterwards, so unchanged
+// resources cost a 304 instead of a full download.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBody is the largest response body kept; bigger responses are passed through uncached.
+const maxBody = 4 << 20
+
+// Results counted by Stats.
+const (
+	ResultHit         = "hit"         // served from memory within the TTL
+	ResultRevalidated = "revalidated" // server answered 304 Not Modified
+	ResultMiss        = "miss"        // downloaded in full
+)
+
+type entry struct {
+	key          string
+	status       string
+	header       http.Header
+	body         []byte
+	etag         string
+	lastModified string
+	stored       time.Time
+}
+
+// Transport caches the GET responses of next that Cacheable accepts. Any other request
+// drops the cached entries of the same scope (see Scope), so a client reads its own writes.
+type Transport struct {
+	next       http.RoundTripper
+	ttl        time.Duration
+	maxEntries int
+	// Cacheable selects the GET requests to cache; nil caches every GET.
+	Cacheable func(req *http.Request) bool
+	// Scope groups URLs that a write may change; nil uses the host.
+	Scope func(u *url.URL) string
+
+	mutex   sync.Mutex
+	entries map[string]*entry
+	order   []string // keys, oldest first, for eviction
+	counts  map[string]int64
+}
+
+// New wraps next (nil uses http.DefaultTransport). Entries are fresh for ttl and at most
+// maxEntries are kept.
+func New(next http.RoundTripper, ttl time.Duration, maxEntries int) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, ttl: ttl, maxEntries: maxEntries, entries: map[string]*entry{}, counts: map[string]int64{}}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		t.invalidate(t.scope(req.URL))
+		return t.next.RoundTrip(req)
+	}
+	if t.Cacheable != nil && !t.Cacheable(req) {
+		return t.next.RoundTrip(req)
+	}
+	key := cacheKey(req)
+	t.mutex.Lock()
+	cached := t.entries[key]
+	fresh := cached != nil && time.Since(cached.stored) < t.ttl
+	t.mutex.Unlock()
+
+	if fresh {
+		t.count(ResultHit)
+		return cached.response(req), nil
+	}
+	outgoing := req
+	if cached != nil && (cached.etag != "" || cached.lastModified != "") {
+		outgoing = req.Clone(req.Context())
+		if cached.etag != "" {
+			outgoing.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+	resp, err := t.next.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.mutex.Lock()
+		cached.stored = time.Now()
+		t.mutex.Unlock()
+		t.count(ResultRevalidated)
+		return cached.response(req), nil
+	}
+	t.count(ResultMiss)
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) <= maxBody {
+		t.store(&entry{
+			key:          key,
+			status:       resp.Status,
+			header:       resp.Header.Clone(),
+			body:         body,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+			stored:       time.Now(),
+		})
+	}
+	return resp, nil
+}
+
+// Stats returns how many cacheable requests ended with each Result* since start.
+func (t *Transport) Stats() map[string]int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	out := make(map[string]int64, len(t.counts))
+	for k, v := range t.counts {
+		out[k] = v
+	}
+	return out
+}
+
+func (t *Transport) count(result string) {
+	t.mutex.Lock()
+	t.counts[result]++
+	t.mutex.Unlock()
+}
+
+func (t *Transport) store(e *entry) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.entries[e.key]; !ok {
+		t.order = append(t.order, e.key)
+	}
+	t.entries[e.key] = e
+	for len(t.order) > t.maxEntries && len(t.order) > 0 {
+		delete(t.entries, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// invalidate drops the entries whose URL is in scope.
+func (t *Transport) invalidate(scope string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	kept := t.order[:0]
+	for _, key := range t.order {
+		if strings.HasPrefix(keyURL(key), scope) {
+			delete(t.entries, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	t.order = kept
+}
+
+func (t *Transport) scope(u *url.URL) string {
+	if t.Scope != nil {
+		return t.Scope(u)
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// cacheKey is the URL and a hash of the credentials, so clients with different accounts never
+// share an entry.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:8]) + " " + req.URL.String()
+}
+
+func keyURL(key string) string {
+	_, u, _ := strings.Cut(key, " ")
+	return u
+}
+
+func (e *entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
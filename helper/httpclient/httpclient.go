@@ -0,0 +1,94 @@
+// Package httpclient builds the outbound HTTP client shared by the Bitbucket client and the AI
+// providers from the http section of the config.
+package httpclient
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+)
+
+// New returns a client with the timeout, proxy, trusted CAs and connection pool of s.
+func New(s model.HTTPSettings) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.Proxy = http.ProxyFromEnvironment
+	if p := strings.TrimSpace(s.Proxy); p != "" {
+		proxyURL, err := url.Parse(p)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid http.proxy %q", s.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if path := strings.TrimSpace(s.CABundle); path != "" {
+		pool, err := certPool(path)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	transport.MaxIdleConns = defaultMaxIdleConns
+	if s.MaxIdleConns > 0 {
+		transport.MaxIdleConns = s.MaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if s.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	}
+	if s.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = s.MaxConnsPerHost
+	}
+
+	log.Infof("HTTP client: timeout=%v proxy=%s caBundle=%s maxIdleConns=%d maxIdleConnsPerHost=%d maxConnsPerHost=%d",
+		s.TimeoutDuration(), describeProxy(s.Proxy), describeOptional(s.CABundle),
+		transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	return &http.Client{Timeout: s.TimeoutDuration(), Transport: transport}, nil
+}
+
+// certPool returns the system CA pool with the certificates of the PEM file at path added.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read http.caBundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		log.Warnf("System CA pool unavailable (%v); trusting only %s", err, path)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("http.caBundle %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// describeProxy names the proxy for the log without the credentials a proxy URL may carry.
+func describeProxy(proxy string) string {
+	if strings.TrimSpace(proxy) == "" {
+		return "from environment"
+	}
+	if u, err := url.Parse(proxy); err == nil {
+		return u.Redacted()
+	}
+	return "(invalid)"
+}
+
+func describeOptional(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package helper
+
+import (
+	"code_nim/model"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Outcomes of the JSON repair pass, counted by JSONRepairStats.
+const (
+	JSONRepairRepaired = "repaired" // the reply parsed after repair
+	JSONRepairFailed   = "failed"   // the reply stayed unparsable
+)
+
+var jsonRepairs = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: map[string]int64{}}
+
+// JSONRepairStats returns how many unparsable AI replies ended with each JSONRepair* outcome
+// since start.
+func JSONRepairStats() map[string]int64 {
+	jsonRepairs.Lock()
+	defer jsonRepairs.Unlock()
+	out := make(map[string]int64, len(jsonRepairs.counts))
+	for k, v := range jsonRepairs.counts {
+		out[k] = v
+	}
+	return out
+}
+
+func countJSONRepair(result string) {
+	jsonRepairs.Lock()
+	jsonRepairs.counts[result]++
+	jsonRepairs.Unlock()
+}
+
+// repairReviewJSON recovers the findings of a review reply that does not parse as JSON. It
+// tries, in order: the largest balanced {"reviews": ...} object in text; text from its first
+// brace with trailing commas removed and unterminated strings, arrays and objects closed; and
+// the complete elements of the "reviews" array. ok is false when none of them yields findings.
+func repairReviewJSON(text string) (items []model.ReviewItem, ok bool) {
+	defer func() {
+		if ok {
+			countJSONRepair(JSONRepairRepaired)
+		} else {
+			countJSONRepair(JSONRepairFailed)
+		}
+	}()
+	best := ""
+	for i := strings.Index(text, "{"); i >= 0; {
+		if rest := strings.TrimLeft(text[i+1:], " \t\r\n"); strings.HasPrefix(rest, `"reviews"`) {
+			if end := balancedEnd(text[i:]); end > len(best) {
+				var resp model.ReviewResponse
+				if json.Unmarshal([]byte(text[i:i+end]), &resp) == nil {
+					best, items = text[i:i+end], resp.Reviews
+				}
+			}
+		}
+		next := strings.Index(text[i+1:], "{")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	if best != "" {
+		return items, true
+	}
+	if i := strings.Index(text, "{"); i >= 0 {
+		var resp model.ReviewResponse
+		if json.Unmarshal([]byte(closeJSON(text[i:])), &resp) == nil {
+			return resp.Reviews, true
+		}
+	}
+	items, _ = completeReviewItems(text)
+	return items, len(items) > 0
+}
+
+// balancedEnd returns the length of the JSON object s starts with, or 0 when it is not closed.
+func balancedEnd(s string) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// closeJSON drops commas before a closing bracket or the end of s and closes an unterminated
+// string and the arrays and objects left open, so that a cut-off or sloppy object may parse.
+func closeJSON(s string) string {
+	var b strings.Builder
+	var open []byte
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{':
+			open = append(open, '}')
+		case ch == '[':
+			open = append(open, ']')
+		case ch == '}' || ch == ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case ch == ',':
+			if rest := strings.TrimLeft(s[i+1:], " \t\r\n"); rest == "" || rest[0] == '}' || rest[0] == ']' {
+				continue
+			}
+		}
+		b.WriteByte(ch)
+	}
+	out := b.String()
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out += `"`
+	}
+	out = strings.TrimRight(out, " \t\r\n")
+	if strings.HasSuffix(out, ":") {
+		out += "null"
+	}
+	out = strings.TrimSuffix(out, ",")
+	for i := len(open) - 1; i >= 0; i-- {
+		out += string(open[i])
+	}
+	return out
+}
+
+// completeReviewItems decodes the elements of the "reviews" array of text up to the first one
+// that is cut off or malformed. complete reports whether the array was closed after them.
+func completeReviewItems(text string) (items []model.ReviewItem, complete bool) {
+	i := strings.Index(text, `"reviews"`)
+	if i < 0 {
+		return nil, false
+	}
+	j := strings.Index(text[i:], "[")
+	if j < 0 {
+		return nil, false
+	}
+	dec := json.NewDecoder(strings.NewReader(text[i+j:]))
+	_, _ = dec.Token()
+	for dec.More() {
+		var item model.ReviewItem
+		if err := dec.Decode(&item); err != nil {
+			return items, false
+		}
+		items = append(items, item)
+	}
+	end, err := dec.Token()
+	return items, err == nil && end == json.Delim(']')
+}
@@ -0,0 +1,117 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key rotation strategies for aiKeys.
+const (
+	KeyRotationRoundRobin = "round-robin"
+	KeyRotationOn429      = "on-429"
+)
+
+const keyCooldown = time.Minute
+
+// keyRing tracks rotation state for one set of API keys; it is shared by every
+// config entry that lists the same keys so their quotas are balanced together.
+type keyRing struct {
+	mutex     sync.Mutex
+	current   int
+	coolUntil map[int]time.Time
+}
+
+var (
+	keyRingsMutex sync.Mutex
+	keyRings      = map[string]*keyRing{}
+)
+
+// apiKeysOf returns the configured keys: aiKeys, else aiKey, else geminiKey.
+func apiKeysOf(cfg *model.AutoReviewPR) []string {
+	var keys []string
+	for _, k := range cfg.AIKeys {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		return keys
+	}
+	if k := strings.TrimSpace(cfg.AIKey); k != "" {
+		return []string{k}
+	}
+	return []string{strings.TrimSpace(cfg.GeminiKey)}
+}
+
+func ringFor(keys []string) *keyRing {
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\x00")))
+	id := hex.EncodeToString(sum[:8])
+	keyRingsMutex.Lock()
+	defer keyRingsMutex.Unlock()
+	ring, ok := keyRings[id]
+	if !ok {
+		ring = &keyRing{coolUntil: map[int]time.Time{}}
+		keyRings[id] = ring
+	}
+	return ring
+}
+
+// pick chooses the key index for the next call, skipping keys that are cooling down
+// after a 429 when another key is available.
+func (r *keyRing) pick(n int, rotation string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	start := r.current % n
+	if rotation != KeyRotationOn429 {
+		// Round-robin: every call moves on to the next key.
+		r.current = (r.current + 1) % n
+	}
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if now.After(r.coolUntil[idx]) {
+			if rotation == KeyRotationOn429 {
+				r.current = idx
+			}
+			return idx
+		}
+	}
+	return start
+}
+
+func (r *keyRing) coolDown(idx, n int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.coolUntil[idx] = time.Now().Add(keyCooldown)
+	if r.current%n == idx {
+		r.current = (idx + 1) % n
+	}
+}
+
+// withAPIKey runs call with a key chosen according to aiKeyRotation. When a key is
+// rate limited it is cooled down and the call is retried with the next key.
+func withAPIKey(cfg *model.AutoReviewPR, call func(apiKey string) error) error {
+	keys := apiKeysOf(cfg)
+	if len(keys) == 1 {
+		return call(keys[0])
+	}
+	rotation := strings.ToLower(strings.TrimSpace(cfg.AIKeyRotation))
+	ring := ringFor(keys)
+	var err error
+	for attempt := 0; attempt < len(keys); attempt++ {
+		idx := ring.pick(len(keys), rotation)
+		err = call(keys[idx])
+		if !errors.Is(err, ErrRateLimited) {
+			return err
+		}
+		ring.coolDown(idx, len(keys))
+		log.Warnf("AI key #%d of %d is rate limited; rotating to the next key", idx+1, len(keys))
+	}
+	return err
+}
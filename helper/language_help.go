@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"path"
+	"strings"
+)
+
+// languagesByExt maps file extensions to the language names used in change stats and prompts.
+var languagesByExt = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin",
+	".rb": "Ruby", ".php": "PHP", ".cs": "C#", ".c": "C", ".h": "C", ".cpp": "C++", ".cc": "C++",
+	".hpp": "C++", ".rs": "Rust", ".swift": "Swift", ".scala": "Scala", ".sh": "Shell", ".bash": "Shell",
+	".sql": "SQL", ".html": "HTML", ".css": "CSS", ".scss": "CSS", ".vue": "Vue", ".dart": "Dart",
+	".tf": "Terraform", ".tfvars": "Terraform", ".yaml": "YAML", ".yml": "YAML", ".json": "JSON",
+	".xml": "XML", ".md": "Markdown", ".txt": "Text",
+}
+
+// FileLanguage names the language of a file from its name, or "Other".
+func FileLanguage(file string) string {
+	base := path.Base(file)
+	if base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") {
+		return "Dockerfile"
+	}
+	if lang, ok := languagesByExt[strings.ToLower(path.Ext(base))]; ok {
+		return lang
+	}
+	return "Other"
+}
+
+// LanguageProfile adapts the inline review prompt to the language of the reviewed file.
+type LanguageProfile struct {
+	Language string
+	Fence    string // Code fence annotation for suggested changes, e.g. "python"
+	Comment  string // Line comment with one %s, used to label Before/After snippets
+	Focus    string // Language-specific review focus; empty for none
+	Example  string // Example review in the prompt's JSON format; empty for none
+}
+
+// Label renders a Before/After label as a comment of the profile's language.
+func (p LanguageProfile) Label(text string) string {
+	return strings.Replace(p.Comment, "%s", text, 1)
+}
+
+// languageProfiles holds the prompt variant of each language; others get a plain fence.
+var languageProfiles = map[string]LanguageProfile{
+	"Go": {Fence: "go", Comment: "// %s",
+		Focus:   "returned errors that are ignored or not wrapped, goroutine and context leaks, defer inside loops, data races on shared state, nil map writes and nil pointer dereferences",
+		Example: `{"lineNumber": 61, "lineText": "+ func matchesEngineID(deploymentName string, engineID string) bool {", "reviewComment": "[Refactor] [Minor] Boundary-safe engine ID matching\nWhy:\n  - strings.Contains(name, suffix- may match unintended names (e.g., dlp vs adlp).\nHow (step-by-step):\n  - Ensure an ID matches only at word/hyphen boundary or end-of-name.\nSuggested change (Before/After):\n~~~go\n// Before\nreturn strings.Contains(deploymentName, engineID+\"-\") || strings.HasSuffix(deploymentName, engineID)\n~~~\n~~~go\n// After\nre := regexp.MustCompile((^|-)" + "" + "" + " + regexp.QuoteMeta(engineID) + "$")\nreturn re.MatchString(deploymentName)\n~~~\nPrompt for AI Agents:\n  - In the file containing matchesEngineID, replace the strings.Contains/HasSuffix logic with a boundary-safe check (regex or equivalent), and keep behavior identical for existing callers."}`},
+	"Python": {Fence: "python", Comment: "# %s",
+		Focus:   "mutable default arguments, bare or overly broad except clauses, resources not closed with a with-block, blocking calls in async code, missing or wrong type hints, and string-built SQL or shell commands",
+		Example: `{"lineNumber": 12, "lineText": "+def add_tag(tag, tags=[]):", "reviewComment": "[Potential issue] [Major] Mutable default argument is shared between calls\nWhy:\n  - The default list is created once, so tags leak from one call into the next.\nHow (step-by-step):\n  - Default to None and create the list inside the function.\nSuggested change (Before/After):\n~~~python\n# Before\ndef add_tag(tag, tags=[]):\n~~~\n~~~python\n# After\ndef add_tag(tag, tags=None):\n    tags = [] if tags is None else tags\n~~~\nPrompt for AI Agents:\n  - In add_tag, replace the mutable default argument with None and initialise the list in the body."}`},
+	"JavaScript": {Fence: "javascript", Comment: "// %s",
+		Focus: "unhandled promise rejections and missing await, loose equality, null/undefined access, unsanitised HTML (XSS), and event listeners or timers that are never cleaned up"},
+	"TypeScript": {Fence: "typescript", Comment: "// %s",
+		Focus: "use of any or non-null assertions that hide type errors, unhandled promise rejections and missing await, null/undefined access, and unsanitised HTML (XSS)"},
+	"Java": {Fence: "java", Comment: "// %s",
+		Focus: "null handling, resources not closed with try-with-resources, equals/hashCode contracts, thread safety of shared state, and swallowed exceptions"},
+	"Kotlin": {Fence: "kotlin", Comment: "// %s", Focus: "unsafe !! assertions, blocking calls inside coroutines, and mutable shared state"},
+	"Ruby":   {Fence: "ruby", Comment: "# %s", Focus: "N+1 queries, mass assignment, and string-built SQL or shell commands"},
+	"PHP":    {Fence: "php", Comment: "// %s", Focus: "SQL injection, unescaped output (XSS), and loose comparisons"},
+	"C#":     {Fence: "csharp", Comment: "// %s", Focus: "IDisposable objects not disposed, async void methods, and null reference risks"},
+	"C":      {Fence: "c", Comment: "// %s", Focus: "buffer overflows, unchecked return values, memory leaks, and use after free"},
+	"C++":    {Fence: "cpp", Comment: "// %s", Focus: "raw owning pointers instead of RAII, undefined behaviour, iterator invalidation, and memory leaks"},
+	"Rust":   {Fence: "rust", Comment: "// %s", Focus: "unwrap/expect on fallible paths, unnecessary clones, and unsafe blocks without a safety argument"},
+	"Swift":  {Fence: "swift", Comment: "// %s", Focus: "force unwraps, retain cycles in closures, and main-thread UI updates"},
+	"Scala":  {Fence: "scala", Comment: "// %s", Focus: "blocking inside futures, partial functions, and mutable shared state"},
+	"Dart":   {Fence: "dart", Comment: "// %s", Focus: "null safety, unawaited futures, and widgets rebuilt more than needed"},
+	"Vue":    {Fence: "vue", Comment: "<!-- %s -->", Focus: "v-html with untrusted data, mutated props, and missing keys in v-for"},
+	"Shell":  {Fence: "bash", Comment: "# %s", Focus: "unquoted variables, missing set -euo pipefail, and unsafe use of eval or temporary files"},
+	"SQL": {Fence: "sql", Comment: "-- %s",
+		Focus:   "UPDATE or DELETE without a WHERE clause, missing indexes for new filters and joins, locking or table rewrites in migrations on large tables, non-reversible migrations, and injection through dynamic SQL",
+		Example: `{"lineNumber": 4, "lineText": "+ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'new';", "reviewComment": "[Potential issue] [Major] Adding a NOT NULL column with a default may rewrite and lock a large table\nWhy:\n  - On older database versions this rewrites every row while holding an exclusive lock.\nHow (step-by-step):\n  - Add the column as nullable, backfill in batches, then add the NOT NULL constraint.\nSuggested change (Before/After):\n~~~sql\n-- Before\nALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'new';\n~~~\n~~~sql\n-- After\nALTER TABLE orders ADD COLUMN status TEXT;\n-- backfill in batches, then:\nALTER TABLE orders ALTER COLUMN status SET NOT NULL;\n~~~\nPrompt for AI Agents:\n  - Split the migration that adds orders.status into add-nullable, batched backfill, and set-not-null steps."}`},
+	"Terraform": {Fence: "hcl", Comment: "# %s",
+		Focus:   "unpinned provider and module versions, resources exposed to 0.0.0.0/0, encryption or logging turned off, hard-coded secrets, and changes that force replacement of stateful resources",
+		Example: `{"lineNumber": 9, "lineText": "+    cidr_blocks = [\"0.0.0.0/0\"]", "reviewComment": "[Potential issue] [Critical] SSH open to the whole internet\nWhy:\n  - Port 22 reachable from 0.0.0.0/0 invites brute-force attacks.\nHow (step-by-step):\n  - Restrict the ingress rule to the VPN or bastion CIDR passed in as a variable.\nSuggested change (Before/After):\n~~~hcl\n# Before\ncidr_blocks = [\"0.0.0.0/0\"]\n~~~\n~~~hcl\n# After\ncidr_blocks = [var.admin_cidr]\n~~~\nPrompt for AI Agents:\n  - In the security group ingress rule for port 22, replace 0.0.0.0/0 with a variable holding the admin CIDR."}`},
+	"Dockerfile": {Fence: "dockerfile", Comment: "# %s", Focus: "unpinned base images, running as root, secrets in build layers, and package caches left in the image"},
+	"YAML":       {Fence: "yaml", Comment: "# %s", Focus: "indentation and type mistakes (unquoted yes/no/on/off, numbers), and for Kubernetes manifests missing resource limits, :latest image tags, and privileged security contexts"},
+	"JSON":       {Fence: "json", Comment: "%s", Focus: "invalid structure, duplicated keys, and literal secrets"},
+	"HTML":       {Fence: "html", Comment: "<!-- %s -->", Focus: "accessibility (labels, alt text), inline scripts, and untrusted content"},
+	"CSS":        {Fence: "css", Comment: "/* %s */"},
+	"XML":        {Fence: "xml", Comment: "<!-- %s -->"},
+	"Markdown":   {Fence: "markdown", Comment: "<!-- %s -->", Focus: "broken links, outdated instructions, and commands that no longer match the code"},
+}
+
+// LanguageProfileFor returns the prompt variant for a file, detected from its name.
+func LanguageProfileFor(file string) LanguageProfile {
+	lang := FileLanguage(file)
+	p, ok := languageProfiles[lang]
+	if !ok {
+		p = LanguageProfile{Comment: "%s"}
+	}
+	p.Language = lang
+	return p
+}
@@ -0,0 +1,199 @@
+package leader
+
+import (
+	"bytes"
+	"code_nim/model"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// leaseTimeFormat is the Kubernetes MicroTime format used by Lease acquire/renew times.
+const leaseTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// KubernetesLease is a Lock backed by a coordination.k8s.io/v1 Lease, using the pod's service
+// account. The account needs get, create and update on leases in its namespace.
+type KubernetesLease struct {
+	url       string // the Lease collection URL
+	name      string
+	namespace string
+	tokenFile string
+	client    *http.Client
+}
+
+type lease struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Metadata   leaseMeta `json:"metadata"`
+	Spec       leaseSpec `json:"spec"`
+}
+
+type leaseMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+func NewKubernetesLease(settings model.KubernetesLeaseSettings) (*KubernetesLease, error) {
+	namespace := settings.Namespace
+	if namespace == "" {
+		raw, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes lease: namespace not configured and not running in a pod: %w", err)
+		}
+		namespace = strings.TrimSpace(string(raw))
+	}
+	name := settings.LeaseName
+	if name == "" {
+		name = "code-nim"
+	}
+	server := strings.TrimRight(settings.APIServer, "/")
+	if server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes lease: apiServer not configured and KUBERNETES_SERVICE_HOST/PORT unset")
+		}
+		server = "https://" + net.JoinHostPort(host, port)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca, err := os.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		tlsConfig.RootCAs = pool
+	}
+	return &KubernetesLease{
+		url:       fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", server, namespace),
+		name:      name,
+		namespace: namespace,
+		tokenFile: serviceAccountDir + "/token",
+		client:    &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}, nil
+}
+
+func (k *KubernetesLease) TryAcquire(identity string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	current, err := k.get()
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		created := lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMeta{Name: k.name, Namespace: k.namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       identity,
+				LeaseDurationSeconds: int(ttl.Seconds()),
+				AcquireTime:          now.Format(leaseTimeFormat),
+				RenewTime:            now.Format(leaseTimeFormat),
+			},
+		}
+		return k.write(http.MethodPost, k.url, created)
+	}
+
+	spec := &current.Spec
+	if spec.HolderIdentity != identity && spec.HolderIdentity != "" && !leaseExpired(spec, now) {
+		return false, nil
+	}
+	if spec.HolderIdentity != identity {
+		spec.HolderIdentity = identity
+		spec.AcquireTime = now.Format(leaseTimeFormat)
+		spec.LeaseTransitions++
+	}
+	spec.LeaseDurationSeconds = int(ttl.Seconds())
+	spec.RenewTime = now.Format(leaseTimeFormat)
+	// The resourceVersion makes the update fail with 409 if another replica wrote the lease first.
+	return k.write(http.MethodPut, k.url+"/"+k.name, *current)
+}
+
+func leaseExpired(spec *leaseSpec, now time.Time) bool {
+	renewed, err := time.Parse(time.RFC3339Nano, spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(spec.LeaseDurationSeconds) * time.Second))
+}
+
+func (k *KubernetesLease) Release(identity string) error {
+	current, err := k.get()
+	if err != nil || current == nil || current.Spec.HolderIdentity != identity {
+		return err
+	}
+	current.Spec.HolderIdentity = ""
+	_, err = k.write(http.MethodPut, k.url+"/"+k.name, *current)
+	return err
+}
+
+// get returns the lease, or nil when it does not exist yet.
+func (k *KubernetesLease) get() (*lease, error) {
+	resp, err := k.do(http.MethodGet, k.url+"/"+k.name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get lease %s/%s: status %d: %s", k.namespace, k.name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var l lease
+	if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// write creates or updates the lease; a conflict means another replica won the race.
+func (k *KubernetesLease) write(method, url string, l lease) (bool, error) {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return false, err
+	}
+	resp, err := k.do(method, url, body)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return false, nil
+	case resp.StatusCode >= 300:
+		raw, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("%s lease %s/%s: status %d: %s", method, k.namespace, k.name, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return l.Spec.HolderIdentity != "", nil
+}
+
+func (k *KubernetesLease) do(method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The projected token is rotated by the kubelet, so read it on every call.
+	if token, err := os.ReadFile(k.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	return k.client.Do(req)
+}
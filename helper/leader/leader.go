@@ -0,0 +1,158 @@
+package leader
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lock is a lease that at most one identity holds at a time.
+type Lock interface {
+	// TryAcquire acquires the lease for identity, or renews it when identity already holds it,
+	// and reports whether identity holds the lease for the next ttl.
+	TryAcquire(identity string, ttl time.Duration) (bool, error)
+	// Release gives the lease up if identity holds it.
+	Release(identity string) error
+}
+
+// Elector keeps trying to hold a Lock and reports whether this replica is the leader.
+type Elector struct {
+	lock     Lock
+	identity string
+	ttl      time.Duration
+	interval time.Duration
+
+	mutex     sync.Mutex
+	leader    bool
+	renewedAt time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New returns an Elector for the configured backend, or nil when leader election is disabled.
+func New(settings model.LeaderElectionSettings) (*Elector, error) {
+	backend := strings.ToLower(strings.TrimSpace(settings.Backend))
+	if backend == "" {
+		return nil, nil
+	}
+	var lock Lock
+	var err error
+	switch backend {
+	case model.LeaderBackendKubernetes:
+		lock, err = NewKubernetesLease(settings.Kubernetes)
+	case model.LeaderBackendRedis:
+		lock, err = NewRedisLock(settings.Redis)
+	default:
+		err = fmt.Errorf("unknown leader election backend %q", settings.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity := settings.Identity
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("leader identity: %w", err)
+		}
+	}
+	ttl := parseDuration(settings.LeaseDuration, 15*time.Second)
+	interval := parseDuration(settings.RenewInterval, 5*time.Second)
+	if interval >= ttl {
+		return nil, fmt.Errorf("leader renewInterval (%v) must be shorter than leaseDuration (%v)", interval, ttl)
+	}
+	return &Elector{lock: lock, identity: identity, ttl: ttl, interval: interval}, nil
+}
+
+func parseDuration(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// Start runs the election loop in the background until Stop is called.
+func (e *Elector) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	log.Infof("Leader election started as %s (lease %v, renew every %v)", e.identity, e.ttl, e.interval)
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			e.tick()
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// tick acquires or renews the lease. A leader that cannot renew steps down before its lease
+// can expire, so two replicas never both believe they lead.
+func (e *Elector) tick() {
+	held, err := e.lock.TryAcquire(e.identity, e.ttl)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if err != nil {
+		log.Warnf("Leader election: %v", err)
+		if e.leader && time.Since(e.renewedAt) > e.ttl-e.interval {
+			e.leader = false
+			log.Warnf("Stepping down as leader: lease could not be renewed")
+		}
+		return
+	}
+	if held {
+		e.renewedAt = time.Now()
+	}
+	if held != e.leader {
+		e.leader = held
+		if held {
+			log.Infof("Became leader as %s; scheduled reviews run on this replica", e.identity)
+		} else {
+			log.Infof("Lost leadership; scheduled reviews are skipped on this replica")
+		}
+	}
+}
+
+// IsLeader reports whether this replica currently holds the lease. A nil Elector always leads.
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
+
+// Identity returns the name this replica competes under.
+func (e *Elector) Identity() string {
+	if e == nil {
+		return ""
+	}
+	return e.identity
+}
+
+// Stop ends the election loop and releases the lease so another replica can take over at once.
+func (e *Elector) Stop() {
+	if e == nil || e.stop == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	e.mutex.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	e.mutex.Unlock()
+	if wasLeader {
+		if err := e.lock.Release(e.identity); err != nil {
+			log.Warnf("Failed to release leader lease: %v", err)
+		}
+	}
+}
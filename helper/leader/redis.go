@@ -0,0 +1,52 @@
+package leader
+
+import (
+	"code_nim/helper/redis"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// acquireScript sets the key to the caller's identity when it is free, or extends it when the
+// caller already holds it. Running it as one script keeps check-and-set atomic.
+const acquireScript = `local v = redis.call('GET', KEYS[1])
+if v == ARGV[1] then redis.call('PEXPIRE', KEYS[1], ARGV[2]) return 1 end
+if not v then redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2]) return 1 end
+return 0`
+
+const releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+
+// RedisLock is a Lock stored as a Redis key with an expiry.
+type RedisLock struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRedisLock(settings model.RedisLockSettings) (*RedisLock, error) {
+	if settings.Addr == "" {
+		return nil, errors.New("redis lock: addr is required")
+	}
+	key := settings.Key
+	if key == "" {
+		key = "code-nim:leader"
+	}
+	return &RedisLock{client: redis.NewClient(settings.Addr, settings.Password, settings.DB, settings.TLS), key: key}, nil
+}
+
+func (r *RedisLock) TryAcquire(identity string, ttl time.Duration) (bool, error) {
+	reply, err := r.client.Do("EVAL", acquireScript, "1", r.key, identity, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, fmt.Errorf("redis lock: %w", err)
+	}
+	return reply == int64(1), nil
+}
+
+func (r *RedisLock) Release(identity string) error {
+	if _, err := r.client.Do("EVAL", releaseScript, "1", r.key, identity); err != nil {
+		return fmt.Errorf("redis lock: %w", err)
+	}
+	return nil
+}
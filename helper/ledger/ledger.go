@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"sync"
+	"time"
+)
+
+// Ledger remembers which notifications were delivered so the same message is not sent twice,
+// even across restarts or when a review is retried. Records are kept for the retention window.
+type Ledger struct {
+	store  storage.Storage
+	window time.Duration
+
+	mutex      sync.Mutex
+	loaded     bool
+	sent       map[string]time.Time
+	inFlight   map[string]bool
+	suppressed map[string]int64 // by channel
+	prunedAt   time.Time
+}
+
+// New returns a Ledger backed by store that remembers deliveries for retentionDays (default 30).
+func New(store storage.Storage, retentionDays int) *Ledger {
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+	return &Ledger{
+		store:      store,
+		window:     time.Duration(retentionDays) * 24 * time.Hour,
+		sent:       map[string]time.Time{},
+		inFlight:   map[string]bool{},
+		suppressed: map[string]int64{},
+	}
+}
+
+// load reads the deliveries of the retention window once. Callers hold the mutex.
+func (l *Ledger) load() {
+	if l.loaded || l.store == nil {
+		return
+	}
+	records, err := l.store.ListNotifications(time.Now().Add(-l.window))
+	if err != nil {
+		// Retry on the next delivery rather than dropping what was already sent.
+		log.Errorf("Failed to load sent notifications: %v", err)
+		return
+	}
+	for _, r := range records {
+		l.sent[r.Key] = r.SentAt
+	}
+	l.loaded = true
+	log.Debugf("Loaded %d sent notifications", len(records))
+}
+
+// prune forgets deliveries older than the retention window, at most hourly, and deletes their
+// stored records. Callers hold the mutex.
+func (l *Ledger) prune() {
+	if time.Since(l.prunedAt) < time.Hour {
+		return
+	}
+	l.prunedAt = time.Now()
+	cutoff := l.prunedAt.Add(-l.window)
+	for key, at := range l.sent {
+		if at.Before(cutoff) {
+			delete(l.sent, key)
+		}
+	}
+	if l.store == nil {
+		return
+	}
+	if purged, err := l.store.PurgeNotifications(cutoff); err != nil {
+		log.Errorf("Failed to purge sent notifications: %v", err)
+	} else if purged > 0 {
+		log.Infof("Purged %d sent notifications older than %s", purged, l.window)
+	}
+}
+
+// Deliver calls send unless n was already delivered or is being delivered right now, and records
+// n once send succeeds. It reports whether send was called and succeeded. A nil Ledger always sends.
+func (l *Ledger) Deliver(n model.Notification, send func() error) (bool, error) {
+	if l == nil {
+		err := send()
+		return err == nil, err
+	}
+	key := n.Key()
+	l.mutex.Lock()
+	l.load()
+	l.prune()
+	if _, ok := l.sent[key]; ok || l.inFlight[key] {
+		l.suppressed[n.Channel]++
+		l.mutex.Unlock()
+		log.Debugf("Suppressed duplicate %s notification %q for %s/%s PR #%d", n.Channel, n.Subject, n.Workspace, n.RepoSlug, n.PullRequestID)
+		return false, nil
+	}
+	l.inFlight[key] = true
+	l.mutex.Unlock()
+
+	err := send()
+	now := time.Now()
+	l.mutex.Lock()
+	delete(l.inFlight, key)
+	if err == nil {
+		l.sent[key] = now
+	}
+	l.mutex.Unlock()
+	if err != nil {
+		return false, err
+	}
+	if l.store != nil {
+		if err := l.store.SaveNotification(model.SentNotification{Key: key, Notification: n, SentAt: now}); err != nil {
+			log.Errorf("Failed to record %s notification for PR #%d: %v", n.Channel, n.PullRequestID, err)
+		}
+	}
+	return true, nil
+}
+
+// Suppressed returns how many duplicate notifications were suppressed per channel.
+func (l *Ledger) Suppressed() map[string]int64 {
+	out := map[string]int64{}
+	if l == nil {
+		return out
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for k, v := range l.suppressed {
+		out[k] = v
+	}
+	return out
+}
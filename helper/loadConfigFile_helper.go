@@ -1,14 +1,35 @@
 package helper
 
 import (
+	"code_nim/helper/sops"
 	"code_nim/log"
 	"code_nim/model"
+	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
 )
 
+// ConfigFilePath is where the service reads its configuration.
+const ConfigFilePath = "config_file/review-config.yaml"
+
+// ReadConfigFile returns the contents of the config file, decrypted when it is SOPS-encrypted.
+func ReadConfigFile() ([]byte, error) {
+	f, err := os.ReadFile(ConfigFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if !sops.IsEncrypted(f) {
+		return f, nil
+	}
+	plain, err := sops.Decrypt(f)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", ConfigFilePath, err)
+	}
+	return plain, nil
+}
+
 func LoadConfigFile(cfg *model.Task) {
-	f, err := os.ReadFile("config_file/review-config.yaml")
+	f, err := ReadConfigFile()
 	if err != nil {
 		log.Error(err)
 	}
@@ -16,5 +37,14 @@ func LoadConfigFile(cfg *model.Task) {
 	err = yaml.Unmarshal(f, &cfg)
 	if err != nil {
 		log.Error(err)
+		return
+	}
+	for _, err := range ApplyCredentialProfiles(cfg) {
+		log.Error(err)
+	}
+
+	warnings, _ := LintConfig(f)
+	for _, w := range warnings {
+		log.Warnf("Config %s at %s (line %d): %s", w.Kind, w.Path, w.Line, w.Message)
 	}
 }
@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"code_nim/log"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LanguageEnglish is the default comment language.
+const LanguageEnglish = "en"
+
+// commentLanguages are the languages the AI can be asked to write comments in, by ISO 639-1 code.
+var commentLanguages = map[string]string{
+	"en": "English", "vi": "Vietnamese", "ja": "Japanese", "ko": "Korean", "zh": "Simplified Chinese",
+	"fr": "French", "de": "German", "es": "Spanish", "pt": "Portuguese", "id": "Indonesian", "th": "Thai",
+}
+
+// commentLocale holds the translations of the fixed headers of a language. Headers without a
+// translation stay in English.
+type commentLocale struct {
+	headers           map[string]string // English header -> translation
+	summaryTitle      string
+	summaryTitleSince string // format with the short hash of the last reviewed commit
+	feedback          string // feedback line of the comment footer
+}
+
+// commentLocales are the languages whose fixed headers are translated when comments are posted.
+var commentLocales = map[string]commentLocale{
+	"vi": {
+		headers: map[string]string{
+			"Why:":                             "Lý do:",
+			"How (step-by-step):":              "Cách khắc phục (từng bước):",
+			"Suggested change (Before/After):": "Đề xuất thay đổi (Trước/Sau):",
+			"Prompt for AI Agents (optional):": "Prompt cho AI Agent (tùy chọn):",
+			"Prompt for AI Agents:":            "Prompt cho AI Agent:",
+			"Fix:":                             "Cách sửa:",
+			"## Summary":                       "## Tóm tắt",
+			"## Breaking Changes":              "## Thay đổi không tương thích",
+			"## API Contract Changes":          "## Thay đổi hợp đồng API",
+			"## Walkthrough":                   "## Diễn giải",
+			"## Changes":                       "## Thay đổi",
+			"## Sequence Flow":                 "## Luồng xử lý",
+			"**New Features**":                 "**Tính năng mới**",
+			"**Bug Fixes**":                    "**Sửa lỗi**",
+			"**Documentation**":                "**Tài liệu**",
+			"**Refactor**":                     "**Tái cấu trúc**",
+			"**Performance**":                  "**Hiệu năng**",
+			"**Tests**":                        "**Kiểm thử**",
+			"**Chores**":                       "**Công việc khác**",
+			"## Suggested reviewers":           "## Người review đề xuất",
+			"## What":                          "## Thay đổi gì",
+			"## Why":                           "## Lý do",
+			"## How":                           "## Cách thực hiện",
+			"## Test plan":                     "## Kế hoạch kiểm thử",
+		},
+		summaryTitle:      "Tóm tắt bởi Nim",
+		summaryTitleSince: "Tóm tắt bởi Nim (các commit mới kể từ %s)",
+		feedback:          "Nhận xét này có hữu ích không? Trả lời 👍 / 👎 cho bình luận này.",
+	},
+	"ja": {
+		headers: map[string]string{
+			"Why:":                             "理由:",
+			"How (step-by-step):":              "対応手順:",
+			"Suggested change (Before/After):": "修正案 (Before/After):",
+			"Prompt for AI Agents (optional):": "AIエージェント向けプロンプト (任意):",
+			"Prompt for AI Agents:":            "AIエージェント向けプロンプト:",
+			"Fix:":                             "修正:",
+			"## Summary":                       "## 概要",
+			"## Breaking Changes":              "## 破壊的変更",
+			"## API Contract Changes":          "## API契約の変更",
+			"## Walkthrough":                   "## 解説",
+			"## Changes":                       "## 変更点",
+			"## Sequence Flow":                 "## 処理の流れ",
+			"**New Features**":                 "**新機能**",
+			"**Bug Fixes**":                    "**バグ修正**",
+			"**Documentation**":                "**ドキュメント**",
+			"**Refactor**":                     "**リファクタリング**",
+			"**Performance**":                  "**パフォーマンス**",
+			"**Tests**":                        "**テスト**",
+			"**Chores**":                       "**その他**",
+			"## Suggested reviewers":           "## 推奨レビュアー",
+			"## What":                          "## 変更内容",
+			"## Why":                           "## 背景",
+			"## How":                           "## 実装方針",
+			"## Test plan":                     "## テスト計画",
+		},
+		summaryTitle:      "Nim による概要",
+		summaryTitleSince: "Nim による概要 (%s 以降の新しいコミット)",
+		feedback:          "このコメントは役に立ちましたか？ 👍 / 👎 で返信してください。",
+	},
+}
+
+// findingHeaders, summaryHeaders and descriptionHeaders are the English headers translated in
+// findings, summaries and generated pull request descriptions.
+var (
+	findingHeaders = []string{"Why:", "How (step-by-step):", "Suggested change (Before/After):", "Prompt for AI Agents (optional):", "Prompt for AI Agents:", "Fix:"}
+	summaryHeaders = []string{"## Summary", "## Breaking Changes", "## API Contract Changes", "## Walkthrough", "## Changes", "## Sequence Flow", "**New Features**", "**Bug Fixes**",
+		"**Documentation**", "**Refactor**", "**Performance**", "**Tests**", "**Chores**", "## Suggested reviewers"}
+	descriptionHeaders = []string{"## What", "## Why", "## How", "## Test plan"}
+)
+
+// NormalizeLanguage lowercases the configured language code and falls back to English for
+// unknown codes.
+func NormalizeLanguage(language string) string {
+	l := strings.ToLower(strings.TrimSpace(language))
+	if l == "" {
+		return LanguageEnglish
+	}
+	if _, ok := commentLanguages[l]; !ok {
+		log.Warnf("Unknown comment language %q; using English", language)
+		return LanguageEnglish
+	}
+	return l
+}
+
+// ValidLanguage reports whether language is empty or a supported language code.
+func ValidLanguage(language string) bool {
+	l := strings.ToLower(strings.TrimSpace(language))
+	_, ok := commentLanguages[l]
+	return l == "" || ok
+}
+
+// reviewLanguageInstructions returns the prompt rules for inline comments in language. The
+// tags and headers stay in English so findings can still be parsed; headers are translated
+// when the comment is posted.
+func reviewLanguageInstructions(language string) string {
+	l := NormalizeLanguage(language)
+	if l == LanguageEnglish {
+		return ""
+	}
+	return fmt.Sprintf(`Language: write every reviewComment in %s.
+- Keep the JSON keys, the [Type] and [Severity] tags, and the headers "Why:", "How (step-by-step):", "Suggested change (Before/After):", "Prompt for AI Agents" and "Fix:" exactly in English.
+- Keep code, identifiers, file paths and lineText unchanged.
+
+`, commentLanguages[l])
+}
+
+// summaryLanguageInstructions returns the prompt rules for the PR summary in language.
+func summaryLanguageInstructions(language string) string {
+	l := NormalizeLanguage(language)
+	if l == LanguageEnglish {
+		return ""
+	}
+	return fmt.Sprintf(`Language: write all prose (bullet items, walkthrough, table descriptions, diagram labels) in %s.
+- Keep the "##" section headings and the bold group names exactly in English; they are translated afterwards.
+- Keep code, identifiers and file paths unchanged.
+
+`, commentLanguages[l])
+}
+
+// localize replaces each header of headers that starts a line of body (after Markdown
+// markup) with its translation in language. Prose mentioning a header is left alone.
+func localize(body, language string, headers []string) string {
+	locale, ok := commentLocales[NormalizeLanguage(language)]
+	if !ok {
+		return body
+	}
+	for _, h := range headers {
+		if t, ok := locale.headers[h]; ok {
+			re := regexp.MustCompile(`(?m)^([\s*_>#-]*)` + regexp.QuoteMeta(h))
+			body = re.ReplaceAllString(body, "${1}"+strings.ReplaceAll(t, "$", "$$"))
+		}
+	}
+	return body
+}
+
+// LocalizeFinding translates the fixed headers ("Why:", "How (step-by-step):", ...) of a
+// formatted inline comment body into language.
+func LocalizeFinding(body, language string) string {
+	return localize(body, language, findingHeaders)
+}
+
+// LocalizeSummary translates the section headings and group names of a formatted summary.
+func LocalizeSummary(body, language string) string {
+	return localize(body, language, summaryHeaders)
+}
+
+// LocalizeDescription translates the section headings of a generated pull request description.
+func LocalizeDescription(body, language string) string {
+	return localize(body, language, descriptionHeaders)
+}
+
+// SummaryTitle returns the title line of a summary comment in language, noting the last
+// reviewed commit when sinceHash is set.
+func SummaryTitle(language, sinceHash string) string {
+	locale, ok := commentLocales[NormalizeLanguage(language)]
+	switch {
+	case ok && sinceHash != "":
+		return fmt.Sprintf(locale.summaryTitleSince, sinceHash)
+	case ok:
+		return locale.summaryTitle
+	case sinceHash != "":
+		return fmt.Sprintf("Summary by Nim (new commits since %s)", sinceHash)
+	}
+	return "Summary by Nim"
+}
+
+// FeedbackLine returns the "Was this helpful?" line of the comment footer in language.
+func FeedbackLine(language string) string {
+	if locale, ok := commentLocales[NormalizeLanguage(language)]; ok && locale.feedback != "" {
+		return locale.feedback
+	}
+	return "Was this helpful? Reply 👍 / 👎 to this comment."
+}
@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"strings"
+)
+
+// SkipRouted is the reason of the files a model route with skipAI leaves out of inline review.
+const SkipRouted = "routed past AI review"
+
+// ModelRouteFor returns the name and settings of the first of cfg.ModelRoutes that a file with
+// lines diff lines matches, or nil when none does. Unnamed routes are named by position.
+func ModelRouteFor(cfg *model.AutoReviewPR, filePath string, lines int) (string, *model.ModelRoute) {
+	for i := range cfg.ModelRoutes {
+		route := &cfg.ModelRoutes[i]
+		if len(route.Paths) > 0 && !IsCriticalPath(route.Paths, filePath) {
+			continue
+		}
+		if route.MinLines > 0 && lines < route.MinLines || route.MaxLines > 0 && lines > route.MaxLines {
+			continue
+		}
+		if route.Critical && !IsCriticalPath(cfg.CriticalPaths, filePath) {
+			continue
+		}
+		name := strings.TrimSpace(route.Name)
+		if name == "" {
+			name = fmt.Sprintf("modelRoutes[%d]", i)
+		}
+		return name, route
+	}
+	return "", nil
+}
+
+// RouteConfig returns the configuration the hunks of filePath are reviewed with: cfg with the
+// model of its model route, or cfg itself when no route picks a model for the file.
+func RouteConfig(cfg *model.AutoReviewPR, filePath string, hunks []map[string]interface{}) *model.AutoReviewPR {
+	name, route := ModelRouteFor(cfg, filePath, DiffLineCount(map[string]interface{}{"hunks": hunks}))
+	if route == nil || route.SkipAI || strings.TrimSpace(route.AIModel) == "" {
+		return cfg
+	}
+	log.Debugf("Model route %s reviews %s with %s", name, filePath, strings.TrimSpace(route.AIModel))
+	c := *cfg
+	withAIModel(&c, route.AIModel)
+	return &c
+}
+
+// withAIModel switches cfg to another model of its provider; Azure OpenAI names the model by
+// its deployment.
+func withAIModel(cfg *model.AutoReviewPR, aiModel string) {
+	cfg.AIModel = strings.TrimSpace(aiModel)
+	if strings.EqualFold(strings.TrimSpace(cfg.AIProvider), "azure-openai") {
+		cfg.AzureDeployment = cfg.AIModel
+	}
+}
+
+// SkipsAI reports whether the model routes of cfg leave every file changed in diff out of
+// inline review, so that the pull request needs no AI call at all. Files left out for another
+// reason, such as lockfiles, count as long as a route leaves out one of them.
+func SkipsAI(cfg *model.AutoReviewPR, diff string) bool {
+	if len(cfg.ModelRoutes) == 0 {
+		return false
+	}
+	routed := false
+	for _, file := range ParseDiff(diff) {
+		reason := SkippedFileReason(cfg, file)
+		if reason == "" {
+			return false
+		}
+		routed = routed || strings.HasPrefix(reason, SkipRouted)
+	}
+	return routed
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+func init() {
+	Register("email", func(s model.NotifierSettings, _ *http.Client) (Notifier, error) {
+		if s.SMTP.Host == "" || s.SMTP.From == "" || len(s.SMTP.To) == 0 {
+			return nil, errors.New("email notifier needs smtp.host, smtp.from and smtp.to")
+		}
+		return emailNotifier{smtp: s.SMTP}, nil
+	})
+}
+
+// emailNotifier sends a plain-text mail through an SMTP server.
+type emailNotifier struct {
+	smtp model.SMTPSettings
+}
+
+func (n emailNotifier) Notify(e model.NotificationEvent) error {
+	return SendMail(n.smtp, "[code-nim] "+headline(e), text(e))
+}
+
+// SendMail sends a plain-text mail to the recipients of s, with STARTTLS when the server offers
+// it and PLAIN authentication when s has a username.
+func SendMail(s model.SMTPSettings, subject, body string) error {
+	if s.Host == "" || s.From == "" || len(s.To) == 0 {
+		return errors.New("smtp.host, smtp.from and smtp.to are required")
+	}
+	port := s.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + strings.Join(s.To, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		strings.ReplaceAll(body, "\n", "\r\n")
+	return smtp.SendMail(fmt.Sprintf("%s:%d", s.Host, port), auth, s.From, s.To, []byte(msg))
+}
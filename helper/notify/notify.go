@@ -0,0 +1,256 @@
+// Package notify sends review events of pull requests to chat, mail and webhook targets.
+// Each notifier type registers a factory; entries pick their targets and event kinds with
+// the notifiers list of their config.
+package notify
+
+import (
+	"bytes"
+	"code_nim/helper"
+	"code_nim/helper/ledger"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers events to one target.
+type Notifier interface {
+	Notify(e model.NotificationEvent) error
+}
+
+// Factory builds the notifier of settings, sending HTTP requests with client.
+type Factory func(s model.NotifierSettings, client *http.Client) (Notifier, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Factory{}
+)
+
+// Register makes a notifier type available under name, replacing an earlier registration.
+func Register(name string, f Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[strings.ToLower(name)] = f
+}
+
+// Types returns the registered notifier types, sorted.
+func Types() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the notifier of s with the factory registered for s.Type.
+func New(s model.NotifierSettings, client *http.Client) (Notifier, error) {
+	registryMutex.RLock()
+	f, ok := registry[strings.ToLower(strings.TrimSpace(s.Type))]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type %q (known: %s)", s.Type, strings.Join(Types(), ", "))
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return f(s, client)
+}
+
+// Dispatcher sends events to the notifiers of an entry. Deliveries are recorded in Ledger, so
+// a retried review does not notify twice. A nil Dispatcher sends nothing.
+type Dispatcher struct {
+	Client *http.Client
+	Ledger *ledger.Ledger
+}
+
+// Send delivers e to each of targets that wants its kind. A critical_finding event carries the
+// new findings of any severity; each target gets those at or above its MinSeverity, and none
+// when there are no such findings. Failures are logged, not returned: a notification never
+// fails the review.
+func (d *Dispatcher) Send(targets []model.NotifierSettings, e model.NotificationEvent) {
+	if d == nil {
+		return
+	}
+	all := e
+	for i, s := range targets {
+		if !s.Wants(e.Kind) {
+			continue
+		}
+		e := all
+		if e.Kind == model.NotifyCriticalFinding {
+			if e = atLeast(e, s.MinSeverity); len(e.Findings) == 0 {
+				continue
+			}
+		}
+		n, err := New(s, d.Client)
+		if err != nil {
+			log.Errorf("Notifier %d of %s: %v", i, e.ProcessName, err)
+			continue
+		}
+		note := model.Notification{
+			Channel:       "notifier:" + strings.ToLower(s.Type) + fmt.Sprintf(":%d", i),
+			Workspace:     e.Workspace,
+			RepoSlug:      e.RepoSlug,
+			PullRequestID: e.PullRequestID,
+			Commit:        e.Commit,
+			Subject:       e.Kind,
+		}
+		if e.Package != "" {
+			// Package profiles number their notifiers on their own
+			note.Channel += ":" + e.Package
+		}
+		switch {
+		case e.Kind == model.NotifyCriticalFinding:
+			// Later commits may add critical findings of their own
+			note.Subject += ":" + findingsKey(e.Findings)
+		case e.Commit == "":
+			// Not tied to a commit, so a later event is news rather than a retry
+			note.Subject += ":" + time.Now().UTC().Format(time.RFC3339)
+		}
+		if _, err := d.Ledger.Deliver(note, func() error { return n.Notify(e) }); err != nil {
+			log.Errorf("Failed to send %s notification for PR #%d to %s: %v", e.Kind, e.PullRequestID, s.Type, err)
+		}
+	}
+}
+
+// atLeast keeps the rated findings of e at or above minSeverity (default "Critical") and
+// recounts its severities.
+func atLeast(e model.NotificationEvent, minSeverity string) model.NotificationEvent {
+	if !helper.ValidSeverity(minSeverity) || helper.SeverityRank(minSeverity) == 0 {
+		minSeverity = "Critical"
+	}
+	min := helper.SeverityRank(minSeverity)
+	var kept []model.NotifiedFinding
+	counts := map[string]int{}
+	for _, f := range e.Findings {
+		if helper.ValidSeverity(f.Severity) && helper.SeverityRank(f.Severity) >= min {
+			kept = append(kept, f)
+			counts[f.Severity]++
+		}
+	}
+	e.Findings, e.Severities = kept, counts
+	return e
+}
+
+// findingsKey identifies a set of findings for the delivery ledger.
+func findingsKey(findings []model.NotifiedFinding) string {
+	keys := make([]string, len(findings))
+	for i, f := range findings {
+		keys[i] = fmt.Sprintf("%s:%d:%s", f.Path, f.Line, f.Title)
+	}
+	sort.Strings(keys)
+	return model.Notification{Subject: strings.Join(keys, "\n")}.Key()
+}
+
+// postJSON sends payload to url and fails on a non-2xx response.
+func postJSON(client *http.Client, url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %d: %s", url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// headline returns the one-line description of e used by the chat and mail notifiers, naming
+// the package profile of a package event.
+func headline(e model.NotificationEvent) string {
+	if e.Package != "" {
+		return kindHeadline(e) + " (" + e.Package + ")"
+	}
+	return kindHeadline(e)
+}
+
+func kindHeadline(e model.NotificationEvent) string {
+	switch e.Kind {
+	case model.NotifyCriticalFinding:
+		n := len(e.Findings)
+		return fmt.Sprintf("%d critical %s on PR #%d in %s/%s", n, helper.Pluralize(n, "finding", "findings"), e.PullRequestID, e.Workspace, e.RepoSlug)
+	case model.NotifyAIFailure:
+		return fmt.Sprintf("AI review failed on PR #%d in %s/%s", e.PullRequestID, e.Workspace, e.RepoSlug)
+	case model.NotifyCircuitOpen:
+		return fmt.Sprintf("Reviews of %s/%s paused: Bitbucket keeps failing", e.Workspace, e.RepoSlug)
+	case model.NotifyCriticalPath:
+		n := len(e.Files)
+		return fmt.Sprintf("PR #%d in %s/%s changes %d critical %s", e.PullRequestID, e.Workspace, e.RepoSlug, n, helper.Pluralize(n, "file", "files"))
+	default:
+		return fmt.Sprintf("Reviewed PR #%d in %s/%s", e.PullRequestID, e.Workspace, e.RepoSlug)
+	}
+}
+
+// severityLine renders counts as "1 Critical, 2 Major", most severe first, or "no open findings".
+func severityLine(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no open findings"
+	}
+	parts := make([]string, 0, len(counts))
+	for _, s := range severityOrder(counts) {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// severityOrder returns the severities of counts, most severe first.
+func severityOrder(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for s := range counts {
+		names = append(names, s)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := helper.SeverityRank(names[i]), helper.SeverityRank(names[j]); ri != rj {
+			return ri > rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// text renders e as plain text: the headline, the pull request and the details of its kind.
+func text(e model.NotificationEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", headline(e))
+	if e.PullRequestID != 0 {
+		fmt.Fprintf(&b, "%s by %s\n", e.Title, e.Author)
+	}
+	fmt.Fprintf(&b, "%s\n", e.URL)
+	switch e.Kind {
+	case model.NotifyCriticalFinding:
+		for _, f := range e.Findings {
+			fmt.Fprintf(&b, "- [%s] %s (%s:%d) %s\n", f.Severity, f.Title, f.Path, f.Line, f.URL)
+		}
+	case model.NotifyAIFailure, model.NotifyCircuitOpen:
+		fmt.Fprintf(&b, "%s\n", e.Message)
+	case model.NotifyCriticalPath:
+		for _, f := range e.Files {
+			fmt.Fprintf(&b, "- %s\n", f)
+		}
+	default:
+		fmt.Fprintf(&b, "Open findings: %s\n", severityLine(e.Severities))
+	}
+	return b.String()
+}
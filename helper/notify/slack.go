@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	Register("slack", func(s model.NotifierSettings, client *http.Client) (Notifier, error) {
+		if s.URL == "" {
+			return nil, errors.New("slack notifier needs url (an incoming webhook)")
+		}
+		return slackNotifier{url: s.URL, client: client}, nil
+	})
+}
+
+// slackNotifier posts to a Slack incoming webhook.
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n slackNotifier) Notify(e model.NotificationEvent) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n", headline(e))
+	if e.PullRequestID != 0 {
+		fmt.Fprintf(&b, "<%s|%s> by %s\n", e.URL, slackEscape(e.Title), slackEscape(e.Author))
+	}
+	switch e.Kind {
+	case model.NotifyCriticalFinding:
+		for _, f := range e.Findings {
+			fmt.Fprintf(&b, "• *%s* <%s|%s:%d> %s\n", f.Severity, f.URL, slackEscape(f.Path), f.Line, slackEscape(f.Title))
+		}
+	case model.NotifyAIFailure, model.NotifyCircuitOpen:
+		fmt.Fprintf(&b, "%s\n", slackEscape(e.Message))
+	case model.NotifyCriticalPath:
+		for _, f := range e.Files {
+			fmt.Fprintf(&b, "• `%s`\n", slackEscape(f))
+		}
+	default:
+		fmt.Fprintf(&b, "Open findings: %s\n", severityLine(e.Severities))
+	}
+	return postJSON(n.client, n.url, map[string]string{"text": b.String()}, nil)
+}
+
+// slackEscape escapes the characters Slack treats as markup in message text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
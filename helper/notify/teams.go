@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func init() {
+	Register("teams", func(s model.NotifierSettings, client *http.Client) (Notifier, error) {
+		if s.URL == "" {
+			return nil, errors.New("teams notifier needs url (an incoming webhook)")
+		}
+		return teamsNotifier{url: s.URL, client: client}, nil
+	})
+}
+
+// teamsNotifier posts an adaptive card to a Microsoft Teams incoming webhook or Workflows URL.
+type teamsNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// adaptiveCardVersion is the card schema version; 1.4 renders in Teams desktop, web and mobile.
+const adaptiveCardVersion = "1.4"
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string                   `json:"$schema"`
+	Type    string                   `json:"type"`
+	Version string                   `json:"version"`
+	Body    []map[string]interface{} `json:"body"`
+	Actions []map[string]interface{} `json:"actions,omitempty"`
+	MSTeams map[string]string        `json:"msteams,omitempty"`
+}
+
+func (n teamsNotifier) Notify(e model.NotificationEvent) error {
+	return postJSON(n.client, n.url, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     teamsCard(e),
+		}},
+	}, nil)
+}
+
+// teamsCard renders e as an adaptive card: the headline, the PR title and author, the findings
+// by severity, and a button that opens the pull request.
+func teamsCard(e model.NotificationEvent) adaptiveCard {
+	color := "default"
+	switch e.Kind {
+	case model.NotifyCriticalFinding, model.NotifyAIFailure, model.NotifyCircuitOpen:
+		color = "attention"
+	case model.NotifyCriticalPath:
+		color = "warning"
+	}
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": headline(e), "weight": "bolder", "size": "medium", "color": color, "wrap": true},
+	}
+	var facts []map[string]string
+	if e.PullRequestID != 0 {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": e.Title, "wrap": true, "spacing": "small"})
+		facts = append(facts, map[string]string{"title": "Author", "value": e.Author})
+	}
+	facts = append(facts, map[string]string{"title": "Repository", "value": e.Workspace + "/" + e.RepoSlug})
+	if e.Commit != "" {
+		commit := e.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		facts = append(facts, map[string]string{"title": "Commit", "value": commit})
+	}
+	body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+
+	switch e.Kind {
+	case model.NotifyAIFailure, model.NotifyCircuitOpen:
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": e.Message, "wrap": true, "color": "attention"})
+	case model.NotifyCriticalPath:
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": "Critical files changed", "weight": "bolder", "wrap": true})
+		for _, f := range e.Files {
+			body = append(body, map[string]interface{}{"type": "TextBlock", "text": "- `" + f + "`", "wrap": true, "spacing": "none"})
+		}
+	default:
+		if len(e.Severities) == 0 {
+			body = append(body, map[string]interface{}{"type": "TextBlock", "text": "No open findings", "wrap": true, "isSubtle": true})
+			break
+		}
+		counts := make([]map[string]string, 0, len(e.Severities))
+		for _, s := range severityOrder(e.Severities) {
+			counts = append(counts, map[string]string{"title": s, "value": strconv.Itoa(e.Severities[s])})
+		}
+		label := "Open findings"
+		if e.Kind == model.NotifyCriticalFinding {
+			label = "New findings"
+		}
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": label, "weight": "bolder", "wrap": true},
+			map[string]interface{}{"type": "FactSet", "facts": counts},
+		)
+	}
+	for _, f := range e.Findings {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": fmt.Sprintf("**%s** [%s:%d](%s) %s", f.Severity, f.Path, f.Line, f.URL, f.Title),
+			"wrap": true,
+		})
+	}
+
+	card := adaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: adaptiveCardVersion,
+		Body:    body,
+		MSTeams: map[string]string{"width": "Full"},
+	}
+	if e.URL != "" {
+		title := "Open pull request"
+		if e.PullRequestID == 0 {
+			title = "Open repository"
+		}
+		card.Actions = []map[string]interface{}{{"type": "Action.OpenUrl", "title": title, "url": e.URL}}
+	}
+	return card
+}
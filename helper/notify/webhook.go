@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"code_nim/model"
+	"errors"
+	"net/http"
+)
+
+func init() {
+	Register("webhook", func(s model.NotifierSettings, client *http.Client) (Notifier, error) {
+		if s.URL == "" {
+			return nil, errors.New("webhook notifier needs url")
+		}
+		return webhookNotifier{url: s.URL, headers: s.Headers, client: client}, nil
+	})
+}
+
+// webhookNotifier posts the event itself as JSON, for custom integrations.
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (n webhookNotifier) Notify(e model.NotificationEvent) error {
+	return postJSON(n.client, n.url, e, n.headers)
+}
@@ -0,0 +1,197 @@
+package helper
+
+import (
+	"code_nim/log"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwksMaxAge         = time.Hour
+	jwksRefreshBackoff = time.Minute
+	tokenLeeway        = time.Minute
+)
+
+// OIDCVerifier validates RS256/RS384/RS512 bearer tokens issued by an OpenID Connect provider.
+// Signing keys are discovered from the issuer and cached; an unknown key ID triggers a refresh.
+type OIDCVerifier struct {
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mutex     sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewOIDCVerifier(issuer, audience string) *OIDCVerifier {
+	return &OIDCVerifier{
+		issuer:   strings.TrimRight(strings.TrimSpace(issuer), "/"),
+		audience: strings.TrimSpace(audience),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify checks the signature, issuer, audience and validity period of token and returns its claims.
+func (v *OIDCVerifier) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("token header: %w", err)
+	}
+	var hash crypto.Hash
+	var digest []byte
+	signed := []byte(parts[0] + "." + parts[1])
+	switch header.Alg {
+	case "RS256":
+		sum := sha256.Sum256(signed)
+		hash, digest = crypto.SHA256, sum[:]
+	case "RS384":
+		sum := sha512.Sum384(signed)
+		hash, digest = crypto.SHA384, sum[:]
+	case "RS512":
+		sum := sha512.Sum512(signed)
+		hash, digest = crypto.SHA512, sum[:]
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("token signature: %w", err)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, sig); err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("token claims: %w", err)
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != v.issuer {
+		return nil, fmt.Errorf("unexpected token issuer %q", iss)
+	}
+	if v.audience != "" && !audienceMatches(claims["aud"], v.audience) {
+		return nil, errors.New("token audience does not match")
+	}
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(tokenLeeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(tokenLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func audienceMatches(aud interface{}, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []interface{}:
+		for _, v := range a {
+			if s, _ := v.(string); s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the signing key with kid, refreshing the JWKS when it is stale or the key is unknown.
+func (v *OIDCVerifier) key(kid string) (*rsa.PublicKey, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if k, ok := v.keys[kid]; ok && time.Since(v.fetchedAt) < jwksMaxAge {
+		return k, nil
+	}
+	if time.Since(v.fetchedAt) >= jwksRefreshBackoff {
+		if err := v.refreshKeys(); err != nil {
+			log.Errorf("Failed to refresh OIDC signing keys from %s: %v", v.issuer, err)
+		}
+	}
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown token signing key %q", kid)
+}
+
+// refreshKeys loads the JWKS advertised by the issuer's discovery document. Callers hold the mutex.
+func (v *OIDCVerifier) refreshKeys() error {
+	v.fetchedAt = time.Now()
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return err
+	}
+	if discovery.JWKSURI == "" {
+		return errors.New("discovery document has no jwks_uri")
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	v.keys = keys
+	log.Infof("Loaded %d OIDC signing keys from %s", len(keys), discovery.JWKSURI)
+	return nil
+}
+
+func (v *OIDCVerifier) getJSON(url string, out interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
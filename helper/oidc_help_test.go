@@ -0,0 +1,138 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oidcProvider serves the discovery document and JWKS of one RSA signing key.
+func oidcProvider(t *testing.T, kid string, key *rsa.PublicKey) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": srv.URL, "jwks_uri": srv.URL + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// signJWT returns a compact JWS of claims signed with key under alg (RS256, RS384 or RS512).
+func signJWT(t *testing.T, key *rsa.PrivateKey, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var hash crypto.Hash
+	var digest []byte
+	switch alg {
+	case "RS256":
+		sum := sha256.Sum256([]byte(signed))
+		hash, digest = crypto.SHA256, sum[:]
+	case "RS384":
+		sum := sha512.Sum384([]byte(signed))
+		hash, digest = crypto.SHA384, sum[:]
+	default:
+		sum := sha512.Sum512([]byte(signed))
+		hash, digest = crypto.SHA512, sum[:]
+	}
+	sig, err := rsa.SignPKCS1v15(nil, key, hash, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCVerifierVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := oidcProvider(t, "k1", &key.PublicKey)
+	now := time.Now().Unix()
+	claims := func(change func(c map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{"iss": srv.URL, "aud": "code-nim", "sub": "ci", "exp": now + 300}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	tamper := func(token string) string {
+		parts := strings.Split(token, ".")
+		payload, _ := json.Marshal(claims(func(c map[string]interface{}) { c["sub"] = "admin" }))
+		return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	}
+	withAlg := func(token, alg string) string {
+		parts := strings.Split(token, ".")
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "k1"})
+		return base64.RawURLEncoding.EncodeToString(header) + "." + parts[1] + "." + parts[2]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"RS256", signJWT(t, key, "RS256", "k1", claims(nil)), ""},
+		{"RS384", signJWT(t, key, "RS384", "k1", claims(nil)), ""},
+		{"RS512", signJWT(t, key, "RS512", "k1", claims(nil)), ""},
+		{"audience list", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["aud"] = []string{"other", "code-nim"} })), ""},
+		{"issuer with trailing slash", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["iss"] = srv.URL + "/" })), ""},
+		{"expired within leeway", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["exp"] = now - 30 })), ""},
+		{"signed by another key", signJWT(t, other, "RS256", "k1", claims(nil)), "invalid token signature"},
+		{"claims changed after signing", tamper(signJWT(t, key, "RS256", "k1", claims(nil))), "invalid token signature"},
+		{"algorithm swapped in the header", withAlg(signJWT(t, key, "RS384", "k1", claims(nil)), "RS256"), "invalid token signature"},
+		{"unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".", "unsupported token algorithm"},
+		{"HMAC", signJWT(t, key, "HS256", "k1", claims(nil)), "unsupported token algorithm"},
+		{"unknown key", signJWT(t, key, "RS256", "k2", claims(nil)), "unknown token signing key"},
+		{"other issuer", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["iss"] = "https://issuer.example.com" })), "unexpected token issuer"},
+		{"other audience", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["aud"] = "other" })), "audience does not match"},
+		{"no audience", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { delete(c, "aud") })), "audience does not match"},
+		{"expired", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["exp"] = now - 120 })), "token expired"},
+		{"no expiry", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { delete(c, "exp") })), "token expired"},
+		{"not valid yet", signJWT(t, key, "RS256", "k1", claims(func(c map[string]interface{}) { c["nbf"] = now + 120 })), "not valid yet"},
+		{"malformed", "not-a-token", "malformed token"},
+	}
+	v := NewOIDCVerifier(srv.URL+"/", "code-nim")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() = %v", err)
+				}
+				if got["sub"] != "ci" {
+					t.Errorf("sub = %v, want ci", got["sub"])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package helper
+
+import (
+	"bytes"
+	"code_nim/log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OSVBaseURL is the OSV.dev API queried for known vulnerabilities.
+var OSVBaseURL = "https://api.osv.dev"
+
+const osvCacheTTL = 6 * time.Hour
+
+// osvTimeout bounds an OSV query, which should not hold up a review as long as an AI call may.
+const osvTimeout = 10 * time.Second
+
+// Vulnerability is one OSV advisory affecting a dependency version.
+type Vulnerability struct {
+	ID       string
+	Aliases  []string
+	Summary  string
+	Severity string // review severity: Critical, Major or Minor
+	Fixed    string // first fixed version, if OSV lists one
+}
+
+// osvVuln is the part of an OSV record that code-nim uses.
+type osvVuln struct {
+	ID               string   `json:"id"`
+	Summary          string   `json:"summary"`
+	Details          string   `json:"details"`
+	Aliases          []string `json:"aliases"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Affected []struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem"`
+		} `json:"package"`
+		Ranges []struct {
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+type osvCacheEntry struct {
+	vulns     []Vulnerability
+	fetchedAt time.Time
+}
+
+var (
+	osvCacheMutex sync.Mutex
+	osvCache      = map[string]osvCacheEntry{}
+)
+
+// LookupVulnerabilities returns the known vulnerabilities of one dependency version from
+// OSV.dev. Results are cached for a few hours so repeated scans do not query OSV again.
+func LookupVulnerabilities(ecosystem, name, version string) ([]Vulnerability, error) {
+	key := ecosystem + "|" + name + "|" + version
+	osvCacheMutex.Lock()
+	if e, ok := osvCache[key]; ok && time.Since(e.fetchedAt) < osvCacheTTL {
+		osvCacheMutex.Unlock()
+		return e.vulns, nil
+	}
+	osvCacheMutex.Unlock()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"package": map[string]string{"name": name, "ecosystem": ecosystem},
+		"version": version,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), osvTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(OSVBaseURL, "/")+"/v1/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("osv query for %s %s: %w", name, version, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("osv query for %s %s: %w", name, version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("osv query for %s %s: status %d", name, version, resp.StatusCode)
+	}
+	var out struct {
+		Vulns []osvVuln `json:"vulns"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("osv response for %s %s: %w", name, version, err)
+	}
+	vulns := make([]Vulnerability, 0, len(out.Vulns))
+	for _, v := range out.Vulns {
+		vulns = append(vulns, v.vulnerability(ecosystem, name))
+	}
+	sort.SliceStable(vulns, func(i, j int) bool {
+		return SeverityRank(vulns[i].Severity) > SeverityRank(vulns[j].Severity)
+	})
+
+	osvCacheMutex.Lock()
+	osvCache[key] = osvCacheEntry{vulns: vulns, fetchedAt: time.Now()}
+	osvCacheMutex.Unlock()
+	return vulns, nil
+}
+
+// vulnerability converts an OSV record into a Vulnerability for the package name.
+func (v osvVuln) vulnerability(ecosystem, name string) Vulnerability {
+	out := Vulnerability{ID: v.ID, Aliases: v.Aliases, Summary: strings.TrimSpace(v.Summary)}
+	if out.Summary == "" {
+		out.Summary = strings.TrimSpace(strings.SplitN(v.Details, "\n", 2)[0])
+	}
+	// GitHub advisories rate CRITICAL/HIGH/MODERATE/LOW; a vulnerability without a rating is
+	// treated as Major rather than dismissed.
+	switch strings.ToUpper(v.DatabaseSpecific.Severity) {
+	case "CRITICAL":
+		out.Severity = "Critical"
+	case "LOW":
+		out.Severity = "Minor"
+	default:
+		out.Severity = "Major"
+	}
+	for _, a := range v.Affected {
+		if a.Package.Ecosystem != ecosystem || !strings.EqualFold(a.Package.Name, name) {
+			continue
+		}
+		for _, r := range a.Ranges {
+			for _, e := range r.Events {
+				if fixed := e["fixed"]; fixed != "" && out.Fixed == "" {
+					out.Fixed = fixed
+				}
+			}
+		}
+	}
+	return out
+}
+
+// DependencyVulnerabilities is a changed dependency together with its known vulnerabilities.
+type DependencyVulnerabilities struct {
+	Dependency DependencyChange
+	Vulns      []Vulnerability
+}
+
+// CheckDependencyVulnerabilities looks up every changed dependency and returns the ones with
+// known vulnerabilities. Lookup failures are logged and skipped so the review still runs.
+func CheckDependencyVulnerabilities(changes []DependencyChange) []DependencyVulnerabilities {
+	var out []DependencyVulnerabilities
+	for _, d := range changes {
+		vulns, err := LookupVulnerabilities(d.Ecosystem, d.Name, d.Version)
+		if err != nil {
+			log.Warnf("Vulnerability lookup failed: %v", err)
+			continue
+		}
+		if len(vulns) > 0 {
+			out = append(out, DependencyVulnerabilities{Dependency: d, Vulns: vulns})
+		}
+	}
+	return out
+}
+
+// VulnerabilityPromptContext renders the vulnerabilities found in a manifest for the review
+// prompt, so the AI can take them into account without reporting them a second time.
+func VulnerabilityPromptContext(found []DependencyVulnerabilities) string {
+	if len(found) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nKnown vulnerabilities in the dependencies changed by this diff (from OSV.dev). They are already reported as separate comments; do not repeat them, but do flag related problems:\n")
+	for _, f := range found {
+		ids := make([]string, 0, len(f.Vulns))
+		for _, v := range f.Vulns {
+			ids = append(ids, v.ID)
+		}
+		fmt.Fprintf(&b, "- %s %s (diff line %d): %s\n", f.Dependency.Name, f.Dependency.Version, f.Dependency.Index, strings.Join(ids, ", "))
+	}
+	return b.String()
+}
+
+// VulnerabilityFinding renders the review comment posted on the manifest line of a vulnerable
+// dependency. Position is the 1-based diff snippet index, like an AI finding before anchoring.
+func VulnerabilityFinding(f DependencyVulnerabilities) (body string, position int, anchor string) {
+	d := f.Dependency
+	severities := make([]string, 0, len(f.Vulns))
+	fixed := ""
+	for _, v := range f.Vulns {
+		severities = append(severities, v.Severity)
+		if CompareVersions(v.Fixed, fixed) > 0 {
+			fixed = v.Fixed
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Potential issue] [%s] %s %s has %d known %s\n", HighestSeverity(severities), d.Name, d.Version, len(f.Vulns), Pluralize(len(f.Vulns), "vulnerability", "vulnerabilities"))
+	b.WriteString("Why:\n")
+	if d.FromVersion != "" {
+		fmt.Fprintf(&b, "  - This change moves %s from %s to %s, which is still affected by the advisories below.\n", d.Name, d.FromVersion, d.Version)
+	}
+	for _, v := range f.Vulns {
+		line := "  - [" + v.ID + "](https://osv.dev/vulnerability/" + v.ID + ")"
+		if len(v.Aliases) > 0 {
+			line += " (" + strings.Join(v.Aliases, ", ") + ")"
+		}
+		if v.Summary != "" {
+			line += ": " + v.Summary
+		}
+		if v.Fixed != "" {
+			line += " (fixed in " + v.Fixed + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("How (step-by-step):\n")
+	if fixed != "" {
+		fmt.Fprintf(&b, "  - Use %s %s or later.\n", d.Name, fixed)
+	} else {
+		b.WriteString("  - No fixed version is listed yet; check whether the vulnerable code is reachable and consider an alternative package.\n")
+	}
+	b.WriteString("  - Re-run the dependency audit of your ecosystem after updating the lock file.\n")
+	return b.String(), d.Index, strings.TrimPrefix(d.LineText, "+")
+}
+
+// CompareVersions compares dotted numeric versions loosely: numeric parts compare as numbers,
+// anything else as text. An empty version sorts first.
+func CompareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	if b == "" {
+		return 1
+	}
+	pa := strings.FieldsFunc(strings.TrimPrefix(a, "v"), func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+	pb := strings.FieldsFunc(strings.TrimPrefix(b, "v"), func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		var na, nb int
+		_, errA := fmt.Sscanf(pa[i], "%d", &na)
+		_, errB := fmt.Sscanf(pb[i], "%d", &nb)
+		if errA == nil && errB == nil && na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+		if (errA != nil || errB != nil) && pa[i] != pb[i] {
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
+
+// Pluralize returns one when n is 1 and many otherwise.
+func Pluralize(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,81 @@
+package helper
+
+import (
+	"code_nim/model"
+	"strings"
+)
+
+// packagePrefix normalizes a packages key, such as "/web/" or "services/payments", to a path
+// without leading or trailing slashes.
+func packagePrefix(prefix string) string {
+	return strings.Trim(strings.TrimSpace(prefix), "/")
+}
+
+// PackageFor returns the package prefix and profile name that cfg.Packages routes filePath to,
+// by the longest matching path prefix, or "" when the file is in no package.
+func PackageFor(cfg *model.AutoReviewPR, filePath string) (string, string) {
+	filePath = strings.TrimPrefix(filePath, "/")
+	var pkg, name string
+	for prefix, profile := range cfg.Packages {
+		p := packagePrefix(prefix)
+		if p == "" || filePath != p && !strings.HasPrefix(filePath, p+"/") {
+			continue
+		}
+		// Keys that only differ in slashes, like "web" and "web/", pick the first profile by name
+		profile = strings.TrimSpace(profile)
+		if len(p) > len(pkg) || len(p) == len(pkg) && profile < name {
+			pkg, name = p, profile
+		}
+	}
+	return pkg, name
+}
+
+// PackageProfileFor returns the name and profile of the package of filePath, or nil when the
+// file is in no package or its package names an unknown profile.
+func PackageProfileFor(cfg *model.AutoReviewPR, filePath string) (string, *model.PackageProfile) {
+	_, name := PackageFor(cfg, filePath)
+	if name == "" {
+		return "", nil
+	}
+	profile, ok := cfg.PackageProfiles[name]
+	if !ok {
+		return "", nil
+	}
+	return name, &profile
+}
+
+// PackageConfig returns the configuration filePath is reviewed with: cfg with the prompt and
+// model overrides of its package profile, or cfg itself when the file is in no package.
+func PackageConfig(cfg *model.AutoReviewPR, filePath string) *model.AutoReviewPR {
+	_, profile := PackageProfileFor(cfg, filePath)
+	if profile == nil {
+		return cfg
+	}
+	c := *cfg
+	if s := strings.TrimSpace(profile.SystemInstructions); s != "" {
+		c.SystemInstructions = s
+	}
+	if s := strings.TrimSpace(profile.ReviewStyle); s != "" {
+		c.ReviewStyle = s
+	}
+	if m := strings.TrimSpace(profile.AIModel); m != "" {
+		withAIModel(&c, m)
+	}
+	return &c
+}
+
+// PackageFiles groups the files changed in diff by the profile name of their package, in diff
+// order, leaving out files in no package.
+func PackageFiles(cfg *model.AutoReviewPR, diff string) map[string][]string {
+	if len(cfg.Packages) == 0 {
+		return nil
+	}
+	files := map[string][]string{}
+	for _, file := range ParseDiff(diff) {
+		filePath, _ := file["path"].(string)
+		if name, profile := PackageProfileFor(cfg, filePath); profile != nil {
+			files[name] = append(files[name], filePath)
+		}
+	}
+	return files
+}
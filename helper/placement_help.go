@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"code_nim/model"
+	"strings"
+)
+
+// Reasons a proposed inline comment placement is rejected.
+const (
+	PlacementOutOfRange    = "out_of_range"   // diff index outside the snippet sent to the AI
+	PlacementDeletedLine   = "deleted_line"   // points at a removed line whose source line is unknown
+	PlacementOutsideHunk   = "outside_hunk"   // destination line is not part of any hunk of the file
+	PlacementAnchorMissing = "anchor_missing" // anchor text does not appear at or next to the line
+)
+
+// anchorSlack is how many diff lines around the placement may hold the anchor text.
+const anchorSlack = 2
+
+// ValidatePlacement re-renders the file diff from its snippet and line map and checks that a
+// comment already mapped to file lines (Position = destination line, or FromLine alone for a
+// removed line) lands on a line of a hunk and, when the AI gave an anchor, that the anchor
+// text is at or right next to that line.
+// It returns "" for a valid placement, otherwise one of the Placement* reasons.
+func ValidatePlacement(diffLines []string, lineMap []DiffLineMapping, c model.ReviewComment) string {
+	idx := -1
+	for i, m := range lineMap {
+		if m.ToLine > 0 && m.ToLine == c.Position ||
+			c.Position <= 0 && m.ToLine <= 0 && m.FromLine > 0 && m.FromLine == c.FromLine {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 || idx >= len(diffLines) {
+		return PlacementOutsideHunk
+	}
+	anchor := stripDiffPrefix(c.Anchor)
+	if anchor == "" {
+		return ""
+	}
+	for i := idx - anchorSlack; i <= idx+anchorSlack; i++ {
+		if i >= 0 && i < len(diffLines) && strings.Contains(stripDiffPrefix(diffLines[i]), anchor) {
+			return ""
+		}
+	}
+	return PlacementAnchorMissing
+}
+
+func stripDiffPrefix(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		return strings.TrimSpace(s[1:])
+	}
+	return s
+}
+
+// RangeStartLine maps the first diff line of a multi-line finding (1-based snippet indexes
+// start to end) to its destination line. It returns 0 when the lines are not one contiguous
+// run of destination lines, such as a range across two hunks, so the finding keeps only its
+// last line.
+func RangeStartLine(lineMap []DiffLineMapping, start, end int) int {
+	if start <= 0 || start >= end || end > len(lineMap) {
+		return 0
+	}
+	first, prev := 0, 0
+	for i := start - 1; i < end; i++ {
+		to := lineMap[i].ToLine
+		if to <= 0 {
+			continue // removed lines sit between destination lines
+		}
+		if prev > 0 && to != prev+1 {
+			return 0
+		}
+		if first == 0 {
+			first = to
+		}
+		prev = to
+	}
+	if first == 0 || first >= prev {
+		return 0
+	}
+	return first
+}
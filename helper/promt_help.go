@@ -75,14 +75,31 @@ func escapeControlCharsInJSONString(s string) string {
 	return b.String()
 }
 
-func CreatePrompt(filePath string, hunkLines []string, pr *model.PullRequest) string {
-	log.Debugf("Begin to Create Prompt for PR: %d", pr.ID)
-	return fmt.Sprintf(`You are an expert code reviewer. Please follow these instructions carefully:
+// languageFocus returns the prompt line with the review focus of a language, or "".
+func languageFocus(lang LanguageProfile) string {
+	if lang.Focus == "" {
+		return ""
+	}
+	return fmt.Sprintf("- This is a %s file: pay particular attention to %s. Write suggested changes in %s.\n", lang.Language, lang.Focus, lang.Language)
+}
+
+// languageExample returns the example review of a language as a prompt list item, or "".
+func languageExample(lang LanguageProfile) string {
+	if lang.Example == "" {
+		return ""
+	}
+	return "- " + lang.Example + "\n"
+}
+
+// reviewInstructions opens every inline review prompt. It is the same for every file, entry and
+// pull request, so providers can cache it (see splitPromptPrefix); per-file details follow it.
+const reviewInstructions = `You are an expert code reviewer. Please follow these instructions carefully:
 
 - Provide your feedback strictly in the following JSON format:
   {"reviews": [{"lineNumber": <diff_line_index>, "lineText": "<exact line snippet>", "reviewComment": "<comment>"}]}
+  When a finding spans several consecutive lines (a whole block or function), add "startLineNumber": <diff_line_index of its first line>; lineNumber and lineText then refer to its last line. Omit startLineNumber for single-line findings.
 
-- Review the unified diff for file "%s" below. The lineNumber refers to the 1-based index of the displayed diff lines (including context and +/- lines). Do not use absolute file line numbers. Also include the exact line text (lineText) you are referring to from the diff to help anchor placement.
+- Review the unified diff of the file named under "File to review" below. The lineNumber refers to the 1-based index of the displayed diff lines (including context and +/- lines). Do not use absolute file line numbers. Also include the exact line text (lineText) you are referring to from the diff to help anchor placement.
 - Your reviewComment must be actionable like CodeRabbit. Use this structure:
   [<Type: Potential issue|Refactor|Nitpick>] [<Severity: Critical|Major|Minor|Trivial|Info>]
   <Short title in one sentence>
@@ -91,12 +108,12 @@ func CreatePrompt(filePath string, hunkLines []string, pr *model.PullRequest) st
   How (step-by-step):
     - <Precise steps to change code>
   Suggested change (Before/After):
-    ~~~go
-    // Before
+    ~~~<fence language of the file>
+    <"Before" label of the file>
     <minimal relevant snippet>
     ~~~
-    ~~~go
-    // After
+    ~~~<fence language of the file>
+    <"After" label of the file>
     <minimal relevant snippet with improvement>
     ~~~
   Prompt for AI Agents (optional):
@@ -127,6 +144,9 @@ func CreatePrompt(filePath string, hunkLines []string, pr *model.PullRequest) st
     - Replace hard-coded credentials with references to secret variables; add pre-commit/CI scanners and update .gitignore to avoid committing generated secret files.
     - Where relevant (Kubernetes), prefer External Secrets or Secrets Store CSI; if storing manifests, store only encrypted material.
   - Your review comment should include concrete Before/After examples in the file’s native format (code, YAML, JSON, .env) illustrating a safe pattern.
+- BREAKING CHANGES: flag changes that can break callers, clients or deployments: removed or renamed exported functions, types or methods; changed signatures of exported functions; added, removed or altered API routes; renamed, removed or re-typed configuration keys and environment variables.
+  - Treat each as [Potential issue] with Severity Major, or Critical when callers fail silently, and start its title with "Breaking change:".
+  - In How, name the callers or deployments to update, or how to keep the old name working during a deprecation period.
 - Maybe Refactor the following code to improve readability, maintainability, and efficiency. Please ensure the logic remains unchanged.
 - Use clear, concise GitHub Markdown in your comments.
 - ONLY provide feedback if improvements are necessary; if the code is optimal, return an empty "reviews" array.
@@ -137,27 +157,58 @@ func CreatePrompt(filePath string, hunkLines []string, pr *model.PullRequest) st
 - If the diff is overly extensive, explicitly mention that it's too large for effective review.
 
 Examples of review comments:
-- {"lineNumber": 61, "lineText": "+ func matchesEngineID(deploymentName string, engineID string) bool {", "reviewComment": "[Refactor] [Minor] Boundary-safe engine ID matching\nWhy:\n  - strings.Contains(name, suffix- may match unintended names (e.g., dlp vs adlp).\nHow (step-by-step):\n  - Ensure an ID matches only at word/hyphen boundary or end-of-name.\nSuggested change (Before/After):\n~~~go\n// Before\nreturn strings.Contains(deploymentName, engineID+\"-\") || strings.HasSuffix(deploymentName, engineID)\n~~~\n~~~go\n// After\nre := regexp.MustCompile((^|-)" + "" + "" + " + regexp.QuoteMeta(engineID) + "$")\nreturn re.MatchString(deploymentName)\n~~~\nPrompt for AI Agents:\n  - In the file containing matchesEngineID, replace the strings.Contains/HasSuffix logic with a boundary-safe check (regex or equivalent), and keep behavior identical for existing callers."}
 - {"lineNumber": 7, "lineText": "+   METRICS_AUTH_PASSWORD: MTIzNDU2", "reviewComment": "[Potential issue] [Critical] Base64-encoded credential committed to repo\n+Why:\n+  - Base64 is reversible and provides no secrecy; anyone can decode the value.\n+  - Committing real secrets risks unauthorized access if reused elsewhere.\n+How (step-by-step):\n+  - Rotate this credential immediately.\n+  - Replace the literal value with a reference to a secret manager variable injected at runtime.\n+  - Add CI scanning to block future secret commits.\n+Suggested change (Before/After):\n+~~~yaml\n+# Before\ndata:\n  METRICS_AUTH_PASSWORD: MTIzNDU2\n+~~~\n+~~~yaml\n+# After (generic example)\n# Use runtime-injected env or a reference to your secret manager\nenv:\n  - name: METRICS_AUTH_PASSWORD\n    valueFrom:\n      secretKeyRef:\n        name: metrics-auth\n        key: password\n+~~~\n+Prompt for AI Agents:\n+  - In the YAML file where METRICS_AUTH_PASSWORD is set, replace the literal with a secret reference and ensure runtime injection; remove the base64 value."}
 
-Pull Request Title: %s
+`
+
+// CreatePrompt builds the inline review prompt for one file: reviewInstructions, then the code
+// fences, comment labels, review focus and example of the file's language. Dockerfiles,
+// Kubernetes manifests, Helm charts, Terraform and SQL migrations get the checklist of their
+// review profile instead, and files under the entry's criticalPaths stricter rules on top.
+func CreatePrompt(filePath string, hunkLines []string, pr *model.PullRequest, cfg *model.AutoReviewPR) string {
+	log.Debugf("Begin to Create Prompt for PR: %d", pr.ID)
+	lang := LanguageProfileFor(filePath)
+	focus := languageFocus(lang)
+	if profile := ReviewProfileFor(filePath, hunkLines); profile != nil {
+		focus = profile.Instructions()
+		lang.Fence, lang.Comment = profile.Fence, profile.Comment
+		log.Debugf("Using %s review profile for %s", profile.Name, filePath)
+	}
+	focus += criticalPathInstructions(cfg, filePath)
+	focus += autoFixInstructions(cfg)
+	if example := languageExample(lang); example != "" {
+		focus += "- Example for this file type:\n  " + example
+	}
+	return reviewInstructions + fmt.Sprintf(`File to review: "%[1]s"
+- Open suggested changes with ~~~%[2]s and label them %[3]q and %[4]q.
+%[5]s
+%[6]sPull Request Title: %[7]s
 
 Pull Request Description:
 ---
-%s
+%[8]s
 ---
 
 Git Diff to Review:
 ---diff
-%s
+%[9]s
 ---
-`, filePath, pr.Title, pr.Description, strings.Join(hunkLines, "\n"))
+`, filePath, lang.Fence, lang.Label("Before"), lang.Label("After"), focus,
+		reviewToneInstructions(ReviewStyle(cfg))+reviewLanguageInstructions(cfg.Language)+systemInstructions(cfg), pr.Title, pr.Description, strings.Join(hunkLines, "\n"))
+}
 
+// splitPromptPrefix splits a prompt built by CreatePrompt into reviewInstructions and the rest.
+// ok is false for other prompts.
+func splitPromptPrefix(prompt string) (prefix, rest string, ok bool) {
+	if rest, ok = strings.CutPrefix(prompt, reviewInstructions); !ok {
+		return "", prompt, false
+	}
+	return reviewInstructions, rest, true
 }
 
 // CreateSummaryPrompt builds a prompt that asks the AI to summarize the PR in
 // a CodeRabbit-like style with grouped bullets.
-func CreateSummaryPrompt(pr *model.PullRequest, diff string) string {
+func CreateSummaryPrompt(pr *model.PullRequest, diff string, cfg *model.AutoReviewPR) string {
 	log.Debugf("Create Summary Prompt for PR: %d", pr.ID)
 	return fmt.Sprintf(`You are an expert code reviewer.
 
@@ -204,6 +255,11 @@ Critical rules:
 - Each item ≤ 140 chars; start with verb, end with period.
 - Omit empty sections completely.
 
+## Breaking Changes
+List each change that can break callers, clients or deployments, one hyphen bullet per change: "- <function, route or config key> (<file>): <what changed and who must adapt>."
+Look specifically for removed or renamed exported functions, types and methods; changed function signatures; added, removed or altered API routes; and renamed, removed or re-typed configuration keys and environment variables.
+Omit this section completely when there are none.
+
 ## Walkthrough
 A short paragraph (3-6 sentences) explaining the overall intent of the change and major areas touched.
 
@@ -226,23 +282,126 @@ Style rules:
 - No shell commands.
 - Output must be valid Markdown.
 
-Pull Request Title: %s
+%sPull Request Title: %s
 
 Pull Request Description:
 ---
 %s
 ---
+%s%s
+Unified Git Diff:
+---diff
+%s
+---
+`, summaryToneInstructions(ReviewStyle(cfg))+summaryLanguageInstructions(cfg.Language)+systemInstructions(cfg), pr.Title, pr.Description, commitMessagesSection(pr.CommitMessages), contractSummaryContext(diff), diff)
+}
+
+// Bounds of the commit messages quoted in the summary prompt.
+const (
+	maxPromptCommits       = 50
+	maxPromptCommitMessage = 1000
+)
+
+// commitMessagesSection quotes the newest maxPromptCommits commit messages, oldest first and
+// each cut at maxPromptCommitMessage bytes, for the summary prompt. It is empty without commits.
+func commitMessagesSection(messages []string) string {
+	var quoted []string
+	for _, m := range messages {
+		if m = strings.TrimSpace(m); m != "" {
+			quoted = append(quoted, m)
+		}
+	}
+	if len(quoted) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nCommit Messages (oldest first; use them to understand the author's intent, but summarize what the diff actually changes):\n---\n")
+	if omitted := len(quoted) - maxPromptCommits; omitted > 0 {
+		fmt.Fprintf(&b, "(%d earlier %s omitted)\n", omitted, Pluralize(omitted, "commit", "commits"))
+		quoted = quoted[omitted:]
+	}
+	for _, m := range quoted {
+		if len(m) > maxPromptCommitMessage {
+			m = strings.ToValidUTF8(m[:maxPromptCommitMessage], "") + "…"
+		}
+		for i, line := range strings.Split(m, "\n") {
+			switch {
+			case i == 0:
+				b.WriteString("- " + line + "\n")
+			case strings.TrimSpace(line) == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString("  " + line + "\n")
+			}
+		}
+	}
+	b.WriteString("---\n")
+	return b.String()
+}
+
+// CreateDescriptionPrompt builds a prompt that asks the AI to write the description of a pull
+// request from its diff, for authors who left it empty or wrote a single line.
+func CreateDescriptionPrompt(pr *model.PullRequest, diff string, cfg *model.AutoReviewPR) string {
+	log.Debugf("Create Description Prompt for PR: %d", pr.ID)
+	return fmt.Sprintf(`You are writing the description of a pull request on behalf of its author.
+
+Produce Markdown that contains EXACTLY these sections, in this order:
+
+## What
+2-5 bullets describing the changes; each bullet starts with a verb and ends with a period.
+
+## Why
+1-3 sentences on the problem this solves. Use the title and the existing description; if the reason is not evident from them or the diff, write "Not stated by the author." instead of guessing.
+
+## How
+2-6 bullets on the approach: key design decisions, new components, changed behaviour.
+
+## Test plan
+Bullets with the checks a reviewer can run to verify the change (tests added or affected, manual steps). Name only tests that appear in the diff.
+
+Rules:
+- Be factual; describe only what is in the diff. No praise, no review findings.
+- No title line, no preamble, no closing remarks; start with "## What".
+- Output must be valid Markdown.
+
+%sPull Request Title: %s
+
+Existing Description:
+---
+%s
+---
 
 Unified Git Diff:
 ---diff
 %s
 ---
-`, pr.Title, pr.Description, diff)
+`, summaryLanguageInstructions(cfg.Language)+systemInstructions(cfg), pr.Title, pr.Description, diff)
 }
 
 func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]model.ReviewComment, error) {
 	// Gemini API endpoint (v1beta/models/gemini-2.0-flash-001:generateContent)
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", geminiModel, geminiKey)
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", geminiAPIBase, geminiModel, geminiKey)
+	return getAIResponseOfGeminiAPI(prompt, url, nil, "", nil)
+}
+
+// postJSON sends a JSON payload with optional extra headers (e.g., Authorization).
+func postJSON(url string, headers map[string]string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return httpClient().Do(req)
+}
+
+// getAIResponseOfGeminiAPI calls any endpoint speaking the Gemini generateContent schema
+// (public Generative Language API or Vertex AI) and parses review comments. A non-empty
+// cachedContent names the context cache holding the start of the prompt.
+// When usage is non-nil it receives the token counts reported by the API.
+func getAIResponseOfGeminiAPI(prompt string, url string, headers map[string]string, cachedContent string, usage *model.AIUsage) ([]model.ReviewComment, error) {
 	payload := map[string]interface{}{
 		"contents": []map[string]interface{}{{"parts": []map[string]string{{"text": prompt}}}},
 		"generationConfig": map[string]interface{}{
@@ -251,8 +410,11 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 			"topP":            0.95,
 		},
 	}
+	if cachedContent != "" {
+		payload["cachedContent"] = cachedContent
+	}
 	b, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(b)))
+	resp, err := postJSON(url, headers, b)
 	if err != nil {
 		log.Errorf("Failed to make request to Gemini API: %v", err)
 		return nil, err
@@ -264,7 +426,7 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 		var errorResult model.GeminiErrorResponse
 		if err := json.NewDecoder(resp.Body).Decode(&errorResult); err != nil {
 			log.Errorf("Failed to decode error response from Gemini API (status %d): %v", resp.StatusCode, err)
-			return nil, fmt.Errorf("gemini API returned status %d", resp.StatusCode)
+			return nil, aiStatusError("gemini", resp.StatusCode, "")
 		}
 
 		// Handle specific error types using the structured response
@@ -277,15 +439,15 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 			log.Errorf("Gemini API rate limit exceeded: %s", message)
 			log.Error("Please check your API quota and billing details")
 			log.Error("For more information: https://ai.google.dev/gemini-api/docs/rate-limits")
-			return nil, fmt.Errorf("gemini API rate limit exceeded: %s", message)
+			return nil, fmt.Errorf("gemini: %w: %s", ErrRateLimited, message)
 		case 401:
 			log.Errorf("Gemini API authentication failed: %s", message)
 			log.Error("Please check your API key")
-			return nil, fmt.Errorf("gemini API authentication failed: %s", message)
+			return nil, fmt.Errorf("gemini: %w: %s", ErrAuth, message)
 		case 403:
 			log.Errorf("Gemini API access forbidden: %s", message)
 			log.Error("Please check your API permissions and billing")
-			return nil, fmt.Errorf("gemini API access forbidden: %s", message)
+			return nil, fmt.Errorf("gemini: %w (access forbidden): %s", ErrAuth, message)
 		case 400:
 			log.Errorf("Gemini API bad request: %s", message)
 			log.Error("Please check your request parameters and model name")
@@ -302,6 +464,7 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 		log.Errorf("Failed to decode successful response from Gemini API: %v", err)
 		return nil, err
 	}
+	extractUsage(result, usage)
 	// Extract text
 	var text string
 	if c, ok := result["candidates"].([]interface{}); ok && len(c) > 0 {
@@ -315,18 +478,21 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 	text = strings.TrimPrefix(text, "```json")
 	text = strings.TrimSuffix(text, "```")
 	text = strings.TrimSpace(text)
+	if geminiFinishReason(result) == "MAX_TOKENS" {
+		return truncatedReviewComments(text, "gemini")
+	}
 
 	// Add validation and better error handling for JSON parsing
 	if text == "" {
 		log.Error("Received empty response from AI")
-		return []model.ReviewComment{}, nil // Return empty slice instead of error
+		return nil, fmt.Errorf("gemini: %w: empty reply", ErrAIInvalidResponse)
 	}
 
 	// Check if the response looks like JSON
 	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
 		log.Errorf("AI response doesn't appear to be JSON. First 100 chars: %s",
 			text[:min(100, len(text))])
-		return []model.ReviewComment{}, nil // Return empty slice instead of error
+		return nil, fmt.Errorf("gemini: %w: reply is not JSON", ErrAIInvalidResponse)
 	}
 
 	// Log the full response for debugging when JSON parsing fails
@@ -334,6 +500,10 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 
 	var respObj model.ReviewResponse
 	if err := json.Unmarshal([]byte(text), &respObj); err != nil {
+		if items, ok := repairReviewJSON(escapeControlCharsInJSONString(text)); ok {
+			log.Warnf("Repaired malformed JSON from gemini (%v); %d findings recovered", err, len(items))
+			return reviewComments(items), nil
+		}
 		log.Errorf("Failed to parse JSON from AI response: %v", err)
 		log.Errorf("Raw AI response (first 500 chars): %s", text[:min(500, len(text))])
 		log.Errorf("Raw AI response (last 200 chars): %s", text[max(0, len(text)-200):])
@@ -343,25 +513,20 @@ func GetAIResponseOfGemini(prompt string, geminiKey, geminiModel string) ([]mode
 			log.Error("AI response appears to be incomplete JSON (missing closing brace)")
 		}
 
-		// Return empty slice instead of error to allow processing to continue
-		return []model.ReviewComment{}, nil
-	}
-	var comments []model.ReviewComment
-	for _, r := range respObj.Reviews {
-		anchor := strings.TrimSpace(r.LineText)
-		comments = append(comments, model.ReviewComment{
-			Body:     r.ReviewComment,
-			Path:     "", // to be filled by caller
-			Position: r.LineNumber,
-			Anchor:   anchor,
-		})
+		return nil, fmt.Errorf("gemini: %w: %v", ErrAIInvalidResponse, err)
 	}
-	return comments, nil
+	return reviewComments(respObj.Reviews), nil
 }
 
 // getGeminiText returns the raw text response from Gemini for a given prompt.
-func getGeminiText(prompt string, geminiKey, geminiModel string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", geminiModel, geminiKey)
+func getGeminiText(prompt string, geminiKey, geminiModel string, cachedContent string, usage *model.AIUsage) (string, error) {
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", geminiAPIBase, geminiModel, geminiKey)
+	return getGeminiAPIText(prompt, url, nil, cachedContent, usage)
+}
+
+// getGeminiAPIText returns the raw text from any Gemini-schema generateContent endpoint;
+// cachedContent is as for getAIResponseOfGeminiAPI.
+func getGeminiAPIText(prompt string, url string, headers map[string]string, cachedContent string, usage *model.AIUsage) (string, error) {
 	payload := map[string]interface{}{
 		"contents": []map[string]interface{}{{"parts": []map[string]string{{"text": prompt}}}},
 		"generationConfig": map[string]interface{}{
@@ -370,8 +535,11 @@ func getGeminiText(prompt string, geminiKey, geminiModel string) (string, error)
 			"topP":            0.95,
 		},
 	}
+	if cachedContent != "" {
+		payload["cachedContent"] = cachedContent
+	}
 	b, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(b)))
+	resp, err := postJSON(url, headers, b)
 	if err != nil {
 		return "", err
 	}
@@ -380,13 +548,14 @@ func getGeminiText(prompt string, geminiKey, geminiModel string) (string, error)
 	if resp.StatusCode != 200 {
 		var errorResult model.GeminiErrorResponse
 		_ = json.NewDecoder(resp.Body).Decode(&errorResult)
-		return "", fmt.Errorf("gemini status %d: %s", resp.StatusCode, errorResult.Error.Message)
+		return "", aiStatusError("gemini", resp.StatusCode, errorResult.Error.Message)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return "", err
 	}
+	extractUsage(result, usage)
 	var text string
 	if c, ok := result["candidates"].([]interface{}); ok && len(c) > 0 {
 		if content, ok := c[0].(map[string]interface{})["content"].(map[string]interface{}); ok {
@@ -405,17 +574,35 @@ func getGeminiText(prompt string, geminiKey, geminiModel string) (string, error)
 	return strings.TrimSpace(text), nil
 }
 
-// GetAISummary returns a Markdown summary text via the configured provider.
-func GetAISummary(prompt string, cfg *model.AutoReviewPR) (string, error) {
-	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
-	modelName := strings.TrimSpace(cfg.AIModel)
-	if modelName == "" {
-		modelName = strings.TrimSpace(cfg.GeminiModel)
+// AIModelName returns the model cfg calls: aiModel, then geminiModel, then gemini-2.5-flash.
+func AIModelName(cfg *model.AutoReviewPR) string {
+	if m := strings.TrimSpace(cfg.AIModel); m != "" {
+		return m
 	}
-	if modelName == "" {
-		modelName = "gemini-2.5-flash"
+	if m := strings.TrimSpace(cfg.GeminiModel); m != "" {
+		return m
 	}
+	return "gemini-2.5-flash"
+}
+
+// GetAISummary returns a Markdown summary text via the configured provider, or its recording
+// under aiReplay.
+func GetAISummary(prompt string, cfg *model.AutoReviewPR) (string, error) {
+	rec := model.AIRecording{Kind: "text", Prompt: prompt}
+	err := withAIReplay(&rec, cfg, func() error {
+		return withAISlot(cfg, prompt, func(usage *model.AIUsage) (err error) {
+			rec.Text, err = getAISummary(prompt, cfg, usage)
+			return err
+		})
+	})
+	return rec.Text, err
+}
+
+func getAISummary(prompt string, cfg *model.AutoReviewPR, usage *model.AIUsage) (string, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
+	modelName := AIModelName(cfg)
 	log.Debugf("Getting AI summary for provider: %s and model %s", provider, modelName)
+	defer func() { recordUsage(cfg, modelName, *usage) }()
 
 	switch provider {
 	case "self":
@@ -430,7 +617,7 @@ func GetAISummary(prompt string, cfg *model.AutoReviewPR) (string, error) {
 			"contents": []map[string]interface{}{{"parts": []map[string]string{{"text": prompt}}}},
 		})
 		log.Debugf("Calling self API for summary at: %s", url)
-		resp, err := http.Post(url, "application/json", strings.NewReader(string(b)))
+		resp, err := httpClient().Post(url, "application/json", strings.NewReader(string(b)))
 		if err != nil {
 			log.Errorf("Self API HTTP error: %v", err)
 			return "", err
@@ -442,6 +629,7 @@ func GetAISummary(prompt string, cfg *model.AutoReviewPR) (string, error) {
 		// Try JSON path first
 		var obj map[string]interface{}
 		if json.Unmarshal(rawBody, &obj) == nil {
+			extractUsage(obj, usage)
 			var text string
 			if c, ok := obj["candidates"].([]interface{}); ok && len(c) > 0 {
 				if content, ok := c[0].(map[string]interface{})["content"].(map[string]interface{}); ok {
@@ -481,29 +669,55 @@ func GetAISummary(prompt string, cfg *model.AutoReviewPR) (string, error) {
 		finalText := strings.TrimSpace(t)
 		log.Debugf("Returning plain text summary, final length: %d", len(finalText))
 		return finalText, nil
+	case "azure-openai":
+		var text string
+		err := withAPIKey(cfg, func(apiKey string) error {
+			var callErr error
+			text, callErr = getAzureText(prompt, cfg, apiKey, usage)
+			return callErr
+		})
+		return text, err
+	case "gemini-vertex":
+		url, headers, err := vertexRequest(cfg, modelName)
+		if err != nil {
+			log.Errorf("Vertex AI setup error: %v", err)
+			return "", err
+		}
+		return getGeminiAPIText(prompt, url, headers, "", usage)
 	default:
 		// Gemini
-		return getGeminiText(prompt, strings.TrimSpace(cfg.GeminiKey), modelName)
+		var text string
+		err := withAPIKey(cfg, func(apiKey string) error {
+			return withContextCache(cfg, apiKey, modelName, prompt, func(prompt, cachedContent string) error {
+				var callErr error
+				text, callErr = getGeminiText(prompt, apiKey, modelName, cachedContent, usage)
+				return callErr
+			})
+		})
+		return text, err
 	}
 }
 
-// GetAIResponse routes to the configured AI provider. Defaults to Gemini.
+// GetAIResponse routes to the configured AI provider, defaulting to Gemini, or returns the
+// recording of the prompt under aiReplay.
 func GetAIResponse(prompt string, cfg *model.AutoReviewPR) ([]model.ReviewComment, error) {
+	rec := model.AIRecording{Kind: "findings", Prompt: prompt}
+	err := withAIReplay(&rec, cfg, func() error {
+		return withAISlot(cfg, prompt, func(usage *model.AIUsage) (err error) {
+			rec.Findings, err = getAIResponse(prompt, cfg, usage)
+			return err
+		})
+	})
+	return rec.Findings, err
+}
+
+func getAIResponse(prompt string, cfg *model.AutoReviewPR, usage *model.AIUsage) ([]model.ReviewComment, error) {
 	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
-	// Resolve model and key (generic first, then Gemini-specific, then default model)
-	modelName := strings.TrimSpace(cfg.AIModel)
-	if modelName == "" {
-		modelName = strings.TrimSpace(cfg.GeminiModel)
-	}
-	if modelName == "" {
-		modelName = "gemini-2.5-flash"
-	}
+	modelName := AIModelName(cfg)
 
-	apiKey := strings.TrimSpace(cfg.AIKey)
-	if apiKey == "" {
-		apiKey = strings.TrimSpace(cfg.GeminiKey)
-	}
+	defer func() { recordUsage(cfg, modelName, *usage) }()
 
+	var comments []model.ReviewComment
 	switch provider {
 	case "self":
 		base := strings.TrimSpace(cfg.SelfAPIBaseURL)
@@ -512,17 +726,50 @@ func GetAIResponse(prompt string, cfg *model.AutoReviewPR) ([]model.ReviewCommen
 			return nil, fmt.Errorf("selfApiBaseUrl is required when aiProvider=self")
 		}
 		log.Debugf("Using AI provider=self, base=%s, model=%s", base, modelName)
-		return getAIResponseOfSelf(prompt, base, modelName)
+		return getAIResponseOfSelf(prompt, base, modelName, usage)
+	case "azure-openai":
+		log.Debugf("Using AI provider=azure-openai, endpoint=%s, deployment=%s", cfg.AzureEndpoint, cfg.AzureDeployment)
+		err := withAPIKey(cfg, func(apiKey string) error {
+			var callErr error
+			comments, callErr = getAIResponseOfAzure(prompt, cfg, apiKey, usage)
+			return callErr
+		})
+		return comments, err
+	case "gemini-vertex":
+		url, headers, err := vertexRequest(cfg, modelName)
+		if err != nil {
+			log.Errorf("Vertex AI setup error: %v", err)
+			return nil, err
+		}
+		log.Debugf("Using AI provider=gemini-vertex, project=%s, region=%s, model=%s", cfg.VertexProject, cfg.VertexRegion, modelName)
+		return geminiReviewCall(cfg)(prompt, url, headers, "", usage)
 	default:
 		// Gemini
 		log.Debugf("Using AI provider=gemini, model=%s", modelName)
-		return GetAIResponseOfGemini(prompt, apiKey, modelName)
+		err := withAPIKey(cfg, func(apiKey string) error {
+			url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", geminiAPIBase, modelName, apiKey)
+			return withContextCache(cfg, apiKey, modelName, prompt, func(prompt, cachedContent string) error {
+				var callErr error
+				comments, callErr = geminiReviewCall(cfg)(prompt, url, nil, cachedContent, usage)
+				return callErr
+			})
+		})
+		return comments, err
+	}
+}
+
+// geminiReviewCall returns the request for the review findings of a Gemini-schema endpoint,
+// streamed when cfg sets aiStreaming.
+func geminiReviewCall(cfg *model.AutoReviewPR) func(prompt string, url string, headers map[string]string, cachedContent string, usage *model.AIUsage) ([]model.ReviewComment, error) {
+	if cfg.AIStreaming {
+		return streamAIResponseOfGeminiAPI
 	}
+	return getAIResponseOfGeminiAPI
 }
 
 // getAIResponseOfSelf calls a self-hosted AI API that mimics Gemini's content API.
 // Expected endpoint form: {base}/v1beta/models/{model}
-func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.ReviewComment, error) {
+func getAIResponseOfSelf(prompt string, baseURL, modelName string, usage *model.AIUsage) ([]model.ReviewComment, error) {
 	base := strings.TrimRight(baseURL, "/")
 	url := fmt.Sprintf("%s/v1beta/models/%s", base, modelName)
 
@@ -536,7 +783,7 @@ func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.Revi
 		},
 	}
 	b, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(b)))
+	resp, err := httpClient().Post(url, "application/json", strings.NewReader(string(b)))
 	if err != nil {
 		log.Errorf("Failed to call self AI API: %v", err)
 		return nil, err
@@ -550,7 +797,7 @@ func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.Revi
 	}
 	if resp.StatusCode != 200 {
 		log.Errorf("Self AI API returned status %d, raw body (first 500 chars): %s", resp.StatusCode, string(rawBody)[:min(500, len(rawBody))])
-		return nil, fmt.Errorf("self AI API returned status %d", resp.StatusCode)
+		return nil, aiStatusError("self AI API", resp.StatusCode, string(rawBody)[:min(200, len(rawBody))])
 	}
 
 	// Parse successful response (try to follow Gemini schema, but be tolerant)
@@ -561,6 +808,7 @@ func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.Revi
 		log.Debugf("Self AI response not a JSON object; will attempt plain text extraction")
 		result = map[string]interface{}{}
 	}
+	extractUsage(result, usage)
 
 	var text string
 	// Preferred: Gemini-like schema
@@ -583,6 +831,16 @@ func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.Revi
 	if text == "" {
 		text = strings.TrimSpace(string(rawBody))
 	}
+	if geminiFinishReason(result) == "MAX_TOKENS" {
+		return truncatedReviewComments(text, "Self AI API")
+	}
+	return parseReviewComments(text, "Self AI API")
+}
+
+// parseReviewComments tolerantly extracts the {"reviews": [...]} JSON from an AI text reply.
+// Fenced blocks and preambles are stripped, and malformed JSON goes through repairReviewJSON;
+// output that still yields no findings is an ErrAIInvalidResponse.
+func parseReviewComments(text string, source string) ([]model.ReviewComment, error) {
 	text = strings.TrimSpace(text)
 	// If response includes a preamble and fenced JSON, extract fenced JSON
 	if strings.Contains(text, "```json") {
@@ -609,34 +867,67 @@ func getAIResponseOfSelf(prompt string, baseURL, modelName string) ([]model.Revi
 	}
 
 	if text == "" {
-		log.Error("Self AI API returned empty text response")
-		return []model.ReviewComment{}, nil
+		log.Errorf("%s returned empty text response", source)
+		return nil, fmt.Errorf("%s: %w: empty reply", source, ErrAIInvalidResponse)
 	}
 	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
-		log.Errorf("Self AI API response is not JSON (first 200 chars): %s", text[:min(200, len(text))])
-		log.Debugf("Self AI extracted text (first 500 chars): %s", text[:min(500, len(text))])
-		return []model.ReviewComment{}, nil
+		log.Errorf("%s response is not JSON (first 200 chars): %s", source, text[:min(200, len(text))])
+		log.Debugf("%s extracted text (first 500 chars): %s", source, text[:min(500, len(text))])
+		return nil, fmt.Errorf("%s: %w: reply is not JSON", source, ErrAIInvalidResponse)
 	}
 
 	// Sanitize control characters inside JSON string literals (e.g., literal tabs)
 	sanitized := escapeControlCharsInJSONString(text)
-	log.Debugf("Parsing self AI API response JSON (length: %d)", len(sanitized))
+	log.Debugf("Parsing %s response JSON (length: %d)", source, len(sanitized))
 
 	var respObj model.ReviewResponse
 	if err := json.Unmarshal([]byte(sanitized), &respObj); err != nil {
-		log.Errorf("Failed to parse JSON from self AI API: %v", err)
+		if items, ok := repairReviewJSON(sanitized); ok {
+			log.Warnf("Repaired malformed JSON from %s (%v); %d findings recovered", source, err, len(items))
+			return reviewComments(items), nil
+		}
+		log.Errorf("Failed to parse JSON from %s: %v", source, err)
 		log.Errorf("Raw AI response (first 500 chars): %s", text[:min(500, len(text))])
-		return []model.ReviewComment{}, nil
+		return nil, fmt.Errorf("%s: %w: %v", source, ErrAIInvalidResponse, err)
 	}
+	return reviewComments(respObj.Reviews), nil
+}
+
+// reviewComments turns the AI's findings into review comments; the caller fills in Path.
+func reviewComments(items []model.ReviewItem) []model.ReviewComment {
 	var comments []model.ReviewComment
-	for _, r := range respObj.Reviews {
-		anchor := strings.TrimSpace(r.LineText)
+	for _, r := range items {
 		comments = append(comments, model.ReviewComment{
-			Body:     r.ReviewComment,
-			Path:     "",
-			Position: r.LineNumber,
-			Anchor:   anchor,
+			Body:      r.ReviewComment,
+			Position:  r.LineNumber,
+			Anchor:    strings.TrimSpace(r.LineText),
+			StartLine: r.StartLineNumber,
+			Fix:       r.Fix,
 		})
 	}
-	return comments, nil
+	return comments
+}
+
+// truncatedReviewComments salvages the findings of a reply that was cut off at the output
+// token limit: every complete element of the "reviews" array before the cut is kept. The
+// error wraps ErrAITruncated unless the array turns out to be complete after all.
+func truncatedReviewComments(text string, source string) ([]model.ReviewComment, error) {
+	items, complete := completeReviewItems(escapeControlCharsInJSONString(text))
+	if complete {
+		return reviewComments(items), nil
+	}
+	log.Warnf("%s reply was cut off at the output token limit; %d complete findings salvaged", source, len(items))
+	return reviewComments(items), fmt.Errorf("%s: %w: %d complete findings salvaged", source, ErrAITruncated, len(items))
+}
+
+// geminiFinishReason returns why the first candidate of a generateContent reply stopped, such as
+// "STOP" or "MAX_TOKENS".
+func geminiFinishReason(result map[string]interface{}) string {
+	if c, ok := result["candidates"].([]interface{}); ok && len(c) > 0 {
+		if candidate, ok := c[0].(map[string]interface{}); ok {
+			reason, _ := candidate["finishReason"].(string)
+			return reason
+		}
+	}
+	return ""
 }
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"code_nim/model"
+	"regexp"
+	"strings"
+)
+
+var markdownHeading = regexp.MustCompile(`^\s*#{1,6}\s+`)
+
+// AdaptCommentBody rewrites a rendered comment for a provider that lacks some features:
+// without suggestion support a ```suggestion block becomes a plain code block introduced by
+// "Suggested change:", and without Markdown fenced blocks are indented by four spaces (which
+// plain-text renderers such as Gerrit show preformatted) and heading and bold markers dropped.
+func AdaptCommentBody(body string, caps model.ProviderCapabilities) string {
+	if caps.Markdown && caps.Suggestions {
+		return body
+	}
+	var out []string
+	fence := "" // Delimiter of the code block being copied, "" outside one
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && codeFence(trimmed) != "":
+			fence = codeFence(trimmed)
+			info := strings.TrimSpace(trimmed[len(fence):])
+			if strings.EqualFold(info, "suggestion") && !caps.Suggestions {
+				out = append(out, "Suggested change:")
+				line = strings.Replace(line, trimmed, fence, 1)
+			}
+			if caps.Markdown {
+				out = append(out, line)
+			}
+		case fence != "" && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "":
+			fence = ""
+			if caps.Markdown {
+				out = append(out, line)
+			}
+		case fence != "" && !caps.Markdown:
+			out = append(out, "    "+line)
+		case fence == "" && !caps.Markdown:
+			out = append(out, strings.ReplaceAll(markdownHeading.ReplaceAllString(line, ""), "**", ""))
+		default:
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// codeFence returns the ``` or ~~~ run a line opening a fenced code block starts with, or "".
+func codeFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,239 @@
+package queue
+
+import (
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrShed is returned when a low-priority job is dropped because the queue is saturated.
+var ErrShed = errors.New("queue saturated: low-priority job shed")
+
+// ErrFull is returned when the queue is at capacity and nothing can be shed to make room.
+var ErrFull = errors.New("queue full")
+
+// Stats is a snapshot of queue depth and backpressure counters.
+type Stats struct {
+	DepthHigh     int
+	DepthLow      int
+	Threshold     int
+	Capacity      int
+	Enqueued      map[string]int64 // by priority
+	Shed          map[string]int64 // by priority
+	Rejected      int64
+	Processed     int64
+	LastSaturated time.Time
+}
+
+// Saturation is the queue depth relative to the shedding threshold (1.0 = shedding starts).
+func (s Stats) Saturation() float64 {
+	if s.Threshold <= 0 {
+		return 0
+	}
+	return float64(s.DepthHigh+s.DepthLow) / float64(s.Threshold)
+}
+
+// JobQueue is what the review handler needs from a queue. Queue keeps jobs in this process;
+// RedisQueue shares them between scheduler and worker replicas.
+type JobQueue interface {
+	Push(job model.ReviewJob) (int, error)
+	Pop(stop <-chan struct{}) (model.ReviewJob, bool)
+	Stats() Stats
+}
+
+// Open builds the queue selected by settings.Backend.
+func Open(settings model.QueueSettings) (JobQueue, error) {
+	switch settings.Backend {
+	case "", model.QueueBackendMemory:
+		if settings.RoleName() != model.QueueRoleAll {
+			return nil, fmt.Errorf("queue role %q needs the redis backend", settings.Role)
+		}
+		return New(settings), nil
+	case model.QueueBackendRedis:
+		switch settings.RoleName() {
+		case model.QueueRoleAll, model.QueueRoleScheduler, model.QueueRoleWorker:
+		default:
+			return nil, fmt.Errorf("unknown queue role %q", settings.Role)
+		}
+		return NewRedisQueue(settings)
+	}
+	return nil, fmt.Errorf("unknown queue backend %q", settings.Backend)
+}
+
+// Queue is an in-memory two-level priority queue with load shedding.
+// High-priority jobs are always served first; once depth reaches the threshold, new
+// low-priority jobs are shed, and at capacity queued low-priority jobs are evicted
+// to make room for high-priority ones.
+type Queue struct {
+	mutex     sync.Mutex
+	high      []model.ReviewJob
+	low       []model.ReviewJob
+	threshold int
+	capacity  int
+	notify    chan struct{}
+	seq       int64
+	stats     Stats
+}
+
+// limits returns the shedding threshold and capacity with defaults applied.
+func limits(settings model.QueueSettings) (int, int) {
+	threshold := settings.Threshold
+	if threshold <= 0 {
+		threshold = 20
+	}
+	capacity := settings.Capacity
+	if capacity <= 0 {
+		capacity = 50
+	}
+	if capacity < threshold {
+		capacity = threshold
+	}
+	return threshold, capacity
+}
+
+func New(settings model.QueueSettings) *Queue {
+	threshold, capacity := limits(settings)
+	return &Queue{
+		threshold: threshold,
+		capacity:  capacity,
+		notify:    make(chan struct{}, 1),
+		stats: Stats{
+			Enqueued: map[string]int64{},
+			Shed:     map[string]int64{},
+		},
+	}
+}
+
+func sameTarget(a, b model.ReviewJob) bool {
+	return a.ProcessName == b.ProcessName && a.PullRequestID == b.PullRequestID
+}
+
+func (q *Queue) depth() int {
+	return len(q.high) + len(q.low)
+}
+
+// positionOf returns the 1-based position of a job in service order, or 0 if absent.
+func (q *Queue) positionOf(job model.ReviewJob) int {
+	for i, j := range q.high {
+		if sameTarget(j, job) {
+			return i + 1
+		}
+	}
+	for i, j := range q.low {
+		if sameTarget(j, job) {
+			return len(q.high) + i + 1
+		}
+	}
+	return 0
+}
+
+// Push enqueues job and returns its 1-based position. A job for a target that is already
+// queued is not duplicated; the existing position is returned (and promoted if needed).
+func (q *Queue) Push(job model.ReviewJob) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	if job.Priority != model.PriorityHigh {
+		job.Priority = model.PriorityLow
+	}
+
+	// Promote an already-queued low-priority job for the same target.
+	if job.Priority == model.PriorityHigh {
+		for i, j := range q.low {
+			if sameTarget(j, job) {
+				q.low = append(q.low[:i], q.low[i+1:]...)
+				break
+			}
+		}
+	}
+	if pos := q.positionOf(job); pos > 0 {
+		return pos, nil
+	}
+
+	if q.depth() >= q.threshold {
+		q.stats.LastSaturated = time.Now()
+		if job.Priority == model.PriorityLow {
+			q.stats.Shed[model.PriorityLow]++
+			return 0, ErrShed
+		}
+	}
+	if q.depth() >= q.capacity {
+		if len(q.low) == 0 {
+			q.stats.Rejected++
+			return 0, ErrFull
+		}
+		// Evict the newest low-priority job; older ones have waited longest.
+		q.low = q.low[:len(q.low)-1]
+		q.stats.Shed[model.PriorityLow]++
+	}
+
+	q.seq++
+	if job.ID == "" {
+		job.ID = fmt.Sprintf("%d-%d", job.EnqueuedAt.UnixNano(), q.seq)
+	}
+	if job.Priority == model.PriorityHigh {
+		q.high = append(q.high, job)
+	} else {
+		q.low = append(q.low, job)
+	}
+	q.stats.Enqueued[job.Priority]++
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return q.positionOf(job), nil
+}
+
+// Pop blocks until a job is available or stop is closed.
+func (q *Queue) Pop(stop <-chan struct{}) (model.ReviewJob, bool) {
+	for {
+		q.mutex.Lock()
+		var job model.ReviewJob
+		ok := false
+		if len(q.high) > 0 {
+			job, q.high = q.high[0], q.high[1:]
+			ok = true
+		} else if len(q.low) > 0 {
+			job, q.low = q.low[0], q.low[1:]
+			ok = true
+		}
+		if ok {
+			q.stats.Processed++
+		}
+		q.mutex.Unlock()
+		if ok {
+			return job, true
+		}
+		select {
+		case <-q.notify:
+		case <-stop:
+			return model.ReviewJob{}, false
+		}
+	}
+}
+
+// Stats returns a copy of the current queue statistics.
+func (q *Queue) Stats() Stats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	st := q.stats
+	st.DepthHigh = len(q.high)
+	st.DepthLow = len(q.low)
+	st.Threshold = q.threshold
+	st.Capacity = q.capacity
+	st.Enqueued = map[string]int64{}
+	st.Shed = map[string]int64{}
+	for k, v := range q.stats.Enqueued {
+		st.Enqueued[k] = v
+	}
+	for k, v := range q.stats.Shed {
+		st.Shed[k] = v
+	}
+	return st
+}
@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"code_nim/helper/redis"
+	"code_nim/log"
+	"code_nim/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// pushScript applies Queue.Push semantics atomically: a queued target is not duplicated (a
+// high-priority push promotes a queued low one), low jobs are shed at the threshold, and at
+// capacity the newest low job is evicted. Each queued target has a marker key holding its job.
+// KEYS: high list, low list, stats hash, target marker.
+// ARGV: job, priority, threshold, capacity, marker TTL (s), marker prefix, now (unix).
+// It returns {status, position}: 0 queued, 1 shed, 2 full.
+const pushScript = `local function position(job)
+  local i = redis.call('LPOS', KEYS[1], job)
+  if i then return i + 1 end
+  i = redis.call('LPOS', KEYS[2], job)
+  if i then return redis.call('LLEN', KEYS[1]) + i + 1 end
+  return false
+end
+local existing = redis.call('GET', KEYS[4])
+if existing then
+  if ARGV[2] == 'high' then redis.call('LREM', KEYS[2], 1, existing) end
+  local pos = position(existing)
+  if pos then return {0, pos} end
+end
+local high = redis.call('LLEN', KEYS[1])
+local low = redis.call('LLEN', KEYS[2])
+if high + low >= tonumber(ARGV[3]) then
+  redis.call('HSET', KEYS[3], 'lastSaturated', ARGV[7])
+  if ARGV[2] == 'low' then
+    redis.call('HINCRBY', KEYS[3], 'shed:low', 1)
+    return {1, 0}
+  end
+end
+if high + low >= tonumber(ARGV[4]) then
+  if low == 0 then
+    redis.call('HINCRBY', KEYS[3], 'rejected', 1)
+    return {2, 0}
+  end
+  local evicted = cjson.decode(redis.call('RPOP', KEYS[2]))
+  redis.call('DEL', ARGV[6] .. evicted.processName .. '#' .. evicted.pullRequestId)
+  redis.call('HINCRBY', KEYS[3], 'shed:low', 1)
+  low = low - 1
+end
+if ARGV[2] == 'high' then redis.call('RPUSH', KEYS[1], ARGV[1]) else redis.call('RPUSH', KEYS[2], ARGV[1]) end
+redis.call('SET', KEYS[4], ARGV[1], 'EX', ARGV[5])
+redis.call('HINCRBY', KEYS[3], 'enqueued:' .. ARGV[2], 1)
+if ARGV[2] == 'high' then return {0, high + 1} end
+return {0, high + low + 1}`
+
+const statsScript = `return {redis.call('LLEN', KEYS[1]), redis.call('LLEN', KEYS[2]), redis.call('HGETALL', KEYS[3])}`
+
+// claimScript takes a claim that is free or already held by the caller, extending it.
+const claimScript = `local v = redis.call('GET', KEYS[1])
+if v and v ~= ARGV[1] then return 0 end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1`
+
+const releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+
+// markerTTL bounds how long a target stays deduplicated if a worker dies between popping a
+// job and clearing its marker.
+const markerTTL = time.Hour
+
+// Claimer is implemented by queues shared between workers, so that two workers never post a
+// review for the same commit.
+type Claimer interface {
+	// Claim takes key for ttl, or extends it when this worker already holds it, and reports
+	// whether this worker holds it.
+	Claim(key string, ttl time.Duration) (bool, error)
+	// Release gives key up if this worker holds it.
+	Release(key string) error
+}
+
+// RedisQueue is a JobQueue kept in Redis lists, so any number of scheduler replicas can push
+// and any number of worker replicas can pop. Delivery is at most once: a job popped by a
+// worker that then crashes is picked up again by the next scheduled scan.
+type RedisQueue struct {
+	client    *redis.Client
+	prefix    string
+	owner     string
+	threshold int
+	capacity  int
+}
+
+func NewRedisQueue(settings model.QueueSettings) (*RedisQueue, error) {
+	if settings.Redis.Addr == "" {
+		return nil, errors.New("redis queue: addr is required")
+	}
+	prefix := settings.Redis.Prefix
+	if prefix == "" {
+		prefix = "code-nim:queue"
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("redis queue: %w", err)
+	}
+	threshold, capacity := limits(settings)
+	return &RedisQueue{
+		client:    redis.NewClient(settings.Redis.Addr, settings.Redis.Password, settings.Redis.DB, settings.Redis.TLS),
+		prefix:    prefix,
+		owner:     fmt.Sprintf("%s-%d", host, os.Getpid()),
+		threshold: threshold,
+		capacity:  capacity,
+	}, nil
+}
+
+func (r *RedisQueue) key(name string) string {
+	return r.prefix + ":" + name
+}
+
+func (r *RedisQueue) marker(job model.ReviewJob) string {
+	return fmt.Sprintf("%s%s#%d", r.key("queued:"), job.ProcessName, job.PullRequestID)
+}
+
+// Push enqueues job and returns its 1-based position, with the same deduplication and
+// shedding rules as Queue.Push.
+func (r *RedisQueue) Push(job model.ReviewJob) (int, error) {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	if job.Priority != model.PriorityHigh {
+		job.Priority = model.PriorityLow
+	}
+	if job.ID == "" {
+		job.ID = fmt.Sprintf("%d-%s", job.EnqueuedAt.UnixNano(), r.owner)
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return 0, err
+	}
+	reply, err := r.client.Do("EVAL", pushScript, "4",
+		r.key("high"), r.key("low"), r.key("stats"), r.marker(job),
+		string(data), job.Priority, strconv.Itoa(r.threshold), strconv.Itoa(r.capacity),
+		strconv.Itoa(int(markerTTL.Seconds())), r.key("queued:"), strconv.FormatInt(time.Now().Unix(), 10))
+	if err != nil {
+		return 0, fmt.Errorf("redis queue: %w", err)
+	}
+	result, ok := reply.([]interface{})
+	if !ok || len(result) != 2 {
+		return 0, fmt.Errorf("redis queue: unexpected push reply %v", reply)
+	}
+	pos, _ := result[1].(int64)
+	switch result[0] {
+	case int64(1):
+		return 0, ErrShed
+	case int64(2):
+		return 0, ErrFull
+	}
+	return int(pos), nil
+}
+
+// Pop blocks until a job is available or stop is closed. Redis errors are logged and retried.
+func (r *RedisQueue) Pop(stop <-chan struct{}) (model.ReviewJob, bool) {
+	for {
+		select {
+		case <-stop:
+			return model.ReviewJob{}, false
+		default:
+		}
+		reply, err := r.client.DoTimeout(10*time.Second, "BLPOP", r.key("high"), r.key("low"), "5")
+		if err != nil {
+			log.Warnf("Redis queue pop failed: %v", err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-stop:
+				return model.ReviewJob{}, false
+			}
+			continue
+		}
+		pair, ok := reply.([]interface{})
+		if !ok || len(pair) != 2 {
+			continue // timed out with nothing queued
+		}
+		data, _ := pair[1].(string)
+		var job model.ReviewJob
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			log.Warnf("Dropping malformed job from redis queue: %v", err)
+			continue
+		}
+		if _, err := r.client.Do("DEL", r.marker(job)); err != nil {
+			log.Warnf("Redis queue: clearing marker of job %s: %v", job.ID, err)
+		}
+		if _, err := r.client.Do("HINCRBY", r.key("stats"), "processed", "1"); err != nil {
+			log.Debugf("Redis queue: counting job %s: %v", job.ID, err)
+		}
+		return job, true
+	}
+}
+
+// Stats returns cluster-wide depth and counters. On a Redis error only the limits are set.
+func (r *RedisQueue) Stats() Stats {
+	st := Stats{
+		Threshold: r.threshold,
+		Capacity:  r.capacity,
+		Enqueued:  map[string]int64{},
+		Shed:      map[string]int64{},
+	}
+	reply, err := r.client.Do("EVAL", statsScript, "3", r.key("high"), r.key("low"), r.key("stats"))
+	if err != nil {
+		log.Warnf("Redis queue stats failed: %v", err)
+		return st
+	}
+	result, ok := reply.([]interface{})
+	if !ok || len(result) != 3 {
+		return st
+	}
+	high, _ := result[0].(int64)
+	low, _ := result[1].(int64)
+	st.DepthHigh, st.DepthLow = int(high), int(low)
+	fields, _ := result[2].([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		n, _ := strconv.ParseInt(value, 10, 64)
+		switch name {
+		case "enqueued:high", "enqueued:low":
+			st.Enqueued[name[len("enqueued:"):]] = n
+		case "shed:high", "shed:low":
+			st.Shed[name[len("shed:"):]] = n
+		case "rejected":
+			st.Rejected = n
+		case "processed":
+			st.Processed = n
+		case "lastSaturated":
+			st.LastSaturated = time.Unix(n, 0)
+		}
+	}
+	return st
+}
+
+// Claim takes key for ttl unless another worker holds it.
+func (r *RedisQueue) Claim(key string, ttl time.Duration) (bool, error) {
+	reply, err := r.client.Do("EVAL", claimScript, "1", r.key("claim:"+key), r.owner, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, fmt.Errorf("redis queue claim: %w", err)
+	}
+	return reply == int64(1), nil
+}
+
+// Release drops key if this worker holds it.
+func (r *RedisQueue) Release(key string) error {
+	if _, err := r.client.Do("EVAL", releaseScript, "1", r.key("claim:"+key), r.owner); err != nil {
+		return fmt.Errorf("redis queue release: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+// Package ratelimit admits the AI requests of all jobs under shared per-minute request (RPM)
+// and token (TPM) limits. Requests over the limits wait in a queue per repository and are
+// admitted round-robin between repositories, so one busy repository cannot starve the others.
+package ratelimit
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTimeout marks a request that waited longer than maxWait for a slot.
+var ErrTimeout = errors.New("no AI request slot became free")
+
+const (
+	// window is the span the RPM and TPM limits count over.
+	window = time.Minute
+	// rateLimitedCooldown pauses a model after the provider answered 429 despite the limits.
+	rateLimitedCooldown = 30 * time.Second
+)
+
+// grant is an admitted request; tokens start as the estimate and become the reported usage.
+type grant struct {
+	at     time.Time
+	tokens int
+}
+
+// bucket tracks the requests admitted to one model within the last window.
+type bucket struct {
+	limit       model.AIRateLimit
+	grants      []*grant // in admission order
+	pausedUntil time.Time
+}
+
+// admitAt returns when a request of tokens fits the limits of b, which is now when it fits at once.
+func (b *bucket) admitAt(now time.Time, tokens int) time.Time {
+	cut := now.Add(-window)
+	for len(b.grants) > 0 && !b.grants[0].at.After(cut) {
+		b.grants = b.grants[1:]
+	}
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil
+	}
+	if b.limit.RPM > 0 && len(b.grants) >= b.limit.RPM {
+		return b.grants[len(b.grants)-b.limit.RPM].at.Add(window)
+	}
+	if b.limit.TPM <= 0 || len(b.grants) == 0 {
+		return now
+	}
+	used := 0
+	for _, g := range b.grants {
+		used += g.tokens
+	}
+	if used+tokens <= b.limit.TPM {
+		return now
+	}
+	// A request larger than the whole TPM still runs once the window is empty
+	for _, g := range b.grants {
+		if used -= g.tokens; used+tokens <= b.limit.TPM {
+			return g.at.Add(window)
+		}
+	}
+	return b.grants[len(b.grants)-1].at.Add(window)
+}
+
+type waiter struct {
+	repo   string
+	model  string
+	tokens int
+	since  time.Time
+	ready  chan *grant
+}
+
+// Stats describes the requests the scheduler has seen since it started.
+type Stats struct {
+	Admitted int64
+	Queued   int64         // requests that had to wait for a slot
+	Timeouts int64         // requests that gave up after maxWait
+	Waited   time.Duration // total wait of the admitted requests
+	// Waiting counts the requests waiting now, by repository.
+	Waiting map[string]int
+}
+
+// Scheduler queues AI requests until they fit the limits of their model. A nil Scheduler
+// admits every request at once.
+type Scheduler struct {
+	settings model.AIRateLimitSettings
+	maxWait  time.Duration
+	reserved int
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	queues  map[string][]*waiter
+	repos   []string // repositories with waiting requests, in round-robin order
+	next    int
+	timer   *time.Timer
+	stats   Stats
+}
+
+// New returns the scheduler of s, or nil when s sets no limit.
+func New(s model.AIRateLimitSettings) *Scheduler {
+	if !s.Enabled() {
+		return nil
+	}
+	maxWait, ok := s.MaxWaitDuration()
+	if !ok {
+		log.Warnf("Invalid aiRateLimit.maxWait %q; using %s", s.MaxWait, maxWait)
+	}
+	return &Scheduler{
+		settings: s,
+		maxWait:  maxWait,
+		reserved: s.ReservedResponseTokens(),
+		buckets:  map[string]*bucket{},
+		queues:   map[string][]*waiter{},
+	}
+}
+
+// Acquire waits until a request from repo to modelName with a prompt of about promptTokens
+// fits the limits, reserving the configured response tokens on top. The returned done
+// reports the tokens the request used (0 keeps the estimate) and whether the provider still
+// answered 429, which pauses the model for 30s. A request that waits longer than maxWait
+// fails with ErrTimeout.
+func (s *Scheduler) Acquire(repo, modelName string, promptTokens int) (func(tokens int, rateLimited bool), error) {
+	if s == nil {
+		return func(int, bool) {}, nil
+	}
+	w := &waiter{
+		repo:   repo,
+		model:  strings.ToLower(strings.TrimSpace(modelName)),
+		tokens: promptTokens + s.reserved,
+		since:  time.Now(),
+		ready:  make(chan *grant, 1),
+	}
+	s.mutex.Lock()
+	if len(s.queues[repo]) == 0 {
+		s.repos = append(s.repos, repo)
+	}
+	s.queues[repo] = append(s.queues[repo], w)
+	s.dispatch()
+	if len(w.ready) == 0 {
+		s.stats.Queued++
+		log.Debugf("AI request of %s to %s is queued behind the rate limit (%d waiting)", repo, modelName, s.waiting())
+	}
+	s.mutex.Unlock()
+
+	timer := time.NewTimer(s.maxWait)
+	defer timer.Stop()
+	select {
+	case g := <-w.ready:
+		return s.done(w.model, g), nil
+	case <-timer.C:
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case g := <-w.ready:
+		return s.done(w.model, g), nil
+	default:
+	}
+	s.remove(w)
+	s.stats.Timeouts++
+	return nil, fmt.Errorf("%w for %s within %s", ErrTimeout, modelName, s.maxWait)
+}
+
+// done returns the completion callback of g.
+func (s *Scheduler) done(modelName string, g *grant) func(tokens int, rateLimited bool) {
+	return func(tokens int, rateLimited bool) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if tokens > 0 {
+			g.tokens = tokens
+		}
+		if rateLimited {
+			log.Warnf("AI provider rate limited %s despite aiRateLimit; pausing its requests for %s", modelName, rateLimitedCooldown)
+			s.bucket(modelName).pausedUntil = time.Now().Add(rateLimitedCooldown)
+		}
+		s.dispatch()
+	}
+}
+
+// bucket returns the bucket of modelName, creating it with the model's limits.
+func (s *Scheduler) bucket(modelName string) *bucket {
+	b, ok := s.buckets[modelName]
+	if !ok {
+		b = &bucket{limit: s.settings.Limit(modelName)}
+		s.buckets[modelName] = b
+	}
+	return b
+}
+
+// dispatch admits the first waiting request of each repository in turn while they fit, and
+// schedules the next try for when the earliest of the others will. Callers hold the mutex.
+func (s *Scheduler) dispatch() {
+	now := time.Now()
+	var wake time.Time
+	for admitted := true; admitted && len(s.repos) > 0; {
+		admitted, wake = false, time.Time{}
+		for i := 0; i < len(s.repos); i++ {
+			idx := (s.next + i) % len(s.repos)
+			w := s.queues[s.repos[idx]][0]
+			b := s.bucket(w.model)
+			if at := b.admitAt(now, w.tokens); at.After(now) {
+				if wake.IsZero() || at.Before(wake) {
+					wake = at
+				}
+				continue
+			}
+			g := &grant{at: now, tokens: w.tokens}
+			b.grants = append(b.grants, g)
+			w.ready <- g
+			s.stats.Admitted++
+			s.stats.Waited += now.Sub(w.since)
+			s.next = idx + 1
+			s.remove(w)
+			admitted = true
+			break
+		}
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if !wake.IsZero() {
+		s.timer = time.AfterFunc(wake.Sub(now), func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.dispatch()
+		})
+	}
+}
+
+// remove takes w out of its queue, and its repository out of the order once nothing waits.
+func (s *Scheduler) remove(w *waiter) {
+	queue := s.queues[w.repo]
+	for i, q := range queue {
+		if q == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		s.queues[w.repo] = queue
+		return
+	}
+	delete(s.queues, w.repo)
+	for i, repo := range s.repos {
+		if repo == w.repo {
+			s.repos = append(s.repos[:i], s.repos[i+1:]...)
+			if s.next > i {
+				s.next--
+			}
+			break
+		}
+	}
+}
+
+// waiting counts the requests in all queues. Callers hold the mutex.
+func (s *Scheduler) waiting() int {
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Stats returns the counters of the scheduler and the requests waiting now.
+func (s *Scheduler) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	st := s.stats
+	st.Waiting = map[string]int{}
+	for repo, q := range s.queues {
+		st.Waiting[repo] = len(q)
+	}
+	return st
+}
+
+// Repos returns the repositories of st.Waiting, sorted.
+func (st Stats) Repos() []string {
+	repos := make([]string, 0, len(st.Waiting))
+	for repo := range st.Waiting {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	return repos
+}
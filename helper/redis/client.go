@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxIdleConns is the number of idle connections a Client keeps for reuse.
+const maxIdleConns = 4
+
+// Client runs Redis commands over RESP on a small pool of persistent connections, which are
+// authenticated and switched to DB once, when they are opened. It is enough for locks and
+// queues that issue a few commands per second, without a client library.
+type Client struct {
+	Addr     string
+	Password string
+	DB       int
+	TLS      *tls.Config // connect over TLS with this config; nil for plain TCP
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+// conn is a pooled connection and its reader, which may hold buffered reply bytes.
+type conn struct {
+	net.Conn
+	rd *bufio.Reader
+}
+
+// NewClient returns a client of the server at addr. With useTLS it connects over TLS and
+// verifies the server against the system roots.
+func NewClient(addr, password string, db int, useTLS bool) *Client {
+	c := &Client{Addr: addr, Password: password, DB: db}
+	if useTLS {
+		c.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return c
+}
+
+// Do runs a command and returns its reply: string for simple and bulk strings, int64 for
+// integers, []interface{} for arrays, and nil for nil replies. Redis errors are returned as error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	return c.DoTimeout(5*time.Second, args...)
+}
+
+// DoTimeout is Do with a custom deadline, for blocking commands such as BLPOP. A pooled
+// connection that the server closed while it was idle is replaced and the command sent again.
+func (c *Client) DoTimeout(timeout time.Duration, args ...string) (interface{}, error) {
+	cn, reused, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.roundTrip(cn, timeout, args)
+	if err != nil && reused && isClosed(err) {
+		if cn, err = c.dial(); err != nil {
+			return nil, err
+		}
+		reply, err = c.roundTrip(cn, timeout, args)
+	}
+	return reply, err
+}
+
+// roundTrip sends args on cn and returns cn to the pool unless the connection failed.
+func (c *Client) roundTrip(cn *conn, timeout time.Duration, args []string) (interface{}, error) {
+	_ = cn.SetDeadline(time.Now().Add(timeout))
+	reply, err := call(cn, cn.rd, args...)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// get returns an idle connection, or a new one, and whether it was idle.
+func (c *Client) get() (*conn, bool, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, true, nil
+	}
+	c.mu.Unlock()
+	cn, err := c.dial()
+	return cn, false, err
+}
+
+// put keeps cn for reuse, or closes it when the pool is full.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxIdleConns {
+		cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+// dial opens a connection and authenticates it and selects DB.
+func (c *Client) dial() (*conn, error) {
+	dialer := &net.Dialer{Timeout: 3 * time.Second}
+	var nc net.Conn
+	var err error
+	if c.TLS != nil {
+		nc, err = tls.DialWithDialer(dialer, "tcp", c.Addr, c.TLS)
+	} else {
+		nc, err = dialer.Dial("tcp", c.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	cn := &conn{Conn: nc, rd: bufio.NewReader(nc)}
+	_ = cn.SetDeadline(time.Now().Add(5 * time.Second))
+	if c.Password != "" {
+		if _, err := call(cn, cn.rd, "AUTH", c.Password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := call(cn, cn.rd, "SELECT", strconv.Itoa(c.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// isClosed reports whether err means the server had closed the connection.
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// Error is an error reply of the server; the connection stays usable.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+func call(w io.Writer, rd *bufio.Reader, args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	reply, err := readReply(rd)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %s: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]interface{}, n)
+		for i := range out {
+			if out[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
@@ -0,0 +1,164 @@
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeServer answers AUTH, SELECT and PING and records the commands and connections it gets.
+type fakeServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	conns    int
+	commands []string
+	open     []net.Conn
+}
+
+func startFakeServer(t *testing.T, ln net.Listener) *fakeServer {
+	s := &fakeServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.open = append(s.open, c)
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	rd := bufio.NewReader(c)
+	for {
+		req, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		args, _ := req.([]interface{})
+		if len(args) == 0 {
+			return
+		}
+		name, _ := args[0].(string)
+		s.mu.Lock()
+		s.commands = append(s.commands, name)
+		s.mu.Unlock()
+		reply := "+OK\r\n"
+		if name == "PING" {
+			reply = "+PONG\r\n"
+		}
+		if name == "FAIL" {
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+// closeAll closes the server side of every connection, as Redis does with idle clients.
+func (s *fakeServer) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.open {
+		c.Close()
+	}
+	s.open = nil
+}
+
+func (s *fakeServer) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.commands {
+		if c == name {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *fakeServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	srv := startFakeServer(t, listen(t))
+	c := NewClient(srv.ln.Addr().String(), "secret", 2, false)
+	for i := 0; i < 3; i++ {
+		if reply, err := c.Do("PING"); err != nil || reply != "PONG" {
+			t.Fatalf("Do(PING) = %v, %v", reply, err)
+		}
+	}
+	// An error reply leaves the connection usable
+	if _, err := c.Do("FAIL"); err == nil {
+		t.Fatal("Do(FAIL) succeeded")
+	}
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.connections(); n != 1 {
+		t.Errorf("opened %d connections, want 1", n)
+	}
+	if n := srv.count("AUTH"); n != 1 {
+		t.Errorf("sent AUTH %d times, want 1", n)
+	}
+	if n := srv.count("SELECT"); n != 1 {
+		t.Errorf("sent SELECT %d times, want 1", n)
+	}
+}
+
+func TestClientRedialsClosedConnection(t *testing.T) {
+	srv := startFakeServer(t, listen(t))
+	c := NewClient(srv.ln.Addr().String(), "", 0, false)
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	srv.closeAll()
+	if reply, err := c.Do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("Do(PING) after the server closed the connection = %v, %v", reply, err)
+	}
+	if n := srv.connections(); n != 2 {
+		t.Errorf("opened %d connections, want 2", n)
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	// The test server only lends its certificate and the client config that trusts it
+	certSrv := httptest.NewTLSServer(nil)
+	defer certSrv.Close()
+	ln := tls.NewListener(listen(t), &tls.Config{Certificates: certSrv.TLS.Certificates})
+	srv := startFakeServer(t, ln)
+
+	c := &Client{Addr: srv.ln.Addr().String(), TLS: certSrv.Client().Transport.(*http.Transport).TLSClientConfig}
+	if reply, err := c.Do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("Do(PING) over TLS = %v, %v", reply, err)
+	}
+
+	untrusted := NewClient(srv.ln.Addr().String(), "", 0, true)
+	if _, err := untrusted.Do("PING"); err == nil {
+		t.Error("Do(PING) trusted a certificate outside the system roots")
+	}
+}
@@ -0,0 +1,45 @@
+package helper
+
+import (
+	"code_nim/model"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MatchRepositories returns the slugs of available that match any of patterns, sorted. Literal
+// patterns match case-insensitively, like Bitbucket slugs; globs use path.Match syntax.
+func MatchRepositories(patterns, available []string) []string {
+	var out []string
+	for _, slug := range available {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(slug)); ok {
+				out = append(out, slug)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// validRepoPattern reports whether a repoSlug glob is well-formed.
+func validRepoPattern(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// ExpandRepoEntry returns one entry per slug, copied from auto. A named entry gets
+// "<processName>/<slug>" names so each repository has its own job.
+func ExpandRepoEntry(auto model.AutoReviewPR, slugs []string) []model.AutoReviewPR {
+	out := make([]model.AutoReviewPR, 0, len(slugs))
+	for _, slug := range slugs {
+		e := auto
+		e.RepoSlug, e.RepoSlugs = slug, nil
+		if strings.TrimSpace(auto.ProcessName) != "" {
+			e.ProcessName = auto.ProcessName + "/" + slug
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ReviewProfile is a built-in set of review rules for a kind of infrastructure file. It replaces
+// the language focus of the inline prompt when its path pattern matches.
+type ReviewProfile struct {
+	Name    string
+	Fence   string   // Code fence of suggested changes
+	Comment string   // Line comment with one %s, used to label Before/After snippets
+	Checks  []string // What the reviewer must look for, most important first
+	// MinSeverity, when set, is the lowest severity of the [Potential issue] findings on files
+	// of the profile; lower ones are raised to it (see EscalateSeverity).
+	MinSeverity string
+}
+
+var (
+	dockerfileProfile = ReviewProfile{Name: "Dockerfile", Fence: "dockerfile", Comment: "# %s", Checks: []string{
+		"Base images pinned to a version tag or, better, a digest; flag :latest and untagged images.",
+		"A non-root USER for the final stage.",
+		"Secrets, tokens or private keys copied into or built into a layer (COPY, ARG, ENV), including in earlier build stages.",
+		"Package manager caches and build tools left in the final image; prefer multi-stage builds.",
+		"apt-get/apk/yum installs without pinned versions or without --no-install-recommends / --no-cache.",
+		"ADD used for remote URLs or where COPY suffices; curl | sh without checksum verification.",
+		"Missing HEALTHCHECK and exposed ports that are not needed.",
+	}}
+	kubernetesProfile = ReviewProfile{Name: "Kubernetes manifest", Fence: "yaml", Comment: "# %s", Checks: []string{
+		"securityContext: runAsNonRoot, readOnlyRootFilesystem, allowPrivilegeEscalation: false, dropped capabilities; flag privileged: true, hostNetwork, hostPID and hostPath mounts.",
+		"resources.requests and resources.limits for CPU and memory on every container.",
+		"Images pinned to a version or digest, never :latest, with a sensible imagePullPolicy.",
+		"liveness and readiness probes on long-running workloads.",
+		"Secrets in plain ConfigMaps or literal env values; base64 in a Secret is not encryption.",
+		"Changes that delete or recreate stateful objects (PersistentVolumeClaims, StatefulSet volumeClaimTemplates, changed selectors or immutable fields) and would lose data or cause downtime.",
+		"RBAC rules granting wildcards or cluster-admin, and Services exposed as LoadBalancer/NodePort without need.",
+	}}
+	helmProfile = ReviewProfile{Name: "Helm chart", Fence: "yaml", Comment: "# %s", Checks: []string{
+		"Every Kubernetes manifest rule: security contexts, resource requests and limits, pinned images, probes, and secret handling, through the values that feed them.",
+		"Values referenced in templates that have no default in values.yaml, and defaults that are insecure (privileged, latest tags, empty resources).",
+		"Template logic: missing quote/toYaml/nindent, wrong indentation, and required values not enforced with required.",
+		"Chart.yaml version bumped when templates or values change, and dependency versions pinned.",
+		"Renames of resources or selector labels that make upgrades delete and recreate workloads or volumes.",
+	}}
+	terraformProfile = ReviewProfile{Name: "Terraform configuration", Fence: "hcl", Comment: "# %s", Checks: []string{
+		"State-destructive changes: renamed resources or modules without a moved block, changed attributes that force replacement of databases, buckets, volumes or clusters, and missing lifecycle { prevent_destroy = true } on stateful resources.",
+		"Provider and module versions pinned with version constraints; modules sourced from a fixed ref.",
+		"Resources exposed to 0.0.0.0/0 or ::/0, public buckets, and IAM policies with wildcard actions or resources.",
+		"Encryption at rest and in transit, logging, and backups disabled or not configured.",
+		"Hard-coded secrets, credentials in variables without sensitive = true, and secrets written to outputs.",
+		"count/for_each changes that re-index existing resources.",
+	}}
+	migrationProfile = ReviewProfile{Name: "database migration", Fence: "sql", Comment: "-- %s", MinSeverity: "Major", Checks: []string{
+		"DDL that locks busy tables: indexes created without CONCURRENTLY (PostgreSQL) or ALGORITHM=INPLACE/LOCK=NONE (MySQL), ALTER COLUMN TYPE that rewrites the table, and NOT NULL columns or constraints added without a default or in one step on existing rows.",
+		"New foreign keys without an index on the referencing columns, and foreign keys added without NOT VALID followed by a separate VALIDATE on large tables.",
+		"Irreversible or data-losing operations: DROP TABLE/COLUMN, column renames and type narrowing, TRUNCATE, and DELETE/UPDATE without a WHERE clause; they need a backup or expand-and-contract plan that keeps the running application version working.",
+		"A missing or incomplete down migration: a golang-migrate *.up.sql without its *.down.sql, a goose file without a -- +goose Down section, or a Down that does not reverse every statement of Up.",
+		"Data backfills mixed into schema changes in one transaction, and long-running statements without batching or a lock_timeout/statement_timeout.",
+		"Edits to a migration that may already have run; changes belong in a new migration with the next version.",
+	}}
+)
+
+// migrationFileName matches the file names of Flyway (V1__x.sql, U1__x.sql, R__x.sql),
+// golang-migrate (1_x.up.sql) and goose (20240101120000_x.sql) migrations.
+var migrationFileName = regexp.MustCompile(`^(?:[vur]\d*(?:[._]\d+)*__.+|\d+_.+)\.sql$`)
+
+// isMigrationFile reports whether a lower-cased path is a SQL migration: a .sql file in a
+// migrations directory, one named like a migration tool expects, or one with goose annotations.
+func isMigrationFile(p string, lines []string) bool {
+	if path.Ext(p) != ".sql" {
+		return false
+	}
+	for _, dir := range []string{"/migrations/", "/migration/", "/migrate/", "/db/migrate/", "/flyway/"} {
+		if strings.Contains("/"+p, dir) {
+			return true
+		}
+	}
+	if migrationFileName.MatchString(path.Base(p)) {
+		return true
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "-- +goose ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isKubernetesManifest reports whether diff lines look like a Kubernetes object.
+func isKubernetesManifest(lines []string) bool {
+	hasAPIVersion, hasKind := false, false
+	for _, line := range lines {
+		t := strings.TrimSpace(strings.TrimLeft(line, "+- "))
+		hasAPIVersion = hasAPIVersion || strings.HasPrefix(t, "apiVersion:")
+		hasKind = hasKind || strings.HasPrefix(t, "kind:")
+	}
+	return hasAPIVersion && hasKind
+}
+
+// ReviewProfileFor picks the built-in review profile of a file from its path and, for plain
+// YAML, from the diff lines. It returns nil when no profile applies.
+func ReviewProfileFor(filePath string, lines []string) *ReviewProfile {
+	p := strings.ToLower(filePath)
+	base := path.Base(p)
+	ext := path.Ext(base)
+	switch {
+	case base == "dockerfile" || strings.HasPrefix(base, "dockerfile.") || ext == ".dockerfile" || base == "containerfile":
+		return &dockerfileProfile
+	case isMigrationFile(p, lines):
+		return &migrationProfile
+	case ext == ".tf" || ext == ".tfvars" || base == "terragrunt.hcl":
+		return &terraformProfile
+	case base == "chart.yaml" || base == "values.yaml" || (strings.HasPrefix(base, "values-") && (ext == ".yaml" || ext == ".yml")) ||
+		ext == ".tpl" || strings.Contains("/"+p, "/charts/") || (strings.Contains("/"+p, "/templates/") && (ext == ".yaml" || ext == ".yml")):
+		return &helmProfile
+	case ext == ".yaml" || ext == ".yml":
+		if base == "kustomization.yaml" || base == "kustomization.yml" || isKubernetesManifest(lines) {
+			return &kubernetesProfile
+		}
+		for _, dir := range []string{"/k8s/", "/kubernetes/", "/manifests/", "/deploy/"} {
+			if strings.Contains("/"+p, dir) {
+				return &kubernetesProfile
+			}
+		}
+	}
+	return nil
+}
+
+// Instructions renders the profile as a block of the inline review prompt.
+func (rp *ReviewProfile) Instructions() string {
+	var b strings.Builder
+	if rp.MinSeverity != "" {
+		fmt.Fprintf(&b, "- This is a %s: review it against this checklist and treat violations as [Potential issue] findings with Severity %s or higher:\n", rp.Name, rp.MinSeverity)
+	} else {
+		fmt.Fprintf(&b, "- This is a %s: review it against this checklist and treat violations as findings with a fitting severity:\n", rp.Name)
+	}
+	for _, c := range rp.Checks {
+		b.WriteString("  - " + c + "\n")
+	}
+	return b.String()
+}
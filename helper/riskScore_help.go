@@ -0,0 +1,127 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultSeverityWeights are the risk points of an open finding by severity. A finding without
+// a known severity counts as Major.
+var DefaultSeverityWeights = map[string]int{
+	"critical": 30,
+	"major":    10,
+	"minor":    3,
+	"trivial":  1,
+	"info":     0,
+}
+
+// Caps of the risk score and of its size and critical-path parts, so that neither outweighs
+// the findings.
+const (
+	maxSizePoints        = 20
+	maxCriticalPathFiles = 3
+	maxRiskScore         = 100
+)
+
+// ScoreRisk rates the merge risk of a pull request from its open findings, the size of diff
+// and the files it changes under criticalPaths, with the weights and thresholds of settings.
+// The result carries each part of the score; the caller fills in the pull request.
+func ScoreRisk(settings model.RiskScoreSettings, criticalPaths []string, findings []model.Finding, diff string) model.RiskScore {
+	r := model.RiskScore{Findings: map[string]int{}}
+
+	weight := func(severity string) int {
+		s := strings.ToLower(strings.TrimSpace(severity))
+		if !ValidSeverity(s) || s == SeverityNone {
+			s = "major"
+		}
+		if w, ok := settings.SeverityWeights[s]; ok {
+			return w
+		}
+		return DefaultSeverityWeights[s]
+	}
+	points := 0.0
+	for _, f := range findings {
+		severity := strings.TrimSpace(f.Severity)
+		if severity == "" {
+			severity = "Unrated"
+		}
+		r.Findings[severity]++
+		factor := 1.0
+		for category, w := range settings.CategoryWeights {
+			if strings.EqualFold(category, strings.TrimSpace(f.Type)) {
+				factor = w
+			}
+		}
+		points += float64(weight(f.Severity)) * factor
+	}
+	r.FindingPoints = int(points + 0.5)
+
+	stats := ComputeDiffStats(diff)
+	r.ChangedLines = stats.Added + stats.Removed
+	perPoint := settings.LinesPerPoint
+	if perPoint == 0 {
+		perPoint = model.DefaultRiskLinesPerPoint
+	}
+	if perPoint > 0 {
+		r.SizePoints = min(r.ChangedLines/perPoint, maxSizePoints)
+	}
+
+	r.CriticalFiles = CriticalFiles(criticalPaths, diff)
+	perFile := settings.CriticalPathPoints
+	if perFile <= 0 {
+		perFile = model.DefaultRiskCriticalPathScore
+	}
+	r.CriticalPoints = perFile * min(len(r.CriticalFiles), maxCriticalPathFiles)
+
+	r.Score = min(r.FindingPoints+r.SizePoints+r.CriticalPoints, maxRiskScore)
+	caution, block := settings.Thresholds()
+	switch {
+	case r.Score >= block:
+		r.Recommendation = model.MergeRecommendationBlock
+	case r.Score >= caution:
+		r.Recommendation = model.MergeRecommendationCaution
+	default:
+		r.Recommendation = model.MergeRecommendationMerge
+	}
+	return r
+}
+
+// FormatMergeRecommendation renders the "Merge recommendation" line of the summary, with what
+// made up the score.
+func FormatMergeRecommendation(r model.RiskScore) string {
+	verdict := "✅ Looks safe to merge"
+	switch r.Recommendation {
+	case model.MergeRecommendationBlock:
+		verdict = "❌ Resolve the open findings before merging"
+	case model.MergeRecommendationCaution:
+		verdict = "⚠️ Merge with care"
+	}
+
+	var parts []string
+	if len(r.Findings) > 0 {
+		severities := make([]string, 0, len(r.Findings))
+		for s := range r.Findings {
+			severities = append(severities, s)
+		}
+		sort.Slice(severities, func(i, j int) bool {
+			if ri, rj := SeverityRank(severities[i]), SeverityRank(severities[j]); ri != rj {
+				return ri > rj
+			}
+			return severities[i] < severities[j]
+		})
+		counts := make([]string, len(severities))
+		for i, s := range severities {
+			counts[i] = fmt.Sprintf("%d %s", r.Findings[s], s)
+		}
+		parts = append(parts, "open findings: "+strings.Join(counts, ", "))
+	} else {
+		parts = append(parts, "no open findings")
+	}
+	parts = append(parts, fmt.Sprintf("%d %s", r.ChangedLines, Pluralize(r.ChangedLines, "changed line", "changed lines")))
+	if n := len(r.CriticalFiles); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", n, Pluralize(n, "critical-path file", "critical-path files")))
+	}
+	return fmt.Sprintf("**Merge recommendation:** %s — risk **%d/100** (%s)", verdict, r.Score, strings.Join(parts, "; "))
+}
@@ -0,0 +1,201 @@
+package secrets
+
+import (
+	"bytes"
+	"code_nim/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWS reads secrets from AWS Secrets Manager with GetSecretValue. A reference is
+// <secret id or ARN>[#<key>]; with a key the SecretString is read as a JSON object and the
+// key's value is returned.
+type AWS struct {
+	region   string
+	endpoint string
+	client   *http.Client
+}
+
+func NewAWS(settings model.AWSSecretsSettings, client *http.Client) *AWS {
+	return &AWS{
+		region:   settings.Region,
+		endpoint: strings.TrimRight(settings.Endpoint, "/"),
+		client:   client,
+	}
+}
+
+func (a *AWS) Fetch(ref string) (string, error) {
+	id, key, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", errors.New("missing secret id")
+	}
+	var out struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary string  `json:"SecretBinary"`
+	}
+	err := CallAWS(a.client, "secretsmanager", a.endpoint, a.regionFor(id), "secretsmanager.GetSecretValue",
+		map[string]string{"SecretId": id}, &out)
+	if err != nil {
+		return "", err
+	}
+	var value string
+	if out.SecretString != nil {
+		value = *out.SecretString
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("decode SecretBinary: %w", err)
+		}
+		value = string(decoded)
+	}
+	if key == "" {
+		return value, nil
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot read key %q", key)
+	}
+	return pickKey(data, key)
+}
+
+// regionFor returns the configured region, else the region of an ARN id, else AWS_REGION.
+func (a *AWS) regionFor(id string) string {
+	if a.region != "" {
+		return a.region
+	}
+	return RegionOf(id)
+}
+
+// RegionOf returns the region of an ARN such as arn:aws:kms:<region>:<account>:key/<id>,
+// else AWS_REGION or AWS_DEFAULT_REGION.
+func RegionOf(arn string) string {
+	if parts := strings.SplitN(arn, ":", 5); len(parts) == 5 && parts[0] == "arn" && parts[3] != "" {
+		return parts[3]
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// CallAWS sends in as a JSON 1.1 request to the target action of an AWS service, signed with the
+// credentials of AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and decodes the
+// reply into out. An empty endpoint uses https://<service>.<region>.amazonaws.com.
+func CallAWS(client *http.Client, service, endpoint, region, target string, in, out interface{}) error {
+	if region == "" {
+		return errors.New("aws region not configured: set AWS_REGION")
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return errors.New("aws credentials not configured: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if endpoint == "" {
+		endpoint = "https://" + service + "." + region + ".amazonaws.com"
+	}
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signV4(req, body, accessKey, secretKey, region, service, time.Now().UTC())
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", service, resp.Status, strings.TrimSpace(string(raw)))
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", service, err)
+	}
+	return nil
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, signing the host, the
+// content type when set, the x-amz-* headers and the body.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = strings.TrimSpace(contentType)
+	}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := sortedNames(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	var pairs []string
+	for _, k := range sortedNames(values) {
+		for _, v := range values[k] {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.ReplaceAll(strings.Join(pairs, "&"), "+", "%20")
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"code_nim/model"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Backend fetches the secrets of one reference scheme.
+type Backend interface {
+	// Fetch returns the secret named by ref, the part of the reference after the scheme.
+	Fetch(ref string) (string, error)
+}
+
+// Resolver replaces secret references in credential fields with the secrets they name.
+type Resolver struct {
+	backends map[string]Backend
+}
+
+// New returns a Resolver with the vault: and aws-sm: backends, which send their requests with
+// client.
+func New(settings model.SecretSettings, client *http.Client) *Resolver {
+	r := &Resolver{backends: map[string]Backend{}}
+	r.Register(model.SecretSchemeVault, NewVault(settings.Vault, client))
+	r.Register(model.SecretSchemeAWS, NewAWS(settings.AWS, client))
+	return r
+}
+
+// Register makes b resolve the references that start with scheme, e.g. "vault:".
+func (r *Resolver) Register(scheme string, b Backend) {
+	r.backends[scheme] = b
+}
+
+// IsReference reports whether value is a vault: or aws-sm: reference.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, model.SecretSchemeVault) || strings.HasPrefix(value, model.SecretSchemeAWS)
+}
+
+// Resolve returns the secret that value refers to, or value itself when it is not a reference.
+func (r *Resolver) Resolve(value string) (string, error) {
+	for scheme, b := range r.backends {
+		if ref, ok := strings.CutPrefix(value, scheme); ok {
+			secret, err := b.Fetch(ref)
+			if err != nil {
+				return "", fmt.Errorf("%s%s: %w", scheme, ref, err)
+			}
+			return secret, nil
+		}
+	}
+	return value, nil
+}
+
+// ResolveEntries resolves the references in the credential fields of entries in place and
+// remembers them in SecretRefs. A reference used by several entries is fetched once.
+func (r *Resolver) ResolveEntries(entries []model.AutoReviewPR) []error {
+	var errs []error
+	fetched := map[string]string{}
+	for i := range entries {
+		auto := &entries[i]
+		auto.AIKeys = append([]string(nil), auto.AIKeys...)
+		auto.Notifiers = append([]model.NotifierSettings(nil), auto.Notifiers...)
+		fields := secretFields(auto)
+		for _, name := range sortedNames(fields) {
+			field := fields[name]
+			if !IsReference(*field) {
+				continue
+			}
+			ref := *field
+			secret, err := r.fetch(ref, fetched)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", auto.ProcessName, name, err))
+				*field = ""
+			} else {
+				*field = secret
+			}
+			if auto.SecretRefs == nil {
+				auto.SecretRefs = map[string]string{}
+			}
+			auto.SecretRefs[name] = ref
+		}
+	}
+	return errs
+}
+
+// Refresh resolves the remembered references of entries again and returns the entries whose
+// credentials changed, with the new values. An entry whose references fail to resolve keeps
+// its current values and is reported in errs.
+func (r *Resolver) Refresh(entries map[string]model.AutoReviewPR) (map[string]model.AutoReviewPR, []error) {
+	changed := map[string]model.AutoReviewPR{}
+	var errs []error
+	fetched := map[string]string{}
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		auto := entries[key]
+		if len(auto.SecretRefs) == 0 {
+			continue
+		}
+		auto.AIKeys = append([]string(nil), auto.AIKeys...)
+		auto.Notifiers = append([]model.NotifierSettings(nil), auto.Notifiers...)
+		fields := secretFields(&auto)
+		updated, failed := false, false
+		for _, name := range sortedNames(auto.SecretRefs) {
+			field, ok := fields[name]
+			if !ok {
+				continue
+			}
+			secret, err := r.fetch(auto.SecretRefs[name], fetched)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", key, name, err))
+				failed = true
+				break
+			}
+			if *field != secret {
+				*field = secret
+				updated = true
+			}
+		}
+		if updated && !failed {
+			changed[key] = auto
+		}
+	}
+	return changed, errs
+}
+
+func (r *Resolver) fetch(ref string, fetched map[string]string) (string, error) {
+	if secret, ok := fetched[ref]; ok {
+		return secret, nil
+	}
+	secret, err := r.Resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	fetched[ref] = secret
+	return secret, nil
+}
+
+// secretFields returns the credential fields of auto that may hold references, by name.
+func secretFields(auto *model.AutoReviewPR) map[string]*string {
+	fields := map[string]*string{
+		"username":      &auto.Username,
+		"appPassword":   &auto.AppPassword,
+		"webhookSecret": &auto.WebhookSecret,
+		"geminiKey":     &auto.GeminiKey,
+		"aiKey":         &auto.AIKey,
+	}
+	for i := range auto.AIKeys {
+		fields[fmt.Sprintf("aiKeys[%d]", i)] = &auto.AIKeys[i]
+	}
+	for i := range auto.Notifiers {
+		fields[fmt.Sprintf("notifiers[%d].url", i)] = &auto.Notifiers[i].URL
+		fields[fmt.Sprintf("notifiers[%d].smtp.password", i)] = &auto.Notifiers[i].SMTP.Password
+	}
+	return fields
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
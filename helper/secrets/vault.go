@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"code_nim/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Vault reads secrets from a HashiCorp Vault KV engine over its HTTP API. A reference is
+// <path>#<key>, where path is the API path without /v1, e.g. secret/data/code-nim for a KV v2
+// mount; the key may be left out when the secret holds a single key.
+type Vault struct {
+	address   string
+	tokenFile string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func NewVault(settings model.VaultSettings, client *http.Client) *Vault {
+	v := &Vault{
+		address:   strings.TrimRight(settings.Address, "/"),
+		tokenFile: settings.TokenFile,
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: settings.Namespace,
+		client:    client,
+	}
+	if v.address == "" {
+		v.address = strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	}
+	if v.namespace == "" {
+		v.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	return v
+}
+
+func (v *Vault) Fetch(ref string) (string, error) {
+	if v.address == "" {
+		return "", errors.New("vault address not configured: set secrets.vault.address or VAULT_ADDR")
+	}
+	path, key, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", errors.New("missing secret path")
+	}
+	token, err := v.readToken()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, v.address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	data := secret.Data
+	// KV v2 nests the key/value pairs under data.data next to data.metadata.
+	if nested, ok := data["data"]; ok {
+		if _, meta := data["metadata"]; meta {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", fmt.Errorf("decode vault response: %w", err)
+			}
+		}
+	}
+	return pickKey(data, key)
+}
+
+func (v *Vault) readToken() (string, error) {
+	if v.tokenFile == "" {
+		if v.token == "" {
+			return "", errors.New("vault token not configured: set secrets.vault.tokenFile or VAULT_TOKEN")
+		}
+		return v.token, nil
+	}
+	// Read on every fetch so a token renewed by the Vault agent is used.
+	raw, err := os.ReadFile(v.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read vault token: %w", err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// pickKey returns the value of key in data, or the only value when key is empty.
+func pickKey(data map[string]json.RawMessage, key string) (string, error) {
+	if key == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("secret has %d keys; name one with #<key>", len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %q", key)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return strings.TrimSpace(string(raw)), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,45 @@
+package helper
+
+import "strings"
+
+// SeverityNone is the auto-approval threshold that allows no findings at all.
+const SeverityNone = "none"
+
+// severityRanks orders the severities the review prompt asks for, least severe first.
+var severityRanks = map[string]int{
+	SeverityNone: 0,
+	"info":       1,
+	"trivial":    2,
+	"minor":      3,
+	"major":      4,
+	"critical":   5,
+}
+
+// SeverityRank returns the rank of a finding severity. Unknown or missing severities rank above
+// Critical so that a finding the bot cannot classify never counts as harmless.
+func SeverityRank(severity string) int {
+	if r, ok := severityRanks[strings.ToLower(strings.TrimSpace(severity))]; ok {
+		return r
+	}
+	return len(severityRanks)
+}
+
+// ValidSeverity reports whether s is a known severity or "none".
+func ValidSeverity(s string) bool {
+	_, ok := severityRanks[strings.ToLower(strings.TrimSpace(s))]
+	return ok
+}
+
+// HighestSeverity returns the most severe of severities, or SeverityNone when the list is empty.
+func HighestSeverity(severities []string) string {
+	highest := SeverityNone
+	for _, s := range severities {
+		if SeverityRank(s) > SeverityRank(highest) {
+			highest = s
+		}
+	}
+	if strings.TrimSpace(highest) == "" {
+		return "unknown"
+	}
+	return highest
+}
@@ -0,0 +1,70 @@
+package helper
+
+import (
+	"code_nim/model"
+	"strings"
+	"unicode"
+)
+
+// DefaultSkipMarkers are the phrases that skip a review when an entry does not set skipMarkers.
+var DefaultSkipMarkers = []string{"[skip nim]", "[nim skip]", "#no-ai-review"}
+
+// FindSkipMarker returns the first of markers (DefaultSkipMarkers when nil) found in the pull
+// request title or description, ignoring case. Blank markers are ignored.
+func FindSkipMarker(markers []string, title, description string) (string, bool) {
+	if markers == nil {
+		markers = DefaultSkipMarkers
+	}
+	text := strings.ToLower(title + "\n" + description)
+	for _, m := range markers {
+		m = strings.TrimSpace(m)
+		if m != "" && strings.Contains(text, strings.ToLower(m)) {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// DefaultLGTMMarkers are the comment markers that pause reviews when an entry does not set
+// lgtmMarkers.
+var DefaultLGTMMarkers = []string{"LGTM", "/nim stop"}
+
+// FindLGTMMarker returns the first of markers (DefaultLGTMMarkers when nil) that opens a line of
+// the comment body, ignoring case. The marker must end there or be followed by a character that
+// is not a letter or digit, so "LGTM!" matches but "LGTMs" and "not LGTM yet" do not. Blank
+// markers are ignored.
+func FindLGTMMarker(markers []string, body string) (string, bool) {
+	if markers == nil {
+		markers = DefaultLGTMMarkers
+	}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		for _, m := range markers {
+			m = strings.TrimSpace(m)
+			n := len(m)
+			if m == "" || len(line) < n || !strings.EqualFold(line[:n], m) {
+				continue
+			}
+			if rest := []rune(line[n:]); len(rest) == 0 || !unicode.IsLetter(rest[0]) && !unicode.IsDigit(rest[0]) {
+				return m, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseIgnoreCommand reports whether the first line of a comment is model.IgnoreCommand,
+// ignoring case, and returns the reason written after it, if any.
+func ParseIgnoreCommand(body string) (string, bool) {
+	first, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	first = strings.TrimSpace(first)
+	n := len(model.IgnoreCommand)
+	if len(first) < n || !strings.EqualFold(first[:n], model.IgnoreCommand) {
+		return "", false
+	}
+	rest := first[n:]
+	if rest != "" && !strings.ContainsAny(rest[:1], " \t:") {
+		return "", false // another word such as "/nim ignored"
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ":")), true
+}
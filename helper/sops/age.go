@@ -0,0 +1,286 @@
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	ageHeaderIntro   = "age-encryption.org/v1"
+	ageX25519Label   = "age-encryption.org/v1/X25519"
+	ageSecretKeyHRP  = "age-secret-key-"
+	ageChunkSize     = 64 * 1024
+	ageArmorBegin    = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorEnd      = "-----END AGE ENCRYPTED FILE-----"
+	ageStanzaColumns = 64
+)
+
+// ageIdentities returns the X25519 identities of SOPS_AGE_KEY, SOPS_AGE_KEY_FILE and the sops
+// default key file ($XDG_CONFIG_HOME/sops/age/keys.txt).
+func ageIdentities() ([]*ecdh.PrivateKey, error) {
+	var text strings.Builder
+	text.WriteString(os.Getenv("SOPS_AGE_KEY") + "\n")
+	files := []string{os.Getenv("SOPS_AGE_KEY_FILE")}
+	if dir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "sops", "age", "keys.txt"))
+	}
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("read age keys: %w", err)
+		}
+		text.Write(raw)
+		text.WriteString("\n")
+	}
+
+	var identities []*ecdh.PrivateKey
+	for _, line := range strings.Split(text.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hrp, data, err := bech32Decode(line)
+		if err != nil || hrp != ageSecretKeyHRP {
+			return nil, errors.New("malformed age secret key")
+		}
+		key, err := ecdh.X25519().NewPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("malformed age secret key: %w", err)
+		}
+		identities = append(identities, key)
+	}
+	if len(identities) == 0 {
+		return nil, errors.New("no age key: set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+	return identities, nil
+}
+
+// decryptAge decrypts an armored age file encrypted to one of identities.
+func decryptAge(armored string, identities []*ecdh.PrivateKey) ([]byte, error) {
+	body := strings.TrimSpace(armored)
+	if !strings.HasPrefix(body, ageArmorBegin) || !strings.HasSuffix(body, ageArmorEnd) {
+		return nil, errors.New("not an armored age file")
+	}
+	body = strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimPrefix(body, ageArmorBegin), ageArmorEnd)), "")
+	file, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode armor: %w", err)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(file))
+	read := 0 // bytes of file consumed by the header lines read so far
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", errors.New("truncated age header")
+		}
+		read += len(line)
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+	if intro, err := readLine(); err != nil || intro != ageHeaderIntro {
+		return nil, errors.New("unsupported age version")
+	}
+	var fileKey []byte
+	var headerMAC string
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, err
+		}
+		if mac, ok := strings.CutPrefix(line, "--- "); ok {
+			headerMAC = mac
+			break
+		}
+		args := strings.Fields(strings.TrimPrefix(line, "-> "))
+		if !strings.HasPrefix(line, "-> ") || len(args) == 0 {
+			return nil, errors.New("malformed age stanza")
+		}
+		var wrapped []byte
+		for {
+			bodyLine, err := readLine()
+			if err != nil {
+				return nil, err
+			}
+			chunk, err := base64.RawStdEncoding.DecodeString(bodyLine)
+			if err != nil {
+				return nil, errors.New("malformed age stanza body")
+			}
+			wrapped = append(wrapped, chunk...)
+			if len(bodyLine) < ageStanzaColumns {
+				break
+			}
+		}
+		if fileKey == nil && args[0] == "X25519" && len(args) == 2 {
+			fileKey = unwrapX25519(args[1], wrapped, identities)
+		}
+	}
+	if fileKey == nil {
+		return nil, errors.New("no matching age identity")
+	}
+
+	// The header MAC covers the header up to and including "---".
+	headerLen := read - len(headerMAC) - 2
+	macKey, err := hkdf.Key(sha256.New, fileKey, nil, "header", 32)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, macKey)
+	h.Write(file[:headerLen])
+	want, err := base64.RawStdEncoding.DecodeString(headerMAC)
+	if err != nil || !hmac.Equal(h.Sum(nil), want) {
+		return nil, errors.New("age header MAC mismatch")
+	}
+	return decryptAgePayload(file[read:], fileKey)
+}
+
+// unwrapX25519 returns the file key of an X25519 stanza, or nil when none of identities
+// unwraps it.
+func unwrapX25519(share string, wrapped []byte, identities []*ecdh.PrivateKey) []byte {
+	raw, err := base64.RawStdEncoding.DecodeString(share)
+	if err != nil {
+		return nil
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil
+	}
+	for _, id := range identities {
+		shared, err := id.ECDH(ephemeral)
+		if err != nil {
+			continue
+		}
+		salt := append(append([]byte(nil), raw...), id.PublicKey().Bytes()...)
+		wrapKey, err := hkdf.Key(sha256.New, shared, salt, ageX25519Label, chacha20poly1305.KeySize)
+		if err != nil {
+			continue
+		}
+		aead, err := chacha20poly1305.New(wrapKey)
+		if err != nil {
+			continue
+		}
+		if fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), wrapped, nil); err == nil {
+			return fileKey
+		}
+	}
+	return nil
+}
+
+// decryptAgePayload decrypts the STREAM-encrypted payload that follows an age header.
+func decryptAgePayload(payload, fileKey []byte) ([]byte, error) {
+	if len(payload) < 16 {
+		return nil, errors.New("truncated age payload")
+	}
+	key, err := hkdf.Key(sha256.New, fileKey, payload[:16], "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	payload = payload[16:]
+	var out []byte
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := uint64(0); ; counter++ {
+		n := min(len(payload), ageChunkSize+aead.Overhead())
+		last := n == len(payload)
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		if last {
+			nonce[11] = 1
+		}
+		chunk, err := aead.Open(nil, nonce, payload[:n], nil)
+		if err != nil {
+			return nil, errors.New("age payload authentication failed")
+		}
+		out = append(out, chunk...)
+		payload = payload[n:]
+		if last {
+			return out, nil
+		}
+	}
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode decodes a bech32 string such as an age secret key into its lower-case
+// human-readable part and data.
+func bech32Decode(s string) (string, []byte, error) {
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errors.New("invalid bech32 string")
+	}
+	hrp := s[:sep]
+	var values []byte
+	for _, c := range s[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, errors.New("invalid bech32 character")
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid bech32 checksum")
+	}
+	// Regroup the 5-bit values, without the checksum, into bytes.
+	var data []byte
+	acc, bits := 0, 0
+	for _, v := range values[:len(values)-6] {
+		acc = (acc<<5 | int(v)) & 0xfff
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			data = append(data, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return "", nil, errors.New("invalid bech32 padding")
+	}
+	return hrp, data, nil
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range []byte(hrp) {
+		out = append(out, c>>5)
+	}
+	out = append(out, 0)
+	for _, c := range []byte(hrp) {
+		out = append(out, c&31)
+	}
+	return out
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
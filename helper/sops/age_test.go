@@ -0,0 +1,320 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The X25519 keys and shared secret of RFC 7748, section 6.1.
+const (
+	rfcAlicePrivate = "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"
+	rfcAlicePublic  = "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a"
+	rfcBobPrivate   = "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb"
+	rfcBobPublic    = "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"
+	rfcShared       = "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"
+)
+
+// The age identity and recipient of the sops test suite.
+const (
+	sopsTestIdentity  = "AGE-SECRET-KEY-1G0Q5K9TV4REQ3ZSQRMTMG8NSWQGYT0T7TZ33RAZEE0GZYVZN0APSU24RK7"
+	sopsTestRecipient = "age1lzd99uklcjnc0e7d860axevet2cz99ce9pq6tzuzd05l5nr28ams36nvun"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func x25519Key(t *testing.T, private string) *ecdh.PrivateKey {
+	t.Helper()
+	k, err := ecdh.X25519().NewPrivateKey(mustHex(t, private))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// useAgeKey makes keys the only age identities ageIdentities finds.
+func useAgeKey(t *testing.T, keys string) {
+	t.Setenv("SOPS_AGE_KEY", keys)
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestAgeIdentities(t *testing.T) {
+	useAgeKey(t, "# created: 2021-01-01\n# public key: "+sopsTestRecipient+"\n"+sopsTestIdentity+"\n")
+	ids, err := ageIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hrp, recipient, err := bech32Decode(sopsTestRecipient)
+	if err != nil || hrp != "age" {
+		t.Fatalf("bech32Decode(recipient) = %q, %v", hrp, err)
+	}
+	if len(ids) != 1 || !bytes.Equal(ids[0].PublicKey().Bytes(), recipient) {
+		t.Errorf("the identity does not belong to %s", sopsTestRecipient)
+	}
+
+	for _, bad := range []string{
+		strings.Replace(sopsTestIdentity, "RK7", "RK8", 1), // checksum
+		sopsTestRecipient,
+	} {
+		useAgeKey(t, bad)
+		if _, err := ageIdentities(); err == nil {
+			t.Errorf("ageIdentities() accepted %s", bad)
+		}
+	}
+}
+
+// wrapX25519 wraps fileKey for recipient as the age v1 spec describes, with a fixed ephemeral key.
+func wrapX25519(t *testing.T, ephemeral *ecdh.PrivateKey, recipient *ecdh.PublicKey, fileKey []byte) (string, []byte) {
+	t.Helper()
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := append(ephemeral.PublicKey().Bytes(), recipient.Bytes()...)
+	wrapKey, err := hkdf.Key(sha256.New, shared, salt, "age-encryption.org/v1/X25519", chacha20poly1305.KeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawStdEncoding.EncodeToString(ephemeral.PublicKey().Bytes()), aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil)
+}
+
+func TestUnwrapX25519(t *testing.T) {
+	alice, bob := x25519Key(t, rfcAlicePrivate), x25519Key(t, rfcBobPrivate)
+	if hex.EncodeToString(alice.PublicKey().Bytes()) != rfcAlicePublic || hex.EncodeToString(bob.PublicKey().Bytes()) != rfcBobPublic {
+		t.Fatal("public keys differ from RFC 7748")
+	}
+	if shared, _ := bob.ECDH(alice.PublicKey()); hex.EncodeToString(shared) != rfcShared {
+		t.Fatalf("shared secret %x differs from RFC 7748", shared)
+	}
+
+	fileKey := []byte("YELLOW SUBMARINE")
+	share, wrapped := wrapX25519(t, alice, bob.PublicKey(), fileKey)
+	other := x25519Key(t, strings.Repeat("11", 32))
+	if got := unwrapX25519(share, wrapped, []*ecdh.PrivateKey{other, bob}); !bytes.Equal(got, fileKey) {
+		t.Errorf("unwrapX25519() = %q, want %q", got, fileKey)
+	}
+	if got := unwrapX25519(share, wrapped, []*ecdh.PrivateKey{other}); got != nil {
+		t.Errorf("unwrapX25519() with another identity = %q, want nil", got)
+	}
+	wrapped[0] ^= 1
+	if got := unwrapX25519(share, wrapped, []*ecdh.PrivateKey{bob}); got != nil {
+		t.Errorf("unwrapX25519() of a changed stanza = %q, want nil", got)
+	}
+}
+
+// sealAge encrypts plaintext to recipient as an armored age v1 file, following the spec with
+// fixed randomness: ephemeral is the stanza's key, nonce the payload nonce. drop leaves out that
+// many chunks from the end of the payload.
+func sealAge(t *testing.T, recipient *ecdh.PublicKey, ephemeral *ecdh.PrivateKey, fileKey, nonce, plaintext []byte, drop int) string {
+	t.Helper()
+	share, wrapped := wrapX25519(t, ephemeral, recipient, fileKey)
+	header := "age-encryption.org/v1\n-> X25519 " + share + "\n" + base64.RawStdEncoding.EncodeToString(wrapped) + "\n---"
+	macKey, _ := hkdf.Key(sha256.New, fileKey, nil, "header", 32)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write([]byte(header))
+	file := []byte(header + " " + base64.RawStdEncoding.EncodeToString(mac.Sum(nil)) + "\n")
+
+	payloadKey, _ := hkdf.Key(sha256.New, fileKey, nonce, "payload", chacha20poly1305.KeySize)
+	aead, _ := chacha20poly1305.New(payloadKey)
+	var chunks [][]byte
+	for counter := uint64(0); ; counter++ {
+		n := min(len(plaintext), ageChunkSize)
+		last := n == len(plaintext)
+		chunkNonce := make([]byte, chacha20poly1305.NonceSize)
+		binary.BigEndian.PutUint64(chunkNonce[3:11], counter)
+		if last {
+			chunkNonce[11] = 1
+		}
+		chunks = append(chunks, aead.Seal(nil, chunkNonce, plaintext[:n], nil))
+		plaintext = plaintext[n:]
+		if last {
+			break
+		}
+	}
+	file = append(file, nonce...)
+	for _, c := range chunks[:len(chunks)-drop] {
+		file = append(file, c...)
+	}
+
+	var b strings.Builder
+	b.WriteString(ageArmorBegin + "\n")
+	encoded := base64.StdEncoding.EncodeToString(file)
+	for len(encoded) > 64 {
+		b.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	b.WriteString(encoded + "\n" + ageArmorEnd + "\n")
+	return b.String()
+}
+
+func TestDecryptAge(t *testing.T) {
+	alice, bob := x25519Key(t, rfcAlicePrivate), x25519Key(t, rfcBobPrivate)
+	fileKey := []byte("YELLOW SUBMARINE")
+	nonce := bytes.Repeat([]byte{0x42}, 16)
+	identities := []*ecdh.PrivateKey{bob}
+
+	for _, size := range []int{0, 32, ageChunkSize, ageChunkSize + 1, 3*ageChunkSize + 7} {
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			plaintext := bytes.Repeat([]byte("sops"), size/4+1)[:size]
+			got, err := decryptAge(sealAge(t, bob.PublicKey(), alice, fileKey, nonce, plaintext, 0), identities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("decryptAge() returned %d bytes, want the %d plaintext bytes", len(got), len(plaintext))
+			}
+		})
+	}
+
+	plaintext := bytes.Repeat([]byte{7}, 2*ageChunkSize+5)
+	armored := sealAge(t, bob.PublicKey(), alice, fileKey, nonce, plaintext, 0)
+	file := func(change func(b []byte)) string {
+		body := strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(armored), ageArmorBegin), ageArmorEnd)), "")
+		raw, _ := base64.StdEncoding.DecodeString(body)
+		change(raw)
+		return ageArmorBegin + "\n" + base64.StdEncoding.EncodeToString(raw) + "\n" + ageArmorEnd
+	}
+	tests := []struct {
+		name       string
+		armored    string
+		identities []*ecdh.PrivateKey
+		wantErr    string
+	}{
+		{"another identity", armored, []*ecdh.PrivateKey{alice}, "no matching age identity"},
+		{"changed header", file(func(b []byte) { b[len("age-encryption.org/v1\n-> X25519 ")] ^= 1 }), identities, "no matching age identity"},
+		{"changed header MAC", file(func(b []byte) {
+			i := bytes.Index(b, []byte("\n--- ")) + 5
+			b[i] = 'A' + (b[i]-'A'+1)%26
+		}), identities, "header MAC mismatch"},
+		{"changed payload", file(func(b []byte) { b[len(b)-1] ^= 1 }), identities, "authentication failed"},
+		{"final chunk dropped", sealAge(t, bob.PublicKey(), alice, fileKey, nonce, plaintext, 1), identities, "authentication failed"},
+		{"not armored", "age-encryption.org/v1\n", identities, "not an armored age file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decryptAge(tt.armored, tt.identities); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decryptAge() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// sopsValue encrypts value as SOPS does: AES-256-GCM under key with a 32-byte IV, authenticated
+// with additionalData.
+func sopsValue(t *testing.T, key []byte, value, typ, additionalData string, iv byte) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{iv}, 32)
+	sealed := gcm.Seal(nil, nonce, []byte(value), []byte(additionalData))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(nonce), base64.StdEncoding.EncodeToString(tag), typ)
+}
+
+func TestDecryptWithAgeKey(t *testing.T) {
+	identity := x25519Key(t, rfcBobPrivate)
+	dataKey := bytes.Repeat([]byte{0x5a}, 32)
+	const lastModified = "2026-01-01T00:00:00Z"
+	document := func(note string) string {
+		sum := sha512.New()
+		for _, v := range []string{"hunter2", "5432", "True", note} {
+			sum.Write([]byte(v))
+		}
+		wrapped := sealAge(t, identity.PublicKey(), x25519Key(t, rfcAlicePrivate), []byte("YELLOW SUBMARINE"), bytes.Repeat([]byte{1}, 16), dataKey, 0)
+		return "database:\n" +
+			"    password: " + sopsValue(t, dataKey, "hunter2", "str", "database:password:", 1) + "\n" +
+			"    port: " + sopsValue(t, dataKey, "5432", "int", "database:port:", 2) + "\n" +
+			"    tls: " + sopsValue(t, dataKey, "True", "bool", "database:tls:", 3) + "\n" +
+			"note_unencrypted: " + note + "\n" +
+			"sops:\n" +
+			"    age:\n" +
+			"        - recipient: age1test\n" +
+			"          enc: |\n" + indent(wrapped, "            ") +
+			"    lastmodified: \"" + lastModified + "\"\n" +
+			"    mac: " + sopsValue(t, dataKey, fmt.Sprintf("%X", sum.Sum(nil)), "str", lastModified, 4) + "\n"
+	}
+
+	useAgeKey(t, ageSecretKey(identity))
+	plain, err := Decrypt([]byte(document("reviewed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "database:\n    password: hunter2\n    port: 5432\n    tls: True\nnote_unencrypted: reviewed\n"
+	if string(plain) != want {
+		t.Errorf("Decrypt() =\n%s\nwant\n%s", plain, want)
+	}
+
+	// A value changed after encryption no longer matches the MAC
+	changed := strings.Replace(document("reviewed"), "note_unencrypted: reviewed", "note_unencrypted: approved", 1)
+	if _, err := Decrypt([]byte(changed)); err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+		t.Errorf("Decrypt() of a changed document = %v, want a MAC mismatch", err)
+	}
+}
+
+func indent(s, prefix string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+	return b.String()
+}
+
+// ageSecretKey returns the AGE-SECRET-KEY-1... encoding of key.
+func ageSecretKey(key *ecdh.PrivateKey) string {
+	var values []byte
+	acc, bits := 0, 0
+	for _, b := range key.Bytes() {
+		acc = acc<<8 | int(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits)&31))
+	}
+	polymod := bech32Polymod(append(append(bech32ExpandHRP(ageSecretKeyHRP), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>(5*(5-i))&31))
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(ageSecretKeyHRP) + "1")
+	for _, v := range values {
+		b.WriteByte(strings.ToUpper(bech32Charset)[v])
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package sops
+
+import (
+	"code_nim/helper/secrets"
+	"net/http"
+)
+
+// decryptKMS decrypts the data key with AWS KMS.
+func decryptKMS(client *http.Client, k kmsKey) ([]byte, error) {
+	in := map[string]interface{}{"KeyId": k.ARN, "CiphertextBlob": k.Enc}
+	if len(k.Context) > 0 {
+		in["EncryptionContext"] = k.Context
+	}
+	var out struct {
+		Plaintext []byte `json:"Plaintext"` // base64 in the JSON reply
+	}
+	if err := secrets.CallAWS(client, "kms", "", secrets.RegionOf(k.ARN), "TrentService.Decrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
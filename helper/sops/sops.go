@@ -0,0 +1,246 @@
+package sops
+
+import (
+	"code_nim/model"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// metadataKey is the top-level key SOPS keeps its metadata under.
+const metadataKey = "sops"
+
+// encryptedValue matches a value encrypted by SOPS.
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// metadata is the part of the sops section needed to decrypt a document.
+type metadata struct {
+	KMS              []kmsKey   `yaml:"kms"`
+	Age              []ageKey   `yaml:"age"`
+	KeyGroups        []keyGroup `yaml:"key_groups"`
+	LastModified     string     `yaml:"lastmodified"`
+	MAC              string     `yaml:"mac"`
+	MACOnlyEncrypted bool       `yaml:"mac_only_encrypted"`
+}
+
+type keyGroup struct {
+	KMS []kmsKey `yaml:"kms"`
+	Age []ageKey `yaml:"age"`
+}
+
+type kmsKey struct {
+	ARN     string            `yaml:"arn"`
+	Enc     string            `yaml:"enc"`
+	Context map[string]string `yaml:"context"`
+}
+
+type ageKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+// IsEncrypted reports whether data is a YAML document encrypted with SOPS.
+func IsEncrypted(data []byte) bool {
+	var doc struct {
+		SOPS *struct {
+			MAC string `yaml:"mac"`
+		} `yaml:"sops"`
+	}
+	return yaml.Unmarshal(data, &doc) == nil && doc.SOPS != nil && doc.SOPS.MAC != ""
+}
+
+// Decrypt returns the plaintext of a SOPS-encrypted YAML document, without the sops section.
+// The data key is decrypted with an age identity from SOPS_AGE_KEY, SOPS_AGE_KEY_FILE or the
+// sops default key file, else with AWS KMS (see kmsClient). The document's MAC is verified, so a
+// file changed after it was encrypted is rejected.
+func Decrypt(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a SOPS-encrypted YAML mapping")
+	}
+	root := doc.Content[0]
+	var meta metadata
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == metadataKey {
+			if err := root.Content[i+1].Decode(&meta); err != nil {
+				return nil, fmt.Errorf("decode sops metadata: %w", err)
+			}
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			found = true
+			break
+		}
+	}
+	if !found || meta.MAC == "" {
+		return nil, errors.New("no sops metadata")
+	}
+
+	key, err := dataKey(meta)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha512.New()
+	if err := decryptNode(root, nil, key, meta.MACOnlyEncrypted, sum); err != nil {
+		return nil, err
+	}
+	mac, _, err := decryptValue(meta.MAC, key, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt MAC: %w", err)
+	}
+	if mac != fmt.Sprintf("%X", sum.Sum(nil)) {
+		return nil, errors.New("MAC mismatch: the file was modified after it was encrypted")
+	}
+	return yaml.Marshal(&doc)
+}
+
+// kmsClient returns the client for requests to KMS. It is built from the process environment
+// only (HTTPS_PROXY, NO_PROXY and SSL_CERT_FILE), never from the document being decrypted: its
+// MAC is checked after the data key is decrypted, so anyone able to edit the file could
+// otherwise send the KMS reply, and with it the plaintext data key, through their own proxy.
+func kmsClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return &http.Client{Timeout: model.DefaultHTTPTimeout, Transport: transport}
+}
+
+// dataKey decrypts the document's data key with the first master key that works.
+func dataKey(meta metadata) ([]byte, error) {
+	ages, kmss := meta.Age, meta.KMS
+	switch len(meta.KeyGroups) {
+	case 0:
+	case 1:
+		ages, kmss = append(ages, meta.KeyGroups[0].Age...), append(kmss, meta.KeyGroups[0].KMS...)
+	default:
+		return nil, errors.New("files split across several key groups (Shamir) are not supported")
+	}
+
+	var errs []error
+	if len(ages) > 0 {
+		identities, err := ageIdentities()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, k := range ages {
+			if len(identities) == 0 {
+				break
+			}
+			key, err := decryptAge(k.Enc, identities)
+			if err == nil {
+				return key, nil
+			}
+			errs = append(errs, fmt.Errorf("age %s: %w", k.Recipient, err))
+		}
+	}
+	client := kmsClient()
+	for _, k := range kmss {
+		key, err := decryptKMS(client, k)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Errorf("kms %s: %w", k.ARN, err))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no age or kms master key to decrypt the data key with")
+	}
+	return nil, fmt.Errorf("cannot decrypt the data key: %w", errors.Join(errs...))
+}
+
+// decryptNode decrypts the values under node in place, in document order, and adds them to the
+// MAC. path holds the mapping keys leading to node, which SOPS authenticates each value with.
+func decryptNode(node *yaml.Node, path []string, key []byte, macOnlyEncrypted bool, sum hash.Hash) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := decryptNode(node.Content[i+1], append(path, node.Content[i].Value), key, macOnlyEncrypted, sum); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := decryptNode(item, path, key, macOnlyEncrypted, sum); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !encryptedValue.MatchString(node.Value) {
+			if !macOnlyEncrypted {
+				sum.Write(macBytes(node.Value, node.ShortTag()))
+			}
+			return nil
+		}
+		value, tag, err := decryptValue(node.Value, key, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %w", strings.Join(path, "."), err)
+		}
+		node.Value, node.Tag, node.Style = value, tag, 0
+		sum.Write(macBytes(value, tag))
+	}
+	return nil
+}
+
+// decryptValue decrypts an ENC[AES256_GCM,...] value and returns it with its YAML tag.
+func decryptValue(value string, key []byte, additionalData string) (string, string, error) {
+	m := encryptedValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", errors.New("not an AES256_GCM value")
+	}
+	var parts [3][]byte
+	for i, s := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", "", err
+		}
+		parts[i] = b
+	}
+	ciphertext, iv, tag := parts[0], parts[1], parts[2]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", errors.New("authentication failed")
+	}
+	switch m[4] {
+	case "int":
+		return string(plain), "!!int", nil
+	case "float":
+		return string(plain), "!!float", nil
+	case "bool":
+		return string(plain), "!!bool", nil
+	default:
+		return string(plain), "!!str", nil
+	}
+}
+
+// macBytes returns the bytes SOPS hashes for a value: its text, with booleans as True/False.
+func macBytes(value, tag string) []byte {
+	switch tag {
+	case "!!bool":
+		if b, err := strconv.ParseBool(value); err == nil && b {
+			return []byte("True")
+		} else if err == nil {
+			return []byte("False")
+		}
+	case "!!null":
+		return nil
+	}
+	return []byte(value)
+}
@@ -0,0 +1,76 @@
+package sops
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingProxy answers every request, including CONNECT, with 502 and counts them.
+func countingProxy(t *testing.T) (*httptest.Server, *int32) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDecryptIgnoresHTTPSectionForKMS(t *testing.T) {
+	envProxy, envHits := countingProxy(t)
+	fileProxy, fileHits := countingProxy(t)
+	t.Setenv("HTTPS_PROXY", envProxy.URL)
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+
+	// An attacker who can edit the file points the http section at their own proxy.
+	doc := `http:
+    proxy: ` + fileProxy.URL + `
+secret: ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]
+sops:
+    kms:
+        - arn: arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab
+          enc: AQIDAHhB
+    lastmodified: "2026-01-01T00:00:00Z"
+    mac: ENC[AES256_GCM,data:AAAA,iv:AAAAAAAAAAAAAAAAAAAAAA==,tag:AAAAAAAAAAAAAAAAAAAAAA==,type:str]
+`
+	_, err := Decrypt([]byte(doc))
+	if err == nil {
+		t.Fatal("Decrypt succeeded without a usable master key")
+	}
+	if !strings.Contains(err.Error(), "kms") {
+		t.Fatalf("Decrypt error %q does not come from the KMS attempt", err)
+	}
+	if n := atomic.LoadInt32(fileHits); n != 0 {
+		t.Errorf("KMS request went through the proxy of the document's http section (%d requests)", n)
+	}
+	if n := atomic.LoadInt32(envHits); n == 0 {
+		t.Error("KMS request did not go through the proxy of the environment")
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want bool
+	}{
+		{"sops document", "a: ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==,type:str]\nsops:\n    mac: ENC[x]\n", true},
+		{"plain config", "autoReviewPR: []\n", false},
+		{"sops section without mac", "sops:\n    kms: []\n", false},
+		{"not yaml", "\t: :", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEncrypted([]byte(tt.doc)); got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReviewRunReport is what one review run of a pull request did, as shown in its status comment.
+type ReviewRunReport struct {
+	Commit        string
+	FilesAnalyzed int
+	// FilesSkipped counts the files left out of inline review by reason, such as "generated".
+	FilesSkipped map[string]int
+	SummaryOnly  string // why no file was reviewed inline, if none was
+	Posting      model.PostingResult
+	OpenFindings []model.Finding // open bot findings after the run
+	Model        string
+	Duration     time.Duration
+	Incomplete   bool // files could not be reviewed or findings not posted
+}
+
+// CountReviewedFiles returns how many files of a parsed diff are reviewed inline for auto and
+// how many are skipped, by the reason of SkippedFileReason without its details.
+func CountReviewedFiles(auto *model.AutoReviewPR, parsed []map[string]interface{}) (int, map[string]int) {
+	analyzed, skipped := 0, map[string]int{}
+	for _, file := range parsed {
+		reason := SkippedFileReason(auto, file)
+		if reason == "" {
+			analyzed++
+			continue
+		}
+		reason, _, _ = strings.Cut(reason, ":")
+		skipped[reason]++
+	}
+	return analyzed, skipped
+}
+
+// FormatReviewRunReport renders the "Review status" comment of a review run.
+func FormatReviewRunReport(r ReviewRunReport) string {
+	var b strings.Builder
+	b.WriteString("## 📋 Review status\n\n")
+	b.WriteString("| | |\n|---|---|\n")
+	commit := r.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	fmt.Fprintf(&b, "| Commit | `%s` |\n", commit)
+
+	files := fmt.Sprintf("%d analyzed", r.FilesAnalyzed)
+	if r.SummaryOnly != "" {
+		files = "not reviewed inline: " + r.SummaryOnly
+	}
+	skippedTotal := 0
+	reasons := make([]string, 0, len(r.FilesSkipped))
+	for reason, n := range r.FilesSkipped {
+		skippedTotal += n
+		reasons = append(reasons, fmt.Sprintf("%d %s", n, reason))
+	}
+	sort.Strings(reasons)
+	if skippedTotal > 0 {
+		files += fmt.Sprintf(", %d skipped (%s)", skippedTotal, strings.Join(reasons, ", "))
+	}
+	fmt.Fprintf(&b, "| Files | %s |\n", files)
+
+	posted := fmt.Sprintf("%d posted", r.Posting.Posted)
+	if r.Posting.Skipped > 0 {
+		posted += fmt.Sprintf(", %d not posted (duplicate, deferred or over the comment cap)", r.Posting.Skipped)
+	}
+	if r.Posting.Failed > 0 {
+		posted += fmt.Sprintf(", %d failed", r.Posting.Failed)
+	}
+	fmt.Fprintf(&b, "| New findings | %s |\n", posted)
+	fmt.Fprintf(&b, "| Open findings | %s |\n", formatSeverityCounts(r.OpenFindings))
+	fmt.Fprintf(&b, "| AI model | `%s` |\n", r.Model)
+	fmt.Fprintf(&b, "| Duration | %s |\n", r.Duration.Round(time.Second))
+	if r.Incomplete {
+		b.WriteString("\n⚠️ Part of this commit could not be reviewed; the next scan tries again.\n")
+	}
+	return b.String()
+}
+
+// formatSeverityCounts renders findings as "3 (1 Critical, 2 Minor)", most severe first.
+func formatSeverityCounts(findings []model.Finding) string {
+	if len(findings) == 0 {
+		return "none"
+	}
+	counts := map[string]int{}
+	for _, f := range findings {
+		severity := strings.TrimSpace(f.Severity)
+		if severity == "" {
+			severity = "Unrated"
+		}
+		counts[severity]++
+	}
+	severities := make([]string, 0, len(counts))
+	for s := range counts {
+		severities = append(severities, s)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		if ri, rj := SeverityRank(severities[i]), SeverityRank(severities[j]); ri != rj {
+			return ri > rj
+		}
+		return severities[i] < severities[j]
+	})
+	parts := make([]string, len(severities))
+	for i, s := range severities {
+		parts[i] = fmt.Sprintf("%d %s", counts[s], s)
+	}
+	return fmt.Sprintf("%d (%s)", len(findings), strings.Join(parts, ", "))
+}
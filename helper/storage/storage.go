@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"code_nim/model"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// Storage persists review data produced by the bot.
+type Storage interface {
+	// Migrate upgrades the persisted schema to the version this build expects.
+	Migrate() error
+	// SaveTranscript appends a prompt/response/findings record.
+	SaveTranscript(t model.Transcript) error
+	// ListTranscripts returns the stored transcripts matching filter, oldest first.
+	ListTranscripts(filter model.TranscriptFilter) ([]model.Transcript, error)
+	// PurgeTranscripts deletes every transcript created before olderThan and returns how many
+	// records were removed.
+	PurgeTranscripts(olderThan time.Time) (int, error)
+	// PurgeFindings deletes the findings created before olderThan and returns how many were removed.
+	PurgeFindings(olderThan time.Time) (int, error)
+	// PurgeFeedback deletes the feedback recorded before olderThan and returns how many records were removed.
+	PurgeFeedback(olderThan time.Time) (int, error)
+	// PurgeRiskScores deletes the risk scores recorded before olderThan and returns how many were removed.
+	PurgeRiskScores(olderThan time.Time) (int, error)
+	// PurgeAIResponses deletes the AI replies cached before olderThan and returns how many were removed.
+	PurgeAIResponses(olderThan time.Time) (int, error)
+	// SaveFinding stores a finding and returns its ID.
+	SaveFinding(f model.Finding) (string, error)
+	// GetFinding loads a finding by ID.
+	GetFinding(id string) (model.Finding, error)
+	// ListFindings returns the stored findings matching filter, oldest first.
+	ListFindings(filter model.FindingFilter) ([]model.Finding, error)
+	// SaveUsage replaces the per-repository usage records of a day (YYYY-MM-DD).
+	SaveUsage(day string, records []model.UsageRecord) error
+	// LoadUsage returns the usage records of a day; a day without usage yields no records.
+	LoadUsage(day string) ([]model.UsageRecord, error)
+	// SaveNotification records a delivered notification.
+	SaveNotification(n model.SentNotification) error
+	// ListNotifications returns the notifications delivered at or after since, oldest first.
+	ListNotifications(since time.Time) ([]model.SentNotification, error)
+	// PurgeNotifications deletes the notifications delivered before olderThan and returns how many were removed.
+	PurgeNotifications(olderThan time.Time) (int, error)
+	// SaveBenchmarkRun records the results of a model benchmark run.
+	SaveBenchmarkRun(r model.BenchmarkRun) error
+	// SaveFeedback records a classified reply to a bot comment.
+	SaveFeedback(f model.Feedback) error
+	// ListFeedback returns the stored feedback matching filter, oldest first.
+	ListFeedback(filter model.FeedbackFilter) ([]model.Feedback, error)
+	// SaveAIResponse stores an AI reply under its Key, replacing any earlier one.
+	SaveAIResponse(r model.AIResponse) error
+	// LoadAIResponse returns the AI reply stored under key, or ErrNotFound.
+	LoadAIResponse(key string) (model.AIResponse, error)
+	// SaveIgnoredPullRequest records an ignored pull request, replacing an earlier record of it.
+	SaveIgnoredPullRequest(p model.IgnoredPullRequest) error
+	// DeleteIgnoredPullRequest drops the record of an ignored pull request, or returns ErrNotFound.
+	DeleteIgnoredPullRequest(workspace, repoSlug string, prID int) error
+	// ListIgnoredPullRequests returns every ignored pull request record, oldest first.
+	ListIgnoredPullRequests() ([]model.IgnoredPullRequest, error)
+	// ListBenchmarkRuns returns the benchmark runs started at or after since, oldest first.
+	ListBenchmarkRuns(since time.Time) ([]model.BenchmarkRun, error)
+	// SaveRiskScore records the merge risk of a reviewed pull request.
+	SaveRiskScore(r model.RiskScore) error
+	// ListRiskScores returns the stored risk scores matching filter, oldest first.
+	ListRiskScores(filter model.RiskScoreFilter) ([]model.RiskScore, error)
+}
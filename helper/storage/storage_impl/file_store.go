@@ -0,0 +1,980 @@
+package storage_impl
+
+import (
+	"bufio"
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const transcriptDir = "transcripts"
+const findingDir = "findings"
+const usageDir = "usage"
+const notificationDir = "notifications"
+const benchmarkDir = "benchmarks"
+const feedbackDir = "feedback"
+const aiResponseDir = "ai_responses"
+const riskDir = "risk_scores"
+const ignoredFile = "ignored_pull_requests.json"
+const dayLayout = "2006-01-02"
+
+var findingIDPattern = regexp.MustCompile(`^(\d{8})-[0-9a-f]+$`)
+var aiResponseKeyPattern = regexp.MustCompile(`^[0-9a-f]{16,64}$`)
+
+type FileStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// New returns a Storage that keeps records as JSON lines under dir.
+// Transcripts are grouped into one file per day so purging stays cheap.
+func New(dir string) storage.Storage {
+	if strings.TrimSpace(dir) == "" {
+		dir = "data"
+	}
+	return &FileStore{dir: dir}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// SaveTranscript appends a transcript to the file of the day it was created.
+func (fs *FileStore) SaveTranscript(t model.Transcript) error {
+	if t.ID == "" {
+		t.ID = newID()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	line, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, transcriptDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, t.CreatedAt.Format(dayLayout)+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ListTranscripts scans the day files from the filter's start onwards.
+func (fs *FileStore) ListTranscripts(filter model.TranscriptFilter) ([]model.Transcript, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, transcriptDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.Transcript
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		loc := time.Local
+		if !filter.From.IsZero() {
+			loc = filter.From.Location()
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), loc)
+		if err != nil || (!filter.From.IsZero() && !day.AddDate(0, 0, 1).After(filter.From)) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return out, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var t model.Transcript
+			if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+				continue
+			}
+			if filter.Matches(t) {
+				out = append(out, t)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return out, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// PurgeTranscripts removes day files that are entirely older than olderThan and
+// rewrites the boundary day file keeping only the records that are still retained.
+func (fs *FileStore) PurgeTranscripts(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	purged := 0
+	dir := filepath.Join(fs.dir, transcriptDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return purged, nil
+		}
+		return purged, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), olderThan.Location())
+		if err != nil {
+			log.Warnf("Skipping unexpected transcript file %s: %v", name, err)
+			continue
+		}
+		// Records of a day file are all created within [day, day+24h).
+		if !day.Before(olderThan) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		kept, removed, err := filterTranscripts(path, olderThan)
+		if err != nil {
+			return purged, err
+		}
+		purged += removed
+		if len(kept) == 0 {
+			if err := os.Remove(path); err != nil {
+				return purged, err
+			}
+			continue
+		}
+		if removed > 0 {
+			if err := os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), 0o600); err != nil {
+				return purged, err
+			}
+		}
+	}
+	return purged, nil
+}
+
+// PurgeNotifications removes the sent-notification day files older than olderThan.
+func (fs *FileStore) PurgeNotifications(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.purgeDayFiles(notificationDir, olderThan)
+}
+
+// PurgeFindings removes the findings created before olderThan.
+func (fs *FileStore) PurgeFindings(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.purgeFindings(olderThan)
+}
+
+// PurgeFeedback removes the feedback day files older than olderThan.
+func (fs *FileStore) PurgeFeedback(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.purgeDayFiles(feedbackDir, olderThan)
+}
+
+// PurgeRiskScores removes the risk score day files older than olderThan.
+func (fs *FileStore) PurgeRiskScores(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.purgeDayFiles(riskDir, olderThan)
+}
+
+// PurgeAIResponses removes the AI replies cached before olderThan.
+func (fs *FileStore) PurgeAIResponses(olderThan time.Time) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.purgeAIResponses(olderThan)
+}
+
+// filterTranscripts splits the lines of a day file into the ones to keep and a removed count.
+func filterTranscripts(path string, olderThan time.Time) ([]string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var kept []string
+	removed := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var t model.Transcript
+		if err := json.Unmarshal([]byte(line), &t); err != nil || t.CreatedAt.Before(olderThan) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept, removed, scanner.Err()
+}
+
+// SaveFinding writes a finding to its own file; the ID embeds the creation day.
+func (fs *FileStore) SaveFinding(f model.Finding) (string, error) {
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+	if f.ID == "" {
+		f.ID = f.CreatedAt.Format("20060102") + "-" + newID()
+	}
+	if !findingIDPattern.MatchString(f.ID) {
+		return "", fmt.Errorf("invalid finding id %q", f.ID)
+	}
+	raw, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, findingDir, f.CreatedAt.Format(dayLayout))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, f.ID+".json"), raw, 0o600); err != nil {
+		return "", err
+	}
+	return f.ID, nil
+}
+
+// GetFinding reads a finding by the ID returned from SaveFinding.
+func (fs *FileStore) GetFinding(id string) (model.Finding, error) {
+	var f model.Finding
+	m := findingIDPattern.FindStringSubmatch(id)
+	if m == nil {
+		return f, storage.ErrNotFound
+	}
+	day, err := time.Parse("20060102", m[1])
+	if err != nil {
+		return f, storage.ErrNotFound
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	raw, err := os.ReadFile(filepath.Join(fs.dir, findingDir, day.Format(dayLayout), id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, storage.ErrNotFound
+		}
+		return f, err
+	}
+	err = json.Unmarshal(raw, &f)
+	return f, err
+}
+
+// ListFindings scans the day directories overlapping the filter's date range.
+func (fs *FileStore) ListFindings(filter model.FindingFilter) ([]model.Finding, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	root := filepath.Join(fs.dir, findingDir)
+	days, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.Finding
+	for _, d := range days {
+		if !d.IsDir() {
+			continue
+		}
+		loc := time.Local
+		if !filter.From.IsZero() {
+			loc = filter.From.Location()
+		}
+		day, err := time.ParseInLocation(dayLayout, d.Name(), loc)
+		if err != nil {
+			continue
+		}
+		// Findings of a day directory are all created within [day, day+24h).
+		if !filter.From.IsZero() && !day.AddDate(0, 0, 1).After(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !day.Before(filter.To) {
+			continue
+		}
+		dayDir := filepath.Join(root, d.Name())
+		files, err := os.ReadDir(dayDir)
+		if err != nil {
+			return out, err
+		}
+		for _, file := range files {
+			raw, err := os.ReadFile(filepath.Join(dayDir, file.Name()))
+			if err != nil {
+				return out, err
+			}
+			var f model.Finding
+			if err := json.Unmarshal(raw, &f); err != nil {
+				log.Warnf("Skipping unreadable finding %s: %v", file.Name(), err)
+				continue
+			}
+			if filter.Matches(f) {
+				out = append(out, f)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// purgeFindings removes finding files created before olderThan. Callers hold the mutex.
+func (fs *FileStore) purgeFindings(olderThan time.Time) (int, error) {
+	root := filepath.Join(fs.dir, findingDir)
+	days, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	purged := 0
+	for _, d := range days {
+		if !d.IsDir() {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, d.Name(), olderThan.Location())
+		if err != nil || !day.Before(olderThan) {
+			continue
+		}
+		dayDir := filepath.Join(root, d.Name())
+		files, err := os.ReadDir(dayDir)
+		if err != nil {
+			return purged, err
+		}
+		remaining := len(files)
+		for _, file := range files {
+			path := filepath.Join(dayDir, file.Name())
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return purged, err
+			}
+			var f model.Finding
+			if json.Unmarshal(raw, &f) == nil && !f.CreatedAt.Before(olderThan) {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return purged, err
+			}
+			purged++
+			remaining--
+		}
+		if remaining == 0 {
+			_ = os.Remove(dayDir)
+		}
+	}
+	return purged, nil
+}
+
+// SaveUsage rewrites the usage file of a day; the file is replaced atomically.
+func (fs *FileStore) SaveUsage(day string, records []model.UsageRecord) error {
+	if _, err := time.Parse(dayLayout, day); err != nil {
+		return fmt.Errorf("invalid usage day %q", day)
+	}
+	raw, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, usageDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, day+".json.tmp")
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, day+".json"))
+}
+
+// LoadUsage reads the usage file of a day.
+func (fs *FileStore) LoadUsage(day string) ([]model.UsageRecord, error) {
+	if _, err := time.Parse(dayLayout, day); err != nil {
+		return nil, fmt.Errorf("invalid usage day %q", day)
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	raw, err := os.ReadFile(filepath.Join(fs.dir, usageDir, day+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []model.UsageRecord
+	err = json.Unmarshal(raw, &records)
+	return records, err
+}
+
+// SaveNotification appends a sent notification to the file of the day it was sent.
+func (fs *FileStore) SaveNotification(n model.SentNotification) error {
+	if n.SentAt.IsZero() {
+		n.SentAt = time.Now()
+	}
+	line, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, notificationDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, n.SentAt.Format(dayLayout)+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ListNotifications reads the day files that may hold notifications sent at or after since.
+func (fs *FileStore) ListNotifications(since time.Time) ([]model.SentNotification, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, notificationDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.SentNotification
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), since.Location())
+		if err != nil || day.AddDate(0, 0, 1).Before(since) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var n model.SentNotification
+			if err := json.Unmarshal(scanner.Bytes(), &n); err != nil {
+				log.Warnf("Skipping corrupt notification record in %s: %v", name, err)
+				continue
+			}
+			if !n.SentAt.Before(since) {
+				out = append(out, n)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SentAt.Before(out[j].SentAt) })
+	return out, nil
+}
+
+// purgeDayFiles removes the JSON-lines day files of subdir older than olderThan. Callers hold the mutex.
+func (fs *FileStore) purgeDayFiles(subdir string, olderThan time.Time) (int, error) {
+	dir := filepath.Join(fs.dir, subdir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	purged := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), olderThan.Location())
+		// Only whole days before olderThan are removed; the boundary day expires with the next purge.
+		if err != nil || day.AddDate(0, 0, 1).After(olderThan) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return purged, err
+		}
+		if err := os.Remove(path); err != nil {
+			return purged, err
+		}
+		purged += strings.Count(string(raw), "\n")
+	}
+	return purged, nil
+}
+
+// SaveBenchmarkRun appends a benchmark run to the file of the day it started. Runs are small
+// and infrequent, so they are kept regardless of the transcript retention.
+func (fs *FileStore) SaveBenchmarkRun(r model.BenchmarkRun) error {
+	if r.StartedAt.IsZero() {
+		r.StartedAt = time.Now()
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, benchmarkDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, r.StartedAt.Format(dayLayout)+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ListBenchmarkRuns reads the day files that may hold runs started at or after since.
+func (fs *FileStore) ListBenchmarkRuns(since time.Time) ([]model.BenchmarkRun, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, benchmarkDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.BenchmarkRun
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), since.Location())
+		if err != nil || day.AddDate(0, 0, 1).Before(since) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var r model.BenchmarkRun
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				log.Warnf("Skipping corrupt benchmark record in %s: %v", name, err)
+				continue
+			}
+			if !r.StartedAt.Before(since) {
+				out = append(out, r)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out, nil
+}
+
+// SaveFeedback appends a feedback record to the file of the day the reply was written.
+func (fs *FileStore) SaveFeedback(fb model.Feedback) error {
+	if fb.CreatedAt.IsZero() {
+		fb.CreatedAt = time.Now()
+	}
+	line, err := json.Marshal(fb)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, feedbackDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, fb.CreatedAt.Format(dayLayout)+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ListFeedback reads the day files overlapping the filter's date range.
+func (fs *FileStore) ListFeedback(filter model.FeedbackFilter) ([]model.Feedback, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, feedbackDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.Feedback
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		loc := time.Local
+		if !filter.From.IsZero() {
+			loc = filter.From.Location()
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), loc)
+		if err != nil || (!filter.From.IsZero() && !day.AddDate(0, 0, 1).After(filter.From)) || (!filter.To.IsZero() && !day.Before(filter.To)) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var fb model.Feedback
+			if err := json.Unmarshal(scanner.Bytes(), &fb); err != nil {
+				log.Warnf("Skipping corrupt feedback record in %s: %v", name, err)
+				continue
+			}
+			if filter.Matches(fb) {
+				out = append(out, fb)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// SaveRiskScore appends a risk score to the file of the day it was computed.
+func (fs *FileStore) SaveRiskScore(r model.RiskScore) error {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, riskDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, r.CreatedAt.Format(dayLayout)+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ListRiskScores reads the day files overlapping the filter's date range.
+func (fs *FileStore) ListRiskScores(filter model.RiskScoreFilter) ([]model.RiskScore, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, riskDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []model.RiskScore
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		loc := time.Local
+		if !filter.From.IsZero() {
+			loc = filter.From.Location()
+		}
+		day, err := time.ParseInLocation(dayLayout, strings.TrimSuffix(name, ".jsonl"), loc)
+		if err != nil || (!filter.From.IsZero() && !day.AddDate(0, 0, 1).After(filter.From)) || (!filter.To.IsZero() && !day.Before(filter.To)) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var r model.RiskScore
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				log.Warnf("Skipping corrupt risk score record in %s: %v", name, err)
+				continue
+			}
+			if filter.Matches(r) {
+				out = append(out, r)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// SaveAIResponse writes an AI reply to its own file named after the key, so a lookup is a
+// single read.
+func (fs *FileStore) SaveAIResponse(r model.AIResponse) error {
+	if !aiResponseKeyPattern.MatchString(r.Key) {
+		return fmt.Errorf("invalid AI response key %q", r.Key)
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	dir := filepath.Join(fs.dir, aiResponseDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, r.Key+".json"), raw, 0o600)
+}
+
+// LoadAIResponse reads the AI reply stored under key.
+func (fs *FileStore) LoadAIResponse(key string) (model.AIResponse, error) {
+	var r model.AIResponse
+	if !aiResponseKeyPattern.MatchString(key) {
+		return r, storage.ErrNotFound
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	raw, err := os.ReadFile(filepath.Join(fs.dir, aiResponseDir, key+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, storage.ErrNotFound
+		}
+		return r, err
+	}
+	err = json.Unmarshal(raw, &r)
+	return r, err
+}
+
+// purgeAIResponses removes the cached AI replies written before olderThan. Callers hold fs.mutex.
+func (fs *FileStore) purgeAIResponses(olderThan time.Time) (int, error) {
+	dir := filepath.Join(fs.dir, aiResponseDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	purged := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(olderThan) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// sameIgnoredPullRequest reports whether p is the record of the given pull request.
+func sameIgnoredPullRequest(p model.IgnoredPullRequest, workspace, repoSlug string, prID int) bool {
+	return p.PullRequestID == prID && strings.EqualFold(p.Workspace, workspace) && strings.EqualFold(p.RepoSlug, repoSlug)
+}
+
+// SaveIgnoredPullRequest rewrites the ignore list with p in it. The list stays small, since
+// records of closed pull requests are deleted, so it is kept in a single file.
+func (fs *FileStore) SaveIgnoredPullRequest(p model.IgnoredPullRequest) error {
+	if p.IgnoredAt.IsZero() {
+		p.IgnoredAt = time.Now()
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	list, err := fs.readIgnored()
+	if err != nil {
+		return err
+	}
+	kept := list[:0]
+	for _, q := range list {
+		if !sameIgnoredPullRequest(q, p.Workspace, p.RepoSlug, p.PullRequestID) {
+			kept = append(kept, q)
+		}
+	}
+	return fs.writeIgnored(append(kept, p))
+}
+
+// DeleteIgnoredPullRequest rewrites the ignore list without the given pull request.
+func (fs *FileStore) DeleteIgnoredPullRequest(workspace, repoSlug string, prID int) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	list, err := fs.readIgnored()
+	if err != nil {
+		return err
+	}
+	kept := list[:0]
+	for _, q := range list {
+		if !sameIgnoredPullRequest(q, workspace, repoSlug, prID) {
+			kept = append(kept, q)
+		}
+	}
+	if len(kept) == len(list) {
+		return storage.ErrNotFound
+	}
+	return fs.writeIgnored(kept)
+}
+
+// ListIgnoredPullRequests reads the ignore list.
+func (fs *FileStore) ListIgnoredPullRequests() ([]model.IgnoredPullRequest, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	list, err := fs.readIgnored()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].IgnoredAt.Before(list[j].IgnoredAt) })
+	return list, nil
+}
+
+// ignoredFormat is the format version of the ignore list file. Changing the shape of
+// ignoredList or model.IgnoredPullRequest needs a new format and a migration that rewrites it.
+const ignoredFormat = 1
+
+// ignoredList is the ignore list file: its format version and the records.
+type ignoredList struct {
+	Format       int                        `json:"format"`
+	PullRequests []model.IgnoredPullRequest `json:"pullRequests"`
+}
+
+// readIgnored loads the ignore list; a missing file is an empty list. Callers hold fs.mutex.
+func (fs *FileStore) readIgnored() ([]model.IgnoredPullRequest, error) {
+	raw, err := os.ReadFile(filepath.Join(fs.dir, ignoredFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file ignoredList
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", ignoredFile, err)
+	}
+	if file.Format != ignoredFormat {
+		return nil, fmt.Errorf("%s has format %d, expected %d", ignoredFile, file.Format, ignoredFormat)
+	}
+	return file.PullRequests, nil
+}
+
+// writeIgnored replaces the ignore list atomically. Callers hold fs.mutex.
+func (fs *FileStore) writeIgnored(list []model.IgnoredPullRequest) error {
+	if list == nil {
+		list = []model.IgnoredPullRequest{}
+	}
+	raw, err := json.MarshalIndent(ignoredList{Format: ignoredFormat, PullRequests: list}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fs.dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(fs.dir, ignoredFile+".tmp")
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(fs.dir, ignoredFile))
+}
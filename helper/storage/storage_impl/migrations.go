@@ -0,0 +1,161 @@
+package storage_impl
+
+import (
+	"code_nim/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const schemaFile = "schema_version.json"
+
+// migration upgrades the data directory from version-1 to version.
+type migration struct {
+	version     int
+	description string
+	apply       func(fs *FileStore) error
+}
+
+// migrations must stay ordered by version; append new steps, never edit released ones.
+//
+// Records are JSON, and their fields are only ever added, with omitempty and a zero value that
+// reads as the old behaviour, so older records stay readable as they are. Any other change of a
+// record format (renaming, retyping or restructuring fields, or moving files) needs a step here
+// that rewrites the stored records, and files that hold a whole list carry a format version.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create transcripts directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, transcriptDir), 0o755)
+		},
+	},
+	{
+		version:     2,
+		description: "create findings directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, findingDir), 0o755)
+		},
+	},
+	{
+		version:     3,
+		description: "create usage directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, usageDir), 0o755)
+		},
+	},
+	{
+		version:     4,
+		description: "create notifications directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, notificationDir), 0o755)
+		},
+	},
+	{
+		version:     5,
+		description: "create benchmarks directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, benchmarkDir), 0o755)
+		},
+	},
+	{
+		version:     6,
+		description: "create feedback directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, feedbackDir), 0o755)
+		},
+	},
+	{
+		version:     7,
+		description: "create AI response cache directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, aiResponseDir), 0o755)
+		},
+	},
+	{
+		version:     8,
+		description: "create risk scores directory",
+		apply: func(fs *FileStore) error {
+			return os.MkdirAll(filepath.Join(fs.dir, riskDir), 0o755)
+		},
+	},
+}
+
+type schemaState struct {
+	Version    int       `json:"version"`
+	MigratedAt time.Time `json:"migratedAt"`
+}
+
+// SchemaVersion is the data layout version this build writes.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func (fs *FileStore) readSchemaVersion() (int, error) {
+	raw, err := os.ReadFile(filepath.Join(fs.dir, schemaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var st schemaState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return 0, fmt.Errorf("corrupt %s: %w", schemaFile, err)
+	}
+	return st.Version, nil
+}
+
+// writeSchemaVersion replaces the version file atomically so a crash never leaves it half written.
+func (fs *FileStore) writeSchemaVersion(version int) error {
+	raw, err := json.MarshalIndent(schemaState{Version: version, MigratedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(fs.dir, schemaFile+".tmp")
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(fs.dir, schemaFile))
+}
+
+// Migrate brings the data directory up to SchemaVersion, applying each pending step in order
+// and recording progress after every step so an interrupted upgrade resumes where it stopped.
+func (fs *FileStore) Migrate() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := os.MkdirAll(fs.dir, 0o755); err != nil {
+		return err
+	}
+	current, err := fs.readSchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := SchemaVersion()
+	if current > latest {
+		return fmt.Errorf("data directory %s has schema version %d but this build supports up to %d; upgrade code-nim", fs.dir, current, latest)
+	}
+	if current == latest {
+		log.Debugf("Storage schema is up to date (version %d)", current)
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		log.Infof("Migrating storage schema %d -> %d: %s", current, m.version, m.description)
+		if err := m.apply(fs); err != nil {
+			return fmt.Errorf("migration to schema version %d failed: %w", m.version, err)
+		}
+		if err := fs.writeSchemaVersion(m.version); err != nil {
+			return err
+		}
+		current = m.version
+	}
+	log.Infof("Storage schema migrated to version %d", current)
+	return nil
+}
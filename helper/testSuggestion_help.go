@@ -0,0 +1,105 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// NoTestSuggestions is what the AI answers when a file has no logic worth testing.
+const NoTestSuggestions = "NO_TESTS_NEEDED"
+
+// untestableLanguages are languages without unit tests of their own, in addition to the
+// non-code languages of the diff stats.
+var untestableLanguages = map[string]bool{"Dockerfile": true, "SQL": true, "HTML": true, "CSS": true}
+
+// testStem returns the lower-cased file name of a source or test file without its extension and
+// test markers, so foo.go, foo_test.go, test_foo.py, Foo.java and FooTest.java pair up.
+func testStem(file string) string {
+	base := path.Base(file)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	for _, suffix := range []string{"Tests", "Test", "Spec"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			name = trimmed
+			break
+		}
+	}
+	name = strings.ToLower(name)
+	for _, suffix := range []string{"_test", ".test", ".spec"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return strings.TrimPrefix(name, "test_")
+}
+
+// UntestedSourceFiles returns the paths of the changed source files of a parsed diff that have
+// no changed test file with the same name: foo.go without foo_test.go, Foo.java without
+// FooTest.java, foo.ts without foo.test.ts or foo.spec.ts. Test files, files without a
+// programming language, infrastructure files with a review profile, and the files left out of
+// inline review (SkippedFileReason) are never returned.
+func UntestedSourceFiles(auto *model.AutoReviewPR, parsed []map[string]interface{}) []string {
+	tested := map[string]bool{}
+	for _, file := range parsed {
+		if p, _ := file["path"].(string); p != "" && IsTestFile(p) {
+			tested[testStem(p)] = true
+		}
+	}
+	var untested []string
+	for _, file := range parsed {
+		p, _ := file["path"].(string)
+		if p == "" || IsTestFile(p) || tested[testStem(p)] {
+			continue
+		}
+		if lang := FileLanguage(p); nonCodeLanguages[lang] || untestableLanguages[lang] {
+			continue
+		}
+		if ReviewProfileFor(p, nil) != nil || SkippedFileReason(auto, file) != "" {
+			continue
+		}
+		untested = append(untested, p)
+	}
+	return untested
+}
+
+// CreateTestSuggestionPrompt builds the prompt asking for the test cases a changed source file
+// needs, derived from the snippet lines of its diff.
+func CreateTestSuggestionPrompt(filePath string, hunkLines []string, pr *model.PullRequest, cfg *model.AutoReviewPR) string {
+	log.Debugf("Create Test Suggestion Prompt for PR: %d, file: %s", pr.ID, filePath)
+	lang := FileLanguage(filePath)
+	return fmt.Sprintf(`You are an expert %[1]s engineer. The file "%[2]s" changed in this pull request, but no test file for it changed.
+
+Recommend the specific test cases this change needs, derived from the diff below. Output Markdown only:
+- One hyphen bullet per test case: "- `+"`"+`<TestName>`+"`"+`: <setup or input> → <expected result>."
+- Group cases of one function under a bold line "**<function or method>**"; where several cases share a shape, propose them as rows of one table-driven test.
+- Cover the changed behaviour, edge conditions (empty, nil/None, zero, boundaries, invalid input), and the error paths the diff adds or changes.
+- Use the test names, framework and idioms of %[1]s. At most 8 cases, most important first.
+- No preamble, no closing remarks, no generic advice such as "add tests" or "increase coverage".
+- If the change has no behaviour worth testing (renames, comments, formatting, constants only), answer exactly %[3]s.
+
+%[4]sPull Request Title: %[5]s
+
+Git Diff of %[2]s:
+---diff
+%[6]s
+---
+`, lang, filePath, NoTestSuggestions, summaryLanguageInstructions(cfg.Language)+systemInstructions(cfg), pr.Title, strings.Join(hunkLines, "\n"))
+}
+
+// TestSuggestion is the list of recommended test cases for one changed source file.
+type TestSuggestion struct {
+	Path  string
+	Cases string // Markdown answer of the AI
+}
+
+// FormatTestSuggestions renders the comment listing the recommended test cases of each file.
+func FormatTestSuggestions(suggestions []TestSuggestion) string {
+	var b strings.Builder
+	b.WriteString("## 🧪 Suggested tests\n\n")
+	fmt.Fprintf(&b, "%s changed without a matching test file. These cases are derived from the diff:\n\n",
+		Pluralize(len(suggestions), "This source file", "These source files"))
+	for _, s := range suggestions {
+		fmt.Fprintf(&b, "### `%s`\n\n%s\n\n", s.Path, strings.TrimSpace(s.Cases))
+	}
+	return b.String()
+}
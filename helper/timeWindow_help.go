@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"code_nim/model"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekdayNames maps the day names of TimeWindow.Days, in lower case, to weekdays.
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// timeWindow is a TimeWindow with its fields parsed.
+type timeWindow struct {
+	days     [7]bool
+	from, to int // minutes since midnight
+	loc      *time.Location
+}
+
+// parseClock parses "HH:MM" into minutes since midnight; "24:00" is allowed as an end.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hours, errH := strconv.Atoi(h)
+	minutes, errM := strconv.Atoi(m)
+	if !ok || errH != nil || errM != nil || hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("time %q is not HH:MM", s)
+	}
+	return hours*60 + minutes, nil
+}
+
+// parseTimeWindow checks w and converts it for lookups.
+func parseTimeWindow(w model.TimeWindow) (timeWindow, error) {
+	var p timeWindow
+	var err error
+	if p.from, err = parseClock(w.From); err != nil {
+		return p, err
+	}
+	if p.to, err = parseClock(w.To); err != nil {
+		return p, err
+	}
+	p.loc = time.UTC
+	if tz := strings.TrimSpace(w.Timezone); tz != "" {
+		if p.loc, err = time.LoadLocation(tz); err != nil {
+			return p, fmt.Errorf("unknown timezone %q", w.Timezone)
+		}
+	}
+	if len(w.Days) == 0 {
+		p.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, d := range w.Days {
+		first, last, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(d)), "-")
+		start, ok := weekdayNames[strings.TrimSpace(first)]
+		end := start
+		if isRange {
+			var known bool
+			end, known = weekdayNames[strings.TrimSpace(last)]
+			ok = ok && known
+		}
+		if !ok {
+			return p, fmt.Errorf("unknown day %q", d)
+		}
+		for day := start; ; day = (day + 1) % 7 {
+			p.days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return p, nil
+}
+
+// contains reports whether t falls in the window.
+func (p timeWindow) contains(t time.Time) bool {
+	t = t.In(p.loc)
+	minute, day := t.Hour()*60+t.Minute(), t.Weekday()
+	switch {
+	case p.from == p.to:
+		return p.days[day]
+	case p.from < p.to:
+		return p.days[day] && minute >= p.from && minute < p.to
+	case minute >= p.from:
+		return p.days[day]
+	}
+	// Past midnight of a window that started the day before
+	return minute < p.to && p.days[(day+6)%7]
+}
+
+// CheckTimeWindow reports what is wrong with w, or nil when it is valid.
+func CheckTimeWindow(w model.TimeWindow) error {
+	_, err := parseTimeWindow(w)
+	return err
+}
+
+// PostingAllowed reports whether the bot may post at now under an entry's active and quiet
+// hours and, when it may not, the first minute it may again within a week. It returns a zero
+// time when posting stays closed all week. Invalid windows are left out; the config linter
+// reports them.
+func PostingAllowed(active, quiet []model.TimeWindow, now time.Time) (bool, time.Time) {
+	parse := func(windows []model.TimeWindow) []timeWindow {
+		var out []timeWindow
+		for _, w := range windows {
+			if p, err := parseTimeWindow(w); err == nil {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	activeWindows, quietWindows := parse(active), parse(quiet)
+	allowed := func(t time.Time) bool {
+		for _, w := range quietWindows {
+			if w.contains(t) {
+				return false
+			}
+		}
+		if len(activeWindows) == 0 {
+			return true
+		}
+		for _, w := range activeWindows {
+			if w.contains(t) {
+				return true
+			}
+		}
+		return false
+	}
+	if allowed(now) {
+		return true, time.Time{}
+	}
+	t := now.Truncate(time.Minute)
+	for i := 0; i < 7*24*60; i++ {
+		t = t.Add(time.Minute)
+		if allowed(t) {
+			return false, t
+		}
+	}
+	return false, time.Time{}
+}
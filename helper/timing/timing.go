@@ -0,0 +1,125 @@
+package timing
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// window is how many recent samples per stage are kept for percentiles.
+const window = 512
+
+// Summary describes the recorded durations of one stage.
+type Summary struct {
+	Count int64
+	Sum   time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+type series struct {
+	count   int64
+	sum     time.Duration
+	samples []time.Duration // ring buffer of the latest window samples
+	next    int
+}
+
+// Recorder collects stage durations. Counts and sums cover the whole process lifetime;
+// percentiles are computed over the latest samples of each stage.
+type Recorder struct {
+	mutex  sync.Mutex
+	stages map[string]*series
+}
+
+func New() *Recorder {
+	return &Recorder{stages: map[string]*series{}}
+}
+
+// Observe records one duration of stage.
+func (r *Recorder) Observe(stage string, d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s, ok := r.stages[stage]
+	if !ok {
+		s = &series{}
+		r.stages[stage] = s
+	}
+	s.count++
+	s.sum += d
+	if len(s.samples) < window {
+		s.samples = append(s.samples, d)
+		return
+	}
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % window
+}
+
+// Snapshot returns the summary of every stage observed so far.
+func (r *Recorder) Snapshot() map[string]Summary {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	out := make(map[string]Summary, len(r.stages))
+	for name, s := range r.stages {
+		sorted := append([]time.Duration(nil), s.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		out[name] = Summary{
+			Count: s.count,
+			Sum:   s.sum,
+			P50:   quantile(sorted, 0.50),
+			P90:   quantile(sorted, 0.90),
+			P99:   quantile(sorted, 0.99),
+		}
+	}
+	return out
+}
+
+// quantile uses the nearest-rank method on sorted samples.
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// Breakdown accumulates the durations spent in each stage while reviewing one pull request.
+type Breakdown struct {
+	mutex  sync.Mutex
+	order  []string
+	totals map[string]time.Duration
+}
+
+func NewBreakdown() *Breakdown {
+	return &Breakdown{totals: map[string]time.Duration{}}
+}
+
+// Add adds d to stage, keeping stages in first-seen order.
+func (b *Breakdown) Add(stage string, d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, ok := b.totals[stage]; !ok {
+		b.order = append(b.order, stage)
+	}
+	b.totals[stage] += d
+}
+
+// String renders the breakdown as "fetch=1.2s ai=8.4s ...".
+func (b *Breakdown) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	out := ""
+	for i, stage := range b.order {
+		if i > 0 {
+			out += " "
+		}
+		out += stage + "=" + b.totals[stage].Round(time.Millisecond).String()
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultConventionalTypes are the Conventional Commits types allowed when titlePolicy.types is empty.
+var DefaultConventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// conventionalTitle matches "type(scope)!: subject" and captures the type.
+var conventionalTitle = regexp.MustCompile(`^([a-zA-Z]+)(\([^()]+\))?!?: \S`)
+
+func conventionalTypes(policy model.TitlePolicySettings) []string {
+	if len(policy.Types) > 0 {
+		return policy.Types
+	}
+	return DefaultConventionalTypes
+}
+
+// CheckTitle reports whether title follows policy and, if not, why. An invalid pattern is
+// logged and not enforced.
+func CheckTitle(policy model.TitlePolicySettings, title string) (bool, string) {
+	title = strings.TrimSpace(title)
+	if policy.Conventional {
+		types := conventionalTypes(policy)
+		m := conventionalTitle.FindStringSubmatch(title)
+		if m == nil {
+			return false, fmt.Sprintf("it is not in the Conventional Commits form `type(scope): subject` (types: %s)", strings.Join(types, ", "))
+		}
+		allowed := false
+		for _, t := range types {
+			if strings.EqualFold(t, m[1]) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, fmt.Sprintf("`%s` is not an allowed type (use one of: %s)", m[1], strings.Join(types, ", "))
+		}
+	}
+	if policy.Pattern != "" {
+		re, err := regexp.Compile(policy.Pattern)
+		if err != nil {
+			log.Errorf("Invalid titlePolicy.pattern %q: %v", policy.Pattern, err)
+			return true, ""
+		}
+		if !re.MatchString(title) {
+			return false, fmt.Sprintf("it does not match the pattern `%s`", policy.Pattern)
+		}
+	}
+	return true, ""
+}
+
+// TitleRules describes policy for the AI that suggests a corrected title.
+func TitleRules(policy model.TitlePolicySettings) string {
+	var rules []string
+	if policy.Conventional {
+		rules = append(rules, fmt.Sprintf("- Use the Conventional Commits form `type(scope): subject` with type one of: %s. The scope is optional; the subject is lowercase imperative without a trailing period.", strings.Join(conventionalTypes(policy), ", ")))
+	}
+	if policy.Pattern != "" {
+		rules = append(rules, fmt.Sprintf("- The title must match the regular expression `%s`.", policy.Pattern))
+	}
+	return strings.Join(rules, "\n")
+}
+
+// CreateTitlePrompt builds a prompt that asks the AI for a pull request title following rules,
+// based on the PR summary.
+func CreateTitlePrompt(pr *model.PullRequest, summary, rules string) string {
+	log.Debugf("Create Title Prompt for PR: %d", pr.ID)
+	return fmt.Sprintf(`Suggest a title for the pull request below.
+
+Rules:
+%s
+- At most 72 characters.
+- Keep identifiers such as ticket keys (e.g. ABC-123) from the current title.
+- Output ONLY the title on one line: no quotes, no Markdown, no explanation.
+
+Current Title: %s
+
+Pull Request Summary:
+---
+%s
+---
+`, rules, pr.Title, summary)
+}
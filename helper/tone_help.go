@@ -0,0 +1,129 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"strings"
+)
+
+// Supported review tones. An empty tone keeps the default CodeRabbit-style output.
+const (
+	ToneDefault   = ""
+	ToneConcise   = "concise"
+	ToneMentoring = "mentoring"
+	ToneStrict    = "strict"
+	// ToneTerse is accepted as another name for ToneConcise.
+	ToneTerse = "terse"
+)
+
+// ReviewStyle returns the tone of an entry: reviewStyle, else the older tone setting.
+func ReviewStyle(cfg *model.AutoReviewPR) string {
+	if strings.TrimSpace(cfg.ReviewStyle) != "" {
+		return cfg.ReviewStyle
+	}
+	return cfg.Tone
+}
+
+// NormalizeTone lowercases the configured tone and falls back to the default for unknown values.
+func NormalizeTone(tone string) string {
+	t := strings.ToLower(strings.TrimSpace(tone))
+	switch t {
+	case ToneDefault, ToneConcise, ToneMentoring, ToneStrict:
+		return t
+	case ToneTerse:
+		return ToneConcise
+	default:
+		log.Warnf("Unknown tone %q; using default tone", tone)
+		return ToneDefault
+	}
+}
+
+// reviewToneInstructions returns extra prompt rules for inline reviews in the given tone.
+func reviewToneInstructions(tone string) string {
+	switch NormalizeTone(tone) {
+	case ToneConcise:
+		return `Tone: CONCISE (overrides the reviewComment structure and examples above):
+- Each reviewComment is EXACTLY two lines separated by "\n":
+  line 1: [<Type>] [<Severity>] <the problem in one sentence>
+  line 2: Fix: <the change to make in one sentence>
+- No "Why", "How", "Suggested change", code blocks, or "Prompt for AI Agents" sections.
+- Skip Nitpick and Trivial findings.
+
+`
+	case ToneMentoring:
+		return `Tone: MENTORING:
+- Write for a developer who is still learning the codebase; be encouraging and explain the underlying principle.
+- In "Why", name the concept involved (e.g., resource leak, race condition, N+1 query) and why it matters.
+- Keep the required structure; prefer one finding with a clear explanation over several terse ones.
+
+`
+	case ToneStrict:
+		return `Tone: STRICT:
+- Report only Potential issue findings with Severity Critical or Major; do not report Refactor or Nitpick.
+- Be direct and factual; no praise, no hedging words like "maybe" or "consider".
+
+`
+	default:
+		return ""
+	}
+}
+
+// summaryToneInstructions returns extra prompt rules for the PR summary in the given tone.
+func summaryToneInstructions(tone string) string {
+	switch NormalizeTone(tone) {
+	case ToneConcise:
+		return `Tone: CONCISE (overrides the section requirements above):
+- Output ONLY the "## Summary" section with at most 3 items per populated group, and "## Breaking Changes" when there are any.
+- Omit "## Walkthrough", "## Changes", and "## Sequence Flow".
+
+`
+	case ToneMentoring:
+		return `Tone: MENTORING:
+- In "## Walkthrough", briefly explain the design choices and any patterns a newcomer should learn from this change.
+
+`
+	case ToneStrict:
+		return `Tone: STRICT:
+- Be direct and factual. In "## Walkthrough", call out risky areas explicitly; no praise.
+
+`
+	default:
+		return ""
+	}
+}
+
+// systemInstructions returns the entry's free-text instructions as a prompt section. They
+// adjust what is reported and how, but cannot change the required output format.
+func systemInstructions(cfg *model.AutoReviewPR) string {
+	text := strings.TrimSpace(cfg.SystemInstructions)
+	if text == "" {
+		return ""
+	}
+	return "Team instructions (follow them unless they conflict with the required output format):\n" + text + "\n\n"
+}
+
+// FormatReviewBodyForTone renders an inline comment body according to the tone.
+// Concise comments are clamped to their first two non-empty lines, per section of a comment
+// merged from several findings on one line.
+func FormatReviewBodyForTone(body, tone string) string {
+	if NormalizeTone(tone) != ToneConcise {
+		return FormatReviewBody(body)
+	}
+	if sections := strings.Split(body, findingSectionSeparator); len(sections) > 1 {
+		for i, section := range sections {
+			sections[i] = FormatReviewBodyForTone(section, tone)
+		}
+		return strings.Join(sections, findingSectionSeparator)
+	}
+	var lines []string
+	for _, ln := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(ln) == "" {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(ln))
+		if len(lines) == 2 {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
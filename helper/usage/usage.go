@@ -0,0 +1,168 @@
+package usage
+
+import (
+	"code_nim/helper/storage"
+	"code_nim/log"
+	"code_nim/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+// Tracker aggregates AI token usage per repository and per day and enforces the daily budget.
+// Only the current day is kept in memory; earlier days are read back from storage on demand.
+type Tracker struct {
+	storage  storage.Storage
+	settings model.UsageSettings
+
+	mutex sync.Mutex
+	day   string
+	today map[string]*model.UsageRecord // keyed by workspace/repoSlug
+}
+
+// New returns a Tracker persisting to store and restores today's totals from it.
+func New(store storage.Storage, settings model.UsageSettings) *Tracker {
+	t := &Tracker{storage: store, settings: settings}
+	t.rollover(time.Now().Format(dayLayout))
+	return t
+}
+
+// rollover switches the in-memory day, loading its records from storage. Callers hold the mutex
+// (or own the tracker exclusively).
+func (t *Tracker) rollover(day string) {
+	t.day = day
+	t.today = map[string]*model.UsageRecord{}
+	if t.storage == nil {
+		return
+	}
+	records, err := t.storage.LoadUsage(day)
+	if err != nil {
+		log.Errorf("Failed to load AI usage of %s: %v", day, err)
+		return
+	}
+	for i := range records {
+		r := records[i]
+		t.today[r.Workspace+"/"+r.RepoSlug] = &r
+	}
+}
+
+// Cost estimates the USD cost of usage on modelName from the configured prices. Cached prompt
+// tokens are billed at cachedPer1K when it is set.
+func (t *Tracker) Cost(modelName string, u model.AIUsage) float64 {
+	price, ok := t.settings.Prices[modelName]
+	if !ok {
+		price = t.settings.Prices["*"]
+	}
+	cachedPrice := price.CachedPer1K
+	if cachedPrice == 0 {
+		cachedPrice = price.PromptPer1K
+	}
+	return float64(u.PromptTokens-u.CachedTokens)/1000*price.PromptPer1K + float64(u.CachedTokens)/1000*cachedPrice + float64(u.ResponseTokens)/1000*price.ResponsePer1K
+}
+
+// Record adds the usage of one AI call made for cfg. It matches helper.UsageRecorder.
+func (t *Tracker) Record(cfg *model.AutoReviewPR, modelName string, u model.AIUsage) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	day := time.Now().Format(dayLayout)
+	if day != t.day {
+		t.rollover(day)
+	}
+	key := cfg.Workspace + "/" + cfg.RepoSlug
+	rec, ok := t.today[key]
+	if !ok {
+		rec = &model.UsageRecord{Day: day, Workspace: cfg.Workspace, RepoSlug: cfg.RepoSlug}
+		t.today[key] = rec
+	}
+	rec.Calls++
+	rec.PromptTokens += u.PromptTokens
+	rec.ResponseTokens += u.ResponseTokens
+	rec.TotalTokens += u.TotalTokens
+	rec.CachedTokens += u.CachedTokens
+	rec.CostUSD += t.Cost(modelName, u)
+	log.Debugf("AI usage for %s on %s: prompt=%d response=%d total=%d", key, modelName, u.PromptTokens, u.ResponseTokens, u.TotalTokens)
+
+	if t.storage == nil {
+		return
+	}
+	if err := t.storage.SaveUsage(day, t.snapshot()); err != nil {
+		log.Errorf("Failed to persist AI usage of %s: %v", day, err)
+	}
+}
+
+// snapshot returns today's records sorted by repository. Callers hold the mutex.
+func (t *Tracker) snapshot() []model.UsageRecord {
+	records := make([]model.UsageRecord, 0, len(t.today))
+	for _, r := range t.today {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Workspace != records[j].Workspace {
+			return records[i].Workspace < records[j].Workspace
+		}
+		return records[i].RepoSlug < records[j].RepoSlug
+	})
+	return records
+}
+
+// Today returns the records of the current day.
+func (t *Tracker) Today() []model.UsageRecord {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if day := time.Now().Format(dayLayout); day != t.day {
+		t.rollover(day)
+	}
+	return t.snapshot()
+}
+
+// Records returns the usage records of every day in [from, to], oldest first.
+func (t *Tracker) Records(from, to time.Time) ([]model.UsageRecord, error) {
+	today := time.Now().Format(dayLayout)
+	var out []model.UsageRecord
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := d.Format(dayLayout)
+		if day == today {
+			out = append(out, t.Today()...)
+			continue
+		}
+		if t.storage == nil {
+			continue
+		}
+		records, err := t.storage.LoadUsage(day)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, records...)
+	}
+	return out, nil
+}
+
+// BudgetExceeded reports whether today's usage across all repositories has reached the
+// configured daily token or cost budget, with a reason suitable for logs.
+func (t *Tracker) BudgetExceeded() (bool, string) {
+	if t.settings.DailyTokenBudget <= 0 && t.settings.DailyCostBudget <= 0 {
+		return false, ""
+	}
+	var tokens int64
+	var cost float64
+	for _, r := range t.Today() {
+		tokens += r.TotalTokens
+		cost += r.CostUSD
+	}
+	if t.settings.DailyTokenBudget > 0 && tokens >= t.settings.DailyTokenBudget {
+		return true, fmt.Sprintf("daily token budget reached (%d/%d tokens)", tokens, t.settings.DailyTokenBudget)
+	}
+	if t.settings.DailyCostBudget > 0 && cost >= t.settings.DailyCostBudget {
+		return true, fmt.Sprintf("daily cost budget reached ($%.4f/$%.2f)", cost, t.settings.DailyCostBudget)
+	}
+	return false, ""
+}
+
+// Settings returns the budget and price configuration.
+func (t *Tracker) Settings() model.UsageSettings {
+	return t.settings
+}
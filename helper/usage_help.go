@@ -0,0 +1,66 @@
+package helper
+
+import (
+	"code_nim/model"
+	"sync"
+)
+
+// UsageRecorder receives the token usage of every AI call made for a config entry.
+type UsageRecorder func(cfg *model.AutoReviewPR, modelName string, usage model.AIUsage)
+
+var (
+	usageRecorderMutex sync.RWMutex
+	usageRecorder      UsageRecorder
+)
+
+// SetUsageRecorder installs the callback used to account AI token usage; nil disables it.
+func SetUsageRecorder(r UsageRecorder) {
+	usageRecorderMutex.Lock()
+	defer usageRecorderMutex.Unlock()
+	usageRecorder = r
+}
+
+func recordUsage(cfg *model.AutoReviewPR, modelName string, usage model.AIUsage) {
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.ResponseTokens
+	}
+	if usage.TotalTokens == 0 {
+		return
+	}
+	usageRecorderMutex.RLock()
+	r := usageRecorder
+	usageRecorderMutex.RUnlock()
+	if r != nil {
+		r(cfg, modelName, usage)
+	}
+}
+
+// extractUsage reads token counts from a decoded AI response. It understands Gemini's
+// usageMetadata and the OpenAI-style usage object that self-hosted gateways often return.
+func extractUsage(result map[string]interface{}, usage *model.AIUsage) {
+	if usage == nil || result == nil {
+		return
+	}
+	if meta, ok := result["usageMetadata"].(map[string]interface{}); ok {
+		usage.PromptTokens += tokenCount(meta["promptTokenCount"])
+		usage.ResponseTokens += tokenCount(meta["candidatesTokenCount"])
+		usage.TotalTokens += tokenCount(meta["totalTokenCount"])
+		usage.CachedTokens += tokenCount(meta["cachedContentTokenCount"])
+		return
+	}
+	if meta, ok := result["usage"].(map[string]interface{}); ok {
+		usage.PromptTokens += tokenCount(meta["prompt_tokens"])
+		usage.ResponseTokens += tokenCount(meta["completion_tokens"])
+		usage.TotalTokens += tokenCount(meta["total_tokens"])
+		if details, ok := meta["prompt_tokens_details"].(map[string]interface{}); ok {
+			usage.CachedTokens += tokenCount(details["cached_tokens"])
+		}
+	}
+}
+
+func tokenCount(v interface{}) int64 {
+	if f, ok := v.(float64); ok {
+		return int64(f)
+	}
+	return 0
+}
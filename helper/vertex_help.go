@@ -0,0 +1,228 @@
+package helper
+
+import (
+	"code_nim/log"
+	"code_nim/model"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+const metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// googleCredentials is the subset of a Google credentials JSON file we understand.
+type googleCredentials struct {
+	Type         string `json:"type"` // "service_account" or "authorized_user"
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type cachedToken struct {
+	value  string
+	expiry time.Time
+}
+
+var (
+	vertexTokenMutex sync.Mutex
+	vertexTokens     = map[string]cachedToken{} // keyed by credentials file ("" = metadata server)
+)
+
+// vertexRequest builds the generateContent URL and auth headers for Vertex AI.
+func vertexRequest(cfg *model.AutoReviewPR, modelName string) (string, map[string]string, error) {
+	project := strings.TrimSpace(cfg.VertexProject)
+	if project == "" {
+		return "", nil, fmt.Errorf("vertexProject is required when aiProvider=gemini-vertex")
+	}
+	region := strings.TrimSpace(cfg.VertexRegion)
+	if region == "" {
+		region = "us-central1"
+	}
+	host := region + "-aiplatform.googleapis.com"
+	if region == "global" {
+		host = "aiplatform.googleapis.com"
+	}
+	token, err := vertexAccessToken(strings.TrimSpace(cfg.VertexCredentialsFile))
+	if err != nil {
+		return "", nil, err
+	}
+	endpoint := fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/publishers/google/models/%s:generateContent", host, project, region, modelName)
+	return endpoint, map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// vertexAccessToken resolves an OAuth2 access token following Application Default Credentials:
+// explicit credentials file, then GOOGLE_APPLICATION_CREDENTIALS, then the gcloud ADC file,
+// and finally the GCE/GKE metadata server (workload identity).
+func vertexAccessToken(credentialsFile string) (string, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if credentialsFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			adc := filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(adc); err == nil {
+				credentialsFile = adc
+			}
+		}
+	}
+
+	vertexTokenMutex.Lock()
+	defer vertexTokenMutex.Unlock()
+	if t, ok := vertexTokens[credentialsFile]; ok && time.Now().Add(time.Minute).Before(t.expiry) {
+		return t.value, nil
+	}
+
+	var token string
+	var expiresIn int
+	var err error
+	if credentialsFile == "" {
+		log.Debug("Fetching Vertex AI token from metadata server")
+		token, expiresIn, err = metadataToken()
+	} else {
+		log.Debugf("Fetching Vertex AI token using credentials file %s", credentialsFile)
+		token, expiresIn, err = credentialsFileToken(credentialsFile)
+	}
+	if err != nil {
+		return "", err
+	}
+	vertexTokens[credentialsFile] = cachedToken{value: token, expiry: time.Now().Add(time.Duration(expiresIn) * time.Second)}
+	return token, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func decodeTokenResponse(resp *http.Response) (string, int, error) {
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != 200 {
+		return "", 0, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(raw)[:min(300, len(raw))])
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(raw, &tr); err != nil {
+		return "", 0, err
+	}
+	if tr.AccessToken == "" {
+		return "", 0, fmt.Errorf("token endpoint returned no access_token")
+	}
+	return tr.AccessToken, tr.ExpiresIn, nil
+}
+
+func metadataToken() (string, int, error) {
+	req, err := http.NewRequest("GET", metadataTokenURL, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("no Google credentials found and metadata server unreachable: %w", err)
+	}
+	return decodeTokenResponse(resp)
+}
+
+func credentialsFileToken(path string) (string, int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	var creds googleCredentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return "", 0, fmt.Errorf("invalid Google credentials file %s: %w", path, err)
+	}
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = googleTokenURL
+	}
+
+	var form url.Values
+	switch creds.Type {
+	case "service_account":
+		assertion, err := signServiceAccountJWT(creds, tokenURI)
+		if err != nil {
+			return "", 0, err
+		}
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+	default:
+		return "", 0, fmt.Errorf("unsupported Google credentials type %q in %s", creds.Type, path)
+	}
+
+	resp, err := httpClient().PostForm(tokenURI, form)
+	if err != nil {
+		return "", 0, err
+	}
+	return decodeTokenResponse(resp)
+}
+
+// signServiceAccountJWT builds the RS256-signed assertion for the JWT bearer grant.
+func signServiceAccountJWT(creds googleCredentials, audience string) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if pkcs1, err1 := x509.ParsePKCS1PrivateKey(block.Bytes); err1 == nil {
+			parsed = pkcs1
+		} else {
+			return "", fmt.Errorf("failed to parse service account private_key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("service account private_key is not an RSA key")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": creds.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": vertexScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Signature headers carrying "sha256=<hex HMAC of the body>": GitHub sends the first,
+// Bitbucket Cloud the second.
+var WebhookSignatureHeaders = []string{"X-Hub-Signature-256", "X-Hub-Signature"}
+
+// VerifyWebhookSignature reports whether signature is a valid "sha256=<hex>" HMAC-SHA256 of body
+// under any of secrets. Several secrets allow rotating a repository's secret without downtime.
+func VerifyWebhookSignature(body []byte, signature string, secrets []string) bool {
+	algo, sig, ok := strings.Cut(strings.TrimSpace(signature), "=")
+	if !ok || !strings.EqualFold(algo, "sha256") {
+		return false
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if hmac.Equal(mac.Sum(nil), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package helper
+
+import "testing"
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	// The example of GitHub's "Validating webhook deliveries" documentation
+	const (
+		secret    = "It's a Secret to Everybody"
+		body      = "Hello, World!"
+		signature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	)
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		secrets   []string
+		want      bool
+	}{
+		{"documented example", body, signature, []string{secret}, true},
+		{"upper-case algorithm and padding", body, "  SHA256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17 ", []string{secret}, true},
+		{"second of two secrets", body, signature, []string{"old-secret", secret}, true},
+		{"other body", "Hello, World?", signature, []string{secret}, false},
+		{"other secret", body, signature, []string{"It's a secret to everybody"}, false},
+		{"no secrets", body, signature, nil, false},
+		// An empty secret is skipped, not used as an HMAC key
+		{"empty secret", body, "sha256=2bbcfa9524f3218c7a34b30e6936f8b1a4516cb097f1a85a1c7d98b5977ec769", []string{""}, false},
+		{"sha1 signature", body, "sha1=757107ea0eb2509fc211221cce984b8a37570b6d", []string{secret}, false},
+		{"not hex", body, "sha256=not-a-signature", []string{secret}, false},
+		{"missing algorithm", body, "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", []string{secret}, false},
+		{"truncated", body, signature[:len(signature)-2], []string{secret}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature([]byte(tt.body), tt.signature, tt.secrets); got != tt.want {
+				t.Errorf("VerifyWebhookSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -34,6 +34,14 @@ func Logger() *MyLogger {
 	return singletonLogger
 }
 
+// Dir returns the directory holding the rotated info/ and error/ log files.
+func Dir() string {
+	if runtime.GOOS == "windows" {
+		return "./log_files/"
+	}
+	return "../../log_files/"
+}
+
 // InitLogger return singleton logger
 func InitLogger(forTest bool) *MyLogger {
 	if Log != nil {
@@ -43,13 +51,7 @@ func InitLogger(forTest bool) *MyLogger {
 		return singletonLogger
 	}
 
-	var logPath string
-	if runtime.GOOS == "windows" {
-		logPath = "./log_files/"
-	} else {
-		logPath = "../../log_files/"
-	}
-
+	logPath := Dir()
 	Log = logrus.New()
 
 	if !forTest {
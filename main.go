@@ -2,10 +2,29 @@ package main
 
 import (
 	"code_nim/handler"
+	"code_nim/helper"
+	"code_nim/helper/atlassian/bitbucket_fake"
 	"code_nim/helper/atlassian/bitbucket_impl"
+	"code_nim/helper/gerrit/gerrit_impl"
+	"code_nim/helper/github/github_impl"
+	"code_nim/helper/httpcache"
+	"code_nim/helper/httpclient"
+	"code_nim/helper/leader"
+	"code_nim/helper/ledger"
+	"code_nim/helper/notify"
+	"code_nim/helper/queue"
+	"code_nim/helper/ratelimit"
+	"code_nim/helper/secrets"
+	"code_nim/helper/storage/storage_impl"
+	"code_nim/helper/timing"
+	"code_nim/helper/usage"
 	"code_nim/log"
+	"code_nim/model"
+	"code_nim/router"
 	"github.com/labstack/echo/v4"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func init() {
@@ -21,13 +40,117 @@ func init() {
 }
 
 func main() {
-	bitbucket := bitbucket_impl.New(nil)
+	var cfg model.Task
+	helper.LoadConfigFile(&cfg)
 
-	autoReviewPRHandler := handler.AutoReviewPRHandler{
-		Bitbucket: bitbucket,
+	httpClient, err := httpclient.New(cfg.HTTP)
+	if err != nil {
+		log.Fatalf("HTTP client setup failed: %v", err)
+	}
+	helper.SetHTTPClient(httpClient)
+	if mode := cfg.AIReplay.ModeName(); mode != "" {
+		log.Warnf("AI replies are in %s mode, stored in %s", mode, cfg.AIReplay.Path(cfg.DataDir))
+		helper.SetAIReplay(mode, cfg.AIReplay.Path(cfg.DataDir))
+	}
+	aiScheduler := ratelimit.New(cfg.AIRateLimit)
+	if aiScheduler != nil {
+		helper.SetAIScheduler(aiScheduler)
+	}
+	secretResolver := secrets.New(cfg.Secrets, httpClient)
+	if errs := secretResolver.ResolveEntries(cfg.AutoReviewPRs); len(errs) > 0 {
+		for _, err := range errs {
+			log.Error(err)
+		}
+		log.Fatalf("Secret resolution failed for %d credential fields", len(errs))
+	}
+	bitbucketClient := httpClient
+	var bitbucketCache *httpcache.Transport
+	if !cfg.HTTP.Cache.Disabled {
+		bitbucketClient, bitbucketCache = bitbucket_impl.WithCache(httpClient, cfg.HTTP.Cache)
+	}
+	bitbucket := bitbucket_impl.New(bitbucketClient)
+	if cfg.FakeBitbucket.Enabled() {
+		log.Warnf("Serving Bitbucket from fixtures in %s; writes are recorded in %s", cfg.FakeBitbucket.Fixtures, cfg.FakeBitbucket.RecordPath(cfg.DataDir))
+		bitbucket, bitbucketCache = bitbucket_fake.New(cfg.FakeBitbucket, cfg.DataDir), nil
+	}
+	store := storage_impl.New(cfg.DataDir)
+	if err := store.Migrate(); err != nil {
+		log.Fatalf("Storage migration failed: %v", err)
+	}
+	usageTracker := usage.New(store, cfg.Usage)
+	helper.SetUsageRecorder(usageTracker.Record)
+	elector, err := leader.New(cfg.LeaderElection)
+	if err != nil {
+		log.Fatalf("Leader election setup failed: %v", err)
+	}
+	if elector != nil {
+		elector.Start()
+		// Hand the lease over at once on shutdown instead of waiting for it to expire.
+		go func() {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+			<-sig
+			elector.Stop()
+			os.Exit(0)
+		}()
+	}
+
+	reviewQueue, err := queue.Open(cfg.Queue)
+	if err != nil {
+		log.Fatalf("Queue setup failed: %v", err)
+	}
+
+	notifications := ledger.New(store, cfg.Notifications.RetentionDays)
+	autoReviewPRHandler := &handler.AutoReviewPRHandler{
+		Bitbucket:     bitbucket,
+		Gerrit:        gerrit_impl.New(httpClient),
+		GitHub:        github_impl.New(httpClient),
+		Queue:         reviewQueue,
+		Webhook:       cfg.Webhook,
+		Leader:        elector,
+		Notifications: notifications,
+		Notifier:      &notify.Dispatcher{Client: httpClient, Ledger: notifications},
+		Storage:       store,
+		Transcripts:   cfg.Transcripts,
+		DashboardURL:  cfg.DashboardURL,
+		Usage:         usageTracker,
+		Timings:       timing.New(),
+		AICache:       cfg.AICache,
+		Secrets:       secretResolver,
+		HTTPCache:     bitbucketCache,
+		AIScheduler:   aiScheduler,
+	}
+	benchmarkHandler := &handler.BenchmarkHandler{
+		Storage:  store,
+		Usage:    usageTracker,
+		Leader:   elector,
+		Settings: cfg.Benchmark,
+		DataDir:  cfg.DataDir,
+		Entries:  cfg.AutoReviewPRs,
+	}
+	autoReviewPRHandler.Benchmark = benchmarkHandler
+	transcriptHandler := handler.TranscriptHandler{
+		Storage:   store,
+		Settings:  cfg.Transcripts,
+		Retention: cfg.Retention,
+		AICache:   cfg.AICache,
 	}
 
 	e := echo.New()
-	autoReviewPRHandler.HandlerAutoReviewPR()
+	api := router.API{
+		Echo:                e,
+		AutoReviewPRHandler: autoReviewPRHandler,
+		TranscriptHandler:   transcriptHandler,
+		FindingHandler:      handler.FindingHandler{Storage: store},
+		UsageHandler:        handler.UsageHandler{Tracker: usageTracker},
+		ReportHandler:       handler.ReportHandler{Storage: store},
+		BenchmarkHandler:    benchmarkHandler,
+		Auth:                cfg.Auth,
+	}
+	api.SetupRouter()
+
+	autoReviewPRHandler.HandlerAutoReviewPR(cfg)
+	transcriptHandler.HandlerTranscriptRetention()
+	benchmarkHandler.HandlerBenchmark()
 	e.Logger.Fatal(e.Start(":1994"))
 }
@@ -0,0 +1,12 @@
+package model
+
+// AdaptivePromptSettings feeds the findings a team keeps dismissing back into the review prompt.
+type AdaptivePromptSettings struct {
+	// Enabled adds the finding kinds with mostly unhelpful feedback (see FeedbackSettings) to
+	// the inline review prompt as kinds to avoid.
+	Enabled bool `yaml:"enabled"`
+	// MinDismissals is how many unhelpful replies a kind needs before it is added (default 3).
+	MinDismissals int `yaml:"minDismissals,omitempty"`
+	// WindowDays is how far back feedback is considered (default 90, bounded by the retention).
+	WindowDays int `yaml:"windowDays,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// DefaultAICacheMaxAge is how long a cached AI reply is reused.
+const DefaultAICacheMaxAge = 7 * 24 * time.Hour
+
+// AIResponse is a cached AI reply. Key hashes the provider, model and prompt, and the prompt
+// embeds the diff, so a reply is only reused while the reviewed diff is unchanged.
+type AIResponse struct {
+	Key           string          `json:"key"`
+	Kind          string          `json:"kind"` // "summary", "description", "title" or "inline"
+	ProcessName   string          `json:"processName,omitempty"`
+	Workspace     string          `json:"workspace,omitempty"`
+	RepoSlug      string          `json:"repoSlug,omitempty"`
+	PullRequestID int             `json:"pullRequestId,omitempty"`
+	Path          string          `json:"path,omitempty"`     // File path for inline replies
+	Response      string          `json:"response,omitempty"` // Text of summary, description and title replies
+	Findings      []ReviewComment `json:"findings,omitempty"` // Parsed findings of inline replies
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// AICacheSettings controls the reuse of AI replies for unchanged diffs.
+type AICacheSettings struct {
+	Disabled bool   `yaml:"disabled,omitempty"`
+	MaxAge   string `yaml:"maxAge,omitempty"` // Oldest reply reused, e.g. "168h" (default)
+}
+
+// MaxAgeDuration parses MaxAge, defaulting to DefaultAICacheMaxAge. ok is false when MaxAge
+// is set but not a positive duration.
+func (s AICacheSettings) MaxAgeDuration() (time.Duration, bool) {
+	if s.MaxAge == "" {
+		return DefaultAICacheMaxAge, true
+	}
+	d, err := time.ParseDuration(s.MaxAge)
+	if err != nil || d <= 0 {
+		return DefaultAICacheMaxAge, false
+	}
+	return d, true
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Defaults of AIRateLimitSettings.
+const (
+	DefaultAIRateLimitMaxWait        = 5 * time.Minute
+	DefaultAIRateLimitResponseTokens = 2048
+)
+
+// AIRateLimit is the per-minute quota of requests (RPM) and prompt plus response tokens (TPM)
+// of one model; 0 leaves that limit out.
+type AIRateLimit struct {
+	RPM int `yaml:"rpm,omitempty"`
+	TPM int `yaml:"tpm,omitempty"`
+}
+
+// AIRateLimitSettings queues the AI requests of all jobs in one scheduler, so that together
+// they stay within the provider's per-minute quota. Waiting requests are admitted round-robin
+// between repositories.
+type AIRateLimitSettings struct {
+	// AIRateLimit applies to each model without an entry in Models.
+	AIRateLimit `yaml:",inline"`
+	// Models are the quotas of individual models, such as gemini-2.5-pro: {rpm: 5}.
+	Models map[string]AIRateLimit `yaml:"models,omitempty"`
+	// MaxWait is how long a request waits for a slot before it fails as rate limited (default 5m).
+	MaxWait string `yaml:"maxWait,omitempty"`
+	// ResponseTokens are reserved per request for the reply until its usage is known (default 2048).
+	ResponseTokens int `yaml:"responseTokens,omitempty"`
+}
+
+// Enabled reports whether any limit is set.
+func (s AIRateLimitSettings) Enabled() bool {
+	if s.RPM > 0 || s.TPM > 0 {
+		return true
+	}
+	for _, l := range s.Models {
+		if l.RPM > 0 || l.TPM > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Limit returns the quota of modelName: its entry in Models, or the default limits.
+func (s AIRateLimitSettings) Limit(modelName string) AIRateLimit {
+	for name, l := range s.Models {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(modelName)) {
+			return l
+		}
+	}
+	return s.AIRateLimit
+}
+
+// MaxWaitDuration returns the parsed MaxWait, the default when unset, and ok=false when it is invalid.
+func (s AIRateLimitSettings) MaxWaitDuration() (time.Duration, bool) {
+	return positiveDuration(s.MaxWait, DefaultAIRateLimitMaxWait)
+}
+
+// ReservedResponseTokens returns ResponseTokens or its default.
+func (s AIRateLimitSettings) ReservedResponseTokens() int {
+	if s.ResponseTokens > 0 {
+		return s.ResponseTokens
+	}
+	return DefaultAIRateLimitResponseTokens
+}
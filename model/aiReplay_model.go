@@ -0,0 +1,49 @@
+package model
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AI replay modes of AIReplaySettings.Mode.
+const (
+	AIReplayRecord = "record" // call the provider and store every reply
+	AIReplayReplay = "replay" // answer only from stored replies; a prompt without one fails
+	AIReplayAuto   = "auto"   // answer from stored replies, calling and storing on a miss
+)
+
+// AIReplaySettings stores AI replies by prompt and serves them again, so reruns of the same
+// prompts are deterministic, cost nothing and work offline.
+type AIReplaySettings struct {
+	Mode string `yaml:"mode,omitempty"` // "record", "replay" or "auto"; empty turns it off
+	Dir  string `yaml:"dir,omitempty"`  // Where replies are stored (default <dataDir>/ai-replay)
+}
+
+// ModeName returns Mode in lower case.
+func (s AIReplaySettings) ModeName() string {
+	return strings.ToLower(strings.TrimSpace(s.Mode))
+}
+
+// Path returns Dir, or the ai-replay folder of dataDir when it is empty.
+func (s AIReplaySettings) Path(dataDir string) string {
+	if dir := strings.TrimSpace(s.Dir); dir != "" {
+		return dir
+	}
+	if strings.TrimSpace(dataDir) == "" {
+		dataDir = "data"
+	}
+	return filepath.Join(dataDir, "ai-replay")
+}
+
+// AIRecording is one stored AI exchange. Reply holds the summary text or the findings.
+type AIRecording struct {
+	Key        string          `json:"key"`
+	Kind       string          `json:"kind"` // "text" or "findings"
+	Provider   string          `json:"provider"`
+	Model      string          `json:"model"`
+	Prompt     string          `json:"prompt"`
+	Text       string          `json:"text,omitempty"`
+	Findings   []ReviewComment `json:"findings,omitempty"`
+	RecordedAt time.Time       `json:"recordedAt"`
+}
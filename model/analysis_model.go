@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Built-in linters of the static analysis step.
+const (
+	LinterGoVet       = "go-vet"
+	LinterStaticcheck = "staticcheck"
+	LinterESLint      = "eslint"
+)
+
+// StaticAnalysisSettings runs linters on a checkout of the PR branch before the AI review and
+// adds their findings on changed files to the review prompt.
+type StaticAnalysisSettings struct {
+	Linters []LinterSettings `yaml:"linters,omitempty"`
+	Timeout string           `yaml:"timeout,omitempty"` // Per clone and per linter, e.g. "5m" (default)
+}
+
+// LinterSettings selects one linter: a built-in by Name, or any tool given by Command.
+type LinterSettings struct {
+	Name string `yaml:"name"` // "go-vet", "staticcheck", "eslint", or a label for Command
+	// Command runs a custom linter in the checkout root. Its output lines must look like
+	// "path:line[:column]: message", e.g. golangci-lint --out-format=line-number.
+	Command []string `yaml:"command,omitempty"`
+}
+
+// Enabled reports whether any linter is configured.
+func (s StaticAnalysisSettings) Enabled() bool {
+	return len(s.Linters) > 0
+}
+
+// TimeoutDuration returns the per-step timeout, 5 minutes when unset or invalid.
+func (s StaticAnalysisSettings) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(s.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// LintFinding is one problem reported by a linter, with Path relative to the repository root.
+type LintFinding struct {
+	Linter  string `json:"linter"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
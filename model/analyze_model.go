@@ -0,0 +1,42 @@
+package model
+
+// AnalyzeRequest is the body of POST /api/v1/analyze: a unified diff to review with the AI
+// settings of a configured entry, without a git host.
+type AnalyzeRequest struct {
+	// ProcessName selects the entry whose AI provider, model, tone, language and instructions
+	// are used; it may be omitted when only one entry is configured.
+	ProcessName string `json:"processName,omitempty"`
+	Diff        string `json:"diff"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Summary also asks the AI for a Markdown summary of the change.
+	Summary bool `json:"summary,omitempty"`
+}
+
+// AnalyzeFinding is one finding of an analyzed diff, anchored to a file line.
+type AnalyzeFinding struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`                // new-file line; 0 on a removed line
+	FromLine  int    `json:"fromLine,omitempty"`  // old-file line of a removed or context line
+	StartLine int    `json:"startLine,omitempty"` // first line of a multi-line finding
+	Type      string `json:"type,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Body      string `json:"body"` // Markdown as it would be posted
+	Fix       string `json:"fix,omitempty"`
+}
+
+// AnalyzeSkippedFile is a file of an analyzed diff that was not reviewed.
+type AnalyzeSkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// AnalyzeResponse is the result of POST /api/v1/analyze.
+type AnalyzeResponse struct {
+	Summary  string               `json:"summary,omitempty"`
+	Findings []AnalyzeFinding     `json:"findings"`
+	Skipped  []AnalyzeSkippedFile `json:"skipped,omitempty"`
+	// Errors holds the AI error of each file that could not be reviewed, by path.
+	Errors map[string]string `json:"errors,omitempty"`
+}
@@ -0,0 +1,35 @@
+package model
+
+// Route groups that auth can protect.
+const (
+	RouteGroupAdmin     = "admin"     // job control and purges
+	RouteGroupAPI       = "api"       // read-only JSON APIs
+	RouteGroupDashboard = "dashboard" // dashboard and finding pages
+	RouteGroupMetrics   = "metrics"   // Prometheus metrics
+	RouteGroupStatus    = "status"    // public status page; open unless listed in protect
+)
+
+// AuthSettings configures authentication of the HTTP server. A request is accepted when it
+// matches any configured method; with no method configured every route stays open.
+type AuthSettings struct {
+	APIKeys   []string          `yaml:"apiKeys,omitempty"`   // Sent as X-API-Key or "Authorization: Bearer <key>"
+	BasicAuth []BasicAuthUser   `yaml:"basicAuth,omitempty"` // Users allowed with HTTP basic auth
+	OIDC      OIDCSettings      `yaml:"oidc,omitempty"`      // Bearer ID/access tokens signed by an OIDC issuer
+	Protect   []string          `yaml:"protect,omitempty"`   // Route groups to protect (default: admin, api, dashboard)
+	Routes    map[string]string `yaml:"routes,omitempty"`    // Per-route overrides: "METHOD /path" -> group or "public"
+}
+
+type BasicAuthUser struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type OIDCSettings struct {
+	Issuer   string `yaml:"issuer,omitempty"`   // e.g. https://login.example.com/realms/eng
+	Audience string `yaml:"audience,omitempty"` // Required "aud" claim; empty skips the check
+}
+
+// Enabled reports whether any authentication method is configured.
+func (a AuthSettings) Enabled() bool {
+	return len(a.APIKeys) > 0 || len(a.BasicAuth) > 0 || a.OIDC.Issuer != ""
+}
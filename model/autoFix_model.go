@@ -0,0 +1,35 @@
+package model
+
+// Defaults of AutoFixSettings.
+const (
+	DefaultAutoFixBranchPrefix = "code-nim/autofix-"
+	DefaultAutoFixMaxFixes     = 10
+)
+
+// AutoFixSettings lets the bot commit the mechanical fixes of its findings, such as typos or
+// missing error checks with obvious handling, to a new branch and open a follow-up pull request
+// into the reviewed pull request's source branch.
+type AutoFixSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// BranchPrefix starts the name of the fix branch, followed by the PR ID and the short hash of
+	// the reviewed commit (default "code-nim/autofix-").
+	BranchPrefix string `yaml:"branchPrefix,omitempty"`
+	// MaxFixes bounds the fixes committed per review (default 10).
+	MaxFixes int `yaml:"maxFixes,omitempty"`
+}
+
+// Prefix returns BranchPrefix, or DefaultAutoFixBranchPrefix when it is unset.
+func (s AutoFixSettings) Prefix() string {
+	if s.BranchPrefix != "" {
+		return s.BranchPrefix
+	}
+	return DefaultAutoFixBranchPrefix
+}
+
+// Limit returns MaxFixes, or DefaultAutoFixMaxFixes when it is unset.
+func (s AutoFixSettings) Limit() int {
+	if s.MaxFixes > 0 {
+		return s.MaxFixes
+	}
+	return DefaultAutoFixMaxFixes
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// BenchmarkSettings configures the periodic model benchmark. It runs a fixed suite of stored
+// diffs through every candidate model and records their quality, latency and cost.
+type BenchmarkSettings struct {
+	Cron     string `yaml:"cron,omitempty"`     // Cron without seconds, e.g. "0 4 * * 1"; empty disables the schedule
+	SuiteDir string `yaml:"suiteDir,omitempty"` // Directory of <case>.diff files (default: <dataDir>/benchmark-suite)
+	// Candidates are AI settings to compare in addition to the distinct providers and models
+	// of the autoReviewPR entries. Only the AI fields of each candidate are used.
+	Candidates []AutoReviewPR `yaml:"candidates,omitempty"`
+	// LineTolerance is how many lines a finding may be away from an expected finding and still
+	// match it (default 3).
+	LineTolerance int `yaml:"lineTolerance,omitempty"`
+}
+
+// Enabled reports whether the benchmark is scheduled.
+func (b BenchmarkSettings) Enabled() bool {
+	return b.Cron != ""
+}
+
+// BenchmarkCase is one stored diff of the suite. Expected lists the findings a good review
+// reports; a case without expectations only measures latency, cost and placement.
+type BenchmarkCase struct {
+	Name        string                 `json:"name"` // file name without .diff
+	Title       string                 `json:"title" yaml:"title"`
+	Description string                 `json:"description,omitempty" yaml:"description"`
+	Expected    []BenchmarkExpectation `json:"expected,omitempty" yaml:"expected"`
+	Diff        string                 `json:"-" yaml:"-"`
+}
+
+// BenchmarkExpectation is a finding the suite expects on a new-file line.
+type BenchmarkExpectation struct {
+	Path string `json:"path" yaml:"path"`
+	Line int    `json:"line" yaml:"line"`
+}
+
+// BenchmarkResult is the outcome of the suite for one candidate model.
+type BenchmarkResult struct {
+	Candidate string `json:"candidate"` // provider/model
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+
+	Cases    int `json:"cases"`
+	Errors   int `json:"errors"`   // files the model failed to review
+	Placed   int `json:"placed"`   // findings anchored to a diff line
+	Rejected int `json:"rejected"` // findings that could not be placed
+	Expected int `json:"expected"`
+	Matched  int `json:"matched"` // expected findings the model reported
+
+	Recall         float64       `json:"recall"`         // Matched / Expected
+	Precision      float64       `json:"precision"`      // placed findings near an expected one / Placed, on cases with expectations
+	PlacementRate  float64       `json:"placementRate"`  // Placed / (Placed + Rejected)
+	AvgCaseLatency time.Duration `json:"avgCaseLatency"` // average time to review one case
+	Tokens         int64         `json:"tokens"`
+	CostUSD        float64       `json:"costUsd"`
+}
+
+// BenchmarkRun is one run of the suite across all candidates.
+type BenchmarkRun struct {
+	StartedAt time.Time         `json:"startedAt"`
+	Duration  time.Duration     `json:"duration"`
+	Cases     []string          `json:"cases"`
+	Results   []BenchmarkResult `json:"results"`
+}
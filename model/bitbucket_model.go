@@ -6,11 +6,30 @@ type PullRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	CreatedOn   string `json:"created_on"`
+	UpdatedOn   string `json:"updated_on"`
 	State       string `json:"state"`
 	Author      struct {
 		DisplayName string `json:"display_name"`
 		Nickname    string `json:"nickname"`
+		UUID        string `json:"uuid"`
+		AccountID   string `json:"account_id"` // used to mention the author as @{account_id}
 	} `json:"author"`
+	Source struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		Repository struct {
+			FullName string `json:"full_name"` // "workspace/repo"; differs from the target for forks
+		} `json:"repository"`
+	} `json:"source"`
+	Destination struct {
+		Commit struct {
+			Hash string `json:"hash"`
+		} `json:"commit"`
+	} `json:"destination"`
+	// CommitMessages are the messages of the PR's commits, oldest first. Bitbucket does not
+	// return them with the PR; callers fill them in so the summary reflects the author's intent.
+	CommitMessages []string `json:"-"`
 }
 
 type PullRequestComment struct {
@@ -25,10 +44,31 @@ type PullRequestComment struct {
 	Inline *struct {
 		Path string `json:"path"` // File path for inline comments
 		To   int    `json:"to"`   // Line number for inline comments
+		From int    `json:"from"` // Source line number, set alone for comments on removed lines
 	} `json:"inline,omitempty"` // Only present for inline comments
+	Deleted    bool      `json:"deleted"`
+	Resolution *struct{} `json:"resolution,omitempty"` // Present once the comment thread is resolved
+	Parent     *struct {
+		ID int `json:"id"`
+	} `json:"parent,omitempty"` // Present on replies
+	CreatedOn string `json:"created_on"`
+}
+
+// PullRequestParticipant is a reviewer or commenter of a pull request and their vote.
+type PullRequestParticipant struct {
+	User struct {
+		DisplayName string `json:"display_name"`
+		Nickname    string `json:"nickname"`
+		UUID        string `json:"uuid"`
+		AccountID   string `json:"account_id"`
+	} `json:"user"`
+	Role     string `json:"role"` // "REVIEWER" or "PARTICIPANT"
+	Approved bool   `json:"approved"`
+	State    string `json:"state"` // "approved", "changes_requested" or empty
 }
 
 type PullRequestCommit struct {
-	Hash string `json:"hash"`
-	Date string `json:"date"`
+	Hash    string `json:"hash"`
+	Date    string `json:"date"`
+	Message string `json:"message"`
 }
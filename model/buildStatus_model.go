@@ -0,0 +1,26 @@
+package model
+
+// Commit build states. A branch restriction requiring passing builds accepts only SUCCESSFUL.
+const (
+	BuildInProgress = "INPROGRESS"
+	BuildSuccessful = "SUCCESSFUL"
+	BuildFailed     = "FAILED"
+)
+
+// BuildStatusSettings posts a commit build status for the review, so a branch restriction
+// can require the bot's pass before merging.
+type BuildStatusSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// FailSeverity is the least severe open finding that fails the build: "Info", "Trivial",
+	// "Minor", "Major" (default) or "Critical".
+	FailSeverity string `yaml:"failSeverity,omitempty"`
+}
+
+// BuildStatus is the body of a Bitbucket commit build status.
+type BuildStatus struct {
+	Key         string `json:"key"`
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Defaults of the Bitbucket circuit breaker.
+const (
+	DefaultCircuitFailures    = 5
+	DefaultCircuitCooldown    = 5 * time.Minute
+	DefaultCircuitMaxCooldown = 6 * time.Hour
+)
+
+// CircuitBreakerSettings stops reviewing a job whose Bitbucket requests keep failing, e.g.
+// after its app password expired or its repository moved, instead of retrying on every tick.
+type CircuitBreakerSettings struct {
+	// Failures is how many runs in a row must fail on Bitbucket before the circuit opens
+	// (default 5); a negative value disables the breaker.
+	Failures int `yaml:"failures,omitempty"`
+	// Cooldown is how long reviews are skipped the first time the circuit opens, e.g. "5m"
+	// (default). It doubles with each further failure, up to MaxCooldown.
+	Cooldown string `yaml:"cooldown,omitempty"`
+	// MaxCooldown bounds the cool-down, e.g. "6h" (default).
+	MaxCooldown string `yaml:"maxCooldown,omitempty"`
+}
+
+// Threshold returns how many failed runs open the circuit, or 0 when the breaker is disabled.
+func (s CircuitBreakerSettings) Threshold() int {
+	switch {
+	case s.Failures < 0:
+		return 0
+	case s.Failures == 0:
+		return DefaultCircuitFailures
+	}
+	return s.Failures
+}
+
+// CooldownAfter returns how long the circuit stays open after failures consecutive failed runs,
+// at least the threshold: Cooldown doubled for each failure past the threshold, up to
+// MaxCooldown. ok is false when Cooldown or MaxCooldown is set but not a positive duration.
+func (s CircuitBreakerSettings) CooldownAfter(failures int) (d time.Duration, ok bool) {
+	base, ok1 := positiveDuration(s.Cooldown, DefaultCircuitCooldown)
+	max, ok2 := positiveDuration(s.MaxCooldown, DefaultCircuitMaxCooldown)
+	d = base
+	for i := s.Threshold(); i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d, ok1 && ok2
+}
+
+// positiveDuration parses s, falling back to def when it is empty or not a positive duration.
+func positiveDuration(s string, def time.Duration) (time.Duration, bool) {
+	if s == "" {
+		return def, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def, false
+	}
+	return d, true
+}
@@ -0,0 +1,9 @@
+package model
+
+// CommentFooterSettings configures the footer appended to the comments the bot posts.
+type CommentFooterSettings struct {
+	// Enabled appends a footer naming the AI model, the review run and how to give feedback.
+	Enabled bool `yaml:"enabled"`
+	// Feedback replaces the default "Was this helpful?" line; "none" leaves it out.
+	Feedback string `yaml:"feedback,omitempty"`
+}
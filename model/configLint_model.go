@@ -0,0 +1,17 @@
+package model
+
+// Config lint warning kinds.
+const (
+	ConfigWarningUnknown    = "unknown"    // Key the config does not define; it is ignored
+	ConfigWarningDeprecated = "deprecated" // Key still honoured but replaced by a newer one
+	ConfigWarningConflict   = "conflict"   // Options that cancel each other out or have no effect together
+	ConfigWarningInvalid    = "invalid"    // Value the service cannot use
+)
+
+// ConfigWarning is one problem found by the config linter.
+type ConfigWarning struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"` // e.g. autoReviewPR[0].geminiKey
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
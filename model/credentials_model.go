@@ -0,0 +1,30 @@
+package model
+
+import "gopkg.in/yaml.v3"
+
+// CredentialProfile is a named set of Bitbucket and AI provider credentials that entries refer
+// to with credentials, so a rotated secret is changed in one place.
+type CredentialProfile struct {
+	Username    string   `yaml:"username,omitempty"`
+	AppPassword string   `yaml:"appPassword,omitempty"`
+	AIKey       string   `yaml:"aiKey,omitempty"`
+	AIKeys      []string `yaml:"aiKeys,omitempty"`
+	// VertexCredentialsFile is the service-account JSON used with aiProvider gemini-vertex.
+	VertexCredentialsFile string `yaml:"vertexCredentialsFile,omitempty"`
+}
+
+// NameList is a list of names that may also be written as a single YAML string.
+type NameList []string
+
+func (n *NameList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*n = NameList{node.Value}
+		return nil
+	}
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		return err
+	}
+	*n = names
+	return nil
+}
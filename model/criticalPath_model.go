@@ -0,0 +1,15 @@
+package model
+
+// DefaultCriticalPathMinSeverity is the least severe finding still posted on a critical file of
+// a busy pull request.
+const DefaultCriticalPathMinSeverity = "Minor"
+
+// CriticalPathSettings tunes the escalated review of the files under AutoReviewPR.CriticalPaths.
+type CriticalPathSettings struct {
+	// Instructions replace helper.DefaultCriticalPathInstructions, the stricter rules added to
+	// the inline review prompt of critical files.
+	Instructions string `yaml:"instructions,omitempty"`
+	// MinSeverity is the least severe finding posted on critical files of busy PRs, where other
+	// files post only from busyPrMinSeverity (default "Minor").
+	MinSeverity string `yaml:"minSeverity,omitempty"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// DescriptionSettings lets the bot write the description of a pull request that has none.
+type DescriptionSettings struct {
+	// Enabled replaces an empty or one-line description with a generated What/Why/How/Test plan
+	// description. A one-line description is kept as its first line.
+	Enabled bool `yaml:"enabled"`
+}
@@ -0,0 +1,33 @@
+package model
+
+// DigestSettings emails a team a periodic summary of review activity.
+type DigestSettings struct {
+	// Team names the digest in its subject and logs.
+	Team string `yaml:"team"`
+	// Cron schedules the digest, with seconds like the entries' cron (default "0 0 8 * * *",
+	// daily at 08:00).
+	Cron string `yaml:"cron,omitempty"`
+	// Entries are the processNames (or workspace/repoSlug of unnamed entries) the team owns;
+	// empty covers every entry.
+	Entries []string `yaml:"entries,omitempty"`
+	// TopFindings is how many of the most severe new findings are listed (default 5).
+	TopFindings int `yaml:"topFindings,omitempty"`
+	// SMTP is the mail server and recipients.
+	SMTP SMTPSettings `yaml:"smtp"`
+}
+
+// Schedule returns the cron of the digest.
+func (d DigestSettings) Schedule() string {
+	if d.Cron == "" {
+		return "0 0 8 * * *"
+	}
+	return d.Cron
+}
+
+// TopFindingsLimit returns how many findings the digest lists.
+func (d DigestSettings) TopFindingsLimit() int {
+	if d.TopFindings <= 0 {
+		return 5
+	}
+	return d.TopFindings
+}
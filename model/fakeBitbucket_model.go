@@ -0,0 +1,32 @@
+package model
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// FakeBitbucketSettings replaces the Bitbucket API with local fixture files, to run the whole
+// review pipeline against recorded data without real credentials.
+type FakeBitbucketSettings struct {
+	// Fixtures is the directory of recorded responses, one folder per workspace and repository.
+	// Setting it selects the fake provider for every Bitbucket entry.
+	Fixtures string `yaml:"fixtures,omitempty"`
+	// RecordDir is where posted comments and other writes are stored (default <dataDir>/fake).
+	RecordDir string `yaml:"recordDir,omitempty"`
+}
+
+// Enabled reports whether Bitbucket requests are served from fixtures.
+func (s FakeBitbucketSettings) Enabled() bool {
+	return strings.TrimSpace(s.Fixtures) != ""
+}
+
+// RecordPath returns RecordDir, or the fake folder of dataDir when it is empty.
+func (s FakeBitbucketSettings) RecordPath(dataDir string) string {
+	if dir := strings.TrimSpace(s.RecordDir); dir != "" {
+		return dir
+	}
+	if strings.TrimSpace(dataDir) == "" {
+		dataDir = "data"
+	}
+	return filepath.Join(dataDir, "fake")
+}
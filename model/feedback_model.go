@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Verdicts of a feedback reply.
+const (
+	FeedbackHelpful   = "helpful"
+	FeedbackUnhelpful = "unhelpful"
+)
+
+// FeedbackSettings collects replies to the bot's comments as feedback on its findings.
+type FeedbackSettings struct {
+	// Cron schedules the collection, with seconds like the entry's cron; empty disables it.
+	Cron string `yaml:"cron,omitempty"`
+}
+
+// Enabled reports whether feedback collection is scheduled.
+func (s FeedbackSettings) Enabled() bool {
+	return s.Cron != ""
+}
+
+// Feedback is a reply to a bot comment classified as helpful or unhelpful.
+type Feedback struct {
+	Workspace     string    `json:"workspace"`
+	RepoSlug      string    `json:"repoSlug"`
+	PullRequestID int       `json:"pullRequestId"`
+	CommentID     int       `json:"commentId"` // the bot comment replied to
+	ReplyID       int       `json:"replyId"`
+	Path          string    `json:"path,omitempty"` // empty for general comments
+	Line          int       `json:"line,omitempty"`
+	Type          string    `json:"type,omitempty"` // of the finding replied to
+	Severity      string    `json:"severity,omitempty"`
+	Title         string    `json:"title,omitempty"`
+	Verdict       string    `json:"verdict"`
+	Author        string    `json:"author"`
+	Text          string    `json:"text"`
+	CreatedAt     time.Time `json:"createdAt"` // when the reply was written
+}
+
+// FeedbackFilter selects stored feedback; zero values match everything.
+type FeedbackFilter struct {
+	Workspace string
+	RepoSlug  string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+}
+
+// Matches reports whether f passes the filter.
+func (ff FeedbackFilter) Matches(f Feedback) bool {
+	if ff.Workspace != "" && !strings.EqualFold(ff.Workspace, f.Workspace) {
+		return false
+	}
+	if ff.RepoSlug != "" && !strings.EqualFold(ff.RepoSlug, f.RepoSlug) {
+		return false
+	}
+	if !ff.From.IsZero() && f.CreatedAt.Before(ff.From) {
+		return false
+	}
+	if !ff.To.IsZero() && !f.CreatedAt.Before(ff.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Finding is a stored review finding whose full details are served by the dashboard.
+type Finding struct {
+	ID               string    `json:"id"`
+	ProcessName      string    `json:"processName"`
+	Workspace        string    `json:"workspace"`
+	RepoSlug         string    `json:"repoSlug"`
+	PullRequestID    int       `json:"pullRequestId"`
+	PullRequestTitle string    `json:"pullRequestTitle"`
+	Path             string    `json:"path"`
+	Line             int       `json:"line"`
+	OldLine          int       `json:"oldLine,omitempty"` // source line of a finding on a removed line (Line is 0)
+	Type             string    `json:"type,omitempty"`
+	Severity         string    `json:"severity,omitempty"`
+	Title            string    `json:"title"`
+	Body             string    `json:"body"`
+	Deferred         bool      `json:"deferred,omitempty"` // held back on a busy PR instead of being posted
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// FindingFilter selects stored findings; zero values match everything.
+type FindingFilter struct {
+	Workspace string
+	RepoSlug  string
+	Severity  string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+}
+
+// Matches reports whether f passes the filter.
+func (ff FindingFilter) Matches(f Finding) bool {
+	if ff.Workspace != "" && !strings.EqualFold(ff.Workspace, f.Workspace) {
+		return false
+	}
+	if ff.RepoSlug != "" && !strings.EqualFold(ff.RepoSlug, f.RepoSlug) {
+		return false
+	}
+	if ff.Severity != "" && !strings.EqualFold(ff.Severity, f.Severity) {
+		return false
+	}
+	if !ff.From.IsZero() && f.CreatedAt.Before(ff.From) {
+		return false
+	}
+	if !ff.To.IsZero() && !f.CreatedAt.Before(ff.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,13 @@
+package model
+
+// FreezeWindow is a period during which a repository is frozen, e.g. around a release. It is
+// either a fixed range (From/To) or a recurring range that starts on Cron and lasts Duration.
+// While a window is active the bot posts a freeze notice on each PR instead of reviewing it.
+type FreezeWindow struct {
+	Name     string `yaml:"name,omitempty"`
+	From     string `yaml:"from,omitempty"`     // "2006-01-02" (start of day, UTC) or RFC 3339
+	To       string `yaml:"to,omitempty"`       // "2006-01-02" (end of day, UTC) or RFC 3339
+	Cron     string `yaml:"cron,omitempty"`     // Start of a recurring window, same format as the job cron
+	Duration string `yaml:"duration,omitempty"` // Length of a recurring window, e.g. "48h"
+	Message  string `yaml:"message,omitempty"`  // Optional text added to the freeze notice
+}
@@ -3,17 +3,30 @@ package model
 type ReviewComment struct {
 	Body     string `json:"body"`
 	Path     string `json:"path"`
-	Position int    `json:"position"` // "to" line in destination/new file
+	Position int    `json:"position"` // "to" line in destination/new file (0 for removed lines)
 	FromLine int    `json:"fromLine"` // "from" line in source/old file (0 or -1 for added lines)
 	Anchor   string `json:"anchor,omitempty"`
+	// StartLine and EndLine are the first and last destination lines of a finding that spans
+	// several lines (EndLine equals Position); both are 0 for single-line findings. Before
+	// anchoring, StartLine is the diff index the AI gave.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// Fix is the replacement text of the commented destination lines, set when the AI marked
+	// the finding as mechanically fixable (see AutoFixSettings).
+	Fix string `json:"fix,omitempty"`
 }
 
 type ReviewResponse struct {
-	Reviews []struct {
-		LineNumber    int    `json:"lineNumber"`
-		ReviewComment string `json:"reviewComment"`
-		LineText      string `json:"lineText,omitempty"`
-	} `json:"reviews"`
+	Reviews []ReviewItem `json:"reviews"`
+}
+
+// ReviewItem is one finding of a ReviewResponse, as the AI writes it.
+type ReviewItem struct {
+	LineNumber      int    `json:"lineNumber"`
+	StartLineNumber int    `json:"startLineNumber,omitempty"` // first line of a multi-line finding
+	ReviewComment   string `json:"reviewComment"`
+	LineText        string `json:"lineText,omitempty"`
+	Fix             string `json:"fix,omitempty"` // replacement of the commented lines, for mechanical fixes
 }
 
 // GeminiErrorResponse represents the error response structure from Gemini API
@@ -0,0 +1,100 @@
+package model
+
+import "strings"
+
+// Git providers an entry can review.
+const (
+	GitProviderBitbucket = "bitbucket"
+	GitProviderGerrit    = "gerrit"
+	GitProviderGitHub    = "github"
+)
+
+// DefaultGerritLabel is the label the bot votes on.
+const DefaultGerritLabel = "Code-Review"
+
+// GerritSettings configures the Gerrit server of an entry with gitProvider gerrit. The entry's
+// username and appPassword are the HTTP credentials of the bot account and repoSlug is the
+// Gerrit project.
+type GerritSettings struct {
+	URL   string `yaml:"url"`             // e.g. https://gerrit.example.com
+	Label string `yaml:"label,omitempty"` // Label voted on (default Code-Review)
+	// FailSeverity is the least severe finding that votes -1: "Info", "Trivial", "Minor",
+	// "Major" (default) or "Critical".
+	FailSeverity string `yaml:"failSeverity,omitempty"`
+	// Approve votes +1 when no finding reaches failSeverity; otherwise the vote is 0.
+	Approve bool `yaml:"approve,omitempty"`
+}
+
+// IsGerrit reports whether the entry reviews Gerrit changes instead of Bitbucket pull requests.
+func (a AutoReviewPR) IsGerrit() bool {
+	return strings.EqualFold(strings.TrimSpace(a.GitProvider), GitProviderGerrit)
+}
+
+// LabelName returns the label the bot votes on.
+func (s GerritSettings) LabelName() string {
+	if s.Label == "" {
+		return DefaultGerritLabel
+	}
+	return s.Label
+}
+
+// GerritChange is a change as listed by the Gerrit REST API with its current revision.
+type GerritChange struct {
+	ID              string                    `json:"id"`
+	Project         string                    `json:"project"`
+	Branch          string                    `json:"branch"`
+	Number          int                       `json:"_number"`
+	Subject         string                    `json:"subject"`
+	Status          string                    `json:"status"`
+	Created         string                    `json:"created"`
+	WorkInProgress  bool                      `json:"work_in_progress"`
+	Owner           GerritAccount             `json:"owner"`
+	CurrentRevision string                    `json:"current_revision"`
+	Revisions       map[string]GerritRevision `json:"revisions"`
+	MoreChanges     bool                      `json:"_more_changes"` // Set on the last change of a page when more follow
+}
+
+type GerritAccount struct {
+	AccountID int    `json:"_account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+}
+
+type GerritRevision struct {
+	Number int `json:"_number"`
+	Commit struct {
+		Message string `json:"message"`
+	} `json:"commit"`
+}
+
+// GerritMessage is a change message, such as the one a review posts.
+type GerritMessage struct {
+	Author         GerritAccount `json:"author"`
+	RevisionNumber int           `json:"_revision_number"`
+	Tag            string        `json:"tag"`
+}
+
+// GerritReviewInput is the body of Gerrit's set-review call: a message, label votes and inline
+// comments by file path, posted together.
+type GerritReviewInput struct {
+	Message  string                          `json:"message,omitempty"`
+	Labels   map[string]int                  `json:"labels,omitempty"`
+	Comments map[string][]GerritCommentInput `json:"comments,omitempty"`
+	Tag      string                          `json:"tag,omitempty"`
+}
+
+type GerritCommentInput struct {
+	Side       string       `json:"side,omitempty"` // "PARENT" for a line of the old file
+	Line       int          `json:"line,omitempty"`
+	Range      *GerritRange `json:"range,omitempty"`
+	Message    string       `json:"message"`
+	Unresolved bool         `json:"unresolved"`
+}
+
+type GerritRange struct {
+	StartLine      int `json:"start_line"`
+	StartCharacter int `json:"start_character"`
+	EndLine        int `json:"end_line"`
+	EndCharacter   int `json:"end_character"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import "strings"
+
+// DefaultGitHubAPIURL is the REST API of github.com.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubSettings configures the GitHub API of an entry with gitProvider github. The entry's
+// workspace is the repository owner, username the bot's login and appPassword its token.
+type GitHubSettings struct {
+	// APIURL is the REST API base URL, e.g. https://github.example.com/api/v3 for GitHub
+	// Enterprise Server (default https://api.github.com).
+	APIURL string `yaml:"apiUrl,omitempty"`
+}
+
+// IsGitHub reports whether the entry reviews GitHub pull requests instead of Bitbucket ones.
+func (a AutoReviewPR) IsGitHub() bool {
+	return strings.EqualFold(strings.TrimSpace(a.GitProvider), GitProviderGitHub)
+}
+
+// BaseURL returns the REST API base URL without a trailing slash.
+func (s GitHubSettings) BaseURL() string {
+	if strings.TrimSpace(s.APIURL) == "" {
+		return DefaultGitHubAPIURL
+	}
+	return strings.TrimRight(strings.TrimSpace(s.APIURL), "/")
+}
+
+// GitHubPullRequest is a pull request as listed by the GitHub REST API.
+type GitHubPullRequest struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	Draft     bool       `json:"draft"`
+	CreatedAt string     `json:"created_at"`
+	User      GitHubUser `json:"user"`
+	Head      GitHubRef  `json:"head"`
+	Base      GitHubRef  `json:"base"`
+}
+
+type GitHubUser struct {
+	Login string `json:"login"`
+}
+
+type GitHubRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// GitHubReview is a submitted pull request review.
+type GitHubReview struct {
+	ID       int64      `json:"id"`
+	User     GitHubUser `json:"user"`
+	Body     string     `json:"body"`
+	CommitID string     `json:"commit_id"`
+	State    string     `json:"state"`
+}
+
+// GitHubReviewInput is the body of GitHub's create-review call: a summary and the inline
+// comments of one commit, submitted as a single review.
+type GitHubReviewInput struct {
+	CommitID string                `json:"commit_id"`
+	Body     string                `json:"body,omitempty"`
+	Event    string                `json:"event"` // COMMENT, APPROVE or REQUEST_CHANGES
+	Comments []GitHubReviewComment `json:"comments,omitempty"`
+}
+
+type GitHubReviewComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"` // RIGHT for the new file, LEFT for the old one
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Body      string `json:"body"`
+}
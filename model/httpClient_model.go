@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// DefaultHTTPTimeout bounds every outbound request when http.timeout is unset; AI replies on
+// large diffs can take a minute or more.
+const DefaultHTTPTimeout = 2 * time.Minute
+
+// HTTPSettings configures the HTTP client shared by the Bitbucket client and the AI providers.
+type HTTPSettings struct {
+	Timeout string `yaml:"timeout,omitempty"` // Whole request including the body, e.g. "90s" (default 2m)
+	// Proxy is the URL of the proxy for all outbound requests; empty uses HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY from the environment.
+	Proxy string `yaml:"proxy,omitempty"`
+	// CABundle is a PEM file of extra trusted CA certificates, added to the system pool, for
+	// proxies that intercept TLS.
+	CABundle            string `yaml:"caBundle,omitempty"`
+	MaxIdleConns        int    `yaml:"maxIdleConns,omitempty"`        // default 100
+	MaxIdleConnsPerHost int    `yaml:"maxIdleConnsPerHost,omitempty"` // default 10
+	MaxConnsPerHost     int    `yaml:"maxConnsPerHost,omitempty"`     // default 0 (no limit)
+	// Cache revalidates Bitbucket pull request lists and comments with ETag / Last-Modified.
+	Cache HTTPCacheSettings `yaml:"cache,omitempty"`
+}
+
+// TimeoutDuration parses Timeout, defaulting to DefaultHTTPTimeout.
+func (s HTTPSettings) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(s.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHTTPTimeout
+}
+
+// HTTPCacheSettings configures the cache of Bitbucket pull request lists and comments.
+type HTTPCacheSettings struct {
+	Disabled   bool   `yaml:"disabled,omitempty"`
+	TTL        string `yaml:"ttl,omitempty"`        // Served without asking Bitbucket for this long, e.g. "30s" (default)
+	MaxEntries int    `yaml:"maxEntries,omitempty"` // default 500
+}
+
+// TTLDuration parses TTL, defaulting to 30 seconds.
+func (s HTTPCacheSettings) TTLDuration() time.Duration {
+	if d, err := time.ParseDuration(s.TTL); err == nil && d >= 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// Entries returns MaxEntries, defaulting to 500.
+func (s HTTPCacheSettings) Entries() int {
+	if s.MaxEntries > 0 {
+		return s.MaxEntries
+	}
+	return 500
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// IgnoreCommand, as the first line of a top-level comment, leaves a pull request out of review
+// for good. Once ignored its comments are no longer read, so it is lifted through the API.
+const IgnoreCommand = "/nim ignore"
+
+// IgnoredPullRequest records that a pull request is left out of review until it is lifted
+// through DELETE /api/v1/ignored/:workspace/:repoSlug/:prID. A lifted record is kept so the
+// comment that asked for it is not honored again; a newer IgnoreCommand ignores it anew.
+type IgnoredPullRequest struct {
+	Workspace     string    `json:"workspace"`
+	RepoSlug      string    `json:"repoSlug"`
+	PullRequestID int       `json:"pullRequestId"`
+	By            string    `json:"by"`               // display name of who asked for it
+	Reason        string    `json:"reason,omitempty"` // text after the command, if any
+	CommentID     int       `json:"commentId,omitempty"`
+	IgnoredAt     time.Time `json:"ignoredAt"`
+	Lifted        bool      `json:"lifted,omitempty"`
+}
@@ -0,0 +1,47 @@
+package model
+
+// Code Insights report results. A report that is not PASSED fails the "Reports" merge check.
+const (
+	InsightsPassed  = "PASSED"
+	InsightsFailed  = "FAILED"
+	InsightsPending = "PENDING"
+)
+
+// CodeInsightsSettings publishes the open findings of a pull request as a Bitbucket Code
+// Insights report on its latest commit, in addition to the review comments.
+type CodeInsightsSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// FailSeverity is the least severe open finding that fails the report: "Info", "Trivial",
+	// "Minor", "Major" (default) or "Critical".
+	FailSeverity string `yaml:"failSeverity,omitempty"`
+}
+
+// InsightsReport is the body of a Code Insights report.
+type InsightsReport struct {
+	Title      string          `json:"title"`
+	Details    string          `json:"details"`
+	ReportType string          `json:"report_type"` // BUG, SECURITY, COVERAGE or TEST
+	Reporter   string          `json:"reporter"`
+	Link       string          `json:"link,omitempty"`
+	Result     string          `json:"result"`
+	Data       []InsightsDatum `json:"data,omitempty"`
+}
+
+// InsightsDatum is one key figure shown on a Code Insights report.
+type InsightsDatum struct {
+	Title string      `json:"title"`
+	Type  string      `json:"type"` // BOOLEAN, DATE, DURATION, LINK, NUMBER, PERCENTAGE or TEXT
+	Value interface{} `json:"value"`
+}
+
+// InsightsAnnotation is one finding of a Code Insights report. Path and Line are optional.
+type InsightsAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"` // BUG, VULNERABILITY or CODE_SMELL
+	Summary        string `json:"summary"`
+	Details        string `json:"details,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Line           int    `json:"line,omitempty"`
+	Severity       string `json:"severity,omitempty"` // CRITICAL, HIGH, MEDIUM or LOW
+	Link           string `json:"link,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// JobStatus is the runtime state of one configured review job, shown on the dashboard.
+type JobStatus struct {
+	Name      string `json:"name"` // processName, or workspace/repoSlug when unnamed
+	Workspace string `json:"workspace"`
+	RepoSlug  string `json:"repoSlug"`
+	Cron      string `json:"cron"`
+	Paused    bool   `json:"paused"`
+	Running   bool   `json:"running"`
+	Frozen    string `json:"frozen,omitempty"` // Name of the active freeze window
+
+	NextRunAt    time.Time     `json:"nextRunAt,omitempty"`
+	FrozenUntil  time.Time     `json:"frozenUntil,omitempty"`
+	LastRunAt    time.Time     `json:"lastRunAt,omitempty"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastResult   string        `json:"lastResult,omitempty"` // "ok" or "error"
+	LastError    string        `json:"lastError,omitempty"`
+
+	// BitbucketFailures counts the runs in a row that failed on Bitbucket.
+	BitbucketFailures int `json:"bitbucketFailures,omitempty"`
+	// CircuitOpenUntil is when reviews resume after repeated Bitbucket failures.
+	CircuitOpenUntil time.Time `json:"circuitOpenUntil,omitempty"`
+
+	Runs           int64 `json:"runs"`
+	PRsReviewed    int64 `json:"prsReviewed"`
+	FindingsPosted int64 `json:"findingsPosted"`
+	AICalls        int64 `json:"aiCalls"`
+	AIErrors       int64 `json:"aiErrors"`
+	APIErrors      int64 `json:"apiErrors"`
+
+	RecentRuns []JobRun `json:"recentRuns,omitempty"` // Newest first
+}
+
+// AIErrorRate is the share of AI calls that failed.
+func (s JobStatus) AIErrorRate() float64 {
+	if s.AICalls == 0 {
+		return 0
+	}
+	return float64(s.AIErrors) / float64(s.AICalls)
+}
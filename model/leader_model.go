@@ -0,0 +1,35 @@
+package model
+
+// Leader election backends.
+const (
+	LeaderBackendKubernetes = "kubernetes"
+	LeaderBackendRedis      = "redis"
+)
+
+// LeaderElectionSettings lets several replicas share one config: only the elected leader runs
+// scheduled reviews. An empty Backend disables election and every replica runs its crons.
+type LeaderElectionSettings struct {
+	Backend       string `yaml:"backend,omitempty"`       // "kubernetes" (Lease API) or "redis"
+	Identity      string `yaml:"identity,omitempty"`      // Unique per replica (default: hostname, i.e. the pod name)
+	LeaseDuration string `yaml:"leaseDuration,omitempty"` // How long a lease is valid without renewal (default: 15s)
+	RenewInterval string `yaml:"renewInterval,omitempty"` // How often the lease is acquired or renewed (default: 5s)
+
+	Kubernetes KubernetesLeaseSettings `yaml:"kubernetes,omitempty"`
+	Redis      RedisLockSettings       `yaml:"redis,omitempty"`
+}
+
+// KubernetesLeaseSettings locates the coordination.k8s.io Lease. In-cluster defaults come from
+// the pod's service account.
+type KubernetesLeaseSettings struct {
+	Namespace string `yaml:"namespace,omitempty"` // default: the pod's namespace
+	LeaseName string `yaml:"leaseName,omitempty"` // default: code-nim
+	APIServer string `yaml:"apiServer,omitempty"` // default: https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT
+}
+
+type RedisLockSettings struct {
+	Addr     string `yaml:"addr,omitempty"` // host:port
+	Password string `yaml:"password,omitempty"`
+	DB       int    `yaml:"db,omitempty"`
+	TLS      bool   `yaml:"tls,omitempty"` // Connect over TLS, verified against the system roots
+	Key      string `yaml:"key,omitempty"` // default: code-nim:leader
+}
@@ -0,0 +1,20 @@
+package model
+
+// ModelRoute sends the inline review of the files it matches to another model of the entry's
+// provider, or leaves them out of AI review. A file matches when it meets every condition set.
+type ModelRoute struct {
+	// Name is shown in logs and in the skipped-files note.
+	Name string `yaml:"name,omitempty"`
+	// Paths are gitignore-style patterns, like criticalPaths, such as *.md or docs/**.
+	Paths []string `yaml:"paths,omitempty"`
+	// MinLines and MaxLines bound the diff lines of the file; 0 leaves a bound out.
+	MinLines int `yaml:"minLines,omitempty"`
+	MaxLines int `yaml:"maxLines,omitempty"`
+	// Critical only matches files under the entry's criticalPaths.
+	Critical bool `yaml:"critical,omitempty"`
+	// AIModel reviews the matched files with another model (or Azure deployment).
+	AIModel string `yaml:"aiModel,omitempty"`
+	// SkipAI leaves the matched files out of inline review. When it leaves out every changed
+	// file of a pull request, the pull request gets no AI summary either.
+	SkipAI bool `yaml:"skipAI,omitempty"`
+}
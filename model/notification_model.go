@@ -0,0 +1,39 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification identifies one message sent on a channel about a pull request.
+type Notification struct {
+	Channel       string `json:"channel"` // e.g. "bitbucket"
+	Workspace     string `json:"workspace"`
+	RepoSlug      string `json:"repoSlug"`
+	PullRequestID int    `json:"pullRequestId"`
+	Commit        string `json:"commit,omitempty"` // Empty when the message is not tied to a commit, e.g. a finding
+	Subject       string `json:"subject"`          // What was sent, e.g. "summary" or "finding:<fingerprint>"
+}
+
+// Key identifies the notification independently of when it was sent.
+func (n Notification) Key() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		n.Channel, n.Workspace, n.RepoSlug, strconv.Itoa(n.PullRequestID), n.Commit, n.Subject,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NotificationSettings controls how long delivered notifications are remembered.
+type NotificationSettings struct {
+	RetentionDays int `yaml:"retentionDays,omitempty"` // Days to remember deliveries (default: 30)
+}
+
+// SentNotification records a delivered notification.
+type SentNotification struct {
+	Key string `json:"key"`
+	Notification
+	SentAt time.Time `json:"sentAt"`
+}
@@ -0,0 +1,85 @@
+package model
+
+// Notifier event kinds, as listed in NotifierSettings.Events.
+const (
+	NotifyReviewCompleted = "review_completed" // a pull request was reviewed with new changes
+	NotifyCriticalFinding = "critical_finding" // a posted finding is at least MinSeverity
+	NotifyAIFailure       = "ai_failure"       // the AI provider failed while reviewing a pull request
+	NotifyCircuitOpen     = "circuit_open"     // Bitbucket kept failing and the job's reviews are paused
+	NotifyCriticalPath    = "critical_path"    // a reviewed commit changes files under criticalPaths
+)
+
+// NotifierSettings configures one notification target of an entry.
+type NotifierSettings struct {
+	// Type is the notifier: "slack", "teams", "email" or "webhook".
+	Type string `yaml:"type"`
+	// URL is the incoming webhook of slack and teams, or the endpoint of webhook, which receives
+	// the NotificationEvent as JSON. It may be a vault: or aws-sm: reference.
+	URL string `yaml:"url,omitempty"`
+	// Headers are added to the requests of webhook, e.g. an Authorization header.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// SMTP is the mail server and recipients of email.
+	SMTP SMTPSettings `yaml:"smtp,omitempty"`
+	// Events lists the event kinds sent to this notifier; empty sends all of them.
+	Events []string `yaml:"events,omitempty"`
+	// MinSeverity is the lowest severity of a critical_finding event (default "Critical").
+	MinSeverity string `yaml:"minSeverity,omitempty"`
+}
+
+// SMTPSettings is the mail server and recipients of an email notifier.
+type SMTPSettings struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port,omitempty"` // default 587
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"` // may be a vault: or aws-sm: reference
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// Wants reports whether the notifier receives events of kind.
+func (s NotifierSettings) Wants(kind string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationEvent is what a notifier is told about a pull request.
+type NotificationEvent struct {
+	Kind          string `json:"kind"`
+	ProcessName   string `json:"processName"`
+	Workspace     string `json:"workspace"`
+	RepoSlug      string `json:"repoSlug"`
+	PullRequestID int    `json:"pullRequestId"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	URL           string `json:"url"` // link to the pull request
+	Commit        string `json:"commit,omitempty"`
+	// Severities counts the open bot findings (review_completed) or the findings of a
+	// critical_finding event by severity.
+	Severities map[string]int `json:"severities,omitempty"`
+	// Findings are the new findings at or above the notifier's MinSeverity (critical_finding).
+	Findings []NotifiedFinding `json:"findings,omitempty"`
+	// Message describes an ai_failure or circuit_open event.
+	Message string `json:"message,omitempty"`
+	// Files are the changed files under the entry's criticalPaths (critical_path) or in the
+	// package of a package event.
+	Files []string `json:"files,omitempty"`
+	// Package is the package profile whose notifiers get the event, limited to the findings
+	// of its files; empty for the entry's notifiers.
+	Package string `json:"package,omitempty"`
+}
+
+// NotifiedFinding is a posted finding as reported in a critical_finding event.
+type NotifiedFinding struct {
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	URL      string `json:"url"` // link to the line in the pull request
+}
@@ -0,0 +1,19 @@
+package model
+
+// PackageProfile overrides the review settings of an autoReviewPR entry for the files of the
+// monorepo packages that AutoReviewPR.Packages routes to it.
+type PackageProfile struct {
+	// SystemInstructions and ReviewStyle replace the entry's in the inline review prompt of the
+	// package's files.
+	SystemInstructions string `yaml:"systemInstructions,omitempty"`
+	ReviewStyle        string `yaml:"reviewStyle,omitempty"`
+	// AIModel reviews the package's files with another model (or Azure deployment) of the
+	// entry's provider.
+	AIModel string `yaml:"aiModel,omitempty"`
+	// MinSeverity is the least severe finding posted on the package's files; less severe ones
+	// are stored as deferred findings. Critical paths still post from criticalPathReview.minSeverity.
+	MinSeverity string `yaml:"minSeverity,omitempty"`
+	// Notifiers also receive the review_completed and critical_finding events of pull requests
+	// that change the package, limited to the package's files.
+	Notifiers []NotifierSettings `yaml:"notifiers,omitempty"`
+}
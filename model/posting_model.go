@@ -0,0 +1,9 @@
+package model
+
+// PostingResult is the outcome of posting the inline comments of one review.
+type PostingResult struct {
+	Posted  int `json:"posted"`
+	Failed  int `json:"failed"`  // still failing after their retries
+	Skipped int `json:"skipped"` // empty, duplicate, deferred or over the comment cap
+	Retries int `json:"retries"` // posts sent again after a 5xx response
+}
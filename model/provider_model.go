@@ -0,0 +1,16 @@
+package model
+
+// ProviderCapabilities lists the comment features a git provider supports, so the review is
+// posted with what the provider renders instead of assuming Bitbucket Cloud.
+type ProviderCapabilities struct {
+	// Markdown renders Markdown in comments; without it fenced code blocks are posted indented.
+	Markdown bool
+	// Suggestions applies ```suggestion blocks with one click; without it they are posted as
+	// plain code blocks.
+	Suggestions bool
+	// LineRanges anchors a comment on several lines; without it a multi-line finding is posted
+	// on its last line.
+	LineRanges bool
+	// Tasks opens tasks on a pull request; without it a task is posted as a comment.
+	Tasks bool
+}
@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// Review job priorities. Webhook-triggered work outranks scheduled scans.
+const (
+	PriorityLow  = "low"
+	PriorityHigh = "high"
+)
+
+// ReviewJob is a unit of review work waiting in the queue.
+type ReviewJob struct {
+	ID            string    `json:"id"`
+	ProcessName   string    `json:"processName"`   // Config entry the job belongs to
+	PullRequestID int       `json:"pullRequestId"` // 0 means scan every open PR of the repo
+	Priority      string    `json:"priority"`
+	Source        string    `json:"source"` // "cron" or "webhook"
+	EnqueuedAt    time.Time `json:"enqueuedAt"`
+}
+
+// Queue backends and replica roles.
+const (
+	QueueBackendMemory = "memory"
+	QueueBackendRedis  = "redis"
+
+	QueueRoleAll       = "all"       // Schedule, accept webhooks and review
+	QueueRoleScheduler = "scheduler" // Schedule and accept webhooks; never review
+	QueueRoleWorker    = "worker"    // Review queued jobs only
+)
+
+// QueueSettings controls backpressure between intake and the review worker.
+type QueueSettings struct {
+	Threshold int                `yaml:"threshold,omitempty"` // Depth at which cron jobs are shed (default: 20)
+	Capacity  int                `yaml:"capacity,omitempty"`  // Hard limit on queued jobs (default: 50)
+	Backend   string             `yaml:"backend,omitempty"`   // "memory" (default) or "redis" to share the queue between replicas
+	Role      string             `yaml:"role,omitempty"`      // "all" (default), "scheduler" or "worker"; split roles need the redis backend
+	ClaimTTL  string             `yaml:"claimTtl,omitempty"`  // How long a reviewed commit stays claimed by its worker (default: 1h)
+	Redis     RedisQueueSettings `yaml:"redis,omitempty"`
+}
+
+// RedisQueueSettings locates the shared queue.
+type RedisQueueSettings struct {
+	Addr     string `yaml:"addr"` // host:port
+	Password string `yaml:"password,omitempty"`
+	DB       int    `yaml:"db,omitempty"`
+	TLS      bool   `yaml:"tls,omitempty"`    // Connect over TLS, verified against the system roots
+	Prefix   string `yaml:"prefix,omitempty"` // Key prefix (default: code-nim:queue)
+}
+
+// RoleName returns the configured role, defaulting to "all".
+func (s QueueSettings) RoleName() string {
+	if s.Role == "" {
+		return QueueRoleAll
+	}
+	return s.Role
+}
+
+// Schedules reports whether this replica runs cron schedules.
+func (s QueueSettings) Schedules() bool {
+	return s.RoleName() != QueueRoleWorker
+}
+
+// Works reports whether this replica reviews queued jobs.
+func (s QueueSettings) Works() bool {
+	return s.RoleName() != QueueRoleScheduler
+}
+
+// ClaimDuration parses ClaimTTL, defaulting to one hour.
+func (s QueueSettings) ClaimDuration() time.Duration {
+	if d, err := time.ParseDuration(s.ClaimTTL); err == nil && d > 0 {
+		return d
+	}
+	return time.Hour
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML accepts repoSlug as a single slug or as a list of slugs and globs; the list
+// form is stored in RepoSlugs.
+func (a *AutoReviewPR) UnmarshalYAML(node *yaml.Node) error {
+	type plain AutoReviewPR
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != "repoSlug" || node.Content[i+1].Kind != yaml.SequenceNode {
+				continue
+			}
+			var slugs []string
+			if err := node.Content[i+1].Decode(&slugs); err != nil {
+				return err
+			}
+			rest := *node
+			rest.Content = append(append([]*yaml.Node{}, node.Content[:i]...), node.Content[i+2:]...)
+			if err := rest.Decode((*plain)(a)); err != nil {
+				return err
+			}
+			a.RepoSlugs = append(slugs, a.RepoSlugs...)
+			return nil
+		}
+	}
+	return node.Decode((*plain)(a))
+}
+
+// RepoPatterns returns the repository slugs and globs the entry reviews.
+func (a AutoReviewPR) RepoPatterns() []string {
+	var patterns []string
+	if strings.TrimSpace(a.RepoSlug) != "" {
+		patterns = append(patterns, strings.TrimSpace(a.RepoSlug))
+	}
+	for _, p := range a.RepoSlugs {
+		if strings.TrimSpace(p) != "" {
+			patterns = append(patterns, strings.TrimSpace(p))
+		}
+	}
+	return patterns
+}
+
+// MultiRepo reports whether the entry names more than one repository or a glob, so it has to
+// be expanded into one entry per repository before reviewing.
+func (a AutoReviewPR) MultiRepo() bool {
+	patterns := a.RepoPatterns()
+	return len(patterns) > 1 || (len(patterns) == 1 && IsRepoGlob(patterns[0]))
+}
+
+// IsRepoGlob reports whether a repoSlug pattern contains glob characters.
+func IsRepoGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
@@ -0,0 +1,8 @@
+package model
+
+// Response is the envelope returned by every HTTP API endpoint.
+type Response struct {
+	StatusCode int         `json:"code"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// RetentionSettings keeps each kind of review history for its own number of days, apart from
+// transcripts. 0, the default, keeps the records forever.
+type RetentionSettings struct {
+	FindingsDays   int `yaml:"findingsDays,omitempty"`   // Posted findings and their details
+	FeedbackDays   int `yaml:"feedbackDays,omitempty"`   // Classified replies to bot comments
+	RiskScoresDays int `yaml:"riskScoresDays,omitempty"` // Merge risk scores of reviewed pull requests
+}
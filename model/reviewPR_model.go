@@ -1,28 +1,230 @@
 package model
 
+import "strings"
+
 type Task struct {
-	AutoReviewPRs []AutoReviewPR `yaml:"autoReviewPR"`
+	AutoReviewPRs []AutoReviewPR     `yaml:"autoReviewPR"`
+	DataDir       string             `yaml:"dataDir,omitempty"`      // Where persisted data lives (default: data)
+	DashboardURL  string             `yaml:"dashboardUrl,omitempty"` // Public base URL of this service, e.g. https://code-nim.example.com
+	Transcripts   TranscriptSettings `yaml:"transcripts,omitempty"`
+	Queue         QueueSettings      `yaml:"queue,omitempty"`
+	Usage         UsageSettings      `yaml:"usage,omitempty"`
+	Auth          AuthSettings       `yaml:"auth,omitempty"`
+	Webhook       WebhookSettings    `yaml:"webhook,omitempty"`
+	// LeaderElection lets replicas share the config; only the leader runs scheduled reviews.
+	LeaderElection LeaderElectionSettings `yaml:"leaderElection,omitempty"`
+	// Benchmark periodically compares the configured AI models on a suite of stored diffs.
+	Benchmark BenchmarkSettings `yaml:"benchmark,omitempty"`
+	// HTTP configures timeouts, proxy, trusted CAs and pooling of outbound requests.
+	HTTP HTTPSettings `yaml:"http,omitempty"`
+	// AICache reuses stored AI replies when a pull request's diff has not changed.
+	AICache AICacheSettings `yaml:"aiCache,omitempty"`
+	// Credentials are named credential profiles that entries refer to with credentials.
+	Credentials map[string]CredentialProfile `yaml:"credentials,omitempty"`
+	// Secrets configures where vault: and aws-sm: references in credential fields are read from.
+	Secrets SecretSettings `yaml:"secrets,omitempty"`
+	// Digests email teams a daily summary of the review activity of their entries.
+	Digests []DigestSettings `yaml:"digests,omitempty"`
+	// CircuitBreaker pauses jobs whose Bitbucket requests keep failing.
+	CircuitBreaker CircuitBreakerSettings `yaml:"circuitBreaker,omitempty"`
+	// SelfTest checks the Bitbucket and AI credentials of every entry at startup.
+	SelfTest SelfTestSettings `yaml:"selfTest,omitempty"`
+	// FakeBitbucket serves Bitbucket from fixture files instead of the API when set.
+	FakeBitbucket FakeBitbucketSettings `yaml:"fakeBitbucket,omitempty"`
+	// AIReplay records AI replies by prompt and replays them on later runs.
+	AIReplay AIReplaySettings `yaml:"aiReplay,omitempty"`
+	// AIRateLimit queues the AI requests of all jobs under shared per-minute limits.
+	AIRateLimit AIRateLimitSettings `yaml:"aiRateLimit,omitempty"`
+	// Notifications sets how long delivered comments are remembered to suppress duplicates.
+	Notifications NotificationSettings `yaml:"notifications,omitempty"`
+	// Retention purges findings, feedback and risk scores on their own schedules; they are kept by default.
+	Retention RetentionSettings `yaml:"retention,omitempty"`
 }
 
 type AutoReviewPR struct {
 	ProcessName  string   `yaml:"processName"`
 	Cron         string   `yaml:"cron"`
-	GitProvider  string   `yaml:"gitProvider"`
+	GitProvider  string   `yaml:"gitProvider"` // "bitbucket" (default), "gerrit" or "github"
 	Workspace    string   `yaml:"workspace"`
 	RepoSlug     string   `yaml:"repoSlug"`
+	RepoSlugs    []string `yaml:"repoSlugs,omitempty"` // More repositories or globs such as "team-*"; repoSlug may also be a list
 	DisplayNames []string `yaml:"displayNames"`
 	Username     string   `yaml:"username"`
 	AppPassword  string   `yaml:"appPassword"`
-	GeminiKey    string   `yaml:"geminiKey"`
-	GeminiModel  string   `yaml:"geminiModel,omitempty"`
+	// Credentials names the profiles of the top-level credentials map this entry takes its
+	// Bitbucket and AI credentials from, in order; values set on the entry itself win.
+	Credentials NameList `yaml:"credentials,omitempty"`
+	// SecretRefs holds the vault: and aws-sm: references the credential fields were resolved
+	// from, by field name (e.g. "appPassword", "aiKeys[1]"), so they can be resolved again.
+	SecretRefs map[string]string `yaml:"-" json:"-"`
+	// WebhookSecret verifies the HMAC-SHA256 signature of webhook deliveries for this repository.
+	WebhookSecret string `yaml:"webhookSecret,omitempty"`
+	GeminiKey     string `yaml:"geminiKey"`
+	GeminiModel   string `yaml:"geminiModel,omitempty"`
 	// Generic AI configuration (optional). If aiProvider=="self", these are used.
-	AIProvider          string `yaml:"aiProvider,omitempty"`     // "gemini" (default) or "self"
-	AIModel             string `yaml:"aiModel,omitempty"`        // Preferred model name; falls back to GeminiModel
-	AIKey               string `yaml:"aiKey,omitempty"`          // Generic API key; falls back to GeminiKey
-	SelfAPIBaseURL      string `yaml:"selfApiBaseUrl,omitempty"` // e.g., http://192.168.101.27:1994
-	MaxInlineComments   int    `yaml:"maxInlineComments,omitempty"`
-	MaxTotalComments    int    `yaml:"maxTotalComments,omitempty"`
+	AIProvider     string   `yaml:"aiProvider,omitempty"`     // "gemini" (default), "gemini-vertex", "azure-openai" or "self"
+	AIModel        string   `yaml:"aiModel,omitempty"`        // Preferred model name; falls back to GeminiModel
+	AIKey          string   `yaml:"aiKey,omitempty"`          // Generic API key; falls back to GeminiKey
+	AIKeys         []string `yaml:"aiKeys,omitempty"`         // Several keys for the same provider, rotated per aiKeyRotation
+	AIKeyRotation  string   `yaml:"aiKeyRotation,omitempty"`  // "round-robin" (default) or "on-429"
+	SelfAPIBaseURL string   `yaml:"selfApiBaseUrl,omitempty"` // e.g., http://192.168.101.27:1994
+	// Azure OpenAI configuration, used when aiProvider=="azure-openai" (key comes from aiKey).
+	AzureEndpoint   string `yaml:"azureEndpoint,omitempty"`   // e.g., https://my-resource.openai.azure.com
+	AzureDeployment string `yaml:"azureDeployment,omitempty"` // Deployment name; falls back to aiModel
+	AzureAPIVersion string `yaml:"azureApiVersion,omitempty"` // default: 2024-06-01
+	// Vertex AI configuration, used when aiProvider=="gemini-vertex".
+	VertexProject         string `yaml:"vertexProject,omitempty"`
+	VertexRegion          string `yaml:"vertexRegion,omitempty"`          // default: us-central1
+	VertexCredentialsFile string `yaml:"vertexCredentialsFile,omitempty"` // service-account JSON; empty uses ADC
+	MaxInlineComments     int    `yaml:"maxInlineComments,omitempty"`
+	MaxTotalComments      int    `yaml:"maxTotalComments,omitempty"`
+	Tone                  string `yaml:"tone,omitempty"`        // "concise", "mentoring", "strict"; empty keeps the default style
+	CommentMode           string `yaml:"commentMode,omitempty"` // "minimal" posts one consolidated comment; empty posts inline comments
+	// PostConcurrency is how many inline comments are posted in parallel (default 4); PostRetries
+	// how often a comment is sent again after a 5xx response (default 2, -1 disables retries).
+	PostConcurrency int `yaml:"postConcurrency,omitempty"`
+	PostRetries     int `yaml:"postRetries,omitempty"`
+	// ContextCache keeps the shared instructions of inline review prompts in a Gemini context
+	// cache for ContextCacheTTL (default 1h), so each file only sends its own part.
+	ContextCache    bool   `yaml:"contextCache,omitempty"`
+	ContextCacheTTL string `yaml:"contextCacheTtl,omitempty"`
+	// AIStreaming streams the inline review replies of gemini and gemini-vertex and stops a
+	// reply as soon as it leaves the expected JSON format.
+	AIStreaming bool `yaml:"aiStreaming,omitempty"`
+	// Language is the ISO 639-1 code ("vi", "ja", ...) review comments are written in; empty is English.
+	Language string `yaml:"language,omitempty"`
+	// ReviewStyle is the preferred name for Tone ("strict", "mentoring", "terse"/"concise") and wins when both are set.
+	ReviewStyle string `yaml:"reviewStyle,omitempty"`
+	// Mode selects what a review posts: "summary" (no file is reviewed inline), "inline" (no
+	// AI summary) or "full" (default, both).
+	Mode string `yaml:"mode,omitempty"`
+	// SystemInstructions is free text added to every review and summary prompt, e.g. "No nitpicks; always suggest tests".
+	SystemInstructions string `yaml:"systemInstructions,omitempty"`
+	// AutoApprove approves the PR when no remaining bot finding is more severe than
+	// AutoApproveMaxSeverity ("none" (default), "Info", "Trivial", "Minor", "Major" or "Critical"),
+	// and withdraws the approval otherwise.
+	AutoApprove            bool   `yaml:"autoApprove,omitempty"`
+	AutoApproveMaxSeverity string `yaml:"autoApproveMaxSeverity,omitempty"`
+	// BusyPRHumanComments marks a PR as busy once it has at least this many human comments
+	// (0 disables). On busy PRs only findings at or above BusyPRMinSeverity (default "Major")
+	// are posted; the rest are kept in the reports store as deferred findings.
+	BusyPRHumanComments int    `yaml:"busyPrHumanComments,omitempty"`
+	BusyPRMinSeverity   string `yaml:"busyPrMinSeverity,omitempty"`
+	// SkipVulnerabilityLookup stops dependencies changed in go.mod, package.json and
+	// requirements.txt from being checked against OSV.dev.
+	SkipVulnerabilityLookup bool `yaml:"skipVulnerabilityLookup,omitempty"`
+	// WelcomeFirstTimeContributors gives authors without a merged PR in the repository a
+	// friendlier, onboarding-oriented first summary that links to ContributingGuideURL.
+	WelcomeFirstTimeContributors bool   `yaml:"welcomeFirstTimeContributors,omitempty"`
+	ContributingGuideURL         string `yaml:"contributingGuideUrl,omitempty"`
+	// StaticAnalysis runs linters on the PR branch and feeds their findings to the AI review.
+	StaticAnalysis StaticAnalysisSettings `yaml:"staticAnalysis,omitempty"`
+	// CodeInsights also publishes the open findings as a Code Insights report on the PR's commit.
+	CodeInsights CodeInsightsSettings `yaml:"codeInsights,omitempty"`
+	// BuildStatus also posts a "code-nim review" build status on the PR's latest commit.
+	BuildStatus BuildStatusSettings `yaml:"buildStatus,omitempty"`
+	// DescribePR writes a description for pull requests opened with an empty or one-line description.
+	DescribePR DescriptionSettings `yaml:"describePR,omitempty"`
+	// TestSuggestions recommends test cases for changed source files without a changed test file.
+	TestSuggestions TestSuggestionSettings `yaml:"testSuggestions,omitempty"`
+	// AutoFix commits the mechanical fixes of posted findings to a branch and opens a follow-up PR.
+	AutoFix AutoFixSettings `yaml:"autoFix,omitempty"`
+	// StatusReport keeps a "Review status" comment that explains what the last review run did.
+	StatusReport StatusReportSettings `yaml:"statusReport,omitempty"`
+	// RiskScore adds a merge recommendation, weighted by open findings, PR size and touched
+	// CriticalPaths, to the summary and keeps it for GET /api/v1/reports/risk.
+	RiskScore RiskScoreSettings `yaml:"riskScore,omitempty"`
+	// CriticalPaths are gitignore-style patterns, such as auth/** or payment/**, of the files
+	// that get a stricter review prompt, post more findings on busy PRs, send a critical_path
+	// notification and raise the risk score when changed.
+	CriticalPaths      []string             `yaml:"criticalPaths,omitempty"`
+	CriticalPathReview CriticalPathSettings `yaml:"criticalPathReview,omitempty"`
+	// Notifiers send review events (review completed, critical finding, AI failure) to Slack,
+	// Microsoft Teams, email or a generic webhook.
+	Notifiers []NotifierSettings `yaml:"notifiers,omitempty"`
+	// Packages routes the files of a monorepo to named PackageProfiles by path prefix, such as
+	// services/payments: strict-go or web/: frontend; the longest matching prefix wins.
+	Packages        map[string]string         `yaml:"packages,omitempty"`
+	PackageProfiles map[string]PackageProfile `yaml:"packageProfiles,omitempty"`
+	// ModelRoutes pick the model of each file's inline review by path, diff size and critical
+	// paths, such as a flash model for small diffs and none for docs; the first match wins.
+	ModelRoutes []ModelRoute `yaml:"modelRoutes,omitempty"`
+	// TitlePolicy posts a reminder when the PR title does not follow the team's convention.
+	TitlePolicy TitlePolicySettings `yaml:"titlePolicy,omitempty"`
+	// StaleReminders nudges the author and reviewers of pull requests that stay open too long.
+	StaleReminders StaleReminderSettings `yaml:"staleReminders,omitempty"`
+	// ReviewerSuggestions proposes (and optionally adds) reviewers from CODEOWNERS and file history.
+	ReviewerSuggestions ReviewerSuggestionSettings `yaml:"reviewerSuggestions,omitempty"`
+	// SkipMarkers are phrases such as "[skip nim]" that skip the whole review when found in the
+	// PR title or description, ignoring case. Unset uses helper.DefaultSkipMarkers; [] disables them.
+	SkipMarkers []string `yaml:"skipMarkers,omitempty"`
+	// LGTMMarkers are comment markers such as "LGTM" that pause all reviews of the PR when a
+	// line of a comment by a displayNames reviewer (anyone when unset) opens with one, ignoring
+	// case. Unset uses helper.DefaultLGTMMarkers; [] disables the pause.
+	LGTMMarkers []string `yaml:"lgtmMarkers,omitempty"`
+	// SkipInlineWhenApproved reviews a PR summary-only once a human reviewer, anyone but the
+	// bot account, approved it on Bitbucket.
+	SkipInlineWhenApproved bool `yaml:"skipInlineWhenApproved,omitempty"`
+	// ReviewUnchanged looks at every open PR on every run. By default a PR whose updated_on has
+	// not moved since its last complete review is skipped without fetching its diff or comments.
+	ReviewUnchanged bool `yaml:"reviewUnchanged,omitempty"`
+	// ReviewGeneratedFiles also reviews generated and minified files and lockfiles inline. By
+	// default they are left out and listed in the summary.
+	ReviewGeneratedFiles bool `yaml:"reviewGeneratedFiles,omitempty"`
+	// MaxFileDiffLines leaves files with more diff lines than this out of inline review
+	// (default 3000, -1 for no limit).
+	MaxFileDiffLines int `yaml:"maxFileDiffLines,omitempty"`
+	// CommentFooter appends the model, the run ID and a feedback line to every bot comment.
+	CommentFooter CommentFooterSettings `yaml:"commentFooter,omitempty"`
+	// Feedback collects helpful/unhelpful replies to bot comments into the review history.
+	Feedback FeedbackSettings `yaml:"feedback,omitempty"`
+	// AdaptivePrompt tells the AI which kinds of findings the team has dismissed before.
+	AdaptivePrompt AdaptivePromptSettings `yaml:"adaptivePrompt,omitempty"`
+	// Gerrit is the server an entry with gitProvider gerrit reviews changes on.
+	Gerrit GerritSettings `yaml:"gerrit,omitempty"`
+	// GitHub is the API an entry with gitProvider github reviews pull requests on.
+	GitHub GitHubSettings `yaml:"github,omitempty"`
+	// FreezeWindows pause reviews around releases; see FreezeWindow.
+	FreezeWindows []FreezeWindow `yaml:"freezeWindows,omitempty"`
+	// ActiveHours, when set, are the only times the bot posts on pull requests; QuietHours are
+	// times it never does. Reviews due outside them wait until posting is allowed again.
+	ActiveHours []TimeWindow `yaml:"activeHours,omitempty"`
+	QuietHours  []TimeWindow `yaml:"quietHours,omitempty"`
+	// IgnorePullRequests lists pull request IDs that are never reviewed, like those ignored
+	// with the IgnoreCommand comment.
+	IgnorePullRequests  []int `yaml:"ignorePullRequests,omitempty"`
 	IgnorePullRequestOf struct {
 		DisplayNames []string `yaml:"displayNames"`
 	} `yaml:"ignorePullRequestOf"`
 }
+
+// Review modes of an entry; see AutoReviewPR.Mode.
+const (
+	ReviewModeFull    = "full"
+	ReviewModeSummary = "summary"
+	ReviewModeInline  = "inline"
+)
+
+// PostsSummary reports whether reviews of the entry generate and post the AI summary.
+func (a AutoReviewPR) PostsSummary() bool {
+	return !strings.EqualFold(strings.TrimSpace(a.Mode), ReviewModeInline)
+}
+
+// ReviewsInline reports whether reviews of the entry review the files of the diff inline.
+func (a AutoReviewPR) ReviewsInline() bool {
+	return !strings.EqualFold(strings.TrimSpace(a.Mode), ReviewModeSummary)
+}
+
+// DefaultMaxFileDiffLines is the diff size from which a file is too large to review inline.
+const DefaultMaxFileDiffLines = 3000
+
+// MaxFileLines returns the diff line limit of a reviewed file, or 0 when there is none.
+func (a AutoReviewPR) MaxFileLines() int {
+	switch {
+	case a.MaxFileDiffLines < 0:
+		return 0
+	case a.MaxFileDiffLines == 0:
+		return DefaultMaxFileDiffLines
+	}
+	return a.MaxFileDiffLines
+}
@@ -0,0 +1,28 @@
+package model
+
+// ReviewerSuggestionSettings suggests reviewers for a pull request from CODEOWNERS and the
+// history of the changed files.
+type ReviewerSuggestionSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// AutoAdd also adds the suggested reviewers to the pull request on its first review.
+	AutoAdd bool `yaml:"autoAdd,omitempty"`
+	// Max is how many reviewers are suggested (default 3).
+	Max int `yaml:"max,omitempty"`
+	// CodeownersPath overrides where CODEOWNERS is read from; by default CODEOWNERS,
+	// .bitbucket/CODEOWNERS, .github/CODEOWNERS and docs/CODEOWNERS are tried.
+	CodeownersPath string `yaml:"codeownersPath,omitempty"`
+}
+
+// BitbucketUser identifies a Bitbucket account. UUID or AccountID is needed to add it as a reviewer.
+type BitbucketUser struct {
+	DisplayName string `json:"display_name,omitempty"`
+	UUID        string `json:"uuid,omitempty"`
+	AccountID   string `json:"account_id,omitempty"`
+}
+
+// SuggestedReviewer is a reviewer proposed for a pull request and why.
+type SuggestedReviewer struct {
+	User   BitbucketUser
+	Reason string
+	Score  int // files owned or commits on the changed files; higher ranks first
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Merge recommendations of a RiskScore, from the score and RiskScoreSettings thresholds.
+const (
+	MergeRecommendationMerge   = "merge"   // ✅ below CautionAt
+	MergeRecommendationCaution = "caution" // ⚠️ from CautionAt
+	MergeRecommendationBlock   = "block"   // ❌ from BlockAt
+)
+
+// Defaults of RiskScoreSettings.
+const (
+	DefaultRiskLinesPerPoint     = 100
+	DefaultRiskCriticalPathScore = 10
+	DefaultRiskCautionAt         = 10
+	DefaultRiskBlockAt           = 30
+)
+
+// RiskScoreSettings rates how risky a pull request is to merge, from 0 to 100, after each
+// review and adds a merge recommendation to its summary.
+type RiskScoreSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// SeverityWeights are the points of an open finding by severity, such as critical: 40;
+	// severities left out keep helper.DefaultSeverityWeights.
+	SeverityWeights map[string]int `yaml:"severityWeights,omitempty"`
+	// CategoryWeights multiply the points of findings by type, such as security: 2 (default 1).
+	CategoryWeights map[string]float64 `yaml:"categoryWeights,omitempty"`
+	// LinesPerPoint adds a point per this many changed lines, up to 20 (default 100, -1 leaves
+	// the size out).
+	LinesPerPoint int `yaml:"linesPerPoint,omitempty"`
+	// CriticalPathPoints are added per changed file under the entry's criticalPaths, up to
+	// three files (default 10).
+	CriticalPathPoints int `yaml:"criticalPathPoints,omitempty"`
+	// CautionAt and BlockAt are the scores from which the recommendation turns from ✅ to ⚠️
+	// and ❌ (default 10 and 30).
+	CautionAt int `yaml:"cautionAt,omitempty"`
+	BlockAt   int `yaml:"blockAt,omitempty"`
+}
+
+// Thresholds returns CautionAt and BlockAt with their defaults applied.
+func (s RiskScoreSettings) Thresholds() (int, int) {
+	caution, block := s.CautionAt, s.BlockAt
+	if caution <= 0 {
+		caution = DefaultRiskCautionAt
+	}
+	if block <= 0 {
+		block = DefaultRiskBlockAt
+	}
+	return caution, block
+}
+
+// RiskScore is the merge risk of a pull request at a reviewed commit.
+type RiskScore struct {
+	ProcessName      string         `json:"processName"`
+	Workspace        string         `json:"workspace"`
+	RepoSlug         string         `json:"repoSlug"`
+	PullRequestID    int            `json:"pullRequestId"`
+	PullRequestTitle string         `json:"pullRequestTitle"`
+	Commit           string         `json:"commit"`
+	Score            int            `json:"score"` // 0-100
+	Recommendation   string         `json:"recommendation"`
+	Findings         map[string]int `json:"findings,omitempty"` // open findings by severity
+	FindingPoints    int            `json:"findingPoints"`
+	ChangedLines     int            `json:"changedLines"`
+	SizePoints       int            `json:"sizePoints"`
+	CriticalFiles    []string       `json:"criticalFiles,omitempty"`
+	CriticalPoints   int            `json:"criticalPoints"`
+	CreatedAt        time.Time      `json:"createdAt"`
+}
+
+// RiskScoreFilter selects stored risk scores; zero values match everything.
+type RiskScoreFilter struct {
+	Workspace string
+	RepoSlug  string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+}
+
+// Matches reports whether r passes the filter.
+func (rf RiskScoreFilter) Matches(r RiskScore) bool {
+	if rf.Workspace != "" && !strings.EqualFold(rf.Workspace, r.Workspace) {
+		return false
+	}
+	if rf.RepoSlug != "" && !strings.EqualFold(rf.RepoSlug, r.RepoSlug) {
+		return false
+	}
+	if !rf.From.IsZero() && r.CreatedAt.Before(rf.From) {
+		return false
+	}
+	if !rf.To.IsZero() && !r.CreatedAt.Before(rf.To) {
+		return false
+	}
+	return true
+}
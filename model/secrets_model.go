@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Secret reference prefixes. A credential field whose value starts with one of them is
+// resolved from the secret backend instead of being used as is.
+const (
+	SecretSchemeVault = "vault:"  // vault:<path>#<key>, e.g. vault:secret/data/code-nim#app_password
+	SecretSchemeAWS   = "aws-sm:" // aws-sm:<secret id or ARN>[#<key>], e.g. aws-sm:code-nim/gemini
+)
+
+// SecretSettings configures the backends that vault: and aws-sm: references are resolved from.
+type SecretSettings struct {
+	// RefreshInterval is how often references are resolved again so rotated secrets are picked
+	// up without a restart, e.g. "15m"; empty resolves them only at startup.
+	RefreshInterval string             `yaml:"refreshInterval,omitempty"`
+	Vault           VaultSettings      `yaml:"vault,omitempty"`
+	AWS             AWSSecretsSettings `yaml:"aws,omitempty"`
+}
+
+// VaultSettings locates the HashiCorp Vault server. Unset fields fall back to the VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE environment variables.
+type VaultSettings struct {
+	Address   string `yaml:"address,omitempty"`   // e.g. https://vault.example.com:8200
+	TokenFile string `yaml:"tokenFile,omitempty"` // File holding the token, e.g. written by the Vault agent
+	Namespace string `yaml:"namespace,omitempty"` // Vault Enterprise namespace
+}
+
+// AWSSecretsSettings configures AWS Secrets Manager. Credentials come from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type AWSSecretsSettings struct {
+	Region   string `yaml:"region,omitempty"`   // Falls back to the ARN's region, then AWS_REGION
+	Endpoint string `yaml:"endpoint,omitempty"` // Overrides https://secretsmanager.<region>.amazonaws.com, e.g. for a VPC endpoint
+}
+
+// RefreshDuration parses RefreshInterval. It returns 0 when secrets are only resolved at
+// startup, and ok is false when RefreshInterval is set but not a positive duration.
+func (s SecretSettings) RefreshDuration() (time.Duration, bool) {
+	if s.RefreshInterval == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(s.RefreshInterval)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
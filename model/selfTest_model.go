@@ -0,0 +1,52 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Startup self-test modes of SelfTestSettings.OnStartup.
+const (
+	SelfTestLog  = "log"
+	SelfTestFail = "fail"
+	SelfTestOff  = "off"
+)
+
+// SelfTestSettings configures the check of every entry's credentials at startup.
+type SelfTestSettings struct {
+	// OnStartup is "log" (default) to check in the background and log failures, "fail" to
+	// check before scheduling anything and exit when a check fails, or "off".
+	OnStartup string `yaml:"onStartup,omitempty"`
+}
+
+// Mode returns OnStartup in lower case, SelfTestLog when it is empty.
+func (s SelfTestSettings) Mode() string {
+	if mode := strings.ToLower(strings.TrimSpace(s.OnStartup)); mode != "" {
+		return mode
+	}
+	return SelfTestLog
+}
+
+// SelfTestCheck is the result of one credential check of an entry.
+type SelfTestCheck struct {
+	Name       string `json:"name"` // "bitbucket", "ai" or "ai key 2/3"
+	OK         bool   `json:"ok"`
+	Skipped    bool   `json:"skipped,omitempty"` // not checked for this provider
+	Detail     string `json:"detail,omitempty"`  // the account or model on success, the error otherwise
+	DurationMs int64  `json:"durationMs"`
+}
+
+// SelfTestEntry holds the checks of one configured entry.
+type SelfTestEntry struct {
+	ProcessName string          `json:"processName"`
+	Provider    string          `json:"provider"`
+	OK          bool            `json:"ok"`
+	Checks      []SelfTestCheck `json:"checks"`
+}
+
+// SelfTestReport is the answer of GET /api/v1/selftest and what the startup check logs.
+type SelfTestReport struct {
+	StartedAt time.Time       `json:"startedAt"`
+	OK        bool            `json:"ok"`
+	Entries   []SelfTestEntry `json:"entries"`
+}
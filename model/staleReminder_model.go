@@ -0,0 +1,19 @@
+package model
+
+// StaleReminderSettings posts a reminder on pull requests that have been open too long with
+// unresolved bot findings or without reviewer activity.
+type StaleReminderSettings struct {
+	// Cron schedules the check, with seconds like the entry's cron; empty disables reminders.
+	Cron string `yaml:"cron,omitempty"`
+	// AfterDays is how long a pull request is open before it is reminded about (default 7).
+	AfterDays int `yaml:"afterDays,omitempty"`
+	// RepeatDays is the minimum gap between two reminders on the same pull request (default AfterDays).
+	RepeatDays int `yaml:"repeatDays,omitempty"`
+	// Reviewers are Bitbucket account IDs mentioned in every reminder.
+	Reviewers []string `yaml:"reviewers,omitempty"`
+}
+
+// Enabled reports whether stale reminders are scheduled.
+func (s StaleReminderSettings) Enabled() bool {
+	return s.Cron != ""
+}
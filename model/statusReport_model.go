@@ -0,0 +1,8 @@
+package model
+
+// StatusReportSettings lets the bot keep a "Review status" comment on each reviewed pull request.
+type StatusReportSettings struct {
+	// Enabled posts the comment after the first review and updates it after each later one,
+	// with the commit reviewed, files analyzed and skipped, findings, AI model and duration.
+	Enabled bool `yaml:"enabled"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// Provider health states shown on the status page.
+const (
+	ProviderUp       = "up"       // recent calls succeed
+	ProviderDegraded = "degraded" // some recent calls failed
+	ProviderDown     = "down"     // the latest calls all failed
+	ProviderUnknown  = "unknown"  // no calls since startup
+)
+
+// JobRun is the outcome of one run of a review job.
+type JobRun struct {
+	ID             string        `json:"id"` // shown in the footer of the comments the run posted
+	StartedAt      time.Time     `json:"startedAt"`
+	FinishedAt     time.Time     `json:"finishedAt,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	Result         string        `json:"result"` // "ok" or "error"
+	Error          string        `json:"error,omitempty"`
+	PRsProcessed   int64         `json:"prsProcessed"` // open pull requests looked at, reviewed or not
+	PRsReviewed    int64         `json:"prsReviewed"`
+	FindingsPosted int64         `json:"findingsPosted"`
+	// PullRequests tells what the run did with each pull request it looked at, and why it
+	// posted nothing on those it skipped.
+	PullRequests []JobRunPR `json:"pullRequests,omitempty"`
+}
+
+// Outcomes of a pull request in a job run.
+const (
+	PROutcomeReviewed  = "reviewed"  // the pipeline ran to the end
+	PROutcomeSkipped   = "skipped"   // a stage ended the pipeline early, see Reason
+	PROutcomeUnchanged = "unchanged" // not updated since its last complete review
+	PROutcomeFailed    = "failed"    // an error stopped the review, see Reason
+)
+
+// JobRunPR is what a job run did with one pull request.
+type JobRunPR struct {
+	ID             int    `json:"id"`
+	Outcome        string `json:"outcome"`
+	Reason         string `json:"reason,omitempty"`
+	SummaryPosted  bool   `json:"summaryPosted,omitempty"`
+	FindingsPosted int    `json:"findingsPosted,omitempty"`
+}
+
+// ProviderHealth summarises the recent AI calls to one provider.
+type ProviderHealth struct {
+	Provider      string    `json:"provider"`
+	Status        string    `json:"status"`
+	RecentCalls   int       `json:"recentCalls"`
+	RecentErrors  int       `json:"recentErrors"`
+	LastSuccessAt time.Time `json:"lastSuccessAt,omitempty"`
+	LastErrorAt   time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// JobSummary is the public view of a job on the status page; it carries no error details.
+type JobSummary struct {
+	Name       string    `json:"name"`
+	Paused     bool      `json:"paused"`
+	Running    bool      `json:"running"`
+	Frozen     bool      `json:"frozen"`
+	NextRunAt  time.Time `json:"nextRunAt,omitempty"`
+	LastResult string    `json:"lastResult,omitempty"`
+	RecentRuns []JobRun  `json:"recentRuns"`
+}
+
+// ServiceStatus is the body of the public status page.
+type ServiceStatus struct {
+	Status     string           `json:"status"` // "ok", or "degraded" when a job or provider is failing
+	StartedAt  time.Time        `json:"startedAt"`
+	Uptime     time.Duration    `json:"uptime"`
+	QueueDepth *int             `json:"queueDepth,omitempty"` // nil without a work queue
+	Providers  []ProviderHealth `json:"providers"`
+	Jobs       []JobSummary     `json:"jobs"`
+}
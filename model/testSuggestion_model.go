@@ -0,0 +1,22 @@
+package model
+
+// DefaultTestSuggestionFiles is how many files get test suggestions per pull request when
+// testSuggestions.maxFiles is unset.
+const DefaultTestSuggestionFiles = 5
+
+// TestSuggestionSettings lets the bot recommend test cases for changed source files whose tests
+// were not changed in the same pull request.
+type TestSuggestionSettings struct {
+	// Enabled posts one comment per review listing the recommended test cases of each such file.
+	Enabled bool `yaml:"enabled"`
+	// MaxFiles bounds how many files get suggestions per pull request (default 5).
+	MaxFiles int `yaml:"maxFiles,omitempty"`
+}
+
+// FileLimit returns MaxFiles, or DefaultTestSuggestionFiles when it is unset.
+func (s TestSuggestionSettings) FileLimit() int {
+	if s.MaxFiles > 0 {
+		return s.MaxFiles
+	}
+	return DefaultTestSuggestionFiles
+}
@@ -0,0 +1,10 @@
+package model
+
+// TimeWindow is a daily period on some weekdays, e.g. 08:00–18:00 Mon–Fri. A window whose To
+// is before its From spans midnight; equal times cover the whole day.
+type TimeWindow struct {
+	Days     []string `yaml:"days,omitempty"`     // "Mon", "Tuesday" or ranges such as "Mon-Fri"; empty is every day
+	From     string   `yaml:"from"`               // "08:00"
+	To       string   `yaml:"to"`                 // "18:00", or "24:00" for the end of the day
+	Timezone string   `yaml:"timezone,omitempty"` // IANA name such as "Europe/Berlin"; default UTC
+}
@@ -0,0 +1,21 @@
+package model
+
+// TitlePolicySettings checks pull request titles against a team convention.
+type TitlePolicySettings struct {
+	// Pattern is a regular expression the title must match.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Conventional requires a Conventional Commits title such as "feat(api): add paging".
+	Conventional bool `yaml:"conventional,omitempty"`
+	// Types are the allowed Conventional Commits types; empty allows feat, fix, docs, style,
+	// refactor, perf, test, build, ci, chore and revert.
+	Types []string `yaml:"types,omitempty"`
+	// SuggestTitle adds a title generated from the PR summary to the reminder.
+	SuggestTitle bool `yaml:"suggestTitle,omitempty"`
+	// Task also opens a pull request task, so a "no open tasks" merge check can enforce the policy.
+	Task bool `yaml:"task,omitempty"`
+}
+
+// Enabled reports whether a title rule is configured.
+func (s TitlePolicySettings) Enabled() bool {
+	return s.Pattern != "" || s.Conventional
+}
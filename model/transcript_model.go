@@ -0,0 +1,50 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Transcript is a recorded AI exchange for a single review step.
+type Transcript struct {
+	ID            string          `json:"id"`
+	Kind          string          `json:"kind"` // "summary" or "inline"
+	ProcessName   string          `json:"processName"`
+	Workspace     string          `json:"workspace"`
+	RepoSlug      string          `json:"repoSlug"`
+	PullRequestID int             `json:"pullRequestId"`
+	Path          string          `json:"path,omitempty"` // File path for inline transcripts
+	Prompt        string          `json:"prompt"`
+	Response      string          `json:"response,omitempty"`
+	Findings      []ReviewComment `json:"findings,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// TranscriptSettings controls prompt/response recording and its retention.
+type TranscriptSettings struct {
+	Enabled       bool   `yaml:"enabled"`
+	RetentionDays int    `yaml:"retentionDays,omitempty"` // Days to keep transcripts (default: 30)
+	PurgeCron     string `yaml:"purgeCron,omitempty"`     // When to purge expired transcripts (default: "0 3 * * *")
+}
+
+// TranscriptFilter selects stored transcripts; zero values match everything.
+type TranscriptFilter struct {
+	Workspace     string
+	RepoSlug      string
+	PullRequestID int
+	From          time.Time // inclusive
+}
+
+// Matches reports whether t passes the filter.
+func (tf TranscriptFilter) Matches(t Transcript) bool {
+	if tf.Workspace != "" && !strings.EqualFold(tf.Workspace, t.Workspace) {
+		return false
+	}
+	if tf.RepoSlug != "" && !strings.EqualFold(tf.RepoSlug, t.RepoSlug) {
+		return false
+	}
+	if tf.PullRequestID != 0 && tf.PullRequestID != t.PullRequestID {
+		return false
+	}
+	return tf.From.IsZero() || !t.CreatedAt.Before(tf.From)
+}
@@ -0,0 +1,37 @@
+package model
+
+// AIUsage is the token usage reported by a single AI call.
+type AIUsage struct {
+	PromptTokens   int64 `json:"promptTokens"`
+	ResponseTokens int64 `json:"responseTokens"`
+	TotalTokens    int64 `json:"totalTokens"`
+	// CachedTokens is the part of PromptTokens served from a context or prompt cache.
+	CachedTokens int64 `json:"cachedTokens,omitempty"`
+}
+
+// UsageRecord aggregates AI usage for one repository on one day.
+type UsageRecord struct {
+	Day            string  `json:"day"` // YYYY-MM-DD
+	Workspace      string  `json:"workspace"`
+	RepoSlug       string  `json:"repoSlug"`
+	Calls          int64   `json:"calls"`
+	PromptTokens   int64   `json:"promptTokens"`
+	ResponseTokens int64   `json:"responseTokens"`
+	TotalTokens    int64   `json:"totalTokens"`
+	CostUSD        float64 `json:"costUsd"`
+	CachedTokens   int64   `json:"cachedTokens,omitempty"` // Part of PromptTokens served from a cache
+}
+
+// ModelPrice is the USD price per 1,000 tokens of a model.
+type ModelPrice struct {
+	PromptPer1K   float64 `yaml:"promptPer1K"`
+	ResponsePer1K float64 `yaml:"responsePer1K"`
+	CachedPer1K   float64 `yaml:"cachedPer1K,omitempty"` // Cached prompt tokens; 0 bills them as prompt tokens
+}
+
+// UsageSettings configures cost estimation and the daily budget across all repositories.
+type UsageSettings struct {
+	DailyTokenBudget int64                 `yaml:"dailyTokenBudget,omitempty"` // 0 disables the token budget
+	DailyCostBudget  float64               `yaml:"dailyCostBudget,omitempty"`  // USD; 0 disables the cost budget
+	Prices           map[string]ModelPrice `yaml:"prices,omitempty"`           // Keyed by model name; "*" is the fallback
+}
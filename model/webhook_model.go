@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// DefaultWebhookMaxAge is how old a webhook delivery may be before it is rejected as stale.
+const DefaultWebhookMaxAge = 15 * time.Minute
+
+// WebhookSettings controls how incoming webhooks are authenticated. Secrets are configured
+// per repository with webhookSecret on each autoReviewPR entry.
+type WebhookSettings struct {
+	RequireSignature bool   `yaml:"requireSignature,omitempty"` // Reject unsigned deliveries even for repos without a secret
+	MaxAge           string `yaml:"maxAge,omitempty"`           // Oldest accepted delivery, e.g. "15m" (default); "0" disables the check
+}
+
+// MaxAgeDuration returns the parsed MaxAge, the default when unset, and ok=false when it is invalid.
+func (w WebhookSettings) MaxAgeDuration() (time.Duration, bool) {
+	if w.MaxAge == "" {
+		return DefaultWebhookMaxAge, true
+	}
+	if w.MaxAge == "0" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(w.MaxAge)
+	if err != nil || d < 0 {
+		return DefaultWebhookMaxAge, false
+	}
+	return d, true
+}
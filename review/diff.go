@@ -0,0 +1,84 @@
+package review
+
+import (
+	"code_nim/helper"
+	"time"
+)
+
+// File is one file of a unified diff.
+type File struct {
+	Path   string // new path of the file ("b/" side)
+	Binary bool   // a binary patch, which has no hunks
+	Hunks  []Hunk
+}
+
+// Hunk is one "@@" section of a file diff.
+type Hunk struct {
+	Header string   // the "@@ -a,b +c,d @@" line
+	Lines  []string // diff lines with their " ", "+" or "-" prefix
+}
+
+// ParseDiff splits a unified diff, such as the output of git diff, into files.
+func ParseDiff(diff string) []File {
+	parsed := helper.ParseDiff(diff)
+	files := make([]File, 0, len(parsed))
+	for _, raw := range parsed {
+		f := File{}
+		f.Path, _ = raw["path"].(string)
+		f.Binary, _ = raw["binary"].(bool)
+		hunks, _ := raw["hunks"].([]map[string]interface{})
+		for _, h := range hunks {
+			header, _ := h["header"].(string)
+			lines, _ := h["lines"].([]string)
+			f.Hunks = append(f.Hunks, Hunk{Header: header, Lines: lines})
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// ParseDiff splits a unified diff into files like the package-level ParseDiff and reports the
+// time it took to Observe.
+func (r *Reviewer) ParseDiff(diff string) []File {
+	start := time.Now()
+	defer r.observe(StepParse, start)
+	return ParseDiff(diff)
+}
+
+// SkipReason returns why f is left out of inline review: it is binary, larger than
+// Config.MaxFileLines, routed away from the AI or, unless Config.ReviewGeneratedFiles is set,
+// generated. It returns "" for files to review.
+func (r *Reviewer) SkipReason(f File) string {
+	return helper.SkippedFileReason(&r.Config, f.raw())
+}
+
+// UntestedFiles returns the paths of the changed source files of files that have no changed
+// test file of the same name, the candidates for SuggestTests.
+func (r *Reviewer) UntestedFiles(files []File) []string {
+	return helper.UntestedSourceFiles(&r.Config, rawFiles(files))
+}
+
+// raw returns f in the shape of helper.ParseDiff, which the helper functions take.
+func (f File) raw() map[string]interface{} {
+	raw := map[string]interface{}{"path": f.Path, "hunks": rawHunks(f.Hunks)}
+	if f.Binary {
+		raw["binary"] = true
+	}
+	return raw
+}
+
+func rawFiles(files []File) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(files))
+	for _, f := range files {
+		out = append(out, f.raw())
+	}
+	return out
+}
+
+func rawHunks(hunks []Hunk) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(hunks))
+	for _, h := range hunks {
+		out = append(out, map[string]interface{}{"header": h.Header, "lines": h.Lines})
+	}
+	return out
+}
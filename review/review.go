@@ -0,0 +1,524 @@
+// Package review is the embeddable core of code-nim. It turns a pull request diff into an AI
+// summary and findings anchored to file lines of the diff, without talking to any git host,
+// so other services can add PR review without running the daemon.
+//
+// The exported API of this package is stable: fields and functions are only ever added.
+// Embedders only need this package and model.
+//
+//	r := review.New(model.AutoReviewPR{AIProvider: "gemini", AIKey: key, AIModel: "gemini-2.5-flash"})
+//	result, err := r.Review(&model.PullRequest{Title: title, Description: body}, diff)
+//	// or, for a bare diff without a summary: result, err := r.ReviewDiff(diff); findings := result.Findings()
+//	for _, f := range result.Files {
+//		for _, c := range f.Placed {
+//			// c.Path, c.Position (new file line, 0 on a removed line), c.FromLine, review.RenderFindingIn(c.Body, r.Style(), r.Config.Language)
+//		}
+//	}
+package review
+
+import (
+	"code_nim/helper"
+	"code_nim/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrEmptySnippet is returned by ReviewFile when a file has no reviewable diff lines.
+var ErrEmptySnippet = errors.New("file has no reviewable diff lines")
+
+// Errors of the AI providers, for use with errors.Is.
+var (
+	ErrAuth              = helper.ErrAuth              // the provider rejected the credentials
+	ErrRateLimited       = helper.ErrRateLimited       // the provider rate limited the request
+	ErrAIInvalidResponse = helper.ErrAIInvalidResponse // the reply was not in the expected format
+	ErrAITruncated       = helper.ErrAITruncated       // the reply was cut off at the output token limit
+)
+
+// Reasons of a Rejection.
+const (
+	PlacementOutOfRange    = helper.PlacementOutOfRange    // diff index outside the snippet sent to the AI
+	PlacementDeletedLine   = helper.PlacementDeletedLine   // points at a removed line whose source line is unknown
+	PlacementOutsideHunk   = helper.PlacementOutsideHunk   // destination line is not part of any hunk of the file
+	PlacementAnchorMissing = helper.PlacementAnchorMissing // anchor text does not appear at or next to the line
+)
+
+// SkippedFile is a file left out of review, with the reason.
+type SkippedFile = helper.SkippedFile
+
+// DependencyVulnerabilities are the known advisories of a changed manifest dependency.
+type DependencyVulnerabilities = helper.DependencyVulnerabilities
+
+// ContractChange is a structural change of an OpenAPI, Swagger or proto contract file.
+type ContractChange = helper.ContractChange
+
+// Steps reported to Reviewer.Observe.
+const (
+	StepAI     = "ai"
+	StepParse  = "parse"
+	StepAnchor = "anchor"
+)
+
+// Reviewer runs AI reviews with the provider, model, keys and tone of Config.
+type Reviewer struct {
+	Config model.AutoReviewPR
+	// Observe, when set, receives the duration of each ai, parse and anchor step.
+	Observe func(step string, d time.Duration)
+	// FileContext, when set, returns extra prompt context for a file, such as linter output.
+	FileContext func(path string) string
+	// Cache, when set, reuses earlier AI replies to the same prompt instead of calling the AI.
+	Cache Cache
+}
+
+// Cache stores AI replies by key (see CacheKey). Load reports false when there is no usable
+// reply; Store receives the reply with Key, Kind, PullRequestID and the reply fields set.
+type Cache interface {
+	Load(key string) (model.AIResponse, bool)
+	Store(r model.AIResponse)
+}
+
+// CacheKey identifies the reply to prompt from the provider and model of cfg. The prompts
+// embed the diff, so the key changes whenever the reviewed diff does.
+func CacheKey(cfg *model.AutoReviewPR, kind, prompt string) string {
+	provider := strings.ToLower(strings.TrimSpace(cfg.AIProvider))
+	sum := sha256.Sum256([]byte(strings.Join([]string{kind, provider, helper.AIModelName(cfg), cfg.AzureDeployment, cfg.SelfAPIBaseURL, prompt}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// New returns a Reviewer for cfg. The AI settings, tone, language and prompt settings of cfg
+// are used, and so are Mode, the package profiles, the model routes and the file skip rules
+// (MaxFileLines, ReviewGeneratedFiles). The git host settings are ignored.
+func New(cfg model.AutoReviewPR) *Reviewer {
+	return &Reviewer{Config: cfg}
+}
+
+// Style returns the tone findings of r are rendered in, for RenderFinding and RenderFindingIn.
+func (r *Reviewer) Style() string {
+	return helper.ReviewStyle(&r.Config)
+}
+
+// Rejection is an AI finding that could not be placed on a line of the diff.
+type Rejection struct {
+	Comment model.ReviewComment // Path is set; Position is 0
+	Reason  string              // one of the Placement* reasons
+}
+
+// FileReview is the outcome of reviewing one file of a diff.
+type FileReview struct {
+	Path   string
+	Prompt string
+	Raw    []model.ReviewComment // findings as returned by the AI, before anchoring
+	// Vulnerabilities are the changed manifest dependencies with known OSV.dev advisories.
+	// Each one is also reported as a finding on its manifest line, ahead of the AI findings.
+	Vulnerabilities []DependencyVulnerabilities
+	// Contract holds the structural changes of an OpenAPI, Swagger or proto contract file.
+	// Each breaking one is also reported as a finding on its line, ahead of the AI findings.
+	Contract []ContractChange
+	Placed   []model.ReviewComment // findings mapped to file lines: Position is the new-file line
+	Rejected []Rejection
+}
+
+// Result is the outcome of reviewing a whole diff.
+type Result struct {
+	Summary string
+	Files   []FileReview
+	// Errors holds the AI error of each file that could not be reviewed, keyed by path.
+	Errors map[string]error
+	// Skipped lists the binary and oversized files that were not reviewed, and the generated
+	// and minified files and lockfiles unless Config.ReviewGeneratedFiles is set.
+	Skipped []SkippedFile
+}
+
+func (r *Reviewer) observe(step string, start time.Time) {
+	if r.Observe != nil {
+		r.Observe(step, time.Since(start))
+	}
+}
+
+// text returns the AI reply to a free-text prompt, from Cache when it holds one.
+func (r *Reviewer) text(kind string, pr *model.PullRequest, prompt string) (string, error) {
+	key := CacheKey(&r.Config, kind, prompt)
+	if r.Cache != nil {
+		if cached, ok := r.Cache.Load(key); ok {
+			return cached.Response, nil
+		}
+	}
+	start := time.Now()
+	text, err := helper.GetAISummary(prompt, &r.Config)
+	r.observe(StepAI, start)
+	if err == nil && r.Cache != nil && strings.TrimSpace(text) != "" {
+		r.Cache.Store(model.AIResponse{Key: key, Kind: kind, PullRequestID: pr.ID, Response: text})
+	}
+	return text, err
+}
+
+// Summarize asks the AI for the Markdown summary of a pull request.
+func (r *Reviewer) Summarize(pr *model.PullRequest, diff string) (string, error) {
+	return r.text("summary", pr, helper.CreateSummaryPrompt(pr, diff, &r.Config))
+}
+
+// Describe asks the AI for a What/Why/How/Test plan description of a pull request, with its
+// headings translated into Config.Language.
+func (r *Reviewer) Describe(pr *model.PullRequest, diff string) (string, error) {
+	text, err := r.text("description", pr, helper.CreateDescriptionPrompt(pr, diff, &r.Config))
+	if err != nil {
+		return "", err
+	}
+	return helper.LocalizeDescription(strings.TrimSpace(text), r.Config.Language), nil
+}
+
+// TitleRules describes a title policy for SuggestTitle.
+func TitleRules(policy model.TitlePolicySettings) string {
+	return helper.TitleRules(policy)
+}
+
+// SuggestTitle asks the AI for a pull request title that follows rules (see TitleRules),
+// based on the PR summary. It returns the first line of the answer.
+func (r *Reviewer) SuggestTitle(pr *model.PullRequest, summary, rules string) (string, error) {
+	text, err := r.text("title", pr, helper.CreateTitlePrompt(pr, summary, rules))
+	if err != nil {
+		return "", err
+	}
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.Trim(strings.TrimSpace(title), "`\"'"), nil
+}
+
+// SuggestTests asks the AI for the test cases the hunks of a changed source file need (see
+// UntestedFiles). It returns "" when the AI finds no behaviour worth testing.
+func (r *Reviewer) SuggestTests(pr *model.PullRequest, path string, hunks []Hunk) (string, error) {
+	prompt := r.TestsPrompt(pr, path, hunks)
+	if prompt == "" {
+		return "", ErrEmptySnippet
+	}
+	text, err := r.text("tests", pr, prompt)
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if strings.Contains(text, helper.NoTestSuggestions) {
+		return "", nil
+	}
+	return text, nil
+}
+
+// TestsPrompt returns the prompt SuggestTests sends for the hunks of a file, or "" when they
+// have no reviewable lines.
+func (r *Reviewer) TestsPrompt(pr *model.PullRequest, path string, hunks []Hunk) string {
+	lines, _ := helper.BuildDiffSnippetAndLineMap(rawHunks(hunks))
+	if len(lines) == 0 {
+		return ""
+	}
+	return helper.CreateTestSuggestionPrompt(path, lines, pr, &r.Config)
+}
+
+// findings returns the parsed AI findings for a file prompt, from Cache when it holds them.
+// A reply that is not valid JSON (ErrAIInvalidResponse) is requested once more.
+func (r *Reviewer) findings(pr *model.PullRequest, path, prompt string) ([]model.ReviewComment, error) {
+	key := CacheKey(&r.Config, "inline", prompt)
+	if r.Cache != nil {
+		if cached, ok := r.Cache.Load(key); ok {
+			return cached.Findings, nil
+		}
+	}
+	start := time.Now()
+	comments, err := helper.GetAIResponse(prompt, &r.Config)
+	r.observe(StepAI, start)
+	if errors.Is(err, ErrAIInvalidResponse) {
+		// Replies are sampled, so asking once more usually yields valid JSON
+		start = time.Now()
+		comments, err = helper.GetAIResponse(prompt, &r.Config)
+		r.observe(StepAI, start)
+	}
+	if err == nil && r.Cache != nil {
+		r.Cache.Store(model.AIResponse{Key: key, Kind: "inline", PullRequestID: pr.ID, Path: path, Findings: comments})
+	}
+	return comments, err
+}
+
+// maxSplitDepth bounds how often the hunks of a file whose review was cut off are halved.
+const maxSplitDepth = 2
+
+// filePrompt returns the inline review prompt for the snippet lines of a file.
+func (r *Reviewer) filePrompt(pr *model.PullRequest, path string, lines []string) string {
+	prompt := helper.CreatePrompt(path, lines, pr, &r.Config)
+	if r.FileContext != nil {
+		prompt += r.FileContext(path)
+	}
+	return prompt
+}
+
+// chunkedFindings returns the findings for prompt, the review prompt of hunks. When the reply
+// is cut off at the output token limit (ErrAITruncated), the two halves of hunks are
+// reviewed separately and their findings joined; a single hunk, or one halved maxSplitDepth
+// times already, keeps the complete findings salvaged from the cut-off reply.
+func (r *Reviewer) chunkedFindings(pr *model.PullRequest, path string, hunks []map[string]interface{}, prompt string, depth int) ([]model.ReviewComment, error) {
+	comments, err := r.findings(pr, path, prompt)
+	if !errors.Is(err, ErrAITruncated) {
+		return comments, err
+	}
+	if len(hunks) < 2 || depth >= maxSplitDepth {
+		return comments, nil
+	}
+	half := len(hunks) / 2
+	firstLines, _ := helper.BuildDiffSnippetAndLineMap(hunks[:half])
+	first, err := r.chunkedFindings(pr, path, hunks[:half], r.filePrompt(pr, path, firstLines), depth+1)
+	if err != nil {
+		return nil, err
+	}
+	secondLines, _ := helper.BuildDiffSnippetAndLineMap(hunks[half:])
+	second, err := r.chunkedFindings(pr, path, hunks[half:], r.filePrompt(pr, path, secondLines), depth+1)
+	if err != nil {
+		return nil, err
+	}
+	// Indexes of the second half are relative to its own snippet
+	for _, c := range second {
+		if c.Position > 0 {
+			c.Position += len(firstLines)
+		}
+		if c.StartLine > 0 {
+			c.StartLine += len(firstLines)
+		}
+		first = append(first, c)
+	}
+	return first, nil
+}
+
+// ReviewFile asks the AI to review the hunks of one file and anchors each finding to a file line.
+// Findings whose placement fails validation are returned in Rejected instead of Placed.
+// Breaking changes of an API contract file are reported as findings of their own, and
+// findings titled "Breaking change" are raised to at least Major (helper.EscalateBreakingChange),
+// like the potential issues on files whose review profile sets a MinSeverity, such as migrations.
+// Findings placed on the same line are merged into one comment (helper.MergeSameLineFindings).
+// A reply that is not valid JSON (ErrAIInvalidResponse) is requested once more, and
+// the hunks of a reply cut off at the output token limit are reviewed in smaller parts.
+// With Cache set, an unchanged file is not sent to the AI again. A file in one of the
+// Config.Packages is reviewed with the prompt and model of its package profile, and a file
+// matching one of the Config.ModelRoutes with the model of that route.
+func (r *Reviewer) ReviewFile(pr *model.PullRequest, path string, hunks []Hunk) (FileReview, error) {
+	raw := rawHunks(hunks)
+	if cfg := helper.RouteConfig(helper.PackageConfig(&r.Config, path), path, raw); cfg != &r.Config {
+		pkg := *r
+		pkg.Config = *cfg
+		return pkg.reviewFile(pr, path, raw)
+	}
+	return r.reviewFile(pr, path, raw)
+}
+
+func (r *Reviewer) reviewFile(pr *model.PullRequest, path string, hunks []map[string]interface{}) (FileReview, error) {
+	fr := FileReview{Path: path}
+	start := time.Now()
+	allLines, lineMap := helper.BuildDiffSnippetAndLineMap(hunks)
+	r.observe(StepParse, start)
+	if len(allLines) == 0 {
+		return fr, ErrEmptySnippet
+	}
+	fr.Prompt = r.filePrompt(pr, path, allLines)
+	if !r.Config.SkipVulnerabilityLookup {
+		if changes := helper.ParseDependencyChanges(path, allLines); len(changes) > 0 {
+			fr.Vulnerabilities = helper.CheckDependencyVulnerabilities(changes)
+			fr.Prompt += helper.VulnerabilityPromptContext(fr.Vulnerabilities)
+		}
+	}
+	if fr.Contract = helper.ParseContractChanges(path, allLines); len(fr.Contract) > 0 {
+		fr.Prompt += helper.ContractPromptContext(fr.Contract)
+	}
+
+	comments, err := r.chunkedFindings(pr, path, hunks, fr.Prompt, 0)
+	if err != nil {
+		return fr, err
+	}
+	fr.Raw = append([]model.ReviewComment(nil), comments...)
+	profile := helper.ReviewProfileFor(path, allLines)
+	for i := range comments {
+		comments[i].Body = helper.EscalateBreakingChange(comments[i].Body)
+		if profile != nil && profile.MinSeverity != "" {
+			comments[i].Body = helper.EscalateSeverity(comments[i].Body, profile.MinSeverity)
+		}
+	}
+	// Advisory and contract findings go first so they lead a merged comment of the same severity.
+	advisories := make([]model.ReviewComment, 0, len(fr.Vulnerabilities)+len(fr.Contract))
+	for _, v := range fr.Vulnerabilities {
+		body, position, anchor := helper.VulnerabilityFinding(v)
+		advisories = append(advisories, model.ReviewComment{Body: body, Position: position, Anchor: anchor})
+	}
+	for _, c := range fr.Contract {
+		if c.Breaking() {
+			body, position, anchor := helper.ContractFinding(c)
+			advisories = append(advisories, model.ReviewComment{Body: body, Position: position, Anchor: anchor})
+		}
+	}
+	comments = append(advisories, comments...)
+
+	start = time.Now()
+	defer r.observe(StepAnchor, start)
+	for _, c := range comments {
+		// Use anchor text to correct the index if present; a range moves with its last line
+		if c.Anchor != "" {
+			idx := helper.NearestMatchingLineIndex(allLines, c.Anchor, c.Position-1)
+			if idx >= 0 && idx < len(lineMap) {
+				if c.StartLine > 0 {
+					c.StartLine += idx + 1 - c.Position
+				}
+				c.Position = idx + 1
+			}
+		}
+		// Map AI diff index (1-based within provided snippet) to file lines
+		if c.Position <= 0 || c.Position > len(lineMap) {
+			fr.Rejected = append(fr.Rejected, reject(c, path, helper.PlacementOutOfRange))
+			continue
+		}
+		mapping := lineMap[c.Position-1]
+		if mapping.ToLine <= 0 && mapping.FromLine <= 0 {
+			fr.Rejected = append(fr.Rejected, reject(c, path, helper.PlacementDeletedLine))
+			continue
+		}
+		c.Path = path
+		if mapping.ToLine <= 0 {
+			// Removed lines only exist in the source file, so the comment goes on the old side
+			c.StartLine, c.EndLine = 0, 0
+		} else if c.StartLine > 0 {
+			// Ranges that do not map to contiguous destination lines fall back to the last line
+			if c.StartLine = helper.RangeStartLine(lineMap, c.StartLine, c.Position); c.StartLine > 0 {
+				c.EndLine = mapping.ToLine
+			}
+		}
+		c.Position = mapping.ToLine   // destination/new file line
+		c.FromLine = mapping.FromLine // source/old file line (-1 for added lines)
+		if c.Position < 0 {
+			c.Position = 0 // removed line: anchored by FromLine only
+		}
+
+		// Simulate the placement against the re-rendered diff before anything is posted
+		if reason := helper.ValidatePlacement(allLines, lineMap, c); reason != "" {
+			fr.Rejected = append(fr.Rejected, reject(c, path, reason))
+			continue
+		}
+		fr.Placed = append(fr.Placed, c)
+	}
+	fr.Placed = helper.MergeSameLineFindings(fr.Placed)
+	return fr, nil
+}
+
+func reject(c model.ReviewComment, path, reason string) Rejection {
+	c.Path = path
+	c.Position = 0
+	c.FromLine = 0
+	c.StartLine, c.EndLine = 0, 0
+	return Rejection{Comment: c, Reason: reason}
+}
+
+// Review summarizes the pull request and reviews every file of diff but the binary, oversized
+// and generated ones (see Result.Skipped). Per-file AI errors are collected in Result.Errors; only a failed summary
+// is returned as an error. The Mode of the configuration leaves out the summary or the files,
+// and so do model routes that leave every changed file out of AI review (helper.SkipsAI).
+func (r *Reviewer) Review(pr *model.PullRequest, diff string) (Result, error) {
+	var res Result
+	if helper.SkipsAI(&r.Config, diff) {
+		for _, file := range r.ParseDiff(diff) {
+			if reason := r.SkipReason(file); reason != "" {
+				res.Skipped = append(res.Skipped, SkippedFile{Path: file.Path, Reason: reason})
+			}
+		}
+		return res, nil
+	}
+	if r.Config.PostsSummary() {
+		summary, err := r.Summarize(pr, diff)
+		if err != nil {
+			return res, err
+		}
+		res.Summary = summary
+	}
+	if r.Config.ReviewsInline() {
+		r.reviewFiles(pr, diff, &res, false)
+	}
+	return res, nil
+}
+
+// ReviewDiff reviews every file of a bare diff, such as the output of git diff, like Review but
+// without a summary or pull request metadata. Per-file AI errors are collected in
+// Result.Errors; when the provider rejects the credentials or rate limits
+// (ErrAuth, ErrRateLimited), the remaining files are not sent and that error is
+// returned with the files reviewed so far.
+func (r *Reviewer) ReviewDiff(diff string) (Result, error) {
+	var res Result
+	err := r.reviewFiles(&model.PullRequest{}, diff, &res, true)
+	return res, err
+}
+
+// reviewFiles adds the review of each file of diff to res. With stopOnAbort, it returns the
+// first error that would fail the remaining files too.
+func (r *Reviewer) reviewFiles(pr *model.PullRequest, diff string, res *Result, stopOnAbort bool) error {
+	for _, file := range r.ParseDiff(diff) {
+		path := file.Path
+		if reason := r.SkipReason(file); reason != "" {
+			res.Skipped = append(res.Skipped, SkippedFile{Path: path, Reason: reason})
+			continue
+		}
+		fr, err := r.ReviewFile(pr, path, file.Hunks)
+		if errors.Is(err, ErrEmptySnippet) {
+			continue
+		}
+		if err != nil {
+			if res.Errors == nil {
+				res.Errors = map[string]error{}
+			}
+			res.Errors[path] = err
+			if stopOnAbort && (errors.Is(err, ErrAuth) || errors.Is(err, ErrRateLimited)) {
+				return err
+			}
+			continue
+		}
+		res.Files = append(res.Files, fr)
+	}
+	return nil
+}
+
+// Findings returns the placed findings of all files, in diff order.
+func (res Result) Findings() []model.ReviewComment {
+	var out []model.ReviewComment
+	for _, f := range res.Files {
+		out = append(out, f.Placed...)
+	}
+	return out
+}
+
+// RenderSummary formats an AI summary as the Markdown posted by code-nim.
+func RenderSummary(summary string) string {
+	return helper.FormatSummaryBody(summary)
+}
+
+// RenderDiffStats renders the size, language and test-to-code figures of diff shown at the top of
+// code-nim summaries. It needs no AI call.
+func RenderDiffStats(diff string) string {
+	return helper.FormatDiffStats(helper.ComputeDiffStats(diff))
+}
+
+// RenderContractChanges renders the "API Contract Changes" section of code-nim summaries: the
+// endpoints and fields added, removed or changed in the OpenAPI, Swagger and proto files of
+// diff. It needs no AI call and is empty when no contract changed.
+func RenderContractChanges(diff string) string {
+	return helper.FormatContractChanges(helper.ContractChangesInDiff(diff))
+}
+
+// RenderFinding formats a finding body for the given tone, as posted inline by code-nim.
+func RenderFinding(body, tone string) string {
+	return helper.FormatReviewBodyForTone(body, tone)
+}
+
+// RenderSummaryIn formats an AI summary like RenderSummary with its headings translated into
+// language ("vi", "ja", ...).
+func RenderSummaryIn(summary, language string) string {
+	return helper.LocalizeSummary(helper.FormatSummaryBody(summary), language)
+}
+
+// RenderFindingIn formats a finding body like RenderFinding with its fixed headers translated
+// into language.
+func RenderFindingIn(body, tone, language string) string {
+	return helper.LocalizeFinding(helper.FormatReviewBodyForTone(body, tone), language)
+}
+
+// RenderFindingsTable renders placed findings as the Markdown table used in minimal mode.
+func RenderFindingsTable(cfg *model.AutoReviewPR, prID int, findings []model.ReviewComment) string {
+	return helper.FormatFindingsTable(cfg, prID, findings, nil)
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"code_nim/handler"
+	"code_nim/log"
+	"code_nim/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+type API struct {
+	Echo                *echo.Echo
+	AutoReviewPRHandler *handler.AutoReviewPRHandler
+	TranscriptHandler   handler.TranscriptHandler
+	FindingHandler      handler.FindingHandler
+	UsageHandler        handler.UsageHandler
+	ReportHandler       handler.ReportHandler
+	ConfigHandler       handler.ConfigHandler
+	BenchmarkHandler    *handler.BenchmarkHandler
+	Auth                model.AuthSettings
+}
+
+func (api *API) SetupRouter() {
+	auth := newAuthenticator(api.Auth)
+	if !api.Auth.Enabled() {
+		log.Warn("No auth configured: management and API endpoints are open")
+	}
+	route := func(method, path, group string, h echo.HandlerFunc) {
+		api.Echo.Add(method, path, h, auth.guard(method, path, group))
+	}
+
+	route("POST", "/webhook/bitbucket", routePublic, api.AutoReviewPRHandler.BitbucketWebhook)
+	route("GET", "/metrics", model.RouteGroupMetrics, api.AutoReviewPRHandler.Metrics)
+
+	route("POST", "/api/v1/transcripts/purge", model.RouteGroupAdmin, api.TranscriptHandler.PurgeTranscripts)
+	// Path before the API was versioned, kept for existing scripts
+	route("POST", "/api/transcripts/purge", model.RouteGroupAdmin, api.TranscriptHandler.PurgeTranscripts)
+
+	route("GET", "/findings/:id", model.RouteGroupDashboard, api.FindingHandler.FindingDetails)
+	route("GET", "/api/v1/findings/:id", model.RouteGroupAPI, api.FindingHandler.GetFinding)
+
+	route("GET", "/api/v1/usage", model.RouteGroupAPI, api.UsageHandler.GetUsage)
+	route("GET", "/api/v1/reports", model.RouteGroupAPI, api.ReportHandler.GetReports)
+	route("GET", "/api/v1/reports/risk", model.RouteGroupAPI, api.ReportHandler.GetRiskScores)
+	route("GET", "/api/v1/feedback", model.RouteGroupAPI, api.ReportHandler.GetFeedback)
+	route("POST", "/api/v1/config/validate", model.RouteGroupAPI, api.ConfigHandler.ValidateConfig)
+	route("POST", "/api/v1/analyze", model.RouteGroupAPI, api.AutoReviewPRHandler.Analyze)
+	route("GET", "/api/v1/benchmarks", model.RouteGroupAPI, api.BenchmarkHandler.GetBenchmarks)
+	route("POST", "/api/v1/benchmarks/run", model.RouteGroupAdmin, api.BenchmarkHandler.RunBenchmark)
+
+	route("GET", "/status", model.RouteGroupStatus, api.AutoReviewPRHandler.StatusPage)
+	route("GET", "/api/v1/status", model.RouteGroupStatus, api.AutoReviewPRHandler.GetStatus)
+
+	route("GET", "/dashboard", model.RouteGroupDashboard, api.AutoReviewPRHandler.Dashboard)
+	route("GET", "/api/v1/jobs", model.RouteGroupAPI, api.AutoReviewPRHandler.ListJobs)
+	route("GET", "/api/v1/selftest", model.RouteGroupAPI, api.AutoReviewPRHandler.SelfTest)
+	route("PATCH", "/api/v1/jobs/:name", model.RouteGroupAdmin, api.AutoReviewPRHandler.UpdateJob)
+	route("POST", "/api/v1/jobs/:name/trigger", model.RouteGroupAdmin, api.AutoReviewPRHandler.TriggerJob)
+	route("POST", "/api/v1/summary/:workspace/:repoSlug/:prID", model.RouteGroupAdmin, api.AutoReviewPRHandler.PostSummaryOnly)
+	route("GET", "/api/v1/ignored", model.RouteGroupAPI, api.AutoReviewPRHandler.ListIgnored)
+	route("DELETE", "/api/v1/ignored/:workspace/:repoSlug/:prID", model.RouteGroupAdmin, api.AutoReviewPRHandler.UnignorePullRequest)
+	route("POST", "/api/v1/jobs/:name/pause", model.RouteGroupAdmin, api.AutoReviewPRHandler.PauseJob)
+	route("POST", "/api/v1/jobs/:name/resume", model.RouteGroupAdmin, api.AutoReviewPRHandler.ResumeJob)
+	route("GET", "/api/v1/jobs/:name/support-bundle", model.RouteGroupAdmin, api.AutoReviewPRHandler.SupportBundle)
+}